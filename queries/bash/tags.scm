@@ -0,0 +1,17 @@
+;; Bash symbol queries
+
+;; Functions, in both forms: name() { ... } and function name { ... }
+(function_definition
+  name: (word) @name) @definition.function
+
+;; Assignments at the top level of the script, which is where configuration lives.
+;; Assignments inside functions, loops and conditionals are not symbols.
+(program
+  (variable_assignment
+    name: (variable_name) @name) @definition.variable)
+
+;; export NAME=value, readonly NAME=value, declare -r NAME=value
+(program
+  (declaration_command
+    (variable_assignment
+      name: (variable_name) @name)) @definition.variable)
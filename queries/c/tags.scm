@@ -1,14 +1,45 @@
 ;; C symbol queries (tree-sitter-c)
 
-;; Functions
+;; Functions, including those returning pointers
 (function_definition
   declarator: (function_declarator
                 declarator: (identifier) @name)) @definition.function
 
-;; Structs
+(function_definition
+  declarator: (pointer_declarator
+                declarator: (function_declarator
+                              declarator: (identifier) @name))) @definition.function
+
+;; Prototypes
+(declaration
+  declarator: (function_declarator
+                declarator: (identifier) @name)) @definition.function
+
+(declaration
+  declarator: (pointer_declarator
+                declarator: (function_declarator
+                              declarator: (identifier) @name))) @definition.function
+
+;; Structs, unions and enums with a body; forward declarations and uses such as `struct Person *p` are skipped
 (struct_specifier
-  name: (type_identifier) @name) @definition.struct
+  name: (type_identifier) @name
+  body: (field_declaration_list)) @definition.struct
+
+(union_specifier
+  name: (type_identifier) @name
+  body: (field_declaration_list)) @definition.union
 
-;; Enums
 (enum_specifier
-  name: (type_identifier) @name) @definition.enum
+  name: (type_identifier) @name
+  body: (enumerator_list)) @definition.enum
+
+;; Typedefs; `typedef struct { ... } Point` becomes a struct named Point
+(type_definition
+  declarator: (type_identifier) @name) @definition.type
+
+;; Macros
+(preproc_def
+  name: (identifier) @name) @definition.macro
+
+(preproc_function_def
+  name: (identifier) @name) @definition.macro
@@ -0,0 +1,71 @@
+;; C++ symbol queries (tree-sitter-cpp)
+
+;; Free functions, including those returning pointers
+(function_definition
+  declarator: (function_declarator
+                declarator: (identifier) @name)) @definition.function
+
+(function_definition
+  declarator: (pointer_declarator
+                declarator: (function_declarator
+                              declarator: (identifier) @name))) @definition.function
+
+;; Methods defined outside their class: void Cart::add(...)
+(function_definition
+  declarator: (function_declarator
+                declarator: (qualified_identifier
+                              name: (identifier) @name))) @definition.method
+
+;; Methods defined or declared inside their class
+(function_definition
+  declarator: (function_declarator
+                declarator: (field_identifier) @name)) @definition.method
+
+(field_declaration
+  declarator: (function_declarator
+                declarator: (field_identifier) @name)) @definition.method
+
+;; Prototypes
+(declaration
+  declarator: (function_declarator
+                declarator: (identifier) @name)) @definition.function
+
+(declaration
+  declarator: (pointer_declarator
+                declarator: (function_declarator
+                              declarator: (identifier) @name))) @definition.function
+
+;; Classes, structs, unions and enums with a body
+(class_specifier
+  name: (type_identifier) @name
+  body: (field_declaration_list)) @definition.class
+
+(struct_specifier
+  name: (type_identifier) @name
+  body: (field_declaration_list)) @definition.struct
+
+(union_specifier
+  name: (type_identifier) @name
+  body: (field_declaration_list)) @definition.union
+
+(enum_specifier
+  name: (type_identifier) @name
+  body: (enumerator_list)) @definition.enum
+
+;; Namespaces
+(namespace_definition
+  name: (namespace_identifier) @name) @definition.namespace
+
+;; Typedefs and using-aliases
+(type_definition
+  declarator: (type_identifier) @name) @definition.type
+
+(alias_declaration
+  name: (type_identifier) @name) @definition.type
+
+;; Macros
+(preproc_def
+  name: (identifier) @name) @definition.macro
+
+(preproc_function_def
+  name: (identifier) @name) @definition.macro
@@ -20,12 +20,10 @@
         name: (type_identifier) @name
         type: (interface_type)) @definition.interface)
 
-; Package-level variable declarations
-(var_declaration
-    (var_spec
-        name: (identifier) @name)) @definition.variable
+; Other named types (type UserID int) and aliases (type Alias = OldName) are
+; extracted in Python (TreeSitterSymbolExtractor._go_type_symbols), which
+; records their underlying or aliased type.
 
-; Package-level constant declarations
-(const_declaration
-    (const_spec
-        name: (identifier) @name)) @definition.constant
+; Package-level const and var declarations are extracted in Python
+; (TreeSitterSymbolExtractor._go_value_symbols) so grouped blocks, multi-name
+; specs and iota repetition can be expanded one symbol per name.
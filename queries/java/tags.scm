@@ -12,10 +12,44 @@
 (enum_declaration
   name: (identifier) @name) @definition.enum
 
+;; Records, and their components as fields
+(record_declaration
+  name: (identifier) @name) @definition.record
+
+(record_declaration
+  parameters: (formal_parameters
+    (formal_parameter
+      name: (identifier) @name) @definition.field))
+
+;; Annotation types, whose elements read like methods: String value() default "";
+(annotation_type_declaration
+  name: (identifier) @name) @definition.annotation
+
+(annotation_type_element_declaration
+  name: (identifier) @name) @definition.method
+
+;; Anonymous classes; the extractor numbers them within their enclosing type
+(object_creation_expression
+  type: (_) @name
+  (class_body)) @definition.class
+
 ;; Methods (instance & static)
 (method_declaration
   name: (identifier) @name) @definition.method
 
-;; Constructors
+;; Constructors, including compact record constructors
 (constructor_declaration
   name: (identifier) @name) @definition.method
+
+(compact_constructor_declaration
+  name: (identifier) @name) @definition.method
+
+;; Fields, one symbol per declared name
+(field_declaration
+  declarator: (variable_declarator
+    name: (identifier) @name)) @definition.field
+
+;; Interface constants
+(constant_declaration
+  declarator: (variable_declarator
+    name: (identifier) @name)) @definition.constant
@@ -1,8 +1,50 @@
-; Example tags.scm for JavaScript (tree-sitter-javascript)
-; See https://github.com/tree-sitter/tree-sitter-javascript/blob/master/queries/highlights.scm for reference
+;; tags.scm for JavaScript symbol extraction (tree-sitter-javascript)
+;; Exported declarations are matched by the same patterns as non-exported ones;
+;; the extractor marks them via the enclosing export_statement.
 
 (function_declaration
-  name: (identifier) @function)
+  name: (identifier) @name) @definition.function
+
+(generator_function_declaration
+  name: (identifier) @name) @definition.function
 
 (class_declaration
-  name: (identifier) @class)
+  name: (identifier) @name) @definition.class
+
+(class_body
+  (method_definition
+    name: (property_identifier) @name) @definition.method)
+
+; Module-scope functions assigned to const/let/var: const foo = () => {}
+(program
+  (lexical_declaration
+    (variable_declarator
+      name: (identifier) @name
+      value: [(arrow_function) (function_expression)]) @definition.function))
+
+(program
+  (variable_declaration
+    (variable_declarator
+      name: (identifier) @name
+      value: [(arrow_function) (function_expression)]) @definition.function))
+
+(program
+  (export_statement
+    declaration: (lexical_declaration
+      (variable_declarator
+        name: (identifier) @name
+        value: [(arrow_function) (function_expression)]) @definition.function)))
+
+(program
+  (export_statement
+    declaration: (variable_declaration
+      (variable_declarator
+        name: (identifier) @name
+        value: [(arrow_function) (function_expression)]) @definition.function)))
+
+; Default exports without a name are reported as "default":
+; export default () => {} / export default function () {}
+(program
+  (export_statement
+    "default" @name
+    [(arrow_function) (function_expression)] @definition.function))
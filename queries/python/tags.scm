@@ -1,6 +1,7 @@
 ;; tags.scm for Python symbol extraction
 
-; Top-level function definitions (direct child of module, potentially decorated)
+; Top-level function definitions (direct child of module, potentially decorated).
+; Async functions match too: the "async" keyword is just an extra child.
 (module
   (decorated_definition
     definition: (function_definition
@@ -9,17 +10,6 @@
   (function_definition
     name: (identifier) @name) @definition.function)
 
-; Async top-level function definitions (direct child of module, potentially decorated) - AFTER general function
-(module
-  (decorated_definition
-      definition: (function_definition
-          "async"
-          name: (identifier) @name) @definition.function))
-(module
-  (function_definition
-    "async"
-    name: (identifier) @name) @definition.function)
-
 ; Class definitions
 (class_definition
   name: (identifier) @name) @definition.class
@@ -35,17 +25,3 @@
     (function_definition
       name: (identifier) @name) @definition.method
   ))
-
-; Async methods within classes (potentially decorated) - AFTER general method
-(class_definition
-  body: (_ 
-      (decorated_definition
-          definition: (function_definition
-              "async"
-              name: (identifier) @name) @definition.method)))
-(class_definition
-  body: (_ 
-    (function_definition
-      "async" ; Match the async keyword
-      name: (identifier) @name) @definition.method
-  ))
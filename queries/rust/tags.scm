@@ -1,21 +1,59 @@
 ;; tags.scm for Rust symbol extraction
 
-(function_item
-  name: (identifier) @name
-  (#set! type "function"))
+; Free functions at crate or module level (nested fns inside bodies are skipped)
+(source_file
+  (function_item
+    name: (identifier) @name) @definition.function)
+
+(mod_item
+  body: (declaration_list
+    (function_item
+      name: (identifier) @name) @definition.function))
+
+; Methods inside impl blocks and trait definitions (with or without default bodies)
+(impl_item
+  body: (declaration_list
+    (function_item
+      name: (identifier) @name) @definition.method))
+
+(trait_item
+  body: (declaration_list
+    (function_item
+      name: (identifier) @name) @definition.method))
+
+(trait_item
+  body: (declaration_list
+    (function_signature_item
+      name: (identifier) @name) @definition.method))
 
 (struct_item
-  name: (type_identifier) @name
-  (#set! type "struct"))
+  name: (type_identifier) @name) @definition.struct
 
 (enum_item
-  name: (type_identifier) @name
-  (#set! type "enum"))
+  name: (type_identifier) @name) @definition.enum
+
+(union_item
+  name: (type_identifier) @name) @definition.union
 
 (trait_item
-  name: (type_identifier) @name
-  (#set! type "trait"))
+  name: (type_identifier) @name) @definition.trait
 
+; impl blocks are named after the implementing type; generic targets such as
+; Wrapper<T> are reduced to the base name by the extractor
 (impl_item
-  type: (type_identifier) @name
-  (#set! type "impl"))
+  type: (_) @name) @definition.impl
+
+(type_item
+  name: (type_identifier) @name) @definition.type
+
+(const_item
+  name: (identifier) @name) @definition.constant
+
+(static_item
+  name: (identifier) @name) @definition.static
+
+(mod_item
+  name: (identifier) @name) @definition.module
+
+(macro_definition
+  name: (identifier) @name) @definition.macro
@@ -1,78 +1,72 @@
 ;; tags.scm for TypeScript symbol extraction
+;; Exported declarations are matched by the same patterns as non-exported ones;
+;; the extractor marks them via the enclosing export_statement.
 
 ; functions
 (function_declaration
-  name: (identifier) @name @definition.function)
+  name: (identifier) @name) @definition.function
 
-; classes (with optional modifiers like export)
+(generator_function_declaration
+  name: (identifier) @name) @definition.function
+
+; classes
 (class_declaration
-  name: (type_identifier) @name @definition.class)
+  name: (type_identifier) @name) @definition.class
+
+(abstract_class_declaration
+  name: (type_identifier) @name) @definition.class
 
 ; interfaces
 (interface_declaration
-  name: (type_identifier) @name @definition.interface)
+  name: (type_identifier) @name) @definition.interface
 
 ; enums
 (enum_declaration
-  name: (identifier) @name @definition.enum)
-
-; Class methods (implementations, explicitly within class_body)
-(class_body
-  (method_definition
-    name: (property_identifier) @name @definition.method))
+  name: (identifier) @name) @definition.enum
 
-; Static Class methods (implementations, explicitly within class_body)
+; Class methods (including static methods and constructors)
 (class_body
   (method_definition
-    "static"
-    name: (property_identifier) @name @definition.method))
+    name: (property_identifier) @name) @definition.method)
 
-; Exported function (variable declarator with function)
-(export_statement
-  declaration: (variable_declaration
+; Module-scope functions assigned to const/let/var: const foo = () => {}
+(program
+  (lexical_declaration
     (variable_declarator
       name: (identifier) @name
-      value: (function_expression)
-    ) @definition.function
-  ))
+      value: [(arrow_function) (function_expression)]) @definition.function))
 
-; Exported function (variable declarator with arrow function)
-(export_statement
-  declaration: (variable_declaration
+(program
+  (variable_declaration
     (variable_declarator
       name: (identifier) @name
-      value: (arrow_function)
-    ) @definition.function
-  ))
+      value: [(arrow_function) (function_expression)]) @definition.function))
 
-; Exported class
-(export_statement
-  declaration: (class_declaration
-    name: (type_identifier) @name @definition.class
-  ))
+(program
+  (export_statement
+    declaration: (lexical_declaration
+      (variable_declarator
+        name: (identifier) @name
+        value: [(arrow_function) (function_expression)]) @definition.function)))
 
-; Exported interface
-(export_statement
-  declaration: (interface_declaration
-    name: (type_identifier) @name @definition.interface
-  ))
+(program
+  (export_statement
+    declaration: (variable_declaration
+      (variable_declarator
+        name: (identifier) @name
+        value: [(arrow_function) (function_expression)]) @definition.function)))
 
-; Exported enum
-(export_statement
-  declaration: (enum_declaration
-    name: (identifier) @name @definition.enum
-  ))
+; Default exports without a name are reported as "default":
+; export default () => {} / export default function () {}
+(program
+  (export_statement
+    "default" @name
+    [(arrow_function) (function_expression)] @definition.function))
 
 ; Type alias
 (type_alias_declaration
-    name: (type_identifier) @name @definition.type)
-
-; Exported type alias
-(export_statement
-    declaration: (type_alias_declaration
-        name: (type_identifier) @name @definition.type
-    ))
+  name: (type_identifier) @name) @definition.type
 
 ; Namespace (try internal_module)
 (internal_module
-  name: (identifier) @name @definition.namespace)
+  name: (identifier) @name) @definition.namespace
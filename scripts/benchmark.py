@@ -1,11 +1,78 @@
+import os
+import tempfile
 import time
 from codekite.repository import Repository as Repo
 
+
+def make_synthetic_tree(root: str, num_files: int) -> None:
+    """Writes *num_files* Python and Go files spread over nested packages."""
+    for i in range(num_files):
+        package = os.path.join(root, f"pkg{i % 20}", f"sub{i % 5}")
+        os.makedirs(package, exist_ok=True)
+        if i % 2:
+            body = "".join(f"def func_{i}_{j}(a, b):\n    return a + b\n\n" for j in range(30))
+            path = os.path.join(package, f"mod{i}.py")
+        else:
+            body = "package p\n\n" + "".join(f"func Func{i}_{j}(a, b int) int {{ return a + b }}\n" for j in range(30))
+            path = os.path.join(package, f"mod{i}.go")
+        with open(path, "w") as f:
+            f.write(body)
+
+
+def bench_parse_directory(num_files: int) -> None:
+    with tempfile.TemporaryDirectory() as tmpdir:
+        make_synthetic_tree(tmpdir, num_files)
+        repo = Repo(tmpdir)
+        for concurrency in (1, os.cpu_count() or 4):
+            start = time.time()
+            symbols, errors = repo.parse_directory(concurrency=concurrency)
+            elapsed = time.time() - start
+            num_symbols = sum(len(syms) for syms in symbols.values())
+            print(
+                f"parse_directory concurrency={concurrency}: {len(symbols)} files, "
+                f"{num_symbols} symbols, {len(errors)} errors in {elapsed:.2f} seconds."
+            )
+
+
+def bench_fuzzy_search(num_symbols: int) -> None:
+    from codekite.fuzzy import fuzzy_search
+
+    words = ["Helper", "Function", "hash", "Table", "Server", "http", "Request", "parse", "Index", "cache"]
+    symbols = [
+        {"name": words[i % 10] + words[(i // 10) % 10].capitalize() + str(i), "type": "function", "file": f"f{i % 100}.go"}
+        for i in range(num_symbols)
+    ]
+    for query in ("HFunc", "srvreq", "idx"):
+        start = time.perf_counter()
+        results = fuzzy_search(symbols, query, limit=20)
+        elapsed = time.perf_counter() - start
+        print(f"fuzzy_search {query!r} over {num_symbols} symbols: {len(results)} results in {elapsed * 1000:.1f} ms.")
+
+
 def main():
     import argparse
     parser = argparse.ArgumentParser(description="Benchmark codekite repo indexing.")
     parser.add_argument("repo", nargs="?", default=".", help="Path to repo root (default: .)")
+    parser.add_argument(
+        "--synthetic",
+        type=int,
+        metavar="N",
+        help="Benchmark parse_directory on a generated tree of N files instead of indexing a repo.",
+    )
+    parser.add_argument(
+        "--fuzzy",
+        type=int,
+        metavar="N",
+        help="Benchmark fuzzy symbol search over N generated symbol names.",
+    )
     args = parser.parse_args()
+    if args.fuzzy:
+        bench_fuzzy_search(args.fuzzy)
+        return
+    if args.synthetic:
+        bench_parse_directory(args.synthetic)
+        return
+
     repo = Repo(args.repo)
 
     print(f"Indexing repo at {args.repo} ...")
@@ -8,7 +8,17 @@ __author__ = "Shane Holloman"
 __version__ = importlib.metadata.version("codekite")
 
 from .repository import Repository
-from .repo_mapper import RepoMapper
+from .repo_mapper import RepoMapper, ExtractionCancelled, FileSymbols
+from .tree_sitter_symbol_extractor import ExtractionOptions
+from .languages import LanguageConflictWarning, SymbolExtractor, register_language, unregister_language
+from .symbol_filter import SymbolFilter, apply_filter
+from .symbol_index import SymbolIndex
+from .fuzzy import ScoredSymbol, fuzzy_search
+from .symbol_store import SymbolStore
+from .watcher import IndexEvent, Watcher
+from .go_build import BuildContext
+from .file_limits import FileLimits, SkippedFile
+from .git_info import GitInfo, NotGitRepositoryError
 from .code_searcher import CodeSearcher
 from .context_extractor import ContextExtractor
 # search helpers
@@ -26,6 +36,25 @@ except ImportError:
 __all__ = [
     "Repository",
     "RepoMapper",
+    "ExtractionCancelled",
+    "FileSymbols",
+    "ExtractionOptions",
+    "SymbolExtractor",
+    "register_language",
+    "unregister_language",
+    "SymbolFilter",
+    "apply_filter",
+    "SymbolIndex",
+    "ScoredSymbol",
+    "fuzzy_search",
+    "SymbolStore",
+    "IndexEvent",
+    "Watcher",
+    "BuildContext",
+    "FileLimits",
+    "SkippedFile",
+    "GitInfo",
+    "NotGitRepositoryError",
     "CodeSearcher",
     "ContextExtractor",
     "VectorSearcher",
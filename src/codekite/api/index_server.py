@@ -0,0 +1,205 @@
+"""A read-only JSON API over one repository's index, for running codekite as a sidecar.
+
+Every response carries the ``schema_version`` of :mod:`codekite.export`, and
+symbols and files have the fields of :class:`~codekite.export.ExportedSymbol`
+and :class:`~codekite.export.ExportedFile`. List endpoints take ``limit`` and
+``offset`` and report the ``total`` before paging, plus the ``next_offset``
+to ask for, or null on the last page::
+
+    GET /v1/symbols?name=^Add&kind=func&file=cart/*.go&limit=50&offset=0
+    GET /v1/files/cart/cart.go/symbols
+    GET /v1/search?q=TODO&regex=false&pattern=*.go
+    GET /v1/tree
+
+``GET /symbols`` and ``GET /file?path=cart/cart.go`` answer like their
+``/v1`` counterparts, for clients written against the unversioned paths.
+"""
+
+from __future__ import annotations
+import re
+import threading
+from contextlib import asynccontextmanager
+from dataclasses import asdict
+from typing import Any, Dict, List, Optional, Sequence
+
+from fastapi import FastAPI, HTTPException, Query
+
+from ..code_searcher import SearchOptions
+from ..export import SCHEMA_VERSION, export_symbols
+from ..repository import Repository
+from ..symbol_filter import SymbolFilter, apply_filter
+
+API_PREFIX = "/v1"
+DEFAULT_LIMIT = 100
+MAX_LIMIT = 1000
+
+
+class IndexState:
+    """
+    The symbols and file tree the API serves, held in memory.
+
+    Built once by :meth:`load`; with *watch*, a :class:`~codekite.watcher.Watcher`
+    keeps the symbols current and the file tree is rebuilt after each change.
+
+    Requests run on uvicorn's worker threads while the watcher updates the
+    index on its own, so readers never see the watcher's index directly:
+    each change builds a new path-to-symbols mapping and swaps it in under
+    :attr:`_lock`, and :meth:`symbols` hands out whichever mapping is current.
+    One request therefore sees one consistent version even if a change lands
+    halfway through it.
+    """
+
+    def __init__(self, repo: Repository, watch: bool = False, debounce: float = 0.2) -> None:
+        self.repo = repo
+        self.watch = watch
+        self.debounce = debounce
+        self._symbols: Dict[str, List[Dict[str, Any]]] = {}
+        self._tree: Optional[List[Dict[str, Any]]] = None
+        self._lock = threading.Lock()
+        self._watcher = None
+
+    def load(self) -> None:
+        if self.watch:
+            with self._lock:
+                self._watcher = self.repo.get_watcher(debounce=self.debounce, callback=self._changed)
+                # The watcher syncs before its thread starts, so the index is complete and not yet changing
+                self._watcher.start()
+                self._symbols = self._watcher.index.symbols
+        else:
+            by_file, _ = self.repo.parse_directory()
+            self._symbols = dict(sorted(by_file.items()))
+
+    def close(self) -> None:
+        if self._watcher is not None:
+            self._watcher.stop()
+            self._watcher = None
+
+    def _changed(self, event: Any) -> None:
+        with self._lock:
+            symbols = dict(self._symbols)
+            if event.op == "removed":
+                symbols.pop(event.path, None)
+            else:
+                symbols[event.path] = event.symbols
+            self._symbols = dict(sorted(symbols.items()))
+            self._tree = None
+
+    def symbols(self) -> Dict[str, List[Dict[str, Any]]]:
+        """Symbols keyed by repository-relative path, in path order. Callers must not modify the mapping."""
+        with self._lock:
+            return self._symbols
+
+    def tree(self) -> List[Dict[str, Any]]:
+        with self._lock:
+            if self._tree is None:
+                # The mapper caches its tree, which would otherwise outlive the changes the watcher saw
+                self.repo.mapper.invalidate_file_tree()
+                self._tree = self.repo.get_file_tree()
+            return self._tree
+
+
+def _paged(key: str, items: Sequence[Any], limit: int, offset: int) -> Dict[str, Any]:
+    """A list response holding the page of *items* under *key*. An offset past the end gives an empty page."""
+    return {
+        "schema_version": SCHEMA_VERSION,
+        "total": len(items),
+        "limit": limit,
+        "offset": offset,
+        "next_offset": offset + limit if offset + limit < len(items) else None,
+        key: list(items[offset : offset + limit]),
+    }
+
+
+def create_index_app(repo: Repository, watch: bool = False, cors: bool = False, debounce: float = 0.2) -> FastAPI:
+    """
+    Builds the API for *repo*; the index is built when the app starts and the watcher stopped when it shuts down.
+
+    Uvicorn shuts down gracefully on SIGTERM or Ctrl-C: it stops accepting
+    connections, finishes requests in flight, then runs the shutdown.
+
+    Args:
+        repo: Repository to serve.
+        watch: Keep the index current as files change, see :meth:`Repository.get_watcher`.
+        cors: Send CORS headers allowing GET requests from any origin, e.g. a local web UI.
+        debounce: Seconds a burst of saves must settle before the watcher re-parses.
+    """
+    state = IndexState(repo, watch=watch, debounce=debounce)
+
+    @asynccontextmanager
+    async def lifespan(app: FastAPI):
+        state.load()
+        try:
+            yield
+        finally:
+            state.close()
+
+    app = FastAPI(title="codekite index API", version=API_PREFIX.strip("/"), lifespan=lifespan)
+    app.state.index = state
+    if cors:
+        from fastapi.middleware.cors import CORSMiddleware
+
+        app.add_middleware(CORSMiddleware, allow_origins=["*"], allow_methods=["GET"], allow_headers=["*"])
+
+    limit_query = Query(DEFAULT_LIMIT, ge=1, le=MAX_LIMIT, description="Items per page.")
+    offset_query = Query(0, ge=0, description="Items to skip.")
+
+    @app.get(f"{API_PREFIX}/symbols")
+    @app.get("/symbols", include_in_schema=False)
+    def list_symbols(
+        name: Optional[str] = Query(None, description="Regular expression the symbol name must match."),
+        kind: Optional[str] = Query(None, description="Comma-separated kinds, e.g. func,type."),
+        file: Optional[str] = Query(None, description="Path or glob the symbol's file must match, e.g. cart/*.go."),
+        limit: int = limit_query,
+        offset: int = offset_query,
+    ) -> Dict[str, Any]:
+        try:
+            symbol_filter = SymbolFilter.from_strings(kind, name, include=[file] if file else None)
+        except re.error as e:
+            raise HTTPException(status_code=400, detail=f"Invalid name pattern: {e}")
+        by_file = {
+            path: apply_filter(symbols, symbol_filter)
+            for path, symbols in state.symbols().items()
+            if symbol_filter.matches_path(path)
+        }
+        document = export_symbols(by_file, repo)
+        return _paged("symbols", [s.to_dict() for s in document.symbols], limit, offset)
+
+    @app.get(API_PREFIX + "/files/{path:path}/symbols")
+    def file_symbols(path: str, limit: int = limit_query, offset: int = offset_query) -> Dict[str, Any]:
+        symbols = state.symbols().get(path)
+        if symbols is None:
+            raise HTTPException(status_code=404, detail=f"No indexed file: {path}")
+        document = export_symbols({path: symbols}, repo)
+        page = _paged("symbols", [s.to_dict() for s in document.symbols], limit, offset)
+        page["file"] = asdict(document.files[0])
+        return page
+
+    @app.get("/file", include_in_schema=False)
+    def file_query(
+        path: str = Query(..., description="Repository-relative path of an indexed file."),
+        limit: int = limit_query,
+        offset: int = offset_query,
+    ) -> Dict[str, Any]:
+        return file_symbols(path, limit=limit, offset=offset)
+
+    @app.get(f"{API_PREFIX}/search")
+    def search(
+        q: str = Query(..., min_length=1, description="Text to search for, or a regular expression with regex=true."),
+        regex: bool = Query(False, description="Treat q as a regular expression."),
+        pattern: str = Query("*", description="Glob the file paths must match, e.g. *.go."),
+        ignore_case: bool = Query(False, description="Match case-insensitively."),
+        limit: int = limit_query,
+        offset: int = offset_query,
+    ) -> Dict[str, Any]:
+        query = q if regex else re.escape(q)
+        try:
+            matches = repo.search_text(query, file_pattern=pattern, options=SearchOptions(case_sensitive=not ignore_case))
+        except re.error as e:
+            raise HTTPException(status_code=400, detail=f"Invalid regular expression: {e}")
+        return _paged("matches", matches, limit, offset)
+
+    @app.get(f"{API_PREFIX}/tree")
+    def tree(limit: int = limit_query, offset: int = offset_query) -> Dict[str, Any]:
+        return _paged("files", state.tree(), limit, offset)
+
+    return app
@@ -0,0 +1,462 @@
+"""Static call graphs for Go code: which functions and methods call which."""
+
+from __future__ import annotations
+import logging
+from collections import deque
+from dataclasses import dataclass, field
+from typing import TYPE_CHECKING, Any, Dict, Iterator, List, Optional, Sequence, Set, Tuple, Union
+
+from .import_graph import IMPORT_BLANK, IMPORT_DOT, extract_go_imports, go_module_path, package_import_path
+from .tree_sitter_symbol_extractor import TreeSitterSymbolExtractor, _go_base_type_name, _go_receiver_type, _node_text
+
+if TYPE_CHECKING:
+    from .repository import Repository
+
+logger = logging.getLogger(__name__)
+
+# Node kinds
+NODE_FUNCTION = "function"
+NODE_METHOD = "method"
+# A method of an interface declared in the repository; calls through interfaces stop here
+NODE_INTERFACE_METHOD = "interface_method"
+# A function from a package outside the repository, e.g. fmt.Println
+NODE_EXTERNAL = "external"
+# A call whose target could not be determined, e.g. through a function value
+NODE_UNRESOLVED = "unresolved"
+
+_GO_BUILTINS = frozenset(
+    {
+        "append", "cap", "clear", "close", "complex", "copy", "delete", "imag", "len", "make", "max", "min", "new",
+        "panic", "print", "println", "real", "recover",
+    }
+)
+_GO_BUILTIN_TYPES = frozenset(
+    {
+        "any", "bool", "byte", "comparable", "complex64", "complex128", "error", "float32", "float64", "int", "int8",
+        "int16", "int32", "int64", "rune", "string", "uint", "uint8", "uint16", "uint32", "uint64", "uintptr",
+    }
+)
+
+SymbolRef = Union[str, Dict[str, Any]]
+
+
+def _walk(node: Any) -> Iterator[Any]:
+    stack = [node]
+    while stack:
+        current = stack.pop()
+        yield current
+        stack.extend(reversed(current.named_children))
+
+
+def _dot_id(name: str) -> str:
+    escaped = name.replace('"', '\\"')
+    return f'"{escaped}"'
+
+
+def _qualify(package: str, name: str) -> str:
+    """``Add`` in the root package stays ``Add``; elsewhere it becomes ``example.com/app/calc.Add``."""
+    return name if package == "." else f"{package}.{name}"
+
+
+class CallGraph:
+    """
+    Caller/callee relationships between the Go functions and methods of a repository.
+
+    Nodes are named like Go qualified names: ``Add`` or ``User.Greet`` for the
+    package at the repository root, ``example.com/app/store.Store.Get`` for a
+    method in another package (using the go.mod module path when there is
+    one), ``fmt.Println`` for functions outside the repository.
+
+    Calls are resolved without type checking: a bare name resolves to the
+    package's function, ``pkg.Name`` goes through the file's imports, and
+    ``x.Method()`` resolves when the static type of ``x`` is known from a
+    receiver, parameter, ``var`` declaration, composite literal or the
+    single result of a repository function. Calls through an interface end at
+    the interface method. Anything else becomes an unresolved node such as
+    ``?.Method``. Builtins and conversions are not calls. ``_test.go`` files
+    are left out.
+    """
+
+    def __init__(self, repository: "Repository"):
+        self.repo = repository
+        self._nodes: Dict[str, Dict[str, Any]] = {}
+        self._callees: Dict[str, Set[str]] = {}
+        self._callers: Dict[str, Set[str]] = {}
+        self._module_path: Optional[str] = None
+        # Declarations of each package, by import path, as gathered by build()
+        self._packages: Dict[str, Dict[str, Any]] = {}
+        self._built = False
+
+    # ---- building ----
+
+    def build(self) -> "CallGraph":
+        """(Re)builds the graph from the repository's Go files; called on first use."""
+        self._nodes, self._callees, self._callers = {}, {}, {}
+        go_mod = self.repo.local_path / "go.mod"
+        module_path = go_module_path(go_mod.read_text(encoding="utf-8")) if go_mod.is_file() else None
+        self._module_path = module_path
+        parser = TreeSitterSymbolExtractor.get_parser(".go")
+
+        files: List[Dict[str, Any]] = []
+        packages: Dict[str, Dict[str, Any]] = {}
+        for file in self.repo.mapper.source_files():
+            if file.suffix != ".go" or file.name.endswith("_test.go"):
+                continue
+            rel_path = file.relative_to(self.repo.local_path).as_posix()
+            try:
+                source = self.repo.get_file_content(rel_path)
+            except (IOError, UnicodeDecodeError) as e:
+                logger.warning(f"Skipping calls in {rel_path}: {e}")
+                continue
+            package = package_import_path(rel_path, module_path)
+            info = packages.setdefault(package, {"functions": {}, "types": {}, "methods": set()})
+            root = parser.parse(source.encode("utf-8")).root_node
+            self._collect_declarations(root, info)
+            files.append({"path": rel_path, "package": package, "root": root, "imports": extract_go_imports(source)})
+
+        for file in files:
+            imports = self.import_names(file["imports"], module_path)
+            for declaration in file["root"].named_children:
+                if declaration.type in ("function_declaration", "method_declaration"):
+                    self._add_function(declaration, file, packages, imports)
+        self._packages = packages
+        self._built = True
+        return self
+
+    @staticmethod
+    def _collect_declarations(root: Any, info: Dict[str, Any]) -> None:
+        for node in root.named_children:
+            if node.type == "function_declaration":
+                name = node.child_by_field_name("name")
+                result = node.child_by_field_name("result")
+                # Only a single unnamed result tells us the type of `x := NewThing()`
+                result_type = _node_text(result) if result is not None and result.type != "parameter_list" else None
+                info["functions"][_node_text(name)] = _go_base_type_name(result_type) if result_type else None
+            elif node.type == "method_declaration":
+                receiver = _go_receiver_type(node)
+                if receiver:
+                    info["methods"].add((_go_base_type_name(receiver), _node_text(node.child_by_field_name("name"))))
+            elif node.type == "type_declaration":
+                for spec in node.named_children:
+                    if spec.type in ("type_spec", "type_alias"):
+                        type_node = spec.child_by_field_name("type")
+                        kind = "interface" if type_node is not None and type_node.type == "interface_type" else "type"
+                        info["types"][_node_text(spec.child_by_field_name("name"))] = kind
+
+    @staticmethod
+    def import_names(imports: List[Dict[str, Any]], module_path: Optional[str]) -> Dict[str, Dict[str, Any]]:
+        """Maps the name a file uses for each import to its package: internal (a graph package) or external."""
+        names: Dict[str, Dict[str, Any]] = {}
+        for entry in imports:
+            if entry["kind"] in (IMPORT_BLANK, IMPORT_DOT):
+                continue
+            path = entry["path"]
+            internal = bool(module_path) and (path == module_path or path.startswith(module_path + "/"))
+            names[entry.get("alias") or path.rsplit("/", 1)[-1]] = {"path": path, "internal": internal}
+        return names
+
+    def _add_node(self, key: str, kind: str, file: Optional[str] = None, line: Optional[int] = None) -> None:
+        node = self._nodes.get(key)
+        if node is None or (file is not None and node.get("file") is None):
+            self._nodes[key] = {"name": key, "kind": kind, "file": file, "line": line}
+        self._callees.setdefault(key, set())
+        self._callers.setdefault(key, set())
+
+    def _add_edge(self, caller: str, callee: str, kind: str) -> None:
+        if callee not in self._nodes:
+            self._add_node(callee, kind)
+        self._callees[caller].add(callee)
+        self._callers[callee].add(caller)
+
+    def _add_function(
+        self, declaration: Any, file: Dict[str, Any], packages: Dict[str, Dict[str, Any]], imports: Dict[str, Any]
+    ) -> None:
+        found = self.function_calls(declaration, file["package"], packages, imports)
+        if found is None:
+            return
+        caller, kind, calls = found
+        self._add_node(caller, kind, file["path"], declaration.start_point[0])
+        for callee, callee_kind in calls:
+            self._add_edge(caller, callee, callee_kind)
+
+    @staticmethod
+    def function_calls(
+        declaration: Any, package: str, packages: Dict[str, Dict[str, Any]], imports: Dict[str, Any]
+    ) -> Optional[Tuple[str, str, List[Tuple[str, str]]]]:
+        """``(caller, kind, [(callee, kind), ...])`` for a function or method declaration, calls in source order."""
+        info = packages[package]
+        name = _node_text(declaration.child_by_field_name("name"))
+        variables: Dict[str, str] = {}
+        if declaration.type == "method_declaration":
+            receiver = _go_receiver_type(declaration)
+            if not receiver:
+                return None
+            receiver_type = _go_base_type_name(receiver)
+            caller, kind = _qualify(package, f"{receiver_type}.{name}"), NODE_METHOD
+            CallGraph._declare_parameters(declaration.child_by_field_name("receiver"), variables)
+        else:
+            caller, kind = _qualify(package, name), NODE_FUNCTION
+        CallGraph._declare_parameters(declaration.child_by_field_name("parameters"), variables)
+
+        calls: List[Tuple[str, str]] = []
+        body = declaration.child_by_field_name("body")
+        if body is None:
+            return caller, kind, calls
+        CallGraph._declare_locals(body, info, variables)
+        for node in _walk(body):
+            if node.type != "call_expression":
+                continue
+            target = CallGraph._resolve(node.child_by_field_name("function"), package, packages, imports, variables)
+            if target is not None:
+                calls.append(target)
+        return caller, kind, calls
+
+    @staticmethod
+    def _declare_parameters(parameter_list: Any, variables: Dict[str, str]) -> None:
+        for parameter in parameter_list.named_children if parameter_list is not None else []:
+            type_node = parameter.child_by_field_name("type")
+            if type_node is None:
+                continue
+            for name in parameter.children_by_field_name("name"):
+                variables[_node_text(name)] = _go_base_type_name(_node_text(type_node))
+
+    @staticmethod
+    def _declare_locals(body: Any, info: Dict[str, Any], variables: Dict[str, str]) -> None:
+        """Records the static types of locals that are evident from their declarations (no shadowing analysis)."""
+        for node in _walk(body):
+            if node.type == "var_spec":
+                type_node = node.child_by_field_name("type")
+                if type_node is not None:
+                    for name in node.children_by_field_name("name"):
+                        variables[_node_text(name)] = _go_base_type_name(_node_text(type_node))
+            elif node.type == "short_var_declaration":
+                left, right = node.child_by_field_name("left"), node.child_by_field_name("right")
+                if left is None or right is None or len(left.named_children) != len(right.named_children):
+                    continue
+                for name, value in zip(left.named_children, right.named_children):
+                    value_type = CallGraph._expression_type(value, info)
+                    if value_type:
+                        variables[_node_text(name)] = value_type
+
+    @staticmethod
+    def _expression_type(value: Any, info: Dict[str, Any]) -> Optional[str]:
+        if value.type == "unary_expression" and value.child_by_field_name("operand") is not None:
+            value = value.child_by_field_name("operand")
+        if value.type == "composite_literal":
+            type_node = value.child_by_field_name("type")
+            return _go_base_type_name(_node_text(type_node)) if type_node is not None else None
+        if value.type == "call_expression":
+            function = value.child_by_field_name("function")
+            if function is not None and function.type == "identifier":
+                return info["functions"].get(_node_text(function))
+        return None
+
+    @staticmethod
+    def _resolve(
+        function: Any,
+        package: str,
+        packages: Dict[str, Dict[str, Any]],
+        imports: Dict[str, Any],
+        variables: Dict[str, str],
+    ) -> Optional[tuple]:
+        """Returns ``(callee, kind)`` for a call's function expression, or None if it is not a call to track."""
+        if function is None:
+            return None
+        info = packages[package]
+        if function.type == "identifier":
+            name = _node_text(function)
+            if name in info["functions"]:
+                return _qualify(package, name), NODE_FUNCTION
+            if name in info["types"] or name in _GO_BUILTINS or name in _GO_BUILTIN_TYPES:
+                # Builtins and conversions are not calls
+                return None
+            # A function value: parameter, closure or dot-imported name
+            return f"?.{name}", NODE_UNRESOLVED
+        if function.type != "selector_expression":
+            return None
+
+        operand, field = function.child_by_field_name("operand"), function.child_by_field_name("field")
+        method = _node_text(field)
+        if operand.type == "identifier":
+            name = _node_text(operand)
+            if name in variables:
+                return CallGraph._resolve_method(variables[name], method, package, info)
+            if name in imports:
+                target = imports[name]
+                if not target["internal"]:
+                    return f"{target['path']}.{method}", NODE_EXTERNAL
+                target_info = packages.get(target["path"])
+                if target_info is not None and method in target_info["functions"]:
+                    return _qualify(target["path"], method), NODE_FUNCTION
+                if target_info is not None and method in target_info["types"]:
+                    return None
+                return f"{target['path']}.{method}", NODE_UNRESOLVED
+        elif operand.type == "composite_literal" or (
+            operand.type == "parenthesized_expression" and operand.named_children
+        ):
+            inner = operand if operand.type == "composite_literal" else operand.named_children[0]
+            value_type = CallGraph._expression_type(inner, info)
+            if value_type:
+                return CallGraph._resolve_method(value_type, method, package, info)
+        return f"?.{method}", NODE_UNRESOLVED
+
+    @staticmethod
+    def _resolve_method(type_name: str, method: str, package: str, info: Dict[str, Any]) -> tuple:
+        if (type_name, method) in info["methods"]:
+            return _qualify(package, f"{type_name}.{method}"), NODE_METHOD
+        if info["types"].get(type_name) == "interface":
+            return _qualify(package, f"{type_name}.{method}"), NODE_INTERFACE_METHOD
+        # A field holding a function, an embedded type's method or a type from another package
+        return f"?.{method}", NODE_UNRESOLVED
+
+    # ---- queries ----
+
+    def _ensure_built(self) -> None:
+        if not self._built:
+            self.build()
+
+    def _key(self, symbol: SymbolRef) -> str:
+        """Accepts a node name, or a symbol from :meth:`Repository.extract_symbols`."""
+        if isinstance(symbol, str):
+            return symbol
+        package = package_import_path(symbol.get("file") or "", self._module_path)
+        return _qualify(package, symbol.get("node_path") or symbol["name"])
+
+    def nodes(self) -> List[str]:
+        """Returns every node name, sorted: repository functions and methods, plus call targets outside them."""
+        self._ensure_built()
+        return sorted(self._nodes)
+
+    def module_path(self) -> Optional[str]:
+        """Returns the module path from the repository's go.mod, or None without one."""
+        self._ensure_built()
+        return self._module_path
+
+    def packages(self) -> Dict[str, Dict[str, Any]]:
+        """
+        Returns the declarations of each package, by import path, as :meth:`function_calls` takes them.
+
+        Each maps ``functions`` to their result types, ``types`` to
+        ``"type"`` or ``"interface"``, and holds ``(type, method)`` pairs in
+        ``methods``. The mapping is a copy; adding packages to it leaves the
+        graph alone.
+        """
+        self._ensure_built()
+        return dict(self._packages)
+
+    def node(self, symbol: SymbolRef) -> Optional[Dict[str, Any]]:
+        """Returns ``{"name", "kind", "file", "line"}`` for a node; file and line are None outside the repository."""
+        self._ensure_built()
+        node = self._nodes.get(self._key(symbol))
+        return dict(node) if node is not None else None
+
+    def callees_of(self, symbol: SymbolRef) -> List[str]:
+        """Returns the sorted names of everything *symbol* calls directly."""
+        self._ensure_built()
+        return sorted(self._callees.get(self._key(symbol), ()))
+
+    def callers_of(self, symbol: SymbolRef) -> List[str]:
+        """Returns the sorted names of the functions and methods that call *symbol* directly."""
+        self._ensure_built()
+        return sorted(self._callers.get(self._key(symbol), ()))
+
+    def _reachable(self, start: str, edges: Dict[str, Set[str]]) -> List[str]:
+        seen = {start}
+        queue = deque([start])
+        while queue:
+            for target in edges.get(queue.popleft(), ()):
+                if target not in seen:
+                    seen.add(target)
+                    queue.append(target)
+        seen.discard(start)
+        return sorted(seen)
+
+    def transitive_callees(self, symbol: SymbolRef) -> List[str]:
+        """Everything reachable from *symbol* through calls, other than itself; call cycles are visited once."""
+        self._ensure_built()
+        return self._reachable(self._key(symbol), self._callees)
+
+    def transitive_callers(self, symbol: SymbolRef) -> List[str]:
+        """Every function that can reach *symbol* through calls, i.e. what a change to it can affect."""
+        self._ensure_built()
+        return self._reachable(self._key(symbol), self._callers)
+
+    def to_dot(self) -> str:
+        """Renders the graph in Graphviz DOT; external and unresolved targets are drawn differently."""
+        self._ensure_built()
+        styles = {
+            NODE_INTERFACE_METHOD: "style=dashed",
+            NODE_EXTERNAL: "style=filled, fillcolor=lightgrey",
+            NODE_UNRESOLVED: "style=dotted",
+        }
+        lines = ["digraph calls {", '  rankdir="LR";', "  node [shape=box];"]
+        for key in self.nodes():
+            style = styles.get(self._nodes[key]["kind"])
+            lines.append(f"  {_dot_id(key)} [{style}];" if style else f"  {_dot_id(key)};")
+        for key in self.nodes():
+            for callee in sorted(self._callees[key]):
+                lines.append(f"  {_dot_id(key)} -> {_dot_id(callee)};")
+        lines.append("}")
+        return "\n".join(lines)
+
+
+@dataclass
+class FileCallGraph:
+    """
+    The calls made by each function and method of one Go file; see :func:`build_file_call_graph`.
+
+    Attributes:
+        calls: For every function and method, the functions, methods and
+            interface methods of the same file it calls, by qualified name
+            (``"Add"``, ``"User.Greet"``), sorted and without repeats.
+        external: For callers that make any, the calls that leave the file or
+            could not be resolved, e.g. ``"fmt.Println"`` or ``"?.Write"``.
+    """
+
+    calls: Dict[str, List[str]] = field(default_factory=dict)
+    external: Dict[str, List[str]] = field(default_factory=dict)
+
+
+def build_file_call_graph(symbols: Sequence[Dict[str, Any]], source: str) -> FileCallGraph:
+    """
+    Lists which functions and methods of a Go file call which, resolving calls within that file only.
+
+    This is :class:`CallGraph` restricted to one file, with the same
+    best-effort rules and the same limits: there is no type checking, so a
+    method call resolves only when the receiver's static type is clear from a
+    receiver, parameter, ``var``, composite literal or the single result of a
+    function in the file. A call through an interface is recorded as the
+    interface method (``Greeter.Greet``) when the interface is declared in
+    the file; which implementation runs is not known. Calls through function
+    values, embedded fields, other files of the package or other packages
+    end up in ``external`` (``?.Name`` when the target is unknown).
+    Builtins and conversions are not calls.
+
+    Args:
+        symbols: The file's symbols from :meth:`Repository.extract_symbols`;
+            functions and methods among them become the graph's callers.
+        source: The file's Go source.
+    """
+    root = TreeSitterSymbolExtractor.get_parser(".go").parse(source.encode("utf-8")).root_node
+    packages: Dict[str, Dict[str, Any]] = {".": {"functions": {}, "types": {}, "methods": set()}}
+    CallGraph._collect_declarations(root, packages["."])
+    # Without a module path every import is outside the file
+    imports = CallGraph.import_names(extract_go_imports(source), None)
+    lines = {s["start_line"] for s in symbols if s.get("type") in (NODE_FUNCTION, NODE_METHOD)}
+
+    graph = FileCallGraph()
+    for declaration in root.named_children:
+        if declaration.type not in ("function_declaration", "method_declaration"):
+            continue
+        if declaration.start_point[0] not in lines:
+            continue
+        found = CallGraph.function_calls(declaration, ".", packages, imports)
+        if found is None:
+            continue
+        caller, _, calls = found
+        internal = {c for c, kind in calls if kind in (NODE_FUNCTION, NODE_METHOD, NODE_INTERFACE_METHOD)}
+        external = {c for c, kind in calls if c not in internal}
+        graph.calls[caller] = sorted(internal | set(graph.calls.get(caller, ())))
+        if external:
+            graph.external[caller] = sorted(external | set(graph.external.get(caller, ())))
+    return graph
@@ -0,0 +1,279 @@
+"""Symbols added, removed or modified between two git revisions."""
+
+from __future__ import annotations
+import logging
+import os
+import re
+import subprocess
+from dataclasses import dataclass
+from typing import TYPE_CHECKING, Any, Dict, Iterable, List, Optional, Set, Tuple
+
+from . import languages
+from .symbol_ids import assign_ids, go_package
+
+if TYPE_CHECKING:
+    from .repository import Repository
+
+logger = logging.getLogger(__name__)
+
+CHANGE_ADDED = "added"
+CHANGE_MODIFIED = "modified"
+CHANGE_REMOVED = "removed"
+# A modification confined to the symbol's doc comment or docstring (see changed_symbols)
+CHANGE_DOC = "doc"
+
+_CHANGE_LABELS = {CHANGE_ADDED: "Added", CHANGE_MODIFIED: "Modified", CHANGE_REMOVED: "Removed", CHANGE_DOC: "Doc changed"}
+# How Go symbol kinds are spelled in summaries: "func Add", "type User"
+_GO_KINDS = {"function": "func", "struct": "type", "interface": "type", "type": "type"}
+
+_HUNK_HEADER = re.compile(r"^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@")
+# The opening quote of a Python docstring at the start of a line
+_DOCSTRING_START = re.compile(r"^[ \t]*[rRuU]?(\"\"\"|'''|\"|')", re.MULTILINE)
+
+
+@dataclass(frozen=True)
+class ChangedSymbol:
+    """
+    One symbol that differs between two revisions.
+
+    Attributes:
+        change: ``"added"``, ``"modified"``, ``"removed"``, or ``"doc"`` for a
+            documentation-only modification when those are reported separately.
+        symbol: The symbol at the head revision; at the base revision when removed.
+        file: Path of :attr:`symbol`'s file at that revision.
+        old_file: The base path when the file was renamed, otherwise None.
+        doc_only: Every changed line of a modified symbol is in its doc comment or docstring.
+    """
+
+    change: str
+    symbol: Dict[str, Any]
+    file: str
+    old_file: Optional[str] = None
+    doc_only: bool = False
+
+    def __str__(self) -> str:
+        """``"Modified: func Add"``, ``"Added: method User.Greet"``."""
+        kind = self.symbol.get("type", "symbol")
+        if os.path.splitext(self.file)[1] == ".go":
+            kind = _GO_KINDS.get(kind, kind)
+        return f"{_CHANGE_LABELS[self.change]}: {kind} {_qualified_name(self.symbol)}"
+
+
+@dataclass
+class _FileDiff:
+    old_path: Optional[str]
+    new_path: Optional[str]
+    # 0-based line numbers touched on each side
+    old_lines: Set[int]
+    new_lines: Set[int]
+
+
+def _qualified_name(symbol: Dict[str, Any]) -> str:
+    return symbol.get("node_path") or symbol["name"]
+
+
+def _strip_prefix(path: str, prefix: str) -> Optional[str]:
+    if path == "/dev/null":
+        return None
+    return path[len(prefix) :] if path.startswith(prefix) else path
+
+
+def parse_unified_diff(diff: str) -> List[_FileDiff]:
+    """
+    Reads the files and touched lines out of ``git diff --unified=0`` output.
+
+    A pure insertion touches no base lines and a pure deletion no head lines;
+    added files have no ``old_path`` and deleted files no ``new_path``.
+    """
+    files: List[_FileDiff] = []
+    current: Optional[_FileDiff] = None
+    # Lines left in the current hunk; until they are read, "--- x" is content, not a header
+    remaining = 0
+    for line in diff.splitlines():
+        if remaining > 0 and line[:1] in ("-", "+"):
+            remaining -= 1
+            continue
+        if line.startswith("diff --git a/"):
+            # "a/P b/P": both halves are the same except for renames, which say so below
+            rest = line[len("diff --git a/") :]
+            path = rest[: (len(rest) - 3) // 2]
+            current = _FileDiff(path, path, set(), set())
+            files.append(current)
+        elif current is None:
+            continue
+        elif line.startswith("new file mode"):
+            current.old_path = None
+        elif line.startswith("deleted file mode"):
+            current.new_path = None
+        elif line.startswith("rename from "):
+            current.old_path = line[len("rename from ") :]
+        elif line.startswith("rename to "):
+            current.new_path = line[len("rename to ") :]
+        elif line.startswith("--- "):
+            current.old_path = _strip_prefix(line[4:], "a/")
+        elif line.startswith("+++ "):
+            current.new_path = _strip_prefix(line[4:], "b/")
+        else:
+            match = _HUNK_HEADER.match(line)
+            if match is None:
+                continue
+            old_start, old_count, new_start, new_count = (
+                int(g) if g is not None else 1 for g in match.groups()
+            )
+            current.old_lines.update(range(old_start - 1, old_start - 1 + old_count))
+            current.new_lines.update(range(new_start - 1, new_start - 1 + new_count))
+            remaining = old_count + new_count
+    return files
+
+
+def _span(symbol: Dict[str, Any]) -> Tuple[int, int]:
+    """The symbol's lines, including a doc comment above the declaration."""
+    return symbol.get("doc_start_line", symbol["start_line"]), symbol["end_line"]
+
+
+def _doc_lines(symbol: Dict[str, Any]) -> Set[int]:
+    """Lines holding the symbol's doc comment (Go) or docstring (Python)."""
+    if "doc_start_line" in symbol:
+        return set(range(symbol["doc_start_line"], symbol["start_line"]))
+    code = symbol.get("code") or ""
+    if not symbol.get("docstring") or not code:
+        return set()
+    # The docstring is the first statement of the body, so skip the def/class header line
+    match = _DOCSTRING_START.search(code, code.find("\n") + 1)
+    if match is None:
+        return set()
+    quote = match.group(1)
+    end = code.find(quote, match.end()) if len(quote) == 3 else match.end()
+    if end < 0:
+        return set()
+    first = symbol["start_line"] + code.count("\n", 0, match.start(1))
+    return set(range(first, symbol["start_line"] + code.count("\n", 0, end) + 1))
+
+
+def _keyed(symbols: Iterable[Dict[str, Any]]) -> Dict[Tuple[str, str, int], Dict[str, Any]]:
+    """Symbols keyed by type, qualified name and ordinal, so that repeated names (Go init) pair up in order."""
+    keyed: Dict[Tuple[str, str, int], Dict[str, Any]] = {}
+    seen: Dict[Tuple[str, str], int] = {}
+    for symbol in sorted(symbols, key=lambda s: s["start_line"]):
+        base = (symbol.get("type", ""), _qualified_name(symbol))
+        ordinal = seen.get(base, 0)
+        seen[base] = ordinal + 1
+        keyed[base + (ordinal,)] = symbol
+    return keyed
+
+
+def _touched(
+    keyed: Dict[Tuple[str, str, int], Dict[str, Any]], lines: Set[int]
+) -> Dict[Tuple[str, str, int], Set[int]]:
+    """Assigns each touched line to the innermost symbol containing it, so an edited method does not modify its class."""
+    touched: Dict[Tuple[str, str, int], Set[int]] = {}
+    for line in lines:
+        best = None
+        for key, symbol in keyed.items():
+            start, end = _span(symbol)
+            if start <= line <= end and (best is None or end - start < best[1]):
+                best = (key, end - start)
+        if best is not None:
+            touched.setdefault(best[0], set()).add(line)
+    return touched
+
+
+class ChangeDetector:
+    """
+    Maps ``git diff`` hunks between two revisions onto the symbols of the changed files.
+
+    Symbols are paired across the revisions by type and qualified name
+    (``User.Greet``), following git's rename detection for the file, so a
+    renamed file only reports the symbols whose lines changed. A symbol is
+    modified when a changed line falls inside it and not inside a smaller
+    symbol nested in it; Go doc comments count as part of the symbol.
+    """
+
+    def __init__(self, repository: "Repository"):
+        self.repo = repository
+
+    def _git(self, *args: str) -> str:
+        try:
+            result = subprocess.run(
+                ["git", "-c", "core.quotepath=false", *args],
+                cwd=self.repo.repo_path,
+                capture_output=True,
+                check=True,
+            )
+        except subprocess.CalledProcessError as e:
+            raise ValueError(f"git {args[0]} failed: {e.stderr.decode('utf-8', errors='replace').strip()}") from e
+        return result.stdout.decode("utf-8", errors="replace")
+
+    def _symbols(self, ref: str, path: Optional[str]) -> Dict[Tuple[str, str, int], Dict[str, Any]]:
+        if path is None:
+            return {}
+        ext = os.path.splitext(path)[1]
+        try:
+            source = self._git("show", f"{ref}:./{path}")
+        except ValueError as e:
+            logger.warning(f"Skipping {path} at {ref}: {e}")
+            return {}
+        symbols = languages.extract_symbols(ext, path, source)
+        for symbol in symbols:
+            symbol["file"] = path
+        assign_ids(path, symbols, go_package(path, source, self.repo.mapper.module_path()))
+        return _keyed(symbols)
+
+    def changed_files(self, base_ref: str) -> List[str]:
+        """
+        Returns the files that differ between *base_ref* and the working tree, sorted.
+
+        Committed, staged and unstaged changes all count, as do untracked files
+        that are not gitignored, so a CI checkout of a pull request lists what
+        the pull request touched. Deleted files are left out; renamed files are
+        listed under their new path. Paths are relative to the repository root.
+
+        Raises:
+            ValueError: If git fails, e.g. for an unknown revision.
+        """
+        diff = self._git("diff", "--name-only", "-z", "--no-renames", "--diff-filter=d", "--relative", base_ref, "--")
+        untracked = self._git("ls-files", "--others", "--exclude-standard", "-z")
+        return sorted({p for p in (diff + untracked).split("\0") if p})
+
+    def changed_symbols(self, base_ref: str, head_ref: str = "HEAD", separate_doc_changes: bool = False) -> List[ChangedSymbol]:
+        """
+        Returns the symbols that differ between *base_ref* and *head_ref*.
+
+        Args:
+            base_ref: The older revision, e.g. ``"main"`` or a commit SHA.
+            head_ref: The newer revision. Defaults to ``"HEAD"``.
+            separate_doc_changes: Report modifications that only touch a Go doc
+                comment or Python docstring as ``"doc"`` instead of ``"modified"``.
+                They have ``doc_only`` set either way.
+
+        Returns:
+            Changes ordered by file and line.
+
+        Raises:
+            ValueError: If git fails, e.g. for an unknown revision.
+        """
+        diff = self._git(
+            "diff", "--unified=0", "--no-color", "--no-ext-diff", "-M", "--relative", base_ref, head_ref, "--"
+        )
+        changes: List[ChangedSymbol] = []
+        for file_diff in parse_unified_diff(diff):
+            base = self._symbols(base_ref, file_diff.old_path)
+            head = self._symbols(head_ref, file_diff.new_path)
+            renamed_from = file_diff.old_path if file_diff.old_path != file_diff.new_path else None
+            for key, symbol in head.items():
+                if key not in base:
+                    changes.append(ChangedSymbol(CHANGE_ADDED, symbol, file_diff.new_path, renamed_from))
+            for key, symbol in base.items():
+                if key not in head:
+                    changes.append(ChangedSymbol(CHANGE_REMOVED, symbol, file_diff.old_path, renamed_from))
+            base_touched = _touched(base, file_diff.old_lines)
+            head_touched = _touched(head, file_diff.new_lines)
+            for key in sorted(set(base_touched) | set(head_touched)):
+                if key not in base or key not in head:
+                    continue
+                doc_only = base_touched.get(key, set()) <= _doc_lines(base[key]) and head_touched.get(
+                    key, set()
+                ) <= _doc_lines(head[key])
+                change = CHANGE_DOC if doc_only and separate_doc_changes else CHANGE_MODIFIED
+                changes.append(ChangedSymbol(change, head[key], file_diff.new_path, renamed_from, doc_only))
+        return sorted(changes, key=lambda c: (c.file, c.symbol["start_line"], c.change))
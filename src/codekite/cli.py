@@ -1,6 +1,10 @@
 """codekite Command Line Interface."""
 
 import importlib.metadata
+import os
+import sys
+from typing import List, Tuple
+
 import typer
 
 app = typer.Typer(help="A modular toolkit for LLM-powered codebase understanding.")
@@ -31,9 +35,29 @@ def version():
     typer.echo(f"codekite version: {_get_version()}")
 
 
+def _parse_addr(addr: str) -> Tuple[str, int]:
+    """Splits a ``host:port`` listen address; an empty host, as in ``:7450``, listens on every interface."""
+    host, sep, port = addr.rpartition(":")
+    if not sep or not port.isdigit():
+        raise typer.BadParameter(f"{addr!r} is not host:port, e.g. :7450 or 127.0.0.1:7450", param_hint="--addr")
+    return host.strip("[]") or "0.0.0.0", int(port)
+
+
 @app.command()
-def serve(host: str = "0.0.0.0", port: int = 8000, reload: bool = True):
+def serve(
+    host: str = "0.0.0.0",
+    port: int = 8000,
+    reload: bool = True,
+    repo: str = typer.Option(
+        None, "--repo", "--root", help="Serve this repository's index under /v1 instead of the multi-repository API."
+    ),
+    addr: str = typer.Option(None, "--addr", help="Listen address as host:port, e.g. :7450; overrides --host and --port."),
+    watch: bool = typer.Option(False, "--watch", help="With --repo, keep the index current as files change."),
+    cors: bool = typer.Option(False, "--cors", help="With --repo, allow GET requests from any origin."),
+):
     """Run the codekite REST API server (requires `codekite[api]` dependencies)."""
+    if addr:
+        host, port = _parse_addr(addr)
     try:
         import uvicorn
         from codekite.api import app as fastapi_app  # Import the FastAPI app instance
@@ -44,6 +68,18 @@ def serve(host: str = "0.0.0.0", port: int = 8000, reload: bool = True):
         )
         raise typer.Exit(code=1)
 
+    if repo:
+        from codekite import Repository
+        from codekite.api.index_server import create_index_app
+
+        if not os.path.isdir(repo):
+            typer.secho(f"Error: No such directory: {repo}", fg=typer.colors.RED, err=True)
+            raise typer.Exit(code=1)
+        typer.echo(f"Serving the index of {repo} on http://{host}:{port}/v1")
+        # Uvicorn finishes requests in flight and stops the watcher on SIGTERM
+        uvicorn.run(create_index_app(Repository(repo), watch=watch, cors=cors), host=host, port=port)
+        return
+
     typer.echo(f"Starting codekite API server on http://{host}:{port}")
     # When reload=True, we must use import string instead of app instance
     if reload:
@@ -57,22 +93,384 @@ def search(
     path: str = typer.Argument(..., help="Path to the local repository."),
     query: str = typer.Argument(..., help="Text or regex pattern to search for."),
     pattern: str = typer.Option("*.py", "--pattern", "-p", help="Glob pattern for files to search."),
+    ignore_case: bool = typer.Option(False, "--ignore-case", "-i", help="Match case-insensitively."),
+    context: int = typer.Option(0, "--context", "-C", help="Lines of context to show around each match."),
+    max_results: int = typer.Option(None, "--max-results", "-m", help="Stop after this many matches."),
+    max_per_file: int = typer.Option(None, "--max-per-file", help="Show at most this many matches per file."),
+    vendored: bool = typer.Option(False, "--vendored", help="Also search vendor/, third_party/ and node_modules/."),
+    generated: bool = typer.Option(False, "--generated", help="Also search generated files such as x.pb.go."),
 ):
     """Perform a textual search in a local repository."""
     from codekite import Repository  # Local import to avoid circular deps if CLI is imported elsewhere
+    from codekite.code_searcher import SearchOptions
 
+    options = SearchOptions(
+        case_sensitive=not ignore_case,
+        context_lines_before=context,
+        context_lines_after=context,
+        max_results=max_results,
+        max_matches_per_file=max_per_file,
+        include_vendored=vendored,
+        include_generated=generated,
+    )
     try:
         repo = Repository(path)
-        results = repo.search_text(query, file_pattern=pattern)
-        if results:
-            for res in results:
-                typer.echo(f"{res['file']}:{res['line_number']}: {res['line'].strip()}")
-        else:
+        found = False
+        # Print as matches stream in rather than after the whole repository is searched
+        for res in repo.iter_search_text(query, file_pattern=pattern, options=options):
+            found = True
+            for offset, line in enumerate(res["context_before"], start=res["line_number"] - len(res["context_before"])):
+                typer.echo(f"{res['file']}-{offset}- {line}")
+            typer.echo(f"{res['file']}:{res['line_number']}: {res['line'].strip()}")
+            for offset, line in enumerate(res["context_after"], start=res["line_number"] + 1):
+                typer.echo(f"{res['file']}-{offset}- {line}")
+        if not found:
             typer.echo("No results found.")
     except Exception as e:
         typer.secho(f"Error: {e}", fg=typer.colors.RED)
         raise typer.Exit(code=1)
 
 
+def _diagnostic_line(diagnostic: dict) -> str:
+    """Renders a parse diagnostic as ``path:line:column: message``, with 1-based lines and columns when known."""
+    if "line" in diagnostic:
+        return f"{diagnostic['file']}:{diagnostic['line'] + 1}:{diagnostic['column'] + 1}: {diagnostic['error']}"
+    return f"{diagnostic['file']}: {diagnostic['error']}"
+
+
+@app.command()
+def symbols(
+    path: str = typer.Argument(..., help="Path to the local repository, or - to read one file from stdin."),
+    file: str = typer.Option(
+        None, "--file", "-f", help="Only extract symbols from this file (relative to the repository); - reads stdin."
+    ),
+    lang: str = typer.Option(
+        None,
+        "--lang",
+        help="Language of source read from stdin, e.g. go, py or ts; otherwise comma-separated languages to keep.",
+    ),
+    output_format: str = typer.Option(
+        "text", "--format", help="Output format: text, table, names, json, yaml, markdown, tree, lsp, dot or ctags."
+    ),
+    positions: bool = typer.Option(False, "--positions", help="Include column and doc comment positions."),
+    kind: str = typer.Option(None, "--kind", help="Comma-separated symbol kinds to keep, e.g. func,type."),
+    name: str = typer.Option(None, "--name", help="Regular expression the symbol name must match, e.g. '^[A-Z]'."),
+    exported: bool = typer.Option(False, "--exported", help="Only keep exported symbols."),
+    min_complexity: int = typer.Option(
+        None,
+        "--min-complexity",
+        help="Only keep functions and methods of at least this cyclomatic complexity, e.g. 10.",
+    ),
+    include: List[str] = typer.Option(
+        None, "--include", help="Only keep files whose path matches this glob, e.g. 'src/**'. Repeatable."
+    ),
+    exclude: List[str] = typer.Option(
+        None, "--exclude", help="Drop files whose path matches this glob, e.g. 'vendor/**'. Repeatable."
+    ),
+    since: str = typer.Option(
+        None, "--since", help="Only parse files changed since this git ref, e.g. origin/main, plus uncommitted ones."
+    ),
+    tests: bool = typer.Option(
+        False, "--tests/--no-tests", help="Also parse test files such as x_test.go, test_x.py and x.spec.ts."
+    ),
+    vendored: bool = typer.Option(True, "--vendored/--no-vendored", help="Parse code copied under third_party/."),
+    generated: bool = typer.Option(
+        True, "--generated/--no-generated", help="Parse generated files, e.g. x.pb.go or 'Code generated' headers."
+    ),
+    gitignore: bool = typer.Option(
+        True, "--gitignore/--no-gitignore", help="Skip files matched by .gitignore files at any level."
+    ),
+    fail_on_diagnostics: bool = typer.Option(
+        False, "--fail-on-diagnostics", help="Exit with status 1 if any file has a syntax error."
+    ),
+    sort: bool = typer.Option(
+        False, "--sorted", help="Order symbols by kind, receiver and name instead of source position."
+    ),
+):
+    """
+    Extract symbols from a local repository.
+
+    Exits with status 0 when no symbol matches, printing nothing, or for
+    --format json a document with no symbols. Exits with status 1 when a
+    file cannot be read or parsed, and with --fail-on-diagnostics when one
+    has a syntax error; the diagnostics go to stderr either way.
+    """
+    import json
+
+    from codekite import Repository
+    from codekite.export import export_symbols
+    from codekite.formatters import FORMATTERS, format_symbols
+    from codekite.languages import parse_reader
+    from codekite.remote import is_remote_url
+    from codekite.symbol_filter import SymbolFilter, apply_filter
+
+    if output_format not in FORMATTERS:
+        raise typer.BadParameter(f"{output_format!r} is not one of {', '.join(FORMATTERS)}", param_hint="--format")
+
+    repo = None
+    diagnostics: List[dict] = []
+    try:
+        from_stdin = path == "-" or file == "-"
+        if from_stdin:
+            # Editors pipe unsaved buffers in, so there is no filename to infer the language from
+            if not lang:
+                raise ValueError("--lang is required when reading from stdin")
+            symbol_filter = SymbolFilter.from_strings(kind, name, exported, min_complexity=min_complexity)
+            by_file = {"<stdin>": parse_reader(sys.stdin, lang)}
+        else:
+            symbol_filter = SymbolFilter.from_strings(
+                kind, name, exported, include, exclude, lang, min_complexity=min_complexity
+            )
+            if not is_remote_url(path) and not os.path.isdir(path):
+                raise FileNotFoundError(f"No such directory: {path}")
+            repo = Repository(path, respect_gitignore=gitignore)
+            if file and since:
+                raise ValueError("--file and --since cannot be combined")
+            if file:
+                if not os.path.isfile(repo.get_abs_path(file)):
+                    raise FileNotFoundError(f"No such file: {file}")
+                file_symbols, syntax_errors = repo.parse_file(file)
+                by_file = {file: file_symbols}
+                # Half-written files still outline; the errors go to stderr so stdout stays parseable
+                diagnostics = [
+                    {
+                        "file": file,
+                        "error": e["message"],
+                        "line": e["start_line"],
+                        "column": e["start_column"],
+                        "severity": "warning",
+                    }
+                    for e in syntax_errors
+                ]
+            else:
+                # Files git reports unchanged are never parsed, which keeps CI runs on large repos short
+                changed = repo.changed_files(since) if since else None
+                by_file, diagnostics = repo.parse_directory(
+                    changed_files=changed, include_tests=tests, include_vendored=vendored, include_generated=generated
+                )
+            by_file = {p: syms for p, syms in by_file.items() if symbol_filter.matches_path(p)}
+            diagnostics = [d for d in diagnostics if symbol_filter.matches_path(d["file"])]
+        by_file = {p: apply_filter(syms, symbol_filter) for p, syms in by_file.items()}
+        if output_format == "json":
+            document = export_symbols(by_file, repo, sort=sort).to_dict()
+            output = json.dumps(document, indent=2, sort_keys=True, ensure_ascii=False)
+        else:
+            extracted = [s for syms in by_file.values() for s in syms]
+            output = format_symbols(extracted, output_format, positions=positions, sort=sort)
+    except Exception as e:
+        typer.secho(f"Error: {e}", fg=typer.colors.RED, err=True)
+        raise typer.Exit(code=1)
+
+    if output:
+        typer.echo(output)
+    failed = False
+    for d in diagnostics:
+        if d.get("severity") == "error" or (fail_on_diagnostics and d.get("severity") == "warning"):
+            failed = True
+        if d.get("severity") in ("error", "warning"):
+            typer.echo(_diagnostic_line(d), err=True)
+    if failed:
+        raise typer.Exit(code=1)
+
+
+@app.command()
+def diff(
+    old: str = typer.Argument(..., help="Symbols JSON of the earlier version, e.g. from `codekite symbols --format json`."),
+    new: str = typer.Argument(..., help="Symbols JSON of the later version."),
+    output_format: str = typer.Option("text", "--format", help="Output format: text or json."),
+    fail_on_breaking: bool = typer.Option(
+        False, "--fail-on-breaking", help="Exit with status 1 if an exported symbol was removed or changed."
+    ),
+):
+    """Compare two symbol sets and report added, removed and changed symbols."""
+    import json
+
+    from codekite.symbol_diff import diff_symbol_sets, load_symbols
+
+    try:
+        result = diff_symbol_sets(load_symbols(old), load_symbols(new))
+    except Exception as e:
+        typer.secho(f"Error: {e}", fg=typer.colors.RED)
+        raise typer.Exit(code=1)
+
+    if output_format == "json":
+        typer.echo(json.dumps(result.to_dict(), indent=2))
+    else:
+        for change in result.removed + result.changed + result.added:
+            flag = "BREAKING " if change.breaking else ""
+            location = change.symbol.get("file") or "?"
+            typer.echo(f"{flag}{change.change}: {change.symbol.get('type')} {change.name} ({location})")
+            if change.change == "changed":
+                typer.echo(f"    - {change.before}")
+                typer.echo(f"    + {change.after}")
+        summary = f"{len(result.added)} added, {len(result.removed)} removed, {len(result.changed)} changed"
+        typer.echo(f"{summary}; {len(result.breaking)} potentially breaking")
+    if fail_on_breaking and result.breaking:
+        raise typer.Exit(code=1)
+
+@app.command()
+def api_hash(
+    path: str = typer.Argument(..., help="Path to the local repository or directory to fingerprint."),
+    output_format: str = typer.Option("text", "--format", help="Output format: text or json."),
+):
+    """Print a hash of each package's exported names and signatures, to spot API changes."""
+    import json
+
+    from codekite import Repository
+
+    try:
+        hashes = Repository(path).api_hashes()
+    except Exception as e:
+        typer.secho(f"Error: {e}", fg=typer.colors.RED)
+        raise typer.Exit(code=1)
+
+    if output_format == "json":
+        typer.echo(json.dumps(hashes, indent=2))
+        return
+    for package, digest in hashes.items():
+        typer.echo(f"{digest}  {package}")
+
+@app.command()
+def stats(
+    path: str = typer.Argument(..., help="Path to the local repository or directory to count."),
+    output_format: str = typer.Option("text", "--format", help="Output format: text or json."),
+    gitignore: bool = typer.Option(
+        True, "--gitignore/--no-gitignore", help="Skip files matched by .gitignore files at any level."
+    ),
+):
+    """Count code, comment and blank lines, rolled up per directory."""
+    import json
+
+    from codekite import Repository
+    from codekite.line_counts import directory_line_counts
+
+    try:
+        files = Repository(path, respect_gitignore=gitignore).line_counts()
+    except Exception as e:
+        typer.secho(f"Error: {e}", fg=typer.colors.RED)
+        raise typer.Exit(code=1)
+
+    directories = directory_line_counts(files)
+    if output_format == "json":
+        document = {
+            "files": {p: c.to_dict() for p, c in files.items()},
+            "directories": {d: c.to_dict() for d, c in directories.items()},
+        }
+        typer.echo(json.dumps(document, indent=2))
+        return
+    width = max([len("directory")] + [len(d) for d in directories])
+    typer.echo(f"{'directory':<{width}}  {'files':>6}  {'code':>8}  {'comment':>8}  {'blank':>8}  {'total':>8}")
+    for directory, c in directories.items():
+        typer.echo(f"{directory:<{width}}  {c.files:>6}  {c.code:>8}  {c.comment:>8}  {c.blank:>8}  {c.total:>8}")
+
+@app.command()
+def todos(
+    path: str = typer.Argument(..., help="Path to the local repository or directory to scan."),
+    markers: str = typer.Option(
+        "TODO,FIXME,XXX", "--markers", help="Comma-separated markers that start a comment line, e.g. TODO,HACK."
+    ),
+    output_format: str = typer.Option("text", "--format", help="Output format: text or json."),
+    gitignore: bool = typer.Option(
+        True, "--gitignore/--no-gitignore", help="Skip files matched by .gitignore files at any level."
+    ),
+):
+    """List TODO, FIXME and XXX comments with the symbol each one is in."""
+    import json
+
+    from codekite import Repository
+
+    try:
+        marker_list = [m.strip() for m in markers.split(",") if m.strip()]
+        found = Repository(path, respect_gitignore=gitignore).todos(markers=marker_list)
+    except Exception as e:
+        typer.secho(f"Error: {e}", fg=typer.colors.RED)
+        raise typer.Exit(code=1)
+
+    if output_format == "json":
+        typer.echo(json.dumps([c.to_dict() for c in found], indent=2))
+        return
+    for c in found:
+        author = f"({c.author})" if c.author else ""
+        where = f"  [{c.symbol}]" if c.symbol else ""
+        typer.echo(f"{c.file}:{c.line + 1}: {c.marker}{author}: {c.text}{where}")
+
+@app.command()
+def watch(
+    path: str = typer.Argument(..., help="Path to the local repository or directory to watch."),
+    debounce: int = typer.Option(200, "--debounce", help="Milliseconds a burst of saves must settle before re-parsing."),
+    output_format: str = typer.Option("text", "--format", help="Output format: text, json, yaml, markdown, tree or lsp."),
+    diff: bool = typer.Option(False, "--diff", help="Print only the symbols each change added, removed or modified."),
+    sort: bool = typer.Option(
+        False, "--sorted", help="Order symbols by kind, receiver and name instead of source position."
+    ),
+    gitignore: bool = typer.Option(
+        True, "--gitignore/--no-gitignore", help="Skip files matched by .gitignore files at any level."
+    ),
+):
+    """Print symbols, then re-print those of each file as it changes, until interrupted."""
+    import json
+
+    from codekite import Repository
+    from codekite.formatters import format_symbols
+    from codekite.watcher import diff_symbols
+
+    try:
+        repo = Repository(path, respect_gitignore=gitignore)
+        watcher = repo.get_watcher(debounce=debounce / 1000)
+        watcher.start()
+    except Exception as e:
+        typer.secho(f"Error: {e}", fg=typer.colors.RED)
+        raise typer.Exit(code=1)
+
+    previous = dict(watcher.index.symbols)
+    typer.echo(format_symbols([s for syms in previous.values() for s in syms], output_format, sort=sort))
+    try:
+        while True:
+            event = watcher.events.get()
+            if not diff:
+                typer.echo(f"== {event.op}: {event.path}")
+                if event.symbols:
+                    typer.echo(format_symbols(event.symbols, output_format, sort=sort))
+            else:
+                changes = diff_symbols(previous.get(event.path, []), event.symbols)
+                if output_format == "json":
+                    typer.echo(json.dumps({"path": event.path, "op": event.op, **changes}, sort_keys=True))
+                else:
+                    for mark, op in (("+", "added"), ("-", "removed"), ("~", "changed")):
+                        for s in changes[op]:
+                            label = f"{s['type']} {s.get('node_path') or s['name']}"
+                            typer.echo(f"{mark} {label} ({event.path}:{s['start_line'] + 1})")
+            if event.op == "removed":
+                previous.pop(event.path, None)
+            else:
+                previous[event.path] = event.symbols
+    except KeyboardInterrupt:
+        pass
+    finally:
+        watcher.stop()
+
+@app.command()
+def mcp(
+    repo: str = typer.Option(".", "--repo", help="Path to the repository the tools operate on."),
+    max_result_chars: int = typer.Option(
+        100_000, "--max-result-chars", min=1, help="Truncate tool results longer than this many characters of JSON."
+    ),
+):
+    """Serve the repository to AI assistants over the Model Context Protocol on stdin and stdout."""
+    import logging
+    import sys
+
+    from codekite import Repository
+    from codekite.mcp_server import MCPServer
+
+    # stdout carries protocol messages only
+    logging.basicConfig(stream=sys.stderr, level=logging.WARNING)
+    try:
+        server = MCPServer(Repository(repo), max_result_chars=max_result_chars)
+    except Exception as e:
+        typer.echo(f"Error: {e}", err=True)
+        raise typer.Exit(code=1)
+    server.serve(sys.stdin, sys.stdout)
+
 if __name__ == "__main__":
     app()
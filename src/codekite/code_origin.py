@@ -0,0 +1,64 @@
+"""Tells vendored and generated files from first-party code, so search results are not flooded by them."""
+
+from __future__ import annotations
+import re
+from pathlib import Path
+from typing import Dict, Optional
+
+# Directories holding copies of other projects' code, at any depth
+VENDORED_DIRS = frozenset({"vendor", "third_party", "node_modules"})
+
+# How far into a file a generated-code marker is looked for; far enough to pass a license header
+GENERATED_HEADER_LINES = 40
+
+# Output of common generators, by file name: protoc, grpc-gateway, stringer and minifiers
+_GENERATED_NAME = re.compile(r"(\.pb\.go|\.pb\.gw\.go|_string\.go|_pb2\.py|_pb2_grpc\.py|\.min\.js|\.min\.css)$")
+_COMMENT = re.compile(r"^\s*(?://|#|--|/\*|\*|<!--)")
+# Go's convention (https://go.dev/s/generatedcode), which most generators follow, and Facebook's @generated
+_MARKER = re.compile(r"\bCode generated\b.*\bDO NOT EDIT\b|@generated\b")
+
+
+def is_vendored(path: str) -> bool:
+    """True for a repository-relative *path* under a ``vendor``, ``third_party`` or ``node_modules`` directory."""
+    return any(part in VENDORED_DIRS for part in path.replace("\\", "/").split("/")[:-1])
+
+
+def has_generated_header(source: str) -> bool:
+    """
+    True if a comment in the first :data:`GENERATED_HEADER_LINES` lines of *source* marks it generated.
+
+    The marker is ``Code generated ... DO NOT EDIT`` or ``@generated``. Later
+    lines are not looked at, so a file that merely mentions the phrase, such
+    as a generator's own source, is not mistaken for its output.
+    """
+    for line in source.split("\n", GENERATED_HEADER_LINES)[:GENERATED_HEADER_LINES]:
+        if _COMMENT.match(line) and _MARKER.search(line):
+            return True
+    return False
+
+
+def is_generated(path: str, source: Optional[str] = None) -> bool:
+    """
+    True if *path* is a generator's output by name (``x.pb.go``, ``color_string.go``,
+    ``app.min.js``), or *source*, when given, has a generated-code header.
+    """
+    return bool(_GENERATED_NAME.search(path)) or (source is not None and has_generated_header(source))
+
+
+def read_header(file: Path) -> str:
+    """The first :data:`GENERATED_HEADER_LINES` lines of *file*, or ``""`` if it cannot be read."""
+    try:
+        with open(file, encoding="utf-8", errors="replace") as f:
+            return "".join(line for _, line in zip(range(GENERATED_HEADER_LINES), f))
+    except OSError:
+        return ""
+
+
+def origin_fields(path: str, source: Optional[str] = None) -> Dict[str, bool]:
+    """``{"vendored": True}`` and ``{"generated": True}`` as they apply to *path*; first-party code gets neither."""
+    fields: Dict[str, bool] = {}
+    if is_vendored(path):
+        fields["vendored"] = True
+    if is_generated(path, source):
+        fields["generated"] = True
+    return fields
@@ -1,9 +1,16 @@
 from __future__ import annotations
+import logging
+import os
 import re
-from pathlib import Path
-from typing import Any, List, Dict, Optional
+from pathlib import Path, PurePosixPath
+from typing import Any, Callable, Iterator, List, Dict, Optional
 from dataclasses import dataclass, field
-import pathspec  # Added for .gitignore handling
+
+from .code_origin import GENERATED_HEADER_LINES, VENDORED_DIRS, is_generated
+from .file_limits import BINARY_SNIFF_BYTES
+from .ignore import IgnoreRules
+
+logger = logging.getLogger(__name__)
 
 
 @dataclass
@@ -14,9 +21,20 @@ class SearchOptions:
     context_lines_before: int = 0
     context_lines_after: int = 0
     use_gitignore: bool = True  # New option for gitignore
+    # Stop after this many matches in total / in any one file (None = unlimited)
+    max_results: Optional[int] = None
+    max_matches_per_file: Optional[int] = None
+    # Also search vendor/, third_party/ and node_modules/, and generated files; see codekite.code_origin
+    include_vendored: bool = False
+    include_generated: bool = False
     # Future options: whole_word: bool = False, exclude_patterns: List[str] = field(default_factory=list)
 
 
+def _is_binary(file: Path) -> bool:
+    with open(file, "rb") as f:
+        return b"\0" in f.read(BINARY_SNIFF_BYTES)
+
+
 class CodeSearcher:
     """
     Provides text and regex search across the repository.
@@ -31,46 +49,114 @@ class CodeSearcher:
         repo_path (str): The path to the repository.
         """
         self.repo_path: Path = Path(repo_path)
-        self._gitignore_spec = self._load_gitignore()  # Load gitignore spec
+        self._ignore_rules = IgnoreRules(self.repo_path)
+        # Still skips .git and .codekiteignore matches when gitignore handling is off
+        self._unfiltered_rules = IgnoreRules(self.repo_path, respect_gitignore=False)
+
+    def _should_ignore(self, file: Path, use_gitignore: bool = True, is_dir: Optional[bool] = None) -> bool:
+        """Checks if a path should be ignored based on .gitignore and .codekiteignore rules."""
+        rules = self._ignore_rules if use_gitignore else self._unfiltered_rules
+        return rules.is_ignored(file, is_dir)
+
+    def _candidate_files(self, file_pattern: str, use_gitignore: bool, include_vendored: bool = True) -> Iterator[Path]:
+        """Yields files whose repository-relative path matches *file_pattern*, in sorted walk order."""
+        for dirpath, dirnames, filenames in os.walk(self.repo_path):
+            current = Path(dirpath)
+            dirnames[:] = sorted(
+                d
+                for d in dirnames
+                if not self._should_ignore(current / d, use_gitignore, is_dir=True)
+                and (include_vendored or d not in VENDORED_DIRS)
+            )
+            for name in sorted(filenames):
+                file = current / name
+                # Like rglob, a pattern matches against the end of the path: "*.py" or "pkg/*.go"
+                if not PurePosixPath(file.relative_to(self.repo_path).as_posix()).match(file_pattern):
+                    continue
+                if not self._should_ignore(file, use_gitignore, is_dir=False):
+                    yield file
+
+    def iter_search_text(
+        self, query: str, file_pattern: str = "*.py", options: Optional[SearchOptions] = None
+    ) -> Iterator[Dict[str, Any]]:
+        """
+        Lazily searches for a regex, yielding matches as they are found.
+
+        Takes the same arguments and yields the same match dicts as
+        :meth:`search_text`. Files are read only as the iterator advances, so
+        taking the first match of a huge repository stops after one hit.
+        """
+        current_options = options or SearchOptions()  # Use defaults if none provided
+        regex_flags = 0 if current_options.case_sensitive else re.IGNORECASE
+        regex = re.compile(query, regex_flags)
 
-    def _load_gitignore(self):
-        """Loads .gitignore rules from the repository root."""
-        gitignore_path = self.repo_path / ".gitignore"
-        if gitignore_path.exists():
+        total = 0
+        files = self._candidate_files(file_pattern, current_options.use_gitignore, current_options.include_vendored)
+        for file in files:
+            rel_path = file.relative_to(self.repo_path).as_posix()
+            # Checked by name first, so a minified bundle is never read
+            if not current_options.include_generated and is_generated(rel_path):
+                continue
             try:
-                with open(gitignore_path, "r", encoding="utf-8") as f:
-                    return pathspec.PathSpec.from_lines("gitwildmatch", f)
-            except Exception as e:
-                # Log this error if logging is set up, or print
-                print(f"Warning: Could not load .gitignore: {e}")
-        return None
-
-    def _should_ignore(self, file: Path) -> bool:
-        """Checks if a file should be ignored based on .gitignore rules."""
-        if not self._gitignore_spec:
-            return False
-
-        # Always ignore .git directory contents directly if pathspec doesn't catch it implicitly
-        # (though pathspec usually handles .git/ if specified in .gitignore)
-        if ".git" in file.parts:
-            return True
-
-        try:
-            rel_path = str(file.relative_to(self.repo_path))
-            return self._gitignore_spec.match_file(rel_path)
-        except ValueError:  # file might not be relative to repo_path, e.g. symlink target outside
-            return False  # Or decide to ignore such cases explicitly
+                if _is_binary(file):
+                    continue
+                with open(file, "r", encoding="utf-8", errors="ignore") as f:
+                    lines = f.readlines()  # Read all lines to handle context
+            except OSError as e:
+                logger.warning(f"Error searching file {file}: {e}")
+                continue
+            header = "".join(lines[:GENERATED_HEADER_LINES])
+            if not current_options.include_generated and is_generated(rel_path, header):
+                continue
+
+            in_file = 0
+            for i, line_content in enumerate(lines):
+                if not regex.search(line_content):
+                    continue
+                start_context_before = max(0, i - current_options.context_lines_before)
+                context_before = [l.rstrip("\n") for l in lines[start_context_before:i]]
+
+                # Context after should not include the matching line itself
+                start_context_after = i + 1
+                end_context_after = start_context_after + current_options.context_lines_after
+                context_after = [l.rstrip("\n") for l in lines[start_context_after:end_context_after]]
+
+                yield {
+                    "file": str(file.relative_to(self.repo_path)),
+                    "line_number": i + 1,  # 1-indexed
+                    "line": line_content.rstrip("\n"),
+                    "context_before": context_before,
+                    "context_after": context_after,
+                }
+                total += 1
+                in_file += 1
+                if current_options.max_results is not None and total >= current_options.max_results:
+                    return
+                # One generated file should not flood the results
+                if current_options.max_matches_per_file is not None and in_file >= current_options.max_matches_per_file:
+                    break
 
     def search_text(
-        self, query: str, file_pattern: str = "*.py", options: Optional[SearchOptions] = None
+        self,
+        query: str,
+        file_pattern: str = "*.py",
+        options: Optional[SearchOptions] = None,
+        callback: Optional[Callable[[Dict[str, Any]], None]] = None,
     ) -> List[Dict[str, Any]]:
         """
         Search for a text pattern (regex) in files matching file_pattern.
 
+        Binary files (a NUL byte in the first 8 KB) are skipped, as are paths
+        excluded by .gitignore files (unless ``options.use_gitignore`` is off),
+        .codekiteignore files and ``.git``. Vendored and generated files are
+        skipped too unless ``options.include_vendored`` or
+        ``options.include_generated`` is set; see :mod:`codekite.code_origin`.
+
         Args:
             query (str): The text pattern to search for.
             file_pattern (str): The file pattern to search in. Defaults to "*.py".
             options (Optional[SearchOptions]): Search configuration options.
+            callback (Optional[Callable]): Called with each match as soon as it is found.
 
         Returns:
             List[Dict[str, Any]]: A list of matches. Each match includes:
@@ -81,41 +167,8 @@ class CodeSearcher:
                 - "context_after" (List[str]): Lines immediately succeeding the match.
         """
         matches: List[Dict[str, Any]] = []
-        current_options = options or SearchOptions()  # Use defaults if none provided
-
-        regex_flags = 0 if current_options.case_sensitive else re.IGNORECASE
-        regex = re.compile(query, regex_flags)
-
-        for file in self.repo_path.rglob(file_pattern):
-            if current_options.use_gitignore and self._should_ignore(file):
-                continue
-            if not file.is_file():
-                continue
-            try:
-                with open(file, "r", encoding="utf-8", errors="ignore") as f:
-                    lines = f.readlines()  # Read all lines to handle context
-
-                for i, line_content in enumerate(lines):
-                    if regex.search(line_content):
-                        start_context_before = max(0, i - current_options.context_lines_before)
-                        context_before = [l.rstrip("\n") for l in lines[start_context_before:i]]
-
-                        # Context after should not include the matching line itself
-                        start_context_after = i + 1
-                        end_context_after = start_context_after + current_options.context_lines_after
-                        context_after = [l.rstrip("\n") for l in lines[start_context_after:end_context_after]]
-
-                        matches.append(
-                            {
-                                "file": str(file.relative_to(self.repo_path)),
-                                "line_number": i + 1,  # 1-indexed
-                                "line": line_content.rstrip("\n"),
-                                "context_before": context_before,
-                                "context_after": context_after,
-                            }
-                        )
-            except Exception as e:
-                # Log the exception for debugging purposes
-                print(f"Error searching file {file}: {e}")
-                continue
+        for match in self.iter_search_text(query, file_pattern, options):
+            if callback is not None:
+                callback(match)
+            matches.append(match)
         return matches
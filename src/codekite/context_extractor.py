@@ -1,47 +1,257 @@
 from __future__ import annotations
+from dataclasses import dataclass, field
 from pathlib import Path
-from typing import Any, Dict, List, Optional
+from typing import Any, Dict, List, Optional, Tuple
 import ast
+from . import languages
+from .encodings import read_text
+from .tokenizers import HeuristicTokenizer, Tokenizer
 from .tree_sitter_symbol_extractor import TreeSitterSymbolExtractor
 
 
+@dataclass(frozen=True)
+class Chunk:
+    """
+    A contiguous piece of a source file, cut at symbol boundaries by :meth:`ContextExtractor.chunk_file`
+    or to a token budget by :meth:`ContextExtractor.chunk_file_by_tokens`.
+
+    Attributes:
+        code: The text of lines ``start_line`` to ``end_line``.
+        start_line: First line, 0-based like symbol positions.
+        end_line: Last line, inclusive.
+        symbols: Qualified names of the top-level symbols in the chunk; empty for the preamble.
+        kind: ``"preamble"`` for the package clause, imports and file comments,
+            ``"symbol"`` for a chunk holding declarations, ``"lines"`` for
+            token-budget chunks.
+        continuation: The chunk continues a symbol split at ``max_lines``, or
+            starts partway through a line too long for the token budget.
+    """
+
+    code: str
+    start_line: int
+    end_line: int
+    symbols: List[str] = field(default_factory=list)
+    kind: str = "symbol"
+    continuation: bool = False
+
+
 class ContextExtractor:
     """
     Extracts context from source code files for chunking, search, and LLM workflows.
     Supports chunking by lines, symbols, and function/class scope.
     """
 
-    def __init__(self, repo_path: str) -> None:
+    def __init__(self, repo_path: str, fallback_encoding: Optional[str] = None) -> None:
         self.repo_path: Path = Path(repo_path)
+        self.fallback_encoding = fallback_encoding
+
+    def _read(self, path: Path) -> str:
+        # Decoded like RepoMapper does, so chunk lines match extracted symbols
+        return read_text(path, self.fallback_encoding)
 
     def chunk_file_by_lines(self, file_path: str, max_lines: int = 50) -> List[str]:
         """
         Chunk file into blocks of at most max_lines lines.
         """
         chunks: List[str] = []
-        with open(self.repo_path / file_path, "r", encoding="utf-8", errors="ignore") as f:
-            lines: List[str] = []
-            for i, line in enumerate(f, 1):
-                lines.append(line)
-                if i % max_lines == 0:
-                    chunks.append("".join(lines))
-                    lines = []
-            if lines:
+        lines: List[str] = []
+        for i, line in enumerate(self._read(self.repo_path / file_path).splitlines(keepends=True), 1):
+            lines.append(line)
+            if i % max_lines == 0:
                 chunks.append("".join(lines))
+                lines = []
+        if lines:
+            chunks.append("".join(lines))
         return chunks
 
     def chunk_file_by_symbols(self, file_path: str) -> List[Dict[str, Any]]:
         ext = Path(file_path).suffix.lower()
         abs_path = self.repo_path / file_path
         try:
-            with open(abs_path, "r", encoding="utf-8", errors="ignore") as f:
-                code = f.read()
+            code = self._read(abs_path)
         except Exception:
             return []
         if ext in TreeSitterSymbolExtractor.LANGUAGES:
             return TreeSitterSymbolExtractor.extract_symbols(ext, code)
         return []
 
+    def chunk_file(self, file_path: str, max_lines: Optional[int] = None) -> List[Chunk]:
+        """
+        Cuts a file into chunks at top-level symbol boundaries, e.g. for embedding.
+
+        Each top-level symbol, with its doc comment, is one chunk; nested symbols
+        such as Python methods stay inside their class's chunk. Everything before
+        the first symbol is a ``"preamble"`` chunk, and lines between two symbols
+        (such as a comment that is not a doc comment) start the following chunk.
+        Blank lines at the edges of a chunk are left out. Symbols sharing a line
+        share a chunk.
+
+        Args:
+            file_path: Path relative to the repository root.
+            max_lines: Split chunks longer than this into pieces of at most this
+                many lines; pieces after the first are marked as continuations.
+
+        Returns:
+            Chunks in file order; empty for unreadable or empty files. A file
+            without symbols is a single preamble chunk.
+        """
+        abs_path = self.repo_path / file_path
+        try:
+            code = self._read(abs_path)
+        except OSError:
+            return []
+        lines = code.splitlines(keepends=True)
+        symbols = languages.extract_symbols(abs_path.suffix.lower(), file_path, code)
+
+        def span(symbol: Dict[str, Any]) -> Tuple[int, int]:
+            return symbol.get("doc_start_line", symbol["start_line"]), symbol["end_line"]
+
+        # Top-level symbols are the ones no other symbol's span contains
+        spans = sorted((span(s), s.get("node_path") or s["name"]) for s in symbols)
+        groups: List[Tuple[int, int, List[str]]] = []
+        for (start, end), name in spans:
+            if groups and start <= groups[-1][1]:
+                if end > groups[-1][1]:
+                    # Overlapping without nesting, e.g. two declarations on one line
+                    groups[-1] = (groups[-1][0], end, groups[-1][2] + [name])
+                elif start == groups[-1][0] and end == groups[-1][1]:
+                    groups[-1][2].append(name)
+                continue
+            groups.append((start, end, [name]))
+
+        def blank(i: int) -> bool:
+            return not lines[i].strip()
+
+        chunks: List[Chunk] = []
+
+        def add(start: int, end: int, names: List[str], kind: str) -> None:
+            while start <= end and blank(start):
+                start += 1
+            while end >= start and blank(end):
+                end -= 1
+            if start > end:
+                return
+            step = max_lines if max_lines and max_lines > 0 else end - start + 1
+            for piece in range(start, end + 1, step):
+                piece_end = min(piece + step - 1, end)
+                chunks.append(
+                    Chunk("".join(lines[piece : piece_end + 1]), piece, piece_end, list(names), kind, piece > start)
+                )
+
+        next_line = 0
+        if groups:
+            add(0, groups[0][0] - 1, [], "preamble")
+        for index, (start, end, names) in enumerate(groups):
+            # Trailing lines after the last symbol have no following chunk to join
+            last = len(lines) - 1 if index == len(groups) - 1 else end
+            add(next_line if index else start, last, names, "symbol")
+            next_line = end + 1
+        if not groups:
+            add(0, len(lines) - 1, [], "preamble")
+        return chunks
+
+    def chunk_file_by_tokens(
+        self, file_path: str, max_tokens: int = 512, overlap: int = 0, tokenizer: Optional[Tokenizer] = None
+    ) -> List[Chunk]:
+        """
+        Cuts a file into runs of whole lines that each fit a token budget.
+
+        A line longer than the budget on its own is hard-split into pieces that
+        fit; pieces after the first are marked as continuations. Consecutive
+        chunks repeat up to *overlap* lines, but never a whole chunk: each chunk
+        adds at least one line (or piece) the previous one did not have, and the
+        overlap is dropped when it would leave no room for new content.
+
+        Args:
+            file_path: Path relative to the repository root.
+            max_tokens: Most tokens a chunk may hold, as *tokenizer* counts them.
+            overlap: Lines of the previous chunk to repeat at the start of the next.
+            tokenizer: Counts tokens; defaults to :class:`~codekite.tokenizers.HeuristicTokenizer`.
+                Pass a :class:`~codekite.tokenizers.TiktokenTokenizer` for exact counts.
+
+        Returns:
+            Chunks of kind ``"lines"`` in file order; empty for unreadable or empty files.
+
+        Raises:
+            ValueError: If *max_tokens* is not positive or *overlap* is negative.
+        """
+        if max_tokens <= 0:
+            raise ValueError("max_tokens must be positive")
+        if overlap < 0:
+            raise ValueError("overlap must not be negative")
+        try:
+            code = self._read(self.repo_path / file_path)
+        except OSError:
+            return []
+        return self.chunk_text_by_tokens(code, max_tokens, overlap, tokenizer)
+
+    @classmethod
+    def chunk_text_by_tokens(
+        cls, code: str, max_tokens: int = 512, overlap: int = 0, tokenizer: Optional[Tokenizer] = None
+    ) -> List[Chunk]:
+        """:meth:`chunk_file_by_tokens` for text already in memory, with lines numbered from 0."""
+        if max_tokens <= 0:
+            raise ValueError("max_tokens must be positive")
+        if overlap < 0:
+            raise ValueError("overlap must not be negative")
+        tokenizer = tokenizer or HeuristicTokenizer()
+        # (line, text, is_first_piece_of_line), each within the budget on its own
+        pieces: List[Tuple[int, str, bool]] = []
+        for number, line in enumerate(code.splitlines(keepends=True)):
+            first = True
+            while tokenizer.count(line) > max_tokens:
+                cut = cls._fitting_prefix(line, max_tokens, tokenizer)
+                pieces.append((number, line[:cut], first))
+                line, first = line[cut:], False
+            pieces.append((number, line, first))
+        counts = [tokenizer.count(text) for _, text, _ in pieces]
+
+        def fits(start: int, end: int) -> bool:
+            # Summed counts are cheap but tokenizers need not be additive; the joined text decides
+            if sum(counts[start : end + 1]) > max_tokens:
+                return False
+            return tokenizer.count("".join(p[1] for p in pieces[start : end + 1])) <= max_tokens
+
+        chunks: List[Chunk] = []
+        start = 0
+        while start < len(pieces):
+            end = start
+            while end + 1 < len(pieces) and fits(start, end + 1):
+                end += 1
+            first_line, last_line = pieces[start][0], pieces[end][0]
+            chunks.append(
+                Chunk(
+                    "".join(p[1] for p in pieces[start : end + 1]),
+                    first_line,
+                    last_line,
+                    kind="lines",
+                    continuation=not pieces[start][2],
+                )
+            )
+            if end + 1 >= len(pieces):
+                break
+            next_start = end + 1
+            if overlap:
+                # Back up to the first piece of the earliest overlapping line, staying past this chunk's start
+                overlap_line = max(last_line - overlap + 1, first_line + 1)
+                candidate = next(i for i in range(start, end + 2) if i > end or pieces[i][0] >= overlap_line)
+                if candidate <= end and fits(candidate, end + 1):
+                    next_start = candidate
+            start = next_start
+        return chunks
+
+    @staticmethod
+    def _fitting_prefix(text: str, max_tokens: int, tokenizer: Tokenizer) -> int:
+        """Length of the longest prefix of *text* within *max_tokens*, at least one character."""
+        low, high = 1, len(text)
+        while low < high:
+            middle = (low + high + 1) // 2
+            if tokenizer.count(text[:middle]) <= max_tokens:
+                low = middle
+            else:
+                high = middle - 1
+        return low
+
     def extract_context_around_line(self, file_path: str, line: int) -> Optional[Dict[str, Any]]:
         """
         Extracts the function/class (or code block) containing the given line.
@@ -50,9 +260,8 @@ class ContextExtractor:
         ext = Path(file_path).suffix.lower()
         abs_path = self.repo_path / file_path
         try:
-            with open(abs_path, "r", encoding="utf-8", errors="ignore") as f:
-                all_lines = f.readlines()
-            code = "".join(all_lines)
+            code = self._read(abs_path)
+            all_lines = code.splitlines(keepends=True)
         except Exception:
             return None
         if ext == ".py":
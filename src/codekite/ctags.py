@@ -0,0 +1,123 @@
+"""Writing symbols as a ``tags`` file in the extended format of Exuberant and Universal Ctags.
+
+Editors such as vim and emacs, and tools built for ctags, look definitions up
+in it. Each tag is one line of tab-separated fields::
+
+    Greet  app/user.go  16;"  kind:method  line:16  language:Go  struct:User
+
+The address is the 1-based line number rather than a search pattern, so edits
+elsewhere in a file don't stop a jump from landing. Lines are sorted bytewise
+after the ``!_TAG_`` header, as ``!_TAG_FILE_SORTED 1`` promises, which lets
+readers binary search. Backslashes, tabs and line breaks in names, paths and
+field values are escaped as Universal Ctags' ``u-ctags`` output mode does.
+"""
+
+from __future__ import annotations
+import os
+from typing import TYPE_CHECKING, Any, Dict, Iterable, List, Sequence, TextIO
+
+from . import languages
+
+if TYPE_CHECKING:
+    from .repository import Repository
+
+PROGRAM_NAME = "codekite"
+PROGRAM_URL = "https://github.com/shaneholloman/codekite"
+
+HEADER = (
+    ("!_TAG_FILE_FORMAT", "2", "extended format; --format=1 will not append ;\" to lines"),
+    ("!_TAG_FILE_SORTED", "1", "0=unsorted, 1=sorted, 2=foldcase"),
+    ("!_TAG_OUTPUT_EXCMD", "number", "number, pattern, mixed, or combineV2"),
+    ("!_TAG_OUTPUT_MODE", "u-ctags", "u-ctags or e-ctags"),
+    ("!_TAG_PROGRAM_NAME", PROGRAM_NAME, ""),
+    ("!_TAG_PROGRAM_URL", PROGRAM_URL, ""),
+)
+
+# The names Universal Ctags gives languages in its language: field
+_CTAGS_LANGUAGES = {
+    "bash": "Sh",
+    "c": "C",
+    "cpp": "C++",
+    "go": "Go",
+    "hcl": "HCL",
+    "java": "Java",
+    "javascript": "JavaScript",
+    "markdown": "Markdown",
+    "python": "Python",
+    "ruby": "Ruby",
+    "rust": "Rust",
+    "sql": "SQL",
+    "tsx": "TypeScript",
+    "typescript": "TypeScript",
+}
+
+# Scope kind of a parent the file doesn't define, such as a Go receiver type declared in another file
+_UNKNOWN_SCOPE_KIND = "type"
+
+_ESCAPES = str.maketrans({"\\": "\\\\", "\t": "\\t", "\n": "\\n", "\r": "\\r"})
+
+
+def escape(value: str) -> str:
+    """Escapes backslash, tab, newline and carriage return in a tag field, e.g. ``a\\tb`` for ``a<TAB>b``."""
+    return value.translate(_ESCAPES)
+
+
+def _language(path: str) -> str:
+    language = languages.language_for(os.path.splitext(path)[1]) or ""
+    return _CTAGS_LANGUAGES.get(language, language)
+
+
+def _tag_line(path: str, symbol: Dict[str, Any], scope_kinds: Dict[str, str]) -> str:
+    line = int(symbol.get("start_line", 0)) + 1
+    fields = [f"kind:{symbol.get('type') or 'unknown'}", f"line:{line}"]
+    language = _language(path)
+    if language:
+        fields.append(f"language:{language}")
+    parent = symbol.get("parent")
+    if parent:
+        fields.append(f"{scope_kinds.get(parent, _UNKNOWN_SCOPE_KIND)}:{parent}")
+    columns = [escape(symbol["name"]), escape(path), f'{line};"'] + [escape(field) for field in fields]
+    return "\t".join(columns)
+
+
+def tag_lines(symbols: Iterable[Dict[str, Any]]) -> List[str]:
+    """
+    The sorted tag lines of *symbols*, without the header.
+
+    Symbols are grouped by their ``file``. A symbol's scope names its
+    ``parent`` prefixed with the kind the file gives that parent, as in
+    ``struct:User``. Symbols with the same name in different files each get
+    a line; anonymous ones, such as Go function literals, get none.
+    """
+    by_file: Dict[str, List[Dict[str, Any]]] = {}
+    for symbol in symbols:
+        if symbol.get("name") and not symbol.get("anonymous"):
+            path = str(symbol.get("file") or ".").replace(os.sep, "/")
+            by_file.setdefault(path, []).append(symbol)
+
+    lines = set()
+    for path, file_symbols in by_file.items():
+        scope_kinds = {s.get("node_path") or s["name"]: str(s.get("type") or "") for s in file_symbols}
+        lines.update(_tag_line(path, s, scope_kinds) for s in file_symbols)
+    # Bytewise, as readers binary search with strcmp
+    return sorted(lines, key=lambda line: line.encode("utf-8"))
+
+
+def header_lines() -> List[str]:
+    """The ``!_TAG_`` pseudo-tags that open a tags file."""
+    return [f"{name}\t{value}\t/{comment}/" for name, value, comment in HEADER]
+
+
+def render_tags(symbols: Sequence[Dict[str, Any]]) -> str:
+    """The complete tags file for *symbols*: the header, then :func:`tag_lines`. Paths are the symbols' ``file``."""
+    return "".join(line + "\n" for line in header_lines() + tag_lines(symbols))
+
+
+def write_tags(repo: "Repository", fp: TextIO) -> None:
+    """
+    Writes the tags file of *repo*, test files included, to *fp*.
+
+    Paths are relative to the repository root, where the file belongs.
+    """
+    by_file, _ = repo.parse_directory(include_tests=True)
+    fp.write(render_tags([s for file_symbols in by_file.values() for s in file_symbols]))
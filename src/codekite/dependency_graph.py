@@ -0,0 +1,361 @@
+"""File and package dependency graphs built from Go, Python and TypeScript/JavaScript imports."""
+
+from __future__ import annotations
+import ast
+import json
+import logging
+import os
+import posixpath
+from collections import deque
+from typing import TYPE_CHECKING, Any, Dict, List, Optional, Set
+
+from .import_graph import (
+    dependency_layers,
+    extract_go_imports,
+    go_module_path,
+    strongly_connected_components,
+    topological_groups,
+)
+from .tree_sitter_symbol_extractor import TreeSitterSymbolExtractor, _node_text
+
+if TYPE_CHECKING:
+    from .repository import Repository
+
+logger = logging.getLogger(__name__)
+
+EDGE_INTERNAL = "internal"
+EDGE_EXTERNAL = "external"
+
+_JS_EXTENSIONS = (".ts", ".tsx", ".js", ".jsx", ".mjs", ".cjs")
+
+
+def _python_imports(source: str) -> List[Dict[str, Any]]:
+    """Returns ``{"module", "names", "level", "line"}`` per import; ``level`` counts the dots of a relative import."""
+    imports = []
+    for node in ast.walk(ast.parse(source)):
+        if isinstance(node, ast.Import):
+            for alias in node.names:
+                imports.append({"module": alias.name, "names": [], "level": 0, "line": node.lineno - 1})
+        elif isinstance(node, ast.ImportFrom):
+            imports.append(
+                {
+                    "module": node.module or "",
+                    "names": [a.name for a in node.names],
+                    "level": node.level,
+                    "line": node.lineno - 1,
+                }
+            )
+    return sorted(imports, key=lambda i: i["line"])
+
+
+def _js_imports(ext: str, source: str) -> List[Dict[str, Any]]:
+    """Returns ``{"module", "line"}`` for import/export ... from, require() and dynamic import() calls."""
+    parser = TreeSitterSymbolExtractor.get_parser(ext)
+    root = parser.parse(source.encode("utf-8")).root_node
+    imports = []
+    stack = [root]
+    while stack:
+        node = stack.pop()
+        stack.extend(reversed(node.named_children))
+        source_node = None
+        if node.type in ("import_statement", "export_statement"):
+            source_node = node.child_by_field_name("source")
+        elif node.type == "call_expression":
+            function = node.child_by_field_name("function")
+            arguments = node.child_by_field_name("arguments")
+            if function is not None and _node_text(function) in ("require", "import") and arguments is not None:
+                strings = [a for a in arguments.named_children if a.type == "string"]
+                source_node = strings[0] if len(arguments.named_children) == 1 and strings else None
+        if source_node is not None:
+            imports.append({"module": _node_text(source_node).strip("'\"`"), "line": node.start_point[0]})
+    return sorted(imports, key=lambda i: i["line"])
+
+
+class DependencyGraph:
+    """
+    Directed import graph between the files of a repository, and between their packages.
+
+    A file's package is its directory (``"."`` for the root). Imports that
+    resolve to a file in the repository are internal edges; anything else is
+    an external edge to the imported name: a Go import path, a Python
+    top-level module or an npm package name. A Go import points at every
+    non-test file of the imported package.
+
+    Each edge is a dict with ``source`` and ``target``, ``kind``
+    (``"internal"`` or ``"external"``), ``import`` (as written), ``line``
+    (0-based) and, for Go aliased, dot and blank imports, ``alias``.
+    """
+
+    def __init__(self, repository: "Repository"):
+        self.repo = repository
+        self.edges: List[Dict[str, Any]] = []
+        self._files: List[str] = []
+        self._imports: Dict[str, Set[str]] = {}
+        self._dependents: Dict[str, Set[str]] = {}
+        self._module_path: Optional[str] = None
+        self._python_modules: Dict[str, str] = {}
+
+    def build(self) -> "DependencyGraph":
+        """Parses the imports of every supported file; :meth:`Repository.get_dependency_graph` calls this."""
+        root = self.repo.mapper.repo_path
+        self._files = [f.relative_to(root).as_posix() for f in self.repo.mapper.source_files()]
+        file_set = set(self._files)
+        go_mod = self.repo.local_path / "go.mod"
+        self._module_path = go_module_path(go_mod.read_text(encoding="utf-8")) if go_mod.is_file() else None
+        self._python_modules = self._python_module_map()
+
+        self.edges = []
+        for path in self._files:
+            try:
+                source = self.repo.get_file_content(path)
+                self.edges.extend(self._file_edges(path, source, file_set))
+            except (IOError, UnicodeDecodeError, SyntaxError) as e:
+                logger.warning(f"Skipping imports of {path}: {e}")
+
+        self._imports = {path: set() for path in self._files}
+        self._dependents = {path: set() for path in self._files}
+        for edge in self.edges:
+            if edge["kind"] == EDGE_INTERNAL:
+                self._imports[edge["source"]].add(edge["target"])
+                self._dependents[edge["target"]].add(edge["source"])
+        return self
+
+    # ---- import resolution ----
+
+    def _file_edges(self, path: str, source: str, file_set: Set[str]) -> List[Dict[str, Any]]:
+        ext = os.path.splitext(path)[1]
+        edges: List[Dict[str, Any]] = []
+
+        def add(target: str, kind: str, imported: str, line: int, alias: Optional[str] = None) -> None:
+            edge = {"source": path, "target": target, "kind": kind, "import": imported, "line": line}
+            if alias is not None:
+                edge["alias"] = alias
+            edges.append(edge)
+
+        if ext == ".go":
+            for entry in extract_go_imports(source):
+                targets = self._go_package_files(entry["path"])
+                for target in targets:
+                    add(target, EDGE_INTERNAL, entry["path"], entry["line"], entry.get("alias"))
+                if not targets:
+                    add(entry["path"], EDGE_EXTERNAL, entry["path"], entry["line"], entry.get("alias"))
+        elif ext == ".py":
+            for entry in _python_imports(source):
+                imported = "." * entry["level"] + entry["module"]
+                targets = self._python_targets(path, entry)
+                for target in targets:
+                    add(target, EDGE_INTERNAL, imported, entry["line"])
+                if not targets:
+                    external = entry["module"].split(".")[0] if entry["level"] == 0 else imported
+                    add(external, EDGE_EXTERNAL, imported, entry["line"])
+        elif ext in _JS_EXTENSIONS:
+            for entry in _js_imports(ext, source):
+                target = self._js_target(path, entry["module"], file_set)
+                if target is not None:
+                    add(target, EDGE_INTERNAL, entry["module"], entry["line"])
+                elif not entry["module"].startswith("."):
+                    parts = entry["module"].split("/")
+                    package = "/".join(parts[:2]) if entry["module"].startswith("@") else parts[0]
+                    add(package, EDGE_EXTERNAL, entry["module"], entry["line"])
+                else:
+                    # A relative import of a file that does not exist (or is not a supported source file)
+                    add(entry["module"], EDGE_EXTERNAL, entry["module"], entry["line"])
+        return edges
+
+    def _go_package_files(self, import_path: str) -> List[str]:
+        module = self._module_path
+        if not module or not (import_path == module or import_path.startswith(module + "/")):
+            return []
+        directory = import_path[len(module) + 1 :] if import_path != module else ""
+        return [
+            f
+            for f in self._files
+            if f.endswith(".go") and not f.endswith("_test.go") and posixpath.dirname(f) == directory
+        ]
+
+    def _python_module_map(self) -> Dict[str, str]:
+        modules = {}
+        for path in self._files:
+            if not path.endswith(".py"):
+                continue
+            module = path[: -len(".py")].replace("/", ".")
+            if module.endswith("__init__"):
+                module = module[: -len("__init__")].rstrip(".")
+            modules[module] = path
+        # src/ layouts import as if src were on the path
+        for module, path in list(modules.items()):
+            if module.startswith("src."):
+                modules.setdefault(module[len("src.") :], path)
+        return modules
+
+    def _python_targets(self, path: str, entry: Dict[str, Any]) -> List[str]:
+        base = entry["module"]
+        if entry["level"]:
+            # The file's directory is its package, for modules and __init__.py alike; each extra dot goes up one
+            package = path.split("/")[:-1]
+            package = package[: max(0, len(package) - (entry["level"] - 1))]
+            base = ".".join(package + ([entry["module"]] if entry["module"] else []))
+        targets = []
+        for name in entry["names"]:
+            # from pkg import module, or from pkg import symbol
+            candidate = f"{base}.{name}" if base else name
+            if candidate in self._python_modules:
+                targets.append(self._python_modules[candidate])
+        if not targets and base in self._python_modules:
+            targets.append(self._python_modules[base])
+        return sorted(set(targets))
+
+    def _js_target(self, path: str, module: str, file_set: Set[str]) -> Optional[str]:
+        if not module.startswith("."):
+            return None
+        base = posixpath.normpath(posixpath.join(posixpath.dirname(path), module))
+        stem, ext = posixpath.splitext(base)
+        candidates = [base]
+        if ext in (".js", ".jsx", ".mjs", ".cjs"):
+            # TypeScript ESM imports name the compiled .js file
+            candidates += [stem + ".ts", stem + ".tsx"]
+        candidates += [base + e for e in _JS_EXTENSIONS] + [f"{base}/index{e}" for e in _JS_EXTENSIONS]
+        return next((c for c in candidates if c in file_set), None)
+
+    # ---- queries ----
+
+    def files(self) -> List[str]:
+        """Returns the repository files in the graph, sorted."""
+        return list(self._files)
+
+    def imports_of(self, path: str) -> List[str]:
+        """Returns the repository files *path* imports directly, sorted."""
+        return sorted(self._imports.get(path, ()))
+
+    def external_imports_of(self, path: str) -> List[str]:
+        """Returns the external packages *path* imports, sorted."""
+        return sorted({e["target"] for e in self.edges if e["source"] == path and e["kind"] == EDGE_EXTERNAL})
+
+    def dependents_of(self, path: str, transitive: bool = True) -> List[str]:
+        """
+        Returns the files that import *path*, directly or (by default) through other files.
+
+        This is the blast radius of a change to *path*. Import cycles are
+        followed once and *path* itself is not included.
+        """
+        if not transitive:
+            return sorted(self._dependents.get(path, ()))
+        seen = {path}
+        queue = deque([path])
+        while queue:
+            for dependent in self._dependents.get(queue.popleft(), ()):
+                if dependent not in seen:
+                    seen.add(dependent)
+                    queue.append(dependent)
+        seen.discard(path)
+        return sorted(seen)
+
+    def file_graph(self) -> Dict[str, List[str]]:
+        """Maps every file to the repository files it imports."""
+        return {path: self.imports_of(path) for path in self._files}
+
+    def package_graph(self) -> Dict[str, Dict[str, List[str]]]:
+        """
+        Maps every package (directory) to ``{"internal": [...], "external": [...]}``:
+        the other repository packages it imports and the external packages it uses.
+        """
+        graph: Dict[str, Dict[str, Set[str]]] = {
+            posixpath.dirname(path) or ".": {"internal": set(), "external": set()} for path in self._files
+        }
+        for edge in self.edges:
+            package = posixpath.dirname(edge["source"]) or "."
+            if edge["kind"] == EDGE_INTERNAL:
+                target = posixpath.dirname(edge["target"]) or "."
+                if target != package:
+                    graph[package]["internal"].add(target)
+            else:
+                graph[package]["external"].add(edge["target"])
+        return {
+            package: {"internal": sorted(deps["internal"]), "external": sorted(deps["external"])}
+            for package, deps in sorted(graph.items())
+        }
+
+    def _graph(self, level: str) -> Dict[str, List[str]]:
+        if level == "file":
+            return self.file_graph()
+        if level == "package":
+            return {p: d["internal"] for p, d in self.package_graph().items()}
+        raise ValueError(f"Unsupported level: {level}")
+
+    def cycles(self, level: str = "file") -> List[List[str]]:
+        """
+        Returns the strongly connected components of the file (or ``level="package"``) graph.
+
+        Each component is a sorted list of files or packages that import each
+        other, directly or indirectly.
+        """
+        return strongly_connected_components(self._graph(level))
+
+    def topological_order(self, level: str = "file") -> List[List[str]]:
+        """
+        Returns the files (or ``level="package"`` packages) with every dependency before its dependents.
+
+        This is a reading order: each file comes after the files it imports.
+        Files in an import cycle come as one group, sorted, in place of a
+        single file; every other group holds one file. Ties go to the
+        smallest path, so the order only changes when the imports do. See
+        :func:`~codekite.import_graph.topological_groups`.
+        """
+        return topological_groups(self._graph(level))
+
+    def layers(self, level: str = "file") -> List[List[str]]:
+        """
+        Buckets the files (or ``level="package"`` packages) by depth from the leaves.
+
+        Layer 0 holds the files that import no other repository file, and each
+        later layer the files whose imports are all in earlier layers. Files of
+        one layer do not import each other unless they share a cycle, so a layer
+        can be processed in parallel once the layers before it are done.
+        """
+        return dependency_layers(self._graph(level))
+
+    # ---- export ----
+
+    def to_json(self) -> Dict[str, Any]:
+        """Returns the files, packages, edges and cycles as JSON-serializable data."""
+        return {
+            "files": self.files(),
+            "edges": sorted(self.edges, key=lambda e: (e["source"], e["line"], e["target"])),
+            "packages": self.package_graph(),
+            "cycles": self.cycles(),
+        }
+
+    def write_json(self, output_path: str) -> None:
+        with open(output_path, "w") as f:
+            json.dump(self.to_json(), f, indent=2)
+
+    def to_dot(self, level: str = "file") -> str:
+        """Renders the file (or package) graph in Graphviz DOT; external packages are grey boxes."""
+        lines = ["digraph dependencies {", '  rankdir="LR";', "  node [shape=box];"]
+        if level == "file":
+            nodes = {path: EDGE_INTERNAL for path in self._files}
+            edges = {(e["source"], e["target"]) for e in self.edges}
+            for edge in self.edges:
+                nodes.setdefault(edge["target"], edge["kind"])
+        elif level == "package":
+            nodes, edges = {}, set()
+            for package, deps in self.package_graph().items():
+                nodes[package] = EDGE_INTERNAL
+                for kind in (EDGE_INTERNAL, EDGE_EXTERNAL):
+                    for target in deps[kind]:
+                        nodes.setdefault(target, kind)
+                        edges.add((package, target))
+        else:
+            raise ValueError(f"Unsupported level: {level}")
+
+        def quoted(name: str) -> str:
+            escaped = name.replace('"', '\\"')
+            return f'"{escaped}"'
+
+        for name, kind in sorted(nodes.items()):
+            style = " [style=filled, fillcolor=lightgrey]" if kind == EDGE_EXTERNAL else ""
+            lines.append(f"  {quoted(name)}{style};")
+        for source, target in sorted(edges):
+            lines.append(f"  {quoted(source)} -> {quoted(target)};")
+        lines.append("}")
+        return "\n".join(lines)
@@ -0,0 +1,113 @@
+"""Finds symbols defined more than once in a package, such as a helper a generated file redefines."""
+
+from __future__ import annotations
+import re
+from dataclasses import dataclass
+from typing import TYPE_CHECKING, Any, Dict, Iterable, List, Mapping, Optional
+
+from .go_build import constraints_disjoint
+
+if TYPE_CHECKING:
+    from .go_build import BuildContext
+
+_PACKAGE_CLAUSE = re.compile(r"^\s*package\s+(\w+)", re.MULTILINE)
+# Go allows any number of these per package
+_REPEATABLE = frozenset({"init", "_"})
+
+
+@dataclass
+class Conflict:
+    """
+    Definitions in different files that share one symbol ID.
+
+    Attributes:
+        id: The shared ID, without the ``#2`` that tells clashes within one file apart.
+        symbols: The clashing definitions, in file order.
+    """
+
+    id: str
+    symbols: List[Dict[str, Any]]
+
+    @property
+    def locations(self) -> List[str]:
+        """``file:line`` of each definition, 1-based."""
+        return [f"{s['file']}:{s['start_line'] + 1}" for s in self.symbols]
+
+    def __str__(self) -> str:
+        """``"function Add defined 2 times: calc/calc.go:5, calc/zz_generated.go:12"``."""
+        first = self.symbols[0]
+        name = first.get("node_path") or first["name"]
+        return f"{first.get('type', 'symbol')} {name} defined {len(self.symbols)} times: {', '.join(self.locations)}"
+
+    def to_dict(self) -> Dict[str, Any]:
+        return {"id": self.id, "locations": self.locations}
+
+
+def _package_name(source: Optional[str]) -> Optional[str]:
+    match = _PACKAGE_CLAUSE.search(source) if source is not None else None
+    return match.group(1) if match else None
+
+
+def find_duplicates(
+    symbols: Iterable[Dict[str, Any]],
+    sources: Optional[Mapping[str, str]] = None,
+    build_context: Optional["BuildContext"] = None,
+) -> List[Conflict]:
+    """
+    Lists the symbol IDs that more than one file defines.
+
+    A Go package is its directory, so two files of a package defining ``Add``
+    give the same ID, which ``go build`` would reject unless build constraints
+    keep the files apart. Go ``init`` functions and blank (``_``) names may
+    repeat and are skipped, as are struct fields, which clash whenever their
+    type does. IDs repeated within one file (``#2``) are left alone, since
+    overloads legitimately share them.
+
+    Args:
+        symbols: Symbols of any number of files, with ``id`` and ``file``, e.g.
+            from :meth:`Repository.parse_directory`.
+        sources: File contents by path. With them, files of different Go
+            packages in one directory (``calc`` and ``calc_test``) do not clash,
+            and neither do files whose build constraints no target satisfies
+            together (``sum_amd64.go`` and ``sum_arm64.go``, ``//go:build purego``
+            and ``!purego``).
+        build_context: Only count Go files this target builds; needs *sources*.
+            Without it every target is considered.
+
+    Returns:
+        One :class:`Conflict` per shared ID, ordered by ID. A definition is
+        listed when it clashes with at least one other.
+    """
+    sources = sources or {}
+    by_id: Dict[str, List[Dict[str, Any]]] = {}
+    for symbol in symbols:
+        if symbol.get("type") == "field" or symbol["name"] in _REPEATABLE or "id" not in symbol:
+            continue
+        file = symbol.get("file", "")
+        source = sources.get(file)
+        if build_context is not None and source is not None and file.endswith(".go"):
+            if not build_context.match_source(file, source):
+                continue
+        by_id.setdefault(symbol["id"].split("#", 1)[0], []).append(symbol)
+
+    def clash(a: Dict[str, Any], b: Dict[str, Any]) -> bool:
+        if a["file"] == b["file"]:
+            return False
+        source_a, source_b = sources.get(a["file"]), sources.get(b["file"])
+        if source_a is None or source_b is None:
+            return True
+        if _package_name(source_a) != _package_name(source_b):
+            return False
+        if build_context is not None:
+            # Both files were kept because this target builds them
+            return True
+        return not constraints_disjoint((a["file"], source_a), (b["file"], source_b))
+
+    conflicts = []
+    for symbol_id, definitions in sorted(by_id.items()):
+        if len({s["file"] for s in definitions}) < 2:
+            continue
+        clashing = [a for a in definitions if any(clash(a, b) for b in definitions if b is not a)]
+        if clashing:
+            conflicts.append(Conflict(symbol_id, sorted(clashing, key=lambda s: (s["file"], s["start_line"]))))
+    return conflicts
@@ -0,0 +1,81 @@
+"""Decoding source files that are not plain UTF-8: byte order marks, UTF-16 and legacy encodings."""
+
+from __future__ import annotations
+import codecs
+from dataclasses import dataclass
+from pathlib import Path
+from typing import Optional, Tuple
+
+# Checked in order: the UTF-32 LE mark starts with the UTF-16 LE one
+_BOMS: Tuple[Tuple[bytes, str], ...] = (
+    (codecs.BOM_UTF8, "utf-8-sig"),
+    (codecs.BOM_UTF32_LE, "utf-32-le"),
+    (codecs.BOM_UTF32_BE, "utf-32-be"),
+    (codecs.BOM_UTF16_LE, "utf-16-le"),
+    (codecs.BOM_UTF16_BE, "utf-16-be"),
+)
+
+
+@dataclass(frozen=True)
+class DecodedSource:
+    """
+    The text of a source file and how it was decoded.
+
+    Attributes:
+        text: The decoded text, without a byte order mark.
+        encoding: The codec used, e.g. ``"utf-8"``, ``"utf-8-sig"`` for UTF-8
+            with a BOM, ``"utf-16-le"`` or the fallback encoding.
+        replaced: Whether undecodable bytes were replaced with U+FFFD.
+    """
+
+    text: str
+    encoding: str
+    replaced: bool = False
+
+
+def bom_encoding(data: bytes) -> Optional[str]:
+    """Returns the encoding named by the byte order mark *data* starts with, or None if there is none."""
+    for bom, encoding in _BOMS:
+        if data.startswith(bom):
+            return encoding
+    return None
+
+
+def decode_source(data: bytes, fallback_encoding: Optional[str] = None) -> DecodedSource:
+    """
+    Decodes the bytes of a source file.
+
+    A UTF-8, UTF-16 or UTF-32 byte order mark decides the encoding and is
+    dropped. Without one, the bytes are read as UTF-8; if they are not valid
+    UTF-8, they are read as *fallback_encoding* (e.g. ``"cp1252"``) when it
+    is given, and otherwise as UTF-8 with each invalid sequence replaced by
+    U+FFFD, which sets ``replaced``. Bytes the fallback cannot decode either
+    are replaced the same way.
+
+    Newlines survive every encoding, so line numbers in the text are line
+    numbers in the file. Byte offsets and columns are not: extracted symbols
+    report them in the UTF-8 encoding of :attr:`DecodedSource.text`, which
+    matches the file only when it is UTF-8 without a BOM.
+    """
+    encoding = bom_encoding(data)
+    if encoding is None:
+        try:
+            return DecodedSource(data.decode("utf-8"), "utf-8")
+        except UnicodeDecodeError:
+            encoding = fallback_encoding or "utf-8"
+    try:
+        text, replaced = data.decode(encoding), False
+    except UnicodeDecodeError:
+        text, replaced = data.decode(encoding, errors="replace"), True
+    # Only utf-8-sig drops the mark itself
+    return DecodedSource(text[1:] if text.startswith("\ufeff") else text, encoding, replaced)
+
+
+def read_source(path: Path, fallback_encoding: Optional[str] = None) -> DecodedSource:
+    """Reads and decodes the file at *path*; see :func:`decode_source`."""
+    return decode_source(path.read_bytes(), fallback_encoding)
+
+
+def read_text(path: Path, fallback_encoding: Optional[str] = None) -> str:
+    """Like :func:`read_source`, with ``\\r\\n`` and ``\\r`` turned into ``\\n`` as a text-mode :func:`open` does."""
+    return read_source(path, fallback_encoding).text.replace("\r\n", "\n").replace("\r", "\n")
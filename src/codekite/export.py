@@ -0,0 +1,341 @@
+"""A versioned JSON export of a repository's files and symbols, and the reader for it.
+
+The document has five top-level keys::
+
+    {
+      "schema_version": 2,
+      "repo": {"name": "shop", "sha": "...", "branch": "main", "remote_url": "..."},
+      "files": [{"path": "cart/cart.go", "language": "go", "size": 1024, "symbol_count": 7}],
+      "symbols": [{"name": "Cart", "kind": "struct", "file": "cart/cart.go", ...}],
+      "skipped": [{"path": "web/app.min.js", "reason": "long_lines", "detail": "..."}]
+    }
+
+``skipped`` lists the files left out under :class:`~codekite.file_limits.FileLimits`,
+so a consumer can tell a file with no symbols from one that was never parsed.
+With :attr:`ExportOptions.stream` the same keys are written in the order
+``schema_version``, ``repo``, ``symbols``, ``files``, ``skipped``.
+
+Field names and meanings only change with ``schema_version``. Fields may be
+added without a version change; :func:`read_export` ignores any it does not
+know, so older readers keep working on newer exports of the same version.
+A symbol's fields are those of :class:`ExportedSymbol`; anything else the
+extractor reported, which differs between languages, goes in its
+``attributes`` object.
+"""
+
+from __future__ import annotations
+import fnmatch
+import json
+import os
+from dataclasses import asdict, dataclass, field, fields
+from pathlib import Path
+from typing import TYPE_CHECKING, Any, Dict, FrozenSet, Iterable, Iterator, List, Optional, TextIO
+
+from . import languages
+from .code_origin import is_generated, is_vendored, read_header
+from .file_limits import FileLimits, SkippedFile
+from .symbol_filter import expand_kinds, is_exported
+
+if TYPE_CHECKING:
+    from .repository import Repository
+
+SCHEMA_VERSION = 2
+
+
+@dataclass
+class ExportOptions:
+    """
+    What :func:`write_export` includes and how it lays it out.
+
+    Attributes:
+        pretty: Indent the JSON by two spaces instead of writing one line.
+        include_code: Add each symbol's source to its ``code`` field.
+        kinds: Symbol kinds to keep, aliases such as ``type`` included (see
+            :data:`~codekite.symbol_filter.KIND_ALIASES`). Empty keeps every kind.
+        paths: :mod:`fnmatch` patterns over repository-relative paths, e.g.
+            ``["cart/*.go"]``, where ``*`` also crosses ``/``. Files matching
+            none of them are left out. Empty keeps every file.
+        limits: Binary, size and line-length limits for parsing; files over
+            them are listed under ``skipped``.
+        stream: Write each file's symbols as soon as it is parsed, so memory
+            stays flat on huge repositories. The document reads back the same,
+            but ``symbols`` comes before ``files`` and ``skipped``, and files
+            are in walk order rather than sorted by path.
+    """
+
+    pretty: bool = False
+    include_code: bool = False
+    kinds: List[str] = field(default_factory=list)
+    paths: List[str] = field(default_factory=list)
+    limits: FileLimits = field(default_factory=FileLimits)
+    stream: bool = False
+
+
+@dataclass
+class ExportedRepo:
+    name: str = ""
+    sha: str = ""
+    branch: str = ""
+    remote_url: str = ""
+
+
+@dataclass
+class ExportedFile:
+    """One parsed file; ``vendored`` and ``generated`` are as :mod:`codekite.code_origin` classifies it."""
+
+    path: str
+    language: str
+    size: int = 0
+    symbol_count: int = 0
+    vendored: bool = False
+    generated: bool = False
+
+
+@dataclass
+class ExportedSymbol:
+    """
+    One symbol in an export.
+
+    Lines and columns are 0-based, with ``end_line`` inclusive, like the
+    symbols of :meth:`Repository.extract_symbols`; ``kind`` is their ``type``.
+    Empty strings stand for values the extractor did not report. ``id`` is
+    the symbol's stable ID; see :func:`~codekite.symbol_ids.symbol_id`.
+    ``qualified_name`` is its name as seen from other modules, such as
+    ``example.com/app/store.Store.Get``; see :func:`~codekite.symbol_ids.full_name`.
+    """
+
+    name: str
+    kind: str
+    file: str
+    language: str
+    id: str = ""
+    qualified_name: str = ""
+    start_line: int = 0
+    end_line: int = 0
+    start_column: int = 0
+    end_column: int = 0
+    node_path: str = ""
+    parent: str = ""
+    signature: str = ""
+    docstring: str = ""
+    exported: bool = True
+    code: Optional[str] = None
+    attributes: Dict[str, Any] = field(default_factory=dict)
+
+    def to_dict(self) -> Dict[str, Any]:
+        data = asdict(self)
+        if self.code is None:
+            del data["code"]
+        return data
+
+
+@dataclass
+class ExportDocument:
+    """A parsed export; see the module documentation for its layout."""
+
+    repo: ExportedRepo
+    files: List[ExportedFile] = field(default_factory=list)
+    symbols: List[ExportedSymbol] = field(default_factory=list)
+    schema_version: int = SCHEMA_VERSION
+    skipped: List[SkippedFile] = field(default_factory=list)
+
+    def to_dict(self) -> Dict[str, Any]:
+        return {
+            "schema_version": self.schema_version,
+            "repo": asdict(self.repo),
+            "files": [asdict(f) for f in self.files],
+            "symbols": [s.to_dict() for s in self.symbols],
+            "skipped": [asdict(s) for s in self.skipped],
+        }
+
+
+def _known(cls: type, data: Dict[str, Any]) -> Dict[str, Any]:
+    names = {f.name for f in fields(cls)}
+    return {k: v for k, v in data.items() if k in names}
+
+
+# Extractor keys that map onto ExportedSymbol fields; "type" becomes "kind" and "code" is optional
+_SYMBOL_KEYS = {f.name for f in fields(ExportedSymbol)} - {"kind", "language", "attributes"} | {"type"}
+
+
+def _language(path: str) -> str:
+    return (languages.language_for(os.path.splitext(path)[1]) or "").lower()
+
+
+def _export_symbol(path: str, symbol: Dict[str, Any], include_code: bool) -> ExportedSymbol:
+    values = {k: v for k, v in symbol.items() if k in _SYMBOL_KEYS and k not in ("type", "code") and v is not None}
+    values.update(file=path, exported=is_exported(dict(symbol, file=path)))
+    return ExportedSymbol(
+        kind=str(symbol.get("type") or "").lower(),
+        language=_language(path),
+        code=symbol.get("code", "") if include_code else None,
+        attributes={k: v for k, v in sorted(symbol.items()) if k not in _SYMBOL_KEYS and v is not None},
+        **values,
+    )
+
+
+def _selected(path: str, options: ExportOptions) -> bool:
+    return not options.paths or any(fnmatch.fnmatchcase(path, pattern) for pattern in options.paths)
+
+
+def _file_symbols(symbols: List[Dict[str, Any]], kinds: FrozenSet[str]) -> List[Dict[str, Any]]:
+    kept = [s for s in symbols if not kinds or str(s.get("type") or "").lower() in kinds]
+    kept.sort(key=lambda s: (s.get("start_line", 0), s.get("end_line", 0), s.get("name") or ""))
+    return kept
+
+
+def _exported_file(repo: Optional["Repository"], path: str, symbol_count: int) -> ExportedFile:
+    full_path = os.path.join(repo.repo_path, path) if repo is not None else None
+    try:
+        size = os.path.getsize(full_path) if full_path is not None else 0
+    except OSError:
+        size = 0
+    header = read_header(Path(full_path)) if full_path is not None else None
+    return ExportedFile(path, _language(path), size, symbol_count, is_vendored(path), is_generated(path, header))
+
+
+def _exported_repo(repo: Optional["Repository"]) -> ExportedRepo:
+    if repo is None:
+        return ExportedRepo()
+    git = repo.provenance() or {}
+    return ExportedRepo(name=os.path.basename(os.path.abspath(repo.repo_path)), **git)
+
+
+def build_export(repo: "Repository", options: Optional[ExportOptions] = None) -> ExportDocument:
+    """
+    Collects the export document for *repo*: files in path order, symbols by file and position.
+
+    Files that fail to parse are left out, as :meth:`Repository.parse_directory` reports them;
+    files skipped under ``options.limits`` are listed in ``skipped``, by path.
+    """
+    options = options or ExportOptions()
+    kinds = expand_kinds(options.kinds)
+    document = ExportDocument(_exported_repo(repo))
+
+    by_file, errors = repo.parse_directory(limits=options.limits)
+    for error in errors:
+        if "skipped" in error and _selected(error["file"], options):
+            document.skipped.append(SkippedFile(error["file"], error["skipped"], error["error"]))
+    selected = {path: _file_symbols(symbols, kinds) for path, symbols in by_file.items() if _selected(path, options)}
+    _add_files(document, repo, selected, options.include_code)
+    return document
+
+
+def export_symbols(
+    by_file: Dict[str, List[Dict[str, Any]]],
+    repo: Optional["Repository"] = None,
+    include_code: bool = False,
+    sort: bool = False,
+) -> ExportDocument:
+    """
+    Builds the export document for symbols already extracted, keyed by repository-relative path.
+
+    For callers that filtered the symbols themselves, such as ``codekite
+    symbols --format json``. Every file in *by_file* is listed, with or
+    without symbols. Without a *repo* the ``repo`` fields are empty and
+    file sizes are 0. *sort* orders ``symbols`` across files by kind,
+    receiver and name, as :func:`~codekite.formatters.sort_symbols` does.
+    """
+    from .formatters import sort_symbols
+
+    document = ExportDocument(_exported_repo(repo))
+    sorted_symbols = {path: _file_symbols(symbols, frozenset()) for path, symbols in by_file.items()}
+    _add_files(document, repo, sorted_symbols, include_code)
+    if sort:
+        ordered = sort_symbols([dict(s, file=path) for path in sorted(by_file) for s in sorted_symbols[path]])
+        document.symbols = [_export_symbol(s["file"], s, include_code) for s in ordered]
+    return document
+
+
+def _add_files(
+    document: ExportDocument,
+    repo: Optional["Repository"],
+    by_file: Dict[str, List[Dict[str, Any]]],
+    include_code: bool,
+) -> None:
+    for path in sorted(by_file):
+        symbols = by_file[path]
+        document.files.append(_exported_file(repo, path, len(symbols)))
+        document.symbols.extend(_export_symbol(path, s, include_code) for s in symbols)
+
+
+def write_export(repo: "Repository", fp: TextIO, options: Optional[ExportOptions] = None) -> None:
+    """Writes the export document for *repo* to an open text file, keys sorted within each object."""
+    options = options or ExportOptions()
+    if options.stream:
+        _stream_export(repo, fp, options)
+        return
+    document = build_export(repo, options).to_dict()
+    fp.write(json.dumps(document, indent=2 if options.pretty else None, sort_keys=True, ensure_ascii=False))
+    fp.write("\n")
+
+
+def _stream_export(repo: "Repository", fp: TextIO, options: ExportOptions) -> None:
+    """
+    Writes the document for ``options.stream``: each file's symbols as soon as it is parsed.
+
+    Only ``files`` and ``skipped``, a small entry per file, are held until
+    the end, which is why they come after ``symbols``.
+    """
+    kinds = expand_kinds(options.kinds)
+    indent = 2 if options.pretty else None
+
+    def dump(value: Any, level: int) -> str:
+        text = json.dumps(value, indent=indent, sort_keys=True, ensure_ascii=False)
+        return text.replace("\n", "\n" + "  " * level) if indent else text
+
+    # Separators matching what json.dumps writes for the same document
+    key_sep, first_item, item_sep, close = (",\n  ", "\n    ", ",\n    ", "\n  ]") if indent else (", ", "", ", ", "]")
+
+    def write_array(items: Iterable[Any]) -> None:
+        count = 0
+        for item in items:
+            fp.write((item_sep if count else first_item) + dump(item, 2))
+            count += 1
+        fp.write(close if count else "]")
+
+    files: List[ExportedFile] = []
+    skipped: List[SkippedFile] = []
+
+    def symbols() -> Iterator[Dict[str, Any]]:
+        for result in repo.iter_symbols(limits=options.limits):
+            for error in result.diagnostics:
+                if "skipped" in error and _selected(result.path, options):
+                    skipped.append(SkippedFile(result.path, error["skipped"], error["error"]))
+            if result.symbols is None or not _selected(result.path, options):
+                continue
+            kept = _file_symbols(result.symbols, kinds)
+            files.append(_exported_file(repo, result.path, len(kept)))
+            for symbol in kept:
+                yield _export_symbol(result.path, symbol, options.include_code).to_dict()
+
+    fp.write("{" + ("\n  " if indent else ""))
+    fp.write(f'"schema_version": {SCHEMA_VERSION}{key_sep}"repo": {dump(asdict(_exported_repo(repo)), 1)}')
+    fp.write(f'{key_sep}"symbols": [')
+    write_array(symbols())
+    fp.write(f'{key_sep}"files": [')
+    write_array(asdict(f) for f in files)
+    fp.write(f'{key_sep}"skipped": [')
+    write_array(asdict(s) for s in skipped)
+    fp.write(("\n" if indent else "") + "}\n")
+
+
+def read_export(fp: TextIO) -> ExportDocument:
+    """
+    Reads a document written by :func:`write_export`, ignoring fields it does not know.
+
+    Raises:
+        ValueError: If the document is not an export or has another ``schema_version``.
+    """
+    data = json.load(fp)
+    if not isinstance(data, dict) or "schema_version" not in data:
+        raise ValueError("Not a codekite export: no schema_version")
+    if data["schema_version"] != SCHEMA_VERSION:
+        raise ValueError(f"Unsupported export schema_version {data['schema_version']}; expected {SCHEMA_VERSION}")
+    return ExportDocument(
+        repo=ExportedRepo(**_known(ExportedRepo, data.get("repo") or {})),
+        files=[ExportedFile(**_known(ExportedFile, f)) for f in data.get("files", [])],
+        symbols=[ExportedSymbol(**_known(ExportedSymbol, s)) for s in data.get("symbols", [])],
+        schema_version=data["schema_version"],
+        skipped=[SkippedFile(**_known(SkippedFile, s)) for s in data.get("skipped", [])],
+    )
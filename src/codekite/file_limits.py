@@ -0,0 +1,94 @@
+"""Limits that keep binary files, build artifacts and minified bundles out of extraction."""
+
+from __future__ import annotations
+import os
+from dataclasses import dataclass
+from pathlib import Path
+from typing import Optional
+
+from .encodings import bom_encoding
+
+# How much of a file is sniffed for NUL bytes to decide that it is binary
+BINARY_SNIFF_BYTES = 8192
+
+DEFAULT_MAX_FILE_SIZE = 1024 * 1024
+
+# Extensions that are never text, whatever their first bytes say
+BINARY_EXTENSIONS = frozenset({
+    ".a", ".bin", ".class", ".dll", ".dylib", ".exe", ".gif", ".gz", ".ico", ".jar", ".jpeg", ".jpg",
+    ".o", ".pdf", ".png", ".pyc", ".so", ".tar", ".wasm", ".webp", ".woff", ".woff2", ".zip",
+})
+
+SKIP_BINARY = "binary"
+SKIP_TOO_LARGE = "too_large"
+SKIP_LONG_LINES = "long_lines"
+SKIP_BROKEN_SYMLINK = "broken_symlink"
+
+
+@dataclass(frozen=True)
+class SkippedFile:
+    """
+    A file extraction left out on purpose, as opposed to one that failed to parse.
+
+    Attributes:
+        path: Repository-relative path.
+        reason: ``"binary"``, ``"too_large"``, ``"long_lines"``, or
+            ``"broken_symlink"`` for a link whose target does not exist.
+        detail: Human-readable explanation, e.g. ``"3.2 MB exceeds the 1.0 MB limit"``.
+    """
+
+    path: str
+    reason: str
+    detail: str = ""
+
+
+@dataclass(frozen=True)
+class FileLimits:
+    """
+    Which files :meth:`RepoMapper.parse_directory` skips before parsing them.
+
+    Attributes:
+        max_file_size: Largest file parsed, in bytes; files above it are
+            skipped unread. None parses files of any size.
+        max_line_length: Skip files with any line longer than this many
+            bytes, which catches minified JavaScript and generated bundles.
+            None, the default, does not look at line lengths.
+        skip_binary: Skip files with a known binary extension or a NUL byte
+            in their first 8 KB, unless a UTF-16 or UTF-32 byte order mark
+            explains the NULs.
+    """
+
+    max_file_size: Optional[int] = DEFAULT_MAX_FILE_SIZE
+    max_line_length: Optional[int] = None
+    skip_binary: bool = True
+
+
+def _megabytes(size: int) -> str:
+    return f"{size / (1024 * 1024):.1f} MB"
+
+
+def check_size(file: Path, rel_path: str, limits: FileLimits) -> Optional[SkippedFile]:
+    """Returns why *file* is skipped without reading it, or None if it may be read."""
+    if file.is_symlink() and not file.exists():
+        # Not a limit: there is nothing to read, whatever the limits say
+        return SkippedFile(rel_path, SKIP_BROKEN_SYMLINK, f"symlink to missing {os.readlink(file)}")
+    if limits.skip_binary and file.suffix.lower() in BINARY_EXTENSIONS:
+        return SkippedFile(rel_path, SKIP_BINARY, f"{file.suffix.lower()} files are binary")
+    if limits.max_file_size is not None:
+        size = file.stat().st_size
+        if size > limits.max_file_size:
+            detail = f"{_megabytes(size)} exceeds the {_megabytes(limits.max_file_size)} limit"
+            return SkippedFile(rel_path, SKIP_TOO_LARGE, detail)
+    return None
+
+
+def check_content(data: bytes, rel_path: str, limits: FileLimits) -> Optional[SkippedFile]:
+    """Returns why a file holding *data* is skipped, or None if it should be parsed."""
+    if limits.skip_binary and b"\0" in data[:BINARY_SNIFF_BYTES] and bom_encoding(data) in (None, "utf-8-sig"):
+        return SkippedFile(rel_path, SKIP_BINARY, "NUL byte in the first 8 KB")
+    if limits.max_line_length is not None:
+        longest = max((len(line) for line in data.split(b"\n")), default=0)
+        if longest > limits.max_line_length:
+            detail = f"a {longest}-byte line exceeds the {limits.max_line_length}-byte limit"
+            return SkippedFile(rel_path, SKIP_LONG_LINES, detail)
+    return None
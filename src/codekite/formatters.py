@@ -0,0 +1,747 @@
+"""Output formats for extracted symbols.
+
+Every formatter takes the list of symbol dicts produced by
+:meth:`codekite.repository.Repository.extract_symbols` and returns a string.
+Output is deterministic for the same input so it can be checked into golden tests.
+Symbols come in source order unless ``sort`` is set, which orders them with
+:func:`sort_symbols` instead, so the output no longer depends on declaration order.
+"""
+
+from __future__ import annotations
+
+import json
+import re
+import shutil
+import textwrap
+from typing import Any, Dict, List, Optional, Sequence, TextIO, Tuple
+
+from .symbol_ids import qualified_name
+
+# Keys that are always present in JSON output, even when the extractor did not
+# populate them, so consumers never need to check for their existence.
+REQUIRED_JSON_FIELDS: Dict[str, Any] = {
+    "name": "",
+    "type": "",
+    "docstring": "",
+    "signature": "",
+    "receiver": "",
+    "start_line": 0,
+    "end_line": 0,
+}
+
+# Column and doc-comment positions are only emitted when explicitly requested,
+# which keeps golden files written before they existed unchanged.
+POSITION_FIELDS = ("start_column", "end_column", "doc_start_line")
+
+
+def _symbol_sort_key(symbol: Dict[str, Any]) -> tuple:
+    return (
+        symbol.get("file") or "",
+        symbol.get("start_line", 0),
+        symbol.get("end_line", 0),
+        symbol.get("name") or "",
+        symbol.get("type") or "",
+        symbol.get("id") or "",
+    )
+
+
+def sort_by_location(symbols: Sequence[Dict[str, Any]]) -> List[Dict[str, Any]]:
+    """Returns symbols ordered by file, then source position, with ties broken on ``id``."""
+    return sorted(symbols, key=_symbol_sort_key)
+
+
+def _kind_sort_key(symbol: Dict[str, Any]) -> tuple:
+    return (
+        symbol.get("type") or "",
+        # Python and Rust methods record their owner as parent rather than receiver
+        symbol.get("receiver") or symbol.get("parent") or "",
+        symbol.get("name") or "",
+        symbol.get("id") or "",
+    ) + _symbol_sort_key(symbol)
+
+
+def sort_symbols(symbols: Sequence[Dict[str, Any]]) -> List[Dict[str, Any]]:
+    """
+    Returns symbols ordered by kind, then receiver, then name, with ties broken on ``id``.
+
+    Unlike :func:`sort_by_location`, the order does not depend on where in a
+    file, or in which file of a package, a declaration sits, so moving code
+    around leaves the output unchanged. Symbols that still tie, such as
+    symbols without IDs, fall back to location order.
+    """
+    return sorted(symbols, key=_kind_sort_key)
+
+
+def _ordered(symbols: Sequence[Dict[str, Any]], sort: bool) -> List[Dict[str, Any]]:
+    return sort_symbols(symbols) if sort else sort_by_location(symbols)
+
+
+def normalize_symbol(symbol: Dict[str, Any], positions: bool = False) -> Dict[str, Any]:
+    """
+    Returns a copy of *symbol* with every required JSON field present.
+
+    Position fields (:data:`POSITION_FIELDS`) are dropped unless *positions* is true.
+    """
+    normalized = dict(REQUIRED_JSON_FIELDS)
+    normalized.update(
+        {k: v for k, v in symbol.items() if v is not None and (positions or k not in POSITION_FIELDS)}
+    )
+    return normalized
+
+
+def symbols_to_json(
+    symbols: Sequence[Dict[str, Any]], indent: int = 2, positions: bool = False, sort: bool = False
+) -> str:
+    """
+    Serializes symbols to JSON with sorted keys and symbols sorted by location.
+
+    Args:
+        symbols: Symbol dicts as returned by ``extract_symbols``.
+        indent: Indentation passed to :func:`json.dumps`.
+        positions: Include ``start_column``, ``end_column`` and ``doc_start_line``.
+
+    Returns:
+        The JSON document as a string (a JSON array of symbol objects).
+    """
+    ordered = [normalize_symbol(s, positions=positions) for s in _ordered(symbols, sort)]
+    return json.dumps(ordered, indent=indent, sort_keys=True, ensure_ascii=False)
+
+
+def write_symbols_json(
+    fp: TextIO, symbols: Sequence[Dict[str, Any]], indent: int = 2, positions: bool = False
+) -> None:
+    """Writes :func:`symbols_to_json` output to an open text file."""
+    fp.write(symbols_to_json(symbols, indent=indent, positions=positions))
+    fp.write("\n")
+
+
+_YAML_PLAIN_KEY = re.compile(r"^[A-Za-z_][A-Za-z0-9_]*$")
+
+
+def _yaml_canonical(value: Any) -> str:
+    return json.dumps(value, sort_keys=True, ensure_ascii=False)
+
+
+def _yaml_anchors(symbols: List[Dict[str, Any]]) -> Dict[str, str]:
+    """
+    Names every non-empty list or mapping that occurs more than once below the symbols.
+
+    Anchors are numbered in the order their value is first written, so the
+    same input always gets the same names.
+    """
+    counts: Dict[str, int] = {}
+    order: List[str] = []
+
+    def visit(value: Any) -> None:
+        if isinstance(value, (list, dict)) and value:
+            key = _yaml_canonical(value)
+            counts[key] = counts.get(key, 0) + 1
+            if counts[key] > 1:
+                # Written as an alias, so nothing inside it is written again
+                return
+            order.append(key)
+            for child in [value[k] for k in sorted(value)] if isinstance(value, dict) else value:
+                visit(child)
+
+    for symbol in symbols:
+        for key in sorted(symbol):
+            visit(symbol[key])
+    shared = [key for key in order if counts[key] > 1]
+    return {key: f"ref{i}" for i, key in enumerate(shared, 1)}
+
+
+def _yaml_block_string(text: str, indent: str) -> Optional[str]:
+    """Renders a multi-line string as a literal block, or None when quoting is safer."""
+    lines = text.split("\n")
+    content = lines[:-1] if text.endswith("\n") else lines
+    first = next((line for line in content if line), "")
+    # Leading spaces would be read as indentation, trailing ones are easy to lose
+    if first.startswith(" ") or "\r" in text or any(line != line.rstrip(" \t") for line in content):
+        return None
+    chomp = "-" if not text.endswith("\n") else ("+" if text.endswith("\n\n") else "")
+    body = "\n".join(f"{indent}{line}" if line else "" for line in content)
+    return f"|{chomp}\n{body}"
+
+
+def _yaml_scalar(value: Any, indent: str) -> str:
+    if value is None:
+        return "null"
+    if isinstance(value, bool):
+        return "true" if value else "false"
+    if isinstance(value, (int, float)):
+        return json.dumps(value)
+    text = str(value)
+    if "\n" in text.rstrip("\n") or text.endswith("\n"):
+        block = _yaml_block_string(text, indent)
+        if block is not None:
+            return block
+    # A JSON string is also a valid YAML double-quoted scalar, and never reads as a number or boolean
+    return json.dumps(text, ensure_ascii=False)
+
+
+def _yaml_key(key: str) -> str:
+    return key if _YAML_PLAIN_KEY.match(key) else json.dumps(key, ensure_ascii=False)
+
+
+def _yaml_node(value: Any, indent: str, anchors: Dict[str, str], written: set) -> Tuple[str, List[str]]:
+    """
+    Returns how *value* is written after ``key:`` or ``-``: inline text, plus
+    continuation lines for block collections.
+    """
+    if not isinstance(value, (list, dict)):
+        return _yaml_scalar(value, indent), []
+    if not value:
+        return ("[]" if isinstance(value, list) else "{}"), []
+    canonical = _yaml_canonical(value)
+    anchor = anchors.get(canonical)
+    if anchor is not None and canonical in written:
+        return f"*{anchor}", []
+    lines = _yaml_collection(value, indent, anchors, written)
+    if anchor is not None:
+        written.add(canonical)
+        return f"&{anchor}", lines
+    return "", lines
+
+
+def _yaml_collection(value: Any, indent: str, anchors: Dict[str, str], written: set) -> List[str]:
+    lines: List[str] = []
+    if isinstance(value, dict):
+        for key in sorted(value):
+            inline, rest = _yaml_node(value[key], indent + "  ", anchors, written)
+            lines.append(f"{indent}{_yaml_key(key)}:{' ' + inline if inline else ''}")
+            lines.extend(rest)
+        return lines
+    for item in value:
+        inline, rest = _yaml_node(item, indent + "  ", anchors, written)
+        if not inline and isinstance(item, dict):
+            # The first key of a mapping shares the dash's line
+            rest[0] = f"{indent}- {rest[0][len(indent) + 2:]}"
+            lines.extend(rest)
+        else:
+            lines.append(f"{indent}-{' ' + inline if inline else ''}")
+            lines.extend(rest)
+    return lines
+
+
+def symbols_to_yaml(symbols: Sequence[Dict[str, Any]], positions: bool = False, sort: bool = False) -> str:
+    """
+    Serializes symbols to YAML with the same fields and order as :func:`symbols_to_json`.
+
+    Keys are sorted and symbols are sorted by location, and every string is
+    double-quoted (multi-line ones become ``|`` literal blocks), so ``""``
+    stays an empty string and ``"no"`` never turns into a boolean. A list or
+    mapping that occurs more than once, such as a field list shared by two
+    types, is written once with an ``&ref1`` anchor and aliased as ``*ref1``
+    afterwards; a YAML parser expands the aliases back into the JSON values.
+    """
+    ordered = [normalize_symbol(s, positions=positions) for s in _ordered(symbols, sort)]
+    if not ordered:
+        return "[]"
+    return "\n".join(_yaml_collection(ordered, "", _yaml_anchors(ordered), set()))
+
+
+def _location(symbol: Dict[str, Any], positions: bool) -> str:
+    location = f"{symbol.get('file', '')}:{symbol.get('start_line', 0) + 1}"
+    if positions:
+        location += f":{symbol.get('start_column', 0) + 1}"
+    return location
+
+
+def symbols_to_text(symbols: Sequence[Dict[str, Any]], positions: bool = False, sort: bool = False) -> str:
+    """
+    Renders one ``file:line: type name`` line per symbol (1-indexed lines).
+
+    With *positions*, the location becomes ``file:line:column`` (1-indexed), the
+    form most editors accept for jump-to-location.
+    """
+    lines = []
+    for symbol in _ordered(symbols, sort):
+        name = symbol.get("node_path") or symbol.get("name")
+        lines.append(f"{_location(symbol, positions)}: {symbol.get('type')} {name}")
+    return "\n".join(lines)
+
+
+def _truncate(text: str, room: int) -> str:
+    if len(text) <= room:
+        return text
+    return text[: room - 1] + "…" if room > 1 else ""
+
+
+def symbols_to_table(
+    symbols: Sequence[Dict[str, Any]], positions: bool = False, width: Optional[int] = None, sort: bool = False
+) -> str:
+    """
+    Renders symbols as aligned ``LOCATION  KIND  NAME  SIGNATURE`` columns under a header row.
+
+    Signatures are joined onto one line and cut with ``…`` so that no row is
+    wider than *width*, which defaults to the terminal width. The other
+    columns are never cut. No symbols render as an empty string.
+    """
+    rows = [
+        (
+            _location(symbol, positions),
+            str(symbol.get("type") or ""),
+            str(symbol.get("node_path") or symbol.get("name") or ""),
+            " ".join(str(symbol.get("signature") or "").split()),
+        )
+        for symbol in _ordered(symbols, sort)
+    ]
+    if not rows:
+        return ""
+    if width is None:
+        width = shutil.get_terminal_size().columns
+    header = ("LOCATION", "KIND", "NAME", "SIGNATURE")
+    widths = [max(len(row[i]) for row in [header] + rows) for i in range(3)]
+    lines = []
+    for row in [header] + rows:
+        prefix = "  ".join(cell.ljust(w) for cell, w in zip(row, widths)) + "  "
+        lines.append((prefix + _truncate(row[3], width - len(prefix))).rstrip())
+    return "\n".join(lines)
+
+
+def symbols_to_names(symbols: Sequence[Dict[str, Any]], positions: bool = False, sort: bool = False) -> str:
+    """
+    Renders one qualified name per line, e.g. ``pkg/user.User.Greet``, for piping into fzf or grep.
+
+    Names are qualified as :func:`~codekite.symbol_ids.qualified_name` does;
+    symbols read from stdin, whose ``file`` is ``<stdin>``, keep their bare
+    ``node_path``. *positions* is accepted for symmetry and ignored.
+    """
+    lines = []
+    for symbol in _ordered(symbols, sort):
+        file_path = symbol.get("file") or ""
+        if file_path and not file_path.startswith("<"):
+            lines.append(qualified_name(file_path, symbol))
+        else:
+            lines.append(str(symbol.get("node_path") or symbol.get("name") or ""))
+    return "\n".join(lines)
+
+
+# Markdown section order. Kinds not listed here follow in alphabetical order.
+_MARKDOWN_SECTIONS: List[tuple] = [
+    ("Types", ("struct", "class", "type", "enum")),
+    ("Interfaces", ("interface", "trait")),
+    ("Functions", ("function",)),
+    ("Constants", ("constant", "const")),
+    ("Variables", ("variable", "var")),
+]
+
+_FENCE_LANGUAGES = {
+    ".go": "go",
+    ".py": "python",
+    ".js": "javascript",
+    ".ts": "typescript",
+    ".tsx": "tsx",
+    ".rs": "rust",
+    ".rb": "ruby",
+    ".java": "java",
+    ".c": "c",
+    ".h": "c",
+    ".cpp": "cpp",
+    ".hpp": "cpp",
+    ".cc": "cpp",
+    ".cxx": "cpp",
+    ".hh": "cpp",
+    ".hxx": "cpp",
+    ".sh": "bash",
+    ".bash": "bash",
+}
+
+
+def _fence_language(symbol: Dict[str, Any]) -> str:
+    file_path = symbol.get("file") or ""
+    dot = file_path.rfind(".")
+    return _FENCE_LANGUAGES.get(file_path[dot:], "") if dot >= 0 else ""
+
+
+def _display_signature(symbol: Dict[str, Any]) -> str:
+    if symbol.get("signature"):
+        return symbol["signature"]
+    # No recorded signature: fall back to the first line of the definition.
+    first_line = (symbol.get("code") or "").strip().splitlines()[:1]
+    return first_line[0].rstrip(" {:") if first_line else symbol.get("name", "")
+
+
+def _go_doc_markdown(docstring: str) -> str:
+    """
+    Turns the indented code blocks of a Go doc comment into fenced ones.
+
+    Markdown would otherwise re-flow them, or keep them only when indented
+    by four spaces or a tab. Indentation relative to the block is kept.
+    """
+    lines = docstring.split("\n")
+    out: List[str] = []
+    i = 0
+    while i < len(lines):
+        if lines[i][:1] in (" ", "\t") and (i == 0 or not lines[i - 1].strip()):
+            end = i
+            while end < len(lines) and (lines[end][:1] in (" ", "\t") or not lines[end].strip()):
+                end += 1
+            while end > i and not lines[end - 1].strip():
+                end -= 1
+            # A line of only spaces is blank, not the start of a block
+            if end > i:
+                out += ["```go", textwrap.dedent("\n".join(lines[i:end])), "```"]
+                i = end
+                continue
+        out.append(lines[i])
+        i += 1
+    return "\n".join(out)
+
+
+def _render_markdown_entry(symbol: Dict[str, Any], heading: str, lines: List[str], positions: bool) -> None:
+    lines.append(f"{heading} {symbol.get('node_path') or symbol.get('name')}")
+    lines.append("")
+    if positions:
+        lines.append(f"`{_location(symbol, positions)}`")
+        lines.append("")
+    lines.append(f"```{_fence_language(symbol)}")
+    lines.append(_display_signature(symbol))
+    lines.append("```")
+    lines.append("")
+    if symbol.get("deprecated"):
+        note = symbol.get("deprecated_note")
+        lines.append(f"> **Deprecated:** {note}" if note else "> **Deprecated.**")
+        lines.append("")
+    if symbol.get("docstring"):
+        is_go = (symbol.get("file") or "").endswith(".go")
+        lines.append(_go_doc_markdown(symbol["docstring"]) if is_go else symbol["docstring"])
+        lines.append("")
+
+
+def render_markdown(symbols: Sequence[Dict[str, Any]], positions: bool = False, sort: bool = False) -> str:
+    """
+    Renders symbols as a Markdown reference document.
+
+    Symbols are grouped into ``##`` sections by kind (Types, Interfaces,
+    Functions, Constants, Variables, then any other kinds alphabetically).
+    Each symbol gets a ``###`` heading, its signature in a fenced code block
+    and its doc comment as prose. Methods are nested under their parent type
+    with ``####`` headings; methods whose type was not extracted are listed in
+    a trailing Methods section.
+
+    Within every section, and within each type's methods, symbols keep
+    source order (file, then line), or :func:`sort_symbols` order with *sort*,
+    so output is stable for the same input.
+    With *positions*, each entry also shows its ``file:line:column`` location.
+    """
+    ordered = _ordered(symbols, sort)
+    methods_by_parent: Dict[str, List[Dict[str, Any]]] = {}
+    top_level: List[Dict[str, Any]] = []
+    for symbol in ordered:
+        if symbol.get("parent") and symbol.get("type") in ("method", "function"):
+            methods_by_parent.setdefault(symbol["parent"], []).append(symbol)
+        else:
+            top_level.append(symbol)
+
+    known_kinds = {kind for _, kinds in _MARKDOWN_SECTIONS for kind in kinds}
+    extra_kinds = sorted({s.get("type") or "" for s in top_level} - known_kinds)
+    sections = list(_MARKDOWN_SECTIONS) + [(f"{kind.title()}s", (kind,)) for kind in extra_kinds]
+
+    lines: List[str] = []
+    claimed_parents = set()
+    for title, kinds in sections:
+        members = [s for s in top_level if s.get("type") in kinds]
+        if not members:
+            continue
+        lines.append(f"## {title}")
+        lines.append("")
+        for symbol in members:
+            _render_markdown_entry(symbol, "###", lines, positions)
+            # Python parents are dotted scopes ("Outer.Inner"), so match on node_path first.
+            name = symbol.get("node_path") or symbol.get("name")
+            if name in methods_by_parent and name not in claimed_parents:
+                claimed_parents.add(name)
+                for method in methods_by_parent[name]:
+                    _render_markdown_entry(method, "####", lines, positions)
+
+    orphans = [m for parent, group in methods_by_parent.items() if parent not in claimed_parents for m in group]
+    if orphans:
+        lines.append("## Methods")
+        lines.append("")
+        for method in _ordered(orphans, sort):
+            _render_markdown_entry(method, "###", lines, positions)
+
+    return "\n".join(lines).rstrip("\n") + "\n" if lines else ""
+
+
+def _tree_children(symbol: Dict[str, Any], nested: List[Dict[str, Any]]) -> List[tuple]:
+    """Returns ``(label, symbol_or_None)`` children of a type: Go struct fields, then nested symbols."""
+    children: List[tuple] = []
+    for field in symbol.get("fields") or []:
+        label = f"embedded {field['type']}" if field.get("embedded") else f"field {field['name']} {field['type']}"
+        children.append((label, None))
+    if not nested:
+        # Go interfaces list their methods as signatures rather than as separate symbols
+        children.extend((f"method {signature}", None) for signature in symbol.get("methods") or [])
+    children.extend((None, child) for child in nested)
+    return children
+
+
+def _by_file(symbols: Sequence[Dict[str, Any]], sort: bool = False) -> Dict[str, List[Dict[str, Any]]]:
+    by_file: Dict[str, List[Dict[str, Any]]] = {}
+    for symbol in _ordered(symbols, sort):
+        by_file.setdefault(symbol.get("file") or ".", []).append(symbol)
+    # Kind order interleaves files, so files are put back in path order
+    return dict(sorted(by_file.items())) if sort else by_file
+
+
+def _nest_by_parent(file_symbols: List[Dict[str, Any]]) -> Tuple[List[Dict[str, Any]], Dict[int, List[Dict[str, Any]]]]:
+    """Splits one file's symbols into top-level ones and the children of each owner, keyed by its ``id()``."""
+    # Rust impl blocks are containers for methods the type already groups
+    type_names = {s.get("node_path") or s.get("name") for s in file_symbols if s.get("type") != "impl"}
+    file_symbols = [s for s in file_symbols if s.get("type") != "impl" or s.get("name") not in type_names]
+    owners = {}
+    for symbol in file_symbols:
+        owners.setdefault(symbol.get("node_path") or symbol.get("name"), symbol)
+    nested: Dict[int, List[Dict[str, Any]]] = {}
+    roots = []
+    for symbol in file_symbols:
+        owner = owners.get(symbol.get("parent")) if symbol.get("parent") else None
+        if owner is not None and owner is not symbol:
+            nested.setdefault(id(owner), []).append(symbol)
+        else:
+            roots.append(symbol)
+    return roots, nested
+
+
+def render_tree(symbols: Sequence[Dict[str, Any]], positions: bool = False, sort: bool = False) -> str:
+    """
+    Renders symbols as an indented outline per file, in the style of the ``tree`` command.
+
+    Types come with their fields and methods nested beneath them; methods are
+    matched to their type by ``parent`` (Go receivers, Rust impl blocks,
+    Python classes), and nested classes nest in turn. Functions and other
+    symbols are listed flat, in source order. Methods whose type is not among
+    the symbols stay at the top level under their qualified name.
+    With *positions*, each symbol is followed by its ``file:line:column``.
+    """
+    blocks = []
+    for file, file_symbols in _by_file(symbols, sort).items():
+        roots, nested = _nest_by_parent(file_symbols)
+        lines = [file]
+
+        def label(symbol: Dict[str, Any], qualified: bool) -> str:
+            name = (symbol.get("node_path") if qualified else None) or symbol.get("name")
+            text = f"{symbol.get('type')} {name}"
+            return f"{text}  {_location(symbol, True)}" if positions else text
+
+        def add(children: List[tuple], prefix: str) -> None:
+            for i, (text, symbol) in enumerate(children):
+                last = i == len(children) - 1
+                # Top-level entries keep qualified names so orphaned methods show their type
+                shown = text if symbol is None else label(symbol, qualified=not prefix)
+                lines.append(f"{prefix}{'└── ' if last else '├── '}{shown}")
+                if symbol is not None:
+                    add(_tree_children(symbol, nested.get(id(symbol), [])), prefix + ("    " if last else "│   "))
+
+        add([(None, root) for root in roots], "")
+        blocks.append("\n".join(lines))
+    return "\n\n".join(blocks)
+
+
+# LSP SymbolKind numbers for our symbol types. Types not listed are reported as Variable (13).
+LSP_SYMBOL_KINDS: Dict[str, int] = {
+    "module": 2,
+    "namespace": 3,
+    "package": 4,
+    "class": 5,
+    "impl": 5,
+    "method": 6,
+    "property": 7,
+    "field": 8,
+    "constructor": 9,
+    "enum": 10,
+    "interface": 11,
+    "trait": 11,
+    "function": 12,
+    "macro": 12,
+    "variable": 13,
+    "static": 13,
+    "constant": 14,
+    "struct": 23,
+    "type": 23,
+    "union": 23,
+    "type_parameter": 26,
+}
+_LSP_VARIABLE = 13
+
+
+def _utf16_len(text: str) -> int:
+    # LSP counts characters in UTF-16 code units unless the client negotiates otherwise
+    return len(text.encode("utf-16-le")) // 2
+
+
+def _lsp_range(start_line: int, start_character: int, end_line: int, end_character: int) -> Dict[str, Any]:
+    return {
+        "start": {"line": start_line, "character": start_character},
+        "end": {"line": end_line, "character": end_character},
+    }
+
+
+def _find_word(lines: List[str], word: str, cursor: Tuple[int, int]) -> Optional[Tuple[int, int]]:
+    """The (line, index) of the first whole-word *word* in *lines* at or after *cursor*."""
+    pattern = re.compile(rf"(?<![\w.]){re.escape(word)}(?!\w)")
+    for i in range(cursor[0], len(lines)):
+        match = pattern.search(lines[i], cursor[1] if i == cursor[0] else 0)
+        if match:
+            return i, match.start()
+    return None
+
+
+class _LspSource:
+    """Maps offsets in a symbol's ``code`` to LSP positions in its file."""
+
+    def __init__(self, symbol: Dict[str, Any]) -> None:
+        self.lines = (symbol.get("code") or "").splitlines() or [""]
+        self.line = symbol.get("start_line", 0)
+        self.column = symbol.get("start_column", 0)
+
+    def position(self, index: int, column: int) -> Tuple[int, int]:
+        prefix = self.lines[index][:column]
+        return self.line + index, _utf16_len(prefix) + (self.column if index == 0 else 0)
+
+    def member(self, name: str, kind: int, detail: str, cursor: Tuple[int, int]) -> Tuple[Dict[str, Any], Tuple[int, int]]:
+        """A child DocumentSymbol for a member found by name after *cursor*, and the cursor past it."""
+        found = _find_word(self.lines, name, cursor)
+        if found is None:
+            line, character = self.position(0, 0)
+            full = selection = _lsp_range(line, character, line, character)
+        else:
+            index, column = found
+            line, character = self.position(index, column)
+            selection = _lsp_range(line, character, line, character + _utf16_len(name))
+            end_line, end_character = self.position(index, len(self.lines[index].rstrip()))
+            full = _lsp_range(line, character, end_line, end_character)
+            cursor = (index, column + len(name))
+        entry = {"name": name, "detail": detail, "kind": kind, "range": full, "selectionRange": selection}
+        return entry, cursor
+
+
+def _document_symbol(symbol: Dict[str, Any], nested: Dict[int, List[Dict[str, Any]]]) -> Dict[str, Any]:
+    source = _LspSource(symbol)
+    start_line, start_character = source.position(0, 0)
+    end_line = symbol.get("end_line", start_line)
+    if end_line - start_line + 1 == len(source.lines):
+        end_line, end_character = source.position(len(source.lines) - 1, len(source.lines[-1]))
+    else:
+        end_character = symbol.get("end_column", 0)
+    name = symbol.get("name") or ""
+    found = _find_word(source.lines[:1], name, (0, 0))
+    if found is not None:
+        line, character = source.position(*found)
+        selection = _lsp_range(line, character, line, character + _utf16_len(name))
+    else:
+        selection = _lsp_range(start_line, start_character, start_line, start_character)
+    # The range covers the doc comment too, as editors fold and highlight them together
+    doc_start = symbol.get("doc_start_line")
+    if doc_start is not None and doc_start < start_line:
+        start_line, start_character = doc_start, 0
+
+    children = []
+    cursor = (1, 0)
+    for field in symbol.get("fields") or []:
+        # Embedded fields are spelled by their type; named ones by name
+        word = field["type"].lstrip("*").split("[")[0] if field.get("embedded") else field["name"]
+        child, cursor = source.member(word, LSP_SYMBOL_KINDS["field"], field["type"], cursor)
+        child["name"] = field["name"]
+        children.append(child)
+    members = nested.get(id(symbol), [])
+    if not members:
+        # Go interfaces list their methods as signatures rather than as separate symbols
+        for signature in symbol.get("methods") or []:
+            method_name = signature.split("(", 1)[0].strip()
+            child, cursor = source.member(method_name, LSP_SYMBOL_KINDS["method"], signature, cursor)
+            children.append(child)
+    children.extend(_document_symbol(member, nested) for member in members)
+
+    entry = {
+        "name": name,
+        "detail": symbol.get("signature") or "",
+        "kind": LSP_SYMBOL_KINDS.get(symbol.get("type") or "", _LSP_VARIABLE),
+        "range": _lsp_range(start_line, start_character, end_line, end_character),
+        "selectionRange": selection,
+    }
+    if children:
+        entry["children"] = children
+    return entry
+
+
+def symbols_to_lsp(symbols: Sequence[Dict[str, Any]], positions: bool = False, sort: bool = False) -> str:
+    """
+    Serializes symbols as LSP ``DocumentSymbol`` JSON, the result of ``textDocument/documentSymbol``.
+
+    Methods nest under their type and Go struct fields and interface methods
+    under theirs, as in :func:`render_tree`. ``kind`` follows
+    :data:`LSP_SYMBOL_KINDS`; ``detail`` is the signature. Positions are 0-based
+    lines and UTF-16 characters, and ``range`` includes the doc comment. Symbols
+    of a single file give a list that can be returned to the client as is;
+    symbols of several files give an object mapping each file to its list.
+    *positions* is accepted for symmetry with the other formatters; LSP output
+    always carries positions.
+    """
+    documents = {}
+    for file, file_symbols in _by_file(symbols, sort).items():
+        roots, nested = _nest_by_parent(file_symbols)
+        documents[file] = [_document_symbol(root, nested) for root in roots]
+    if len(documents) <= 1:
+        return json.dumps(next(iter(documents.values()), []), indent=2)
+    return json.dumps(documents, indent=2)
+
+
+def symbols_to_dot(symbols: Sequence[Dict[str, Any]], positions: bool = False, sort: bool = False) -> str:
+    """
+    Renders the Go structs and interfaces among *symbols* as a Graphviz digraph of implements and embeds edges.
+
+    See :func:`~codekite.type_analyzer.render_dot`. Nodes and edges are always
+    ordered by name, so *positions* and *sort* are accepted for symmetry and ignored.
+    """
+    # The implements edges parse method signatures, which needs the tree-sitter extractor
+    from .type_analyzer import render_dot
+
+    return render_dot(list(symbols))
+
+
+def symbols_to_ctags(symbols: Sequence[Dict[str, Any]], positions: bool = False, sort: bool = False) -> str:
+    """
+    Renders *symbols* as a ctags ``tags`` file, see :mod:`codekite.ctags`.
+
+    Tag lines are always sorted bytewise, as the format requires, so *positions* and *sort* are ignored.
+    """
+    # Tags name the language, which comes from the extension registry
+    from .ctags import render_tags
+
+    return render_tags(list(symbols)).rstrip("\n")
+
+
+FORMATTERS = {
+    "text": symbols_to_text,
+    "table": symbols_to_table,
+    "names": symbols_to_names,
+    "json": symbols_to_json,
+    "yaml": symbols_to_yaml,
+    "markdown": render_markdown,
+    "tree": render_tree,
+    "lsp": symbols_to_lsp,
+    "dot": symbols_to_dot,
+    "ctags": symbols_to_ctags,
+}
+
+
+def format_symbols(
+    symbols: Sequence[Dict[str, Any]], output_format: str = "text", positions: bool = False, sort: bool = False
+) -> str:
+    """
+    Renders symbols in the requested output format.
+
+    *positions* adds column and doc-comment positions (see :data:`POSITION_FIELDS`).
+    *sort* orders symbols by kind, receiver and name (see :func:`sort_symbols`)
+    instead of by source position; formats that group by file keep files in path order.
+
+    Raises:
+        ValueError: If *output_format* is not one of :data:`FORMATTERS`.
+    """
+    formatter = FORMATTERS.get(output_format)
+    if formatter is None:
+        raise ValueError(f"Unsupported output format: {output_format}. Choose from: {', '.join(FORMATTERS)}")
+    return formatter(symbols, positions=positions, sort=sort)
@@ -0,0 +1,135 @@
+"""fzf-style fuzzy matching of symbol names."""
+
+from __future__ import annotations
+from dataclasses import dataclass
+from typing import Any, Dict, Iterable, List, Optional, Tuple
+
+SCORE_MATCH = 16
+# Bonuses for where a query character lands in the candidate
+BONUS_START = 16
+BONUS_BOUNDARY = 12
+BONUS_CONSECUTIVE = 6
+# The whole query is a prefix of the candidate
+BONUS_PREFIX = 24
+PENALTY_GAP = 1
+
+_SEPARATORS = frozenset("_-.:/$ ")
+
+
+@dataclass(frozen=True)
+class ScoredSymbol:
+    """A symbol matched by :func:`fuzzy_search` and its score; higher is better."""
+
+    score: int
+    symbol: Dict[str, Any]
+
+
+def _bonus_at(name: str, i: int) -> int:
+    """Bonus for matching position *i*: word starts after separators, camelCase humps and digit runs."""
+    if i == 0:
+        return BONUS_START
+    char, prev = name[i], name[i - 1]
+    if prev in _SEPARATORS and char not in _SEPARATORS:
+        return BONUS_BOUNDARY
+    if char.isupper() and (prev.islower() or prev.isdigit()):
+        return BONUS_BOUNDARY
+    if char.isupper() and prev.isupper() and i + 1 < len(name) and name[i + 1].islower():
+        # The S in HTTPServer starts a word
+        return BONUS_BOUNDARY
+    if char.isdigit() and not prev.isdigit():
+        return BONUS_BOUNDARY
+    return 0
+
+
+def fuzzy_score(query: str, candidate: str, case_sensitive: Optional[bool] = None) -> Optional[int]:
+    """
+    Scores *candidate* against *query*, or returns None if the query is not a subsequence of it.
+
+    Matching is smart-case by default: an all-lowercase query matches case-insensitively,
+    any uppercase letter makes it case-sensitive. Every matched character scores,
+    with bonuses for landing on word boundaries (``HF`` in ``HelperFunction``)
+    and for runs of consecutive characters, and a small penalty per skipped character.
+    """
+    if not query:
+        return 0
+    if case_sensitive is None:
+        case_sensitive = query != query.lower()
+    q = query if case_sensitive else query.lower()
+    c = candidate if case_sensitive else candidate.lower()
+    n, m = len(q), len(c)
+    if n > m:
+        return None
+
+    # Each query character can only land between its earliest and latest feasible positions
+    lows, pos = [], -1
+    for char in q:
+        pos = c.find(char, pos + 1)
+        if pos < 0:
+            return None
+        lows.append(pos)
+    highs, pos = [0] * n, m
+    for i in range(n - 1, -1, -1):
+        pos = c.rfind(q[i], 0, pos)
+        highs[i] = pos
+
+    # (position, best score with the query so far ending there), over the feasible occurrences
+    best: List[Tuple[int, int]] = []
+    for i, char in enumerate(q):
+        current = []
+        j = lows[i]
+        while 0 <= j <= highs[i]:
+            if i == 0:
+                score = -PENALTY_GAP * j
+            else:
+                score = None
+                for k, prev in best:
+                    if k >= j:
+                        break
+                    step = prev + BONUS_CONSECUTIVE if k == j - 1 else prev - PENALTY_GAP * (j - k - 1)
+                    if score is None or step > score:
+                        score = step
+            if score is not None:
+                current.append((j, score + SCORE_MATCH + _bonus_at(candidate, j)))
+            j = c.find(char, j + 1)
+        best = current
+
+    score = max(s for _, s in best)
+    if c.startswith(q):
+        score += BONUS_PREFIX
+    return score
+
+
+def _is_subsequence(query: str, candidate: str) -> bool:
+    """True if the characters of *query* appear in *candidate* in order, not necessarily adjacent."""
+    i = 0
+    for char in query:
+        i = candidate.find(char, i) + 1
+        if i == 0:
+            return False
+    return True
+
+
+def fuzzy_search(symbols: Iterable[Dict[str, Any]], query: str, limit: int = 20) -> List[ScoredSymbol]:
+    """
+    Ranks symbols whose names fuzzily match *query*, best first.
+
+    Ties are broken by shorter name, then by file and line. Names the query is
+    not a subsequence of are rejected by a linear scan before any scoring, so
+    large indexes stay fast.
+    """
+    if not query:
+        return []
+    case_sensitive = query != query.lower()
+    q = query if case_sensitive else query.lower()
+    scored = []
+    for symbol in symbols:
+        name = symbol.get("name") or ""
+        if not _is_subsequence(q, name if case_sensitive else name.lower()):
+            continue
+        score = fuzzy_score(query, name, case_sensitive)
+        if score is not None:
+            scored.append(ScoredSymbol(score, symbol))
+    scored.sort(
+        key=lambda s: (-s.score, len(s.symbol.get("name") or ""), s.symbol.get("file") or "", s.symbol.get("start_line", 0))
+    )
+    return scored[:limit] if limit is not None else scored
@@ -0,0 +1,154 @@
+"""Reading the checked-out commit, branch and remote of a git repository from its metadata files."""
+
+from __future__ import annotations
+import re
+from dataclasses import asdict, dataclass
+from pathlib import Path
+from typing import Dict, Optional, Tuple
+from urllib.parse import urlsplit, urlunsplit
+
+_SECTION = re.compile(r'^\[\s*([^\s\]"]+)(?:\s+"((?:[^"\\]|\\.)*)")?\s*\]')
+# Symbolic refs pointing at symbolic refs are rare; this only guards against loops
+_MAX_REF_DEPTH = 5
+
+
+class NotGitRepositoryError(Exception):
+    """Raised when a path is not inside a git checkout."""
+
+
+@dataclass(frozen=True)
+class GitInfo:
+    """
+    Where a checkout's files came from.
+
+    Attributes:
+        sha: The full commit SHA of HEAD; empty before the first commit.
+        branch: The checked-out branch, e.g. ``"main"``; empty on a detached HEAD.
+        remote_url: The URL of ``origin`` (or the first remote), without any
+            credentials embedded in it; empty when there is no remote.
+    """
+
+    sha: str
+    branch: str
+    remote_url: str
+
+    def to_dict(self) -> Dict[str, str]:
+        return asdict(self)
+
+
+def git_dirs(path: str) -> Tuple[Path, Path]:
+    """
+    Finds the git directory for *path* and the common directory its refs and config live in.
+
+    The search walks up from *path* like git does. A ``.git`` file, as used by
+    worktrees and submodules, is followed to the directory it names; for a
+    worktree the common directory is the main repository's ``.git``.
+
+    Raises:
+        NotGitRepositoryError: If neither *path* nor any parent has a ``.git``.
+    """
+    start = Path(path).resolve()
+    for directory in (start, *start.parents):
+        dot_git = directory / ".git"
+        if dot_git.is_dir():
+            git_dir = dot_git
+        elif dot_git.is_file():
+            content = dot_git.read_text(encoding="utf-8").strip()
+            if not content.startswith("gitdir:"):
+                raise NotGitRepositoryError(f"{dot_git} does not point at a git directory")
+            git_dir = (directory / content[len("gitdir:") :].strip()).resolve()
+        else:
+            continue
+        if not (git_dir / "HEAD").is_file():
+            raise NotGitRepositoryError(f"{git_dir} is not a git directory")
+        common_dir = git_dir
+        commondir_file = git_dir / "commondir"
+        if commondir_file.is_file():
+            common_dir = (git_dir / commondir_file.read_text(encoding="utf-8").strip()).resolve()
+        return git_dir, common_dir
+    raise NotGitRepositoryError(f"{start} is not inside a git repository")
+
+
+def _resolve_ref(git_dir: Path, common_dir: Path, ref: str) -> str:
+    """Returns the SHA *ref* points at, or "" for a branch without commits."""
+    for _ in range(_MAX_REF_DEPTH):
+        target = None
+        # Per-worktree refs live in the worktree's own directory, shared ones in the common one
+        for directory in (git_dir, common_dir):
+            ref_file = directory / ref
+            if ref_file.is_file():
+                target = ref_file.read_text(encoding="utf-8").strip()
+                break
+        if target is None:
+            return _packed_ref(common_dir, ref)
+        if not target.startswith("ref:"):
+            return target
+        ref = target[len("ref:") :].strip()
+    return ""
+
+
+def _packed_ref(common_dir: Path, ref: str) -> str:
+    packed = common_dir / "packed-refs"
+    if not packed.is_file():
+        return ""
+    for line in packed.read_text(encoding="utf-8").splitlines():
+        # "# pack-refs with: ..." headers and "^<sha>" peeled tag lines
+        if line.startswith(("#", "^")):
+            continue
+        sha, _, name = line.partition(" ")
+        if name.strip() == ref:
+            return sha
+    return ""
+
+
+def _remote_urls(config_text: str) -> Dict[str, str]:
+    """The ``url`` of every ``[remote "name"]`` section, in file order."""
+    urls: Dict[str, str] = {}
+    remote: Optional[str] = None
+    for raw in config_text.splitlines():
+        line = raw.strip()
+        section = _SECTION.match(line)
+        if section:
+            remote = section.group(2) if section.group(1).lower() == "remote" else None
+            continue
+        if remote is None or "=" not in line:
+            continue
+        key, _, value = line.partition("=")
+        if key.strip().lower() == "url" and remote not in urls:
+            urls[remote] = value.strip().strip('"')
+    return urls
+
+
+def _without_credentials(url: str) -> str:
+    """Drops ``user:token@`` from http(s) URLs, such as the ones private clones are made with."""
+    parts = urlsplit(url)
+    if parts.scheme not in ("http", "https") or "@" not in parts.netloc:
+        return url
+    return urlunsplit(parts._replace(netloc=parts.netloc.rsplit("@", 1)[1]))
+
+
+def read_git_info(path: str) -> GitInfo:
+    """
+    Reads HEAD, its branch and the remote URL of the checkout containing *path*.
+
+    Only metadata files are read, so the ``git`` executable is not needed.
+
+    Raises:
+        NotGitRepositoryError: If *path* is not inside a git checkout.
+    """
+    git_dir, common_dir = git_dirs(path)
+    head = (git_dir / "HEAD").read_text(encoding="utf-8").strip()
+    if head.startswith("ref:"):
+        ref = head[len("ref:") :].strip()
+        sha = _resolve_ref(git_dir, common_dir, ref)
+        branch = ref[len("refs/heads/") :] if ref.startswith("refs/heads/") else ""
+    else:
+        sha, branch = head, ""
+
+    remote_url = ""
+    config = common_dir / "config"
+    if config.is_file():
+        urls = _remote_urls(config.read_text(encoding="utf-8"))
+        url = urls.get("origin") or next(iter(urls.values()), "")
+        remote_url = _without_credentials(url)
+    return GitInfo(sha=sha, branch=branch, remote_url=remote_url)
@@ -0,0 +1,304 @@
+"""Go build constraints: deciding which .go files ``go build`` would compile for a target."""
+
+from __future__ import annotations
+import itertools
+import os
+import platform
+import re
+from dataclasses import dataclass, field
+from typing import Callable, FrozenSet, List, Optional, Tuple
+
+# Values accepted as a GOOS / GOARCH filename suffix; other suffixes are just part of the name.
+KNOWN_OS = frozenset(
+    {
+        "aix", "android", "darwin", "dragonfly", "freebsd", "hurd", "illumos", "ios", "js", "linux", "nacl",
+        "netbsd", "openbsd", "plan9", "solaris", "wasip1", "windows", "zos",
+    }
+)
+KNOWN_ARCH = frozenset(
+    {
+        "386", "amd64", "amd64p32", "arm", "armbe", "arm64", "arm64be", "loong64", "mips", "mipsle", "mips64",
+        "mips64le", "mips64p32", "mips64p32le", "ppc", "ppc64", "ppc64le", "riscv", "riscv64", "s390", "s390x",
+        "sparc", "sparc64", "wasm",
+    }
+)
+UNIX_OS = frozenset(
+    {"aix", "android", "darwin", "dragonfly", "freebsd", "hurd", "illumos", "ios", "linux", "netbsd", "openbsd", "solaris"}
+)
+# Latest go1.N release tag satisfied by default, as by a current toolchain
+GO_RELEASE = 22
+
+_HOST_ARCH = {"x86_64": "amd64", "amd64": "amd64", "aarch64": "arm64", "arm64": "arm64", "i386": "386", "i686": "386"}
+
+
+class ConstraintError(ValueError):
+    """Raised for a malformed ``//go:build`` expression."""
+
+
+def _tokenize(expr: str) -> List[str]:
+    tokens = re.findall(r"\|\||&&|!|\(|\)|[\w.]+|\S", expr)
+    for token in tokens:
+        if not re.fullmatch(r"\|\||&&|!|\(|\)|[\w.]+", token):
+            raise ConstraintError(f"unexpected {token!r} in build constraint: {expr}")
+    return tokens
+
+
+def parse_build_expr(expr: str) -> Callable[[Callable[[str], bool]], bool]:
+    """
+    Parses a ``//go:build`` expression such as ``linux && (amd64 || arm64) && !purego``.
+
+    Returns a function that evaluates the expression given a predicate telling
+    whether a tag is satisfied. ``&&`` binds tighter than ``||``, as in Go.
+    """
+    tokens = _tokenize(expr)
+    pos = 0
+
+    def peek() -> Optional[str]:
+        return tokens[pos] if pos < len(tokens) else None
+
+    def advance() -> str:
+        nonlocal pos
+        if pos >= len(tokens):
+            raise ConstraintError(f"unexpected end of build constraint: {expr}")
+        pos += 1
+        return tokens[pos - 1]
+
+    def parse_or() -> Callable[[Callable[[str], bool]], bool]:
+        terms = [parse_and()]
+        while peek() == "||":
+            advance()
+            terms.append(parse_and())
+        return terms[0] if len(terms) == 1 else (lambda ok: any(t(ok) for t in terms))
+
+    def parse_and() -> Callable[[Callable[[str], bool]], bool]:
+        terms = [parse_not()]
+        while peek() == "&&":
+            advance()
+            terms.append(parse_not())
+        return terms[0] if len(terms) == 1 else (lambda ok: all(t(ok) for t in terms))
+
+    def parse_not() -> Callable[[Callable[[str], bool]], bool]:
+        token = advance()
+        if token == "!":
+            inner = parse_not()
+            return lambda ok: not inner(ok)
+        if token == "(":
+            inner = parse_or()
+            if advance() != ")":
+                raise ConstraintError(f"missing ) in build constraint: {expr}")
+            return inner
+        if token in ("||", "&&", ")"):
+            raise ConstraintError(f"unexpected {token!r} in build constraint: {expr}")
+        return lambda ok: ok(token)
+
+    result = parse_or()
+    if pos != len(tokens):
+        raise ConstraintError(f"unexpected {tokens[pos]!r} in build constraint: {expr}")
+    return result
+
+
+def parse_plus_build(line: str) -> Callable[[Callable[[str], bool]], bool]:
+    """
+    Parses the options of one legacy ``// +build`` line.
+
+    Space-separated options are ORed, comma-separated terms within an option
+    are ANDed, and ``!`` negates a term: ``linux,386 darwin,!cgo``.
+    """
+    options = []
+    for option in line.split():
+        terms = []
+        for term in option.split(","):
+            negated = term.startswith("!")
+            name = term[1:] if negated else term
+            if not name or not re.fullmatch(r"[\w.]+", name):
+                raise ConstraintError(f"invalid term {term!r} in +build line: {line}")
+            terms.append((name, negated))
+        options.append(terms)
+    return lambda ok: any(all(ok(name) != negated for name, negated in terms) for terms in options)
+
+
+def _header_constraints(source: str) -> List[str]:
+    """Returns the comment lines before the package clause that can hold build constraints."""
+    lines = []
+    in_block = False
+    for raw in source.splitlines():
+        line = raw.strip()
+        if in_block:
+            if "*/" in line:
+                in_block = False
+            continue
+        if not line:
+            continue
+        if line.startswith("//"):
+            lines.append(line)
+            continue
+        if line.startswith("/*"):
+            in_block = "*/" not in line
+            continue
+        # The first non-comment line (normally `package x`) ends the header
+        break
+    return lines
+
+
+def _filename_terms(filename: str) -> List[str]:
+    """The tags a ``_GOOS``, ``_GOARCH`` or ``_GOOS_GOARCH`` filename suffix requires, as in :meth:`BuildContext.match_filename`."""
+    stem = os.path.basename(filename).split(".", 1)[0]
+    if stem.endswith("_test"):
+        stem = stem[: -len("_test")]
+    parts = stem.split("_")[1:]
+    if len(parts) >= 2 and parts[-2] in KNOWN_OS and parts[-1] in KNOWN_ARCH:
+        return parts[-2:]
+    if parts and (parts[-1] in KNOWN_OS or parts[-1] in KNOWN_ARCH):
+        return parts[-1:]
+    return []
+
+
+def file_constraint(filename: str, source: str) -> Optional[str]:
+    """
+    Returns the build constraint of a Go file as one ``//go:build`` expression, or None if it has none.
+
+    Filename suffixes become terms (``sys_linux_arm64.go`` gives
+    ``linux && arm64``) ANDed with the ``//go:build`` line, or with the legacy
+    ``// +build`` lines rewritten in ``//go:build`` syntax when there is no
+    such line. Expressions are kept as written, not validated.
+    """
+    terms = _filename_terms(filename)
+    header = None
+    plus_build = []
+    for line in _header_constraints(source):
+        if re.match(r"//go:build(\s|$)", line):
+            header = line[len("//go:build"):].strip()
+            break
+        body = line[2:].strip()
+        if re.match(r"\+build(\s|$)", body):
+            options = [" && ".join(option.split(",")) for option in body[len("+build"):].split()]
+            plus_build.append(" || ".join(f"({o})" if "&&" in o and len(options) > 1 else o for o in options))
+    expressions = [header] if header else [e for e in plus_build if e]
+    if len(terms) + len(expressions) > 1:
+        expressions = [f"({e})" if "||" in e else e for e in expressions]
+    combined = " && ".join(terms + expressions)
+    return combined or None
+
+
+@dataclass
+class BuildContext:
+    """
+    Target platform and tags for evaluating Go build constraints, like ``go/build.Context``.
+
+    Attributes:
+        goos: Target operating system, e.g. ``"linux"``.
+        goarch: Target architecture, e.g. ``"amd64"``.
+        tags: Extra tags, as passed to ``go build -tags``.
+        cgo_enabled: Whether the ``cgo`` tag is satisfied.
+        compiler: Toolchain tag, ``"gc"`` or ``"gccgo"``.
+        go_release: Highest ``go1.N`` release tag satisfied.
+    """
+
+    goos: str = "linux"
+    goarch: str = "amd64"
+    tags: List[str] = field(default_factory=list)
+    cgo_enabled: bool = False
+    compiler: str = "gc"
+    go_release: int = GO_RELEASE
+
+    @classmethod
+    def host(cls, tags: Optional[List[str]] = None) -> "BuildContext":
+        """Returns a context for the machine codekite runs on, like ``go build`` without GOOS/GOARCH set."""
+        system = platform.system().lower()
+        goos = system if system in KNOWN_OS else "linux"
+        goarch = _HOST_ARCH.get(platform.machine().lower(), "amd64")
+        return cls(goos=os.environ.get("GOOS", goos), goarch=os.environ.get("GOARCH", goarch), tags=list(tags or []))
+
+    def satisfied_tags(self) -> FrozenSet[str]:
+        tags = {self.goos, self.goarch, self.compiler, *self.tags}
+        tags.update(f"go1.{n}" for n in range(1, self.go_release + 1))
+        if self.cgo_enabled:
+            tags.add("cgo")
+        if self.goos in UNIX_OS:
+            tags.add("unix")
+        # GOOS values that imply another one, as in go/build
+        if self.goos == "android":
+            tags.add("linux")
+        elif self.goos == "illumos":
+            tags.add("solaris")
+        elif self.goos == "ios":
+            tags.add("darwin")
+        return frozenset(tags)
+
+    def match_filename(self, filename: str) -> bool:
+        """
+        Applies the ``_GOOS``, ``_GOARCH`` and ``_GOOS_GOARCH`` filename suffix rules.
+
+        Files whose names start with ``_`` or ``.`` are never built.
+        """
+        name = os.path.basename(filename)
+        if name.startswith(("_", ".")):
+            return False
+        satisfied = self.satisfied_tags()
+        return all(term == self.goarch if term in KNOWN_ARCH else term in satisfied for term in _filename_terms(name))
+
+    def match_source(self, filename: str, source: str) -> bool:
+        """
+        Tells whether ``go build`` would compile a file, mirroring ``go/build.Context.MatchFile``.
+
+        The filename rules apply first. Then a ``//go:build`` line in the file
+        header decides; only when there is none are the legacy ``// +build``
+        lines used, all of which must be satisfied.
+
+        Raises:
+            ConstraintError: If a constraint line is malformed.
+        """
+        if not self.match_filename(filename):
+            return False
+        satisfied = self.satisfied_tags().__contains__
+        plus_build = []
+        for line in _header_constraints(source):
+            if re.match(r"//go:build(\s|$)", line):
+                return parse_build_expr(line[len("//go:build"):].strip())(satisfied)
+            body = line[2:].strip()
+            if re.match(r"\+build(\s|$)", body):
+                plus_build.append(body[len("+build"):])
+        return all(parse_plus_build(line)(satisfied) for line in plus_build)
+
+
+def _constraint_tags(filename: str, source: str) -> FrozenSet[str]:
+    """Every tag a file's name suffix or header constraint lines mention."""
+    tags = set(_filename_terms(filename))
+    for line in _header_constraints(source):
+        body = line[2:].strip()
+        if re.match(r"//go:build(\s|$)", line) or re.match(r"\+build(\s|$)", body):
+            tags.update(re.findall(r"[\w.]+", re.sub(r"^(go:build|\+build)", "", body)))
+    return frozenset(t for t in tags if t)
+
+
+def constraints_disjoint(a: Tuple[str, str], b: Tuple[str, str], max_tags: int = 10) -> bool:
+    """
+    Tells whether no build target compiles both of two Go files.
+
+    Each file is given as ``(filename, source)``. Every combination of the
+    GOOS, GOARCH, cgo, compiler and custom tags the two files mention is tried,
+    plus an OS and an architecture neither mentions, so ``foo_linux.go`` and
+    ``foo_windows.go``, or ``//go:build purego`` and ``//go:build !purego``,
+    are disjoint. With more than *max_tags* custom tags, or a malformed
+    constraint, the answer is False, as is safest for callers reporting clashes.
+    """
+    mentioned = _constraint_tags(*a) | _constraint_tags(*b)
+    custom = sorted(t for t in mentioned - KNOWN_OS - KNOWN_ARCH - {"unix", "cgo", "gc", "gccgo"} if not t.startswith("go1."))
+    if len(custom) > max_tags:
+        return False
+    # One unmentioned OS of each family stands for all the others
+    spare_os = [min(UNIX_OS - mentioned, default=None), min(KNOWN_OS - UNIX_OS - mentioned, default=None)]
+    operating_systems = sorted(mentioned & KNOWN_OS) + [os_name for os_name in spare_os if os_name]
+    architectures = sorted(mentioned & KNOWN_ARCH) + [min(KNOWN_ARCH - mentioned, default="amd64")]
+    try:
+        for goos, goarch, cgo, compiler in itertools.product(
+            operating_systems, architectures, (False, True), ("gc", "gccgo")
+        ):
+            for chosen in itertools.product((False, True), repeat=len(custom)):
+                tags = [tag for tag, on in zip(custom, chosen) if on]
+                context = BuildContext(goos=goos, goarch=goarch, tags=tags, cgo_enabled=cgo, compiler=compiler)
+                if context.match_source(*a) and context.match_source(*b):
+                    return False
+    except ConstraintError:
+        return False
+    return True
@@ -0,0 +1,188 @@
+// Command resolve_types type-checks the Go package in a directory and prints
+// its functions and methods with signatures as go/types resolves them.
+//
+// codekite runs it for ExtractionOptions.resolve_types:
+//
+//	go run resolve_types.go <dir>
+//
+// Dependencies are loaded from the export data "go list -export" builds, as
+// golang.org/x/tools/go/packages does, so the build cache makes repeated runs
+// cheap. Only the standard library is used. Any load or type error exits
+// non-zero and codekite keeps the syntax-only signatures.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+type function struct {
+	File      string `json:"file"`
+	Line      int    `json:"line"`
+	Name      string `json:"name"`
+	Signature string `json:"signature"`
+	// Named types in the signature whose underlying type is basic, e.g. "time.Duration": "int64"
+	Underlying map[string]string `json:"underlying,omitempty"`
+}
+
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Fprintln(os.Stderr, "usage: resolve_types <dir>")
+		os.Exit(2)
+	}
+	functions, err := resolve(os.Args[1])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := json.NewEncoder(os.Stdout).Encode(functions); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func resolve(dir string) ([]function, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	// ImportDir applies build constraints and leaves out _test.go files, like go build
+	pkgInfo, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, name := range pkgInfo.GoFiles {
+		file, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	importPath, exports, err := listExports(dir)
+	if err != nil {
+		return nil, err
+	}
+	lookup := func(path string) (io.ReadCloser, error) {
+		export, ok := exports[path]
+		if !ok || export == "" {
+			return nil, fmt.Errorf("no export data for %s", path)
+		}
+		return os.Open(export)
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "gc", lookup)}
+	info := &types.Info{Defs: map[*ast.Ident]types.Object{}}
+	pkg, err := conf.Check(importPath, fset, files, info)
+	if err != nil {
+		return nil, err
+	}
+	// The package's own types stay unqualified; everything else gets its import path
+	qualifier := types.RelativeTo(pkg)
+
+	functions := []function{}
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok {
+				continue
+			}
+			obj, ok := info.Defs[fn.Name].(*types.Func)
+			if !ok {
+				continue
+			}
+			sig := obj.Type().(*types.Signature)
+			underlying := map[string]string{}
+			collectUnderlying(sig.Params(), qualifier, underlying)
+			collectUnderlying(sig.Results(), qualifier, underlying)
+			pos := fset.Position(fn.Pos())
+			entry := function{
+				File:      filepath.Base(pos.Filename),
+				Line:      pos.Line,
+				Name:      fn.Name.Name,
+				Signature: signature(obj, sig, qualifier),
+			}
+			if len(underlying) > 0 {
+				entry.Underlying = underlying
+			}
+			functions = append(functions, entry)
+		}
+	}
+	return functions, nil
+}
+
+// listExports returns the import path of the package in dir and the export
+// data file of each of its dependencies.
+func listExports(dir string) (string, map[string]string, error) {
+	cmd := exec.Command("go", "list", "-e", "-export", "-deps", "-f", "{{.ImportPath}}\t{{.Export}}", ".")
+	cmd.Dir = dir
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", nil, fmt.Errorf("go list: %w", err)
+	}
+	exports := map[string]string{}
+	importPath := ""
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		path, export, _ := strings.Cut(line, "\t")
+		exports[path] = export
+		// -deps lists dependencies first and the package itself last
+		importPath = path
+	}
+	return importPath, exports, nil
+}
+
+// signature formats a declaration header: "func (s *Store) Get(key string) (Value, error)".
+func signature(obj *types.Func, sig *types.Signature, qualifier types.Qualifier) string {
+	var b strings.Builder
+	b.WriteString("func ")
+	if recv := sig.Recv(); recv != nil {
+		b.WriteString("(")
+		if recv.Name() != "" && recv.Name() != "_" {
+			b.WriteString(recv.Name() + " ")
+		}
+		b.WriteString(types.TypeString(recv.Type(), qualifier))
+		b.WriteString(") ")
+	}
+	b.WriteString(obj.Name())
+	// TypeString writes "func[T any](x T) T"; the receiver is never part of it
+	b.WriteString(strings.TrimPrefix(types.TypeString(sig, qualifier), "func"))
+	return b.String()
+}
+
+func collectUnderlying(tuple *types.Tuple, qualifier types.Qualifier, out map[string]string) {
+	for i := 0; i < tuple.Len(); i++ {
+		collectType(tuple.At(i).Type(), qualifier, out)
+	}
+}
+
+func collectType(t types.Type, qualifier types.Qualifier, out map[string]string) {
+	switch t := t.(type) {
+	case *types.Named:
+		if basic, ok := t.Underlying().(*types.Basic); ok {
+			out[types.TypeString(t, qualifier)] = basic.Name()
+		}
+	case *types.Pointer:
+		collectType(t.Elem(), qualifier, out)
+	case *types.Slice:
+		collectType(t.Elem(), qualifier, out)
+	case *types.Array:
+		collectType(t.Elem(), qualifier, out)
+	case *types.Map:
+		collectType(t.Key(), qualifier, out)
+		collectType(t.Elem(), qualifier, out)
+	case *types.Chan:
+		collectType(t.Elem(), qualifier, out)
+	}
+}
@@ -0,0 +1,268 @@
+"""Links Go tests, benchmarks and fuzz targets to the functions and methods they exercise."""
+
+from __future__ import annotations
+import logging
+import posixpath
+import re
+from dataclasses import dataclass
+from typing import TYPE_CHECKING, Any, Dict, Iterator, List, Optional, Tuple
+
+from .call_graph import NODE_FUNCTION, NODE_METHOD, CallGraph, _qualify, _walk
+from .import_graph import extract_go_imports, package_import_path
+from .symbol_filter import is_exported
+from .tree_sitter_symbol_extractor import TreeSitterSymbolExtractor, _go_base_type_name, _go_receiver_type, _node_text
+
+if TYPE_CHECKING:
+    from .repository import Repository
+
+logger = logging.getLogger(__name__)
+
+# How a link was found
+LINK_NAME = "name"
+LINK_CALL = "call"
+
+# Test kinds, from the function name prefix; subtests are t.Run calls inside one of them
+_PREFIXES = {"Test": "test", "Benchmark": "benchmark", "Fuzz": "fuzz", "Example": "example"}
+KIND_SUBTEST = "subtest"
+
+# go test only runs TestX when X does not start with a lower-case letter
+_TEST_NAME = re.compile(r"^(Test|Benchmark|Fuzz|Example)(?![a-z])(.*)$")
+_STRING_LITERALS = ("interpreted_string_literal", "raw_string_literal")
+
+
+def is_go_test_file(path: str) -> bool:
+    """True for files ``go test`` compiles only when testing, i.e. names ending in ``_test.go``."""
+    return path.endswith("_test.go")
+
+
+@dataclass(frozen=True)
+class GoTestLink:
+    """
+    A test linked to a function or method it exercises.
+
+    Attributes:
+        test: The test as ``go test -run`` names it: ``TestAdd``, ``TestAdd/negative``
+            for a subtest, or ``CalcSuite.TestAdd`` for a testify suite method.
+        kind: ``"test"``, ``"benchmark"``, ``"fuzz"``, ``"example"`` or ``"subtest"``.
+        file: Repository-relative path of the ``_test.go`` file.
+        line: 1-based line of the test function, or of the ``t.Run`` call for a subtest.
+        symbol: ID of the linked function or method, as in :func:`~codekite.symbol_ids.symbol_id`.
+        via: How the link was found, sorted: ``"call"`` when the test calls the
+            symbol directly, ``"name"`` when the names match (``TestAdd`` and
+            ``Add``, ``TestUser_Greet`` and ``User.Greet``), or both.
+    """
+
+    test: str
+    kind: str
+    file: str
+    line: int
+    symbol: str
+    via: Tuple[str, ...]
+
+    def to_dict(self) -> Dict[str, Any]:
+        return {
+            "test": self.test,
+            "kind": self.kind,
+            "file": self.file,
+            "line": self.line,
+            "symbol": self.symbol,
+            "via": list(self.via),
+        }
+
+
+def _string_value(node: Any) -> Optional[str]:
+    if node is not None and node.type == "literal_element" and node.named_children:
+        node = node.named_children[0]
+    if node is None or node.type not in _STRING_LITERALS:
+        return None
+    return _node_text(node)[1:-1]
+
+
+def _subtest_names(body: Any) -> Iterator[Tuple[str, int]]:
+    """``(name, line)`` for each ``t.Run`` in *body* whose name is a literal or a field of a table literal."""
+    calls = []
+    for node in _walk(body):
+        if node.type != "call_expression":
+            continue
+        function, arguments = node.child_by_field_name("function"), node.child_by_field_name("arguments")
+        if function is None or function.type != "selector_expression" or arguments is None:
+            continue
+        if _node_text(function.child_by_field_name("field")) != "Run" or len(arguments.named_children) != 2:
+            continue
+        if arguments.named_children[1].type != "func_literal":
+            continue
+        calls.append((arguments.named_children[0], node.start_point[0] + 1))
+
+    for name_node, line in calls:
+        literal = _string_value(name_node)
+        if literal is not None:
+            yield literal, line
+        elif name_node.type == "selector_expression":
+            # t.Run(tc.name, ...) over a table: the names are the "name:" values of the table's rows
+            field = _node_text(name_node.child_by_field_name("field"))
+            for node in _walk(body):
+                if node.type == "keyed_element" and len(node.named_children) == 2:
+                    key, value = node.named_children
+                    key = key.named_children[0] if key.type == "literal_element" and key.named_children else key
+                    name = _string_value(value)
+                    if _node_text(key) == field and name is not None:
+                        yield name, line
+
+
+class GoTestLinker:
+    """
+    Links the tests of a repository's ``_test.go`` files to the functions and methods they exercise.
+
+    A test hits a symbol by name when the part of its name after the prefix
+    (``Test``, ``Benchmark``, ``Fuzz``, ``Example``) is the symbol's name,
+    with ``_`` separating a type from its method and anything after a further
+    ``_`` ignored: ``TestAdd``, ``TestAdd_overflow`` and ``FuzzAdd`` link to
+    ``Add``, ``TestUser_Greet`` to ``User.Greet``. ``TestGreet`` links to a
+    method only when no function is called ``Greet`` and one type alone has
+    such a method. Methods named ``Test...`` (testify suites) and subtests
+    named by ``t.Run("Add overflow", ...)``, including the ``name:`` fields of a
+    table the loop runs over, are matched the same way, spaces counting as
+    ``_`` as they do in ``go test`` output.
+
+    A test hits a symbol by call when its body, subtests included, calls the
+    symbol directly, resolved as :class:`~codekite.call_graph.CallGraph`
+    resolves calls. Only symbols of the test file's directory are linked, and
+    external test packages (``package calc_test``) reach them through their
+    import of the package, which needs a go.mod module path.
+    """
+
+    def __init__(self, repository: "Repository"):
+        self.repo = repository
+        self._links: Dict[str, List[GoTestLink]] = {}
+        # Non-test functions and methods by directory, in source order
+        self._symbols: Dict[str, List[Dict[str, Any]]] = {}
+        self._built = False
+
+    def build(self) -> "GoTestLinker":
+        """(Re)reads the repository's Go files; called on first use."""
+        graph = CallGraph(self.repo).build()
+        packages = graph.packages()
+        module_path = graph.module_path()
+        self._links, self._symbols = {}, {}
+        test_files: List[str] = []
+        by_key: Dict[str, Dict[str, Any]] = {}
+        for file in self.repo.mapper.source_files():
+            if file.suffix != ".go":
+                continue
+            rel_path = file.relative_to(self.repo.local_path).as_posix()
+            if is_go_test_file(rel_path):
+                test_files.append(rel_path)
+                continue
+            package = package_import_path(rel_path, module_path)
+            for symbol in self.repo.extract_symbols(rel_path):
+                if symbol.get("type") in (NODE_FUNCTION, NODE_METHOD):
+                    self._symbols.setdefault(posixpath.dirname(rel_path) or ".", []).append(symbol)
+                    by_key[_qualify(package, symbol.get("node_path") or symbol["name"])] = symbol
+
+        parser = TreeSitterSymbolExtractor.get_parser(".go")
+        found: Dict[Tuple[str, str], Dict[str, Any]] = {}
+        for rel_path in test_files:
+            try:
+                source = self.repo.get_file_content(rel_path)
+            except (IOError, UnicodeDecodeError) as e:
+                logger.warning(f"Skipping tests in {rel_path}: {e}")
+                continue
+            package = package_import_path(rel_path, module_path)
+            packages.setdefault(package, {"functions": {}, "types": {}, "methods": set()})
+            imports = CallGraph.import_names(extract_go_imports(source), module_path)
+            candidates = self._symbols.get(posixpath.dirname(rel_path) or ".", [])
+            root = parser.parse(source.encode("utf-8")).root_node
+            for declaration in root.named_children:
+                if declaration.type not in ("function_declaration", "method_declaration"):
+                    continue
+                test = self._test_of(declaration)
+                if test is None:
+                    continue
+                name, kind, remainder = test
+                line = declaration.start_point[0] + 1
+                targets: List[Tuple[str, str, int, Optional[Dict[str, Any]], str]] = [
+                    (name, kind, line, self._match_name(remainder, candidates), LINK_NAME)
+                ]
+                calls = CallGraph.function_calls(declaration, package, packages, imports)
+                for callee, _ in calls[2] if calls is not None else ():
+                    targets.append((name, kind, line, by_key.get(callee), LINK_CALL))
+                body = declaration.child_by_field_name("body")
+                for subtest, subtest_line in _subtest_names(body) if body is not None else ():
+                    subtest = subtest.replace(" ", "_")
+                    targets.append(
+                        (f"{name}/{subtest}", KIND_SUBTEST, subtest_line, self._match_name(subtest, candidates), LINK_NAME)
+                    )
+                for test_name, test_kind, test_line, symbol, via in targets:
+                    if symbol is None:
+                        continue
+                    entry = found.setdefault(
+                        (test_name, symbol["id"]),
+                        {"kind": test_kind, "file": rel_path, "line": test_line, "symbol": symbol["id"], "via": set()},
+                    )
+                    entry["via"].add(via)
+
+        for (test_name, symbol_id), entry in found.items():
+            link = GoTestLink(test_name, entry["kind"], entry["file"], entry["line"], symbol_id, tuple(sorted(entry["via"])))
+            self._links.setdefault(symbol_id, []).append(link)
+        for links in self._links.values():
+            links.sort(key=lambda link: (link.file, link.line, link.test))
+        self._built = True
+        return self
+
+    @staticmethod
+    def _test_of(declaration: Any) -> Optional[Tuple[str, str, str]]:
+        """``(test name, kind, name after the prefix)`` for a test function or suite method, else None."""
+        name = _node_text(declaration.child_by_field_name("name"))
+        match = _TEST_NAME.match(name)
+        if match is None or name == "TestMain":
+            return None
+        if declaration.type == "method_declaration":
+            receiver = _go_receiver_type(declaration)
+            if not receiver or match.group(1) != "Test":
+                return None
+            return f"{_go_base_type_name(receiver)}.{name}", _PREFIXES["Test"], match.group(2)
+        return name, _PREFIXES[match.group(1)], match.group(2)
+
+    @staticmethod
+    def _match_name(remainder: str, candidates: List[Dict[str, Any]]) -> Optional[Dict[str, Any]]:
+        """The symbol the name after a test's prefix refers to, see the class documentation."""
+        remainder = remainder.lstrip("_")
+        if not remainder:
+            return None
+        by_path = {s.get("node_path") or s["name"]: s for s in candidates}
+        head, _, rest = remainder.partition("_")
+        method = rest.partition("_")[0]
+        options = [remainder, f"{head}.{method}" if method else "", head]
+        for option in [o for o in options if o] + [o[0].lower() + o[1:] for o in options if o]:
+            if option in by_path:
+                return by_path[option]
+        methods = [s for s in candidates if s.get("type") == NODE_METHOD and s["name"] == head]
+        return methods[0] if len(methods) == 1 else None
+
+    def _ensure_built(self) -> None:
+        if not self._built:
+            self.build()
+
+    def tests_for(self, symbol: Dict[str, Any]) -> List[GoTestLink]:
+        """Returns the tests linked to *symbol*, a function or method from :meth:`Repository.extract_symbols`."""
+        self._ensure_built()
+        return list(self._links.get(symbol.get("id", ""), ()))
+
+    def untested_symbols(self, package: str = ".") -> List[Dict[str, Any]]:
+        """
+        Returns the exported functions and methods of *package* that no test is linked to, in source order.
+
+        Args:
+            package: The package's directory relative to the repository root, ``"."`` for the root.
+        """
+        self._ensure_built()
+        directory = posixpath.normpath(package.replace("\\", "/").strip("/") or ".")
+        return [
+            s for s in self._symbols.get(directory, []) if is_exported(s, ".go") and s["id"] not in self._links
+        ]
+
+    def links(self) -> List[GoTestLink]:
+        """Returns every link, ordered by test file and line."""
+        self._ensure_built()
+        result = [link for links in self._links.values() for link in links]
+        return sorted(result, key=lambda link: (link.file, link.line, link.test, link.symbol))
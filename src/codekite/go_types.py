@@ -0,0 +1,97 @@
+"""Go signatures with types resolved by the Go toolchain, for ExtractionOptions.resolve_types."""
+
+from __future__ import annotations
+import json
+import logging
+import shutil
+import subprocess
+import threading
+from pathlib import Path
+from typing import Any, Dict, List, Optional, Tuple
+
+logger = logging.getLogger(__name__)
+
+HELPER = Path(__file__).parent / "go_helpers" / "resolve_types.go"
+# A cold build cache compiles the package's dependencies first
+TIMEOUT = 300
+
+# (file name, 1-based line) -> what the helper reported for the function declared there
+_Resolved = Dict[Tuple[str, int], Dict[str, Any]]
+
+
+class GoTypeResolver:
+    """
+    Type-checks Go packages with ``go run`` and the bundled helper, one directory at a time.
+
+    Results are cached per directory until one of its ``.go`` files changes, so
+    a directory walk type-checks each package once. When ``go`` is not
+    installed or the package does not build, :meth:`apply` leaves the symbols'
+    syntax-only signatures in place.
+    """
+
+    def __init__(self) -> None:
+        self._cache: Dict[Path, Tuple[tuple, Optional[_Resolved]]] = {}
+        self._lock = threading.Lock()
+        self._dir_locks: Dict[Path, threading.Lock] = {}
+
+    @staticmethod
+    def _stamp(directory: Path) -> tuple:
+        return tuple(sorted((p.name, p.stat().st_mtime_ns) for p in directory.glob("*.go")))
+
+    def _run(self, directory: Path) -> Optional[_Resolved]:
+        go = shutil.which("go")
+        if go is None:
+            logger.warning("resolve_types needs the go toolchain on PATH; using syntax-only signatures")
+            return None
+        try:
+            result = subprocess.run(
+                [go, "run", str(HELPER), "."],
+                cwd=directory,
+                capture_output=True,
+                text=True,
+                timeout=TIMEOUT,
+            )
+        except (OSError, subprocess.TimeoutExpired) as e:
+            logger.warning(f"Could not resolve Go types in {directory}: {e}")
+            return None
+        if result.returncode != 0:
+            logger.warning(f"Could not resolve Go types in {directory}: {result.stderr.strip()}")
+            return None
+        return {(f["file"], f["line"]): f for f in json.loads(result.stdout)}
+
+    def resolve(self, directory: Path) -> Optional[_Resolved]:
+        """The type-checked functions of the package in *directory*, or None if it could not be loaded."""
+        directory = directory.resolve()
+        with self._lock:
+            dir_lock = self._dir_locks.setdefault(directory, threading.Lock())
+        # Files of one package are often parsed in parallel; only one of them runs the helper
+        with dir_lock:
+            stamp = self._stamp(directory)
+            cached = self._cache.get(directory)
+            if cached is not None and cached[0] == stamp:
+                return cached[1]
+            resolved = self._run(directory)
+            self._cache[directory] = (stamp, resolved)
+            return resolved
+
+    def apply(self, path: Path, symbols: List[Dict[str, Any]]) -> None:
+        """
+        Replaces the signatures of the functions and methods in *symbols*, extracted from *path*, with resolved ones.
+
+        Resolved signatures qualify other packages' types by import path
+        (``*example.com/app/calc.Value``) and spell every parameter's type out.
+        Named types with a basic underlying type are listed in ``underlying``,
+        e.g. ``{"time.Duration": "int64"}``.
+        """
+        resolved = self.resolve(path.parent)
+        if not resolved:
+            return
+        for symbol in symbols:
+            if symbol.get("type") not in ("function", "method"):
+                continue
+            entry = resolved.get((path.name, symbol["start_line"] + 1))
+            if entry is None or entry["name"] != symbol["name"]:
+                continue
+            symbol["signature"] = entry["signature"]
+            if entry.get("underlying"):
+                symbol["underlying"] = entry["underlying"]
@@ -0,0 +1,109 @@
+"""Gitignore-style filtering of repository paths."""
+
+from __future__ import annotations
+import logging
+from pathlib import Path
+from typing import Dict, List, Optional
+
+import pathspec
+
+logger = logging.getLogger(__name__)
+
+# Version-control metadata is never part of the indexed tree, whatever the ignore files say
+ALWAYS_SKIP_DIRS = frozenset({".git", ".hg", ".svn"})
+
+GITIGNORE_FILE = ".gitignore"
+# Same syntax as .gitignore, for paths that should be tracked by git but not indexed
+CODEKITE_IGNORE_FILE = ".codekiteignore"
+
+
+class IgnoreRules:
+    """
+    Decides which paths under a repository root are ignored.
+
+    Ignore files are read from every directory, not just the root, and
+    evaluated the way git does: patterns are relative to the directory holding
+    the file, the last matching pattern wins (so ``!important.log`` re-includes
+    a path), files in deeper directories override shallower ones, and nothing
+    inside an ignored directory can be re-included. Within one directory,
+    ``.codekiteignore`` is applied after ``.gitignore``.
+    """
+
+    def __init__(self, root: Path, respect_gitignore: bool = True) -> None:
+        """
+        Args:
+            root: Repository root; every path passed in must be below it.
+            respect_gitignore: Read ``.gitignore`` files. ``.codekiteignore`` files
+                and the version-control directories are honoured regardless.
+        """
+        self.root = Path(root)
+        self.respect_gitignore = respect_gitignore
+        self._specs: Dict[Path, List[pathspec.PathSpec]] = {}
+        self._ignored_dirs: Dict[Path, bool] = {}
+
+    def _specs_for(self, directory: Path) -> List[pathspec.PathSpec]:
+        specs = self._specs.get(directory)
+        if specs is not None:
+            return specs
+        names = [GITIGNORE_FILE, CODEKITE_IGNORE_FILE] if self.respect_gitignore else [CODEKITE_IGNORE_FILE]
+        specs = []
+        for name in names:
+            ignore_file = directory / name
+            if not ignore_file.is_file():
+                continue
+            try:
+                with open(ignore_file, "r", encoding="utf-8", errors="ignore") as f:
+                    specs.append(pathspec.PathSpec.from_lines("gitwildmatch", f))
+            except OSError as e:
+                logger.warning(f"Could not read {ignore_file}: {e}")
+        self._specs[directory] = specs
+        return specs
+
+    def _match(self, path: Path, is_dir: bool) -> Optional[bool]:
+        """Returns True or False when some ignore file decides *path*, None when no pattern matches it."""
+        decision: Optional[bool] = None
+        directory = self.root
+        parents = path.relative_to(self.root).parts[:-1]
+        for depth in range(len(parents) + 1):
+            if depth:
+                directory = directory / parents[depth - 1]
+            rel_path = path.relative_to(directory).as_posix()
+            # Directory-only patterns such as "build/" need the trailing slash to match
+            if is_dir:
+                rel_path += "/"
+            for spec in self._specs_for(directory):
+                for pattern in spec.patterns:
+                    if pattern.include is not None and pattern.regex.match(rel_path):
+                        decision = pattern.include
+        return decision
+
+    def _is_ignored_dir(self, directory: Path) -> bool:
+        if directory == self.root:
+            return False
+        cached = self._ignored_dirs.get(directory)
+        if cached is None:
+            cached = self.is_ignored(directory, is_dir=True)
+            self._ignored_dirs[directory] = cached
+        return cached
+
+    def is_ignored(self, path: Path, is_dir: Optional[bool] = None) -> bool:
+        """
+        Checks whether *path* is ignored.
+
+        Args:
+            path: Path below the root, absolute or joined onto the root.
+            is_dir: Whether *path* is a directory; looked up on disk when omitted.
+        """
+        path = Path(path)
+        try:
+            parts = path.relative_to(self.root).parts
+        except ValueError:
+            # e.g. a symlink target outside the repository
+            return False
+        if not parts:
+            return False
+        if any(part in ALWAYS_SKIP_DIRS for part in parts):
+            return True
+        if self._is_ignored_dir(path.parent):
+            return True
+        return self._match(path, path.is_dir() if is_dir is None else is_dir) is True
@@ -0,0 +1,266 @@
+"""Go import extraction, package import graphs and cycle detection."""
+
+from __future__ import annotations
+import heapq
+import logging
+import os
+import re
+from collections import deque
+from typing import Any, Dict, List, Optional, Set, Tuple
+
+from .tree_sitter_symbol_extractor import TreeSitterSymbolExtractor, _node_text
+
+logger = logging.getLogger(__name__)
+
+# Import kinds: `import "fmt"`, `import f "fmt"`, `import . "fmt"` and `import _ "embed"`
+IMPORT_NORMAL = "normal"
+IMPORT_ALIAS = "alias"
+IMPORT_DOT = "dot"
+IMPORT_BLANK = "blank"
+
+
+def extract_go_imports(source: str) -> List[Dict[str, Any]]:
+    """
+    Returns the imports of a Go file in source order.
+
+    Each import is a dict with ``path`` (the import path, unquoted), ``kind``
+    (``"normal"``, ``"alias"``, ``"dot"`` or ``"blank"``), ``line`` (0-based)
+    and, unless the kind is normal, the ``alias`` as written (``"."`` or ``"_"``
+    for dot and blank imports).
+    """
+    root = TreeSitterSymbolExtractor.get_parser(".go").parse(source.encode("utf-8")).root_node
+    imports: List[Dict[str, Any]] = []
+    for declaration in root.named_children:
+        if declaration.type != "import_declaration":
+            continue
+        specs = []
+        for child in declaration.named_children:
+            if child.type == "import_spec":
+                specs.append(child)
+            elif child.type == "import_spec_list":
+                specs.extend(c for c in child.named_children if c.type == "import_spec")
+        for spec in specs:
+            path_node = spec.child_by_field_name("path")
+            if path_node is None:
+                continue
+            entry: Dict[str, Any] = {
+                "path": _node_text(path_node).strip("\"`"),
+                "kind": IMPORT_NORMAL,
+                "line": spec.start_point[0],
+            }
+            name_node = spec.child_by_field_name("name")
+            if name_node is not None:
+                alias = _node_text(name_node)
+                entry["alias"] = alias
+                entry["kind"] = {".": IMPORT_DOT, "_": IMPORT_BLANK}.get(alias, IMPORT_ALIAS)
+            imports.append(entry)
+    return imports
+
+
+def go_module_path(go_mod_source: str) -> Optional[str]:
+    """Returns the module path declared by a go.mod file, or None."""
+    match = re.search(r"^\s*module\s+(\"[^\"]+\"|\S+)", go_mod_source, re.MULTILINE)
+    return match.group(1).strip('"') if match else None
+
+
+def package_import_path(file_path: str, module_path: Optional[str] = None) -> str:
+    """
+    Returns the import path of the package containing *file_path*.
+
+    The package is the file's directory, prefixed with *module_path* (from
+    go.mod) so it can be compared with import paths. Without a module path the
+    repository-relative directory is used, ``"."`` for the root.
+    """
+    directory = os.path.dirname(file_path).replace(os.sep, "/")
+    if module_path:
+        return f"{module_path}/{directory}" if directory else module_path
+    return directory or "."
+
+
+def build_import_graph(
+    imports_by_file: Dict[str, List[Dict[str, Any]]], module_path: Optional[str] = None
+) -> Dict[str, List[str]]:
+    """
+    Maps each package to the sorted list of packages it imports.
+
+    Args:
+        imports_by_file: Imports keyed by repository-relative file path, as
+            returned by :func:`extract_go_imports`.
+        module_path: Module path from go.mod, used to name the repository's packages.
+
+    Returns:
+        Every package with at least one file is a key, even with no imports.
+        Imported packages from outside the repository appear only as values.
+    """
+    graph: Dict[str, set] = {}
+    for file_path, imports in imports_by_file.items():
+        package = package_import_path(file_path, module_path)
+        dependencies = graph.setdefault(package, set())
+        dependencies.update(entry["path"] for entry in imports if entry["path"] != package)
+    return {package: sorted(deps) for package, deps in sorted(graph.items())}
+
+
+def strongly_connected_components(graph: Dict[str, List[str]]) -> List[List[str]]:
+    """
+    Returns the groups of nodes that can all reach each other, i.e. that form import cycles.
+
+    Only groups of two or more nodes, or a single node with an edge to itself,
+    are returned. Each group is sorted and the groups are sorted by first node.
+    Targets that are not keys of *graph* are ignored.
+    """
+    index_of: Dict[str, int] = {}
+    lowlink: Dict[str, int] = {}
+    on_stack: set = set()
+    stack: List[str] = []
+    components: List[List[str]] = []
+    counter = 0
+
+    # Iterative Tarjan so deep graphs cannot hit the recursion limit
+    for start in sorted(graph):
+        if start in index_of:
+            continue
+        work = [(start, iter(graph.get(start, [])))]
+        index_of[start] = lowlink[start] = counter
+        counter += 1
+        stack.append(start)
+        on_stack.add(start)
+        while work:
+            node, edges = work[-1]
+            advanced = False
+            for target in edges:
+                if target not in graph:
+                    continue
+                if target not in index_of:
+                    index_of[target] = lowlink[target] = counter
+                    counter += 1
+                    stack.append(target)
+                    on_stack.add(target)
+                    work.append((target, iter(graph.get(target, []))))
+                    advanced = True
+                    break
+                if target in on_stack:
+                    lowlink[node] = min(lowlink[node], index_of[target])
+            if advanced:
+                continue
+            work.pop()
+            if work:
+                parent = work[-1][0]
+                lowlink[parent] = min(lowlink[parent], lowlink[node])
+            if lowlink[node] == index_of[node]:
+                component = []
+                while True:
+                    member = stack.pop()
+                    on_stack.discard(member)
+                    component.append(member)
+                    if member == node:
+                        break
+                if len(component) > 1 or node in graph.get(node, []):
+                    components.append(sorted(component))
+    return sorted(components)
+
+
+def detect_cycles(graph: Dict[str, List[str]]) -> List[List[str]]:
+    """
+    Finds import cycles in a graph from :func:`build_import_graph`.
+
+    Returns one cycle per strongly connected group of packages, written as a
+    path that starts and ends at the group's smallest package, e.g.
+    ``["a", "b", "a"]``. The path is a shortest cycle through that package.
+    Cycles are sorted by their first package.
+    """
+    components = strongly_connected_components(graph)
+    cycles = [_shortest_cycle(graph, component[0], set(component)) for component in components]
+    return sorted(cycles)
+
+
+def _shortest_cycle(graph: Dict[str, List[str]], start: str, members: set) -> List[str]:
+    """Breadth-first search for the shortest path from *start* back to itself within *members*."""
+    previous: Dict[str, str] = {}
+    queue = deque([start])
+    while queue:
+        node = queue.popleft()
+        for target in graph.get(node, []):
+            if target not in members:
+                continue
+            if target == start:
+                path = [node]
+                while path[-1] != start:
+                    path.append(previous[path[-1]])
+                return [start] + list(reversed(path[:-1])) + [start]
+            if target not in previous:
+                previous[target] = node
+                queue.append(target)
+    return [start, start]
+
+
+def _condense(graph: Dict[str, List[str]]) -> Tuple[List[List[str]], List[Set[int]]]:
+    """
+    Collapses *graph* into its strongly connected components, in no particular order.
+
+    Returns the groups, each sorted, and for each group the indexes of the
+    other groups it has an edge to. Targets that are not keys of *graph* are ignored.
+    """
+    groups = strongly_connected_components(graph)
+    in_cycle = {node for group in groups for node in group}
+    groups += [[node] for node in sorted(graph) if node not in in_cycle]
+    group_of = {node: index for index, group in enumerate(groups) for node in group}
+    edges: List[Set[int]] = [set() for _ in groups]
+    for node, targets in graph.items():
+        for target in targets:
+            if target in group_of and group_of[target] != group_of[node]:
+                edges[group_of[node]].add(group_of[target])
+    return groups, edges
+
+
+def _topological_indexes(groups: List[List[str]], edges: List[Set[int]]) -> List[int]:
+    """Kahn's algorithm over the condensed graph, taking the ready group with the smallest first node."""
+    waiting = [len(targets) for targets in edges]
+    dependents: List[List[int]] = [[] for _ in groups]
+    for index, targets in enumerate(edges):
+        for target in targets:
+            dependents[target].append(index)
+    ready = [(group[0], index) for index, group in enumerate(groups) if not waiting[index]]
+    heapq.heapify(ready)
+    order: List[int] = []
+    while ready:
+        _, index = heapq.heappop(ready)
+        order.append(index)
+        for dependent in dependents[index]:
+            waiting[dependent] -= 1
+            if not waiting[dependent]:
+                heapq.heappush(ready, (groups[dependent][0], dependent))
+    return order
+
+
+def topological_groups(graph: Dict[str, List[str]]) -> List[List[str]]:
+    """
+    Orders the nodes of *graph* so that every node comes after the nodes it has edges to.
+
+    For an import graph, dependencies come before the files or packages that
+    import them. Nodes in a cycle cannot be ordered among themselves, so each
+    strongly connected component is one group, sorted; every other node is a
+    group of its own. When several groups are ready, the one with the smallest
+    first node goes first, so the order depends only on the graph.
+    """
+    groups, edges = _condense(graph)
+    return [groups[index] for index in _topological_indexes(groups, edges)]
+
+
+def dependency_layers(graph: Dict[str, List[str]]) -> List[List[str]]:
+    """
+    Buckets the nodes of *graph* by their distance from the leaves.
+
+    Layer 0 holds the nodes without edges, and each later layer the nodes
+    whose targets are all in earlier layers, at least one in the layer just
+    before. Nodes of a layer never depend on each other, except the members of
+    one cycle, which share a layer. Each layer is sorted.
+    """
+    groups, edges = _condense(graph)
+    depth: Dict[int, int] = {}
+    layers: List[List[str]] = []
+    for index in _topological_indexes(groups, edges):
+        depth[index] = 1 + max((depth[target] for target in edges[index]), default=-1)
+        if depth[index] == len(layers):
+            layers.append([])
+        layers[depth[index]].extend(groups[index])
+    return [sorted(layer) for layer in layers]
@@ -0,0 +1,355 @@
+"""Registry mapping file extensions to symbol extractors."""
+
+from __future__ import annotations
+import logging
+import re
+import threading
+import warnings
+from dataclasses import dataclass
+from typing import IO, Any, Callable, Dict, FrozenSet, Iterable, Iterator, List, Optional, Protocol
+
+from .code_origin import origin_fields
+from .go_build import file_constraint
+from .markdown_symbols import MarkdownExtractor
+from .sql_symbols import SqlExtractor
+from .symbol_ids import assign_ids, go_package, iter_ids, overloaded_ids
+from .tree_sitter_symbol_extractor import LANGUAGES, ExtractionOptions, TreeSitterSymbolExtractor
+
+logger = logging.getLogger(__name__)
+
+# Test file naming conventions: Go, Python (pytest), JS/TS (Jest, Vitest, Mocha), Java (JUnit) and Ruby (RSpec)
+_TEST_FILE = re.compile(
+    r"(_test\.go|^test_.*\.py|_test\.py|^conftest\.py|\.(test|spec)\.[cm]?[jt]sx?|Tests?\.java|_spec\.rb)$"
+)
+_TEST_DIRS = frozenset({"test", "tests", "spec", "__tests__", "testdata"})
+
+
+def is_test_file(path: str) -> bool:
+    """
+    True for test sources by name (``x_test.go``, ``test_x.py``, ``x.spec.ts``, ``XTest.java``) or by directory.
+
+    Files under a ``test``, ``tests``, ``spec``, ``__tests__`` or ``testdata`` directory
+    count too, except Go files outside ``testdata``: a Go package in a
+    ``tests`` directory is built like any other, and only ``_test.go`` files
+    are left to ``go test``.
+    """
+    parts = path.replace("\\", "/").split("/")
+    if _TEST_FILE.search(parts[-1]):
+        return True
+    directories = parts[:-1]
+    if parts[-1].endswith(".go"):
+        return "testdata" in directories
+    return any(part in _TEST_DIRS for part in directories)
+
+
+class SymbolExtractor(Protocol):
+    """
+    Extracts symbols from one source file.
+
+    Implementations return symbol dicts with at least ``name``, ``type``,
+    ``start_line`` and ``end_line`` (0-based) and raise on unparseable input.
+    The repository fills in ``file``.
+    """
+
+    def extract(self, path: str, source: str) -> List[Dict[str, Any]]: ...
+
+
+class LanguageConflictWarning(UserWarning):
+    """Warns that a registration took an extension away from another language."""
+
+
+class TreeSitterExtractor:
+    """The built-in extractor for one extension, using the tree-sitter grammar and tags.scm query behind it."""
+
+    def __init__(self, ext: str) -> None:
+        self.ext = ext
+
+    def extract(
+        self,
+        path: str,
+        source: str,
+        options: Optional[ExtractionOptions] = None,
+        raise_errors: bool = True,
+        diagnostics: Optional[List[Dict[str, Any]]] = None,
+    ) -> List[Dict[str, Any]]:
+        return TreeSitterSymbolExtractor.extract_symbols(
+            self.ext, source, options, raise_errors=raise_errors, diagnostics=diagnostics
+        )
+
+    def iter_extract(
+        self,
+        path: str,
+        source: str,
+        options: Optional[ExtractionOptions] = None,
+        diagnostics: Optional[List[Dict[str, Any]]] = None,
+    ) -> Iterator[Dict[str, Any]]:
+        return TreeSitterSymbolExtractor.iter_symbols(self.ext, source, options, diagnostics=diagnostics)
+
+
+def _file_fields(path: str, source: str) -> Dict[str, Any]:
+    """
+    Fields every symbol of a file gets from the file itself, whichever extractor found it.
+
+    Symbols of test files (see :func:`is_test_file`) get ``is_test: True``,
+    those of vendored or generated files ``vendored: True`` or ``generated: True``
+    (see :mod:`codekite.code_origin`), and those of Go files with build
+    constraints get the ``build_constraint`` they were compiled under, e.g.
+    ``"windows && amd64"``, so per-platform variants of one function can be
+    told apart. Other files add no fields.
+    """
+    fields: Dict[str, Any] = {}
+    if is_test_file(path):
+        fields["is_test"] = True
+    fields.update(origin_fields(path, source))
+    if not path.endswith(".go"):
+        return fields
+    constraint = file_constraint(path, source)
+    if constraint:
+        fields["build_constraint"] = constraint
+    return fields
+
+
+@dataclass(frozen=True)
+class _Registration:
+    language: str
+    extractor: SymbolExtractor
+    builtin: bool = False
+
+
+_lock = threading.Lock()
+_registry: Dict[str, _Registration] = {}
+
+
+def _normalize_extension(ext: str) -> str:
+    ext = ext.lower()
+    return ext if ext.startswith(".") else f".{ext}"
+
+
+def _register(name: str, extensions: List[str], extractor: SymbolExtractor, builtin: bool = False) -> List[str]:
+    """Claims *extensions* for *name*; returns the other languages that held any of them."""
+    displaced: List[str] = []
+    with _lock:
+        for ext in extensions:
+            previous = _registry.get(ext)
+            if previous is not None and previous.language != name and previous.language not in displaced:
+                displaced.append(previous.language)
+            _registry[ext] = _Registration(name, extractor, builtin)
+    return displaced
+
+
+def register_language(name: str, extensions: Iterable[str], extractor: SymbolExtractor) -> None:
+    """
+    Registers *extractor* for files with the given extensions.
+
+    The built-in languages are registered the same way when the module loads,
+    so the repository, the CLI and :func:`parse_reader` all dispatch through
+    this one registry. Registration is thread-safe and last-wins: claiming an
+    extension that another language holds, including a built-in one such as
+    ``.py``, replaces its extractor and emits a :class:`LanguageConflictWarning`.
+    Registering the same language again replaces it silently. To make
+    conflicts fatal, turn the warning into an error with
+    ``warnings.simplefilter("error", LanguageConflictWarning)``.
+
+    Args:
+        name: Language name, reported by :func:`language_for`.
+        extensions: Extensions with or without the leading dot, e.g. ``[".foo"]``.
+        extractor: Object with an ``extract(path, source)`` method.
+    """
+    normalized = [_normalize_extension(ext) for ext in extensions]
+    if not normalized:
+        raise ValueError("register_language needs at least one extension")
+    displaced = _register(name, normalized, extractor)
+    if displaced:
+        warnings.warn(
+            f"{name} replaces {', '.join(displaced)} for {', '.join(normalized)}",
+            LanguageConflictWarning,
+            stacklevel=2,
+        )
+
+
+def unregister_language(name: str) -> None:
+    """Removes every registration for *name*, restoring built-in extractors for the extensions it claimed."""
+    with _lock:
+        for ext, registration in list(_registry.items()):
+            if registration.language != name or registration.builtin:
+                continue
+            if ext in _builtins:
+                _registry[ext] = _builtins[ext]
+            else:
+                del _registry[ext]
+
+
+for _ext, _language in LANGUAGES.items():
+    _register(_language, [_ext], TreeSitterExtractor(_ext), builtin=True)
+# Markdown needs no grammar: headings and fences are found line by line
+_register("markdown", [".md", ".markdown"], MarkdownExtractor(), builtin=True)
+# Neither does SQL: CREATE statements are found once comments and literals are masked
+_register("sql", [".sql"], SqlExtractor(), builtin=True)
+_builtins: Dict[str, _Registration] = dict(_registry)
+
+
+def language_for(ext: str) -> Optional[str]:
+    """Returns the language registered for an extension, or None."""
+    with _lock:
+        registration = _registry.get(_normalize_extension(ext))
+    return registration.language if registration else None
+
+
+def extension_for(language: str) -> Optional[str]:
+    """
+    Resolves a language given by name or extension to the extension used for dispatch.
+
+    Accepts registered language names (``"python"``, ``"go"``) as well as
+    extensions with or without the dot (``"py"``, ``".ts"``). Returns None if
+    nothing is registered for it.
+    """
+    ext = _normalize_extension(language)
+    with _lock:
+        if ext in _registry:
+            return ext
+        name = language.lower()
+        return next((ext for ext, registration in sorted(_registry.items()) if registration.language == name), None)
+
+
+def supported_extensions() -> FrozenSet[str]:
+    """Returns every extension with a registered extractor."""
+    with _lock:
+        return frozenset(_registry)
+
+
+def is_tree_sitter(ext: str) -> bool:
+    """True if *ext* is handled by a built-in tree-sitter extractor rather than a registered plugin."""
+    with _lock:
+        registration = _registry.get(_normalize_extension(ext))
+    return registration is not None and isinstance(registration.extractor, TreeSitterExtractor)
+
+
+def extract_symbols(
+    ext: str,
+    path: str,
+    source: str,
+    options: Optional[ExtractionOptions] = None,
+    raise_errors: bool = False,
+    diagnostics: Optional[List[Dict[str, Any]]] = None,
+) -> List[Dict[str, Any]]:
+    """
+    Extracts symbols from *source* with the extractor registered for *ext*.
+
+    Args:
+        ext: File extension used for dispatch.
+        path: Path of the file, passed through to custom extractors.
+        source: File contents.
+        options: Options for the built-in extractors; custom extractors ignore them.
+        raise_errors: Propagate extraction errors instead of logging them and returning [].
+        diagnostics: List the syntax errors tree-sitter recovered from are appended
+            to; custom extractors report none.
+    """
+    ext = _normalize_extension(ext)
+    with _lock:
+        registration = _registry.get(ext)
+    if registration is None:
+        return []
+    if isinstance(registration.extractor, TreeSitterExtractor):
+        symbols = registration.extractor.extract(
+            path, source, options, raise_errors=raise_errors, diagnostics=diagnostics
+        )
+    else:
+        try:
+            symbols = list(registration.extractor.extract(path, source))
+        except Exception as e:
+            if raise_errors:
+                raise
+            logger.warning(f"{registration.language} extractor failed for {path}: {e}")
+            return []
+    return list(_with_file_fields(path, source, symbols))
+
+
+def iter_symbols(
+    ext: str,
+    path: str,
+    source: str,
+    options: Optional[ExtractionOptions] = None,
+) -> Iterator[Dict[str, Any]]:
+    """
+    Yields the symbols :func:`extract_symbols` returns, one at a time.
+
+    The built-in extractors yield each symbol as the tree-sitter query finds
+    it, so the caller can write it out and drop it; see
+    :meth:`TreeSitterSymbolExtractor.iter_symbols`. Custom extractors return
+    a list, which is yielded from. Errors propagate to the caller.
+    """
+    ext = _normalize_extension(ext)
+    with _lock:
+        registration = _registry.get(ext)
+    if registration is None:
+        return iter(())
+    if isinstance(registration.extractor, TreeSitterExtractor):
+        return _with_file_fields(path, source, registration.extractor.iter_extract(path, source, options))
+    return _with_file_fields(path, source, registration.extractor.extract(path, source))
+
+
+def _with_file_fields(path: str, source: str, symbols: Iterable[Dict[str, Any]]) -> Iterator[Dict[str, Any]]:
+    fields = _file_fields(path, source)
+    for symbol in symbols:
+        symbol.update(fields)
+        yield symbol
+
+
+def parse_reader(
+    reader: IO[str],
+    language: str,
+    path: str = "<stdin>",
+    options: Optional[ExtractionOptions] = None,
+) -> List[Dict[str, Any]]:
+    """
+    Extracts symbols from source read from a stream, such as an unsaved editor buffer.
+
+    Args:
+        reader: Text stream to read the source from.
+        language: Language name or extension, see :func:`extension_for`.
+        path: Name reported to custom extractors and recorded as the symbols' ``file``.
+        options: Options for the built-in extractors.
+
+    Raises:
+        ValueError: If no extractor is registered for *language*.
+    """
+    ext = extension_for(language)
+    if ext is None:
+        raise ValueError(f"Unsupported language: {language}")
+    source = reader.read()
+    symbols = extract_symbols(ext, path, source, options, raise_errors=True)
+    for s in symbols:
+        s["file"] = path
+    assign_ids(path, symbols, go_package(path, source))
+    return symbols
+
+
+def parse_stream(
+    reader: IO[str],
+    language: str,
+    callback: Callable[[Dict[str, Any]], None],
+    path: str = "<stdin>",
+    options: Optional[ExtractionOptions] = None,
+) -> None:
+    """
+    Like :func:`parse_reader`, but calls *callback* with each symbol as it is found instead of returning a list.
+
+    Meant for indexing pipelines that write each symbol out and discard it:
+    the symbols of a huge generated file are never all in memory at once.
+    Symbols are passed with ``file`` and ``id`` set; IDs match those of
+    :func:`parse_reader`. An exception raised by *callback* stops the
+    extraction and propagates to the caller.
+
+    Raises:
+        ValueError: If no extractor is registered for *language*.
+    """
+    ext = extension_for(language)
+    if ext is None:
+        raise ValueError(f"Unsupported language: {language}")
+    source = reader.read()
+    # A first pass keeps only the IDs, so overloads get the same IDs as in parse_reader
+    overloaded = overloaded_ids(path, iter_symbols(ext, path, source, options))
+    symbols = iter_symbols(ext, path, source, options)
+    for symbol in iter_ids(path, symbols, overloaded, go_package(path, source)):
+        symbol["file"] = path
+        callback(symbol)
@@ -0,0 +1,120 @@
+"""Counting code, comment and blank lines per file, and rolling the counts up by directory."""
+
+from __future__ import annotations
+import logging
+import posixpath
+from dataclasses import asdict, dataclass
+from typing import TYPE_CHECKING, Any, Dict, Iterator, Optional
+
+from . import languages
+from .tree_sitter_symbol_extractor import TreeSitterSymbolExtractor
+
+if TYPE_CHECKING:
+    from .repository import Repository
+
+logger = logging.getLogger(__name__)
+
+
+@dataclass
+class LineCounts:
+    """
+    Lines of a file, or of every file under a directory.
+
+    Attributes:
+        total: Every line; a final line without a newline counts too.
+        code: Lines with anything besides comments and whitespace, including
+            lines holding code followed by a comment and Python docstrings.
+        comment: Lines holding only comments.
+        blank: Lines holding only whitespace.
+        files: Files counted.
+    """
+
+    total: int = 0
+    code: int = 0
+    comment: int = 0
+    blank: int = 0
+    files: int = 0
+
+    def add(self, other: "LineCounts") -> None:
+        self.total += other.total
+        self.code += other.code
+        self.comment += other.comment
+        self.blank += other.blank
+        self.files += other.files
+
+    def to_dict(self) -> Dict[str, int]:
+        return asdict(self)
+
+
+def comment_ranges(root: Any) -> Iterator[Any]:
+    """Yields ``(start_byte, end_byte)`` of every comment node under the tree-sitter node *root*."""
+    stack = [root]
+    while stack:
+        node = stack.pop()
+        if node.type.endswith("comment"):
+            yield node.start_byte, node.end_byte
+            continue
+        stack.extend(node.children)
+
+
+def count_lines(source: str, ext: str) -> LineCounts:
+    """
+    Counts the lines of *source*, a file with extension *ext*.
+
+    Comments are found with the file's tree-sitter grammar, so ``#`` inside a
+    string is not a comment. Files in languages without a built-in grammar
+    report every non-blank line as code.
+    """
+    source_bytes = source.encode("utf-8")
+    masked = bytearray(source_bytes)
+    parser = TreeSitterSymbolExtractor.get_parser(ext) if languages.is_tree_sitter(ext) else None
+    if parser is not None:
+        for start, end in comment_ranges(parser.parse(source_bytes).root_node):
+            # Blank out the comment but keep its newlines, so lines stay aligned
+            masked[start:end] = bytes(b if b == 0x0A else 0x20 for b in source_bytes[start:end])
+
+    lines = source_bytes.split(b"\n")
+    if lines and lines[-1] == b"":
+        lines.pop()
+    counts = LineCounts(total=len(lines), files=1)
+    offset = 0
+    for line in lines:
+        if not line.strip():
+            counts.blank += 1
+        elif not masked[offset : offset + len(line)].strip():
+            counts.comment += 1
+        else:
+            counts.code += 1
+        offset += len(line) + 1
+    return counts
+
+
+def file_line_counts(repo: "Repository", root: Optional[str] = None) -> Dict[str, LineCounts]:
+    """Line counts of every source file under *root*, keyed by repository-relative path in walk order."""
+    repo_root = repo.mapper.repo_path
+    counts: Dict[str, LineCounts] = {}
+    for file in repo.mapper.source_files(root):
+        path = file.relative_to(repo_root).as_posix()
+        try:
+            counts[path] = count_lines(repo.get_file_content(path), file.suffix.lower())
+        except (IOError, UnicodeDecodeError) as e:
+            logger.warning(f"Skipping line counts for {path}: {e}")
+    return counts
+
+
+def directory_line_counts(counts: Dict[str, LineCounts]) -> Dict[str, LineCounts]:
+    """
+    Sums file counts into every directory above each file, sorted by directory.
+
+    ``"."`` is the repository root and so holds the grand total; ``"cart"``
+    holds ``cart/*`` and ``cart/internal/*`` alike.
+    """
+    rollup: Dict[str, LineCounts] = {}
+    for path, file_counts in counts.items():
+        directory = posixpath.dirname(path)
+        while True:
+            rollup.setdefault(directory or ".", LineCounts()).add(file_counts)
+            if not directory:
+                break
+            directory = posixpath.dirname(directory)
+    return dict(sorted(rollup.items()))
@@ -1,22 +1,122 @@
 """Utilities to assemble rich prompts for LLMs.
 
 This is intentionally lightweight - it glues together repository data
-(diff, file bodies, search hits, etc.) into a single string that can be
-fed straight into a chat completion.
+(diff, file bodies, symbols, search hits, notes) into a single string that
+can be fed straight into a chat completion, optionally cut to a token budget.
 """
 
 from __future__ import annotations
 
+from dataclasses import dataclass, field
 from pathlib import Path
-from typing import Any, Dict, List, Optional, Sequence, TYPE_CHECKING
+from typing import Any, Dict, List, Optional, Sequence, Tuple, TYPE_CHECKING
+
+from .tokenizers import HeuristicTokenizer, Tokenizer
 
 if TYPE_CHECKING:
     from .repository import Repository
 
+STATUS_INCLUDED = "included"
+STATUS_TRUNCATED = "truncated"
+STATUS_EXCLUDED = "excluded"
+STATUS_DUPLICATE = "duplicate"
+
+_SEPARATOR = "\n\n"
+
+
+@dataclass
+class _Item:
+    kind: str
+    header: str
+    body: str
+    fence: Optional[str]
+    priority: int
+    order: int
+    file: Optional[str] = None
+    # 0-based, inclusive, like symbol positions
+    start_line: Optional[int] = None
+    end_line: Optional[int] = None
+
+    def render(self, body: Optional[str] = None) -> str:
+        body = self.body if body is None else body
+        if self.fence is None:
+            return f"{self.header}\n{body}"
+        return f"{self.header}\n```{self.fence}\n{body}\n```"
+
+
+@dataclass(frozen=True)
+class ManifestEntry:
+    """
+    What became of one item added to a :class:`ContextAssembler`.
+
+    Attributes:
+        kind: ``"diff"``, ``"file"``, ``"symbol"``, ``"search"`` or ``"note"``.
+        header: The item's header line, e.g. ``"## app/db.py:10-42 (function connect)"``.
+        priority: The priority it was added with.
+        status: ``"included"``, ``"truncated"`` (its header and the lines
+            that fit were kept), ``"excluded"`` (no room left) or
+            ``"duplicate"`` (the same file and range was added before).
+        tokens: Tokens it takes up in the context; 0 unless included or truncated.
+        file: File the item came from, if any.
+        start_line: First line of the item in *file*, 0-based.
+        end_line: Last line, inclusive.
+    """
+
+    kind: str
+    header: str
+    priority: int
+    status: str
+    tokens: int = 0
+    file: Optional[str] = None
+    start_line: Optional[int] = None
+    end_line: Optional[int] = None
+
+    def to_dict(self) -> Dict[str, Any]:
+        return {
+            "kind": self.kind,
+            "header": self.header,
+            "priority": self.priority,
+            "status": self.status,
+            "tokens": self.tokens,
+            "file": self.file,
+            "start_line": self.start_line,
+            "end_line": self.end_line,
+        }
+
+
+@dataclass
+class AssembledContext:
+    """The output of :meth:`ContextAssembler.assemble`: the prompt text and a manifest in the order items were added."""
+
+    text: str
+    tokens: int
+    manifest: List[ManifestEntry] = field(default_factory=list)
+
+    @property
+    def included(self) -> List[ManifestEntry]:
+        return [e for e in self.manifest if e.status in (STATUS_INCLUDED, STATUS_TRUNCATED)]
+
+    @property
+    def excluded(self) -> List[ManifestEntry]:
+        return [e for e in self.manifest if e.status in (STATUS_EXCLUDED, STATUS_DUPLICATE)]
+
+
+def _line_range(start: Optional[int], end: Optional[int]) -> str:
+    if start is None:
+        return ""
+    # 1-based in headers, as editors and stack traces show them
+    return f":{start + 1}" if end is None or end == start else f":{start + 1}-{end + 1}"
+
 
 class ContextAssembler:
     """Collects pieces of context and spits out a prompt blob.
 
+    Every piece is added with a priority. Without a token budget all of them
+    are included; with one, the lowest-priority pieces are truncated or left
+    out first, and ties go against the piece added last. Pieces appear in the
+    order they were added either way, so the same calls always produce the
+    same prompt.
+
     Parameters
     ----------
     repo
@@ -24,22 +124,37 @@ class ContextAssembler:
         we want to reason about. The assembler uses it to fetch file content
         and (in the future) symbol relationships.
     title
-        Optional global title prepended to the context (not used by default).
+        Optional global title prepended to the context; always kept.
+    max_tokens
+        Token budget for the whole context, or None for no limit.
+    tokenizer
+        Counts tokens against *max_tokens*; defaults to
+        :class:`codekite.tokenizers.HeuristicTokenizer`.
     """
 
-    def __init__(self, repo: Repository, *, title: Optional[str] = None) -> None:
+    def __init__(
+        self,
+        repo: Repository,
+        *,
+        title: Optional[str] = None,
+        max_tokens: Optional[int] = None,
+        tokenizer: Optional[Tokenizer] = None,
+    ) -> None:
+        if max_tokens is not None and max_tokens <= 0:
+            raise ValueError("max_tokens must be positive")
         self.repo = repo
-        self._sections: List[str] = []
-        if title:
-            self._sections.append(f"# {title}\n")
+        self.title = title
+        self.max_tokens = max_tokens
+        self.tokenizer: Tokenizer = tokenizer or HeuristicTokenizer()
+        self._items: List[_Item] = []
 
-    def add_diff(self, diff: str) -> None:
+    def add_diff(self, diff: str, *, priority: int = 0) -> None:
         """Add a raw git diff section."""
         if not diff.strip():
             return
-        self._sections.append("## Diff\n```diff\n" + diff.strip() + "\n```")
+        self._items.append(_Item("diff", "## Diff", diff.strip(), "diff", priority, len(self._items)))
 
-    def add_file(self, file_path: str, *, highlight_changes: bool = False) -> None:
+    def add_file(self, file_path: str, *, highlight_changes: bool = False, priority: int = 0) -> None:
         """Embed full file content.
 
         If *highlight_changes* is true we still just inline raw content -
@@ -50,20 +165,139 @@ class ContextAssembler:
         except FileNotFoundError:
             return
         lang = Path(file_path).suffix.lstrip(".") or "text"
-        header = f"## {file_path} (full)" if not highlight_changes else f"## {file_path} (with changes highlighted)"
-        self._sections.append(f"{header}\n```{lang}\n{code}\n```")
+        last = max(len(code.splitlines()) - 1, 0)
+        note = "full" if not highlight_changes else "with changes highlighted"
+        header = f"## {file_path}{_line_range(0, last)} ({note})"
+        self._items.append(_Item("file", header, code.rstrip("\n"), lang, priority, len(self._items), file_path, 0, last))
 
-    def add_search_results(self, results: Sequence[Dict[str, Any]], *, query: str) -> None:
-        """Append semantic search matches to the context."""
-        if not results:
-            return
-        blob = [f"## Semantic search for: {query}"]
+    def add_symbol(self, file_path: str, symbol: Dict[str, Any], *, priority: int = 0) -> None:
+        """Embed one extracted symbol's code, e.g. from :meth:`Repository.extract_symbols`."""
+        start, end = symbol.get("start_line"), symbol.get("end_line")
+        name = symbol.get("node_path") or symbol.get("name", "")
+        header = f"## {file_path}{_line_range(start, end)} ({symbol.get('type', 'symbol')} {name})"
+        lang = Path(file_path).suffix.lstrip(".") or "text"
+        code = (symbol.get("code") or "").rstrip("\n")
+        self._items.append(_Item("symbol", header, code, lang, priority, len(self._items), file_path, start, end))
+
+    def add_search_results(self, results: Sequence[Dict[str, Any]], *, query: str, priority: int = 0) -> None:
+        """Append search matches to the context, one item each, keeping their order."""
         for i, res in enumerate(results, 1):
-            code = res.get("code") or res.get("snippet") or ""
-            file = res.get("file", f"result_{i}")
-            blob.append(f"### {file}\n```\n{code}\n```")
-        self._sections.append("\n".join(blob))
+            code = res.get("code") or res.get("snippet") or res.get("line") or ""
+            file = res.get("file")
+            start = res.get("start_line")
+            end = res.get("end_line", start)
+            if start is None and res.get("line_number") is not None:
+                # search_text reports 1-based line numbers
+                start = end = res["line_number"] - 1
+            header = f"## {file or f'result_{i}'}{_line_range(start, end)} (search: {query})"
+            self._items.append(
+                _Item("search", header, code.rstrip("\n"), "", priority, len(self._items), file, start, end)
+            )
+
+    def add_note(self, text: str, *, title: Optional[str] = None, priority: int = 0) -> None:
+        """Add free text, such as instructions or a ticket description."""
+        if not text.strip():
+            return
+        header = f"## {title}" if title else "## Note"
+        self._items.append(_Item("note", header, text.strip(), None, priority, len(self._items)))
+
+    def _count(self, text: str) -> int:
+        return self.tokenizer.count(text)
+
+    def _truncated(self, item: _Item, budget: int) -> Optional[str]:
+        """The longest run of *item*'s leading lines that fits *budget* with its header, or None."""
+        lines = item.body.splitlines()
+
+        def render(keep: int) -> str:
+            marker = f"... [{len(lines) - keep} more lines truncated]"
+            return item.render("\n".join(lines[:keep] + [marker]))
+
+        if self._count(render(0) + _SEPARATOR) > budget:
+            return None
+        low, high = 0, len(lines) - 1
+        while low < high:
+            middle = (low + high + 1) // 2
+            if self._count(render(middle) + _SEPARATOR) <= budget:
+                low = middle
+            else:
+                high = middle - 1
+        return render(low)
+
+    def assemble(self) -> AssembledContext:
+        """
+        Builds the context and reports what went into it.
+
+        Items with the same file and line range as an earlier one, such as a
+        symbol reached both directly and through search, are kept once, at the
+        higher of their priorities. Under a budget, items are admitted from the
+        highest priority down; one that does not fit whole keeps its header and
+        as many of its first lines as fit, and is left out only if not even
+        the header fits.
+        """
+        items: List[_Item] = []
+        priority = {item.order: item.priority for item in self._items}
+        duplicates = set()
+        seen: Dict[Tuple[str, int, Optional[int]], _Item] = {}
+        for item in self._items:
+            if item.file is not None and item.start_line is not None:
+                key = (item.file, item.start_line, item.end_line)
+                first = seen.get(key)
+                if first is not None:
+                    priority[first.order] = max(priority[first.order], item.priority)
+                    duplicates.add(item.order)
+                    continue
+                seen[key] = item
+            items.append(item)
+
+        rendered: Dict[int, str] = {}
+        statuses: Dict[int, str] = {}
+        title = f"# {self.title}\n" if self.title else None
+        remaining = None
+        if self.max_tokens is not None:
+            remaining = self.max_tokens - (self._count(title + _SEPARATOR) if title else 0)
+        for item in sorted(items, key=lambda i: (-priority[i.order], i.order)):
+            text = item.render()
+            if remaining is None:
+                rendered[item.order], statuses[item.order] = text, STATUS_INCLUDED
+                continue
+            cost = self._count(text + _SEPARATOR)
+            if cost <= remaining:
+                rendered[item.order], statuses[item.order] = text, STATUS_INCLUDED
+                remaining -= cost
+                continue
+            truncated = self._truncated(item, remaining)
+            if truncated is None:
+                statuses[item.order] = STATUS_EXCLUDED
+                continue
+            rendered[item.order], statuses[item.order] = truncated, STATUS_TRUNCATED
+            remaining -= self._count(truncated + _SEPARATOR)
+
+        def join() -> str:
+            sections = ([title] if title else []) + [rendered[i.order] for i in items if i.order in rendered]
+            return _SEPARATOR.join(sections)
+
+        text = join()
+        if self.max_tokens is not None:
+            # Counts of separate sections only approximate the joined text's; shed from the bottom until it fits
+            for item in sorted(items, key=lambda i: (priority[i.order], -i.order)):
+                if self._count(text) <= self.max_tokens:
+                    break
+                if item.order in rendered:
+                    del rendered[item.order]
+                    statuses[item.order] = STATUS_EXCLUDED
+                    text = join()
+
+        manifest = []
+        for item in self._items:
+            status = STATUS_DUPLICATE if item.order in duplicates else statuses[item.order]
+            tokens = self._count(rendered[item.order]) if item.order in rendered else 0
+            manifest.append(
+                ManifestEntry(
+                    item.kind, item.header, item.priority, status, tokens, item.file, item.start_line, item.end_line
+                )
+            )
+        return AssembledContext(text, self._count(text), manifest)
 
     def format_context(self) -> str:
         """Return the accumulated context."""
-        return "\n\n".join(self._sections)
+        return self.assemble().text
@@ -0,0 +1,138 @@
+"""Markdown headings and fenced code blocks as symbols, so documentation is indexed next to the code."""
+
+from __future__ import annotations
+import re
+from typing import Any, Dict, List, Optional, Tuple
+
+SECTION = "section"
+CODE_BLOCK = "code_block"
+
+_ATX_HEADING = re.compile(r"^ {0,3}(#{1,6})(?=[ \t]|$)(.*)$")
+# A closing run of #s needs a space before it: "# C#" is a heading named "C#"
+_ATX_CLOSING = re.compile(r"(?:^|[ \t]+)#+[ \t]*$")
+_SETEXT_UNDERLINE = re.compile(r"^ {0,3}(=+|-+)\s*$")
+_FENCE_OPEN = re.compile(r"^ {0,3}(`{3,}|~{3,})(.*)$")
+# Lines that start something other than a paragraph, so a --- below them is a thematic break
+_NOT_PARAGRAPH = re.compile(r"^ {0,3}([-+*>]|\d{1,9}[.)])([ \t]|$)|^( {4}|\t)")
+
+
+def _front_matter_end(lines: List[str]) -> int:
+    """The index of the first line after a leading ``---`` YAML block, or 0 if there is none."""
+    if not lines or lines[0].rstrip() != "---":
+        return 0
+    for i in range(1, len(lines)):
+        if lines[i].rstrip() in ("---", "..."):
+            return i + 1
+    return 0
+
+
+def _closes(line: str, character: str, length: int) -> bool:
+    """Whether *line* closes a fence opened with *length* or more of *character*."""
+    closing = line.strip()
+    indent = len(line) - len(line.lstrip(" "))
+    return indent < 4 and bool(closing) and set(closing) == {character} and len(closing) >= length
+
+
+class MarkdownExtractor:
+    """
+    Reports Markdown headings as ``section`` symbols and fenced code blocks as ``code_block`` symbols.
+
+    ATX (``## Install``, with or without closing ``##``) and setext headings
+    (text underlined with ``===`` or ``---``) are both recognised. A
+    section's ``level`` is 1 to 6, and it runs from its heading to the line
+    before the next heading of the same or a higher level, or to the end of
+    the file. Its ``parent`` is the heading of the section around it. A code
+    block's ``language`` is the first word of its info string (``""`` when
+    there is none), which also names the symbol, and its ``parent`` is the
+    section it appears in. Headings inside code blocks and a leading
+    ``---`` front-matter block are skipped.
+    """
+
+    def extract(self, path: str, source: str) -> List[Dict[str, Any]]:
+        lines = source.split("\n")
+        if lines and lines[-1] == "":
+            lines.pop()
+        offsets = [0]
+        for line in lines:
+            offsets.append(offsets[-1] + len(line.encode("utf-8")) + 1)
+
+        headings: List[Tuple[int, int, str]] = []  # (start line, level, name)
+        blocks: List[Tuple[int, int, str]] = []  # (start line, end line, language)
+        fence: Optional[Tuple[str, int, str]] = None  # (fence character, length, language)
+        fence_start = 0
+        paragraph_start: Optional[int] = None
+        for i in range(_front_matter_end(lines), len(lines)):
+            line = lines[i]
+            if fence is not None:
+                if _closes(line, fence[0], fence[1]):
+                    blocks.append((fence_start, i, fence[2]))
+                    fence = None
+                continue
+            opening = _FENCE_OPEN.match(line)
+            if opening and not (opening.group(1)[0] == "`" and "`" in opening.group(2)):
+                info = opening.group(2).strip()
+                fence = (opening.group(1)[0], len(opening.group(1)), info.split()[0].strip("{}.") if info else "")
+                fence_start, paragraph_start = i, None
+                continue
+            atx = _ATX_HEADING.match(line)
+            if atx:
+                name = _ATX_CLOSING.sub("", atx.group(2).strip()).strip()
+                if name:
+                    headings.append((i, len(atx.group(1)), name))
+                paragraph_start = None
+                continue
+            underline = _SETEXT_UNDERLINE.match(line)
+            if underline and paragraph_start is not None:
+                name = " ".join(l.strip() for l in lines[paragraph_start:i])
+                headings.append((paragraph_start, 1 if underline.group(1)[0] == "=" else 2, name))
+                paragraph_start = None
+                continue
+            if not line.strip() or _NOT_PARAGRAPH.match(line):
+                paragraph_start = None
+            elif paragraph_start is None:
+                paragraph_start = i
+        if fence is not None:
+            # An unclosed fence runs to the end of the document
+            blocks.append((fence_start, len(lines) - 1, fence[2]))
+
+        symbols: List[Dict[str, Any]] = []
+        sections: List[Dict[str, Any]] = []
+        stack: List[Dict[str, Any]] = []
+        for index, (start, level, name) in enumerate(headings):
+            end = next((h[0] - 1 for h in headings[index + 1 :] if h[1] <= level), len(lines) - 1)
+            while stack and stack[-1]["level"] >= level:
+                stack.pop()
+            symbol = self._symbol(name, SECTION, start, end, lines, offsets)
+            symbol["level"] = level
+            # Setext headings are outlined in ATX form like the rest
+            symbol["signature"] = f"{'#' * level} {name}"
+            if stack:
+                symbol["parent"] = stack[-1]["name"]
+            stack.append(symbol)
+            sections.append(symbol)
+            symbols.append(symbol)
+        for start, end, language in blocks:
+            symbol = self._symbol(language or "code", CODE_BLOCK, start, end, lines, offsets)
+            symbol["language"] = language
+            enclosing = [s for s in sections if s["start_line"] <= start <= s["end_line"]]
+            if enclosing:
+                symbol["parent"] = enclosing[-1]["name"]
+            symbols.append(symbol)
+        symbols.sort(key=lambda s: s["start_line"])
+        return symbols
+
+    @staticmethod
+    def _symbol(name: str, kind: str, start: int, end: int, lines: List[str], offsets: List[int]) -> Dict[str, Any]:
+        code = "\n".join(lines[start : end + 1])
+        return {
+            "name": name,
+            "type": kind,
+            "start_line": start,
+            "end_line": end,
+            "line_count": end - start + 1,
+            "start_column": 0,
+            "end_column": len(lines[end]),
+            "start_byte": offsets[start],
+            "end_byte": offsets[start] + len(code.encode("utf-8")),
+            "code": code,
+        }
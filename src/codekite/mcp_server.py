@@ -0,0 +1,306 @@
+"""A Model Context Protocol server exposing a repository's symbols, search and files to AI assistants.
+
+Messages are JSON-RPC 2.0, one per line on stdin and stdout, as the MCP stdio
+transport specifies; logging goes to stderr so stdout carries nothing else.
+Each tool returns one JSON text content item. A tool that fails answers with
+``isError: true`` and the message as its text, so the client sees the error
+and the server keeps running. Results longer than ``max_result_chars`` are cut
+down and marked ``"truncated": true``, with ``total`` giving the full count.
+"""
+
+from __future__ import annotations
+import json
+import logging
+import os
+import re
+from dataclasses import dataclass
+from typing import TYPE_CHECKING, Any, Callable, Dict, List, Optional, TextIO
+
+from .code_searcher import SearchOptions
+from .export import export_symbols
+
+if TYPE_CHECKING:
+    from .repository import Repository
+
+logger = logging.getLogger(__name__)
+
+PROTOCOL_VERSION = "2024-11-05"
+DEFAULT_MAX_RESULT_CHARS = 100_000
+
+# JSON-RPC 2.0 error codes
+PARSE_ERROR = -32700
+INVALID_REQUEST = -32600
+METHOD_NOT_FOUND = -32601
+INVALID_PARAMS = -32602
+
+
+class ToolError(Exception):
+    """A failure reported to the client as a tool result with ``isError: true``."""
+
+
+@dataclass(frozen=True)
+class Tool:
+    """
+    One tool the server offers.
+
+    Attributes:
+        name: Name clients call it by.
+        description: What it does, shown to the model.
+        input_schema: JSON Schema of its arguments.
+        handler: Called with the arguments; returns a JSON-serializable dict.
+        bulk_key: Key of the list or string in the result to shorten when it is too long.
+    """
+
+    name: str
+    description: str
+    input_schema: Dict[str, Any]
+    handler: Callable[..., Dict[str, Any]]
+    bulk_key: str
+
+    def to_dict(self) -> Dict[str, Any]:
+        return {"name": self.name, "description": self.description, "inputSchema": self.input_schema}
+
+
+def _schema(properties: Dict[str, Any], required: List[str]) -> Dict[str, Any]:
+    return {"type": "object", "properties": properties, "required": required, "additionalProperties": False}
+
+
+def truncate_result(result: Dict[str, Any], key: str, max_chars: int) -> Dict[str, Any]:
+    """
+    Shortens ``result[key]`` until *result* serializes to at most *max_chars* characters.
+
+    A list keeps as many leading items as fit and a string as many leading
+    lines. A shortened result gets ``"truncated": true`` and ``total``, the
+    number of items or lines before shortening; one that already fits is
+    returned unchanged.
+    """
+    if len(json.dumps(result)) <= max_chars:
+        return result
+    bulk = result[key]
+    items = bulk if isinstance(bulk, list) else bulk.splitlines(keepends=True)
+
+    def shortened(count: int) -> Dict[str, Any]:
+        kept = items[:count] if isinstance(bulk, list) else "".join(items[:count])
+        return dict(result, **{key: kept, "truncated": True, "total": len(items)})
+
+    # The largest count that fits, found by bisection
+    low, high = 0, len(items)
+    while low < high:
+        middle = (low + high + 1) // 2
+        if len(json.dumps(shortened(middle))) <= max_chars:
+            low = middle
+        else:
+            high = middle - 1
+    return shortened(low)
+
+
+class MCPServer:
+    """Answers MCP requests about one repository; see the module documentation for the protocol."""
+
+    def __init__(self, repo: "Repository", max_result_chars: int = DEFAULT_MAX_RESULT_CHARS) -> None:
+        """
+        Args:
+            repo: Repository the tools operate on.
+            max_result_chars: Longest tool result, in characters of JSON, before it is truncated.
+        """
+        self.repo = repo
+        self.max_result_chars = max_result_chars
+        self.tools: Dict[str, Tool] = {tool.name: tool for tool in self._tools()}
+
+    def _tools(self) -> List[Tool]:
+        path = {"type": "string", "description": "File path relative to the repository root."}
+        return [
+            Tool(
+                "extract_symbols",
+                "List the symbols (functions, types, methods, ...) defined in one file.",
+                _schema({"path": path}, ["path"]),
+                self._extract_symbols,
+                "symbols",
+            ),
+            Tool(
+                "search_text",
+                "Search the repository's files for text, or a regular expression with regex=true.",
+                _schema(
+                    {
+                        "query": {"type": "string", "description": "Text or regular expression to find."},
+                        "regex": {"type": "boolean", "description": "Treat query as a regular expression.", "default": False},
+                        "pattern": {"type": "string", "description": "Glob the file paths must match, e.g. *.go.", "default": "*"},
+                    },
+                    ["query"],
+                ),
+                self._search_text,
+                "matches",
+            ),
+            Tool(
+                "find_usages",
+                "Find every reference to a symbol, given by name or qualified name such as User.Greet.",
+                _schema(
+                    {
+                        "symbol": {"type": "string", "description": "Symbol name or qualified name."},
+                        "path": dict(path, description="Only consider definitions in this file."),
+                    },
+                    ["symbol"],
+                ),
+                self._find_usages,
+                "usages",
+            ),
+            Tool(
+                "get_file_tree",
+                "List the repository's files and directories, without ignored paths.",
+                _schema({}, []),
+                self._get_file_tree,
+                "files",
+            ),
+            Tool(
+                "get_file_content",
+                "Read a file, or the 1-based inclusive line range start_line to end_line of it.",
+                _schema(
+                    {
+                        "path": path,
+                        "start_line": {"type": "integer", "minimum": 1, "description": "First line to return."},
+                        "end_line": {"type": "integer", "minimum": 1, "description": "Last line to return."},
+                    },
+                    ["path"],
+                ),
+                self._get_file_content,
+                "content",
+            ),
+        ]
+
+    def _checked_path(self, path: str) -> str:
+        root = os.path.realpath(self.repo.repo_path)
+        full_path = os.path.realpath(os.path.join(root, path))
+        if os.path.commonpath([root, full_path]) != root:
+            raise ToolError(f"Path is outside the repository: {path}")
+        if not os.path.isfile(full_path):
+            raise ToolError(f"No such file: {path}")
+        return os.path.relpath(full_path, root).replace(os.sep, "/")
+
+    def _extract_symbols(self, path: str) -> Dict[str, Any]:
+        path = self._checked_path(path)
+        document = export_symbols({path: self.repo.extract_symbols(path)}, self.repo)
+        return {"file": path, "symbols": [s.to_dict() for s in document.symbols]}
+
+    def _search_text(self, query: str, regex: bool = False, pattern: str = "*") -> Dict[str, Any]:
+        try:
+            matches = self.repo.search_text(query if regex else re.escape(query), pattern, SearchOptions())
+        except re.error as e:
+            raise ToolError(f"Invalid regular expression: {e}")
+        return {"query": query, "matches": matches}
+
+    def _find_usages(self, symbol: str, path: Optional[str] = None) -> Dict[str, Any]:
+        files = [self._checked_path(path)] if path else None
+        by_file, _ = self.repo.parse_directory(changed_files=files)
+        definitions = [
+            s for symbols in by_file.values() for s in symbols if symbol in (s.get("node_path"), s.get("name"))
+        ]
+        if not definitions:
+            raise ToolError(f"No symbol named {symbol}")
+        usages = []
+        for definition in definitions:
+            for file, references in self.repo.find_usages(definition).items():
+                usages.extend(dict(r, file=file, symbol=definition.get("id", "")) for r in references)
+        return {"symbol": symbol, "definitions": [d.get("id", "") for d in definitions], "usages": usages}
+
+    def _get_file_tree(self) -> Dict[str, Any]:
+        return {"files": self.repo.get_file_tree()}
+
+    def _get_file_content(
+        self, path: str, start_line: Optional[int] = None, end_line: Optional[int] = None
+    ) -> Dict[str, Any]:
+        path = self._checked_path(path)
+        lines = self.repo.get_file_content(path).splitlines(keepends=True)
+        first = start_line or 1
+        last = min(end_line or len(lines), len(lines))
+        if first > last and lines:
+            raise ToolError(f"Line range {first}-{end_line} is outside {path}, which has {len(lines)} lines")
+        return {"path": path, "start_line": first, "end_line": last, "content": "".join(lines[first - 1 : last])}
+
+    def call_tool(self, name: str, arguments: Dict[str, Any]) -> Dict[str, Any]:
+        """Runs a tool and returns the ``tools/call`` result, with failures as ``isError`` results."""
+        tool = self.tools.get(name)
+        if tool is None:
+            return _tool_error(f"Unknown tool: {name}")
+        properties = tool.input_schema["properties"]
+        missing = [key for key in tool.input_schema["required"] if key not in arguments]
+        unknown = [key for key in arguments if key not in properties]
+        if missing or unknown:
+            problems = [f"missing {', '.join(missing)}"] * bool(missing) + [f"unknown {', '.join(unknown)}"] * bool(unknown)
+            return _tool_error(f"Invalid arguments for {name}: {'; '.join(problems)}")
+        for key, value in arguments.items():
+            if not _matches_type(value, properties[key]["type"]):
+                return _tool_error(f"Invalid arguments for {name}: {key} must be a {properties[key]['type']}")
+        try:
+            result = tool.handler(**arguments)
+        except (ToolError, OSError) as e:
+            return _tool_error(str(e))
+        except Exception as e:
+            logger.warning(f"Tool {name} failed: {e}", exc_info=True)
+            return _tool_error(f"{type(e).__name__}: {e}")
+        result = truncate_result(result, tool.bulk_key, self.max_result_chars)
+        return {"content": [{"type": "text", "text": json.dumps(result, ensure_ascii=False)}], "isError": False}
+
+    def handle(self, message: Any) -> Optional[Dict[str, Any]]:
+        """Answers one JSON-RPC message; notifications, which have no ``id``, get no answer."""
+        if not isinstance(message, dict) or message.get("jsonrpc") != "2.0" or "method" not in message:
+            return _error(message.get("id") if isinstance(message, dict) else None, INVALID_REQUEST, "Invalid request")
+        if "id" not in message:
+            return None
+        request_id, method, params = message["id"], message["method"], message.get("params") or {}
+        if method == "initialize":
+            return _result(request_id, {
+                "protocolVersion": params.get("protocolVersion") or PROTOCOL_VERSION,
+                "capabilities": {"tools": {"listChanged": False}},
+                "serverInfo": {"name": "codekite", "version": _version()},
+            })
+        if method == "ping":
+            return _result(request_id, {})
+        if method == "tools/list":
+            return _result(request_id, {"tools": [tool.to_dict() for tool in self.tools.values()]})
+        if method == "tools/call":
+            if not isinstance(params.get("name"), str) or not isinstance(params.get("arguments", {}), dict):
+                return _error(request_id, INVALID_PARAMS, "tools/call needs a name and an arguments object")
+            return _result(request_id, self.call_tool(params["name"], params.get("arguments") or {}))
+        return _error(request_id, METHOD_NOT_FOUND, f"Method not found: {method}")
+
+    def serve(self, stdin: TextIO, stdout: TextIO) -> None:
+        """Reads messages from *stdin* and writes answers to *stdout* until *stdin* closes."""
+        for line in stdin:
+            if not line.strip():
+                continue
+            try:
+                message = json.loads(line)
+            except json.JSONDecodeError as e:
+                response: Optional[Dict[str, Any]] = _error(None, PARSE_ERROR, f"Parse error: {e}")
+            else:
+                response = self.handle(message)
+            if response is not None:
+                stdout.write(json.dumps(response, ensure_ascii=False) + "\n")
+                stdout.flush()
+
+
+def _matches_type(value: Any, json_type: str) -> bool:
+    if json_type == "integer":
+        return isinstance(value, int) and not isinstance(value, bool)
+    return isinstance(value, {"string": str, "boolean": bool, "object": dict, "array": list}[json_type])
+
+
+def _tool_error(message: str) -> Dict[str, Any]:
+    return {"content": [{"type": "text", "text": message}], "isError": True}
+
+
+def _result(request_id: Any, result: Dict[str, Any]) -> Dict[str, Any]:
+    return {"jsonrpc": "2.0", "id": request_id, "result": result}
+
+
+def _error(request_id: Any, code: int, message: str) -> Dict[str, Any]:
+    return {"jsonrpc": "2.0", "id": request_id, "error": {"code": code, "message": message}}
+
+
+def _version() -> str:
+    import importlib.metadata
+
+    try:
+        return importlib.metadata.version("codekite")
+    except importlib.metadata.PackageNotFoundError:
+        return "unknown"
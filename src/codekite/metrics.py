@@ -0,0 +1,102 @@
+"""Size and complexity metrics of functions and methods, aggregated per file and over a repository."""
+
+from __future__ import annotations
+import math
+from dataclasses import asdict, dataclass, field
+from typing import Any, Dict, Iterable, List, Mapping
+
+# The per-symbol fields an extractor records for a function or method; Go records all three
+METRICS = ("code_lines", "param_count", "complexity")
+_MEASURED_TYPES = frozenset({"function", "method"})
+
+
+@dataclass
+class MetricSummary:
+    """
+    One metric over a set of functions and methods.
+
+    Attributes:
+        count: Functions and methods measured.
+        max: The largest value.
+        mean: The average, rounded to two decimals.
+        p95: The 95th percentile by nearest rank: the smallest value at least
+            95% of the functions do not exceed.
+    """
+
+    count: int = 0
+    max: int = 0
+    mean: float = 0.0
+    p95: int = 0
+
+    @classmethod
+    def of(cls, values: Iterable[int]) -> "MetricSummary":
+        ordered = sorted(values)
+        if not ordered:
+            return cls()
+        rank = math.ceil(0.95 * len(ordered))
+        return cls(len(ordered), ordered[-1], round(sum(ordered) / len(ordered), 2), ordered[rank - 1])
+
+    def to_dict(self) -> Dict[str, Any]:
+        return asdict(self)
+
+
+@dataclass
+class MetricsReport:
+    """
+    Function metrics of a set of files.
+
+    Attributes:
+        files: For each file with a measured function, its summary of each of
+            :data:`METRICS`, keyed by repository-relative path in walk order.
+        total: The same summaries over every measured function of every file.
+        symbols: The measured functions and methods, in file and source order.
+    """
+
+    files: Dict[str, Dict[str, MetricSummary]] = field(default_factory=dict)
+    total: Dict[str, MetricSummary] = field(default_factory=dict)
+    symbols: List[Dict[str, Any]] = field(default_factory=list)
+
+    def to_dict(self) -> Dict[str, Any]:
+        return {
+            "files": {path: {m: s.to_dict() for m, s in summaries.items()} for path, summaries in self.files.items()},
+            "total": {m: s.to_dict() for m, s in self.total.items()},
+        }
+
+
+def is_measured(symbol: Dict[str, Any]) -> bool:
+    """Tells whether *symbol* is a function or method its extractor recorded metrics for."""
+    return symbol.get("type") in _MEASURED_TYPES and all(m in symbol for m in METRICS)
+
+
+def _summaries(symbols: List[Dict[str, Any]]) -> Dict[str, MetricSummary]:
+    return {m: MetricSummary.of(s[m] for s in symbols) for m in METRICS}
+
+
+def compute_metrics(by_file: Mapping[str, List[Dict[str, Any]]]) -> MetricsReport:
+    """
+    Aggregates the metrics of the functions and methods of *by_file*, symbols keyed by path.
+
+    Each function or method carries its own metrics from extraction:
+
+    * ``code_lines``: lines of the declaration holding code, leaving out
+      blank lines and lines holding only comments.
+    * ``param_count``: declared parameters, each name counting once, so
+      ``(a, b int)`` is two; a receiver is not a parameter.
+    * ``complexity``: cyclomatic complexity, 1 plus each branch of the body.
+      For Go that is each ``if``, ``for``, ``case`` of a ``switch`` or
+      ``select`` other than ``default``, ``&&`` and ``||``, as gocyclo counts
+      them; ``fallthrough`` adds no path, and function literals count
+      towards the function that holds them.
+
+    Go functions and methods have them today. An extractor for another
+    language opts in by recording the same fields; symbols without them,
+    such as Python functions, are left out of every summary.
+    """
+    report = MetricsReport()
+    for path, symbols in by_file.items():
+        measured = [s for s in symbols if is_measured(s)]
+        if measured:
+            report.files[path] = _summaries(measured)
+            report.symbols.extend(measured)
+    report.total = _summaries(report.symbols)
+    return report
@@ -0,0 +1,265 @@
+"""Finding the places a symbol is referenced, using the syntax tree rather than plain text."""
+
+from __future__ import annotations
+import logging
+import os
+from typing import TYPE_CHECKING, Any, Dict, Iterator, List, Optional, Tuple
+
+from .import_graph import IMPORT_BLANK, IMPORT_DOT, extract_go_imports, go_module_path, package_import_path
+from .tree_sitter_symbol_extractor import TreeSitterSymbolExtractor, _char_column, _node_text
+
+if TYPE_CHECKING:
+    from .repository import Repository
+
+logger = logging.getLogger(__name__)
+
+REFERENCE_DEFINITION = "definition"
+REFERENCE_USAGE = "usage"
+
+# Nodes whose `name` field declares something; a matching name there is not a reference
+_DECLARATION_TYPES = frozenset(
+    {
+        "function_declaration", "method_declaration", "type_spec", "type_alias", "const_spec", "var_spec",
+        "field_declaration", "method_elem", "method_spec", "parameter_declaration", "type_parameter_declaration",
+        "function_definition", "class_definition", "class_declaration", "method_definition", "interface_declaration",
+        "function_item", "struct_item", "enum_item", "trait_item", "function_signature_item", "type_item",
+    }
+)
+# Go symbol kinds that are reached through a value (x.Name) rather than a package
+_GO_MEMBER_TYPES = frozenset({"method", "field"})
+
+
+def _identifiers(root: Any, name: str) -> Iterator[Any]:
+    """Yields identifier-like leaves spelled *name*; comments and string literals never contain them."""
+    stack = [root]
+    while stack:
+        node = stack.pop()
+        if node.type.endswith("identifier") and _node_text(node) == name:
+            yield node
+            continue
+        stack.extend(reversed(node.children))
+
+
+def _is_declared_name(node: Any) -> bool:
+    parent = node.parent
+    if parent is None or parent.type not in _DECLARATION_TYPES:
+        return False
+    return any(n.start_byte == node.start_byte for n in parent.children_by_field_name("name"))
+
+
+def _go_qualifier(node: Any) -> Optional[Any]:
+    """Returns the operand of ``x.Name`` or the package of ``pkg.Type`` when *node* is the Name part."""
+    parent = node.parent
+    if parent is None:
+        return None
+    if parent.type == "selector_expression":
+        field = parent.child_by_field_name("field")
+        if field is not None and field.start_byte == node.start_byte:
+            return parent.child_by_field_name("operand")
+    if parent.type == "qualified_type":
+        return parent.child_by_field_name("package")
+    return None
+
+
+def _go_type_param_names(declaration: Any) -> List[str]:
+    """The type parameters a Go declaration binds: ``[K, V any]``, or for a method the receiver's ``Set[T]``."""
+    names = []
+    params = declaration.child_by_field_name("type_parameters")
+    if params is not None:
+        for param in params.named_children:
+            names.extend(_node_text(name) for name in param.children_by_field_name("name"))
+    receiver = declaration.child_by_field_name("receiver") if declaration.type == "method_declaration" else None
+    stack = [receiver] if receiver is not None else []
+    while stack:
+        node = stack.pop()
+        if node.type == "type_arguments":
+            for argument in node.named_children:
+                # Newer grammars wrap each argument in a type_elem
+                inner = argument.named_children if argument.type == "type_elem" else [argument]
+                names.extend(_node_text(n) for n in inner if n.type == "type_identifier")
+            continue
+        stack.extend(node.named_children)
+    return names
+
+
+def _go_is_type_param(node: Any) -> bool:
+    """True when a bare identifier names a type parameter of an enclosing generic declaration."""
+    name = _node_text(node)
+    ancestor = node.parent
+    while ancestor is not None:
+        if ancestor.type in ("function_declaration", "method_declaration", "type_spec", "type_alias"):
+            return name in _go_type_param_names(ancestor)
+        ancestor = ancestor.parent
+    return False
+
+
+def _go_package_name(root: Any) -> Optional[str]:
+    for child in root.named_children:
+        if child.type == "package_clause":
+            for name in child.named_children:
+                if name.type == "package_identifier":
+                    return _node_text(name)
+    return None
+
+
+class ReferenceFinder:
+    """
+    Finds references to a symbol across a repository.
+
+    Go references are matched by identifier and scoped by package: inside the
+    defining package a bare ``Add`` refers to it, elsewhere only ``pkg.Add``
+    through an import of that package does (and only for exported names).
+    Methods and fields match any ``x.Name`` selector in packages that can see
+    them. A type parameter (the ``T`` of ``func Map[T any]``) is never taken
+    for a package-level type of the same name. Other languages match
+    identifiers of the same name in files of the same language. No type
+    checking is done, so a local variable shadowing the name is still reported.
+    """
+
+    def __init__(self, repository: "Repository"):
+        self.repo = repository
+        self._symbols: Dict[str, List[Dict[str, Any]]] = {}
+
+    def _module_path(self) -> Optional[str]:
+        go_mod = self.repo.local_path / "go.mod"
+        return go_module_path(go_mod.read_text(encoding="utf-8")) if go_mod.is_file() else None
+
+    def _files(self, ext: str) -> List[str]:
+        root = self.repo.mapper.repo_path
+        return [f.relative_to(root).as_posix() for f in self.repo.mapper.source_files() if f.suffix == ext]
+
+    def find_usages(self, symbol: Dict[str, Any]) -> Dict[str, List[Dict[str, Any]]]:
+        """
+        Returns every reference to *symbol*, grouped by repository-relative file.
+
+        Args:
+            symbol: A symbol as returned by :meth:`Repository.extract_symbols`; it
+                needs ``name``, ``type`` and ``file``.
+
+        Returns:
+            Files in sorted order, each with its references in source order. A
+            reference has ``line`` and ``column`` (0-based), ``kind``
+            (``"definition"`` for the symbol's own name, otherwise ``"usage"``),
+            ``enclosing`` (the innermost symbol containing it, e.g. ``"main"`` or
+            ``"User.Greet"``, or None at top level) and ``context`` (the source line).
+        """
+        name, file = symbol.get("name"), symbol.get("file")
+        if not name or not file:
+            raise ValueError("symbol needs a name and a file")
+        ext = os.path.splitext(file)[1]
+        parser = TreeSitterSymbolExtractor.get_parser(ext)
+        if parser is None:
+            return {}
+
+        go_scope = self._go_scope(symbol, parser) if ext == ".go" else None
+        results: Dict[str, List[Dict[str, Any]]] = {}
+        for path in self._files(ext):
+            try:
+                source = self.repo.get_file_content(path)
+            except (IOError, UnicodeDecodeError) as e:
+                logger.warning(f"Skipping references in {path}: {e}")
+                continue
+            if name not in source:
+                continue
+            source_bytes = source.encode("utf-8")
+            root = parser.parse(source_bytes).root_node
+            references = []
+            for node, kind in self._matches(root, path, symbol, source, go_scope):
+                references.append(self._reference(node, kind, path, symbol, source_bytes))
+            if references:
+                results[path] = sorted(references, key=lambda r: (r["line"], r["column"]))
+        return dict(sorted(results.items()))
+
+    def _go_scope(self, symbol: Dict[str, Any], parser: Any) -> Dict[str, Any]:
+        """The defining package of a Go symbol: its directory, package clause name and import path."""
+        source = self.repo.get_file_content(symbol["file"])
+        return {
+            "dir": os.path.dirname(symbol["file"]),
+            "package": _go_package_name(parser.parse(source.encode("utf-8")).root_node),
+            "import_path": package_import_path(symbol["file"], self._module_path()),
+        }
+
+    def _matches(
+        self, root: Any, path: str, symbol: Dict[str, Any], source: str, go_scope: Optional[Dict[str, Any]]
+    ) -> Iterator[Tuple[Any, str]]:
+        definition_found = False
+        importers = None
+        same_package = True
+        if go_scope is not None:
+            same_package = os.path.dirname(path) == go_scope["dir"] and _go_package_name(root) == go_scope["package"]
+            if not same_package:
+                importers = self._go_import_names(source, go_scope["import_path"])
+                if importers is None:
+                    return
+        for node in _identifiers(root, symbol["name"]):
+            if _is_declared_name(node):
+                if not definition_found and path == symbol["file"] and self._within(node, symbol):
+                    definition_found = True
+                    yield node, REFERENCE_DEFINITION
+                continue
+            if go_scope is None or self._go_visible(node, symbol, same_package, importers):
+                yield node, REFERENCE_USAGE
+
+    @staticmethod
+    def _within(node: Any, symbol: Dict[str, Any]) -> bool:
+        if "start_byte" in symbol and "end_byte" in symbol:
+            return symbol["start_byte"] <= node.start_byte < symbol["end_byte"]
+        return symbol.get("start_line", -1) <= node.start_point[0] <= symbol.get("end_line", -1)
+
+    @staticmethod
+    def _go_import_names(source: str, import_path: str) -> Optional[Dict[str, Any]]:
+        """How a file refers to the package at *import_path*: ``{"names": [...], "dot": bool}``, or None."""
+        names, dot = [], False
+        for entry in extract_go_imports(source):
+            if entry["path"] != import_path or entry["kind"] == IMPORT_BLANK:
+                continue
+            if entry["kind"] == IMPORT_DOT:
+                dot = True
+            else:
+                names.append(entry.get("alias") or import_path.rsplit("/", 1)[-1])
+        return {"names": names, "dot": dot} if names or dot else None
+
+    @staticmethod
+    def _go_visible(node: Any, symbol: Dict[str, Any], same_package: bool, importers: Optional[Dict[str, Any]]) -> bool:
+        qualifier = _go_qualifier(node)
+        if qualifier is None and _go_is_type_param(node):
+            # The T in func Map[T any] shadows any package-level T
+            return False
+        exported = symbol["name"][:1].isupper()
+        if symbol.get("type") in _GO_MEMBER_TYPES:
+            # x.Greet(): any selector, as long as the member is reachable from this package
+            return qualifier is not None and (same_package or exported)
+        if same_package:
+            return qualifier is None
+        if not exported:
+            return False
+        if qualifier is None:
+            return importers["dot"]
+        return qualifier.type in ("identifier", "package_identifier") and _node_text(qualifier) in importers["names"]
+
+    def _reference(
+        self, node: Any, kind: str, path: str, symbol: Dict[str, Any], source_bytes: bytes
+    ) -> Dict[str, Any]:
+        enclosing = None
+        for candidate in self._file_symbols(path):
+            if "start_byte" not in candidate or not candidate["start_byte"] <= node.start_byte < candidate["end_byte"]:
+                continue
+            if kind == REFERENCE_DEFINITION and candidate["start_byte"] == symbol.get("start_byte"):
+                # The definition is not enclosed by itself
+                continue
+            if enclosing is None or candidate["end_byte"] - candidate["start_byte"] < enclosing["end_byte"] - enclosing["start_byte"]:
+                enclosing = candidate
+        line_start = source_bytes.rfind(b"\n", 0, node.start_byte) + 1
+        line_end = source_bytes.find(b"\n", node.start_byte)
+        return {
+            "line": node.start_point[0],
+            "column": _char_column(source_bytes, node.start_byte, node.start_point[1]),
+            "kind": kind,
+            "enclosing": (enclosing.get("node_path") or enclosing["name"]) if enclosing else None,
+            "context": source_bytes[line_start : line_end if line_end >= 0 else None].decode("utf-8", errors="ignore").strip(),
+        }
+
+    def _file_symbols(self, path: str) -> List[Dict[str, Any]]:
+        if path not in self._symbols:
+            self._symbols[path] = self.repo.extract_symbols(path)
+        return self._symbols[path]
@@ -0,0 +1,195 @@
+"""Shallow clones of remote repositories, cached on disk and shared between opens."""
+
+from __future__ import annotations
+import base64
+import hashlib
+import logging
+import os
+import re
+import shutil
+import subprocess
+import sys
+import tempfile
+import threading
+import time
+from contextlib import contextmanager
+from pathlib import Path
+from typing import Dict, Iterator, List, Optional
+from urllib.parse import urlsplit
+
+logger = logging.getLogger(__name__)
+
+_REMOTE_SCHEMES = ("http://", "https://", "ssh://", "git://", "file://")
+# scp-like "git@github.com:org/repo.git"
+_SCP_URL = re.compile(r"^[\w.-]+@[\w.-]+:")
+# Touched on every open; eviction goes by its mtime rather than the clone's age
+_LAST_USED = ".codekite-last-used"
+
+_thread_locks: Dict[str, threading.Lock] = {}
+_thread_locks_guard = threading.Lock()
+
+
+def is_remote_url(path_or_url: str) -> bool:
+    """True for git URLs (``https://``, ``ssh://``, ``file://``, ``git@host:path``) rather than local paths."""
+    return path_or_url.startswith(_REMOTE_SCHEMES) or bool(_SCP_URL.match(path_or_url))
+
+
+def default_cache_dir() -> Path:
+    """
+    The per-user cache directory clones go to: ``$CODEKITE_CACHE_DIR`` if set,
+    otherwise ``codekite`` under the platform's user cache directory.
+    """
+    override = os.environ.get("CODEKITE_CACHE_DIR")
+    if override:
+        return Path(override).expanduser()
+    if sys.platform == "win32":
+        base = os.environ.get("LOCALAPPDATA") or os.path.expanduser("~/AppData/Local")
+    elif sys.platform == "darwin":
+        base = os.path.expanduser("~/Library/Caches")
+    else:
+        base = os.environ.get("XDG_CACHE_HOME") or os.path.expanduser("~/.cache")
+    return Path(base) / "codekite"
+
+
+def cache_key(url: str, ref: Optional[str] = None) -> str:
+    """A readable, filesystem-safe directory name unique to *url* and *ref*."""
+    parts = urlsplit(url) if "://" in url else urlsplit("ssh://" + url.replace(":", "/", 1))
+    # Credentials in the URL must not end up in directory names
+    host = parts.netloc.rsplit("@", 1)[-1]
+    readable = re.sub(r"[^\w.-]+", "-", f"{host}{parts.path}".removesuffix(".git")).strip("-")
+    if ref:
+        readable += "@" + re.sub(r"[^\w.-]+", "-", ref)
+    digest = hashlib.sha256(f"{url}\0{ref or ''}".encode("utf-8")).hexdigest()[:12]
+    return f"{readable[:80]}-{digest}"
+
+
+@contextmanager
+def _clone_lock(lock_path: Path) -> Iterator[None]:
+    """Serialises clones of one cache entry across threads and, through a lock file, processes."""
+    with _thread_locks_guard:
+        thread_lock = _thread_locks.setdefault(str(lock_path), threading.Lock())
+    with thread_lock, open(lock_path, "a+b") as lock_file:
+        if sys.platform == "win32":
+            import msvcrt
+
+            lock_file.seek(0)
+            msvcrt.locking(lock_file.fileno(), msvcrt.LK_LOCK, 1)
+            try:
+                yield
+            finally:
+                lock_file.seek(0)
+                msvcrt.locking(lock_file.fileno(), msvcrt.LK_UNLCK, 1)
+        else:
+            import fcntl
+
+            fcntl.flock(lock_file.fileno(), fcntl.LOCK_EX)
+            try:
+                yield
+            finally:
+                fcntl.flock(lock_file.fileno(), fcntl.LOCK_UN)
+
+
+def _git(args: List[str], token: Optional[str], cwd: Optional[str] = None) -> None:
+    # Fail instead of waiting for a password when no credential helper can answer
+    env = dict(os.environ, GIT_TERMINAL_PROMPT="0")
+    secrets: List[str] = []
+    if token:
+        # Sent as a header for this command only, through the environment rather than the command
+        # line that other users can list, so the token is never written to .git/config either
+        credentials = base64.b64encode(f"x-access-token:{token}".encode("utf-8")).decode("ascii")
+        count = int(env.get("GIT_CONFIG_COUNT") or 0)
+        env["GIT_CONFIG_COUNT"] = str(count + 1)
+        env[f"GIT_CONFIG_KEY_{count}"] = "http.extraHeader"
+        env[f"GIT_CONFIG_VALUE_{count}"] = f"Authorization: Basic {credentials}"
+        secrets = [credentials, token]
+    try:
+        subprocess.run(["git"] + args, cwd=cwd, env=env, check=True, capture_output=True)
+    except subprocess.CalledProcessError as e:
+        message = e.stderr.decode("utf-8", errors="replace").strip()
+        for secret in secrets:
+            message = message.replace(secret, "***")
+        raise RuntimeError(f"git {args[0]} failed: {message}") from e
+
+
+def _shallow_clone(url: str, ref: Optional[str], token: Optional[str], target: Path) -> None:
+    if ref is None:
+        _git(["clone", "--depth=1", "--", url, str(target)], token)
+        return
+    # Fetching the ref directly works for branches, tags and (on most hosts) commit SHAs alike
+    target.mkdir(parents=True)
+    _git(["init", "-q"], token, cwd=str(target))
+    _git(["remote", "add", "origin", url], token, cwd=str(target))
+    _git(["fetch", "-q", "--depth=1", "origin", ref], token, cwd=str(target))
+    _git(["checkout", "-q", "--detach", "FETCH_HEAD"], token, cwd=str(target))
+
+
+def clone_cached(
+    url: str, ref: Optional[str] = None, token: Optional[str] = None, cache_dir: Optional[str] = None
+) -> Path:
+    """
+    Returns a shallow checkout of *url* at *ref*, cloning it into the cache on first use.
+
+    Later calls with the same URL and ref reuse the checkout without touching
+    the network. Concurrent calls, from threads or processes, wait for a single
+    clone rather than racing: the clone is made in a temporary directory and
+    renamed into place, so an interrupted clone never looks complete.
+
+    Args:
+        url: Any URL ``git clone`` accepts.
+        ref: Branch, tag or commit to check out. Defaults to the remote's default branch.
+        token: Access token for private HTTPS repositories. Without one, git's
+            configured credential helpers are used.
+        cache_dir: Where checkouts are kept. Defaults to :func:`default_cache_dir`.
+
+    Raises:
+        RuntimeError: If git fails, for example for an unknown ref or missing credentials.
+    """
+    root = Path(cache_dir).expanduser() if cache_dir else default_cache_dir()
+    root.mkdir(parents=True, exist_ok=True)
+    key = cache_key(url, ref)
+    checkout = root / key
+    with _clone_lock(root / f"{key}.lock"):
+        if not checkout.is_dir():
+            staging = Path(tempfile.mkdtemp(prefix=f"{key}.", suffix=".tmp", dir=root))
+            try:
+                _shallow_clone(url, ref, token, staging / "checkout")
+                os.replace(staging / "checkout", checkout)
+            finally:
+                shutil.rmtree(staging, ignore_errors=True)
+            logger.info(f"Cloned {url}{'@' + ref if ref else ''} into {checkout}")
+        (checkout / ".git" / _LAST_USED).touch()
+    return checkout
+
+
+def remove_clone(checkout: Path) -> None:
+    """Deletes one cached checkout, waiting for any clone of it in progress."""
+    with _clone_lock(checkout.parent / f"{checkout.name}.lock"):
+        shutil.rmtree(checkout, ignore_errors=True)
+
+
+def _last_used(checkout: Path) -> float:
+    marker = checkout / ".git" / _LAST_USED
+    return marker.stat().st_mtime if marker.exists() else checkout.stat().st_mtime
+
+
+def evict_clones(max_age: float, cache_dir: Optional[str] = None) -> List[Path]:
+    """
+    Deletes cached checkouts that have not been opened for *max_age* seconds.
+
+    Returns:
+        The checkouts that were removed.
+    """
+    root = Path(cache_dir).expanduser() if cache_dir else default_cache_dir()
+    if not root.is_dir():
+        return []
+    cutoff = time.time() - max_age
+    removed = []
+    for checkout in sorted(root.iterdir()):
+        if not checkout.is_dir() or checkout.suffix == ".tmp" or _last_used(checkout) >= cutoff:
+            continue
+        with _clone_lock(root / f"{checkout.name}.lock"):
+            # Checked again under the lock: another process may have just opened it
+            if checkout.is_dir() and _last_used(checkout) < cutoff:
+                shutil.rmtree(checkout, ignore_errors=True)
+                removed.append(checkout)
+    return removed
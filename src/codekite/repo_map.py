@@ -0,0 +1,128 @@
+"""A compact, ranked outline of a repository's top-level symbols for priming LLM prompts."""
+
+from __future__ import annotations
+import logging
+import posixpath
+from dataclasses import dataclass, field
+from pathlib import Path
+from typing import TYPE_CHECKING, Any, Dict, Iterable, List, Optional
+
+from .languages import is_test_file
+from .tokenizers import HeuristicTokenizer, Tokenizer
+
+if TYPE_CHECKING:
+    from .repository import Repository
+
+logger = logging.getLogger(__name__)
+
+def _top_level(symbols: List[Dict[str, Any]]) -> List[Dict[str, Any]]:
+    """Symbols no other symbol's span contains, such as Go methods but not Python methods, in source order."""
+    top: List[Dict[str, Any]] = []
+    for symbol in sorted(symbols, key=lambda s: (s.get("start_line", 0), -s.get("end_line", 0))):
+        span = (symbol.get("start_line", 0), symbol.get("end_line", 0))
+        if top:
+            outer = (top[-1].get("start_line", 0), top[-1].get("end_line", 0))
+            if outer[0] <= span[0] and span[1] <= outer[1] and span != outer:
+                continue
+        top.append(symbol)
+    return top
+
+
+def _outline_line(path: str, symbol: Dict[str, Any]) -> str:
+    signature = symbol.get("signature") or f"{symbol.get('type', 'symbol')} {symbol.get('node_path') or symbol.get('name')}"
+    return f"{path}: {signature}"
+
+
+@dataclass
+class RepoMap:
+    """
+    The result of :meth:`Repository.repo_map`.
+
+    Attributes:
+        text: One line per top-level symbol, ``path: signature``, files in path order.
+        tokens: Tokens *text* takes, as the tokenizer counts them.
+        files: Files in the map: pinned ones, then the rest most central first.
+        omitted: Files that did not fit the budget, most central first.
+        ranks: Each ranked file's score: how many repository files import it.
+    """
+
+    text: str
+    tokens: int
+    files: List[str] = field(default_factory=list)
+    omitted: List[str] = field(default_factory=list)
+    ranks: Dict[str, int] = field(default_factory=dict)
+
+
+def build_repo_map(
+    repo: "Repository",
+    max_tokens: int = 1024,
+    pinned: Iterable[str] = (),
+    exclude_tests: bool = False,
+    tokenizer: Optional[Tokenizer] = None,
+) -> RepoMap:
+    """
+    Outlines the repository's top-level symbol signatures within a token budget.
+
+    Files are ranked by how many other files import them according to the
+    dependency graph, then by symbol count, then by path; when the graph
+    cannot be built, symbol count alone decides. Files are added whole, from
+    the top of the ranking, skipping any that no longer fit, so the map never
+    cuts a file's outline short.
+
+    Args:
+        repo: The repository to outline.
+        max_tokens: Budget for the whole outline.
+        pinned: Repository-relative paths that are always included, ahead of
+            the ranking and even past the budget.
+        exclude_tests: Leave out test files (see :func:`is_test_file`) unless pinned.
+        tokenizer: Counts tokens; defaults to :class:`~codekite.tokenizers.HeuristicTokenizer`.
+
+    Raises:
+        ValueError: If *max_tokens* is not positive.
+    """
+    if max_tokens <= 0:
+        raise ValueError("max_tokens must be positive")
+    tokenizer = tokenizer or HeuristicTokenizer()
+    root = repo.mapper.repo_path
+    outlines: Dict[str, List[str]] = {}
+    for file, symbols in repo.mapper.get_repo_map()["symbols"].items():
+        path = Path(file).relative_to(root).as_posix() if Path(file).is_absolute() else Path(file).as_posix()
+        lines = [_outline_line(path, s) for s in _top_level(symbols)]
+        if lines:
+            outlines[path] = lines
+
+    try:
+        graph = repo.get_dependency_graph()
+        dependents = {path: len(graph.dependents_of(path, transitive=False)) for path in outlines}
+    except Exception as e:
+        logger.warning(f"Ranking the repository map by symbol count, the dependency graph failed: {e}")
+        dependents = {path: 0 for path in outlines}
+
+    pinned_paths = [posixpath.normpath(p) for p in pinned]
+    ranked = sorted(
+        (p for p in outlines if p not in pinned_paths and not (exclude_tests and is_test_file(p))),
+        key=lambda p: (-dependents[p], -len(outlines[p]), p),
+    )
+
+    included = list(dict.fromkeys(p for p in pinned_paths if p in outlines))
+    pinned_count = len(included)
+    for path in pinned_paths:
+        if path not in outlines:
+            logger.warning(f"Pinned path {path} has no symbols to outline")
+    remaining = max_tokens - sum(tokenizer.count("\n".join(outlines[p]) + "\n") for p in included)
+    for path in ranked:
+        cost = tokenizer.count("\n".join(outlines[path]) + "\n")
+        if cost <= remaining:
+            included.append(path)
+            remaining -= cost
+
+    def render(paths: List[str]) -> str:
+        return "".join("\n".join(outlines[p]) + "\n" for p in sorted(paths))
+
+    text = render(included)
+    # Block counts only approximate the joined text's; drop the least central files (never pinned ones) until it fits
+    while tokenizer.count(text) > max_tokens and len(included) > pinned_count:
+        included.pop()
+        text = render(included)
+    omitted = [p for p in ranked if p not in included]
+    return RepoMap(text, tokenizer.count(text), included, omitted, {p: dependents[p] for p in ranked})
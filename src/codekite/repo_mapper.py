@@ -1,11 +1,92 @@
 from __future__ import annotations
 import os
+import posixpath
 import time
 import logging
+import threading
+import itertools
+from collections import deque
+from concurrent.futures import Future, ThreadPoolExecutor
+from dataclasses import dataclass
+from datetime import datetime
 from pathlib import Path
-from typing import Any, Dict, List, Optional
+from typing import Any, Callable, Deque, Dict, Generator, Iterable, Iterator, List, Optional, Tuple, Union
 import pathspec
-from .tree_sitter_symbol_extractor import TreeSitterSymbolExtractor
+from . import languages
+from .code_origin import is_generated, is_vendored, origin_fields, read_header
+from .encodings import decode_source, read_text
+from .file_limits import FileLimits, check_content, check_size
+from .go_build import BuildContext
+from .go_types import GoTypeResolver
+from .ignore import IgnoreRules
+from .import_graph import go_module_path
+from .symbol_ids import assign_ids, go_package
+from .tree_sitter_symbol_extractor import ExtractionOptions
+
+
+# Directories that never contain first-party source worth indexing; version-control
+# directories such as .git are skipped by the ignore rules for every walk
+DEFAULT_SKIP_DIRS = frozenset({"vendor", "node_modules"})
+
+# How many directories deep a walk that follows symlinks may go, as a backstop to loop detection
+MAX_FOLLOW_DEPTH = 40
+
+# Severities of the diagnostics parse_directory reports
+SEVERITY_ERROR = "error"
+SEVERITY_WARNING = "warning"
+SEVERITY_INFO = "info"
+
+
+class ExtractionCancelled(Exception):
+    """
+    Raised by :meth:`RepoMapper.parse_directory` when its cancel event is set before every file was parsed.
+
+    ``symbols`` and ``errors`` hold the partial results, in the same shape as a
+    completed call returns them.
+    """
+
+    def __init__(
+        self, symbols: Dict[str, List[Dict[str, Any]]], errors: List[Dict[str, Any]], files: Optional[int] = None
+    ) -> None:
+        if files is None:
+            # A file can have several diagnostics, or symbols and diagnostics both
+            files = len(set(symbols) | {e["file"] for e in errors})
+        super().__init__(f"Extraction cancelled after {files} files")
+        self.symbols = symbols
+        self.errors = errors
+
+
+@dataclass
+class FileSymbols:
+    """
+    One file's results, as :meth:`RepoMapper.iter_directory` yields them.
+
+    Attributes:
+        path: Repository-relative path.
+        symbols: The file's symbols, or None if it could not be parsed or was skipped.
+        diagnostics: Its entries of :meth:`RepoMapper.parse_directory`'s errors, in the same shape.
+    """
+
+    path: str
+    symbols: Optional[List[Dict[str, Any]]]
+    diagnostics: List[Dict[str, Any]]
+
+
+# What parsing one file produces: its path, its symbols (None on failure) and its diagnostics
+_Parsed = Tuple[str, Optional[List[Dict[str, Any]]], List[Dict[str, Any]]]
+
+
+def _inode(path: Path) -> Tuple[int, int]:
+    info = path.stat()
+    return info.st_dev, info.st_ino
+
+
+def _mtime(file: Path) -> float:
+    try:
+        return file.stat().st_mtime
+    except OSError:
+        # Kept, so parse_directory reports why the file cannot be read
+        return float("inf")
 
 
 class RepoMapper:
@@ -15,48 +96,113 @@ class RepoMapper:
     Supports multi-language via tree-sitter queries.
     """
 
-    def __init__(self, repo_path: str) -> None:
+    def __init__(
+        self,
+        repo_path: str,
+        respect_gitignore: bool = True,
+        fallback_encoding: Optional[str] = None,
+        follow_symlinks: bool = False,
+    ) -> None:
         self.repo_path: Path = Path(repo_path)
+        # Encoding for files that are not valid UTF-8 and have no BOM; see codekite.encodings.decode_source
+        self.fallback_encoding = fallback_encoding
+        # Descend into symlinked directories; see _walk for the loop and escape rules
+        self.follow_symlinks = follow_symlinks
+        self._real_root = os.path.realpath(self.repo_path)
         self._symbol_map: Dict[str, Dict[str, Any]] = {}  # file -> {mtime, symbols}
         self._file_tree: Optional[List[Dict[str, Any]]] = None
-        self._gitignore_spec = self._load_gitignore()
-
-    def _load_gitignore(self):
-        gitignore_path = self.repo_path / ".gitignore"
-        if gitignore_path.exists():
-            with open(gitignore_path) as f:
-                return pathspec.PathSpec.from_lines("gitwildmatch", f)
-        return None
-
-    def _should_ignore(self, file: Path) -> bool:
-        rel_path = str(file.relative_to(self.repo_path))
-        # Always ignore .git and its contents
-        if ".git" in file.parts:
-            return True
-        # Ignore files matching .gitignore
-        if self._gitignore_spec and self._gitignore_spec.match_file(rel_path):
-            return True
-        return False
+        self._ignore_rules = IgnoreRules(self.repo_path, respect_gitignore)
+        self._go_types = GoTypeResolver()
+        # (mtime, module path) of the root go.mod, re-read when it changes
+        self._go_mod: Optional[Tuple[float, Optional[str]]] = None
+
+    def _should_ignore(self, file: Path, is_dir: Optional[bool] = None) -> bool:
+        return self._ignore_rules.is_ignored(file, is_dir)
+
+    def is_ignored(self, path: Path, is_dir: Optional[bool] = None) -> bool:
+        """Whether .gitignore or .codekiteignore rules, or the default skips such as ``.git``, leave *path* out."""
+        return self._should_ignore(path, is_dir)
+
+    def _walk(
+        self, start: Optional[Path] = None, skip: Optional[Callable[[Path, bool], bool]] = None
+    ) -> Iterator[Tuple[Path, bool]]:
+        """
+        Yields ``(path, is_dir)`` for every path under *start* that is not ignored, in sorted order.
+
+        Ignored directories are pruned rather than filtered, so their contents are
+        never visited. *skip* can reject further paths the same way.
+
+        Symlinked directories are left out unless ``follow_symlinks`` is set.
+        When it is, each directory is visited once, by its device and inode,
+        so a link to an ancestor cannot loop, and the walk stops
+        :data:`MAX_FOLLOW_DEPTH` levels down. Symlinked files are yielded under
+        their link path, broken ones included. A symlink whose target is
+        outside the repository is never yielded.
+        """
+        start = start or self.repo_path
+        visited = {_inode(start)}
+        for dirpath, dirnames, filenames in os.walk(start, followlinks=self.follow_symlinks):
+            current = Path(dirpath)
+            at_limit = self.follow_symlinks and len(current.relative_to(start).parts) >= MAX_FOLLOW_DEPTH
+            kept = []
+            for name in sorted(dirnames):
+                path = current / name
+                if path.is_symlink() and not (self.follow_symlinks and self._inside_root(path)):
+                    continue
+                if not at_limit and not self._should_ignore(path, is_dir=True) and not (skip and skip(path, True)):
+                    kept.append(name)
+            if self.follow_symlinks:
+                # Real directories claim their inode before the links to them do
+                fresh = set()
+                for name in sorted(kept, key=lambda n: (current / n).is_symlink()):
+                    inode = _inode(current / name)
+                    if inode not in visited:
+                        visited.add(inode)
+                        fresh.add(name)
+                kept = [name for name in kept if name in fresh]
+            # Prune in place so skipped trees are never descended into
+            dirnames[:] = kept
+            for name in kept:
+                yield current / name, True
+            for name in sorted(filenames):
+                path = current / name
+                if path.is_symlink() and not self._inside_root(path):
+                    continue
+                if not self._should_ignore(path, is_dir=False) and not (skip and skip(path, False)):
+                    yield path, False
+
+    def _inside_root(self, path: Path) -> bool:
+        """Whether *path*, after resolving every symlink, is inside the repository."""
+        target = os.path.realpath(path)
+        return target == self._real_root or target.startswith(self._real_root + os.sep)
+
+    def invalidate_file_tree(self) -> None:
+        """Drops the tree cached by :meth:`get_file_tree`, so the next call walks the repository again."""
+        self._file_tree = None
 
     def get_file_tree(self) -> List[Dict[str, Any]]:
         """
         Returns a list of dicts representing all files in the repo.
         Each dict contains: path, size, mtime, is_file.
+        Paths excluded by .gitignore or .codekiteignore files at any level are left out.
+        Vendored and generated files also have ``vendored: True`` or
+        ``generated: True``; see :mod:`codekite.code_origin`.
         """
         if self._file_tree is not None:
             return self._file_tree
         tree = []
-        for path in self.repo_path.rglob("*"):
-            if self._should_ignore(path):
-                continue
-            tree.append(
-                {
-                    "path": str(path.relative_to(self.repo_path)),
-                    "is_dir": path.is_dir(),
-                    "name": path.name,
-                    "size": path.stat().st_size if path.is_file() else 0,
-                }
-            )
+        for path, is_dir in self._walk():
+            entry = {
+                "path": str(path.relative_to(self.repo_path)),
+                "is_dir": is_dir,
+                "name": path.name,
+                "size": path.stat().st_size if not is_dir and path.exists() else 0,
+            }
+            if not is_dir:
+                rel_path = path.relative_to(self.repo_path).as_posix()
+                # Only the header is read; a generated file is marked near its top
+                entry.update(origin_fields(rel_path, read_header(path)))
+            tree.append(entry)
         self._file_tree = tree
         return tree
 
@@ -65,13 +211,9 @@ class RepoMapper:
         Scan all supported files and update symbol map incrementally.
         Uses mtime to avoid redundant parsing.
         """
-        for file in self.repo_path.rglob("*"):
-            if not file.is_file():
-                continue
-            if self._should_ignore(file):
-                continue
-            ext = file.suffix.lower()
-            if ext in TreeSitterSymbolExtractor.LANGUAGES or ext == ".py":
+        extensions = languages.supported_extensions()
+        for file, is_dir in self._walk():
+            if not is_dir and file.suffix.lower() in extensions:
                 self._scan_file(file)
 
     def _scan_file(self, file: Path) -> None:
@@ -85,26 +227,46 @@ class RepoMapper:
         except Exception as e:
             logging.warning(f"Error scanning file {file}: {e}", exc_info=True)
 
+    def module_path(self) -> Optional[str]:
+        """The Go module path declared by the repository's root go.mod, or None."""
+        go_mod = self.repo_path / "go.mod"
+        try:
+            mtime = go_mod.stat().st_mtime
+        except OSError:
+            return None
+        if self._go_mod is None or self._go_mod[0] != mtime:
+            try:
+                self._go_mod = (mtime, go_module_path(read_text(go_mod)))
+            except (OSError, UnicodeDecodeError) as e:
+                logging.warning(f"Could not read {go_mod}: {e}")
+                return None
+        return self._go_mod[1]
+
+    def _go_package(self, rel_path: str, code: str) -> Optional[str]:
+        """The Go package qualifying the symbols of *rel_path*; see :func:`~codekite.symbol_ids.go_package`."""
+        return go_package(rel_path, code, self.module_path())
+
     def _extract_symbols_from_file(self, file: Path) -> List[Dict[str, Any]]:
         ext = file.suffix.lower()
         try:
-            with open(file, "r", encoding="utf-8", errors="ignore") as f:
-                code = f.read()
+            code = read_text(file, self.fallback_encoding)
         except Exception as e:
             logging.warning(f"Could not read file {file} for symbol extraction: {e}")
             return []
-        if ext in TreeSitterSymbolExtractor.LANGUAGES:
+        if ext in languages.supported_extensions():
             try:
-                symbols = TreeSitterSymbolExtractor.extract_symbols(ext, code)
+                rel_path = file.relative_to(self.repo_path).as_posix()
+                symbols = languages.extract_symbols(ext, rel_path, code)
                 for s in symbols:
                     s["file"] = str(file)
+                assign_ids(rel_path, symbols, self._go_package(rel_path, code))
                 return symbols
             except Exception as e:
                 logging.warning(f"Error extracting symbols from {file} using TreeSitter: {e}")
                 return []
         return []
 
-    def extract_symbols(self, file_path: str) -> List[Dict[str, Any]]:
+    def extract_symbols(self, file_path: str, options: Optional[ExtractionOptions] = None) -> List[Dict[str, Any]]:
         """
         Extracts symbols from a single specified file on demand.
         This method performs a fresh extraction and does not use the internal cache.
@@ -112,6 +274,7 @@ class RepoMapper:
 
         Args:
             file_path (str): The relative path to the file from the repository root.
+            options (Optional[ExtractionOptions]): Opt-in extraction behaviour, e.g. nested functions.
 
         Returns:
             List[Dict[str, Any]]: A list of symbols extracted from the file.
@@ -124,12 +287,16 @@ class RepoMapper:
             return []
 
         ext = abs_path.suffix.lower()
-        if ext in TreeSitterSymbolExtractor.LANGUAGES:
+        if ext in languages.supported_extensions():
             try:
-                code = abs_path.read_text(encoding="utf-8", errors="ignore")
-                symbols = TreeSitterSymbolExtractor.extract_symbols(ext, code)
+                code = read_text(abs_path, self.fallback_encoding)
+                symbols = languages.extract_symbols(ext, file_path, code, options)
+                if ext == ".go" and options is not None and options.resolve_types:
+                    self._go_types.apply(abs_path, symbols)
                 for s in symbols:
                     s["file"] = str(abs_path.relative_to(self.repo_path))
+                rel_path = abs_path.relative_to(self.repo_path).as_posix()
+                assign_ids(rel_path, symbols, self._go_package(rel_path, code))
                 return symbols
             except Exception as e:
                 logging.warning(f"Error extracting symbols from {abs_path} in extract_symbols: {e}")
@@ -138,13 +305,333 @@ class RepoMapper:
             logging.debug(f"File type {ext} not supported for symbol extraction: {file_path}")
             return []
 
+    def parse_directory(
+        self,
+        root: Optional[str] = None,
+        ignore: Optional[List[str]] = None,
+        options: Optional[ExtractionOptions] = None,
+        concurrency: Optional[int] = None,
+        cancel: Optional[threading.Event] = None,
+        build_context: Optional[BuildContext] = None,
+        limits: Optional[FileLimits] = None,
+        fail_fast: bool = False,
+        modified_since: Optional[Union[float, datetime]] = None,
+        changed_files: Optional[Iterable[str]] = None,
+        include_tests: bool = False,
+        include_vendored: bool = True,
+        include_generated: bool = True,
+    ) -> Tuple[Dict[str, List[Dict[str, Any]]], List[Dict[str, Any]]]:
+        """
+        Walks a directory and extracts symbols from every supported file.
+
+        ``vendor/``, ``node_modules/`` and ``.git/`` are always skipped, as is
+        anything matched by .gitignore or .codekiteignore files or by *ignore*
+        (gitignore-style patterns relative to the repository root). A file that
+        cannot be read or parsed is reported in the returned errors instead of
+        aborting the walk. A file with syntax errors still contributes the
+        symbols tree-sitter recovered around them, and each error becomes a
+        ``warning`` diagnostic; *fail_fast* aborts on the first problem instead.
+
+        Files are parsed on a pool of *concurrency* threads. Results are
+        collected in path order, so the output is identical for any pool size;
+        :meth:`iter_directory` yields them one file at a time instead, for
+        repositories too large to hold every symbol in memory. Setting *cancel*
+        stops the walk promptly, whether it is still listing directories or
+        already parsing: files not yet started are skipped and
+        :class:`ExtractionCancelled` is raised with what was parsed.
+        With a *build_context*, Go files its build constraints exclude are left
+        out of the results, as ``go build`` would leave them out of the package.
+        Files that break *limits* (binary files and files over 1 MB by default)
+        are not parsed; each is reported in the errors with the reason in its
+        ``skipped`` key, so it does not silently vanish. Files with a BOM, or
+        that are not valid UTF-8, are decoded as
+        :func:`codekite.encodings.decode_source` describes, using the mapper's
+        ``fallback_encoding``.
+
+        Args:
+            root (Optional[str]): Directory to walk, relative to the repository root. Defaults to the root.
+            ignore (Optional[List[str]]): Extra gitignore-style patterns to skip.
+            options (Optional[ExtractionOptions]): Opt-in extraction behaviour.
+            concurrency (Optional[int]): Number of worker threads. Defaults to the CPU count; 1 parses sequentially.
+            cancel (Optional[threading.Event]): Event that aborts the walk when set, e.g. from another thread or a timer.
+            build_context (Optional[BuildContext]): GOOS, GOARCH and tags to evaluate Go build constraints against.
+            limits (Optional[FileLimits]): Which files to skip unparsed. Defaults to ``FileLimits()``.
+            fail_fast (bool): Raise on the first file that cannot be read or
+                parsed, or that has a syntax error, instead of collecting diagnostics.
+            modified_since (Optional[Union[float, datetime]]): Only parse files
+                modified after this instant, a POSIX timestamp or a datetime.
+            changed_files (Optional[Iterable[str]]): Only parse these
+                repository-relative paths. See :meth:`select_files`.
+            include_tests (bool): Also parse test files, whose symbols carry
+                ``is_test: True``. By default they are left out entirely, as an
+                index of the public API wants. See :func:`codekite.languages.is_test_file`.
+            include_vendored (bool): Parse copies of other projects' code under
+                ``third_party/``, marking their symbols ``vendored: True``.
+                ``vendor/`` and ``node_modules/`` are never walked either way.
+            include_generated (bool): Parse generated files, such as ``x.pb.go``
+                or files with a ``Code generated ... DO NOT EDIT.`` header,
+                marking their symbols ``generated: True``. See :mod:`codekite.code_origin`.
+
+        Returns:
+            A ``(symbols, errors)`` tuple. ``symbols`` maps repository-relative
+            paths to their symbol lists. ``errors`` holds the diagnostics in
+            path order, each with ``file``, ``error`` (the message) and
+            ``severity``:
+
+            * ``"error"``: the file could not be read or parsed and has no symbols.
+            * ``"warning"``: a syntax error the file's symbols were recovered
+              around, with its 0-based ``line`` and ``column``; or undecodable
+              bytes replaced with U+FFFD, with the ``encoding`` tried.
+            * ``"info"``: the file was skipped under *limits*; ``skipped`` holds
+              the :class:`SkippedFile` reason.
+
+        Raises:
+            ExtractionCancelled: If *cancel* was set before every file was parsed.
+            SyntaxError: With *fail_fast*, for the first syntax error, carrying its file, line and column.
+        """
+        files = self.select_files(root, ignore, modified_since, changed_files, cancel, include_tests, include_vendored)
+        symbols_by_file: Dict[str, List[Dict[str, Any]]] = {}
+        errors: List[Dict[str, Any]] = []
+        completed = 0
+        for rel_path, symbols, problems in self._parse_files(
+            files, options, concurrency, cancel, build_context, limits, fail_fast, include_generated
+        ):
+            completed += 1
+            errors.extend(problems)
+            if symbols is not None:
+                symbols_by_file[rel_path] = symbols
+        if completed < len(files):
+            raise ExtractionCancelled(symbols_by_file, errors)
+        return symbols_by_file, errors
+
+    def iter_directory(
+        self,
+        root: Optional[str] = None,
+        ignore: Optional[List[str]] = None,
+        options: Optional[ExtractionOptions] = None,
+        concurrency: Optional[int] = None,
+        cancel: Optional[threading.Event] = None,
+        build_context: Optional[BuildContext] = None,
+        limits: Optional[FileLimits] = None,
+        fail_fast: bool = False,
+        modified_since: Optional[Union[float, datetime]] = None,
+        changed_files: Optional[Iterable[str]] = None,
+        include_tests: bool = False,
+        include_vendored: bool = True,
+        include_generated: bool = True,
+    ) -> Generator[FileSymbols, None, None]:
+        """
+        Like :meth:`parse_directory`, but yields each file's results as soon as it is parsed.
+
+        Files are yielded in the same path order, and only a few per worker
+        thread are parsed ahead of the consumer, so memory stays flat however
+        large the repository is, as long as the consumer does not keep every
+        result. Go files excluded by *build_context*, and files left out by
+        *include_tests*, *include_vendored* or *include_generated*, are not yielded. Closing
+        the generator, or breaking out of a loop over it, stops the walk:
+        files not yet started are never parsed.
+
+        Raises:
+            ExtractionCancelled: If *cancel* was set before every file was
+                parsed. Its ``symbols`` and ``errors`` are empty, since the
+                results were already yielded.
+            SyntaxError: With *fail_fast*, for the first syntax error.
+        """
+        files = self.select_files(root, ignore, modified_since, changed_files, cancel, include_tests, include_vendored)
+        completed = 0
+        for rel_path, symbols, problems in self._parse_files(
+            files, options, concurrency, cancel, build_context, limits, fail_fast, include_generated
+        ):
+            completed += 1
+            if symbols is not None or problems:
+                yield FileSymbols(rel_path, symbols, problems)
+        if completed < len(files):
+            raise ExtractionCancelled({}, [], completed)
+
+    def _parse_files(
+        self,
+        files: List[Path],
+        options: Optional[ExtractionOptions],
+        concurrency: Optional[int],
+        cancel: Optional[threading.Event],
+        build_context: Optional[BuildContext],
+        limits: Optional[FileLimits],
+        fail_fast: bool,
+        include_generated: bool = True,
+    ) -> Iterator[_Parsed]:
+        """Yields ``(path, symbols, diagnostics)`` for *files* in order, without those cancel stopped."""
+        workers = concurrency if concurrency is not None else (os.cpu_count() or 4)
+        limits = limits if limits is not None else FileLimits()
+        # Set on the first fail_fast error, or when the consumer stops early
+        stop = threading.Event()
+
+        def parse(file: Path) -> Optional[_Parsed]:
+            if (cancel is not None and cancel.is_set()) or stop.is_set():
+                return None
+            rel_path = file.relative_to(self.repo_path).as_posix()
+            syntax_errors: List[Dict[str, Any]] = []
+            try:
+                # Checked before reading, so a huge artifact is never loaded
+                skipped = check_size(file, rel_path, limits)
+                if skipped is None:
+                    data = file.read_bytes()
+                    skipped = check_content(data, rel_path, limits)
+                if skipped is not None:
+                    entry = {"file": rel_path, "error": f"Skipped: {skipped.detail}", "skipped": skipped.reason}
+                    return rel_path, None, [dict(entry, severity=SEVERITY_INFO)]
+                decoded = decode_source(data, self.fallback_encoding)
+                code = decoded.text
+                if build_context is not None and file.suffix.lower() == ".go":
+                    if not build_context.match_source(file.name, code):
+                        # Excluded by build constraints: neither symbols nor an error
+                        return rel_path, None, []
+                if not include_generated and is_generated(rel_path, code):
+                    return rel_path, None, []
+                symbols = languages.extract_symbols(
+                    file.suffix.lower(), rel_path, code, options, raise_errors=True, diagnostics=syntax_errors
+                )
+                if file.suffix.lower() == ".go" and options is not None and options.resolve_types:
+                    self._go_types.apply(file, symbols)
+            except Exception as e:
+                if fail_fast:
+                    stop.set()
+                    raise
+                error = {"file": rel_path, "error": f"{type(e).__name__}: {e}", "severity": SEVERITY_ERROR}
+                return rel_path, None, [error]
+            if fail_fast and syntax_errors:
+                stop.set()
+                first = syntax_errors[0]
+                line, column = first["start_line"], first["start_column"]
+                raise SyntaxError(first["message"], (rel_path, line + 1, column + 1, code.split("\n")[line]))
+            for s in symbols:
+                s["file"] = rel_path
+            assign_ids(rel_path, symbols, self._go_package(rel_path, code))
+            diagnostics = [
+                {
+                    "file": rel_path,
+                    "error": e["message"],
+                    "line": e["start_line"],
+                    "column": e["start_column"],
+                    "severity": SEVERITY_WARNING,
+                }
+                for e in syntax_errors
+            ]
+            if decoded.replaced:
+                message = f"Invalid {decoded.encoding} bytes replaced with U+FFFD"
+                diagnostics.insert(
+                    0, {"file": rel_path, "error": message, "encoding": decoded.encoding, "severity": SEVERITY_WARNING}
+                )
+            return rel_path, symbols, diagnostics
+
+        if workers <= 1:
+            for file in files:
+                result = parse(file)
+                if result is None:
+                    return
+                yield result
+            return
+        # A bounded window of futures in submission order, so results are yielded in path order
+        # while at most a few files per worker are parsed ahead of the consumer
+        window = workers * 2
+        pending: Deque[Future[Optional[_Parsed]]] = deque()
+        with ThreadPoolExecutor(max_workers=workers) as executor:
+            try:
+                queue = iter(files)
+                for file in itertools.islice(queue, window):
+                    pending.append(executor.submit(parse, file))
+                while pending:
+                    result = pending.popleft().result()
+                    if result is None:
+                        # Cancelled: nothing more is queued, but files already being parsed are still yielded
+                        stop.set()
+                        continue
+                    if not stop.is_set():
+                        for file in itertools.islice(queue, 1):
+                            pending.append(executor.submit(parse, file))
+                    yield result
+            finally:
+                # Queued files return None straight away instead of parsing
+                stop.set()
+
+    def select_files(
+        self,
+        root: Optional[str] = None,
+        ignore: Optional[List[str]] = None,
+        modified_since: Optional[Union[float, datetime]] = None,
+        changed_files: Optional[Iterable[str]] = None,
+        cancel: Optional[threading.Event] = None,
+        include_tests: bool = False,
+        include_vendored: bool = True,
+    ) -> List[Path]:
+        """
+        Returns the source files :meth:`parse_directory` would parse, in walk order.
+
+        Files left out by *modified_since*, *changed_files*, *include_tests* or
+        *include_vendored* produce neither symbols nor diagnostics, so a CI job can parse only
+        what a change touched. Paths in *changed_files* that are ignored, unsupported,
+        outside *root* or deleted are dropped. A naive *modified_since*
+        datetime is taken as local time, as :meth:`datetime.timestamp` does.
+
+        Raises:
+            ExtractionCancelled: If *cancel* was set before the walk finished, with nothing parsed.
+        """
+        files = self.source_files(root, ignore, cancel)
+        if not include_tests:
+            files = [f for f in files if not languages.is_test_file(f.relative_to(self.repo_path).as_posix())]
+        if not include_vendored:
+            files = [f for f in files if not is_vendored(f.relative_to(self.repo_path).as_posix())]
+        if changed_files is not None:
+            wanted = {posixpath.normpath(p.replace("\\", "/")) for p in changed_files}
+            files = [f for f in files if f.relative_to(self.repo_path).as_posix() in wanted]
+        if modified_since is not None:
+            since = modified_since.timestamp() if isinstance(modified_since, datetime) else modified_since
+            files = [f for f in files if _mtime(f) > since]
+        return files
+
+    def source_files(
+        self,
+        root: Optional[str] = None,
+        ignore: Optional[List[str]] = None,
+        cancel: Optional[threading.Event] = None,
+    ) -> List[Path]:
+        """
+        Returns supported source files under *root* in sorted walk order, honouring skip and ignore rules.
+
+        Unlike :meth:`select_files`, test and vendored files are both
+        included. *ignore* holds extra gitignore-style patterns relative to the
+        repository root. *cancel* is checked at every path, so a walk of a huge
+        tree stops promptly with :class:`ExtractionCancelled`.
+        """
+        ignore_spec = pathspec.PathSpec.from_lines("gitwildmatch", ignore) if ignore else None
+        start = self.repo_path / root if root else self.repo_path
+        extensions = languages.supported_extensions()
+
+        def skip(path: Path, is_dir: bool) -> bool:
+            if is_dir and path.name in DEFAULT_SKIP_DIRS:
+                return True
+            if not is_dir and path.suffix.lower() not in extensions:
+                return True
+            if ignore_spec is None:
+                return False
+            rel_path = path.relative_to(self.repo_path).as_posix()
+            # Directory patterns such as "build/" only match paths with a trailing slash
+            return ignore_spec.match_file(rel_path + "/" if is_dir else rel_path)
+
+        files = []
+        for path, is_dir in self._walk(start, skip):
+            if cancel is not None and cancel.is_set():
+                raise ExtractionCancelled({}, [], 0)
+            if not is_dir:
+                files.append(path)
+        return files
+
     def get_repo_map(self) -> Dict[str, Any]:
         """
         Returns a dict with file tree and a mapping of files to their symbols.
         Ensures the symbol map is up-to-date by scanning the repo and refreshes the file tree.
         """
         self.scan_repo()
-        self._file_tree = None
+        self.invalidate_file_tree()
         return {"file_tree": self.get_file_tree(), "symbols": {k: v["symbols"] for k, v in self._symbol_map.items()}}
 
     # --- Helper methods ---
@@ -1,19 +1,49 @@
 from __future__ import annotations
-from typing import TYPE_CHECKING, Any, Dict, List, Optional, Union
-from .repo_mapper import RepoMapper
-from .code_searcher import CodeSearcher
+from datetime import datetime
+from typing import (
+    TYPE_CHECKING, Any, BinaryIO, Callable, Dict, Generator, Iterable, Iterator, List, Optional, Sequence, TextIO,
+    Tuple, Union,
+)
+from .repo_mapper import FileSymbols, RepoMapper
+from .code_searcher import CodeSearcher, SearchOptions
 from .context_extractor import ContextExtractor
+from .encodings import read_text
 from .vector_searcher import VectorSearcher
 from .llm_context import ContextAssembler
+from .git_info import GitInfo, NotGitRepositoryError, read_git_info
+from .remote import clone_cached, is_remote_url, remove_clone
 import os
-import tempfile
-import subprocess
+import logging
+import threading
 from pathlib import Path
 
 # Use TYPE_CHECKING for Summarizer to avoid circular imports
 if TYPE_CHECKING:
     from .summaries import Summarizer, OpenAIConfig, AnthropicConfig, GoogleConfig
+    from .summary_pipeline import LLMClient, SummaryPipeline
     from .dependency_analyzer import DependencyAnalyzer
+    from .dependency_graph import DependencyGraph
+    from .type_analyzer import EmbeddingReport, TaggedField, TypeAnalyzer
+    from .call_graph import CallGraph, FileCallGraph
+    from .go_tests import GoTestLink
+    from .duplicates import Conflict
+    from .line_counts import LineCounts
+    from .metrics import MetricsReport
+    from .todos import Comment
+    from .changes import ChangedSymbol
+    from .context_extractor import Chunk
+    from .tokenizers import Tokenizer
+    from .repo_map import RepoMap
+    from .vector_index import Embedder, VectorIndex
+    from .export import ExportOptions
+    from .symbol_index import SymbolIndex
+    from .symbol_store import SymbolStore
+    from .go_build import BuildContext
+    from .file_limits import FileLimits
+    from .watcher import IndexEvent, Watcher
+    from .tree_sitter_symbol_extractor import ExtractionOptions
+
+logger = logging.getLogger(__name__)
 
 
 class Repository:
@@ -22,15 +52,50 @@ class Repository:
     Provides a unified API for downstream tools and workflows.
     """
 
-    def __init__(self, path_or_url: str, github_token: Optional[str] = None, cache_dir: Optional[str] = None) -> None:
-        if path_or_url.startswith("http://") or path_or_url.startswith("https://"):  # Remote repo
-            self.local_path = self._clone_github_repo(path_or_url, github_token, cache_dir)
+    def __init__(
+        self,
+        path_or_url: str,
+        github_token: Optional[str] = None,
+        cache_dir: Optional[str] = None,
+        respect_gitignore: bool = True,
+        ref: Optional[str] = None,
+        fallback_encoding: Optional[str] = None,
+        follow_symlinks: bool = False,
+    ) -> None:
+        """
+        Args:
+            path_or_url: Local path, or URL of a git repository to clone.
+            github_token: Token for cloning private repositories over HTTPS. Without
+                one, git's configured credential helpers are used.
+            cache_dir: Where remote repositories are cloned to. Defaults to
+                ``codekite`` under the user cache directory (see :func:`codekite.remote.default_cache_dir`).
+            respect_gitignore: Leave out paths matched by .gitignore files. Paths
+                matched by .codekiteignore files and .git/ are always left out.
+            ref: Branch, tag or commit to check out when cloning. Clones are
+                cached per URL and ref and reused by later opens.
+            fallback_encoding: Encoding of files that are not valid UTF-8 and
+                have no byte order mark, e.g. ``"cp1252"``. Without one, invalid
+                bytes are replaced with U+FFFD. See :func:`codekite.encodings.decode_source`.
+            follow_symlinks: Walk into symlinked directories, visiting each
+                directory once. Symlinks that resolve outside the repository
+                are never followed, and symlinked files are always indexed.
+        """
+        self._cloned = is_remote_url(path_or_url)
+        if self._cloned:
+            self.local_path = clone_cached(path_or_url, ref, github_token, cache_dir)
         else:
+            if ref is not None:
+                raise ValueError("ref only applies to remote repositories")
             self.local_path = Path(path_or_url).resolve()
         self.repo_path: str = str(self.local_path)
-        self.mapper: RepoMapper = RepoMapper(self.repo_path)
+        self.mapper: RepoMapper = RepoMapper(
+            self.repo_path,
+            respect_gitignore=respect_gitignore,
+            fallback_encoding=fallback_encoding,
+            follow_symlinks=follow_symlinks,
+        )
         self.searcher: CodeSearcher = CodeSearcher(self.repo_path)
-        self.context: ContextExtractor = ContextExtractor(self.repo_path)
+        self.context: ContextExtractor = ContextExtractor(self.repo_path, fallback_encoding=fallback_encoding)
         self.vector_searcher: Optional[VectorSearcher] = None
 
     def __str__(self) -> str:
@@ -38,53 +103,58 @@ class Repository:
         # The self.repo_path is already a string, set in __init__
         path_info = self.repo_path
 
-        # Check if it's a git repo and try to get ref.
-        # This assumes local_path is a Path object and points to a git repo.
         ref_info = ""
-        # self.local_path is already a Path object from __init__
-        git_dir = self.local_path / ".git"
-        if git_dir.exists() and git_dir.is_dir():
-            try:
-                # Get current branch name
-                branch_cmd = ["git", "rev-parse", "--abbrev-ref", "HEAD"]
-                # Use self.repo_path (string) for cwd as subprocess expects string path
-                branch_result = subprocess.run(
-                    branch_cmd, cwd=self.repo_path, capture_output=True, text=True, check=False
-                )
-                if branch_result.returncode == 0 and branch_result.stdout.strip() != "HEAD":
-                    ref_info = f", branch: {branch_result.stdout.strip()}"
-                else:
-                    # If not on a branch (detached HEAD), get commit SHA
-                    sha_cmd = ["git", "rev-parse", "--short", "HEAD"]
-                    sha_result = subprocess.run(
-                        sha_cmd, cwd=self.repo_path, capture_output=True, text=True, check=False
-                    )
-                    if sha_result.returncode == 0:
-                        ref_info = f", commit: {sha_result.stdout.strip()}"
-            except Exception:
-                pass  # Silently ignore errors in getting git info for __str__
+        try:
+            git = self.git_info()
+            if git.branch:
+                ref_info = f", branch: {git.branch}"
+            elif git.sha:
+                ref_info = f", commit: {git.sha[:7]}"
+        except (NotGitRepositoryError, OSError):
+            pass  # Not a checkout, or unreadable metadata; __str__ never fails
 
         return f"<Repository path='{path_info}'{ref_info}, files: {file_count}>"
 
-    def _clone_github_repo(self, url: str, token: Optional[str], cache_dir: Optional[str]) -> Path:
-        from urllib.parse import urlparse
+    def git_info(self) -> GitInfo:
+        """
+        Reads the commit, branch and remote URL of the checkout this repository is in.
 
-        repo_name = urlparse(url).path.strip("/").replace("/", "-")
-        cache_root = Path(cache_dir or tempfile.gettempdir()) / "codekite-repo-cache"
-        cache_root.mkdir(parents=True, exist_ok=True)
+        Worktrees and submodules, whose ``.git`` is a file pointing elsewhere, are
+        followed to their real git directory.
 
-        repo_path = cache_root / repo_name
-        if repo_path.exists() and (repo_path / ".git").exists():
-            # Optionally: git pull to update
-            return repo_path
+        Raises:
+            NotGitRepositoryError: If the repository is not inside a git checkout.
+        """
+        return read_git_info(self.repo_path)
 
-        clone_url = url
+    def current_sha(self) -> str:
+        """The full SHA of the checked-out commit; empty before the first commit."""
+        return self.git_info().sha
 
-        if token:
-            # Insert token for private repos
-            clone_url = url.replace("https://", f"https://{token}@")
-        subprocess.run(["git", "clone", "--depth=1", clone_url, str(repo_path)], check=True)
-        return repo_path
+    def current_branch(self) -> str:
+        """The checked-out branch; empty on a detached HEAD."""
+        return self.git_info().branch
+
+    def remote_url(self) -> str:
+        """The URL of ``origin``, or of the first remote if there is no ``origin``; empty without remotes."""
+        return self.git_info().remote_url
+
+    def provenance(self) -> Optional[Dict[str, str]]:
+        """The ``sha``, ``branch`` and ``remote_url`` from :meth:`git_info`, or None outside a git checkout."""
+        try:
+            return self.git_info().to_dict()
+        except NotGitRepositoryError:
+            return None
+
+    def cleanup(self) -> None:
+        """
+        Deletes the cached clone of a remote repository; local repositories are left alone.
+
+        The repository must not be used afterwards. To bound the cache as a whole,
+        see :func:`codekite.remote.evict_clones`.
+        """
+        if self._cloned:
+            remove_clone(self.local_path)
 
     def get_file_tree(self) -> List[Dict[str, Any]]:
         """
@@ -95,30 +165,290 @@ class Repository:
         """
         return self.mapper.get_file_tree()
 
-    def extract_symbols(self, file_path: Optional[str] = None) -> List[Dict[str, Any]]:
+    def extract_symbols(
+        self, file_path: Optional[str] = None, options: Optional["ExtractionOptions"] = None
+    ) -> List[Dict[str, Any]]:
         """
         Extracts symbols from the repository.
 
         Args:
             file_path (Optional[str], optional): The path to the file to extract symbols from. Defaults to None.
+            options (Optional[ExtractionOptions], optional): Opt-in extraction behaviour such as
+                ``include_nested``. Defaults to the extractor's defaults.
 
         Returns:
             List[Dict[str, Any]]: A list of dictionaries representing the extracted symbols.
         """
-        return self.mapper.extract_symbols(file_path)  # type: ignore[arg-type]
+        return self.mapper.extract_symbols(file_path, options)  # type: ignore[arg-type]
+
+    def parse_file(
+        self, file_path: str, options: Optional["ExtractionOptions"] = None
+    ) -> Tuple[List[Dict[str, Any]], List[Dict[str, Any]]]:
+        """
+        Extracts symbols from one file and reports the syntax errors in it.
+
+        Half-written code still yields every declaration tree-sitter can
+        recover, so an editor outline keeps the functions above a broken line.
+        Symbols whose own source contains an error have ``has_errors: True``.
+
+        Args:
+            file_path (str): Path to the file, relative to the repository root.
+            options (Optional[ExtractionOptions], optional): Opt-in extraction behaviour.
+
+        Returns:
+            A ``(symbols, errors)`` tuple. Each error has a ``message`` and
+            0-based ``start_line``, ``start_column``, ``end_line`` and
+            ``end_column``. Files parsed by a plugin extractor, and ignored
+            files, report no errors.
+        """
+        from . import languages
+        from .tree_sitter_symbol_extractor import TreeSitterSymbolExtractor
+
+        symbols = self.extract_symbols(file_path, options)
+        ext = os.path.splitext(file_path)[1].lower()
+        if not languages.is_tree_sitter(ext) or self.mapper.is_ignored(self.mapper.repo_path / file_path):
+            return symbols, []
+        return symbols, TreeSitterSymbolExtractor.syntax_errors(ext, self.get_file_content(file_path))
 
-    def search_text(self, query: str, file_pattern: str = "*") -> List[Dict[str, Any]]:
+    def parse_directory(
+        self,
+        root: Optional[str] = None,
+        ignore: Optional[List[str]] = None,
+        options: Optional["ExtractionOptions"] = None,
+        concurrency: Optional[int] = None,
+        cancel: Optional[threading.Event] = None,
+        build_context: Optional["BuildContext"] = None,
+        limits: Optional["FileLimits"] = None,
+        fail_fast: bool = False,
+        modified_since: Optional[Union[float, datetime]] = None,
+        changed_files: Optional[Iterable[str]] = None,
+        include_tests: bool = False,
+        include_vendored: bool = True,
+        include_generated: bool = True,
+    ) -> Tuple[Dict[str, List[Dict[str, Any]]], List[Dict[str, Any]]]:
+        """
+        Extracts symbols from every supported file under a directory.
+
+        *modified_since* and *changed_files* narrow the walk for incremental
+        runs, e.g. ``changed_files=repo.changed_files("origin/main")`` in CI.
+        Files they leave out are simply absent from the results.
+
+        Args:
+            root (Optional[str], optional): Directory relative to the repository root. Defaults to the root.
+            ignore (Optional[List[str]], optional): Extra gitignore-style patterns to skip.
+            options (Optional[ExtractionOptions], optional): Opt-in extraction behaviour.
+            concurrency (Optional[int], optional): Worker threads. Defaults to the CPU count.
+            cancel (Optional[threading.Event], optional): Stops the walk when set.
+            build_context (Optional[BuildContext], optional): Skip Go files whose build constraints exclude this target.
+            limits (Optional[FileLimits], optional): Size, binary and line-length limits. Defaults to ``FileLimits()``.
+            fail_fast (bool, optional): Raise on the first unreadable file or syntax error. Defaults to False.
+            modified_since (Optional[Union[float, datetime]], optional): Only parse files with a later mtime.
+            changed_files (Optional[Iterable[str]], optional): Only parse these repository-relative paths.
+            include_tests (bool, optional): Also parse test files such as ``x_test.go``, ``test_x.py`` and
+                ``x.spec.ts``, marking their symbols ``is_test: True``. Defaults to False, so test helpers
+                stay out of API indexes and diffs.
+            include_vendored (bool, optional): Parse code copied under ``third_party/``, marking its symbols
+                ``vendored: True``. ``vendor/`` and ``node_modules/`` are never walked. Defaults to True.
+            include_generated (bool, optional): Parse generated files such as ``x.pb.go``, marking their
+                symbols ``generated: True``. Defaults to True. See :mod:`codekite.code_origin`.
+
+        Returns:
+            A ``(symbols, errors)`` tuple: symbols keyed by repository-relative
+            path, and the diagnostics, each a ``{"file", "error", "severity"}``
+            dict. A file that failed is an ``"error"``; each syntax error in a
+            file that still has symbols is a ``"warning"`` with its ``line``
+            and ``column``; a file skipped under *limits* is an ``"info"`` with
+            a ``skipped`` reason.
+
+        Raises:
+            ExtractionCancelled: If *cancel* was set first; it carries the partial results.
+            SyntaxError: With *fail_fast*, for the first syntax error found.
+        """
+        return self.mapper.parse_directory(
+            root,
+            ignore,
+            options,
+            concurrency=concurrency,
+            cancel=cancel,
+            build_context=build_context,
+            limits=limits,
+            fail_fast=fail_fast,
+            modified_since=modified_since,
+            changed_files=changed_files,
+            include_tests=include_tests,
+            include_vendored=include_vendored,
+            include_generated=include_generated,
+        )
+
+    def iter_symbols(
+        self,
+        root: Optional[str] = None,
+        ignore: Optional[List[str]] = None,
+        options: Optional["ExtractionOptions"] = None,
+        concurrency: Optional[int] = None,
+        cancel: Optional[threading.Event] = None,
+        build_context: Optional["BuildContext"] = None,
+        limits: Optional["FileLimits"] = None,
+        fail_fast: bool = False,
+        modified_since: Optional[Union[float, datetime]] = None,
+        changed_files: Optional[Iterable[str]] = None,
+        include_tests: bool = False,
+        include_vendored: bool = True,
+        include_generated: bool = True,
+    ) -> Generator[FileSymbols, None, None]:
+        """
+        Yields the symbols of every supported file under a directory, one file at a time.
+
+        Takes the same arguments as :meth:`parse_directory` and reports the
+        same symbols and diagnostics, in the same order, but never holds more
+        than a few files' results at once, so memory stays flat on repositories
+        of any size. Breaking out of the loop stops parsing.
+
+        Yields:
+            A :class:`FileSymbols` per file with symbols or diagnostics.
+
+        Raises:
+            ExtractionCancelled: If *cancel* was set first, after the files parsed so far were yielded.
+            SyntaxError: With *fail_fast*, for the first syntax error found.
+        """
+        return self.mapper.iter_directory(
+            root,
+            ignore,
+            options,
+            concurrency=concurrency,
+            cancel=cancel,
+            build_context=build_context,
+            limits=limits,
+            fail_fast=fail_fast,
+            modified_since=modified_since,
+            changed_files=changed_files,
+            include_tests=include_tests,
+            include_vendored=include_vendored,
+            include_generated=include_generated,
+        )
+
+    def stream_symbols(
+        self,
+        callback: Callable[[FileSymbols], None],
+        root: Optional[str] = None,
+        ignore: Optional[List[str]] = None,
+        options: Optional["ExtractionOptions"] = None,
+        concurrency: Optional[int] = None,
+        cancel: Optional[threading.Event] = None,
+        limits: Optional["FileLimits"] = None,
+    ) -> None:
+        """
+        Calls *callback* with each file's results as :meth:`iter_symbols` yields them.
+
+        An exception raised by *callback* stops parsing, so files not yet
+        started are never parsed, and propagates to the caller.
+
+        Args:
+            callback (Callable[[FileSymbols], None]): Called once per file, in path order, on the calling thread.
+            root (Optional[str], optional): Directory relative to the repository root. Defaults to the root.
+            ignore (Optional[List[str]], optional): Extra gitignore-style patterns to skip.
+            options (Optional[ExtractionOptions], optional): Opt-in extraction behaviour.
+            concurrency (Optional[int], optional): Worker threads. Defaults to the CPU count.
+            cancel (Optional[threading.Event], optional): Stops the walk when set.
+            limits (Optional[FileLimits], optional): Size, binary and line-length limits. Defaults to ``FileLimits()``.
+
+        Raises:
+            ExtractionCancelled: If *cancel* was set before every file was parsed.
+        """
+        files = self.iter_symbols(root, ignore, options, concurrency=concurrency, cancel=cancel, limits=limits)
+        try:
+            for file in files:
+                callback(file)
+        finally:
+            # Stops the workers now rather than whenever the generator is collected
+            files.close()
+
+    def parse_packages(
+        self,
+        root: Optional[str] = None,
+        ignore: Optional[List[str]] = None,
+        options: Optional["ExtractionOptions"] = None,
+        modified_since: Optional[Union[float, datetime]] = None,
+        changed_files: Optional[Iterable[str]] = None,
+    ) -> Tuple[List[Dict[str, Any]], List[Dict[str, Any]]]:
+        """
+        Like :meth:`parse_directory`, but merged per package with methods attached to their types.
+
+        See :func:`codekite.type_analyzer.merge_package` for the merged form.
+        With *modified_since* or *changed_files*, every file of each package
+        holding a selected file is parsed, so a method in a changed file still
+        finds its type in an unchanged one. Packages with no selected file are
+        left out.
+
+        Returns:
+            A ``(symbols, errors)`` tuple: the merged symbols, and the per-file diagnostics.
+        """
+        from .type_analyzer import merge_package
+
+        if modified_since is not None or changed_files is not None:
+            selected = self.mapper.select_files(root, ignore, modified_since, changed_files)
+            packages = {file.parent for file in selected}
+            changed_files = [
+                file.relative_to(self.mapper.repo_path).as_posix()
+                for file in self.mapper.select_files(root, ignore)
+                if file.parent in packages
+            ]
+        symbols, errors = self.parse_directory(root, ignore, options, changed_files=changed_files)
+        return merge_package(symbols), errors
+
+    def parse_directory_cached(
+        self, index: "SymbolIndex", root: Optional[str] = None, cancel: Optional[threading.Event] = None
+    ) -> Tuple[Dict[str, List[Dict[str, Any]]], Dict[str, List[str]]]:
+        """
+        Extracts symbols under a directory, reusing *index* for files whose content hash is unchanged.
+
+        Files deleted since the last call are evicted from the index, and the
+        index is saved afterwards so it survives restarts.
+
+        Args:
+            index (SymbolIndex): Index to read from and update, e.g. from :meth:`get_symbol_index`.
+            root (Optional[str], optional): Directory relative to the repository root. Defaults to the root.
+            cancel (Optional[threading.Event], optional): Stops the update between files when set.
+
+        Returns:
+            A ``(symbols, changes)`` tuple: symbols keyed by repository-relative
+            path, and the ``added``/``changed``/``removed``/``cached`` paths
+            reported by :meth:`SymbolIndex.update`.
+
+        Raises:
+            ExtractionCancelled: If *cancel* was set first; the index keeps what was re-parsed.
+        """
+        changes = index.update(self, root, cancel)
+        return index.symbols_under(root), changes
+
+    def search_text(
+        self,
+        query: str,
+        file_pattern: str = "*",
+        options: Optional[SearchOptions] = None,
+        callback: Optional[Callable[[Dict[str, Any]], None]] = None,
+    ) -> List[Dict[str, Any]]:
         """
         Searches for text in the repository.
 
         Args:
             query (str): The text to search for.
             file_pattern (str, optional): The file pattern to search in. Defaults to "*".
+            options (Optional[SearchOptions], optional): Case sensitivity, context lines and result caps.
+            callback (Optional[Callable], optional): Called with each match as soon as it is found.
 
         Returns:
             List[Dict[str, Any]]: A list of dictionaries representing the search results.
         """
-        return self.searcher.search_text(query, file_pattern)
+        return self.searcher.search_text(query, file_pattern, options, callback)
+
+    def iter_search_text(
+        self, query: str, file_pattern: str = "*", options: Optional[SearchOptions] = None
+    ) -> Iterator[Dict[str, Any]]:
+        """
+        Like :meth:`search_text`, but yields matches lazily, e.g. to stop at the first hit.
+        """
+        return self.searcher.iter_search_text(query, file_pattern, options)
 
     def chunk_file_by_lines(self, file_path: str, max_lines: int = 50) -> List[str]:
         """
@@ -145,6 +475,37 @@ class Repository:
         """
         return self.context.chunk_file_by_symbols(file_path)
 
+    def chunk_file(self, file_path: str, max_lines: Optional[int] = None) -> List["Chunk"]:
+        """
+        Cuts a file into retrieval chunks: a preamble, then one chunk per top-level symbol with its doc comment.
+
+        Args:
+            file_path (str): The path to the file to chunk.
+            max_lines (Optional[int], optional): Split longer chunks into pieces of this many lines.
+
+        Example:
+            >>> [(c.kind, c.symbols) for c in repo.chunk_file("golden_go.go")][:2]
+            [('preamble', []), ('symbol', ['User'])]
+        """
+        return self.context.chunk_file(file_path, max_lines)
+
+    def chunk_file_by_tokens(
+        self, file_path: str, max_tokens: int = 512, overlap: int = 0, tokenizer: Optional["Tokenizer"] = None
+    ) -> List["Chunk"]:
+        """
+        Cuts a file into runs of lines that each fit a token budget, hard-splitting lines longer than the budget.
+
+        Args:
+            file_path (str): The path to the file to chunk.
+            max_tokens (int, optional): Most tokens per chunk. Defaults to 512.
+            overlap (int, optional): Lines repeated between consecutive chunks. Defaults to 0.
+            tokenizer (Optional[Tokenizer], optional): Token counter; defaults to about four characters per token.
+
+        Raises:
+            ValueError: If max_tokens is not positive or overlap is negative.
+        """
+        return self.context.chunk_file_by_tokens(file_path, max_tokens, overlap, tokenizer)
+
     def extract_context_around_line(self, file_path: str, line: int) -> Optional[Dict[str, Any]]:
         """
         Extracts context around a line in a file.
@@ -162,6 +523,10 @@ class Repository:
         """
         Reads and returns the content of a file within the repository.
 
+        A byte order mark is dropped, and files that are not UTF-8 are decoded
+        as :func:`codekite.encodings.decode_source` describes, so the text is
+        the one symbols were extracted from.
+
         Args:
             file_path (str): The path to the file, relative to the repository root.
 
@@ -175,8 +540,7 @@ class Repository:
         if not full_path.is_file():
             raise FileNotFoundError(f"File not found in repository: {file_path}")
         try:
-            with open(full_path, "r", encoding="utf-8") as f:
-                return f.read()
+            return read_text(full_path, self.mapper.fallback_encoding)
         except Exception as e:
             # Catch potential decoding errors or other file reading issues
             raise IOError(f"Error reading file {file_path}: {e}") from e
@@ -185,6 +549,9 @@ class Repository:
         """
         Builds and returns a full index of the repo, including file tree and symbols.
 
+        The ``repo`` key holds the ``sha``, ``branch`` and ``remote_url`` from
+        :meth:`git_info`, or None when the repository is not a git checkout.
+
         Returns:
             Dict[str, Any]: A dictionary representing the index.
         """
@@ -193,8 +560,34 @@ class Repository:
             "file_tree": tree,  # legacy key
             "files": tree,  # preferred
             "symbols": self.mapper.get_repo_map()["symbols"],
+            "repo": self.provenance(),
         }
 
+    def build_vector_index(
+        self, embedder: "Embedder", chunk_by: str = "symbols", max_tokens: int = 512
+    ) -> "VectorIndex":
+        """
+        Embeds the repository's chunks into a :class:`~codekite.vector_index.VectorIndex` for semantic search.
+
+        Unlike :meth:`get_vector_searcher` this needs no vector database; save
+        the index with ``index.save(dir)`` and reuse it with ``VectorIndex.load``.
+
+        Args:
+            embedder (Embedder): Any object with ``embed(texts) -> vectors``.
+            chunk_by (str, optional): ``"symbols"`` or ``"tokens"``. Defaults to ``"symbols"``.
+            max_tokens (int, optional): Budget per chunk when chunking by tokens. Defaults to 512.
+
+        Example:
+            >>> index = repo.build_vector_index(HashingEmbedder())
+            >>> index.search("greeting users", k=1)[0].chunk.symbols
+            ['User.Greet']
+        """
+        from .vector_index import VectorIndex
+
+        index = VectorIndex(embedder)
+        index.index_repository(self, chunk_by=chunk_by, max_tokens=max_tokens)
+        return index
+
     def get_vector_searcher(self, embed_fn=None, backend=None, persist_dir=None):
         if self.vector_searcher is None:
             if embed_fn is None:
@@ -248,9 +641,49 @@ class Repository:
             # Return the initialized Summarizer
             return Summarizer(repo=self, config=llm_config)
 
-    def get_context_assembler(self) -> "ContextAssembler":
-        """Return a ContextAssembler bound to this repository."""
-        return ContextAssembler(self)
+    def get_summary_pipeline(
+        self,
+        client: "LLMClient",
+        *,
+        cache_path: Optional[str] = None,
+        max_workers: int = 4,
+        rate_limit: Optional[float] = None,
+        max_input_tokens: int = 3000,
+    ) -> "SummaryPipeline":
+        """
+        Returns a :class:`~codekite.summary_pipeline.SummaryPipeline` that summarizes this repository with *client*.
+
+        Unlike :meth:`get_summarizer` it works with any model: *client* only
+        needs ``complete(prompt) -> str``.
+
+        Args:
+            client (LLMClient): Answers prompts.
+            cache_path (str, optional): JSON file to keep summaries in between runs.
+                Defaults to None, an in-memory cache.
+            max_workers (int, optional): Most prompts in flight at once. Defaults to 4.
+            rate_limit (float, optional): Most model calls per second. Defaults to None, no limit.
+            max_input_tokens (int, optional): Longer inputs are summarized in parts. Defaults to 3000.
+
+        Example:
+            >>> pipeline = repo.get_summary_pipeline(my_client, cache_path=".codekite/summaries.json")
+            >>> summaries = pipeline.summarize_repository(level="symbol")
+        """
+        from .summary_pipeline import SummaryCache, SummaryPipeline
+
+        return SummaryPipeline(
+            self,
+            client,
+            cache=SummaryCache(cache_path),
+            max_workers=max_workers,
+            rate_limit=rate_limit,
+            max_input_tokens=max_input_tokens,
+        )
+
+    def get_context_assembler(
+        self, *, max_tokens: Optional[int] = None, tokenizer: Optional["Tokenizer"] = None
+    ) -> "ContextAssembler":
+        """Return a ContextAssembler bound to this repository, optionally limited to max_tokens."""
+        return ContextAssembler(self, max_tokens=max_tokens, tokenizer=tokenizer)
 
     def get_dependency_analyzer(self) -> "DependencyAnalyzer":
         """
@@ -272,6 +705,417 @@ class Repository:
 
         return DependencyAnalyzer(self)
 
+    def get_dependency_graph(self) -> "DependencyGraph":
+        """
+        Builds the import graph between this repository's files and packages.
+
+        Go, Python and TypeScript/JavaScript imports are resolved to files
+        where possible; everything else is recorded as an external dependency.
+
+        Example:
+            >>> graph = repo.get_dependency_graph()
+            >>> graph.dependents_of("store/store.go")  # files affected by a change
+            ['api/handlers.go', 'main.go']
+            >>> graph.cycles()
+            [['a.py', 'b.py']]
+        """
+        from .dependency_graph import DependencyGraph
+
+        return DependencyGraph(self).build()
+
+    def repo_map(
+        self,
+        max_tokens: int = 1024,
+        pinned: Optional[List[str]] = None,
+        exclude_tests: bool = False,
+        tokenizer: Optional["Tokenizer"] = None,
+    ) -> "RepoMap":
+        """
+        Outlines the top-level symbol signatures of the repository's most central files within a token budget.
+
+        Files imported by many others rank highest; see :func:`codekite.repo_map.build_repo_map`.
+
+        Args:
+            max_tokens (int, optional): Budget for the outline. Defaults to 1024.
+            pinned (Optional[List[str]], optional): Files to include regardless of rank or budget.
+            exclude_tests (bool, optional): Leave out test files. Defaults to False.
+            tokenizer (Optional[Tokenizer], optional): Token counter; defaults to about four characters per token.
+
+        Example:
+            >>> print(repo.repo_map(max_tokens=200).text)
+            store/store.go: func (s *Store) Get(key string) (Value, error)
+            store/store.go: type Store struct
+        """
+        from .repo_map import build_repo_map
+
+        return build_repo_map(self, max_tokens, pinned or (), exclude_tests, tokenizer)
+
+    def extract_imports(self, file_path: str) -> List[Dict[str, Any]]:
+        """
+        Returns the imports of a Go file: ``path``, ``kind`` (normal, alias, dot or blank), ``line`` and ``alias``.
+
+        Args:
+            file_path (str): The relative path to the file from the repository root.
+        """
+        from .import_graph import extract_go_imports
+
+        if not file_path.endswith(".go"):
+            return []
+        return extract_go_imports(self.get_file_content(file_path))
+
+    def get_import_graph(self, root: Optional[str] = None) -> Dict[str, List[str]]:
+        """
+        Maps every Go package under *root* to the sorted packages it imports.
+
+        Packages are named by import path, using the module path from the
+        repository's go.mod when there is one, so internal imports line up
+        with graph keys. Pass the result to :func:`codekite.import_graph.detect_cycles`
+        to find import cycles. ``_test.go`` files are left out.
+
+        Example:
+            >>> graph = repo.get_import_graph()
+            >>> graph["example.com/app"]
+            ['example.com/app/store', 'fmt']
+        """
+        from .import_graph import build_import_graph, go_module_path
+
+        go_mod = self.local_path / "go.mod"
+        module_path = go_module_path(go_mod.read_text(encoding="utf-8")) if go_mod.is_file() else None
+        imports_by_file = {}
+        for file in self.mapper.source_files(root):
+            if file.suffix != ".go" or file.name.endswith("_test.go"):
+                continue
+            rel_path = file.relative_to(self.local_path).as_posix()
+            try:
+                imports_by_file[rel_path] = self.extract_imports(rel_path)
+            except (IOError, UnicodeDecodeError) as e:
+                logger.warning(f"Skipping imports of {rel_path}: {e}")
+        return build_import_graph(imports_by_file, module_path)
+
+    def get_type_analyzer(self) -> "TypeAnalyzer":
+        """
+        Factory method to get a TypeAnalyzer bound to this repository.
+
+        The TypeAnalyzer answers structural Go questions such as which types
+        implement an interface.
+
+        Example:
+            >>> analyzer = repo.get_type_analyzer()
+            >>> analyzer.implementations("", "Greeter")
+            [{'name': 'User', 'file': 'golden_go.go', 'line': 5, 'pointer': False}]
+        """
+        from .type_analyzer import TypeAnalyzer
+
+        return TypeAnalyzer(self)
+
+    def line_counts(self, root: Optional[str] = None) -> Dict[str, "LineCounts"]:
+        """
+        Counts code, comment and blank lines in every source file.
+
+        The counts come from the raw source, so comments outside any symbol,
+        such as a Go package comment, are included. Sum them by directory with
+        :func:`codekite.line_counts.directory_line_counts`.
+
+        Args:
+            root (Optional[str], optional): Directory relative to the repository root. Defaults to the root.
+
+        Returns:
+            Dict[str, LineCounts]: Counts keyed by repository-relative path.
+
+        Example:
+            >>> repo.line_counts()["golden_go.go"].to_dict()
+            {'total': 36, 'code': 24, 'comment': 5, 'blank': 7, 'files': 1}
+        """
+        from .line_counts import file_line_counts
+
+        return file_line_counts(self, root)
+
+    def metrics(self, root: Optional[str] = None) -> "MetricsReport":
+        """
+        Summarizes the size and complexity of every function and method, per file and over the repository.
+
+        Each summary has the ``count``, ``max``, ``mean`` and ``p95`` of a
+        function's ``code_lines``, ``param_count`` and ``complexity``. See
+        :func:`codekite.metrics.compute_metrics` for how they are counted.
+
+        Args:
+            root (Optional[str], optional): Directory relative to the repository root. Defaults to the root.
+
+        Returns:
+            MetricsReport: Summaries by file in ``files`` and over every file in ``total``.
+
+        Example:
+            >>> repo.metrics().total["complexity"].to_dict()
+            {'count': 4, 'max': 1, 'mean': 1.0, 'p95': 1}
+        """
+        from .metrics import compute_metrics
+
+        by_file, _ = self.parse_directory(root)
+        return compute_metrics(by_file)
+
+    def todos(self, root: Optional[str] = None, markers: Optional[Sequence[str]] = None) -> List["Comment"]:
+        """
+        Lists the TODO, FIXME and XXX comments in every source file.
+
+        Doc comments and inline comments are both scanned; a comment counts
+        when a marker starts one of its lines, as in ``// TODO(alice): tidy up``.
+        See :func:`codekite.todos.find_todos` for the rules.
+
+        Args:
+            root (Optional[str], optional): Directory relative to the repository root. Defaults to the root.
+            markers (Optional[Sequence[str]], optional): Markers to look for,
+                replacing the default ``TODO``, ``FIXME`` and ``XXX``.
+
+        Returns:
+            List[Comment]: The comments in walk order, each with the innermost symbol it appears in.
+
+        Example:
+            >>> [c.to_dict() for c in repo.todos()]
+            [{'marker': 'TODO', 'text': 'handle overflow', 'line': 22, 'file': 'math.go', 'author': 'alice', 'symbol': 'Add'}]
+        """
+        from .todos import DEFAULT_MARKERS, file_todos
+
+        return file_todos(self, root, markers if markers is not None else DEFAULT_MARKERS)
+
+    def get_call_graph(self) -> "CallGraph":
+        """
+        Factory method to get the static call graph of this repository's Go code.
+
+        The graph is built on first use.
+
+        Example:
+            >>> graph = repo.get_call_graph()
+            >>> graph.callees_of("main")
+            ['Add', 'HelperFunction', 'User.Greet', 'fmt.Println']
+            >>> open("calls.dot", "w").write(graph.to_dot())
+        """
+        from .call_graph import CallGraph
+
+        return CallGraph(self)
+
+    def file_call_graph(self, file_path: str) -> "FileCallGraph":
+        """
+        Lists the calls each function and method of one Go file makes, resolved within that file only.
+
+        Args:
+            file_path (str): Path to a Go file, relative to the repository root.
+
+        Returns:
+            FileCallGraph: In-file callees by caller in ``calls``; calls leaving the file in ``external``.
+
+        Example:
+            >>> graph = repo.file_call_graph("main.go")
+            >>> graph.calls["main"]
+            ['Add', 'HelperFunction', 'User.Greet']
+            >>> graph.external["main"]
+            ['fmt.Println']
+        """
+        from .call_graph import build_file_call_graph
+
+        return build_file_call_graph(self.extract_symbols(file_path), self.get_file_content(file_path))
+
+    def tests_for(self, symbol: Dict[str, Any]) -> List["GoTestLink"]:
+        """
+        Lists the Go tests, benchmarks, fuzz targets and subtests linked to a function or method.
+
+        A test is linked when its name matches the symbol's (``TestAdd``,
+        ``TestUser_Greet``, a ``t.Run`` name) or when it calls the symbol
+        directly; each link's ``via`` says which. See :class:`~codekite.go_tests.GoTestLinker`.
+
+        Args:
+            symbol (Dict[str, Any]): A function or method from :meth:`extract_symbols`.
+
+        Example:
+            >>> add = next(s for s in repo.extract_symbols("calc/calc.go") if s["name"] == "Add")
+            >>> [(t.test, t.via) for t in repo.tests_for(add)]
+            [('TestAdd', ('call', 'name')), ('TestAdd/overflow', ('name',)), ('BenchmarkSum', ('call',))]
+        """
+        from .go_tests import GoTestLinker
+
+        return GoTestLinker(self).tests_for(symbol)
+
+    def untested_symbols(self, package: str = ".") -> List[Dict[str, Any]]:
+        """
+        Lists the exported Go functions and methods of a package that no test is linked to.
+
+        Args:
+            package (str): The package's directory relative to the repository root; ``"."`` for the root.
+
+        Example:
+            >>> [s["node_path"] for s in repo.untested_symbols("calc")]
+            ['Divide', 'Calculator.Reset']
+        """
+        from .go_tests import GoTestLinker
+
+        return GoTestLinker(self).untested_symbols(package)
+
+    def find_duplicates(
+        self, root: Optional[str] = None, build_context: Optional["BuildContext"] = None
+    ) -> List["Conflict"]:
+        """
+        Lists symbols defined in more than one file of a package, e.g. a helper a generated file redefines.
+
+        Go files whose build constraints no target satisfies together, such as
+        ``sum_amd64.go`` and ``sum_arm64.go``, do not clash; with *build_context*
+        only the files that target builds are compared. See
+        :func:`~codekite.duplicates.find_duplicates`.
+
+        Args:
+            root (Optional[str]): Only compare files under this directory.
+            build_context (Optional[BuildContext]): Target whose files to compare.
+
+        Example:
+            >>> [str(c) for c in repo.find_duplicates()]
+            ['function clamp defined 2 times: calc/calc.go:40, calc/zz_generated.go:12']
+        """
+        from .duplicates import find_duplicates
+
+        by_file, _ = self.parse_directory(root, build_context=build_context)
+        sources = {}
+        for path in by_file:
+            if path.endswith(".go"):
+                try:
+                    sources[path] = self.get_file_content(path)
+                except (IOError, UnicodeDecodeError):
+                    # Compared by ID alone, as if its constraints were unknown
+                    continue
+        return find_duplicates((s for symbols in by_file.values() for s in symbols), sources, build_context)
+
+    def resolve_embedding(self, root: Optional[str] = None) -> "EmbeddingReport":
+        """
+        Works out the fields and methods Go structs gain by embedding, and each interface's full method set.
+
+        See :func:`~codekite.type_analyzer.resolve_embedding` for the rules,
+        including names two embedded types promote at the same depth, which
+        are dropped and reported as collisions.
+
+        Args:
+            root (Optional[str]): Only resolve packages under this directory.
+
+        Example:
+            >>> report = repo.resolve_embedding()
+            >>> [p["chain"] for p in report.promoted["users.Admin"] if p["name"] == "Greet"]
+            ['Admin -> User -> Greet']
+        """
+        from .type_analyzer import resolve_embedding
+
+        by_file, _ = self.parse_directory(root)
+        return resolve_embedding([s for path, symbols in by_file.items() if path.endswith(".go") for s in symbols])
+
+    def find_fields_by_tag_key(
+        self, key: str, root: Optional[str] = None, missing: bool = False
+    ) -> List["TaggedField"]:
+        """
+        Lists the Go struct fields whose struct tag has *key*, or with *missing* those that lack it.
+
+        See :func:`~codekite.type_analyzer.find_fields_by_tag_key`.
+
+        Args:
+            key (str): The tag key, e.g. ``"json"``.
+            root (Optional[str]): Only look at packages under this directory.
+            missing (bool): List exported, non-embedded fields without the key instead.
+
+        Example:
+            >>> [str(f) for f in repo.find_fields_by_tag_key("json", missing=True)]
+            ['model/account.go:12: Account.Secret has no json tag']
+        """
+        from .type_analyzer import find_fields_by_tag_key
+
+        by_file, _ = self.parse_directory(root)
+        return find_fields_by_tag_key(
+            (s for path, symbols in by_file.items() if path.endswith(".go") for s in symbols), key, missing
+        )
+
+    def api_hashes(self, root: Optional[str] = None) -> Dict[str, str]:
+        """
+        Fingerprints the public API of each package: a hash of its exported symbols' names and signatures.
+
+        The hash of a package only changes when an exported symbol is added,
+        removed or changes its signature, so comparing two runs tells whether a
+        change needs an API review. See :func:`~codekite.symbol_diff.api_hash`.
+
+        Args:
+            root (Optional[str]): Only hash packages under this directory.
+
+        Returns:
+            Dict[str, str]: SHA-256 hex digest by package directory, ``"."`` for the root.
+
+        Example:
+            >>> repo.api_hashes()["calc"]
+            '3f0c9e...'
+        """
+        from .symbol_diff import api_hashes
+
+        # Test files are not part of any package's API
+        by_file, _ = self.parse_directory(root, include_tests=False)
+        return api_hashes(s for symbols in by_file.values() for s in symbols)
+
+    def get_symbol_index(
+        self, cache_path: Optional[str] = None, options: Optional["ExtractionOptions"] = None
+    ) -> "SymbolIndex":
+        """
+        Factory method to get a SymbolIndex persisted for this repository.
+
+        Call ``update`` on the result to (re)index; only files whose content
+        changed since the last update are parsed again.
+
+        Args:
+            cache_path (Optional[str], optional): Cache file. Defaults to ``.kit_cache/symbol_index.json`` in the repository.
+            options (Optional[ExtractionOptions], optional): Opt-in extraction behaviour.
+
+        Example:
+            >>> index = repo.get_symbol_index()
+            >>> index.update(repo)
+            {'added': ['main.go'], 'changed': [], 'removed': [], 'cached': []}
+        """
+        from .symbol_index import SymbolIndex
+
+        if cache_path is None:
+            cache_path = os.path.join(self.repo_path, ".kit_cache", "symbol_index.json")
+        return SymbolIndex(cache_path, options)
+
+    def get_symbol_store(self, db_path: Optional[str] = None) -> "SymbolStore":
+        """
+        Factory method to get a SQLite SymbolStore for this repository.
+
+        Args:
+            db_path (Optional[str], optional): Database file. Defaults to ``.kit_cache/symbols.db`` in the repository.
+
+        Example:
+            >>> store = repo.get_symbol_store()
+            >>> store.index(repo)
+            []
+            >>> [s["file"] for s in store.symbols_by_name("Greeter")]
+            ['golden_go.go']
+        """
+        from .symbol_store import SymbolStore
+
+        if db_path is None:
+            db_path = os.path.join(self.repo_path, ".kit_cache", "symbols.db")
+        return SymbolStore(db_path)
+
+    def get_watcher(
+        self,
+        callback: Optional[Callable[["IndexEvent"], None]] = None,
+        index: Optional["SymbolIndex"] = None,
+        debounce: float = 0.2,
+    ) -> "Watcher":
+        """
+        Factory method to get a Watcher that keeps a SymbolIndex live for this repository.
+
+        Args:
+            callback (Optional[Callable[[IndexEvent], None]], optional): Called with each change.
+            index (Optional[SymbolIndex], optional): Index to update. Defaults to :meth:`get_symbol_index`.
+            debounce (float, optional): Seconds a burst of changes must settle before it is indexed.
+
+        Example:
+            >>> with repo.get_watcher(callback=print):
+            ...     ...  # edits show up as IndexEvent(path, op, symbols)
+        """
+        from .watcher import Watcher
+
+        return Watcher(self, index=index, callback=callback, debounce=debounce)
+
     def find_symbol_usages(self, symbol_name: str, symbol_type: Optional[str] = None) -> List[Dict[str, Any]]:
         """
         Finds all usages of a symbol (by name and optional type) across the repo's indexed symbols.
@@ -309,6 +1153,70 @@ class Repository:
             )
         return usages
 
+    def find_usages(self, symbol: Dict[str, Any]) -> Dict[str, List[Dict[str, Any]]]:
+        """
+        Finds every reference to an extracted symbol, grouped by file.
+
+        Unlike :meth:`find_symbol_usages`, matches come from the syntax tree, so
+        comments and string literals are never reported, and Go references are
+        scoped by package (``pkg.Add`` from importing packages). Each reference
+        has ``line``, ``column``, ``kind`` (``"definition"`` or ``"usage"``),
+        ``enclosing`` and ``context``.
+
+        Args:
+            symbol (Dict[str, Any]): A symbol from :meth:`extract_symbols`, with ``name``, ``type`` and ``file``.
+
+        Example:
+            >>> add = next(s for s in repo.extract_symbols("golden_go.go") if s["name"] == "Add")
+            >>> [(r["kind"], r["enclosing"]) for r in repo.find_usages(add)["golden_go.go"]]
+            [('definition', None), ('usage', 'main')]
+        """
+        from .references import ReferenceFinder
+
+        return ReferenceFinder(self).find_usages(symbol)
+
+    def changed_files(self, base_ref: str) -> List[str]:
+        """
+        Lists the files that differ between a git revision and the working tree.
+
+        Committed, staged, unstaged and untracked changes all count; deleted
+        files are left out. Pass the result to ``parse_directory(changed_files=...)``
+        to parse only what a pull request touched.
+
+        Args:
+            base_ref (str): The revision to compare with, e.g. ``"origin/main"``.
+
+        Example:
+            >>> repo.changed_files("main")
+            ['cart/cart.go', 'cart/discount.go']
+        """
+        from .changes import ChangeDetector
+
+        return ChangeDetector(self).changed_files(base_ref)
+
+    def changed_symbols(
+        self, base_ref: str, head_ref: str = "HEAD", separate_doc_changes: bool = False
+    ) -> List["ChangedSymbol"]:
+        """
+        Lists the symbols added, removed or modified between two git revisions.
+
+        Files renamed between the revisions are followed, so only the symbols
+        whose lines changed are reported rather than the whole file.
+
+        Args:
+            base_ref (str): The older revision, e.g. ``"main"``.
+            head_ref (str): The newer revision. Defaults to ``"HEAD"``.
+            separate_doc_changes (bool): Report changes confined to a doc comment or
+                docstring as ``"doc"`` rather than ``"modified"``.
+
+        Example:
+            >>> [str(c) for c in repo.changed_symbols("main", "feature")]
+            ['Modified: func Add', 'Added: method User.Greet', 'Removed: type OldThing']
+        """
+        from .changes import ChangeDetector
+
+        return ChangeDetector(self).changed_symbols(base_ref, head_ref, separate_doc_changes)
+
     def write_index(self, file_path: str) -> None:
         """
         Writes the full repo index (file tree and symbols) to a JSON file.
@@ -320,20 +1228,71 @@ class Repository:
         with open(file_path, "w") as f:
             json.dump(self.index(), f, indent=2)
 
-    def write_symbols(self, file_path: str, symbols: Optional[list] = None) -> None:
+    def write_symbols(self, file_path: str, symbols: Optional[list] = None, positions: bool = False) -> None:
         """
         Writes all extracted symbols (or provided symbols) to a JSON file.
+        Output is stable: symbols are sorted by location and keys are sorted.
         Args:
             file_path (str): The path to the output file.
             symbols (Optional[list]): List of symbol dicts. If None, extracts all symbols in the repo.
+            positions (bool): Include column and doc comment positions.
         """
-        import json
+        from .formatters import write_symbols_json
 
         syms = (
             symbols if symbols is not None else [s for file_syms in self.index()["symbols"].values() for s in file_syms]
         )
         with open(file_path, "w") as f:
-            json.dump(syms, f, indent=2)
+            write_symbols_json(f, syms, positions=positions)
+
+    def export_json(self, fp: TextIO, options: Optional["ExportOptions"] = None) -> None:
+        """
+        Writes the repository's files and symbols as a versioned JSON document.
+
+        Unlike :meth:`write_symbols`, the layout is fixed by ``schema_version``
+        and documented in :mod:`codekite.export`; read it back with
+        :func:`codekite.export.read_export`.
+
+        Args:
+            fp (TextIO): Open text file to write to.
+            options (Optional[ExportOptions]): Pretty-printing, source code and
+                kind or path filters. Defaults to compact output of every symbol without code.
+        """
+        from .export import write_export
+
+        write_export(self, fp, options)
+
+    def export_scip(self, fp: BinaryIO, include_references: bool = True) -> None:
+        """
+        Writes the repository's symbols as a SCIP index for Sourcegraph and editors that read it.
+
+        Every parsed file with symbols becomes a document with the symbols'
+        definitions, signatures and doc comments; see :mod:`codekite.scip` for
+        how symbols are named.
+
+        Args:
+            fp (BinaryIO): File opened in binary mode, e.g. ``open("index.scip", "wb")``.
+            include_references (bool): Also record the references :meth:`find_usages` reports. Defaults to True.
+        """
+        from .scip import write_scip
+
+        write_scip(self, fp, include_references)
+
+    def export_ctags(self, fp: TextIO) -> None:
+        """
+        Writes the repository's symbols as a ``tags`` file for vim, emacs and other tools that read ctags.
+
+        Tags are in the extended Exuberant/Universal Ctags format, addressed by
+        line number with ``kind``, ``line``, ``language`` and scope fields; see
+        :mod:`codekite.ctags`. Paths are relative to the repository root, so
+        write the file there, e.g. ``open(repo.get_abs_path("tags"), "w")``.
+
+        Args:
+            fp (TextIO): Open text file to write to.
+        """
+        from .ctags import write_tags
+
+        write_tags(self, fp)
 
     def write_file_tree(self, file_path: str) -> None:
         """
@@ -0,0 +1,330 @@
+"""Writing a repository's symbols as a SCIP index, the format Sourcegraph and editors read for code navigation.
+
+The index is a protobuf ``Index`` message as defined by
+https://github.com/sourcegraph/scip/blob/main/scip.proto. Only the messages and
+fields listed below are written, encoded by hand so no protobuf runtime is
+needed::
+
+    Index             metadata = 1, documents = 2
+    Metadata          version = 1, tool_info = 2, project_root = 3, text_document_encoding = 4
+    ToolInfo          name = 1, version = 2
+    Document          relative_path = 1, occurrences = 2, symbols = 3, language = 4, position_encoding = 6
+    Occurrence        range = 1 (packed), symbol = 2, symbol_roles = 3, enclosing_range = 7
+    SymbolInformation symbol = 1, documentation = 3, display_name = 6
+
+Symbols are named ``codekite <language> <package> . <descriptors>``. The
+package is the Go import path of the file's directory, or the file's path
+without its extension for other languages, and the descriptors follow the
+symbol's ``node_path``: ``codekite go example.com/shop/cart . Cart#Add().``.
+"""
+
+from __future__ import annotations
+import importlib.metadata
+import logging
+import os
+import re
+from pathlib import Path
+from typing import TYPE_CHECKING, Any, BinaryIO, Dict, Iterable, List, Optional, Tuple
+
+from . import languages
+from .import_graph import go_module_path, package_import_path
+from .references import REFERENCE_USAGE, ReferenceFinder
+from .tree_sitter_symbol_extractor import TreeSitterSymbolExtractor, _char_column, _node_text
+
+if TYPE_CHECKING:
+    from .repository import Repository
+
+logger = logging.getLogger(__name__)
+
+SCHEME = "codekite"
+
+# SymbolRole bits
+ROLE_DEFINITION = 0x1
+# TextEncoding.UTF8
+_TEXT_ENCODING_UTF8 = 1
+# PositionEncoding.UTF32CodeUnitOffsetFromLineStart: columns count characters, as symbol columns do
+_POSITION_ENCODING_CHARACTERS = 3
+
+# Names of the Language enum in scip.proto
+_SCIP_LANGUAGES = {
+    "bash": "ShellScript",
+    "c": "C",
+    "cpp": "CPP",
+    "go": "Go",
+    "hcl": "HCL",
+    "java": "Java",
+    "javascript": "JavaScript",
+    "markdown": "Markdown",
+    "python": "Python",
+    "ruby": "Ruby",
+    "rust": "Rust",
+    "tsx": "TypeScriptReact",
+    "typescript": "TypeScript",
+}
+
+_TYPE_KINDS = frozenset(
+    {"class", "struct", "interface", "enum", "trait", "type", "type_alias", "module", "impl", "record", "annotation"}
+)
+_CALLABLE_KINDS = frozenset({"function", "method", "constructor"})
+_SIMPLE_NAME = re.compile(r"^[A-Za-z0-9_+$-]+$")
+
+Range = Tuple[int, int, int, int]
+
+
+# Protobuf wire format
+def _varint(value: int) -> bytes:
+    if value < 0:
+        # Negative int32s are sign-extended to 64 bits
+        value += 1 << 64
+    out = bytearray()
+    while value > 0x7F:
+        out.append(value & 0x7F | 0x80)
+        value >>= 7
+    out.append(value)
+    return bytes(out)
+
+
+def _field_varint(number: int, value: int) -> bytes:
+    return _varint(number << 3) + _varint(value) if value else b""
+
+
+def _field_bytes(number: int, data: bytes) -> bytes:
+    return _varint(number << 3 | 2) + _varint(len(data)) + data
+
+
+def _field_string(number: int, value: str) -> bytes:
+    return _field_bytes(number, value.encode("utf-8")) if value else b""
+
+
+def _field_packed(number: int, values: Iterable[int]) -> bytes:
+    return _field_bytes(number, b"".join(_varint(v) for v in values))
+
+
+def _range_values(span: Range) -> List[int]:
+    start_line, start_column, end_line, end_column = span
+    if start_line == end_line:
+        return [start_line, start_column, end_column]
+    return [start_line, start_column, end_line, end_column]
+
+
+# Symbol names
+def _escape_space(value: str) -> str:
+    return (value or ".").replace(" ", "  ")
+
+
+def _descriptor_name(name: str) -> str:
+    if _SIMPLE_NAME.match(name):
+        return name
+    return "`" + name.replace("`", "``") + "`"
+
+
+def _descriptors(symbol: Dict[str, Any], kinds_by_path: Dict[str, str]) -> str:
+    parts = (symbol.get("node_path") or symbol["name"]).split(".")
+    out = []
+    for i, part in enumerate(parts):
+        last = i == len(parts) - 1
+        kind = str(symbol.get("type", "")).lower() if last else kinds_by_path.get(".".join(parts[: i + 1]), "class")
+        if kind in _CALLABLE_KINDS:
+            suffix = "()."
+        elif kind in _TYPE_KINDS:
+            suffix = "#"
+        else:
+            suffix = "."
+        out.append(_descriptor_name(part) + suffix)
+    return "".join(out)
+
+
+def symbol_moniker(
+    language: str, package: str, symbol: Dict[str, Any], kinds_by_path: Optional[Dict[str, str]] = None
+) -> str:
+    """
+    The SCIP symbol name for *symbol*: ``codekite <language> <package> . <descriptors>``.
+
+    Args:
+        language: The file's language, e.g. ``"go"``.
+        package: The Go import path, or the file's path without extension.
+        symbol: A symbol from :meth:`Repository.extract_symbols`.
+        kinds_by_path: Kinds of the enclosing symbols by ``node_path``, so a
+            Python function nested in a function gets ``outer().inner().``;
+            enclosing symbols not listed are taken to be types.
+    """
+    return f"{SCHEME} {_escape_space(language)} {_escape_space(package)} . {_descriptors(symbol, kinds_by_path or {})}"
+
+
+def _package(path: str, language: str, module_path: Optional[str]) -> str:
+    if language == "go":
+        return package_import_path(path, module_path)
+    return os.path.splitext(path)[0]
+
+
+def _span(symbol: Dict[str, Any]) -> Range:
+    return (
+        symbol.get("start_line", 0),
+        symbol.get("start_column", 0),
+        symbol.get("end_line", symbol.get("start_line", 0)),
+        symbol.get("end_column", 0),
+    )
+
+
+def _identifier_nodes(root: Any) -> Dict[str, List[Any]]:
+    """Identifier-like leaves by spelling, in source order."""
+    found: Dict[str, List[Any]] = {}
+    stack = [root]
+    while stack:
+        node = stack.pop()
+        if node.type.endswith("identifier"):
+            found.setdefault(_node_text(node), []).append(node)
+            continue
+        stack.extend(reversed(node.children))
+    return found
+
+
+def _is_name_of(node: Any, parent: Any) -> bool:
+    return any(n.start_byte == node.start_byte for n in parent.children_by_field_name("name"))
+
+
+def _name_range(symbol: Dict[str, Any], identifiers: Dict[str, List[Any]], source_bytes: bytes) -> Range:
+    """Where the symbol's name is spelled in its definition; the whole definition if it cannot be found."""
+    name = symbol["name"]
+    start, end = symbol.get("start_byte"), symbol.get("end_byte")
+    if start is not None and end is not None:
+        inside = [n for n in identifiers.get(name, ()) if start <= n.start_byte < end]
+        declared = [n for n in inside if n.parent is not None and _is_name_of(n, n.parent)]
+        node = (declared or inside or [None])[0]
+        if node is not None:
+            column = _char_column(source_bytes, node.start_byte, node.start_point[1])
+            return node.start_point[0], column, node.start_point[0], column + len(name)
+    # Symbols from plugin extractors: the first spelling of the name in their code
+    code = symbol.get("code") or ""
+    offset = code.find(name)
+    if offset < 0:
+        return _span(symbol)
+    line = symbol.get("start_line", 0) + code.count("\n", 0, offset)
+    line_start = code.rfind("\n", 0, offset)
+    column = symbol.get("start_column", 0) + offset if line_start < 0 else offset - line_start - 1
+    return line, column, line, column + len(name)
+
+
+def _documentation(symbol: Dict[str, Any], language: str) -> List[str]:
+    documentation = []
+    if symbol.get("signature"):
+        documentation.append(f"```{language}\n{symbol['signature']}\n```")
+    if symbol.get("docstring"):
+        documentation.append(symbol["docstring"])
+    return documentation
+
+
+def _occurrence(span: Range, moniker: str, roles: int = 0, enclosing: Optional[Range] = None) -> bytes:
+    data = _field_packed(1, _range_values(span)) + _field_string(2, moniker) + _field_varint(3, roles)
+    if enclosing is not None:
+        data += _field_packed(7, _range_values(enclosing))
+    return data
+
+
+def _symbol_information(moniker: str, symbol: Dict[str, Any], language: str) -> bytes:
+    data = _field_string(1, moniker)
+    for text in _documentation(symbol, language):
+        data += _field_string(3, text)
+    return data + _field_string(6, symbol["name"])
+
+
+def _tool_version() -> str:
+    try:
+        return importlib.metadata.version("codekite")
+    except importlib.metadata.PackageNotFoundError:
+        return ""
+
+
+def build_scip_index(repo: "Repository", include_references: bool = True) -> bytes:
+    """
+    Encodes *repo*'s symbols as a SCIP ``Index`` message.
+
+    Each file with symbols becomes a ``Document`` with a definition
+    occurrence per symbol, ranged over its name and enclosing its whole
+    declaration, and a ``SymbolInformation`` carrying its signature and doc
+    comment. Test files are included; files that are not parsed or have no
+    symbols are left out.
+
+    Args:
+        repo: The repository to index.
+        include_references: Also add an occurrence for every reference
+            :meth:`Repository.find_usages` reports. These are matched by name,
+            not type checked, so outside Go a reference may be attributed to
+            every symbol of that name in the language.
+    """
+    by_file, _ = repo.parse_directory(include_tests=True)
+    go_mod = repo.local_path / "go.mod"
+    module_path = go_module_path(go_mod.read_text(encoding="utf-8")) if go_mod.is_file() else None
+
+    occurrences: Dict[str, List[bytes]] = {}
+    information: Dict[str, Dict[str, bytes]] = {}
+    file_languages: Dict[str, str] = {}
+    definitions: List[Tuple[str, str, Dict[str, Any]]] = []
+    for path in sorted(by_file):
+        symbols = by_file[path]
+        ext = os.path.splitext(path)[1]
+        language = (languages.language_for(ext) or "").lower()
+        if not symbols or not language:
+            continue
+        file_languages[path] = language
+        source_bytes = repo.get_file_content(path).encode("utf-8")
+        parser = TreeSitterSymbolExtractor.get_parser(ext) if languages.is_tree_sitter(ext) else None
+        identifiers = _identifier_nodes(parser.parse(source_bytes).root_node) if parser is not None else {}
+        package = _package(path, language, module_path)
+        kinds_by_path = {s.get("node_path") or s["name"]: str(s.get("type", "")).lower() for s in symbols}
+
+        occurrences[path], information[path] = [], {}
+        for symbol in sorted(symbols, key=lambda s: (s.get("start_line", 0), s.get("start_column", 0))):
+            moniker = symbol_moniker(language, package, symbol, kinds_by_path)
+            occurrences[path].append(
+                _occurrence(_name_range(symbol, identifiers, source_bytes), moniker, ROLE_DEFINITION, _span(symbol))
+            )
+            # Repeated declarations, such as Go init functions, describe one symbol
+            if moniker not in information[path]:
+                information[path][moniker] = _symbol_information(moniker, symbol, language)
+                definitions.append((path, moniker, symbol))
+
+    if include_references:
+        finder = ReferenceFinder(repo)
+        seen = set()
+        for path, moniker, symbol in definitions:
+            try:
+                usages = finder.find_usages(dict(symbol, file=path))
+            except Exception as e:
+                logger.warning(f"Skipping references to {moniker}: {e}")
+                continue
+            for file, references in usages.items():
+                if file not in occurrences:
+                    continue
+                for reference in references:
+                    if reference["kind"] != REFERENCE_USAGE:
+                        continue
+                    line, column = reference["line"], reference["column"]
+                    key = (file, line, column, moniker)
+                    if key in seen:
+                        continue
+                    seen.add(key)
+                    span = (line, column, line, column + len(symbol["name"]))
+                    occurrences[file].append(_occurrence(span, moniker))
+
+    tool = _field_string(1, SCHEME) + _field_string(2, _tool_version())
+    metadata = (
+        _field_bytes(2, tool)
+        + _field_string(3, Path(repo.local_path).resolve().as_uri())
+        + _field_varint(4, _TEXT_ENCODING_UTF8)
+    )
+    index = _field_bytes(1, metadata)
+    for path in sorted(occurrences):
+        language = file_languages[path]
+        document = _field_string(1, path)
+        document += b"".join(_field_bytes(2, o) for o in occurrences[path])
+        document += b"".join(_field_bytes(3, s) for s in information[path].values())
+        document += _field_string(4, _SCIP_LANGUAGES.get(language, language))
+        document += _field_varint(6, _POSITION_ENCODING_CHARACTERS)
+        index += _field_bytes(2, document)
+    return index
+
+
+def write_scip(repo: "Repository", fp: BinaryIO, include_references: bool = True) -> None:
+    """Writes the SCIP index of *repo* (see :func:`build_scip_index`) to a file opened in binary mode."""
+    fp.write(build_scip_index(repo, include_references))
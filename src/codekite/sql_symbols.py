@@ -0,0 +1,264 @@
+"""Tables, views, functions and procedures of SQL files as symbols, so database objects are indexed next to the code."""
+
+from __future__ import annotations
+import bisect
+import re
+from typing import Any, Dict, List, Optional, Tuple
+
+TABLE = "table"
+VIEW = "view"
+FUNCTION = "function"
+PROCEDURE = "procedure"
+FIELD = "field"
+
+_IDENTIFIER = r'(?:"(?:[^"]|"")+"|[A-Za-z_][\w$]*)'
+_NAME = rf"{_IDENTIFIER}(?:\s*\.\s*{_IDENTIFIER})*"
+_CREATE = re.compile(
+    r"CREATE\s+(?:OR\s+REPLACE\s+)?(?:(?:GLOBAL|LOCAL)\s+)?"
+    r"(?:(TEMP|TEMPORARY|UNLOGGED|MATERIALIZED|RECURSIVE)\s+)?"
+    rf"(TABLE|VIEW|FUNCTION|PROCEDURE)\s+(?:IF\s+NOT\s+EXISTS\s+)?({_NAME})",
+    re.IGNORECASE,
+)
+_PART = re.compile(_IDENTIFIER)
+# Where a routine's header ends and its body or options begin
+_ROUTINE_BODY = re.compile(r"\b(?:AS|LANGUAGE|BEGIN|RETURN|IMMUTABLE|STABLE|VOLATILE)\b", re.IGNORECASE)
+_VIEW_BODY = re.compile(r"\bAS\b", re.IGNORECASE)
+_BEGIN_ATOMIC = re.compile(r"\bBEGIN\s+ATOMIC\b", re.IGNORECASE)
+_ENDS_WITH_END = re.compile(r"\bEND\s*$", re.IGNORECASE)
+# Table elements that are constraints or options rather than columns
+_NOT_A_COLUMN = frozenset({"CONSTRAINT", "PRIMARY", "FOREIGN", "UNIQUE", "CHECK", "EXCLUDE", "LIKE", "PERIOD"})
+_DOLLAR_TAG = re.compile(r"\$(?:[A-Za-z_]\w*)?\$")
+# A $ after one of these is part of a name or a parameter ($1), not a quote
+_WORD = re.compile(r"[\w$]")
+
+
+def _unquote(identifier: str) -> str:
+    if identifier.startswith('"'):
+        return identifier[1:-1].replace('""', '"')
+    return identifier
+
+
+def _blank(text: str) -> str:
+    """*text* with everything but line breaks turned into spaces, so offsets and lines are kept."""
+    return re.sub(r"[^\n]", " ", text)
+
+
+def _mask(source: str) -> Tuple[str, str]:
+    """
+    Returns *source* with comments blanked, and a copy that also blanks the inside of literals.
+
+    The first is what symbols report as code; the second is what statements
+    are split and matched on, so a ``;`` or ``CREATE`` inside a string, a
+    dollar-quoted function body or a quoted identifier is never seen.
+    """
+    code: List[str] = []
+    masked: List[str] = []
+    i, n = 0, len(source)
+    while i < n:
+        c = source[i]
+        if source.startswith("--", i):
+            end = source.find("\n", i)
+            end = n if end == -1 else end
+            code.append(_blank(source[i:end]))
+            masked.append(code[-1])
+        elif source.startswith("/*", i):
+            # PostgreSQL block comments nest
+            depth, end = 1, i + 2
+            while end < n and depth:
+                if source.startswith("/*", end):
+                    depth, end = depth + 1, end + 2
+                elif source.startswith("*/", end):
+                    depth, end = depth - 1, end + 2
+                else:
+                    end += 1
+            code.append(_blank(source[i:end]))
+            masked.append(code[-1])
+        elif c in "'\"":
+            end = i + 1
+            while end < n:
+                if source[end] == c:
+                    if source.startswith(c * 2, end):
+                        end += 2
+                        continue
+                    end += 1
+                    break
+                end += 1
+            code.append(source[i:end])
+            # Quoted identifiers are names, so they stay readable
+            masked.append(source[i:end] if c == '"' else c + _blank(source[i + 1 : end - 1]) + c)
+        elif c == "$" and _DOLLAR_TAG.match(source, i) and not (i and _WORD.match(source[i - 1])):
+            tag = _DOLLAR_TAG.match(source, i).group(0)
+            close = source.find(tag, i + len(tag))
+            if close == -1:
+                # An unterminated body runs to the end of the file
+                close, tag_end = n, n
+            else:
+                tag_end = close + len(tag)
+            end = tag_end
+            code.append(source[i:end])
+            masked.append(tag + _blank(source[i + len(tag) : close]) + source[close:tag_end])
+        else:
+            end = i + 1
+            code.append(c)
+            masked.append(c)
+        i = end
+    return "".join(code), "".join(masked)
+
+
+def _statements(masked: str) -> List[Tuple[int, int]]:
+    """``(start, end)`` offsets of each statement, ``end`` just past its ``;`` or at the end of the file."""
+    statements: List[Tuple[int, int]] = []
+    start = 0
+    atomic = False
+    for match in re.finditer(r";", masked + ";"):
+        end = min(match.end(), len(masked))
+        text = masked[start : match.start()]
+        # A SQL-standard BEGIN ATOMIC body holds statements of its own and only ends at END
+        atomic = atomic or bool(_BEGIN_ATOMIC.search(text) and _CREATE.match(text.strip()))
+        if atomic and not _ENDS_WITH_END.search(text) and match.start() < len(masked):
+            continue
+        atomic = False
+        if text.strip():
+            statements.append((start + len(text) - len(text.lstrip()), end))
+        start = end
+    return statements
+
+
+def _closing_paren(masked: str, start: int) -> int:
+    """The offset of the ``)`` matching the ``(`` at *start*, or -1."""
+    depth = 0
+    for i in range(start, len(masked)):
+        if masked[i] == "(":
+            depth += 1
+        elif masked[i] == ")":
+            depth -= 1
+            if depth == 0:
+                return i
+    return -1
+
+
+def _split_top_level(masked: str, start: int, end: int) -> List[Tuple[int, int]]:
+    """``(start, end)`` offsets of the comma-separated items of ``masked[start:end]``, outside nested parentheses."""
+    items, depth, item_start = [], 0, start
+    for i in range(start, end):
+        if masked[i] == "(":
+            depth += 1
+        elif masked[i] == ")":
+            depth -= 1
+        elif masked[i] == "," and depth == 0:
+            items.append((item_start, i))
+            item_start = i + 1
+    items.append((item_start, end))
+    return items
+
+
+class SqlExtractor:
+    """
+    Reports the objects created by SQL files: ``table``, ``view``, ``function`` and ``procedure`` symbols.
+
+    Statements are read as ANSI SQL with PostgreSQL's extensions: ``--`` and
+    nested ``/* */`` comments, ``'...'`` strings, ``"..."`` identifiers and
+    dollar-quoted function bodies (``$$ ... $$``, ``$body$ ... $body$``), so
+    anything inside them is skipped. ``CREATE OR REPLACE``, ``TEMP``,
+    ``UNLOGGED``, ``MATERIALIZED`` and ``IF NOT EXISTS`` are accepted, and a
+    ``BEGIN ATOMIC ... END`` body may contain semicolons. Other dialects'
+    quoting (MySQL backticks, T-SQL brackets) and batch separators
+    (``DELIMITER``, ``GO``) are not understood.
+
+    A symbol is named by the object's name without its schema, which is kept
+    in ``schema`` when the name is qualified (``public.users``). A table's
+    columns are ``field`` symbols with the table as their ``parent``; table
+    constraints are skipped. A materialized view has ``materialized: True``.
+    The block of ``--`` comment lines directly above a statement is its
+    ``docstring``, with the dashes and one following space removed.
+    """
+
+    def extract(self, path: str, source: str) -> List[Dict[str, Any]]:
+        code, masked = _mask(source)
+        line_starts = [0] + [m.end() for m in re.finditer("\n", source)]
+        lines = source.split("\n")
+
+        def position(offset: int) -> Tuple[int, int]:
+            line = bisect.bisect_right(line_starts, offset) - 1
+            return line, offset - line_starts[line]
+
+        def symbol(name: str, kind: str, start: int, end: int, signature: str) -> Dict[str, Any]:
+            start_line, start_column = position(start)
+            end_line, end_column = position(end)
+            return {
+                "name": name,
+                "type": kind,
+                "start_line": start_line,
+                "end_line": end_line,
+                "start_column": start_column,
+                "end_column": end_column,
+                "start_byte": len(source[:start].encode("utf-8")),
+                "end_byte": len(source[:end].encode("utf-8")),
+                "code": source[start:end],
+                "signature": " ".join(signature.split()),
+            }
+
+        symbols: List[Dict[str, Any]] = []
+        for start, end in _statements(masked):
+            statement = masked[start:end]
+            match = _CREATE.match(statement)
+            if match is None:
+                continue
+            modifier, keyword, qualified = (match.group(1) or "").upper(), match.group(2).upper(), match.group(3)
+            parts = [_unquote(p) for p in _PART.findall(qualified)]
+            kind = {"TABLE": TABLE, "VIEW": VIEW, "FUNCTION": FUNCTION, "PROCEDURE": PROCEDURE}[keyword]
+            name_end = start + match.end()
+            header_end = name_end
+            columns: Optional[Tuple[int, int]] = None
+            rest = masked[name_end:end]
+            if rest.lstrip().startswith("("):
+                open_paren = name_end + len(rest) - len(rest.lstrip())
+                close_paren = _closing_paren(masked, open_paren)
+                if close_paren != -1 and close_paren < end:
+                    columns = (open_paren + 1, close_paren)
+                    if kind != TABLE:
+                        header_end = close_paren + 1
+            if kind in (FUNCTION, PROCEDURE):
+                body = _ROUTINE_BODY.search(masked, header_end, end)
+                header_end = body.start() if body else end
+            elif kind == VIEW:
+                body = _VIEW_BODY.search(masked, header_end, end)
+                header_end = body.start() if body else header_end
+            # The statement's ; is not part of the object
+            code_end = end - 1 if masked[end - 1 : end] == ";" else end
+            created = symbol(parts[-1], kind, start, code_end, code[start:header_end].rstrip().rstrip(";"))
+            if len(parts) > 1:
+                created["schema"] = ".".join(parts[:-1])
+            if modifier == "MATERIALIZED":
+                created["materialized"] = True
+            docstring = self._docstring(lines, created["start_line"])
+            if docstring:
+                created["docstring"] = docstring
+            symbols.append(created)
+
+            if kind == TABLE and columns is not None:
+                for item_start, item_end in _split_top_level(masked, *columns):
+                    text = masked[item_start:item_end]
+                    stripped = text.strip()
+                    column = _PART.match(stripped)
+                    if not stripped or column is None or column.group(0).upper() in _NOT_A_COLUMN:
+                        continue
+                    column_start = item_start + len(text) - len(text.lstrip())
+                    column_end = item_start + len(text.rstrip())
+                    signature = code[column_start:column_end]
+                    field = symbol(_unquote(column.group(0)), FIELD, column_start, column_end, signature)
+                    field["parent"] = created["name"]
+                    field["node_path"] = f"{created['name']}.{field['name']}"
+                    symbols.append(field)
+        return symbols
+
+    @staticmethod
+    def _docstring(lines: List[str], start_line: int) -> str:
+        """The ``--`` comment lines directly above *start_line*, without their dashes."""
+        comment: List[str] = []
+        line = start_line - 1
+        while line >= 0 and lines[line].lstrip().startswith("--"):
+            text = lines[line].lstrip()[2:]
+            comment.append(text[1:] if text.startswith(" ") else text)
+            line -= 1
+        return "\n".join(reversed(comment)).rstrip()
@@ -514,9 +514,9 @@ class Summarizer:
         symbols = self.repo.extract_symbols(file_path)
         function_code = None
         for symbol in symbols:
-            # Use node_path if available (more precise), fallback to name
-            current_symbol_name = symbol.get("node_path", symbol.get("name"))
-            if current_symbol_name == function_name and symbol.get("type", "").upper() in ["FUNCTION", "METHOD"]:
+            # Match the qualified node_path (e.g. "User.Greet") or the bare name
+            symbol_names = (symbol.get("node_path"), symbol.get("name"))
+            if function_name in symbol_names and symbol.get("type", "").upper() in ["FUNCTION", "METHOD"]:
                 function_code = symbol.get("code")
                 break
 
@@ -668,9 +668,9 @@ class Summarizer:
         symbols = self.repo.extract_symbols(file_path)
         class_code = None
         for symbol in symbols:
-            # Use node_path if available (more precise), fallback to name
-            current_symbol_name = symbol.get("node_path", symbol.get("name"))
-            if current_symbol_name == class_name and symbol.get("type", "").upper() == "CLASS":
+            # Match the qualified node_path (e.g. "Outer.Inner") or the bare name
+            symbol_names = (symbol.get("node_path"), symbol.get("name"))
+            if class_name in symbol_names and symbol.get("type", "").upper() == "CLASS":
                 class_code = symbol.get("code")
                 break
 
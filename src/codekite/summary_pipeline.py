@@ -0,0 +1,323 @@
+"""Summarizing files and symbols through any LLM client, with caching and hierarchical merging of long inputs."""
+
+from __future__ import annotations
+import hashlib
+import json
+import logging
+import threading
+import time
+from concurrent.futures import ThreadPoolExecutor
+from pathlib import Path
+from typing import TYPE_CHECKING, Any, Callable, Dict, List, Optional, Protocol, Tuple, Union, runtime_checkable
+
+from .context_extractor import ContextExtractor
+from .summaries import LLMError, SymbolNotFoundError
+from .tokenizers import HeuristicTokenizer, Tokenizer
+
+if TYPE_CHECKING:
+    from .repository import Repository
+
+logger = logging.getLogger(__name__)
+
+_INSTRUCTIONS = "Answer with the paragraph only."
+
+_SUMMARY_PROMPT = (
+    "Summarize {subject} in one paragraph: what it is for, what it does and anything a caller must know. "
+    f"{_INSTRUCTIONS}\n\n{{doc}}```\n{{body}}\n```"
+)
+_PART_PROMPT = (
+    "Below is part {part} of {parts} (lines {start}-{end}) of {subject}, which is too long to read at once. "
+    f"Summarize this part in one paragraph. {_INSTRUCTIONS}\n\n{{doc}}```\n{{body}}\n```"
+)
+_MERGE_PROMPT = (
+    "Below are summaries of consecutive parts of {subject}, in order. "
+    f"Combine them into one paragraph summarizing the whole. {_INSTRUCTIONS}\n\n{{summaries}}"
+)
+
+
+@runtime_checkable
+class LLMClient(Protocol):
+    """The one call the pipeline needs from a model; wrap OpenAI, Anthropic or a local model behind it."""
+
+    def complete(self, prompt: str) -> str:
+        """Returns the model's answer to *prompt*."""
+        ...
+
+
+class SummaryCache:
+    """
+    Summaries keyed by the SHA-256 of the prompt that produced them, optionally kept in a JSON file.
+
+    A prompt holds the code it summarizes, so a key changes exactly when the
+    code, its doc comment or its place in the file does.
+    """
+
+    def __init__(self, path: Optional[str] = None) -> None:
+        self.path = Path(path) if path else None
+        self._entries: Dict[str, str] = {}
+        self._lock = threading.Lock()
+        if self.path is not None and self.path.exists():
+            self._entries = json.loads(self.path.read_text(encoding="utf-8"))
+
+    def __len__(self) -> int:
+        return len(self._entries)
+
+    def get(self, key: str) -> Optional[str]:
+        with self._lock:
+            return self._entries.get(key)
+
+    def put(self, key: str, summary: str) -> None:
+        with self._lock:
+            self._entries[key] = summary
+
+    def save(self) -> None:
+        """Writes the cache to its file; a no-op for in-memory caches."""
+        if self.path is None:
+            return
+        self.path.parent.mkdir(parents=True, exist_ok=True)
+        with self._lock:
+            text = json.dumps(self._entries, indent=2, sort_keys=True)
+        self.path.write_text(text, encoding="utf-8")
+
+
+class RateLimiter:
+    """Spaces calls at least ``1 / calls_per_second`` seconds apart, across threads."""
+
+    def __init__(
+        self,
+        calls_per_second: float,
+        clock: Callable[[], float] = time.monotonic,
+        sleep: Callable[[float], None] = time.sleep,
+    ) -> None:
+        if calls_per_second <= 0:
+            raise ValueError("calls_per_second must be positive")
+        self._interval = 1.0 / calls_per_second
+        self._clock = clock
+        self._sleep = sleep
+        self._next = 0.0
+        self._lock = threading.Lock()
+
+    def wait(self) -> None:
+        """Blocks until the next call is allowed."""
+        # Sleeping under the lock is deliberate: waiting threads queue up behind it
+        with self._lock:
+            now = self._clock()
+            if self._next > now:
+                self._sleep(self._next - now)
+                now = self._next
+            self._next = now + self._interval
+
+
+class SummaryPipeline:
+    """
+    Produces one-paragraph summaries of files and symbols with an :class:`LLMClient`.
+
+    Every prompt's answer is cached, so code that did not change is never sent
+    to the model again. Text longer than *max_input_tokens* is cut into
+    line-aligned parts that are summarized separately, and their summaries are
+    then merged, in rounds if need be, into one.
+
+    Args:
+        repo: The repository to read files and symbols from.
+        client: Answers prompts; see :class:`LLMClient`.
+        cache: Where summaries are kept; defaults to an in-memory cache.
+        max_workers: Most prompts in flight at once in :meth:`summarize_repository`.
+        rate_limit: Most model calls per second, or None for no limit. Cache hits do not count.
+        max_input_tokens: Budget for the code or summaries in one prompt, not counting instructions.
+        tokenizer: Counts tokens; defaults to :class:`~codekite.tokenizers.HeuristicTokenizer`.
+    """
+
+    def __init__(
+        self,
+        repo: "Repository",
+        client: LLMClient,
+        *,
+        cache: Optional[SummaryCache] = None,
+        max_workers: int = 4,
+        rate_limit: Optional[float] = None,
+        max_input_tokens: int = 3000,
+        tokenizer: Optional[Tokenizer] = None,
+    ) -> None:
+        if max_workers < 1:
+            raise ValueError("max_workers must be at least 1")
+        if max_input_tokens <= 0:
+            raise ValueError("max_input_tokens must be positive")
+        self.repo = repo
+        self.client = client
+        self.cache = cache if cache is not None else SummaryCache()
+        self.max_workers = max_workers
+        self.max_input_tokens = max_input_tokens
+        self.tokenizer: Tokenizer = tokenizer or HeuristicTokenizer()
+        self._limiter = RateLimiter(rate_limit) if rate_limit is not None else None
+        # Model calls made, i.e. cache misses
+        self.calls = 0
+        self._lock = threading.Lock()
+
+    def _complete(self, prompt: str) -> str:
+        key = hashlib.sha256(prompt.encode("utf-8")).hexdigest()
+        cached = self.cache.get(key)
+        if cached is not None:
+            return cached
+        if self._limiter is not None:
+            self._limiter.wait()
+        with self._lock:
+            self.calls += 1
+        try:
+            summary = self.client.complete(prompt)
+        except Exception as e:
+            raise LLMError(f"LLM call failed: {e}") from e
+        if not summary or not summary.strip():
+            raise LLMError("LLM returned an empty summary")
+        summary = summary.strip()
+        self.cache.put(key, summary)
+        return summary
+
+    def _summarize(self, subject: str, body: str, doc: str = "") -> str:
+        doc_section = f"Its doc comment reads:\n{doc}\n\n" if doc else ""
+        if self.tokenizer.count(body) <= self.max_input_tokens:
+            return self._complete(_SUMMARY_PROMPT.format(subject=subject, doc=doc_section, body=body))
+        parts = ContextExtractor.chunk_text_by_tokens(body, self.max_input_tokens, tokenizer=self.tokenizer)
+        logger.debug(f"Summarizing {subject} in {len(parts)} parts")
+        summaries = [
+            self._complete(
+                _PART_PROMPT.format(
+                    part=i,
+                    parts=len(parts),
+                    start=part.start_line + 1,
+                    end=part.end_line + 1,
+                    subject=subject,
+                    doc=doc_section,
+                    body=part.code.rstrip("\n"),
+                )
+            )
+            for i, part in enumerate(parts, 1)
+        ]
+        return self._merge(subject, summaries)
+
+    def _merge(self, subject: str, summaries: List[str]) -> str:
+        while len(summaries) > 1:
+            groups = self._pack(summaries)
+            if len(groups) == len(summaries):
+                # No two summaries fit one prompt; merging all at once is over budget but always ends
+                groups = [summaries]
+            summaries = [
+                self._complete(_MERGE_PROMPT.format(subject=subject, summaries=_numbered(group)))
+                if len(group) > 1
+                else group[0]
+                for group in groups
+            ]
+        return summaries[0]
+
+    def _pack(self, summaries: List[str]) -> List[List[str]]:
+        """Runs of consecutive summaries that each fit one merge prompt."""
+        groups: List[List[str]] = [[summaries[0]]]
+        for summary in summaries[1:]:
+            if self.tokenizer.count(_numbered(groups[-1] + [summary])) <= self.max_input_tokens:
+                groups[-1].append(summary)
+            else:
+                groups.append([summary])
+        return groups
+
+    def summarize_file(self, file_path: str) -> str:
+        """
+        Summarizes one file.
+
+        Returns:
+            The summary; empty for a file holding only whitespace.
+
+        Raises:
+            FileNotFoundError: If the file does not exist.
+            LLMError: If the model fails or returns nothing.
+        """
+        content = self.repo.get_file_content(file_path)
+        if not content.strip():
+            return ""
+        return self._summarize(f"the file {file_path}", content.rstrip("\n"))
+
+    def summarize_symbol(self, file_path: str, symbol: Union[str, Dict[str, Any]]) -> str:
+        """
+        Summarizes one symbol from its code and doc comment.
+
+        Args:
+            file_path: File the symbol is in, relative to the repository root.
+            symbol: A symbol from :meth:`Repository.extract_symbols`, or its
+                name or qualified name (``"User.Greet"``) to look up.
+
+        Raises:
+            SymbolNotFoundError: If no symbol in the file has that name.
+            LLMError: If the model fails or returns nothing.
+        """
+        if isinstance(symbol, str):
+            symbols = self.repo.extract_symbols(file_path)
+            found = next((s for s in symbols if s.get("node_path") == symbol), None)
+            found = found or next((s for s in symbols if s.get("name") == symbol), None)
+            if found is None:
+                raise SymbolNotFoundError(f"Symbol {symbol} not found in {file_path}")
+            symbol = found
+        code = (symbol.get("code") or "").rstrip("\n")
+        if not code.strip():
+            return ""
+        name = symbol.get("node_path") or symbol.get("name")
+        subject = f"the {symbol.get('type', 'symbol')} {name} in {file_path}"
+        doc = symbol.get("docstring") or ""
+        # Python docstrings are already part of the code
+        return self._summarize(subject, code, "" if doc and doc in code else doc)
+
+    def summarize_repository(self, level: str = "file", file_paths: Optional[List[str]] = None) -> Dict[str, str]:
+        """
+        Summarizes every source file, or every symbol in them, with up to *max_workers* prompts at once.
+
+        A summary that fails is logged and left out of the result rather than
+        stopping the run. A file-backed cache is saved when the run finishes.
+
+        Args:
+            level: ``"file"`` or ``"symbol"``.
+            file_paths: Files to cover, relative to the repository root; defaults to all source files.
+
+        Returns:
+            Summaries by file path, or by ``"path::qualified.name"`` at symbol level, in file order.
+
+        Raises:
+            ValueError: If *level* is neither ``"file"`` nor ``"symbol"``.
+        """
+        if level not in ("file", "symbol"):
+            raise ValueError(f"level must be 'file' or 'symbol', not {level!r}")
+        if file_paths is None:
+            root = self.repo.mapper.repo_path
+            file_paths = [f.relative_to(root).as_posix() for f in self.repo.mapper.source_files()]
+
+        tasks: List[Tuple[str, Callable[[], str]]] = []
+        for path in file_paths:
+            if level == "file":
+                tasks.append((path, lambda path=path: self.summarize_file(path)))
+                continue
+            try:
+                symbols = self.repo.extract_symbols(path)
+            except Exception as e:
+                logger.error(f"Failed to extract symbols from {path}: {e}")
+                continue
+            seen = set()
+            for symbol in symbols:
+                key = f"{path}::{symbol.get('node_path') or symbol.get('name')}"
+                # Repeated declarations, such as Go init functions, get one summary
+                if key in seen:
+                    continue
+                seen.add(key)
+                tasks.append((key, lambda path=path, symbol=symbol: self.summarize_symbol(path, symbol)))
+
+        def run(task: Tuple[str, Callable[[], str]]) -> Optional[str]:
+            key, summarize = task
+            try:
+                return summarize()
+            except Exception as e:
+                logger.error(f"Failed to summarize {key}: {e}")
+                return None
+
+        with ThreadPoolExecutor(max_workers=self.max_workers) as executor:
+            results = list(executor.map(run, tasks))
+        self.cache.save()
+        return {key: summary for (key, _), summary in zip(tasks, results) if summary}
+
+
+def _numbered(summaries: List[str]) -> str:
+    return "\n\n".join(f"{i}. {summary}" for i, summary in enumerate(summaries, 1))
@@ -0,0 +1,196 @@
+"""Comparing two symbol sets for API changes, e.g. to gate CI on breaking changes."""
+
+from __future__ import annotations
+import hashlib
+import json
+import os
+from dataclasses import dataclass, field
+from typing import Any, Dict, Iterable, List, Optional, Tuple
+
+from .symbol_filter import is_exported
+
+CHANGE_ADDED = "added"
+CHANGE_REMOVED = "removed"
+CHANGE_CHANGED = "changed"
+
+
+@dataclass(frozen=True)
+class SymbolChange:
+    """
+    One symbol added, removed or changed between two symbol sets.
+
+    Attributes:
+        change: ``"added"``, ``"removed"`` or ``"changed"``.
+        symbol: The new symbol; the old one when removed.
+        before: The old signature of a changed symbol.
+        after: The new signature of a changed symbol.
+        breaking: The change can break callers: an exported symbol was
+            removed or its signature changed. Additions never break.
+    """
+
+    change: str
+    symbol: Dict[str, Any]
+    before: Optional[str] = None
+    after: Optional[str] = None
+    breaking: bool = False
+
+    @property
+    def name(self) -> str:
+        return self.symbol.get("node_path") or self.symbol["name"]
+
+    def to_dict(self) -> Dict[str, Any]:
+        entry = {
+            "change": self.change,
+            "name": self.name,
+            "type": self.symbol.get("type"),
+            "file": self.symbol.get("file"),
+            "breaking": self.breaking,
+        }
+        if self.change == CHANGE_CHANGED:
+            entry.update(before=self.before, after=self.after)
+        return entry
+
+
+@dataclass
+class SymbolDiff:
+    """The result of :func:`diff_symbol_sets`, each list ordered by package, type and name."""
+
+    added: List[SymbolChange] = field(default_factory=list)
+    removed: List[SymbolChange] = field(default_factory=list)
+    changed: List[SymbolChange] = field(default_factory=list)
+
+    @property
+    def breaking(self) -> List[SymbolChange]:
+        """Removed or changed exported symbols."""
+        return [c for c in self.removed + self.changed if c.breaking]
+
+    def to_dict(self) -> Dict[str, Any]:
+        return {
+            "added": [c.to_dict() for c in self.added],
+            "removed": [c.to_dict() for c in self.removed],
+            "changed": [c.to_dict() for c in self.changed],
+            "breaking": len(self.breaking),
+        }
+
+
+def _key(symbol: Dict[str, Any]) -> Tuple[str, str, str]:
+    # A symbol keeps its identity when it moves to another file of the same package
+    package = os.path.dirname(symbol.get("file") or "")
+    return package, symbol.get("type", ""), symbol.get("node_path") or symbol["name"]
+
+
+def _by_key(symbols: Iterable[Dict[str, Any]]) -> Dict[Tuple[str, str, str], Dict[str, Any]]:
+    keyed: Dict[Tuple[str, str, str], Dict[str, Any]] = {}
+    for symbol in symbols:
+        # Repeated declarations (Go init functions) are one API entry
+        keyed.setdefault(_key(symbol), symbol)
+    return keyed
+
+
+def _is_api(symbol: Dict[str, Any]) -> bool:
+    # Test helpers are exported within their test package, but nothing outside it can import them
+    return is_exported(symbol) and not symbol.get("is_test")
+
+
+def diff_symbol_sets(old: Iterable[Dict[str, Any]], new: Iterable[Dict[str, Any]]) -> SymbolDiff:
+    """
+    Compares the symbols of two versions of a codebase.
+
+    Symbols are matched by package directory, type and qualified name, so
+    moving a function between files of one package is not a change. A matched
+    symbol is changed when its ``signature`` differs; edits to its body are not
+    API changes and are ignored. Removing or changing an unexported symbol, or
+    one of a test file (``is_test``), is never breaking.
+
+    Args:
+        old: Symbols of the earlier version, with ``file`` set.
+        new: Symbols of the later version.
+    """
+    old_symbols, new_symbols = _by_key(old), _by_key(new)
+    diff = SymbolDiff()
+    for key in sorted(set(old_symbols) | set(new_symbols)):
+        before, after = old_symbols.get(key), new_symbols.get(key)
+        if before is None:
+            diff.added.append(SymbolChange(CHANGE_ADDED, after))
+        elif after is None:
+            diff.removed.append(SymbolChange(CHANGE_REMOVED, before, breaking=_is_api(before)))
+        elif before.get("signature") != after.get("signature"):
+            diff.changed.append(
+                SymbolChange(
+                    CHANGE_CHANGED,
+                    after,
+                    before=before.get("signature"),
+                    after=after.get("signature"),
+                    # Only code outside the package can be broken, and it could only use exported symbols
+                    breaking=_is_api(before),
+                )
+            )
+    return diff
+
+
+def _api_entry(symbol: Dict[str, Any]) -> Optional[str]:
+    """The line *symbol* contributes to an API hash, or None if it is not part of the public API."""
+    if not _is_api(symbol):
+        return None
+    signature = " ".join(str(symbol.get("signature") or "").split())
+    entry = f"{symbol.get('type', '')} {symbol.get('node_path') or symbol['name']}: {signature}"
+    if symbol.get("type") == "interface" and symbol.get("methods"):
+        # An interface's header is all its signature shows; the methods are its contract
+        entry += " {" + "; ".join(sorted(" ".join(m.split()) for m in symbol["methods"])) + "}"
+    return entry
+
+
+def api_hash(symbols: Iterable[Dict[str, Any]]) -> str:
+    """
+    Returns a SHA-256 hex digest of the exported API of *symbols*, e.g. the symbols of one package.
+
+    The hash covers each exported symbol's type, qualified name and
+    signature, plus the methods of an interface, so it changes whenever
+    :func:`diff_symbol_sets` would report an exported symbol added, removed
+    or changed. Unexported symbols (see
+    :func:`~codekite.symbol_filter.is_exported`), symbols of test files, doc
+    comments, bodies, positions and the file a symbol is declared in play no
+    part, and neither does the order of *symbols*. Two commits with the same
+    hash have the same public API as far as signatures show it.
+    """
+    entries = sorted({entry for entry in map(_api_entry, symbols) if entry is not None})
+    return hashlib.sha256("\n".join(entries).encode("utf-8")).hexdigest()
+
+
+def api_hashes(symbols: Iterable[Dict[str, Any]]) -> Dict[str, str]:
+    """
+    Returns :func:`api_hash` per package, keyed by directory (``"."`` for the root) in sorted order.
+
+    Every package with a symbol gets a hash, even one that exports nothing.
+    """
+    packages: Dict[str, List[Dict[str, Any]]] = {}
+    for symbol in symbols:
+        packages.setdefault(os.path.dirname(symbol.get("file") or "") or ".", []).append(symbol)
+    return {package: api_hash(members) for package, members in sorted(packages.items())}
+
+
+def _from_export(symbol: Dict[str, Any]) -> Dict[str, Any]:
+    # Export symbols call their type "kind" and keep language-specific fields under "attributes"
+    restored = dict(symbol.get("attributes") or {})
+    restored.update((k, v) for k, v in symbol.items() if k not in ("kind", "attributes"))
+    restored["type"] = symbol.get("kind")
+    return restored
+
+
+def load_symbols(path: str) -> List[Dict[str, Any]]:
+    """
+    Reads symbols saved as JSON: a list, as written by :meth:`Repository.write_symbols`,
+    an index from :meth:`Repository.write_index`, or an export from
+    :meth:`Repository.export_json` or ``codekite symbols --format json``.
+    """
+    with open(path, encoding="utf-8") as f:
+        data = json.load(f)
+    if isinstance(data, dict) and "schema_version" in data:
+        return [_from_export(s) for s in data.get("symbols") or []]
+    if isinstance(data, dict):
+        data = data.get("symbols")
+        if isinstance(data, dict):
+            return [dict(s, file=s.get("file") or file) for file, symbols in data.items() for s in symbols]
+    if not isinstance(data, list):
+        raise ValueError(f"{path} holds neither a symbol list nor an index")
+    return data
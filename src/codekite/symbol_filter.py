@@ -0,0 +1,153 @@
+"""Selecting symbols by kind, name, visibility, file and language."""
+
+from __future__ import annotations
+import fnmatch
+import os
+import re
+from dataclasses import dataclass, field
+from typing import Any, Dict, FrozenSet, List, Optional, Pattern, Sequence
+
+# Shorthands accepted wherever a kind is given. A kind that is not listed
+# here matches the symbol ``type`` exactly.
+KIND_ALIASES: Dict[str, FrozenSet[str]] = {
+    "func": frozenset({"function"}),
+    "fn": frozenset({"function"}),
+    "type": frozenset({"type", "struct", "interface", "class", "enum", "union", "trait", "record", "annotation"}),
+    "const": frozenset({"constant"}),
+    "var": frozenset({"variable"}),
+}
+
+
+def expand_kinds(kinds: List[str]) -> FrozenSet[str]:
+    """Resolves aliases such as ``func`` and ``type`` to the symbol types they stand for."""
+    expanded = set()
+    for kind in kinds:
+        kind = kind.strip().lower()
+        if kind:
+            expanded.update(KIND_ALIASES.get(kind, {kind}))
+    return frozenset(expanded)
+
+
+def _name_exported(name: str, ext: str) -> bool:
+    if ext == ".go":
+        return name[:1].isupper()
+    if ext == ".py":
+        # Dunder names such as __init__ are part of the public protocol
+        return not name.startswith("_") or (name.startswith("__") and name.endswith("__") and len(name) > 4)
+    return True
+
+
+def is_exported(symbol: Dict[str, Any], ext: Optional[str] = None) -> bool:
+    """
+    Tells whether a symbol is visible outside its module.
+
+    An ``exported`` field recorded by the extractor wins. Otherwise Go names
+    are exported when they start with an upper-case letter and Python names
+    when they do not start with an underscore; symbols from other languages
+    count as exported. A method or nested definition is only exported if the
+    type or scope it belongs to (its ``parent``) is too, so methods of an
+    unexported Go type are unexported whatever their own name.
+
+    Args:
+        symbol: The symbol to check.
+        ext: File extension deciding the rules; defaults to that of ``symbol["file"]``.
+    """
+    if "exported" in symbol:
+        return bool(symbol["exported"])
+    if ext is None:
+        ext = os.path.splitext(symbol.get("file") or "")[1]
+    names = [symbol.get("name") or ""]
+    if symbol.get("parent"):
+        names.extend(str(symbol["parent"]).split("."))
+    return all(_name_exported(name, ext) for name in names)
+
+
+@dataclass
+class SymbolFilter:
+    """
+    Criteria for :func:`apply_filter`. Every criterion that is set must match.
+
+    Attributes:
+        kinds: Symbol types to keep, e.g. ``["function", "interface"]``, or the
+            aliases in :data:`KIND_ALIASES`. Empty keeps every kind.
+        name_pattern: Regular expression searched for in the symbol name.
+        exported_only: Drop symbols that :func:`is_exported` rejects.
+        include: :mod:`fnmatch` patterns over the symbol's repository-relative
+            ``file``, e.g. ``["src/**"]``, where ``*`` also crosses ``/``. Empty
+            keeps every file.
+        exclude: Patterns like *include*; a file matching any of them is dropped
+            even if *include* keeps it.
+        languages: Languages to keep, by name or extension as
+            :func:`~codekite.languages.extension_for` accepts them, e.g.
+            ``["go", "ts"]``. Empty keeps every language.
+        min_complexity: Only keep functions and methods whose ``complexity`` is
+            at least this; symbols without one are dropped. None keeps every symbol.
+    """
+
+    kinds: List[str] = field(default_factory=list)
+    name_pattern: Optional[Pattern[str]] = None
+    exported_only: bool = False
+    include: List[str] = field(default_factory=list)
+    exclude: List[str] = field(default_factory=list)
+    languages: List[str] = field(default_factory=list)
+    min_complexity: Optional[int] = None
+
+    @classmethod
+    def from_strings(
+        cls,
+        kinds: Optional[str] = None,
+        name: Optional[str] = None,
+        exported_only: bool = False,
+        include: Optional[Sequence[str]] = None,
+        exclude: Optional[Sequence[str]] = None,
+        languages: Optional[str] = None,
+        min_complexity: Optional[int] = None,
+    ) -> "SymbolFilter":
+        """
+        Builds a filter from CLI-style values: comma-separated kinds and languages,
+        an uncompiled name regex, and lists of path patterns.
+        """
+        return cls(
+            kinds=[k for k in (kinds or "").split(",") if k.strip()],
+            name_pattern=re.compile(name) if name else None,
+            exported_only=exported_only,
+            include=list(include or []),
+            exclude=list(exclude or []),
+            languages=[lang.strip() for lang in (languages or "").split(",") if lang.strip()],
+            min_complexity=min_complexity,
+        )
+
+    def matches_path(self, path: str) -> bool:
+        """Tells whether symbols of the file at repository-relative *path* can match at all."""
+        if self.include and not any(fnmatch.fnmatchcase(path, pattern) for pattern in self.include):
+            return False
+        if any(fnmatch.fnmatchcase(path, pattern) for pattern in self.exclude):
+            return False
+        if self.languages:
+            # Imported here: the languages registry imports the extractor, which imports this module
+            from .languages import extension_for, language_for
+
+            wanted = {language_for(extension_for(lang) or lang) for lang in self.languages}
+            if language_for(os.path.splitext(path)[1]) not in wanted - {None}:
+                return False
+        return True
+
+    def matches(self, symbol: Dict[str, Any]) -> bool:
+        if (self.include or self.exclude or self.languages) and not self.matches_path(symbol.get("file") or ""):
+            return False
+        if self.kinds and symbol.get("type") not in expand_kinds(self.kinds):
+            return False
+        if self.name_pattern is not None and not self.name_pattern.search(symbol.get("name") or ""):
+            return False
+        if self.exported_only and not is_exported(symbol):
+            return False
+        if self.min_complexity is not None:
+            complexity = symbol.get("complexity")
+            if complexity is None or complexity < self.min_complexity:
+                return False
+        return True
+
+
+def apply_filter(symbols: List[Dict[str, Any]], symbol_filter: SymbolFilter) -> List[Dict[str, Any]]:
+    """Returns the symbols matching *symbol_filter*, in their original order."""
+    return [s for s in symbols if symbol_filter.matches(s)]
@@ -0,0 +1,215 @@
+"""Stable identifiers for symbols, so annotations stored elsewhere can be re-attached after re-indexing."""
+
+from __future__ import annotations
+import hashlib
+import posixpath
+import re
+from collections import Counter
+from typing import AbstractSet, Any, Dict, Iterable, Iterator, List, Optional, Set
+
+_GO_PACKAGE_CLAUSE = re.compile(r"^\s*package\s+(\w+)", re.MULTILINE)
+_PYTHON_EXTENSIONS = (".py", ".pyi")
+_JS_EXTENSIONS = (".ts", ".tsx", ".mts", ".cts", ".js", ".jsx", ".mjs", ".cjs")
+_IDENTIFIER = re.compile(r"[A-Za-z_$][\w$]*")
+_TOKEN = re.compile(rf"{_IDENTIFIER.pattern}|\.\.\.|::|\S")
+_ANNOTATION = re.compile(r"@[\w.]+(\s*\([^()]*\))?")
+_OPENING, _CLOSING = "([{<", ")]}>"
+
+
+def _module(path: str, symbol: Dict[str, Any]) -> str:
+    path = posixpath.normpath(path.replace("\\", "/"))
+    if path.endswith(".go") and not (symbol.get("name") == "init" and symbol.get("type") == "function"):
+        # A Go package is its directory, so moving a function between its files keeps the ID.
+        # init may be declared once per file and is never referenced, so it stays per file.
+        return posixpath.dirname(path)
+    return path
+
+
+def qualified_name(path: str, symbol: Dict[str, Any]) -> str:
+    """
+    Returns *symbol*'s name qualified by its package, e.g. ``pkg/user.User.Greet``.
+
+    This is :func:`symbol_id` without the type: the package is the Go
+    package directory or, for other languages, the file path.
+    """
+    module = _module(path, symbol)
+    name = symbol.get("node_path") or symbol.get("name") or ""
+    return f"{module + '.' if module else ''}{name}"
+
+
+def go_package(path: str, source: Optional[str] = None, module_path: Optional[str] = None) -> Optional[str]:
+    """
+    Returns the import path of the Go package of the file at *path*, or None for other files.
+
+    With *module_path*, the module path from go.mod, the package is
+    ``example.com/app/store`` for ``store/store.go``. Without it the
+    repository-relative directory stands in, and a package at the root is
+    named by its package clause in *source*, e.g. ``main``.
+    """
+    path = posixpath.normpath(path.replace("\\", "/"))
+    if not path.endswith(".go"):
+        return None
+    directory = posixpath.dirname(path)
+    if module_path:
+        return f"{module_path}/{directory}" if directory else module_path
+    if directory:
+        return directory
+    match = _GO_PACKAGE_CLAUSE.search(source) if source is not None else None
+    return match.group(1) if match else ""
+
+
+def full_name(path: str, symbol: Dict[str, Any], package: Optional[str] = None) -> str:
+    """
+    Returns *symbol*'s name qualified the way its language names it from outside its module.
+
+    - Go: the package's import path, see :func:`go_package`, e.g.
+      ``example.com/app/store.Store.Get``; *package* overrides the directory.
+    - Python: the dotted module path, ``app.models.Cart.add`` for
+      ``app/models.py``, with a package's ``__init__`` left out.
+    - TypeScript and JavaScript: the module path without its extension, and
+      without a trailing ``/index``, then the name: ``src/cart.Cart.add``.
+    - Other languages: as :func:`qualified_name`.
+
+    Like IDs, these names depend only on the path and the symbol's name, so
+    they can key an index kept on disk.
+    """
+    name = symbol.get("node_path") or symbol.get("name") or ""
+    normalized = posixpath.normpath(path.replace("\\", "/"))
+    stem, ext = posixpath.splitext(normalized)
+    if ext == ".go":
+        module = package if package is not None else go_package(normalized)
+    elif ext in _PYTHON_EXTENSIONS:
+        parts = stem.split("/")
+        if parts[-1] == "__init__":
+            parts.pop()
+        module = ".".join(parts)
+    elif ext in _JS_EXTENSIONS:
+        module = stem[: -len("/index")] if stem.endswith("/index") else stem
+        module = "" if module == "index" else module
+    else:
+        return qualified_name(path, symbol)
+    return f"{module + '.' if module else ''}{name}"
+
+
+def symbol_id(path: str, symbol: Dict[str, Any]) -> str:
+    """
+    Returns the ID of *symbol*, declared in the file at repository-relative *path*.
+
+    The ID is the symbol's package, its qualified name and its type, e.g.
+    ``pkg/user.User.Greet:method`` for the ``Greet`` method of ``User`` in
+    any file of the Go package ``pkg/user``. Other languages use the file
+    path as the package: ``app/models.py.Cart.add:method``. Lines, columns
+    and the symbol's code play no part, so edits elsewhere in the file, or
+    inside the symbol, leave it unchanged. Symbols of a Go package at the
+    repository root have no package part: ``main:function``.
+    """
+    return f"{qualified_name(path, symbol)}:{symbol.get('type') or ''}"
+
+
+def _split_parameters(text: str) -> List[str]:
+    """Splits a parameter list's text at the commas outside brackets, so ``Map<K, V> m`` stays whole."""
+    parts, depth, start = [], 0, 0
+    for i, char in enumerate(text):
+        if char in _OPENING:
+            depth += 1
+        elif char in _CLOSING and not (char == ">" and i > 0 and text[i - 1] in "=-"):
+            depth -= 1
+        elif char == "," and depth == 0:
+            parts.append(text[start:i])
+            start = i + 1
+    parts.append(text[start:])
+    return [part for part in parts if part.strip()]
+
+
+def _parameter_type(parameter: str) -> str:
+    """One parameter's type with its name, default, annotations and spacing dropped: ``Item &`` for ``Item& item``."""
+    parameter = re.split(r"=(?!>)", _ANNOTATION.sub(" ", parameter), maxsplit=1)[0]
+    tokens = [t for t in _TOKEN.findall(parameter) if t != "final"]
+    if ":" in tokens:
+        # name: type, as in Python, TypeScript and Rust; *args and ...rest keep their marker
+        colon = tokens.index(":")
+        markers = [t for t in tokens[:colon] if t in ("*", "...")]
+        return " ".join(markers + tokens[colon + 1 :])
+    words = [i for i, t in enumerate(tokens) if _IDENTIFIER.fullmatch(t)]
+    if len(words) > 1 and all(t in "[]" for t in tokens[words[-1] + 1 :]):
+        # type name, as in Java and C++
+        del tokens[words[-1]]
+    return " ".join(tokens)
+
+
+def parameter_types(symbol: Dict[str, Any]) -> Optional[List[str]]:
+    """
+    Returns the types of *symbol*'s parameters, or None if it has no parameter list.
+
+    Go symbols carry them in ``params``; for the rest they are read from the
+    parameter list in ``signature``, so ``public void add(final Item item)``
+    gives ``["Item"]``. Untyped parameters, as in plain Python, keep their name.
+    """
+    if symbol.get("params") is not None:
+        return [str(p.get("type") or "") for p in symbol["params"]]
+    signature, name = symbol.get("signature") or "", symbol.get("name") or ""
+    match = re.search(r"(?<![\w$])" + re.escape(name) + r"\s*(?:<[^()]*>\s*)?\(", signature) if name else None
+    if match is None:
+        return None
+    depth, start = 0, match.end()
+    for end in range(start, len(signature)):
+        if signature[end] == "(":
+            depth += 1
+        elif signature[end] == ")":
+            if depth == 0:
+                return [_parameter_type(p) for p in _split_parameters(signature[start:end])]
+            depth -= 1
+    return None
+
+
+def assign_ids(path: str, symbols: List[Dict[str, Any]], package: Optional[str] = None) -> None:
+    """
+    Sets ``id`` and ``qualified_name`` on each of *symbols*, the symbols of the file at *path*.
+
+    Symbols that would share an ID, such as overloads of a Java method, each
+    get a hash of their :func:`parameter_types`, as in
+    ``Cart.java.Cart.add:method#1a2b3c4d``, so adding, moving or removing
+    other overloads leaves an overload's ID alone. Overloads with the same
+    parameter types are numbered ``#2``, ``#3`` and so on in source order,
+    and one without a parameter list keeps the plain ID. *package* is the
+    Go package's import path for ``qualified_name``, see :func:`full_name`.
+    """
+    ordered = sorted(symbols, key=lambda s: (s.get("start_byte", 0), s.get("start_line", 0)))
+    for _ in iter_ids(path, ordered, overloaded_ids(path, ordered), package):
+        pass
+
+
+def overloaded_ids(path: str, symbols: Iterable[Dict[str, Any]]) -> Set[str]:
+    """Returns the :func:`symbol_id` values that more than one of *symbols*, the symbols of *path*, share."""
+    counts = Counter(symbol_id(path, symbol) for symbol in symbols)
+    return {identifier for identifier, count in counts.items() if count > 1}
+
+
+def iter_ids(
+    path: str,
+    symbols: Iterable[Dict[str, Any]],
+    overloaded: AbstractSet[str],
+    package: Optional[str] = None,
+) -> Iterator[Dict[str, Any]]:
+    """
+    Sets ``id`` and ``qualified_name`` on each of *symbols* as it passes through, for symbols produced one at a time.
+
+    *overloaded* holds the IDs the file's symbols share, from
+    :func:`overloaded_ids`; they are told apart as in :func:`assign_ids`,
+    which the IDs match when the symbols arrive in source order.
+    """
+    seen: Set[str] = set()
+    for symbol in symbols:
+        base = symbol_id(path, symbol)
+        types = parameter_types(symbol) if base in overloaded else None
+        identifier = base
+        if types is not None:
+            identifier = f"{base}#{hashlib.sha256(','.join(types).encode('utf-8')).hexdigest()[:8]}"
+        n = 2
+        while identifier in seen:
+            identifier = f"{base}#{n}"
+            n += 1
+        seen.add(identifier)
+        symbol["id"] = identifier
+        symbol["qualified_name"] = full_name(path, symbol, package)
+        yield symbol
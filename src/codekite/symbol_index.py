@@ -0,0 +1,238 @@
+"""Persistent, incrementally updated symbol index keyed by file content hashes."""
+
+from __future__ import annotations
+import dataclasses
+import hashlib
+import json
+import logging
+import os
+from pathlib import Path
+import threading
+from typing import TYPE_CHECKING, Any, Dict, List, Optional
+
+from . import languages
+from .encodings import decode_source
+from .fuzzy import ScoredSymbol, fuzzy_search
+from .repo_mapper import ExtractionCancelled
+from .symbol_ids import assign_ids, go_package
+from .tree_sitter_symbol_extractor import ExtractionOptions
+
+if TYPE_CHECKING:
+    from .repository import Repository
+
+logger = logging.getLogger(__name__)
+
+# Bump whenever the cache layout or the shape of extracted symbols changes;
+# caches written with another version are discarded instead of being misread.
+SCHEMA_VERSION = 3
+
+
+def _root_prefix(root: Optional[str]) -> str:
+    """Returns the path prefix shared by index entries under *root*; empty for the repository root."""
+    if not root:
+        return ""
+    normalized = Path(os.path.normpath(root)).as_posix()
+    return "" if normalized == "." else normalized + "/"
+
+
+class SymbolIndex:
+    """
+    Symbols for every supported file in a repository, cached on disk.
+
+    Each entry records the SHA-256 of the file content it was extracted from,
+    so :meth:`update` only re-parses files whose content changed, drops files
+    that were deleted and parses new ones. The cache is a single JSON file.
+    """
+
+    def __init__(self, cache_path: str, options: Optional[ExtractionOptions] = None) -> None:
+        """
+        Args:
+            cache_path: Location of the cache file. Parent directories are created on save.
+            options: Extraction options; a cache written with different options is discarded.
+        """
+        self.cache_path = Path(cache_path)
+        self.options = options or ExtractionOptions()
+        # rel_path -> {"sha256": ..., "symbols": [...]} or {"sha256": ..., "error": ...}
+        self._entries: Dict[str, Dict[str, Any]] = self._load()
+
+    def _options_key(self) -> Dict[str, Any]:
+        return dataclasses.asdict(self.options)
+
+    def _load(self) -> Dict[str, Dict[str, Any]]:
+        if not self.cache_path.exists():
+            return {}
+        try:
+            with open(self.cache_path, "r", encoding="utf-8") as fp:
+                data = json.load(fp)
+        except (OSError, ValueError) as e:
+            logger.warning(f"Discarding unreadable symbol index {self.cache_path}: {e}")
+            return {}
+        if not isinstance(data, dict) or data.get("schema_version") != SCHEMA_VERSION:
+            logger.info(f"Discarding symbol index {self.cache_path} written with another schema version")
+            return {}
+        if data.get("options") != self._options_key():
+            logger.info(f"Discarding symbol index {self.cache_path} built with other extraction options")
+            return {}
+        return data.get("files", {})
+
+    def save(self) -> None:
+        """Writes the index to its cache file, replacing the previous one atomically."""
+        self.cache_path.parent.mkdir(parents=True, exist_ok=True)
+        data = {"schema_version": SCHEMA_VERSION, "options": self._options_key(), "files": self._entries}
+        tmp_path = self.cache_path.with_name(self.cache_path.name + ".tmp")
+        with open(tmp_path, "w", encoding="utf-8") as fp:
+            json.dump(data, fp, sort_keys=True)
+        os.replace(tmp_path, self.cache_path)
+
+    def update(
+        self, repository: "Repository", root: Optional[str] = None, cancel: Optional[threading.Event] = None
+    ) -> Dict[str, List[str]]:
+        """
+        Brings the index in line with the repository and saves it.
+
+        Args:
+            repository: Repository to index.
+            root: Only refresh files under this directory, relative to the repository root.
+                Entries elsewhere are left untouched.
+            cancel: Stops the update between files when set, e.g. on shutdown.
+                The files re-parsed so far are saved and nothing is removed, so
+                the next update carries on from there.
+
+        Returns:
+            The repository-relative paths that were ``added``, ``changed`` and
+            ``removed`` since the previous update, and those served from the
+            index because their content hash was unchanged (``cached``).
+
+        Raises:
+            ExtractionCancelled: If *cancel* was set before every file was
+                checked; ``files`` counts the files checked so far.
+        """
+        changes: Dict[str, List[str]] = {"added": [], "changed": [], "removed": [], "cached": []}
+        seen = set()
+        mapper = repository.mapper
+        for file in mapper.source_files(root, cancel=cancel):
+            if cancel is not None and cancel.is_set():
+                self.save()
+                raise ExtractionCancelled({}, [], len(seen))
+            rel_path = file.relative_to(mapper.repo_path).as_posix()
+            seen.add(rel_path)
+            try:
+                content = file.read_bytes()
+            except OSError as e:
+                logger.warning(f"Could not read {rel_path} for the symbol index: {e}")
+                continue
+            digest = hashlib.sha256(content).hexdigest()
+            previous = self._entries.get(rel_path)
+            if previous is not None and previous.get("sha256") == digest:
+                changes["cached"].append(rel_path)
+                continue
+            self._entries[rel_path] = self._extract(
+                rel_path, file.suffix.lower(), content, digest, mapper.fallback_encoding, mapper.module_path()
+            )
+            changes["changed" if previous is not None else "added"].append(rel_path)
+
+        prefix = _root_prefix(root)
+        for rel_path in sorted(p for p in set(self._entries) - seen if p.startswith(prefix)):
+            del self._entries[rel_path]
+            changes["removed"].append(rel_path)
+
+        self.save()
+        return changes
+
+    def update_paths(self, repository: "Repository", paths: List[str]) -> Dict[str, List[str]]:
+        """
+        Refreshes only the given files and saves the index.
+
+        Paths that no longer exist, are ignored or are not supported source
+        files are removed from the index. Returns the same change lists as :meth:`update`.
+        """
+        changes: Dict[str, List[str]] = {"added": [], "changed": [], "removed": [], "cached": []}
+        mapper = repository.mapper
+        extensions = languages.supported_extensions()
+        for rel_path in sorted(set(paths)):
+            file = mapper.repo_path / rel_path
+            previous = self._entries.get(rel_path)
+            indexable = (
+                file.suffix.lower() in extensions and file.is_file() and not mapper.is_ignored(file, is_dir=False)
+            )
+            content = None
+            if indexable:
+                try:
+                    content = file.read_bytes()
+                except OSError as e:
+                    logger.warning(f"Could not read {rel_path} for the symbol index: {e}")
+            if content is None:
+                if previous is not None:
+                    del self._entries[rel_path]
+                    changes["removed"].append(rel_path)
+                continue
+            digest = hashlib.sha256(content).hexdigest()
+            if previous is not None and previous.get("sha256") == digest:
+                changes["cached"].append(rel_path)
+                continue
+            self._entries[rel_path] = self._extract(
+                rel_path, file.suffix.lower(), content, digest, mapper.fallback_encoding, mapper.module_path()
+            )
+            changes["changed" if previous is not None else "added"].append(rel_path)
+
+        self.save()
+        return changes
+
+    def entry_symbols(self, rel_path: str) -> List[Dict[str, Any]]:
+        """Returns the indexed symbols of one file; empty if it is not indexed or failed to parse."""
+        return self._entries.get(rel_path, {}).get("symbols", [])
+
+    def _extract(
+        self,
+        rel_path: str,
+        ext: str,
+        content: bytes,
+        digest: str,
+        fallback_encoding: Optional[str] = None,
+        module_path: Optional[str] = None,
+    ) -> Dict[str, Any]:
+        try:
+            code = decode_source(content, fallback_encoding).text
+            symbols = languages.extract_symbols(ext, rel_path, code, self.options, raise_errors=True)
+        except Exception as e:
+            # Recorded with the hash so an unchanged broken file is not re-parsed on every update
+            return {"sha256": digest, "error": f"{type(e).__name__}: {e}"}
+        for s in symbols:
+            s["file"] = rel_path
+        assign_ids(rel_path, symbols, go_package(rel_path, code, module_path))
+        return {"sha256": digest, "symbols": symbols}
+
+    @property
+    def symbols(self) -> Dict[str, List[Dict[str, Any]]]:
+        """Symbols keyed by repository-relative path, for files that parsed successfully."""
+        return {path: entry["symbols"] for path, entry in sorted(self._entries.items()) if "symbols" in entry}
+
+    @property
+    def errors(self) -> List[Dict[str, str]]:
+        """One ``{"file", "error"}`` dict per file that failed to parse, like :meth:`Repository.parse_directory`."""
+        return [{"file": path, "error": entry["error"]} for path, entry in sorted(self._entries.items()) if "error" in entry]
+
+    def symbols_under(self, root: Optional[str] = None) -> Dict[str, List[Dict[str, Any]]]:
+        """Like :attr:`symbols`, restricted to files under *root*."""
+        prefix = _root_prefix(root)
+        return {path: symbols for path, symbols in self.symbols.items() if path.startswith(prefix)}
+
+    def fuzzy_search(
+        self, query: str, limit: int = 20, include_vendored: bool = False, include_generated: bool = False
+    ) -> List[ScoredSymbol]:
+        """
+        Ranks indexed symbols by fuzzy name match, for "go to symbol" lookups.
+
+        See :func:`codekite.fuzzy.fuzzy_search` for the scoring; callers can
+        threshold on :attr:`ScoredSymbol.score`. Symbols of vendored and
+        generated files are left out unless *include_vendored* or
+        *include_generated* is set, so a protobuf message does not outrank
+        the code that uses it.
+        """
+        candidates = (
+            s
+            for symbols in self.symbols.values()
+            for s in symbols
+            if (include_vendored or not s.get("vendored")) and (include_generated or not s.get("generated"))
+        )
+        return fuzzy_search(candidates, query, limit)
@@ -0,0 +1,131 @@
+"""SQLite-backed symbol storage that can be queried without loading the whole index."""
+
+from __future__ import annotations
+import json
+import logging
+import os
+import sqlite3
+from pathlib import Path
+from typing import TYPE_CHECKING, Any, Dict, List, Optional
+
+if TYPE_CHECKING:
+    from .repository import Repository
+
+logger = logging.getLogger(__name__)
+
+_SCHEMA = """
+CREATE TABLE IF NOT EXISTS files (
+    path TEXT PRIMARY KEY
+);
+CREATE TABLE IF NOT EXISTS symbols (
+    id INTEGER PRIMARY KEY,
+    file TEXT NOT NULL REFERENCES files(path) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    start_line INTEGER NOT NULL,
+    data TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS symbols_by_name ON symbols(name);
+CREATE INDEX IF NOT EXISTS symbols_by_kind ON symbols(kind);
+CREATE INDEX IF NOT EXISTS symbols_by_file ON symbols(file, start_line);
+"""
+
+
+class SymbolStore:
+    """
+    Symbols persisted in a SQLite database, indexed by file, name and kind.
+
+    Each :meth:`write` is a single transaction, so a crash part-way through
+    indexing leaves the store as it was before that batch. Lookups hit an
+    index and return full symbol dicts, ordered by file and line.
+    """
+
+    def __init__(self, db_path: str) -> None:
+        """
+        Args:
+            db_path: Database file, created if missing. ``":memory:"`` keeps it in memory.
+        """
+        self.db_path = db_path
+        if db_path != ":memory:":
+            Path(db_path).parent.mkdir(parents=True, exist_ok=True)
+        self._conn = sqlite3.connect(db_path)
+        self._conn.execute("PRAGMA foreign_keys = ON")
+        self._conn.executescript(_SCHEMA)
+
+    def close(self) -> None:
+        self._conn.close()
+
+    def __enter__(self) -> "SymbolStore":
+        return self
+
+    def __exit__(self, *exc_info: Any) -> None:
+        self.close()
+
+    def write(self, symbols_by_file: Dict[str, List[Dict[str, Any]]]) -> None:
+        """
+        Replaces the stored symbols of every file in *symbols_by_file* in one transaction.
+
+        Files not mentioned keep their symbols; a file mapped to an empty list
+        is kept with no symbols.
+        """
+        with self._conn:
+            for path, symbols in symbols_by_file.items():
+                self._conn.execute("DELETE FROM files WHERE path = ?", (path,))
+                self._conn.execute("INSERT INTO files (path) VALUES (?)", (path,))
+                self._conn.executemany(
+                    "INSERT INTO symbols (file, name, kind, start_line, data) VALUES (?, ?, ?, ?, ?)",
+                    [
+                        (path, s["name"], s["type"], s.get("start_line", 0), json.dumps(dict(s, file=path), sort_keys=True))
+                        for s in symbols
+                    ],
+                )
+
+    def remove_files(self, paths: List[str]) -> None:
+        """Drops the given files and their symbols in one transaction."""
+        with self._conn:
+            self._conn.executemany("DELETE FROM files WHERE path = ?", [(p,) for p in paths])
+
+    def _query(self, where: str, value: str) -> List[Dict[str, Any]]:
+        rows = self._conn.execute(f"SELECT data FROM symbols WHERE {where} = ? ORDER BY file, start_line, id", (value,))
+        return [json.loads(data) for (data,) in rows]
+
+    def symbols_by_name(self, name: str) -> List[Dict[str, Any]]:
+        """Returns every symbol with exactly this name."""
+        return self._query("name", name)
+
+    def symbols_in_file(self, path: str) -> List[Dict[str, Any]]:
+        """Returns the symbols of one repository-relative file, in line order."""
+        return self._query("file", path)
+
+    def symbols_by_kind(self, kind: str) -> List[Dict[str, Any]]:
+        """Returns every symbol whose ``type`` is *kind*, e.g. ``"function"``."""
+        return self._query("kind", kind)
+
+    def files(self) -> List[str]:
+        """Returns the stored file paths, sorted."""
+        return [path for (path,) in self._conn.execute("SELECT path FROM files ORDER BY path")]
+
+    def compact(self, repository: "Repository") -> List[str]:
+        """
+        Removes files that no longer exist in *repository* and reclaims their space.
+
+        Returns:
+            The removed repository-relative paths.
+        """
+        missing = [path for path in self.files() if not os.path.isfile(os.path.join(repository.repo_path, path))]
+        if missing:
+            self.remove_files(missing)
+            logger.info(f"Removed {len(missing)} deleted files from {self.db_path}")
+        self._conn.execute("VACUUM")
+        return missing
+
+    def index(self, repository: "Repository", root: Optional[str] = None) -> List[Dict[str, Any]]:
+        """
+        Extracts every supported file under *root* and writes the result as one batch.
+
+        Returns:
+            The per-file diagnostics reported by :meth:`Repository.parse_directory`.
+        """
+        symbols_by_file, errors = repository.parse_directory(root)
+        self.write(symbols_by_file)
+        return errors
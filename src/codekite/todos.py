@@ -0,0 +1,150 @@
+"""Collecting TODO, FIXME and XXX comments into a punch-list, each tied to the symbol it appears in."""
+
+from __future__ import annotations
+import logging
+import re
+from dataclasses import asdict, dataclass
+from typing import TYPE_CHECKING, Any, Dict, Iterator, List, Optional, Sequence, Tuple
+
+from . import languages
+from .line_counts import comment_ranges
+from .tree_sitter_symbol_extractor import TreeSitterSymbolExtractor
+
+if TYPE_CHECKING:
+    from .repository import Repository
+
+logger = logging.getLogger(__name__)
+
+DEFAULT_MARKERS = ("TODO", "FIXME", "XXX")
+
+# Comment punctuation and whitespace before the text of one comment line
+_COMMENT_PREFIX = re.compile(r"^\s*(?:/\*+!?|\*+|//+!?|#+|--|\"\"\"|''')?\s*")
+_COMMENT_SUFFIX = re.compile(r"\s*(?:\*+/|\"\"\"|''')?\s*$")
+
+
+@dataclass
+class Comment:
+    """
+    One marked comment line.
+
+    Attributes:
+        marker: The marker that starts the line, e.g. ``"TODO"``.
+        text: What follows the marker, without the colon.
+        line: 0-based line, like a symbol's ``start_line``.
+        file: Repository-relative path; empty for source that was not read from a file.
+        author: The name in ``TODO(alice):``, or ``""``.
+        symbol: ``node_path`` (or name) of the innermost symbol the comment is
+            in or documents, or ``""`` at file level.
+    """
+
+    marker: str
+    text: str
+    line: int
+    file: str = ""
+    author: str = ""
+    symbol: str = ""
+
+    def to_dict(self) -> Dict[str, Any]:
+        return asdict(self)
+
+
+def _marker_pattern(markers: Sequence[str]) -> "re.Pattern[str]":
+    alternatives = "|".join(re.escape(m) for m in sorted(markers, key=len, reverse=True))
+    # The marker must be a whole word: TODOS and XXXL are not markers
+    return re.compile(rf"^({alternatives})(?!\w)(?:\(([^)]*)\))?:?\s*(.*)$")
+
+
+def _docstring_ranges(root: Any) -> Iterator[Tuple[int, int]]:
+    """Byte ranges of Python docstrings: a string alone as the first statement of a module, class or function."""
+    stack = [root]
+    while stack:
+        node = stack.pop()
+        if node.type in ("module", "block"):
+            first = next((c for c in node.named_children if c.type != "comment"), None)
+            if first is not None and first.type == "expression_statement" and first.named_child_count == 1:
+                if first.named_children[0].type == "string":
+                    yield first.start_byte, first.end_byte
+        stack.extend(node.named_children)
+
+
+def _enclosing_symbol(line: int, symbols: List[Dict[str, Any]]) -> str:
+    best: Optional[Dict[str, Any]] = None
+    for s in symbols:
+        # A Go doc comment sits above start_line but still belongs to the symbol
+        if s.get("doc_start_line", s["start_line"]) <= line <= s["end_line"]:
+            if best is None or s["end_line"] - s["start_line"] <= best["end_line"] - best["start_line"]:
+                best = s
+    return (best.get("node_path") or best.get("name") or "") if best is not None else ""
+
+
+def find_todos(
+    source: str,
+    ext: str,
+    path: str = "",
+    markers: Sequence[str] = DEFAULT_MARKERS,
+    symbols: Optional[List[Dict[str, Any]]] = None,
+) -> List[Comment]:
+    """
+    Returns the marked comments in *source*, a file with extension *ext*, in line order.
+
+    Line and block comments, Go and Rust doc comments and Python docstrings
+    are all scanned, a line at a time. A line counts when one of *markers*
+    starts it, after the comment punctuation: ``// TODO(alice): tidy up``
+    yields marker ``TODO``, author ``alice`` and text ``tidy up``, while
+    ``// see the TODO below`` yields nothing. Markers are case-sensitive.
+    Comments are found with the tree-sitter grammar, so a marker inside a
+    string literal is skipped, and files without a built-in grammar yield
+    nothing. Each comment is tied to the innermost of *symbols* (the file's
+    extracted symbols) whose lines it falls within.
+    """
+    ext = ext.lower()
+    parser = TreeSitterSymbolExtractor.get_parser(ext) if languages.is_tree_sitter(ext) else None
+    if parser is None or not markers:
+        return []
+    source_bytes = source.encode("utf-8")
+    root = parser.parse(source_bytes).root_node
+    ranges = list(comment_ranges(root))
+    if ext == ".py":
+        ranges.extend(_docstring_ranges(root))
+
+    pattern = _marker_pattern(markers)
+    comments: List[Comment] = []
+    for start, end in sorted(ranges):
+        first_line = source_bytes.count(b"\n", 0, start)
+        text = source_bytes[start:end].decode("utf-8", errors="replace")
+        for offset, raw in enumerate(text.split("\n")):
+            line = _COMMENT_SUFFIX.sub("", _COMMENT_PREFIX.sub("", raw, count=1))
+            match = pattern.match(line)
+            if not match:
+                continue
+            marker, author, rest = match.groups()
+            comments.append(
+                Comment(
+                    marker=marker,
+                    text=rest.strip(),
+                    line=first_line + offset,
+                    file=path,
+                    author=(author or "").strip(),
+                    symbol=_enclosing_symbol(first_line + offset, symbols or []),
+                )
+            )
+    return comments
+
+
+def file_todos(
+    repo: "Repository", root: Optional[str] = None, markers: Sequence[str] = DEFAULT_MARKERS
+) -> List[Comment]:
+    """Marked comments of every source file under *root*, in walk order and then line order."""
+    repo_root = repo.mapper.repo_path
+    comments: List[Comment] = []
+    for file in repo.mapper.source_files(root):
+        path = file.relative_to(repo_root).as_posix()
+        ext = file.suffix.lower()
+        try:
+            source = repo.get_file_content(path)
+        except (IOError, UnicodeDecodeError) as e:
+            logger.warning(f"Skipping TODOs in {path}: {e}")
+            continue
+        symbols = languages.extract_symbols(ext, path, source)
+        comments.extend(find_todos(source, ext, path, markers, symbols))
+    return comments
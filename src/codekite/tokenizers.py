@@ -0,0 +1,49 @@
+"""Token counting for chunking text to an LLM context budget."""
+
+from __future__ import annotations
+import math
+from typing import Any, Optional, Protocol, runtime_checkable
+
+
+@runtime_checkable
+class Tokenizer(Protocol):
+    """Anything that can count the tokens in a piece of text."""
+
+    def count(self, text: str) -> int:
+        """Returns the number of tokens *text* encodes to."""
+        ...
+
+
+class HeuristicTokenizer:
+    """Estimates tokens from length, about four characters each for English text and code."""
+
+    def __init__(self, chars_per_token: float = 4.0) -> None:
+        if chars_per_token <= 0:
+            raise ValueError("chars_per_token must be positive")
+        self.chars_per_token = chars_per_token
+
+    def count(self, text: str) -> int:
+        return math.ceil(len(text) / self.chars_per_token)
+
+
+class TiktokenTokenizer:
+    """
+    Counts tokens exactly with a tiktoken encoding.
+
+    Args:
+        model: Model name to pick the encoding for, e.g. ``"gpt-4o"``.
+        encoding: Encoding name, e.g. ``"cl100k_base"``; used when *model* is not given
+            or tiktoken does not know it.
+    """
+
+    def __init__(self, model: Optional[str] = None, encoding: str = "cl100k_base") -> None:
+        import tiktoken
+
+        self._encoding: Any
+        try:
+            self._encoding = tiktoken.encoding_for_model(model) if model else tiktoken.get_encoding(encoding)
+        except KeyError:
+            self._encoding = tiktoken.get_encoding(encoding)
+
+    def count(self, text: str) -> int:
+        return len(self._encoding.encode(text, disallowed_special=()))
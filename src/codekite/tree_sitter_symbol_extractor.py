@@ -1,10 +1,17 @@
 import os
+import re
+import ast
+import inspect
 import logging
+import threading
 import traceback
+from dataclasses import dataclass
 from pathlib import Path
-from typing import List, Dict, Optional, Any, ClassVar, cast
+from typing import Any, Callable, ClassVar, Dict, Iterator, List, Optional, Set, Tuple, cast
 from tree_sitter_language_pack import get_parser, get_language
 
+from .symbol_filter import is_exported
+
 # Set up module-level logger
 logger = logging.getLogger(__name__)
 
@@ -17,16 +24,1002 @@ LANGUAGES: dict[str, str] = {
     ".hcl": "hcl",
     ".tf": "hcl",
     ".ts": "typescript",
-    ".tsx": "typescript",
+    ".tsx": "tsx",
     ".c": "c",
+    ".h": "c",
+    ".cpp": "cpp",
+    ".hpp": "cpp",
+    ".cc": "cpp",
+    ".cxx": "cpp",
+    ".hh": "cpp",
+    ".hxx": "cpp",
     ".rb": "ruby",
     ".java": "java",
+    ".sh": "bash",
+    ".bash": "bash",
 }
 
+@dataclass
+class ExtractionOptions:
+    """Opt-in extraction behaviour; the defaults match what the tags.scm queries report."""
+
+    # Report Python functions defined inside other functions or methods, and Go,
+    # JavaScript and TypeScript function literals such as closures and callbacks
+    include_nested: bool = False
+    # Report Go identifiers starting with a lower-case letter and Python names starting
+    # with "_"; methods follow their type (see symbol_filter.is_exported)
+    include_unexported: bool = True
+    # Type-check Go packages with the go toolchain so signatures carry resolved,
+    # import-path-qualified types; needs a buildable module (see go_types.GoTypeResolver)
+    resolve_types: bool = False
+
+
+# Languages whose grammar is a superset of another's reuse that language's tags.scm.
+# TSX only adds JSX nodes to TypeScript, so the TypeScript query applies unchanged.
+QUERY_DIRS: dict[str, str] = {"tsx": "typescript"}
+
+# Grammars that share the JavaScript export/JSDoc handling
+_JS_LANGUAGES = ("javascript", "typescript", "tsx")
+
 # Always use absolute path for queries root (one level higher)
 QUERIES_ROOT: str = os.path.abspath(os.path.join(os.path.dirname(__file__), "../../queries"))
 
 
+def _node_text(node: Any) -> str:
+    """Returns the UTF-8 decoded text of a tree-sitter node."""
+    text = getattr(node, "text", None)
+    if isinstance(text, bytes):
+        return text.decode("utf-8", errors="ignore")
+    return str(text) if text is not None else ""
+
+
+# Go toolchain directives such as //go:generate or //nolint:errcheck are not documentation
+_DIRECTIVE_COMMENT = re.compile(r"^//[a-z0-9]+:[a-z0-9]")
+
+
+def _leading_comment_nodes(node: Any) -> List[Any]:
+    """
+    Returns the comment nodes immediately preceding *node*, in source order.
+    The block ends at the first blank line, non-comment sibling, or trailing
+    comment that shares a line with preceding code.
+    """
+    comments: List[Any] = []
+    expected_row = node.start_point[0]
+    prev = node.prev_sibling
+    while prev is not None and prev.type == "comment":
+        if prev.end_point[0] < expected_row - 1:
+            break  # separated by a blank line
+        before = prev.prev_sibling
+        # Anonymous tokens (e.g. Go's "\n" terminator) end on the comment's row without being code
+        if (
+            before is not None
+            and before.is_named
+            and before.type != "comment"
+            and before.end_point[0] == prev.start_point[0]
+        ):
+            break  # trailing comment of the previous statement
+        comments.insert(0, prev)
+        expected_row = prev.start_point[0]
+        prev = before
+    return comments
+
+
+def _clean_comment_text(comment_nodes: List[Any]) -> str:
+    """
+    Strips comment markers (//, ///, //!, /* */ and leading asterisks) from a
+    comment block while preserving blank lines between paragraphs and the
+    indentation of the remaining text.
+    """
+    lines: List[str] = []
+    for comment in comment_nodes:
+        text = _node_text(comment)
+        if text.startswith("/*"):
+            body = text[2:-2] if text.endswith("*/") else text[2:]
+            body_lines = body.lstrip("*!").split("\n")
+            if all(line.strip().startswith("*") for line in body_lines[1:] if line.strip()):
+                # Javadoc style: strip the leading " * " gutter
+                body_lines = [body_lines[0]] + [re.sub(r"^\s*\* ?", "", line) for line in body_lines[1:]]
+            if all(not line.strip() or line.startswith(" ") for line in body_lines):
+                body_lines = [line[1:] for line in body_lines]  # "/* text */" -> "text"
+            lines.extend(body_lines)
+        elif _DIRECTIVE_COMMENT.match(text):
+            continue
+        else:
+            line = re.sub(r"^//[/!]?", "", text)
+            lines.append(line[1:] if line.startswith(" ") else line)
+    # Trim leading/trailing blank lines and trailing whitespace
+    lines = [line.rstrip() for line in lines]
+    while lines and not lines[0].strip():
+        lines.pop(0)
+    while lines and not lines[-1].strip():
+        lines.pop()
+    return "\n".join(lines)
+
+
+def _go_deprecation(docstring: str) -> Optional[str]:
+    """
+    Returns the note of a ``Deprecated:`` paragraph in a Go doc comment, or None if there is none.
+
+    Per Go convention the paragraph may appear anywhere in the comment; its
+    lines are joined into one note, e.g. ``"Use NewClient instead."``.
+    """
+    for paragraph in re.split(r"\n\s*\n", docstring):
+        if paragraph.startswith("Deprecated:"):
+            return " ".join(line.strip() for line in paragraph.splitlines())[len("Deprecated:") :].strip()
+    return None
+
+
+def _set_go_doc(symbol: Dict[str, Any], docstring: str, comments: List[Any]) -> None:
+    """Records a Go doc comment on *symbol*: its text, first line and any deprecation notice."""
+    symbol["docstring"] = docstring
+    # start_line stays on the declaration itself; the comment gets its own line
+    symbol["doc_start_line"] = comments[0].start_point[0]
+    note = _go_deprecation(docstring)
+    if note is not None:
+        symbol["deprecated"] = True
+        symbol["deprecated_note"] = note
+
+
+def _go_doc_comment_nodes(definition_node: Any) -> List[Any]:
+    """Returns the comment nodes forming the doc comment of a Go declaration, spec, or method."""
+    comments = _leading_comment_nodes(definition_node)
+    parent = definition_node.parent
+    if (
+        not comments
+        and parent is not None
+        and parent.type in ("type_declaration", "const_declaration", "var_declaration")
+        and parent.start_point[0] == definition_node.start_point[0]
+    ):
+        # Ungrouped spec (`type User struct`): the comment precedes the declaration keyword
+        comments = _leading_comment_nodes(parent)
+    return comments
+
+
+def _char_column(source_bytes: bytes, byte_offset: int, byte_column: int) -> int:
+    """Converts a tree-sitter byte column into a character column on the same line."""
+    line_start = byte_offset - byte_column
+    return len(source_bytes[line_start:byte_offset].decode("utf-8", errors="ignore"))
+
+
+def _line_count(node: Any) -> int:
+    """Lines *node* spans; a trailing newline, as #define lines end with, does not start another."""
+    end = node.end_point[0]
+    if node.end_point[1] == 0 and end > node.start_point[0]:
+        end -= 1
+    return end - node.start_point[0] + 1
+
+
+def _code_line_count(node: Any) -> int:
+    """Lines of *node* holding anything besides comments and whitespace, as ``LineCounts.code`` counts them."""
+    text = bytearray(node.text)
+    stack = list(node.children)
+    while stack:
+        child = stack.pop()
+        if child.type.endswith("comment"):
+            # Blank out the comment but keep its newlines, so lines stay aligned
+            start, end = child.start_byte - node.start_byte, child.end_byte - node.start_byte
+            text[start:end] = bytes(b if b == 0x0A else 0x20 for b in text[start:end])
+            continue
+        stack.extend(child.children)
+    return sum(1 for line in bytes(text).split(b"\n") if line.strip())
+
+
+def _span_symbol(name: str, symbol_type: str, node: Any, source_bytes: bytes) -> Dict[str, Any]:
+    """Builds the common symbol fields (name, type, lines, line count, columns, byte offsets, code) for *node*."""
+    return {
+        "name": name,
+        "type": symbol_type,
+        "start_line": node.start_point[0],
+        "end_line": node.end_point[0],
+        "line_count": _line_count(node),
+        # Character (not byte) columns, 0-based, so multi-byte identifiers don't shift positions
+        "start_column": _char_column(source_bytes, node.start_byte, node.start_point[1]),
+        "end_column": _char_column(source_bytes, node.end_byte, node.end_point[1]),
+        # Byte offsets into the UTF-8 encoded source (0-based, end exclusive)
+        "start_byte": node.start_byte,
+        "end_byte": node.end_byte,
+        "code": source_bytes[node.start_byte : node.end_byte].decode("utf-8", errors="ignore"),
+    }
+
+
+def _syntax_errors(root: Any, source_bytes: bytes) -> List[Dict[str, Any]]:
+    """
+    Returns one entry per ``ERROR`` or missing node in the tree, in source order.
+
+    Nothing inside an ``ERROR`` node is reported separately, and subtrees
+    without errors are not walked at all.
+    """
+    errors: List[Dict[str, Any]] = []
+    stack = [root]
+    while stack:
+        node = stack.pop()
+        if node.is_missing:
+            message = f"missing {node.type}"
+        elif node.type == "ERROR":
+            text = _node_text(node).strip().split("\n", 1)[0]
+            message = f"unexpected {text[:40]!r}" if text else "syntax error"
+        else:
+            if node.has_error:
+                stack.extend(reversed(node.children))
+            continue
+        start_row, start_column = node.start_point
+        end_row, end_column = node.end_point
+        errors.append({
+            "message": message,
+            "start_line": start_row,
+            "start_column": _char_column(source_bytes, node.start_byte, start_column),
+            "end_line": end_row,
+            "end_column": _char_column(source_bytes, node.end_byte, end_column),
+        })
+    return errors
+
+
+def _go_value_specs(declaration: Any) -> List[Any]:
+    """Returns the const_spec/var_spec nodes of a declaration, grouped or not."""
+    specs = []
+    for child in declaration.named_children:
+        if child.type in ("const_spec", "var_spec"):
+            specs.append(child)
+        elif child.type == "var_spec_list":
+            # Newer grammars wrap grouped `var ( ... )` specs in a list node
+            specs.extend(c for c in child.named_children if c.type == "var_spec")
+    return specs
+
+
+# Default types of untyped constants, by the kind of literal they are written as
+_GO_LITERAL_TYPES = {
+    "int_literal": "int",
+    "rune_literal": "rune",
+    "float_literal": "float64",
+    "imaginary_literal": "complex128",
+    "interpreted_string_literal": "string",
+    "raw_string_literal": "string",
+    "true": "bool",
+    "false": "bool",
+}
+# Mixing untyped numeric constants yields the later kind in this list, as in Go
+_GO_NUMERIC_ORDER = ["int", "rune", "float64", "complex128"]
+
+
+def _go_spec_values(spec: Any) -> List[Any]:
+    """Returns the value expressions of a const_spec/var_spec, one per entry in its expression list."""
+    value = spec.child_by_field_name("value")
+    if value is None:
+        return []
+    if value.type != "expression_list":
+        return [value]
+    return [c for c in value.named_children if c.type != "comment"]
+
+
+def _go_literal_type(expr: Any) -> Optional[str]:
+    """Infers the default type of an untyped constant expression, or None if it is not built from literals."""
+    if expr.type in _GO_LITERAL_TYPES:
+        return _GO_LITERAL_TYPES[expr.type]
+    if expr.type == "identifier" and _node_text(expr) == "iota":
+        return "int"
+    if expr.type in ("parenthesized_expression", "unary_expression") and expr.named_child_count == 1:
+        return _go_literal_type(expr.named_children[0])
+    if expr.type == "binary_expression":
+        operator = expr.child_by_field_name("operator")
+        if operator is not None and _node_text(operator) in ("==", "!=", "<", "<=", ">", ">=", "&&", "||"):
+            return "bool"
+        left, right = expr.child_by_field_name("left"), expr.child_by_field_name("right")
+        if left is None or right is None:
+            return None
+        left_type, right_type = _go_literal_type(left), _go_literal_type(right)
+        if left_type == right_type:
+            return left_type
+        if left_type in _GO_NUMERIC_ORDER and right_type in _GO_NUMERIC_ORDER:
+            return max(left_type, right_type, key=_GO_NUMERIC_ORDER.index)
+    return None
+
+
+# The largest shift count, and the most bits of a constant, the Go compiler accepts
+_GO_MAX_BITS = 512
+
+
+def _go_int_literal(text: str) -> int:
+    digits = text.replace("_", "")
+    if len(digits) > 1 and digits[0] == "0" and digits[1].isdigit():
+        # Octal without the 0o prefix, as in 0755
+        return int(digits, 8)
+    return int(digits, 0)
+
+
+def _go_divide(a: int, b: int) -> int:
+    # Go integer division truncates toward zero; Python's // floors
+    quotient = abs(a) // abs(b)
+    return -quotient if (a < 0) != (b < 0) else quotient
+
+
+def _go_shift(value: int, count: int, left: bool) -> int:
+    # Without a bound, `1 << 1000000000` would build a 125 MB integer
+    if count < 0 or count > _GO_MAX_BITS:
+        raise ValueError(f"shift count {count} out of range")
+    return value << count if left else value >> count
+
+
+_GO_BINARY_OPERATORS: Dict[str, Callable[[int, int], int]] = {
+    "+": lambda a, b: a + b,
+    "-": lambda a, b: a - b,
+    "*": lambda a, b: a * b,
+    "/": _go_divide,
+    "%": lambda a, b: a - b * _go_divide(a, b),
+    "<<": lambda a, b: _go_shift(a, b, left=True),
+    ">>": lambda a, b: _go_shift(a, b, left=False),
+    "&": lambda a, b: a & b,
+    "|": lambda a, b: a | b,
+    "^": lambda a, b: a ^ b,
+    "&^": lambda a, b: a & ~b,
+}
+
+
+def _go_eval_iota(expression: Any, iota: int) -> Optional[int]:
+    """
+    Evaluates the integer constant expression node *expression*, such as ``1 << (10 * iota)``, for one iota value.
+
+    The syntax tree already groups operators by Go's precedence, so
+    ``1<<iota - 1`` is ``(1<<iota) - 1``, and division truncates toward zero
+    exactly. Only integer literals, ``iota`` and arithmetic, shift and bitwise
+    operators are understood; anything else (names, conversions, calls)
+    returns None, as does a shift by a negative count or by more than 512
+    bits, or a value wider than ``1 << 512``.
+    """
+
+    def operator_of(node: Any) -> str:
+        operator = node.child_by_field_name("operator")
+        return _node_text(operator) if operator is not None else ""
+
+    def evaluate(node: Any) -> int:
+        if node is None:
+            # A field missing from a node the parser recovered from an error
+            raise ValueError("incomplete constant expression")
+        if node.type == "int_literal":
+            value = _go_int_literal(_node_text(node))
+        elif node.type in ("iota", "identifier") and _node_text(node) == "iota":
+            value = iota
+        elif node.type == "parenthesized_expression" and node.named_child_count == 1:
+            value = evaluate(node.named_children[0])
+        elif node.type == "unary_expression":
+            operator = operator_of(node)
+            operand = evaluate(node.child_by_field_name("operand"))
+            if operator not in ("-", "+", "^"):
+                raise ValueError(f"unsupported unary operator {operator}")
+            value = -operand if operator == "-" else ~operand if operator == "^" else operand
+        elif node.type == "binary_expression":
+            operator = operator_of(node)
+            if operator not in _GO_BINARY_OPERATORS:
+                raise ValueError(f"unsupported binary operator {operator}")
+            left, right = evaluate(node.child_by_field_name("left")), evaluate(node.child_by_field_name("right"))
+            value = _GO_BINARY_OPERATORS[operator](left, right)
+        else:
+            raise ValueError(f"unsupported constant expression: {node.type}")
+        # 1 << 512 is the widest, a 513-bit value
+        if value.bit_length() > _GO_MAX_BITS + 1:
+            raise ValueError("constant overflow")
+        return value
+
+    try:
+        return evaluate(expression)
+    except (ValueError, ZeroDivisionError, OverflowError):
+        return None
+
+
+def _normalize_signature(text: str) -> str:
+    """Collapses whitespace and multi-line parameter lists into a single line."""
+    text = re.sub(r"\s+", " ", text).strip()
+    text = re.sub(r"\(\s+", "(", text)
+    return re.sub(r",?\s*\)", ")", text)
+
+
+def _declaration_header(node: Any) -> str:
+    """Returns the source of *node* up to (not including) its body, or the whole node if it has none."""
+    body = node.child_by_field_name("body")
+    raw = node.text if isinstance(node.text, bytes) else _node_text(node).encode("utf-8")
+    if body is not None:
+        raw = raw[: body.start_byte - node.start_byte]
+    return raw.decode("utf-8", errors="ignore")
+
+
+def _go_receiver_type(method_node: Any) -> Optional[str]:
+    """
+    Returns the receiver type of a Go method declaration exactly as written,
+    e.g. "User", "*User" or "*Store[T]".
+    """
+    receiver = method_node.child_by_field_name("receiver")
+    if receiver is None:
+        return None
+    for param in receiver.named_children:
+        if param.type == "parameter_declaration":
+            type_node = param.child_by_field_name("type")
+            if type_node is not None:
+                return _node_text(type_node)
+    # Fallback: last token of the receiver list, e.g. "(u User)" -> "User"
+    tokens = _node_text(receiver).strip("() \t\n").split()
+    return tokens[-1] if tokens else None
+
+
+def _go_base_type_name(type_text: str) -> str:
+    """Strips pointers, parentheses and type arguments: "*Store[T]" -> "Store"."""
+    base = type_text.strip().lstrip("(*").rstrip(")").strip()
+    return re.split(r"[\[\s]", base, maxsplit=1)[0]
+
+
+def _go_type_params(definition_node: Any) -> List[Dict[str, str]]:
+    """
+    Returns the type parameters of a generic Go function or type as
+    ``[{"name": "K", "constraint": "comparable"}, ...]``.
+
+    ``[K, V any]`` yields one entry per name, each with the shared constraint.
+    """
+    param_list = definition_node.child_by_field_name("type_parameters")
+    if param_list is None:
+        return []
+    params: List[Dict[str, str]] = []
+    for decl in param_list.named_children:
+        # Older grammars reuse parameter_declaration inside type_parameter_list
+        if decl.type not in ("type_parameter_declaration", "parameter_declaration"):
+            continue
+        constraint_node = decl.child_by_field_name("type")
+        constraint = _normalize_signature(_node_text(constraint_node)) if constraint_node is not None else ""
+        for name_node in decl.children_by_field_name("name"):
+            params.append({"name": _node_text(name_node), "constraint": constraint})
+    return params
+
+
+def _go_param_list(param_list: Any) -> List[Dict[str, str]]:
+    """
+    Returns the parameters of a Go ``parameter_list`` as ``[{"name": "a", "type": "int"}, ...]``.
+
+    ``(a, b int)`` yields one entry per name, each with the shared type;
+    unnamed parameters such as ``(int, error)`` have an empty name, and a
+    variadic parameter's type keeps its ``...``.
+    """
+    params: List[Dict[str, str]] = []
+    for decl in param_list.named_children:
+        if decl.type not in ("parameter_declaration", "variadic_parameter_declaration"):
+            continue
+        type_node = decl.child_by_field_name("type")
+        type_text = _normalize_signature(_node_text(type_node)) if type_node is not None else ""
+        if decl.type == "variadic_parameter_declaration":
+            type_text = f"...{type_text}"
+        names = decl.children_by_field_name("name")
+        params.extend({"name": _node_text(n), "type": type_text} for n in names)
+        if not names:
+            params.append({"name": "", "type": type_text})
+    return params
+
+
+def _go_signature_params(definition_node: Any) -> Tuple[List[Dict[str, str]], List[Dict[str, str]]]:
+    """Returns the ``(params, results)`` of a Go function or method; a bare result type is one unnamed result."""
+    param_list = definition_node.child_by_field_name("parameters")
+    params = _go_param_list(param_list) if param_list is not None else []
+    result = definition_node.child_by_field_name("result")
+    if result is None:
+        results: List[Dict[str, str]] = []
+    elif result.type == "parameter_list":
+        results = _go_param_list(result)
+    else:
+        results = [{"name": "", "type": _normalize_signature(_node_text(result))}]
+    return params, results
+
+
+# Branches counted by _go_complexity; default and else add no path of their own
+_GO_DECISION_NODES = frozenset({"if_statement", "for_statement", "expression_case", "type_case", "communication_case"})
+
+
+def _go_complexity(definition_node: Any) -> int:
+    """Cyclomatic complexity as gocyclo counts it: 1 plus each if, for, non-default case, && and ||."""
+    complexity = 1
+    body = definition_node.child_by_field_name("body")
+    stack = [body] if body is not None else []
+    while stack:
+        node = stack.pop()
+        if node.type in _GO_DECISION_NODES:
+            complexity += 1
+        elif node.type == "binary_expression":
+            operator = node.child_by_field_name("operator")
+            if operator is not None and _node_text(operator) in ("&&", "||"):
+                complexity += 1
+        # Function literals are part of the function, as in gocyclo
+        stack.extend(node.children)
+    return complexity
+
+
+def _go_struct_fields(struct_node: Any) -> List[Dict[str, Any]]:
+    """
+    Returns the fields of a Go struct_type in declaration order.
+
+    Each field has ``name``, ``type`` and ``embedded``; ``tag`` and its parsed
+    ``tags`` (see :func:`_go_parse_struct_tag`) are added when the field has a
+    struct tag. ``X, Y int`` yields two fields. Embedded fields are named after
+    their type without pointer or package: ``*sync.Mutex`` -> ``Mutex``.
+    """
+    field_list = next((c for c in struct_node.named_children if c.type == "field_declaration_list"), None)
+    if field_list is None:
+        return []
+    fields: List[Dict[str, Any]] = []
+    for decl in field_list.named_children:
+        if decl.type != "field_declaration":
+            continue
+        type_node = decl.child_by_field_name("type")
+        if type_node is None:
+            continue
+        type_text = _normalize_signature(_node_text(type_node))
+        tag = _go_struct_tag(decl.child_by_field_name("tag"))
+        names = [_node_text(n) for n in decl.children_by_field_name("name")]
+        embedded = not names
+        if embedded:
+            names = [_go_base_type_name(type_text).split(".")[-1]]
+        for name in names:
+            field: Dict[str, Any] = {"name": name, "type": type_text, "embedded": embedded}
+            if tag is not None:
+                field["tag"] = tag
+                field["tags"] = _go_parse_struct_tag(tag)
+            fields.append(field)
+    return fields
+
+
+def _go_interface_members(interface_node: Any) -> Tuple[List[str], List[str]]:
+    """
+    Returns ``(methods, embeds)`` for a Go interface_type: method signatures as
+    written (``"Greet() string"``) and the names of embedded interfaces.
+    Type-set elements such as ``~int | ~float64`` are neither.
+    """
+    methods: List[str] = []
+    embeds: List[str] = []
+    for element in interface_node.named_children:
+        if element.type in ("method_elem", "method_spec"):
+            methods.append(_normalize_signature(_node_text(element)))
+        elif element.type in ("type_elem", "constraint_elem", "interface_type_name"):
+            inner = element.named_children
+            if len(inner) == 1 and inner[0].type in ("type_identifier", "qualified_type"):
+                embeds.append(_node_text(inner[0]))
+        elif element.type in ("type_identifier", "qualified_type"):
+            embeds.append(_node_text(element))
+    return methods, embeds
+
+
+def _go_struct_tag(tag_node: Any) -> Optional[str]:
+    """Returns the contents of a struct tag literal: `json:"id"` -> json:"id"."""
+    if tag_node is None:
+        return None
+    raw = _node_text(tag_node)
+    if tag_node.type == "raw_string_literal":
+        return raw[1:-1]
+    try:
+        value = ast.literal_eval(raw)
+    except (ValueError, SyntaxError):
+        return raw.strip('"')
+    return value if isinstance(value, str) else raw
+
+
+def _go_parse_struct_tag(tag: str) -> Dict[str, str]:
+    """
+    Parses a struct tag's ``key:"value"`` pairs as ``reflect.StructTag.Lookup`` does.
+
+    ``json:"id,omitempty" db:"id"`` -> ``{"json": "id,omitempty", "db": "id"}``.
+    Values are unquoted, so ``\\"`` is ``"``. The first of repeated keys
+    wins, and parsing stops at the first pair that breaks the convention, as
+    it does in Go; a tag like ``json:id`` yields nothing.
+    """
+    tags: Dict[str, str] = {}
+    i = 0
+    while i < len(tag):
+        while i < len(tag) and tag[i] == " ":
+            i += 1
+        start = i
+        # A key is any run of non-control characters other than space, quote and colon
+        while i < len(tag) and tag[i] > " " and tag[i] not in ':"\x7f':
+            i += 1
+        if i == start or i + 1 >= len(tag) or tag[i] != ":" or tag[i + 1] != '"':
+            break
+        key = tag[start:i]
+        i += 2
+        value_start = i
+        while i < len(tag) and tag[i] != '"':
+            i += 2 if tag[i] == "\\" else 1
+        if i >= len(tag):
+            break
+        try:
+            value = ast.literal_eval('"' + tag[value_start:i] + '"')
+        except (ValueError, SyntaxError):
+            break
+        tags.setdefault(key, value)
+        i += 1
+    return tags
+
+
+# Function literals reported with ExtractionOptions.include_nested, and the named definitions that enclose them
+_JS_FUNCTION_LITERALS = frozenset({"arrow_function", "function_expression", "generator_function"})
+_JS_SCOPES = frozenset(
+    {
+        "function_declaration",
+        "generator_function_declaration",
+        "class_declaration",
+        "abstract_class_declaration",
+        "method_definition",
+    }
+)
+
+
+def _js_is_query_function(literal: Any) -> bool:
+    """Whether tags.scm reports *literal* itself: module-level ``const f = () => {}`` or ``export default () => {}``."""
+    holder = literal.parent
+    if holder is None:
+        return False
+    if holder.type == "export_statement":
+        return holder.parent is not None and holder.parent.type == "program"
+    value = holder.child_by_field_name("value") if holder.type == "variable_declarator" else None
+    if value is None or value.start_byte != literal.start_byte:
+        return False
+    statement = _js_statement(holder)
+    return statement.parent is not None and statement.parent.type == "program"
+
+
+def _js_statement(definition_node: Any) -> Any:
+    """Returns the top-level statement of a JS/TS definition: the export_statement when exported."""
+    node = definition_node
+    if node.type == "variable_declarator" and node.parent is not None:
+        node = node.parent  # lexical_declaration / variable_declaration
+    if node.parent is not None and node.parent.type == "export_statement":
+        return node.parent
+    return node
+
+
+def _jsdoc_comment(statement_node: Any) -> Optional[str]:
+    """Returns the cleaned JSDoc block (``/** ... */``) directly above a statement."""
+    comments = _leading_comment_nodes(statement_node)
+    if not comments or not _node_text(comments[-1]).startswith("/**"):
+        return None
+    return _clean_comment_text([comments[-1]]) or None
+
+
+def _is_rust_doc_comment(text: str, inner: bool = False) -> bool:
+    """Checks for ``///``/``/** */`` (outer) or ``//!``/``/*! */`` (inner) doc comments; ``////`` is plain."""
+    if inner:
+        return text.startswith("//!") or text.startswith("/*!")
+    return (text.startswith("///") and not text.startswith("////")) or (
+        text.startswith("/**") and not text.startswith("/***") and text != "/**/"
+    )
+
+
+def _rust_doc_comment_nodes(definition_node: Any) -> List[Any]:
+    """
+    Returns the doc comments of a Rust item: outer ``///`` comments above it,
+    looking past attributes such as ``#[derive(...)]``, or for a module the
+    inner ``//!`` comments at the top of its body.
+    """
+    comments: List[Any] = []
+    sibling = definition_node.prev_sibling
+    while sibling is not None and sibling.type in ("line_comment", "block_comment", "attribute_item"):
+        if sibling.type != "attribute_item":
+            if not _is_rust_doc_comment(_node_text(sibling)):
+                break
+            comments.append(sibling)
+        sibling = sibling.prev_sibling
+    comments.reverse()
+    if comments or definition_node.type != "mod_item":
+        return comments
+    body = definition_node.child_by_field_name("body")
+    for child in body.named_children if body is not None else []:
+        if child.type not in ("line_comment", "block_comment") or not _is_rust_doc_comment(
+            _node_text(child), inner=True
+        ):
+            break
+        comments.append(child)
+    return comments
+
+
+# Rust items that can carry a visibility modifier
+_RUST_VISIBLE_ITEMS = frozenset(
+    {"function_item", "struct_item", "enum_item", "union_item", "trait_item", "type_item", "const_item", "static_item", "mod_item"}
+)
+
+
+def _rust_is_pub(item_node: Any) -> bool:
+    """Plain ``pub`` exports an item; ``pub(crate)`` and friends keep it inside the crate."""
+    return any(c.type == "visibility_modifier" and _node_text(c) == "pub" for c in item_node.children)
+
+
+def _rust_trait_members(trait_node: Any) -> Tuple[List[str], List[str]]:
+    """
+    Returns ``(methods, provided)`` for a trait_item: the signatures of every
+    method, like a Go interface's method set, and those with a default body.
+    """
+    methods: List[str] = []
+    provided: List[str] = []
+    body = trait_node.child_by_field_name("body")
+    for child in body.named_children if body is not None else []:
+        if child.type not in ("function_item", "function_signature_item"):
+            continue
+        signature = _normalize_signature(_declaration_header(child)).rstrip(";")
+        methods.append(signature)
+        if child.type == "function_item":
+            provided.append(signature)
+    return methods, provided
+
+
+def _rust_base_type_name(type_text: str) -> str:
+    """Reduces an impl target to its type name: "&'a mut crate::store::Store<T>" -> "Store"."""
+    base = type_text.split("<", 1)[0].split("::")[-1]
+    tokens = base.replace("&", " ").replace("*", " ").split()
+    return tokens[-1] if tokens else type_text
+
+
+def _python_docstring(definition_node: Any) -> Optional[str]:
+    """Returns the cleaned docstring of a Python function/class (first string literal in its body)."""
+    body = definition_node.child_by_field_name("body")
+    if body is None:
+        return None
+    first_statement = next((c for c in body.named_children if c.type != "comment"), None)
+    if first_statement is None or first_statement.type != "expression_statement":
+        return None
+    string_node = first_statement.named_children[0] if first_statement.named_children else None
+    if string_node is None or string_node.type != "string":
+        return None
+    raw = _node_text(string_node)
+    try:
+        value = ast.literal_eval(raw)
+    except (ValueError, SyntaxError):
+        # f-strings and other non-literal strings are not docstrings
+        return None
+    if not isinstance(value, str):
+        return None
+    return inspect.cleandoc(value)
+
+
+def _python_decorators(definition_node: Any) -> List[str]:
+    """Returns decorator expressions (without the leading '@') applied to a Python definition."""
+    parent = definition_node.parent
+    if parent is None or parent.type != "decorated_definition":
+        return []
+    return [_node_text(d).lstrip("@").strip() for d in parent.named_children if d.type == "decorator"]
+
+
+def _python_signature(definition_node: Any, decorators: List[str]) -> str:
+    """Returns the def/class header without the trailing colon, decorators on their own lines first."""
+    header = _normalize_signature(_declaration_header(definition_node)).rstrip(":").rstrip()
+    return "\n".join([f"@{d}" for d in decorators] + [header])
+
+
+def _python_is_nested_function(definition_node: Any) -> bool:
+    """Checks whether a function_definition's nearest enclosing scope is another function."""
+    ancestor = definition_node.parent
+    while ancestor is not None:
+        if ancestor.type == "function_definition":
+            return True
+        if ancestor.type == "class_definition":
+            return False
+        ancestor = ancestor.parent
+    return False
+
+
+def _python_scope_names(definition_node: Any) -> List[str]:
+    """Returns the names of enclosing classes/functions, outermost first."""
+    names: List[str] = []
+    ancestor = definition_node.parent
+    while ancestor is not None:
+        if ancestor.type in ("class_definition", "function_definition"):
+            name_node = ancestor.child_by_field_name("name")
+            if name_node is not None:
+                names.append(_node_text(name_node))
+        ancestor = ancestor.parent
+    names.reverse()
+    return names
+
+
+# Java declarations that can enclose members
+_JAVA_TYPES = frozenset(
+    {"class_declaration", "interface_declaration", "enum_declaration", "record_declaration", "annotation_type_declaration"}
+)
+_JAVA_ANNOTATIONS = ("marker_annotation", "annotation")
+
+
+def _java_modifier_nodes(declaration_node: Any) -> List[Any]:
+    modifiers = next((c for c in declaration_node.children if c.type == "modifiers"), None)
+    return list(modifiers.children) if modifiers is not None else []
+
+
+def _java_modifiers(declaration_node: Any) -> List[str]:
+    """Returns the modifier keywords of a Java declaration, e.g. ``["public", "static", "final"]``."""
+    return [_node_text(c) for c in _java_modifier_nodes(declaration_node) if c.type not in _JAVA_ANNOTATIONS]
+
+
+def _java_annotations(declaration_node: Any) -> List[str]:
+    """Returns the annotations of a Java declaration without the leading '@', e.g. ``["Override"]``."""
+    nodes = _java_modifier_nodes(declaration_node)
+    return [_node_text(c).lstrip("@").strip() for c in nodes if c.type in _JAVA_ANNOTATIONS]
+
+
+def _java_is_anonymous(node: Any) -> bool:
+    """True for ``new Runnable() { ... }``: an object creation with a class body."""
+    return node.type == "object_creation_expression" and any(c.type == "class_body" for c in node.named_children)
+
+
+def _java_enclosing_types(declaration_node: Any) -> List[Any]:
+    """Returns the type declarations around *declaration_node*, anonymous classes included, outermost first."""
+    types: List[Any] = []
+    child, ancestor = declaration_node, declaration_node.parent
+    while ancestor is not None:
+        # `new Foo(new Bar() {...})`: Bar's body is inside Foo's arguments, not inside a class body of Foo
+        if ancestor.type in _JAVA_TYPES or (_java_is_anonymous(ancestor) and child.type == "class_body"):
+            types.append(ancestor)
+        child, ancestor = ancestor, ancestor.parent
+    types.reverse()
+    return types
+
+
+def _java_type_name(type_node: Any) -> str:
+    """
+    The simple name of a Java type declaration.
+
+    Anonymous classes are numbered from 1 in source order within the type
+    declaring them, as javac names them ``Outer$1``, so the first one in
+    ``Outer`` is ``1`` and is qualified as ``Outer.1``.
+    """
+    name = type_node.child_by_field_name("name")
+    if name is not None:
+        return _node_text(name)
+    enclosing = _java_enclosing_types(type_node)
+    if not enclosing:
+        return "anonymous"
+    anonymous: List[Any] = []
+    stack = list(reversed(enclosing[-1].children))
+    while stack:
+        node = stack.pop()
+        if node.type in _JAVA_TYPES:
+            continue
+        if _java_is_anonymous(node):
+            # Classes inside it are numbered within it instead
+            anonymous.append(node)
+            continue
+        stack.extend(reversed(node.children))
+    number = next((i for i, n in enumerate(anonymous, 1) if n.start_byte == type_node.start_byte), len(anonymous) + 1)
+    return str(number)
+
+
+def _java_is_visible(declaration_node: Any, enclosing: Optional[Any]) -> bool:
+    """Public and protected declarations are visible outside the package; interface members are implicitly public."""
+    if _java_is_anonymous(declaration_node):
+        return False
+    if declaration_node.type == "formal_parameter":
+        # Record components are read through public accessors
+        return True
+    modifiers = _java_modifiers(declaration_node)
+    if "public" in modifiers or "protected" in modifiers:
+        return True
+    if "private" in modifiers:
+        return False
+    return enclosing is not None and enclosing.type in ("interface_declaration", "annotation_type_declaration")
+
+
+def _block_doc_comment(declaration_node: Any) -> Optional[str]:
+    """Returns the cleaned ``/** ... */`` comment directly above a Java, C or C++ declaration."""
+    prev = declaration_node.prev_sibling
+    if prev is None or prev.type not in ("block_comment", "comment"):
+        return None
+    text = _node_text(prev)
+    if not text.startswith("/**") or text == "/**/":
+        return None
+    return _clean_comment_text([prev]) or None
+
+
+def _java_signature(declaration_node: Any) -> str:
+    """Returns a Java declaration's header without annotations or body, e.g. ``"public void add(Item item)"``."""
+    raw = declaration_node.text
+    start = declaration_node.start_byte
+    body = declaration_node.child_by_field_name("body")
+    if body is None and _java_is_anonymous(declaration_node):
+        body = next(c for c in declaration_node.named_children if c.type == "class_body")
+    end = body.start_byte if body is not None else declaration_node.end_byte
+    pieces, position = [], start
+    for annotation in (c for c in _java_modifier_nodes(declaration_node) if c.type in _JAVA_ANNOTATIONS):
+        pieces.append(raw[position - start : annotation.start_byte - start])
+        position = annotation.end_byte
+    pieces.append(raw[position - start : end - start])
+    header = b" ".join(pieces).decode("utf-8", errors="ignore")
+    return _normalize_signature(header).rstrip(";").rstrip()
+
+
+_C_LANGUAGES = ("c", "cpp")
+_C_TAGGED_TYPES = {"struct_specifier": "struct", "union_specifier": "union", "enum_specifier": "enum"}
+_C_CLASS_TYPES = ("class_specifier", "struct_specifier", "union_specifier")
+
+
+def _c_function_declarator(node: Any) -> Optional[Any]:
+    """The function_declarator of a C or C++ function definition or prototype, under any pointer declarators."""
+    declarator = node.child_by_field_name("declarator")
+    while declarator is not None and declarator.type != "function_declarator":
+        declarator = declarator.child_by_field_name("declarator")
+    return declarator
+
+
+def _c_statement(node: Any) -> Any:
+    """The declaration a struct or enum specifier opens, e.g. the whole ``struct Person {...} people[4];``."""
+    while node.parent is not None and node.parent.type == "declaration" and node.parent.start_byte == node.start_byte:
+        node = node.parent
+    return node
+
+
+def _c_enclosing_classes(node: Any) -> List[str]:
+    """Names of the C++ classes and structs around *node*, outermost first."""
+    names: List[str] = []
+    parent = node.parent
+    while parent is not None:
+        if parent.type in _C_CLASS_TYPES and parent.child_by_field_name("body") is not None:
+            name = parent.child_by_field_name("name")
+            if name is not None:
+                names.insert(0, _node_text(name))
+        parent = parent.parent
+    return names
+
+
+def _c_include_guards(root: Any) -> Set[int]:
+    """Start bytes of ``#define X_H`` lines that only guard their header (``#ifndef X_H``) against double inclusion."""
+    guards: Set[int] = set()
+    for node in root.named_children:
+        if node.type != "preproc_ifndef":
+            continue
+        name = node.child_by_field_name("name")
+        body = [c for c in node.named_children if c.start_byte >= name.end_byte and c.type != "comment"]
+        if body and body[0].type == "preproc_def" and body[0].child_by_field_name("value") is None:
+            if _node_text(body[0].child_by_field_name("name")) == _node_text(name):
+                guards.add(body[0].start_byte)
+    return guards
+
+
+def _c_tidy(root: Any, symbols: List[Dict[str, Any]]) -> List[Dict[str, Any]]:
+    """
+    Drops include-guard macros, and tagged structs that a typedef of the same name already reports.
+
+    ``typedef struct Point {...} Point;`` matches both the struct and the
+    typedef; only the typedef, which spans the whole declaration, is kept.
+    """
+    guards = _c_include_guards(root)
+    typedefs = [s for s in symbols if s.get("typedef")]
+    kept = []
+    for symbol in symbols:
+        if symbol["type"] == "macro" and symbol["start_byte"] in guards:
+            continue
+        if not symbol.get("typedef") and any(
+            t["name"] == symbol["name"] and t["start_byte"] <= symbol["start_byte"] < t["end_byte"] for t in typedefs
+        ):
+            continue
+        kept.append(symbol)
+    return kept
+
+
+# ShellCheck directives such as "# shellcheck disable=SC2034" are not documentation
+_SHELL_DIRECTIVE = re.compile(r"^#\s*shellcheck\s")
+
+
+def _shell_doc_comment(definition_node: Any) -> Optional[str]:
+    """Returns the ``#`` comment block directly above a shell function or assignment, without the markers."""
+    lines: List[str] = []
+    for comment in _leading_comment_nodes(definition_node):
+        text = _node_text(comment)
+        if (comment.start_point[0] == 0 and text.startswith("#!")) or _SHELL_DIRECTIVE.match(text):
+            continue
+        line = text[1:]
+        lines.append((line[1:] if line.startswith(" ") else line).rstrip())
+    while lines and not lines[0].strip():
+        lines.pop(0)
+    while lines and not lines[-1].strip():
+        lines.pop()
+    return "\n".join(lines) or None
+
+
+def _shell_assignment(definition_node: Any, name: str) -> Optional[Any]:
+    """The ``NAME=value`` node for *name*: the definition itself, or one of a declaration's assignments."""
+    if definition_node.type == "variable_assignment":
+        return definition_node
+    for child in definition_node.named_children:
+        if child.type == "variable_assignment" and _node_text(child.child_by_field_name("name")) == name:
+            return child
+    return None
+
+
 class TreeSitterSymbolExtractor:
     """
     Multi-language symbol extractor using tree-sitter queries (tags.scm).
@@ -34,31 +1027,41 @@ class TreeSitterSymbolExtractor:
     """
 
     LANGUAGES = set(LANGUAGES.keys())
-    _parsers: ClassVar[dict[str, Any]] = {}
-    _queries: ClassVar[dict[str, Any]] = {}
+    # Parsers and queries carry per-call cursor state, so each thread gets its own.
+    _local: ClassVar[threading.local] = threading.local()
+
+    @classmethod
+    def _thread_cache(cls, name: str) -> dict[str, Any]:
+        cache = getattr(cls._local, name, None)
+        if cache is None:
+            cache = {}
+            setattr(cls._local, name, cache)
+        return cache
 
     @classmethod
     def get_parser(cls, ext: str) -> Optional[Any]:
         if ext not in LANGUAGES:
             return None
-        if ext not in cls._parsers:
+        parsers = cls._thread_cache("parsers")
+        if ext not in parsers:
             lang_name = LANGUAGES[ext]
             parser = get_parser(cast(Any, lang_name))  # type: ignore[arg-type]
-            cls._parsers[ext] = parser
-        return cls._parsers[ext]
+            parsers[ext] = parser
+        return parsers[ext]
 
     @classmethod
     def get_query(cls, ext: str) -> Optional[Any]:
         if ext not in LANGUAGES:
             logger.debug(f"get_query: Extension {ext} not supported.")
             return None
-        if ext in cls._queries:
+        queries = cls._thread_cache("queries")
+        if ext in queries:
             logger.debug(f"get_query: query cached for ext {ext}")
-            return cls._queries[ext]
+            return queries[ext]
 
         lang_name = LANGUAGES[ext]
         logger.debug(f"get_query: lang={lang_name}")
-        query_dir: str = lang_name
+        query_dir: str = QUERY_DIRS.get(lang_name, lang_name)
         tags_path: str = os.path.join(QUERIES_ROOT, query_dir, "tags.scm")
         logger.debug(f"get_query: tags_path={tags_path} exists={os.path.exists(tags_path)}")
         if not os.path.exists(tags_path):
@@ -69,7 +1072,7 @@ class TreeSitterSymbolExtractor:
             with open(tags_path, "r") as f:
                 tags_content = f.read()
             query = language.query(tags_content)
-            cls._queries[ext] = query
+            queries[ext] = query
             logger.debug(f"get_query: Query loaded successfully for ext {ext}")
             return query
         except Exception as e:
@@ -78,131 +1081,627 @@ class TreeSitterSymbolExtractor:
             return None
 
     @staticmethod
-    def extract_symbols(ext: str, source_code: str) -> List[Dict[str, Any]]:
-        """Extracts symbols from source code using tree-sitter queries."""
+    def extract_symbols(
+        ext: str,
+        source_code: str,
+        options: Optional[ExtractionOptions] = None,
+        raise_errors: bool = False,
+        diagnostics: Optional[List[Dict[str, Any]]] = None,
+    ) -> List[Dict[str, Any]]:
+        """
+        Extracts symbols from source code using tree-sitter queries.
+
+        Errors are logged and yield an empty list unless *raise_errors* is set,
+        in which case they propagate to the caller. Code that does not parse is
+        not an error: tree-sitter recovers around it, and when *diagnostics* is
+        given, the syntax errors it recovered from (see :meth:`syntax_errors`)
+        are appended to it from the same parse.
+        """
+        try:
+            symbols = list(TreeSitterSymbolExtractor.iter_symbols(ext, source_code, options, diagnostics))
+        except Exception as e:
+            if raise_errors:
+                raise
+            logger.error(f"[EXTRACT] Error parsing or processing file with ext {ext}: {e}")
+            logger.error(traceback.format_exc())
+            return []  # Return empty list on error
+
+        logger.debug(f"[EXTRACT] Finished extraction for ext {ext}. Found {len(symbols)} symbols.")
+        return symbols
+
+    @staticmethod
+    def iter_symbols(
+        ext: str,
+        source_code: str,
+        options: Optional[ExtractionOptions] = None,
+        diagnostics: Optional[List[Dict[str, Any]]] = None,
+    ) -> Iterator[Dict[str, Any]]:
+        """
+        Yields the symbols :meth:`extract_symbols` returns, one at a time as the query finds them.
+
+        Only the syntax tree is held while iterating, not the symbols already
+        yielded. C and C++ are the exception: a struct is dropped when a
+        typedef of the same name covers it, so their symbols are collected
+        before the first is yielded. Errors propagate to the caller.
+        """
         logger.debug(f"[EXTRACT] Attempting to extract symbols for ext: {ext}")
-        symbols: List[Dict[str, Any]] = []
+        options = options or ExtractionOptions()
         query = TreeSitterSymbolExtractor.get_query(ext)
         parser = TreeSitterSymbolExtractor.get_parser(ext)
 
         if not query or not parser:
             logger.warning(f"[EXTRACT] No query or parser available for extension: {ext}")
-            return []
+            return
 
-        try:
-            tree = parser.parse(bytes(source_code, "utf8"))
-            root = tree.root_node
-
-            matches = query.matches(root)
-            logger.debug(f"[EXTRACT] Found {len(matches)} matches.")
-
-            # matches is List[Tuple[int, Dict[str, Node]]]
-            # Each tuple is (pattern_index, {capture_name: Node})
-            for pattern_index, captures in matches:
-                logger.debug(f"[MATCH pattern={pattern_index}] Processing match with captures: {list(captures.keys())}")
-
-                # Determine symbol name: prefer @name, fallback to @type for blocks like terraform/locals
-                node_candidate = None
-                if "name" in captures:
-                    node_candidate = captures["name"]
-                elif "type" in captures:
-                    node_candidate = captures["type"]
-                else:
-                    # Fallback: take the first capture node
-                    first_capture_node = next(iter(captures.values()), None)
-                    if not first_capture_node:
-                        continue
-                    node_candidate = first_capture_node
+        source_bytes = bytes(source_code, "utf8")
+        tree = parser.parse(source_bytes)
+        root = tree.root_node
+        if diagnostics is not None and root.has_error:
+            diagnostics.extend(_syntax_errors(root, source_bytes))
 
-                # Handle list of nodes (tree-sitter may return a list)
-                if isinstance(node_candidate, list):
-                    if not node_candidate:
-                        continue  # skip empty list
-                    actual_name_node = node_candidate[0]
-                else:
-                    actual_name_node = node_candidate
-
-                # Now extract symbol name as before
-                symbol_name = (
-                    actual_name_node.text.decode() if hasattr(actual_name_node, "text") else str(actual_name_node)
-                )
-                # HCL: Strip quotes from string literals
-                if ext == ".tf" and hasattr(actual_name_node, "type") and actual_name_node.type == "string_lit":
-                    if len(symbol_name) >= 2 and symbol_name.startswith('"') and symbol_name.endswith('"'):
-                        symbol_name = symbol_name[1:-1]
-
-                definition_capture = next(
-                    ((name, node) for name, node in captures.items() if name.startswith("definition.")), None
-                )
-                subtype = None
-                if definition_capture:
-                    definition_capture_name, definition_node = definition_capture
-                    symbol_type = definition_capture_name.split(".")[-1]
-                    # HCL: For resource/data, combine type and name, and set subtype to the specific resource/data type
-                    if ext == ".tf" and symbol_type in ["resource", "data"]:
-                        type_node = captures.get("type")
-                        if type_node:
-                            if isinstance(type_node, list):
-                                type_node = type_node[0] if type_node else None
-                            if type_node and hasattr(type_node, "text"):
-                                type_name = type_node.text.decode()
-                                if hasattr(type_node, "type") and type_node.type == "string_lit":
-                                    if len(type_name) >= 2 and type_name.startswith('"') and type_name.endswith('"'):
-                                        type_name = type_name[1:-1]
-                                symbol_name = f"{type_name}.{symbol_name}"
-                                subtype = type_name
-                else:
-                    # Fallback: infer symbol type from first capture label (e.g., 'function', 'class')
-                    fallback_label = next(iter(captures.keys()), "symbol")
-                    symbol_type = fallback_label.lstrip("definition.").lstrip("@")
-
-                # Determine the node for the full symbol body, its span, and its code content.
-                # Default to actual_name_node if no specific body capture is found.
-                node_for_body_span_and_code = actual_name_node
-                if definition_capture:
-                    _, captured_body_node = definition_capture  # This is the node from @definition.foo
-                    temp_body_node = None
-                    if isinstance(captured_body_node, list):
-                        temp_body_node = captured_body_node[0] if captured_body_node else None
-                    else:
-                        temp_body_node = captured_body_node
-
-                    if temp_body_node:  # If a valid body node was found from definition_capture
-                        node_for_body_span_and_code = temp_body_node
-
-                # Extract start_line, end_line, and code content from node_for_body_span_and_code
-                symbol_start_line = node_for_body_span_and_code.start_point[0]
-                symbol_end_line = node_for_body_span_and_code.end_point[0]
-
-                if hasattr(node_for_body_span_and_code, "text") and isinstance(node_for_body_span_and_code.text, bytes):
-                    symbol_code_content = node_for_body_span_and_code.text.decode("utf-8", errors="ignore")
-                elif hasattr(node_for_body_span_and_code, "start_byte") and hasattr(
-                    node_for_body_span_and_code, "end_byte"
-                ):
-                    # Fallback for nodes where .text might not be the full desired content or not directly available as decodable bytes
-                    symbol_code_content = source_code[
-                        node_for_body_span_and_code.start_byte : node_for_body_span_and_code.end_byte
-                    ]
+        for symbol in TreeSitterSymbolExtractor._file_symbols(ext, query, root, source_bytes, options):
+            if options.include_unexported or is_exported(symbol, ext):
+                yield symbol
+
+    @staticmethod
+    def _file_symbols(
+        ext: str, query: Any, root: Any, source_bytes: bytes, options: ExtractionOptions
+    ) -> Iterator[Dict[str, Any]]:
+        """The symbols the query matches, then those a language finds by walking the tree itself."""
+        lang_name = LANGUAGES.get(ext)
+        symbols = TreeSitterSymbolExtractor._query_symbols(ext, query, root, source_bytes)
+        if lang_name in _C_LANGUAGES:
+            # Whether a typedef covers a struct is only known once every typedef has been matched
+            yield from _c_tidy(root, list(symbols))
+            return
+        yield from symbols
+        if lang_name == "go":
+            yield from TreeSitterSymbolExtractor._go_type_symbols(ext, root, source_bytes)
+            yield from TreeSitterSymbolExtractor._go_value_symbols(root, source_bytes)
+        if lang_name == "python" and options.include_nested:
+            yield from TreeSitterSymbolExtractor._python_nested_functions(ext, root, source_bytes)
+        if lang_name == "go" and options.include_nested:
+            yield from TreeSitterSymbolExtractor._go_function_literals(root, source_bytes)
+        if lang_name in _JS_LANGUAGES and options.include_nested:
+            yield from TreeSitterSymbolExtractor._js_function_literals(root, source_bytes)
+
+    @staticmethod
+    def _query_symbols(ext: str, query: Any, root: Any, source_bytes: bytes) -> Iterator[Dict[str, Any]]:
+        """Yields a symbol for each definition the tags.scm *query* matches, in match order."""
+        seen_definitions: set = set()
+        matches = query.matches(root)
+        logger.debug(f"[EXTRACT] Found {len(matches)} matches.")
+
+        # matches is List[Tuple[int, Dict[str, Node]]]
+        # Each tuple is (pattern_index, {capture_name: Node})
+        for pattern_index, captures in matches:
+            logger.debug(f"[MATCH pattern={pattern_index}] Processing match with captures: {list(captures.keys())}")
+
+            # Determine symbol name: prefer @name, fallback to @type for blocks like terraform/locals
+            node_candidate = None
+            if "name" in captures:
+                node_candidate = captures["name"]
+            elif "type" in captures:
+                node_candidate = captures["type"]
+            else:
+                # Fallback: take the first capture node
+                first_capture_node = next(iter(captures.values()), None)
+                if not first_capture_node:
+                    continue
+                node_candidate = first_capture_node
+
+            # Handle list of nodes (tree-sitter may return a list)
+            if isinstance(node_candidate, list):
+                if not node_candidate:
+                    continue  # skip empty list
+                actual_name_node = node_candidate[0]
+            else:
+                actual_name_node = node_candidate
+
+            # Now extract symbol name as before
+            symbol_name = (
+                actual_name_node.text.decode() if hasattr(actual_name_node, "text") else str(actual_name_node)
+            )
+            # HCL: Strip quotes from string literals
+            if ext == ".tf" and hasattr(actual_name_node, "type") and actual_name_node.type == "string_lit":
+                if len(symbol_name) >= 2 and symbol_name.startswith('"') and symbol_name.endswith('"'):
+                    symbol_name = symbol_name[1:-1]
+
+            definition_capture = next(
+                ((name, node) for name, node in captures.items() if name.startswith("definition.")), None
+            )
+            subtype = None
+            if definition_capture:
+                definition_capture_name, definition_node = definition_capture
+                symbol_type = definition_capture_name.split(".")[-1]
+                # HCL: For resource/data, combine type and name, and set subtype to the specific resource/data type
+                if ext == ".tf" and symbol_type in ["resource", "data"]:
+                    type_node = captures.get("type")
+                    if type_node:
+                        if isinstance(type_node, list):
+                            type_node = type_node[0] if type_node else None
+                        if type_node and hasattr(type_node, "text"):
+                            type_name = type_node.text.decode()
+                            if hasattr(type_node, "type") and type_node.type == "string_lit":
+                                if len(type_name) >= 2 and type_name.startswith('"') and type_name.endswith('"'):
+                                    type_name = type_name[1:-1]
+                            symbol_name = f"{type_name}.{symbol_name}"
+                            subtype = type_name
+            else:
+                # Fallback: infer symbol type from first capture label (e.g., 'function', 'class')
+                fallback_label = next(iter(captures.keys()), "symbol")
+                symbol_type = fallback_label.lstrip("definition.").lstrip("@")
+
+            # Determine the node for the full symbol body, its span, and its code content.
+            # Default to actual_name_node if no specific body capture is found.
+            node_for_body_span_and_code = actual_name_node
+            if definition_capture:
+                _, captured_body_node = definition_capture  # This is the node from @definition.foo
+                temp_body_node = None
+                if isinstance(captured_body_node, list):
+                    temp_body_node = captured_body_node[0] if captured_body_node else None
                 else:
-                    # Last resort, if node_for_body_span_and_code is unusual and lacks .text (bytes) or start/end_byte
-                    symbol_code_content = symbol_name  # Fallback to just the name string
-
-                symbol = {
-                    "name": symbol_name,  # symbol_name is from actual_name_node, potentially modified by HCL logic
-                    "type": symbol_type,
-                    "start_line": symbol_start_line,
-                    "end_line": symbol_end_line,
-                    "code": symbol_code_content,
-                }
-                if subtype:
-                    symbol["subtype"] = subtype
+                    temp_body_node = captured_body_node
+
+                if temp_body_node:  # If a valid body node was found from definition_capture
+                    node_for_body_span_and_code = temp_body_node
+
+            # Overlapping query patterns can match the same definition more than once
+            definition_key = (symbol_name, symbol_type, node_for_body_span_and_code.start_byte)
+            if definition_key in seen_definitions:
+                continue
+            seen_definitions.add(definition_key)
+
+            symbol = _span_symbol(symbol_name, symbol_type, node_for_body_span_and_code, source_bytes)
+            if node_for_body_span_and_code.has_error:
+                # Recovered from code that does not parse; the span and signature may be cut short
+                symbol["has_errors"] = True
+            if subtype:
+                symbol["subtype"] = subtype
+            TreeSitterSymbolExtractor._enrich_symbol(ext, symbol, node_for_body_span_and_code)
+            yield symbol
+
+    @staticmethod
+    def syntax_errors(ext: str, source_code: str) -> List[Dict[str, Any]]:
+        """
+        Returns the syntax errors tree-sitter recovered from in *source_code*.
+
+        Each error has a ``message`` and 0-based ``start_line``, ``start_column``,
+        ``end_line`` and ``end_column``, like a symbol's. Extensions without a
+        tree-sitter grammar report no errors.
+        """
+        parser = TreeSitterSymbolExtractor.get_parser(ext)
+        if parser is None:
+            return []
+        source_bytes = bytes(source_code, "utf8")
+        return _syntax_errors(parser.parse(source_bytes).root_node, source_bytes)
+
+    @staticmethod
+    def _go_type_symbols(ext: str, root: Any, source_bytes: bytes) -> List[Dict[str, Any]]:
+        """
+        Extracts the package-level Go aliases and named types that are not structs or interfaces.
+
+        Each is a ``type`` symbol. A named type such as ``type UserID int``
+        records its ``underlying`` type text, and a function type such as
+        ``type Handler func(w http.ResponseWriter)`` also its ``params`` and
+        ``results``. An alias such as ``type Alias = OldName`` has ``alias: True``
+        and the aliased type as ``alias_of``.
+        """
+        symbols: List[Dict[str, Any]] = []
+        for declaration in root.named_children:
+            if declaration.type != "type_declaration":
+                continue
+            for spec in declaration.named_children:
+                type_node = spec.child_by_field_name("type")
+                name_node = spec.child_by_field_name("name")
+                if spec.type not in ("type_spec", "type_alias") or type_node is None or name_node is None:
+                    continue
+                if spec.type == "type_spec" and type_node.type in ("struct_type", "interface_type"):
+                    # Matched by tags.scm
+                    continue
+                symbol = _span_symbol(_node_text(name_node), "type", spec, source_bytes)
+                if spec.has_error:
+                    symbol["has_errors"] = True
+                TreeSitterSymbolExtractor._enrich_symbol(ext, symbol, spec)
                 symbols.append(symbol)
+        return symbols
+
+    @staticmethod
+    def _go_value_symbols(root: Any, source_bytes: bytes) -> List[Dict[str, Any]]:
+        """
+        Extracts package-level Go constants and variables, one symbol per declared name.
+
+        Every symbol spans its whole declaration, so members of a ``const ( ... )``
+        block share the block's range. Blank identifiers are skipped. Inside a
+        const block a spec without a value repeats the previous spec's type and
+        expressions, as iota sequences do, so ``B`` in ``const ( A Kind = iota; B )``
+        is typed ``Kind`` with value ``iota``.
+
+        Besides the signature each symbol records its ``value`` expression, its
+        ``value_type`` (declared, or inferred from an untyped literal) and, for
+        constants built from ``iota``, the spec's ``iota`` and the ``resolved_value``
+        when the expression is plain integer arithmetic.
+        """
+        symbols: List[Dict[str, Any]] = []
+        for declaration in root.named_children:
+            if declaration.type == "const_declaration":
+                keyword, symbol_type = "const", "constant"
+            elif declaration.type == "var_declaration":
+                keyword, symbol_type = "var", "variable"
+            else:
                 continue
+            previous_type: Optional[str] = None
+            previous_values: List[Any] = []
+            for iota, spec in enumerate(_go_value_specs(declaration)):
+                type_node = spec.child_by_field_name("type")
+                type_text = _normalize_signature(_node_text(type_node)) if type_node is not None else None
+                values = _go_spec_values(spec)
+                if keyword == "const":
+                    if not values:
+                        type_text, values = previous_type, previous_values
+                    previous_type, previous_values = type_text, values
+                comments = _go_doc_comment_nodes(spec)
+                docstring = _clean_comment_text(comments) if comments else ""
+                for index, name_node in enumerate(spec.children_by_field_name("name")):
+                    name = _node_text(name_node)
+                    if name == "_":
+                        continue
+                    symbol = _span_symbol(name, symbol_type, declaration, source_bytes)
+                    symbol["signature"] = f"{keyword} {name} {type_text}" if type_text else f"{keyword} {name}"
+                    if docstring:
+                        _set_go_doc(symbol, docstring, comments)
+                    # `var a, b = f()` assigns one multi-valued expression to several names
+                    value = values[index] if index < len(values) else (values[0] if len(values) == 1 else None)
+                    value_type = type_text or (_go_literal_type(value) if value is not None else None)
+                    if value_type:
+                        symbol["value_type"] = value_type
+                    if value is not None:
+                        value_text = _normalize_signature(_node_text(value))
+                        symbol["value"] = value_text
+                        if keyword == "const" and re.search(r"\biota\b", value_text):
+                            symbol["iota"] = iota
+                            resolved = _go_eval_iota(value, iota)
+                            if resolved is not None:
+                                symbol["resolved_value"] = resolved
+                    symbols.append(symbol)
+        return symbols
 
-        except Exception as e:
-            logger.error(f"[EXTRACT] Error parsing or processing file with ext {ext}: {e}")
-            logger.error(traceback.format_exc())
-            return []  # Return empty list on error
+    @staticmethod
+    def _python_nested_functions(ext: str, root: Any, source_bytes: bytes) -> List[Dict[str, Any]]:
+        """Extracts functions defined inside other functions; tags.scm only covers module and class scope."""
+        symbols: List[Dict[str, Any]] = []
+        stack = [root]
+        while stack:
+            node = stack.pop()
+            stack.extend(reversed(node.named_children))
+            if node.type != "function_definition" or not _python_is_nested_function(node):
+                continue
+            name_node = node.child_by_field_name("name")
+            if name_node is None:
+                continue
+            symbol = _span_symbol(_node_text(name_node), "function", node, source_bytes)
+            TreeSitterSymbolExtractor._enrich_symbol(ext, symbol, node)
+            symbols.append(symbol)
+        return symbols
 
-        logger.debug(f"[EXTRACT] Finished extraction for ext {ext}. Found {len(symbols)} symbols.")
+    @staticmethod
+    def _go_function_literals(root: Any, source_bytes: bytes) -> List[Dict[str, Any]]:
+        """
+        Extracts Go function literals, named the way the Go runtime names closures.
+
+        The literals of a function ``F`` are ``F.func1``, ``F.func2``, ... in
+        source order, and those inside ``F.func1`` are ``F.func1.1``, ``F.func1.2``.
+        A method's are qualified by its type, as in ``Server.Start.func1``, and
+        literals in package-level variables are ``init.func1``, ...; Go numbers
+        those across the package, while they are numbered per file here. Each
+        literal is ``anonymous``, unexported and has its enclosing function or
+        literal as ``parent``. One started by ``go func() { ... }()`` is reported
+        once, with ``goroutine: True``.
+        """
+        symbols: List[Dict[str, Any]] = []
+        counts: Dict[str, int] = {}
+        for declaration in root.named_children:
+            name_node = declaration.child_by_field_name("name")
+            if declaration.type == "function_declaration" and name_node is not None:
+                scope: str = _node_text(name_node)
+                parent: Optional[str] = scope
+            elif declaration.type == "method_declaration" and name_node is not None:
+                receiver = _go_receiver_type(declaration)
+                scope = _node_text(name_node)
+                if receiver:
+                    scope = f"{_go_base_type_name(receiver)}.{scope}"
+                parent = scope
+            elif declaration.type == "var_declaration":
+                scope, parent = "init", None
+            else:
+                continue
+            # (node, enclosing name, parent, inside a literal); popped in source order
+            stack = [(child, scope, parent, False) for child in reversed(declaration.named_children)]
+            while stack:
+                node, enclosing, owner, nested = stack.pop()
+                if node.type != "func_literal":
+                    stack.extend((child, enclosing, owner, nested) for child in reversed(node.named_children))
+                    continue
+                counts[enclosing] = counts.get(enclosing, 0) + 1
+                name = f"{enclosing}.{counts[enclosing]}" if nested else f"{enclosing}.func{counts[enclosing]}"
+                symbol = _span_symbol(name, "function", node, source_bytes)
+                symbol.update(node_path=name, anonymous=True, exported=False)
+                if owner:
+                    symbol["parent"] = owner
+                symbol["signature"] = _normalize_signature(_declaration_header(node))
+                symbol["params"], symbol["results"] = _go_signature_params(node)
+                call = node.parent
+                # go func() { ... }(): the literal is the called function of the go statement's call
+                if call is not None and call.type == "call_expression" and call.parent is not None:
+                    if call.parent.type == "go_statement":
+                        symbol["goroutine"] = True
+                symbols.append(symbol)
+                stack.extend((child, name, name, True) for child in reversed(node.named_children))
         return symbols
+
+    @staticmethod
+    def _js_function_literals(root: Any, source_bytes: bytes) -> List[Dict[str, Any]]:
+        """
+        Extracts JavaScript and TypeScript arrow functions and function expressions the query does not cover.
+
+        Module-level ``const f = () => {}`` is already the function ``f``; any
+        other literal is named after its enclosing symbol. One assigned to a
+        variable takes the variable's name, as in ``mount.render``, and any
+        other, such as a callback, is ``<parent>.<anonymous:line>`` with its
+        1-based line, e.g. ``mount.<anonymous:2>``, and ``anonymous: True``.
+        Literals inside literals nest their names the same way. Each is
+        unexported and has its enclosing symbol, if any, as ``parent``.
+        """
+        symbols: List[Dict[str, Any]] = []
+        stack: List[Tuple[Any, Optional[str]]] = [(root, None)]
+        while stack:
+            node, scope = stack.pop()
+            holder = node.parent
+            name_node = node.child_by_field_name("name")
+            declarator = holder is not None and holder.type == "variable_declarator"
+            if node.type in _JS_FUNCTION_LITERALS and _js_is_query_function(node):
+                # Named by the query after its declarator, or "default"
+                scope = _node_text(holder.child_by_field_name("name")) if declarator else "default"
+            elif node.type in _JS_FUNCTION_LITERALS:
+                assigned = holder.child_by_field_name("name") if declarator else None
+                if assigned is not None and assigned.type == "identifier":
+                    local = _node_text(assigned)
+                else:
+                    local = f"<anonymous:{node.start_point[0] + 1}>"
+                name = f"{scope}.{local}" if scope else local
+                symbol = _span_symbol(name, "function", node, source_bytes)
+                symbol.update(node_path=name, exported=False)
+                if assigned is None or assigned.type != "identifier":
+                    symbol["anonymous"] = True
+                if scope:
+                    symbol["parent"] = scope
+                symbol["signature"] = _normalize_signature(_declaration_header(node))
+                symbols.append(symbol)
+                scope = name
+            elif node.type in _JS_SCOPES and name_node is not None:
+                # Methods the query reports as Class.method; functions and classes by their own name
+                own = _node_text(name_node)
+                scope = f"{scope}.{own}" if node.type == "method_definition" and scope else own
+            stack.extend((child, scope) for child in reversed(node.named_children))
+        return symbols
+
+    @staticmethod
+    def _enrich_symbol(ext: str, symbol: Dict[str, Any], node: Any) -> None:
+        """Adds language-specific details to a symbol using its definition node."""
+        lang_name = LANGUAGES.get(ext)
+        if lang_name == "go" and hasattr(node, "prev_sibling"):
+            comments = _go_doc_comment_nodes(node)
+            docstring = _clean_comment_text(comments) if comments else ""
+            if docstring:
+                _set_go_doc(symbol, docstring, comments)
+        if lang_name == "go" and getattr(node, "type", None) in ("function_declaration", "method_declaration"):
+            # Parameters are kept exactly as written: (a, b int) is not expanded
+            symbol["signature"] = _normalize_signature(_declaration_header(node))
+            symbol["params"], symbol["results"] = _go_signature_params(node)
+            symbol["param_count"] = len(symbol["params"])
+            symbol["code_lines"] = _code_line_count(node)
+            symbol["complexity"] = _go_complexity(node)
+        if lang_name == "go" and getattr(node, "type", None) == "type_spec":
+            type_node = node.child_by_field_name("type")
+            if type_node is not None and type_node.type in ("struct_type", "interface_type"):
+                # "type Store[T any] struct" - the header without the field/method list
+                header = node.text[: type_node.start_byte - node.start_byte].decode("utf-8", errors="ignore")
+                keyword = "struct" if type_node.type == "struct_type" else "interface"
+                symbol["signature"] = _normalize_signature(f"type {header} {keyword}")
+                if type_node.type == "struct_type":
+                    symbol["fields"] = _go_struct_fields(type_node)
+                else:
+                    methods, embeds = _go_interface_members(type_node)
+                    symbol["methods"] = methods
+                    if embeds:
+                        # Embedded interfaces are referenced by name, not expanded
+                        symbol["embeds"] = embeds
+            elif type_node is not None:
+                # "type UserID int", "type Handler func(w http.ResponseWriter, r *http.Request)"
+                symbol["signature"] = _normalize_signature(f"type {_node_text(node)}")
+                symbol["underlying"] = _normalize_signature(_node_text(type_node))
+                if type_node.type == "function_type":
+                    symbol["params"], symbol["results"] = _go_signature_params(type_node)
+        if lang_name == "go" and getattr(node, "type", None) == "type_alias":
+            # "type Alias = OldName" declares no new type, so methods can't be declared on Alias
+            type_node = node.child_by_field_name("type")
+            symbol["signature"] = _normalize_signature(f"type {_node_text(node)}")
+            symbol["alias"] = True
+            if type_node is not None:
+                symbol["alias_of"] = _normalize_signature(_node_text(type_node))
+        if lang_name == "go" and getattr(node, "type", None) in ("function_declaration", "type_spec", "type_alias"):
+            type_params = _go_type_params(node)
+            if type_params:
+                symbol["type_params"] = type_params
+        if lang_name == "go" and symbol["type"] == "method" and getattr(node, "type", None) == "method_declaration":
+            receiver = _go_receiver_type(node)
+            if receiver:
+                # "receiver" keeps the type as written so pointer vs value receivers stay distinguishable;
+                # "parent" is the base type name and is what methods should be grouped by.
+                parent = _go_base_type_name(receiver)
+                symbol["receiver"] = receiver
+                symbol["parent"] = parent
+                symbol["node_path"] = f"{parent}.{symbol['name']}"
+        elif lang_name in _JS_LANGUAGES and hasattr(node, "parent"):
+            owner = node
+            if node.type == "method_definition" and node.parent is not None and node.parent.parent is not None:
+                # Methods report their class's export status and are grouped under it
+                owner = node.parent.parent
+                class_name = owner.child_by_field_name("name")
+                if class_name is not None:
+                    symbol["parent"] = _node_text(class_name)
+                    symbol["node_path"] = f"{symbol['parent']}.{symbol['name']}"
+            symbol["exported"] = _js_statement(owner).type == "export_statement"
+            docstring = _jsdoc_comment(node if owner is not node else _js_statement(node))
+            if docstring:
+                symbol["docstring"] = docstring
+        elif lang_name == "rust" and hasattr(node, "parent"):
+            comments = _rust_doc_comment_nodes(node)
+            docstring = _clean_comment_text(comments) if comments else ""
+            if docstring:
+                symbol["docstring"] = docstring
+            if node.type in ("function_item", "function_signature_item"):
+                symbol["signature"] = _normalize_signature(_declaration_header(node)).rstrip(";")
+            if node.type in _RUST_VISIBLE_ITEMS:
+                symbol["exported"] = _rust_is_pub(node)
+            if node.type == "trait_item":
+                methods, provided = _rust_trait_members(node)
+                symbol["methods"] = methods
+                if provided:
+                    symbol["provided"] = provided
+                bounds = node.child_by_field_name("bounds")
+                if bounds is not None:
+                    # trait Store: Send + Sync - supertraits play the part of Go's embedded interfaces
+                    symbol["supertraits"] = [_node_text(b) for b in bounds.named_children]
+            if node.type == "impl_item":
+                target = _node_text(node.child_by_field_name("type"))
+                symbol["name"] = _rust_base_type_name(target)
+                symbol["target"] = target
+                trait = node.child_by_field_name("trait")
+                if trait is not None:
+                    symbol["trait"] = _node_text(trait)
+            container = node.parent.parent if node.parent is not None else None
+            if symbol["type"] == "method" and container is not None:
+                if container.type == "impl_item":
+                    # Methods attach to the implementing type; trait impls also record the trait
+                    symbol["parent"] = _rust_base_type_name(_node_text(container.child_by_field_name("type")))
+                    trait = container.child_by_field_name("trait")
+                    if trait is not None:
+                        symbol["trait"] = _node_text(trait)
+                        # Trait methods are as visible as the trait itself, whatever the impl says
+                        symbol["exported"] = True
+                else:
+                    symbol["parent"] = _node_text(container.child_by_field_name("name"))
+                    symbol["exported"] = _rust_is_pub(container)
+                symbol["node_path"] = f"{symbol['parent']}.{symbol['name']}"
+        elif lang_name == "java" and hasattr(node, "parent"):
+            enclosing = _java_enclosing_types(node)
+            if _java_is_anonymous(node):
+                symbol["name"] = _java_type_name(node)
+                symbol["anonymous"] = True
+                symbol["extends"] = _node_text(node.child_by_field_name("type"))
+            if enclosing:
+                # Inner classes and members are qualified by every enclosing type: Cart.Item.price
+                symbol["parent"] = ".".join(_java_type_name(t) for t in enclosing)
+                symbol["node_path"] = f"{symbol['parent']}.{symbol['name']}"
+            type_params = node.child_by_field_name("type_parameters")
+            if type_params is not None:
+                params = [p for p in type_params.named_children if p.type == "type_parameter"]
+                symbol["type_params"] = [_node_text(p) for p in params]
+            modifiers = _java_modifiers(node)
+            if modifiers:
+                symbol["modifiers"] = modifiers
+            annotations = _java_annotations(node)
+            if annotations:
+                symbol["annotations"] = annotations
+            # A member reaches outside the package only if every type around it does too
+            scopes = [None] + enclosing
+            symbol["exported"] = all(_java_is_visible(n, outer) for n, outer in zip(enclosing + [node], scopes))
+            docstring = _block_doc_comment(node)
+            if docstring:
+                symbol["docstring"] = docstring
+            if node.type in ("field_declaration", "constant_declaration", "formal_parameter"):
+                # One declaration can name several fields; each gets its own signature
+                type_text = _normalize_signature(_node_text(node.child_by_field_name("type")))
+                symbol["signature"] = " ".join(modifiers + [type_text, symbol["name"]])
+            else:
+                symbol["signature"] = _java_signature(node)
+        elif lang_name in _C_LANGUAGES and hasattr(node, "parent"):
+            docstring = _block_doc_comment(_c_statement(node))
+            if docstring:
+                symbol["docstring"] = docstring
+            if lang_name == "cpp":
+                classes = _c_enclosing_classes(node)
+                if classes:
+                    symbol["parent"] = ".".join(classes)
+            if node.type in ("function_definition", "declaration", "field_declaration"):
+                if node.type != "function_definition":
+                    symbol["prototype"] = True
+                header = _declaration_header(node)
+                old_style = next((c for c in node.named_children if c.type == "declaration"), None)
+                if node.type == "function_definition" and old_style is not None:
+                    # K&R parameter declarations sit between the declarator and the body: int f(a) int a; {...}
+                    header = node.text[: old_style.start_byte - node.start_byte].decode("utf-8", errors="ignore")
+                symbol["signature"] = _normalize_signature(header).rstrip(";").rstrip()
+                declarator = _c_function_declarator(node)
+                target = declarator.child_by_field_name("declarator") if declarator is not None else None
+                if target is not None and target.type == "qualified_identifier":
+                    # void Cart::add(...) belongs to Cart, wherever it is defined
+                    scope = target.child_by_field_name("scope")
+                    if scope is not None:
+                        symbol["parent"] = _node_text(scope).replace("::", ".")
+                if symbol.get("parent"):
+                    symbol["type"] = "method"
+            elif node.type == "type_definition":
+                symbol["typedef"] = True
+                type_node = node.child_by_field_name("type")
+                tagged = type_node is not None and type_node.type in _C_TAGGED_TYPES
+                if tagged and type_node.child_by_field_name("body") is not None:
+                    symbol["type"] = _C_TAGGED_TYPES[type_node.type]
+                    tag = type_node.child_by_field_name("name")
+                    if tag is not None and _node_text(tag) != symbol["name"]:
+                        symbol["tag"] = _node_text(tag)
+                else:
+                    symbol["signature"] = _normalize_signature(_node_text(node)).rstrip(";").rstrip()
+            elif node.type in ("preproc_def", "preproc_function_def"):
+                parameters = node.child_by_field_name("parameters")
+                symbol["signature"] = f"#define {symbol['name']}{_node_text(parameters) if parameters is not None else ''}"
+                value = node.child_by_field_name("value")
+                if value is not None:
+                    symbol["value"] = _node_text(value).strip()
+            if symbol.get("parent"):
+                symbol["node_path"] = f"{symbol['parent']}.{symbol['name']}"
+        elif lang_name == "bash" and hasattr(node, "parent"):
+            docstring = _shell_doc_comment(node)
+            if docstring:
+                symbol["docstring"] = docstring
+            if node.type == "function_definition":
+                # "deploy()" or "function deploy", as written
+                symbol["signature"] = _normalize_signature(_declaration_header(node))
+            else:
+                if node.type == "declaration_command":
+                    # The keyword and its flags: ["export"], ["declare", "-r"]
+                    modifiers = [
+                        _node_text(c) for c in node.children if c.type not in ("variable_assignment", "variable_name")
+                    ]
+                    symbol["modifiers"] = modifiers
+                    if modifiers[0] == "readonly" or any(f.startswith("-") and "r" in f for f in modifiers[1:]):
+                        symbol["type"] = "constant"
+                assignment = _shell_assignment(node, symbol["name"])
+                value = assignment.child_by_field_name("value") if assignment is not None else None
+                if value is not None:
+                    symbol["value"] = _node_text(value)
+        elif lang_name == "python" and getattr(node, "type", None) in ("function_definition", "class_definition"):
+            scope = _python_scope_names(node)
+            if scope:
+                symbol["parent"] = ".".join(scope)
+                symbol["node_path"] = ".".join(scope + [symbol["name"]])
+            docstring = _python_docstring(node)
+            if docstring is not None:
+                symbol["docstring"] = docstring
+            decorators = _python_decorators(node)
+            if decorators:
+                symbol["decorators"] = decorators
+            symbol["signature"] = _python_signature(node, decorators)
+            if any(child.type == "async" for child in node.children):
+                symbol["async"] = True
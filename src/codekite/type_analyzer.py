@@ -0,0 +1,817 @@
+"""Structural analysis of Go types: interfaces, method sets and implementations."""
+
+from __future__ import annotations
+from dataclasses import dataclass, field
+from typing import Any, Dict, Iterable, List, Optional, Set, Tuple, TYPE_CHECKING
+import json
+import os
+import re
+import logging
+
+from .tree_sitter_symbol_extractor import (
+    TreeSitterSymbolExtractor,
+    _go_base_type_name,
+    _go_receiver_type,
+    _node_text,
+    _normalize_signature,
+)
+
+logger = logging.getLogger(__name__)
+
+if TYPE_CHECKING:
+    from .repository import Repository
+
+# A method is identified by its name plus parameter and result type text, e.g.
+# ("Greet", (), ("string",)). Parameter names are ignored.
+MethodKey = Tuple[str, Tuple[str, ...], Tuple[str, ...]]
+
+# Predeclared interfaces that may be embedded without being declared in the package.
+_BUILTIN_INTERFACES: Dict[str, List[MethodKey]] = {
+    "error": [("Error", (), ("string",))],
+    "any": [],
+}
+
+
+def _parameter_types(param_list: Any) -> Tuple[str, ...]:
+    """Returns one type string per parameter: (a, b int, rest ...string) -> ("int", "int", "...string")."""
+    if param_list is None:
+        return ()
+    types: List[str] = []
+    for decl in param_list.named_children:
+        type_node = decl.child_by_field_name("type")
+        if type_node is None:
+            continue
+        type_text = _normalize_signature(_node_text(type_node))
+        if decl.type == "variadic_parameter_declaration":
+            type_text = "..." + type_text
+        names = decl.children_by_field_name("name")
+        types.extend([type_text] * max(len(names), 1))
+    return tuple(types)
+
+
+def _result_types(result: Any) -> Tuple[str, ...]:
+    if result is None:
+        return ()
+    if result.type == "parameter_list":
+        return _parameter_types(result)
+    return (_normalize_signature(_node_text(result)),)
+
+
+def _method_key(node: Any) -> Optional[MethodKey]:
+    name_node = node.child_by_field_name("name")
+    if name_node is None:
+        return None
+    return (
+        _node_text(name_node),
+        _parameter_types(node.child_by_field_name("parameters")),
+        _result_types(node.child_by_field_name("result")),
+    )
+
+
+def _interface_method_set(package: Dict[str, Any], name: str, seen: Optional[set] = None) -> Optional[List[MethodKey]]:
+    """Returns all methods of an interface including embedded ones, or None if it cannot be resolved."""
+    if name in _BUILTIN_INTERFACES:
+        return list(_BUILTIN_INTERFACES[name])
+    interface = package["interfaces"].get(name)
+    seen = seen if seen is not None else set()
+    if interface is None or interface["constraint"] or name in seen:
+        return None
+    seen.add(name)
+    methods = list(interface["methods"])
+    for embedded in interface["embeds"]:
+        embedded_methods = _interface_method_set(package, embedded, seen)
+        if embedded_methods is None:
+            # e.g. an interface from another package: we cannot tell what it requires
+            return None
+        methods.extend(embedded_methods)
+    return methods
+
+
+def _satisfies(package: Dict[str, Any], type_name: str, required: List[MethodKey]) -> Optional[bool]:
+    """
+    Checks whether *type_name* implements the required methods.
+
+    Returns None if it does not, False if the value type does, and True if
+    only the pointer type does (some required methods have pointer receivers).
+    """
+    methods = {m["key"]: m["pointer"] for m in package["methods"].get(type_name, [])}
+    if any(key not in methods for key in required):
+        return None
+    return any(methods[key] for key in required)
+
+
+def _signature_key(signature: str) -> Optional[MethodKey]:
+    """
+    Parses a recorded signature back into a :data:`MethodKey`.
+
+    Accepts method symbol signatures (``"func (u User) Greet() string"``) and
+    interface method entries (``"Greet() string"``).
+    """
+    declaration = signature if signature.startswith("func ") else f"func {signature}"
+    source = f"package p\n{declaration} {{}}\n".encode("utf-8")
+    root = TreeSitterSymbolExtractor.get_parser(".go").parse(source).root_node
+    node = next((c for c in root.named_children if c.type in ("function_declaration", "method_declaration")), None)
+    return _method_key(node) if node is not None else None
+
+
+def _new_package() -> Dict[str, Any]:
+    return {"interfaces": {}, "types": {}, "methods": {}}
+
+
+def find_implementers(symbols: List[Dict[str, Any]]) -> Dict[str, List[str]]:
+    """
+    Maps each Go interface in *symbols* to the structs and other named types that implement it.
+
+    Run this after every file of a package has been extracted: symbols are
+    grouped into packages by the directory of their ``file``, and interfaces
+    only match types from the same package, whichever file declares them.
+    Implementers are listed by name, prefixed with ``*`` when only the pointer
+    type satisfies the interface. Keys are interface names, qualified by
+    package directory outside the root (``"store.Store"``). Interfaces without
+    methods, or embedding interfaces that are not in the symbol set, are left out.
+    """
+    packages: Dict[str, Dict[str, Any]] = {}
+    for symbol in symbols:
+        package = packages.setdefault(os.path.dirname(symbol.get("file") or ""), _new_package())
+        if symbol.get("type") == "interface" and "methods" in symbol:
+            keys = [_signature_key(m) for m in symbol["methods"]]
+            package["interfaces"][symbol["name"]] = {
+                "methods": [k for k in keys if k is not None],
+                "embeds": symbol.get("embeds", []),
+                "constraint": False,
+            }
+        elif symbol.get("type") == "struct" or (symbol.get("type") == "type" and not symbol.get("alias")):
+            # Named types such as `type HandlerFunc func()` have methods too; an alias's belong to its target
+            package["types"][symbol["name"]] = symbol
+        elif (
+            symbol.get("type") == "method"
+            and symbol.get("parent")
+            and symbol.get("signature", "").startswith("func ")
+        ):
+            key = _signature_key(symbol["signature"])
+            if key is not None:
+                package["methods"].setdefault(symbol["parent"], []).append(
+                    {"key": key, "pointer": symbol.get("receiver", "").startswith("*")}
+                )
+
+    implementers: Dict[str, List[str]] = {}
+    for package_path, package in sorted(packages.items()):
+        for interface_name in sorted(package["interfaces"]):
+            required = _interface_method_set(package, interface_name)
+            if not required:
+                continue
+            names = []
+            for type_name in sorted(package["types"]):
+                pointer = _satisfies(package, type_name, required)
+                if pointer is not None:
+                    names.append(f"*{type_name}" if pointer else type_name)
+            key = f"{package_path}.{interface_name}" if package_path else interface_name
+            implementers[key] = names
+    return implementers
+
+
+# Symbol types that methods can attach to
+_OWNER_TYPES = frozenset({"struct", "interface", "type", "class", "enum", "trait", "union"})
+
+
+def merge_package(files: Dict[str, List[Dict[str, Any]]]) -> List[Dict[str, Any]]:
+    """
+    Consolidates per-file symbols into one view per package, with methods attached to their types.
+
+    Packages are directories, as in :func:`find_implementers`, so a Go type
+    declared in ``user.go`` collects the methods from ``user_methods.go`` and
+    any other file of the package. Each type gains ``members`` (its methods,
+    sorted by file and line) and ``files`` (every file that contributes to it).
+    Methods whose ``parent`` type is not declared in the package stay in the
+    list with ``receiver_missing`` set. Every returned symbol is a copy with
+    ``file`` and ``package`` (``"."`` for the root) set; the input is not modified.
+
+    Args:
+        files: Symbols keyed by repository-relative path, as returned by
+            :meth:`Repository.parse_directory`.
+
+    Returns:
+        Types, functions and other symbols (not methods that found their type),
+        sorted by package, file and line.
+    """
+    packages: Dict[str, Dict[str, Any]] = {}
+    for path, symbols in sorted(files.items()):
+        directory = os.path.dirname(path)
+        package = packages.setdefault(directory, {"owners": {}, "symbols": [], "methods": []})
+        for symbol in symbols:
+            entry = dict(symbol, file=symbol.get("file") or path, package=directory or ".")
+            if entry.get("type") == "method" and entry.get("parent"):
+                package["methods"].append(entry)
+                continue
+            package["symbols"].append(entry)
+            if entry.get("type") in _OWNER_TYPES:
+                # Python parents are dotted scopes ("Outer.Inner"), so register the qualified name
+                package["owners"].setdefault(entry.get("node_path") or entry["name"], entry)
+
+    def location(symbol: Dict[str, Any]) -> tuple:
+        return (symbol["file"], symbol.get("start_line", 0))
+
+    merged: List[Dict[str, Any]] = []
+    for _, package in sorted(packages.items()):
+        for owner in package["owners"].values():
+            owner["members"] = []
+            owner["files"] = [owner["file"]]
+        for method in sorted(package["methods"], key=location):
+            owner = package["owners"].get(method["parent"])
+            if owner is None:
+                method["receiver_missing"] = True
+                package["symbols"].append(method)
+                continue
+            owner["members"].append(method)
+            if method["file"] not in owner["files"]:
+                owner["files"].append(method["file"])
+        for owner in package["owners"].values():
+            owner["files"].sort()
+        merged.extend(sorted(package["symbols"], key=location))
+    return merged
+
+
+@dataclass
+class Collision:
+    """
+    A name two embedded types promote at the same depth, which Go leaves unselectable.
+
+    Attributes:
+        type: The struct or interface, qualified by package directory outside the root (``"store.Admin"``).
+        name: The clashing field or method name.
+        chains: Each promotion that clashed, e.g. ``"Admin -> User -> ID"``.
+        file: File declaring the type.
+        line: 1-based line of the type's declaration.
+    """
+
+    type: str
+    name: str
+    chains: List[str]
+    file: str
+    line: int
+
+    def __str__(self) -> str:
+        """``"store/admin.go:12: Admin.ID is ambiguous: Admin -> User -> ID, Admin -> Account -> ID"``."""
+        name = f"{self.type.rsplit('.', 1)[-1]}.{self.name}"
+        return f"{self.file}:{self.line}: {name} is ambiguous: {', '.join(self.chains)}"
+
+    def to_dict(self) -> Dict[str, Any]:
+        return {"type": self.type, "name": self.name, "chains": list(self.chains), "file": self.file, "line": self.line}
+
+
+@dataclass
+class EmbeddingReport:
+    """
+    What :func:`resolve_embedding` found, keyed by type name qualified as in :func:`find_implementers`.
+
+    Attributes:
+        promoted: For each struct that embeds something, the fields and
+            methods it gains, as put in the struct symbol's ``promoted``.
+        method_sets: For each interface, every method it requires, embedded ones included.
+        collisions: Names dropped because two promotions tie, in type order.
+        unresolved: For each type, the embedded types declared outside its
+            package (``sync.Mutex``, ``io.Reader``), whose members are unknown.
+    """
+
+    promoted: Dict[str, List[Dict[str, Any]]] = field(default_factory=dict)
+    method_sets: Dict[str, List[str]] = field(default_factory=dict)
+    collisions: List[Collision] = field(default_factory=list)
+    unresolved: Dict[str, List[str]] = field(default_factory=dict)
+
+    def to_dict(self) -> Dict[str, Any]:
+        return {
+            "promoted": self.promoted,
+            "method_sets": self.method_sets,
+            "collisions": [c.to_dict() for c in self.collisions],
+            "unresolved": self.unresolved,
+        }
+
+
+def _method_name(signature: str) -> str:
+    """The name of an interface method entry: ``"Get(key string) string"`` -> ``"Get"``."""
+    return signature.split("(", 1)[0].strip()
+
+
+def _flatten_interface(
+    package: Dict[str, Any], name: str, chain: Tuple[str, ...] = ()
+) -> Tuple[Dict[str, List[Tuple[str, str]]], List[str]]:
+    """
+    Returns the methods of the interface *name*, by method name, each as ``(signature, chain)``, and its unknown embeds.
+
+    An interface reached again through its own embeds contributes nothing the second time.
+    """
+    methods: Dict[str, List[Tuple[str, str]]] = {}
+    unresolved: List[str] = []
+    chain = chain + (name,)
+    if name in _BUILTIN_INTERFACES and name not in package["interfaces"]:
+        return {"Error": [("Error() string", " -> ".join(chain + ("Error",)))]} if name == "error" else {}, []
+    interface = package["interfaces"][name]
+    for signature in interface.get("methods", []):
+        method = _method_name(signature)
+        methods.setdefault(method, []).append((signature, " -> ".join(chain + (method,))))
+    for embedded in interface.get("embeds", []):
+        if embedded in chain:
+            continue
+        if embedded not in package["interfaces"] and embedded not in _BUILTIN_INTERFACES:
+            unresolved.append(embedded)
+            continue
+        inner, inner_unresolved = _flatten_interface(package, embedded, chain)
+        for method, entries in inner.items():
+            methods.setdefault(method, []).extend(entries)
+        unresolved.extend(e for e in inner_unresolved if e not in unresolved)
+    return methods, unresolved
+
+
+def _struct_members(package: Dict[str, Any], type_name: str) -> List[Tuple[str, str, str, bool]]:
+    """``(name, kind, type or signature, pointer receiver)`` of the fields and methods *type_name* declares."""
+    members = [(f["name"], "field", f.get("type", ""), False) for f in package["structs"][type_name].get("fields", [])]
+    for method in package["methods"].get(type_name, []):
+        pointer = method.get("receiver", "").startswith("*")
+        members.append((method["name"], "method", method.get("signature", ""), pointer))
+    return members
+
+
+def resolve_embedding(symbols: List[Dict[str, Any]]) -> EmbeddingReport:
+    """
+    Works out what Go embedding adds to each struct and interface in *symbols*.
+
+    A struct that embeds ``User`` can be used as if it declared ``User``'s
+    fields and methods, and so on through ``User``'s own embedded fields.
+    Following Go's selector rules, the shallowest declaration of a name wins,
+    and a name found at the same depth through two embedded fields is
+    ambiguous: it is promoted by neither and reported as a :class:`Collision`.
+    A type embedded twice at one depth makes all of its members ambiguous.
+    An interface's method set is its own methods plus those of the
+    interfaces it embeds; methods met twice with the same signature are one
+    method, while the same name with different signatures is a collision.
+
+    Symbols are grouped into packages by the directory of their ``file``, as
+    in :func:`find_implementers`, so run this once every file of a package
+    has been extracted. Each struct symbol that embeds something gains
+    ``promoted``: entries with the member's ``name``, ``kind`` (``"field"``
+    or ``"method"``), ``type`` (a field's type or a method's signature),
+    ``depth``, ``from`` (the type declaring it) and ``chain``, e.g.
+    ``"Admin -> User -> Greet"``, with ``pointer: True`` for a method only
+    ``*Admin`` has, because it has a pointer receiver and no embedded
+    pointer leads to it. Each interface gains ``method_set``, its methods'
+    signatures sorted by name. The symbols are updated in place.
+
+    Returns:
+        The same results as an :class:`EmbeddingReport`.
+    """
+    packages: Dict[str, Dict[str, Any]] = {}
+    for symbol in symbols:
+        directory = os.path.dirname(symbol.get("file") or "")
+        package = packages.setdefault(directory, {"structs": {}, "interfaces": {}, "methods": {}})
+        if symbol.get("type") == "struct":
+            package["structs"][symbol["name"]] = symbol
+        elif symbol.get("type") == "interface" and "methods" in symbol:
+            package["interfaces"][symbol["name"]] = symbol
+        elif symbol.get("type") == "method" and symbol.get("parent"):
+            package["methods"].setdefault(symbol["parent"], []).append(symbol)
+
+    report = EmbeddingReport()
+    for directory, package in sorted(packages.items()):
+        for name in sorted(set(package["structs"]) | set(package["interfaces"])):
+            key = f"{directory}.{name}" if directory else name
+            symbol = package["structs"].get(name) or package["interfaces"][name]
+            location = (symbol.get("file", ""), symbol.get("start_line", 0) + 1)
+            if name in package["interfaces"] and name not in package["structs"]:
+                methods, unresolved = _flatten_interface(package, name)
+                method_set = []
+                for method, entries in sorted(methods.items()):
+                    signatures = {signature for signature, _ in entries}
+                    if len(signatures) > 1:
+                        report.collisions.append(Collision(key, method, [chain for _, chain in entries], *location))
+                    else:
+                        method_set.append(entries[0][0])
+                symbol["method_set"] = method_set
+                report.method_sets[key] = method_set
+            else:
+                promoted, unresolved = _promote(package, name, key, location, report.collisions)
+                if promoted:
+                    symbol["promoted"] = promoted
+                    report.promoted[key] = promoted
+            if unresolved:
+                report.unresolved[key] = unresolved
+    return report
+
+
+def _promote(
+    package: Dict[str, Any], name: str, key: str, location: Tuple[str, int], collisions: List[Collision]
+) -> Tuple[List[Dict[str, Any]], List[str]]:
+    """The members the struct *name* gains through embedding, breadth first, and its unknown embeds."""
+    # Names declared by the struct itself shadow every promoted one
+    blocked = {member[0] for member in _struct_members(package, name)}
+    promoted: List[Dict[str, Any]] = []
+    unresolved: List[str] = []
+    seen = {name}
+    # (embedded type, chain of field names so far, whether a pointer was embedded along the way)
+    level: List[Tuple[str, Tuple[str, ...], bool]] = []
+    for embedded in package["structs"][name].get("fields", []):
+        if embedded.get("embedded"):
+            level.append((embedded["type"], (name, embedded["name"]), embedded["type"].lstrip("(").startswith("*")))
+    depth = 1
+    while level:
+        found: Dict[str, List[Dict[str, Any]]] = {}
+        counts: Dict[str, int] = {}
+        for type_text, _, _ in level:
+            base = _go_base_type_name(type_text)
+            counts[base] = counts.get(base, 0) + 1
+        next_level: List[Tuple[str, Tuple[str, ...], bool]] = []
+        for type_text, chain, pointer in level:
+            base = _go_base_type_name(type_text)
+            if base in seen:
+                continue
+            if base in package["structs"]:
+                members = _struct_members(package, base)
+                for embedded in package["structs"][base].get("fields", []):
+                    if embedded.get("embedded"):
+                        embedded_pointer = pointer or embedded["type"].lstrip("(").startswith("*")
+                        next_level.append((embedded["type"], chain + (embedded["name"],), embedded_pointer))
+            elif base in package["interfaces"] or base in _BUILTIN_INTERFACES:
+                methods, inner_unresolved = _flatten_interface(package, base)
+                members = [(method, "method", entries[0][0], False) for method, entries in sorted(methods.items())]
+                unresolved.extend(e for e in inner_unresolved if e not in unresolved)
+            else:
+                if type_text.lstrip("*") not in unresolved:
+                    unresolved.append(type_text.lstrip("*"))
+                continue
+            for member, kind, member_type, pointer_receiver in members:
+                if member in blocked:
+                    continue
+                entry = {
+                    "name": member,
+                    "kind": kind,
+                    "type": member_type,
+                    "depth": depth,
+                    "from": base,
+                    "chain": " -> ".join(chain + (member,)),
+                }
+                if pointer_receiver and not pointer:
+                    entry["pointer"] = True
+                # A type embedded twice at this depth is reached twice, which is ambiguous too
+                found.setdefault(member, []).extend([entry] * counts[base])
+        seen.update(_go_base_type_name(type_text) for type_text, _, _ in level)
+        for member, entries in sorted(found.items()):
+            blocked.add(member)
+            if len(entries) == 1:
+                promoted.append(entries[0])
+            else:
+                chains = list(dict.fromkeys(e["chain"] for e in entries))
+                collisions.append(Collision(key, member, chains, *location))
+        level = next_level
+        depth += 1
+    return promoted, unresolved
+
+
+# Edge styles of render_dot: an interface is implemented (dashed), a type is embedded (solid)
+DOT_IMPLEMENTS_STYLE = "dashed"
+DOT_EMBEDS_STYLE = "solid"
+
+# A name an interface can embed, as opposed to a constraint element such as ``~int | ~string``
+_EMBEDDABLE_NAME = re.compile(r"^[\w.]+(\[.*\])?$")
+_METHOD_RECEIVER = re.compile(r"^func\s*\([^)]*\)\s*")
+
+
+def _dot_id(name: str) -> str:
+    escaped = name.replace('"', '\\"')
+    return f'"{escaped}"'
+
+
+def _record_text(text: str) -> str:
+    """Escapes the braces, bars and angle brackets that structure a record label: ``struct{}``, ``chan<- int``."""
+    return re.sub(r"([{}|<>])", r"\\\1", text)
+
+
+def _record_label(name: str, *sections: List[str]) -> str:
+    """``{User|ID int\\lName string\\l|Greet() string\\l}``: the name over sections of left-aligned lines."""
+    parts = [_record_text(name)] + ["".join(f"{_record_text(line)}\\l" for line in section) for section in sections]
+    return "{" + "|".join(parts) + "}"
+
+
+def render_dot(symbols: List[Dict[str, Any]]) -> str:
+    """
+    Renders the Go types in *symbols* as a Graphviz digraph, for ``dot -Tsvg``.
+
+    Each struct, interface and named type is a record node listing its
+    fields, or a named type's underlying type, and its methods, with
+    interfaces drawn rounded; aliases are left out. An implementer points at each interface it
+    implements with a dashed ``implements`` edge, as found by
+    :func:`find_implementers`, so only methods a type declares itself count;
+    the label reads ``implements (pointer)`` when only the pointer type
+    does. A struct points at each type it embeds, and an interface at each
+    interface it embeds, with a solid ``embeds`` edge, ``embeds (pointer)``
+    for an embedded pointer. Embedded types declared outside the package,
+    such as ``sync.Mutex``, are dotted boxes. Nodes are named by type, qualified
+    by package directory outside the root as in :func:`find_implementers`, and
+    each such package is a cluster. The output is the same whatever the order of *symbols*.
+    """
+    packages: Dict[str, Dict[str, Any]] = {}
+    for symbol in symbols:
+        package = packages.setdefault(os.path.dirname(symbol.get("file") or ""), {"types": {}, "methods": {}})
+        if symbol.get("type") in ("struct", "type") and not symbol.get("alias"):
+            package["types"][symbol["name"]] = symbol
+        elif symbol.get("type") == "interface" and "methods" in symbol:
+            package["types"][symbol["name"]] = symbol
+        elif symbol.get("type") == "method" and symbol.get("parent"):
+            package["methods"].setdefault(symbol["parent"], []).append(symbol)
+
+    def qualify(directory: str, name: str) -> str:
+        return f"{directory}.{name}" if directory else name
+
+    lines = ["digraph types {", '  rankdir="BT";', "  node [shape=record];"]
+    edges: List[Tuple[str, str, str, str]] = []
+    external: Set[str] = set()
+    for directory, package in sorted(packages.items()):
+        if not package["types"]:
+            continue
+        indent = "  "
+        if directory:
+            lines.append(f"  subgraph {_dot_id('cluster_' + directory)} {{")
+            lines.append(f"    label={_dot_id(directory)};")
+            indent = "    "
+        for name, symbol in sorted(package["types"].items()):
+            key = qualify(directory, name)
+            if symbol["type"] == "interface":
+                embeds = [(e, False) for e in symbol.get("embeds", []) if _EMBEDDABLE_NAME.match(e)]
+                label = _record_label(name, sorted(symbol["methods"]))
+                lines.append(f"{indent}{_dot_id(key)} [shape=Mrecord, label={_dot_id(label)}];")
+            else:
+                fields = symbol.get("fields", [])
+                embeds = [(f["type"], f["type"].lstrip("(").startswith("*")) for f in fields if f.get("embedded")]
+                declared = [f"{f['name']} {f.get('type', '')}".strip() for f in fields if not f.get("embedded")]
+                if symbol["type"] == "type":
+                    declared = [symbol.get("underlying", "")]
+                signatures = [m.get("signature", "") for m in package["methods"].get(name, [])]
+                methods = sorted(_METHOD_RECEIVER.sub("", signature) for signature in signatures)
+                lines.append(f"{indent}{_dot_id(key)} [label={_dot_id(_record_label(name, declared, methods))}];")
+            for type_text, pointer in embeds:
+                base = _go_base_type_name(type_text)
+                if base in package["types"]:
+                    target = qualify(directory, base)
+                else:
+                    target = type_text.strip().lstrip("(*").rstrip(")")
+                    external.add(target)
+                edges.append((key, target, "embeds (pointer)" if pointer else "embeds", DOT_EMBEDS_STYLE))
+        if directory:
+            lines.append("  }")
+    for interface, implementers in find_implementers(symbols).items():
+        directory = interface.rsplit(".", 1)[0] if "." in interface else ""
+        for implementer in implementers:
+            label = "implements (pointer)" if implementer.startswith("*") else "implements"
+            edges.append((qualify(directory, implementer.lstrip("*")), interface, label, DOT_IMPLEMENTS_STYLE))
+
+    for name in sorted(external):
+        lines.append(f"  {_dot_id(name)} [shape=box, style=dotted];")
+    for tail, head, label, style in sorted(set(edges)):
+        lines.append(f"  {_dot_id(tail)} -> {_dot_id(head)} [label={_dot_id(label)}, style={style}];")
+    lines.append("}")
+    return "\n".join(lines)
+
+
+@dataclass
+class TaggedField:
+    """
+    A struct field matched by :func:`find_fields_by_tag_key`.
+
+    Attributes:
+        struct: The struct symbol declaring the field.
+        field: The entry of the struct's ``fields``, with ``tags`` when it has a struct tag.
+        key: The tag key that was looked up.
+    """
+
+    struct: Dict[str, Any]
+    field: Dict[str, Any]
+    key: str
+
+    @property
+    def name(self) -> str:
+        """``"Account.ID"``."""
+        return f"{self.struct.get('node_path') or self.struct['name']}.{self.field['name']}"
+
+    @property
+    def value(self) -> Optional[str]:
+        """The field's value for the key, ``"id,omitempty"`` for ``json:"id,omitempty"``; None if it has none."""
+        return self.field.get("tags", {}).get(self.key)
+
+    @property
+    def location(self) -> str:
+        """``file:line`` of the struct's declaration, 1-based; fields carry no position of their own."""
+        return f"{self.struct.get('file', '')}:{self.struct.get('start_line', 0) + 1}"
+
+    def __str__(self) -> str:
+        """``"model/account.go:12: Account.ID json:\"id,omitempty\""``, or ``"... Account.ID has no json tag"``."""
+        if self.value is None:
+            return f"{self.location}: {self.name} has no {self.key} tag"
+        return f"{self.location}: {self.name} {self.key}:{json.dumps(self.value)}"
+
+    def to_dict(self) -> Dict[str, Any]:
+        return {
+            "struct": self.struct.get("id") or self.struct["name"],
+            "field": self.field["name"],
+            "type": self.field.get("type", ""),
+            "key": self.key,
+            "value": self.value,
+            "location": self.location,
+        }
+
+
+def find_fields_by_tag_key(symbols: Iterable[Dict[str, Any]], key: str, missing: bool = False) -> List[TaggedField]:
+    """
+    Lists the Go struct fields whose struct tag has *key*, e.g. every field with a ``json`` tag.
+
+    Tags are read from each field's ``tags``, parsed with
+    ``reflect.StructTag`` rules, so ``json:"-"`` has the key (with value
+    ``"-"``) and a malformed tag only has the pairs before the error.
+
+    Args:
+        symbols: Symbols of any number of files; only structs with ``fields`` are looked at.
+        key: The tag key, such as ``"json"``, ``"db"`` or ``"validate"``.
+        missing: List the fields *without* the key instead, to find what an
+            audit should flag. Embedded and unexported fields are skipped then,
+            as ``encoding/json`` and most encoders inline or ignore them.
+
+    Returns:
+        The fields in the order of *symbols*, then of declaration.
+    """
+    found: List[TaggedField] = []
+    for symbol in symbols:
+        if symbol.get("type") != "struct":
+            continue
+        for entry in symbol.get("fields", []):
+            tagged = TaggedField(symbol, entry, key)
+            if not missing and tagged.value is not None:
+                found.append(tagged)
+            elif missing and tagged.value is None and not entry.get("embedded") and entry["name"][:1].isupper():
+                found.append(tagged)
+    return found
+
+
+class TypeAnalyzer:
+    """
+    Answers structural questions about Go types without a full type check.
+
+    A Go package is a directory; every ``.go`` file in it (excluding ``_test.go``
+    files) contributes declarations. A type implements an interface when its
+    method set contains every interface method with the same name, parameter
+    types and result types, compared as written in the source.
+    """
+
+    def __init__(self, repository: "Repository"):
+        """
+        Initialize the analyzer with a Repository instance.
+
+        Args:
+            repository: A codekite.Repository instance
+        """
+        self.repo = repository
+        self._packages: Dict[str, Dict[str, Any]] = {}
+
+    @staticmethod
+    def _normalize_package(package_path: str) -> str:
+        normalized = os.path.normpath(package_path) if package_path else "."
+        return "" if normalized == "." else normalized
+
+    def _package_files(self, package_path: str) -> List[str]:
+        files = []
+        for file_info in self.repo.get_file_tree():
+            path = file_info["path"]
+            if file_info.get("is_dir") or not path.endswith(".go") or path.endswith("_test.go"):
+                continue
+            if os.path.dirname(path) == package_path:
+                files.append(path)
+        return sorted(files)
+
+    def _load_package(self, package_path: str) -> Dict[str, Any]:
+        package_path = self._normalize_package(package_path)
+        if package_path in self._packages:
+            return self._packages[package_path]
+
+        parser = TreeSitterSymbolExtractor.get_parser(".go")
+        package = _new_package()
+        for path in self._package_files(package_path):
+            try:
+                source = self.repo.get_file_content(path)
+            except IOError as e:
+                logger.warning(f"Skipping {path}: {e}")
+                continue
+            root = parser.parse(source.encode("utf-8")).root_node
+            self._collect_declarations(root, path, package)
+
+        self._packages[package_path] = package
+        return package
+
+    @staticmethod
+    def _collect_declarations(root: Any, path: str, package: Dict[str, Any]) -> None:
+        for node in root.named_children:
+            if node.type == "type_declaration":
+                for spec in node.named_children:
+                    if spec.type != "type_spec":
+                        continue
+                    name_node = spec.child_by_field_name("name")
+                    type_node = spec.child_by_field_name("type")
+                    if name_node is None or type_node is None:
+                        continue
+                    ref = {"name": _node_text(name_node), "file": path, "line": spec.start_point[0]}
+                    if type_node.type == "interface_type":
+                        ref.update(TypeAnalyzer._interface_elements(type_node))
+                        package["interfaces"][ref["name"]] = ref
+                    else:
+                        package["types"][ref["name"]] = ref
+            elif node.type == "method_declaration":
+                receiver = _go_receiver_type(node)
+                key = _method_key(node)
+                if receiver is None or key is None:
+                    continue
+                package["methods"].setdefault(_go_base_type_name(receiver), []).append(
+                    {"key": key, "pointer": receiver.lstrip("(").startswith("*")}
+                )
+
+    @staticmethod
+    def _interface_elements(interface_node: Any) -> Dict[str, Any]:
+        """Splits an interface body into its own methods, embedded interface names and type constraints."""
+        methods: List[MethodKey] = []
+        embeds: List[str] = []
+        constraint = False
+        for element in interface_node.named_children:
+            if element.type in ("method_elem", "method_spec"):
+                key = _method_key(element)
+                if key is not None:
+                    methods.append(key)
+            elif element.type in ("type_elem", "constraint_elem", "interface_type_name", "type_identifier"):
+                inner = element.named_children if element.type != "type_identifier" else [element]
+                if len(inner) == 1 and inner[0].type in ("type_identifier", "qualified_type"):
+                    embeds.append(_node_text(inner[0]))
+                else:
+                    # Unions and ~T approximations make this a constraint, not a method-set interface
+                    constraint = True
+            elif element.type != "comment":
+                constraint = True
+        return {"methods": methods, "embeds": embeds, "constraint": constraint}
+
+    def implementations(self, package_path: str, interface_name: str) -> List[Dict[str, Any]]:
+        """
+        Returns the concrete types in a package whose method sets satisfy an interface.
+
+        Args:
+            package_path: Directory of the Go package, relative to the repository root.
+            interface_name: Name of an interface declared in that package.
+
+        Returns:
+            Type references sorted by file and line, each a dict with ``name``,
+            ``file``, ``line`` (0-based) and ``pointer`` (True when only ``*T``
+            implements the interface because some methods have pointer receivers).
+
+        Raises:
+            ValueError: If the interface is not declared in the package or cannot
+                be resolved (it embeds an interface from another package).
+        """
+        package = self._load_package(package_path)
+        if interface_name not in package["interfaces"]:
+            raise ValueError(f"Interface not found in package '{package_path}': {interface_name}")
+        required = _interface_method_set(package, interface_name)
+        if required is None:
+            raise ValueError(f"Cannot resolve the method set of interface: {interface_name}")
+
+        results = []
+        for type_name, ref in package["types"].items():
+            pointer = _satisfies(package, type_name, required)
+            if pointer is not None:
+                results.append(dict(ref, pointer=pointer))
+        return sorted(results, key=lambda r: (r["file"], r["line"]))
+
+    def interfaces_satisfied_by(self, type_name: str, package_path: Optional[str] = None) -> List[Dict[str, Any]]:
+        """
+        Returns the interfaces a concrete type implements.
+
+        Only interfaces declared in the same package as the type are considered.
+        When *package_path* is omitted every package declaring *type_name* is searched.
+
+        Returns:
+            Interface references sorted by file and line, with the same keys as
+            :meth:`implementations`; ``pointer`` tells whether ``*T`` is required.
+        """
+        if package_path is not None:
+            packages = [self._normalize_package(package_path)]
+        else:
+            packages = sorted(
+                {os.path.dirname(f["path"]) for f in self.repo.get_file_tree() if f["path"].endswith(".go")}
+            )
+
+        results = []
+        for candidate in packages:
+            package = self._load_package(candidate)
+            if type_name not in package["types"]:
+                continue
+            for interface_name, ref in package["interfaces"].items():
+                required = _interface_method_set(package, interface_name)
+                # Method-less interfaces are satisfied by everything and say nothing useful
+                if not required:
+                    continue
+                pointer = _satisfies(package, type_name, required)
+                if pointer is not None:
+                    results.append(
+                        {"name": ref["name"], "file": ref["file"], "line": ref["line"], "pointer": pointer}
+                    )
+        return sorted(results, key=lambda r: (r["file"], r["line"]))
@@ -0,0 +1,223 @@
+"""A self-contained embedding index over file chunks, persisted as flat float32 vectors plus JSON metadata."""
+
+from __future__ import annotations
+import hashlib
+import json
+import math
+import re
+import sys
+import zlib
+from array import array
+from dataclasses import asdict, dataclass
+from pathlib import Path
+from typing import TYPE_CHECKING, Dict, List, Mapping, Protocol, Sequence, Tuple, runtime_checkable
+
+from .context_extractor import Chunk
+
+if TYPE_CHECKING:
+    from .repository import Repository
+
+VECTORS_FILE = "vectors.f32"
+METADATA_FILE = "index.json"
+FORMAT_VERSION = 1
+
+
+@runtime_checkable
+class Embedder(Protocol):
+    """Turns texts into vectors; wrap OpenAI, Ollama or a local model behind this."""
+
+    def embed(self, texts: List[str]) -> List[List[float]]:
+        """Returns one vector per text, all of the same length."""
+        ...
+
+
+_WORD = re.compile(r"[A-Za-z][a-z]*|[A-Z]+(?![a-z])|\d+")
+
+
+def _stem(word: str) -> str:
+    for suffix in ("ing", "ers", "er", "es", "ed", "s"):
+        if word.endswith(suffix) and len(word) - len(suffix) >= 3:
+            return word[: -len(suffix)]
+    return word
+
+
+class HashingEmbedder:
+    """
+    A deterministic bag-of-words embedder needing no model or network, for tests and offline use.
+
+    Identifiers are split at camelCase and underscores, words are crudely
+    stemmed (``greeting`` and ``Greeter`` both count as ``greet``) and hashed
+    into *dimensions* buckets. It matches shared vocabulary only, not meaning.
+    """
+
+    def __init__(self, dimensions: int = 256) -> None:
+        self.dimensions = dimensions
+
+    def embed(self, texts: List[str]) -> List[List[float]]:
+        vectors = []
+        for text in texts:
+            vector = [0.0] * self.dimensions
+            for word in _WORD.findall(text):
+                vector[zlib.crc32(_stem(word.lower()).encode("utf-8")) % self.dimensions] += 1.0
+            vectors.append(vector)
+        return vectors
+
+
+@dataclass(frozen=True)
+class VectorHit:
+    """A chunk matching a query, with its cosine similarity in ``score`` (1 is identical)."""
+
+    file: str
+    chunk: Chunk
+    score: float
+
+
+@dataclass(frozen=True)
+class _Entry:
+    file: str
+    chunk: Chunk
+    content_hash: str
+
+
+def _content_hash(code: str) -> str:
+    return hashlib.sha256(code.encode("utf-8")).hexdigest()
+
+
+def _normalized(vector: Sequence[float]) -> array:
+    norm = math.sqrt(sum(x * x for x in vector))
+    return array("f", (x / norm for x in vector) if norm else vector)
+
+
+class VectorIndex:
+    """
+    Chunks of a repository with their embeddings, searchable by cosine similarity.
+
+    Vectors are kept normalized, so a search is one dot product per chunk;
+    that is fast enough for a repository's worth of chunks without a vector
+    database. :meth:`update` re-embeds only chunks whose content changed.
+    """
+
+    def __init__(self, embedder: Embedder, batch_size: int = 64) -> None:
+        self.embedder = embedder
+        self.batch_size = batch_size
+        self._entries: List[_Entry] = []
+        self._vectors: List[array] = []
+        self.dimensions = 0
+
+    def __len__(self) -> int:
+        return len(self._entries)
+
+    def _embed(self, texts: List[str]) -> List[array]:
+        vectors = []
+        for start in range(0, len(texts), self.batch_size):
+            batch = texts[start : start + self.batch_size]
+            embedded = self.embedder.embed(batch)
+            if len(embedded) != len(batch):
+                raise ValueError(f"Embedder returned {len(embedded)} vectors for {len(batch)} texts")
+            vectors.extend(_normalized(v) for v in embedded)
+        for vector in vectors:
+            if self.dimensions and len(vector) != self.dimensions:
+                raise ValueError(f"Embedder returned a {len(vector)}-dimensional vector; the index holds {self.dimensions}")
+            self.dimensions = self.dimensions or len(vector)
+        return vectors
+
+    def update(self, chunks: Mapping[str, Sequence[Chunk]]) -> int:
+        """
+        Replaces the index contents with *chunks*, keyed by repository-relative file.
+
+        Chunks whose code hashes the same as one already indexed, in any file,
+        keep their vector; only new or changed code is sent to the embedder.
+
+        Returns:
+            The number of chunks that were embedded.
+        """
+        known: Dict[str, array] = {e.content_hash: v for e, v in zip(self._entries, self._vectors)}
+        entries = [
+            _Entry(file, chunk, _content_hash(chunk.code)) for file in sorted(chunks) for chunk in chunks[file]
+        ]
+        missing = list(dict.fromkeys(e.content_hash for e in entries if e.content_hash not in known))
+        code_by_hash = {e.content_hash: e.chunk.code for e in entries}
+        known.update(zip(missing, self._embed([code_by_hash[h] for h in missing])))
+        self._entries = entries
+        self._vectors = [known[e.content_hash] for e in entries]
+        return len(missing)
+
+    def index_repository(self, repo: "Repository", chunk_by: str = "symbols", max_tokens: int = 512) -> int:
+        """
+        Chunks every source file of *repo* and updates the index with them.
+
+        Args:
+            chunk_by: ``"symbols"`` for :meth:`Repository.chunk_file` or
+                ``"tokens"`` for :meth:`Repository.chunk_file_by_tokens`.
+            max_tokens: Token budget per chunk when chunking by tokens.
+
+        Returns:
+            The number of chunks that were embedded.
+        """
+        if chunk_by not in ("symbols", "tokens"):
+            raise ValueError(f"chunk_by must be 'symbols' or 'tokens', not {chunk_by!r}")
+        root = repo.mapper.repo_path
+        chunks: Dict[str, List[Chunk]] = {}
+        for file in repo.mapper.source_files():
+            path = file.relative_to(root).as_posix()
+            if chunk_by == "symbols":
+                chunks[path] = repo.chunk_file(path)
+            else:
+                chunks[path] = repo.chunk_file_by_tokens(path, max_tokens=max_tokens)
+        return self.update(chunks)
+
+    def search(self, query: str, k: int = 5) -> List[VectorHit]:
+        """The *k* chunks most similar to *query*, best first; ties go by file and line."""
+        if k <= 0 or not self._entries:
+            return []
+        query_vector = self._embed([query])[0]
+        scored: List[Tuple[float, int]] = []
+        for i, vector in enumerate(self._vectors):
+            scored.append((sum(a * b for a, b in zip(query_vector, vector)), i))
+        scored.sort(key=lambda s: (-s[0], self._entries[s[1]].file, self._entries[s[1]].chunk.start_line))
+        return [VectorHit(self._entries[i].file, self._entries[i].chunk, score) for score, i in scored[:k]]
+
+    def save(self, directory: str) -> None:
+        """Writes the vectors as little-endian float32s and the chunks as JSON into *directory*."""
+        target = Path(directory)
+        target.mkdir(parents=True, exist_ok=True)
+        flat = array("f")
+        for vector in self._vectors:
+            flat.extend(vector)
+        if sys.byteorder != "little":
+            flat.byteswap()
+        (target / VECTORS_FILE).write_bytes(flat.tobytes())
+        metadata = {
+            "version": FORMAT_VERSION,
+            "dimensions": self.dimensions,
+            "chunks": [{"file": e.file, "hash": e.content_hash, **asdict(e.chunk)} for e in self._entries],
+        }
+        (target / METADATA_FILE).write_text(json.dumps(metadata, indent=2), encoding="utf-8")
+
+    @classmethod
+    def load(cls, directory: str, embedder: Embedder, batch_size: int = 64) -> "VectorIndex":
+        """
+        Reads an index written by :meth:`save`. *embedder* must be the one it was built with.
+
+        Raises:
+            ValueError: If the files are missing pieces or from another format version.
+        """
+        source = Path(directory)
+        metadata = json.loads((source / METADATA_FILE).read_text(encoding="utf-8"))
+        if metadata.get("version") != FORMAT_VERSION:
+            raise ValueError(f"Unsupported vector index version {metadata.get('version')} in {directory}")
+        flat = array("f")
+        flat.frombytes((source / VECTORS_FILE).read_bytes())
+        if sys.byteorder != "little":
+            flat.byteswap()
+        dimensions, entries = metadata["dimensions"], metadata["chunks"]
+        if len(flat) != dimensions * len(entries):
+            raise ValueError(f"{VECTORS_FILE} in {directory} does not match {METADATA_FILE}")
+
+        index = cls(embedder, batch_size)
+        index.dimensions = dimensions
+        for i, entry in enumerate(entries):
+            file, digest = entry.pop("file"), entry.pop("hash")
+            index._entries.append(_Entry(file, Chunk(**entry), digest))
+            index._vectors.append(flat[i * dimensions : (i + 1) * dimensions])
+        return index
@@ -10,6 +10,20 @@ except ImportError:
 from pathlib import Path
 
 
+def _scalar_metadata(metadata: Dict[str, Any]) -> Dict[str, Any]:
+    """
+    Vector DB metadata only accepts scalar values; list values are joined into
+    strings and any other non-scalar values (None, nested dicts) are dropped.
+    """
+    flat: Dict[str, Any] = {}
+    for key, value in metadata.items():
+        if isinstance(value, (str, int, float, bool)):
+            flat[key] = value
+        elif isinstance(value, (list, tuple)) and all(isinstance(v, str) for v in value):
+            flat[key] = ", ".join(value)
+    return flat
+
+
 class VectorDBBackend:
     """
     Abstract vector DB interface for pluggable backends.
@@ -128,7 +142,7 @@ class VectorSearcher:
                 chunks = self.repo.chunk_file_by_symbols(path)
                 for chunk in chunks:
                     code = chunk["code"]
-                    self.chunk_metadatas.append({"file": path, **chunk})
+                    self.chunk_metadatas.append(_scalar_metadata({"file": path, **chunk}))
                     chunk_codes.append(code)
             else:
                 chunks = self.repo.chunk_file_by_lines(path, max_lines=50)
@@ -0,0 +1,251 @@
+"""Keeps a SymbolIndex in sync with the files of a repository as they change."""
+
+from __future__ import annotations
+import logging
+import queue
+import threading
+import time
+from dataclasses import dataclass, field
+from typing import TYPE_CHECKING, Any, Callable, Dict, List, Optional, Tuple
+
+if TYPE_CHECKING:
+    from .repository import Repository
+    from .symbol_index import SymbolIndex
+
+logger = logging.getLogger(__name__)
+
+# (mtime_ns, size) of a file; a change in either marks the file as modified
+_Stamp = Tuple[int, int]
+
+
+@dataclass(frozen=True)
+class IndexEvent:
+    """
+    One file whose symbols changed in the index.
+
+    Attributes:
+        path: Repository-relative path.
+        op: ``"added"``, ``"changed"`` or ``"removed"``. A rename is reported as
+            a removal of the old path and an addition of the new one.
+        symbols: The file's symbols after the change; empty when removed.
+    """
+
+    path: str
+    op: str
+    symbols: List[Dict[str, Any]] = field(default_factory=list)
+
+
+def diff_symbols(before: List[Dict[str, Any]], after: List[Dict[str, Any]]) -> Dict[str, List[Dict[str, Any]]]:
+    """
+    Compares one file's symbols before and after a change.
+
+    Symbols are matched by type and qualified name (``User.Greet``); a matched
+    symbol counts as changed when its code differs, so edits that only move it
+    are not reported. Returns ``{"added": [...], "removed": [...], "changed": [...]}``
+    with ``changed`` holding the new versions.
+    """
+
+    def keyed(symbols: List[Dict[str, Any]]) -> Dict[Tuple[str, str, int], Dict[str, Any]]:
+        result: Dict[Tuple[str, str, int], Dict[str, Any]] = {}
+        for symbol in symbols:
+            base = (symbol.get("type", ""), symbol.get("node_path") or symbol["name"])
+            # Repeated names such as Go init functions pair up in order
+            ordinal = sum(1 for key in result if key[:2] == base)
+            result[base + (ordinal,)] = symbol
+        return result
+
+    old, new = keyed(before), keyed(after)
+    return {
+        "added": [s for key, s in new.items() if key not in old],
+        "removed": [s for key, s in old.items() if key not in new],
+        "changed": [s for key, s in new.items() if key in old and old[key].get("code") != s.get("code")],
+    }
+
+
+class Watcher:
+    """
+    Watches a repository for changed source files and feeds them into a :class:`SymbolIndex`.
+
+    The background thread sleeps until the file system reports a change,
+    through watchfiles (inotify, FSEvents or ReadDirectoryChangesW), and only
+    then scans the tree. Without watchfiles, or if the OS refuses to watch the
+    tree, it falls back to scanning every *poll_interval* seconds.
+
+    A scan walks the same tree as :meth:`Repository.parse_directory`, so
+    ignored paths (``.git``, ``node_modules``, .gitignore matches) never produce
+    events and directories created after the watch started are picked up.
+    Changes are debounced: a batch is indexed only once no file has changed
+    for *debounce* seconds, which folds a burst of saves, or an editor writing
+    a temporary file and renaming it over the original, into a single event
+    per path.
+
+    Events are delivered to *callback* if one is given, and always put on
+    :attr:`events`.
+    """
+
+    def __init__(
+        self,
+        repository: "Repository",
+        index: Optional["SymbolIndex"] = None,
+        callback: Optional[Callable[[IndexEvent], None]] = None,
+        debounce: float = 0.2,
+        poll_interval: float = 2.0,
+        use_events: bool = True,
+    ) -> None:
+        """
+        Args:
+            repository: Repository to watch.
+            index: Index to keep up to date. Defaults to :meth:`Repository.get_symbol_index`.
+            callback: Called from the watcher thread with each :class:`IndexEvent`.
+            debounce: Seconds without further changes before a batch is indexed.
+            poll_interval: Seconds between scans of the tree when file system events are unavailable.
+            use_events: Wait for file system events; False always polls.
+        """
+        self.repo = repository
+        self.index = index if index is not None else repository.get_symbol_index()
+        self.callback = callback
+        self.debounce = debounce
+        self.poll_interval = poll_interval
+        self.use_events = use_events
+        self.events: "queue.Queue[IndexEvent]" = queue.Queue()
+
+        self._committed: Dict[str, _Stamp] = {}
+        self._observed: Dict[str, _Stamp] = {}
+        self._last_change: Optional[float] = None
+        self._stop = threading.Event()
+        # Set by the observer on any file system event, and by stop()
+        self._wake = threading.Event()
+        self._observer: Optional[threading.Thread] = None
+        self._started = 0.0
+        self._thread: Optional[threading.Thread] = None
+
+    def _snapshot(self) -> Dict[str, _Stamp]:
+        mapper = self.repo.mapper
+        snapshot: Dict[str, _Stamp] = {}
+        for file in mapper.source_files():
+            try:
+                stat = file.stat()
+            except OSError:
+                # Deleted between the walk and the stat
+                continue
+            snapshot[file.relative_to(mapper.repo_path).as_posix()] = (stat.st_mtime_ns, stat.st_size)
+        return snapshot
+
+    def sync(self) -> None:
+        """Brings the index up to date with the whole tree without emitting events; called by :meth:`start`."""
+        self._committed = self._observed = self._snapshot()
+        self._last_change = None
+        self.index.update(self.repo)
+
+    def poll(self, now: Optional[float] = None) -> List[IndexEvent]:
+        """
+        Scans the tree once and indexes the pending batch if it has settled.
+
+        The background thread calls this in a loop; it is public so callers can
+        drive the watcher from their own loop instead. Returns the emitted events.
+        """
+        now = time.monotonic() if now is None else now
+        current = self._snapshot()
+        if current != self._observed:
+            self._observed = current
+            self._last_change = now
+            return []
+        if self._last_change is None or now - self._last_change < self.debounce:
+            return []
+
+        # Paths that appeared, disappeared or changed stamp since the last indexed batch
+        paths = [p for p in set(current) | set(self._committed) if current.get(p) != self._committed.get(p)]
+        self._committed = current
+        self._last_change = None
+        changes = self.index.update_paths(self.repo, paths)
+
+        emitted = []
+        for op in ("removed", "changed", "added"):
+            for path in changes[op]:
+                symbols = [] if op == "removed" else self.index.entry_symbols(path)
+                emitted.append(IndexEvent(path, op, symbols))
+        for event in emitted:
+            if self.callback is not None:
+                try:
+                    self.callback(event)
+                except Exception as e:
+                    logger.warning(f"Watcher callback failed for {event.path}: {e}", exc_info=True)
+            self.events.put(event)
+        return emitted
+
+    def _start_observer(self) -> Optional[threading.Thread]:
+        """Starts a thread that sets :attr:`_wake` on every file system event, or returns None if it can't."""
+        try:
+            from watchfiles import watch
+        except ImportError:
+            logger.info("watchfiles is not installed; polling for changes")
+            return None
+
+        def observe() -> None:
+            try:
+                # The default filter already leaves out .git, node_modules and editor swap files
+                for _ in watch(self.repo.mapper.repo_path, stop_event=self._stop, debounce=50, step=50):
+                    self._wake.set()
+            except Exception as e:
+                # Such as running out of inotify watches on a large tree
+                logger.warning(f"Watching for file system events failed, polling instead: {e}")
+                self._observer = None
+                self._wake.set()
+
+        observer = threading.Thread(target=observe, name="codekite-watcher-events", daemon=True)
+        observer.start()
+        return observer
+
+    def _run(self) -> None:
+        while not self._stop.is_set():
+            # Cleared before the scan, so an event arriving during it wakes the next one
+            self._wake.clear()
+            try:
+                self.poll()
+            except Exception as e:
+                logger.warning(f"Watcher poll failed: {e}", exc_info=True)
+            if self._last_change is not None:
+                # A batch is pending: look again once it may have settled
+                timeout: Optional[float] = max(self.debounce - (time.monotonic() - self._last_change), 0.0)
+            elif self._observer is None or time.monotonic() - self._started < self.poll_interval:
+                # Events are missed until the OS watch is set up, so rescan once more after starting
+                timeout = self.poll_interval
+            else:
+                timeout = None
+            self._wake.wait(timeout)
+
+    def start(self) -> "Watcher":
+        """Syncs the index and starts watching on a daemon thread."""
+        if self._thread is not None:
+            return self
+        self._stop.clear()
+        self._wake.clear()
+        # Started before the sync, so changes made during it are not missed
+        self._observer = self._start_observer() if self.use_events else None
+        self._started = time.monotonic()
+        try:
+            self.sync()
+        except Exception:
+            self.stop()
+            raise
+        self._thread = threading.Thread(target=self._run, name="codekite-watcher", daemon=True)
+        self._thread.start()
+        return self
+
+    def stop(self) -> None:
+        """Stops the watcher thread; pending changes that have not settled are not indexed."""
+        self._stop.set()
+        self._wake.set()
+        if self._thread is not None:
+            self._thread.join()
+            self._thread = None
+        observer, self._observer = self._observer, None
+        if observer is not None:
+            # watchfiles checks the stop event between its 50 ms steps
+            observer.join()
+
+    def __enter__(self) -> "Watcher":
+        return self.start()
+
+    def __exit__(self, *exc_info: Any) -> None:
+        self.stop()
@@ -0,0 +1,7 @@
+def pytest_addoption(parser):
+    parser.addoption(
+        "--update-golden",
+        action="store_true",
+        default=False,
+        help="Rewrite the .golden.json files under tests/fixtures/<lang>/ from the current extraction output.",
+    )
@@ -0,0 +1,31 @@
+"""A deterministic stand-in for an LLM, for testing summarization offline."""
+
+import hashlib
+import threading
+
+
+class FakeLLMClient:
+    """
+    Answers every prompt with ``summary-<first 8 hex digits of its SHA-256>``.
+
+    The same prompt always gets the same answer and different prompts
+    practically never do, so a test can tell which summary came from which
+    prompt. Prompts are recorded in ``prompts``; one containing *fail_on*
+    raises instead.
+    """
+
+    def __init__(self, fail_on=None):
+        self.fail_on = fail_on
+        self.prompts = []
+        self._lock = threading.Lock()
+
+    @staticmethod
+    def answer(prompt):
+        return "summary-" + hashlib.sha256(prompt.encode("utf-8")).hexdigest()[:8]
+
+    def complete(self, prompt):
+        with self._lock:
+            self.prompts.append(prompt)
+        if self.fail_on is not None and self.fail_on in prompt:
+            raise RuntimeError("model unavailable")
+        return self.answer(prompt)
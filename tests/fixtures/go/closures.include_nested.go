@@ -0,0 +1,27 @@
+package main
+
+import "fmt"
+
+type Server struct{}
+
+func (s *Server) Start(ports []int) {
+	for _, p := range ports {
+		go func(port int) {
+			fmt.Println(port)
+		}(p)
+	}
+}
+
+func main() {
+	add := func(a, b int) int { return a + b }
+	apply := func(f func() error) error {
+		return f()
+	}
+	_ = apply(func() error {
+		defer func() {
+			recover()
+		}()
+		return nil
+	})
+	fmt.Println(add(1, 2))
+}
@@ -0,0 +1,34 @@
+package named
+
+import "net/http"
+
+// UserID identifies a user.
+type UserID int
+
+// String formats the ID for logs.
+func (id UserID) String() string {
+	return "user"
+}
+
+// Handler serves one request.
+type Handler func(w http.ResponseWriter, r *http.Request)
+
+// ServeHTTP calls h itself.
+func (h Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h(w, r)
+}
+
+// OldName is what Alias used to be called.
+type OldName struct {
+	ID UserID
+}
+
+// Alias keeps callers of the old name compiling.
+type Alias = OldName
+
+type (
+	// Celsius is a temperature.
+	Celsius float64
+	Set[T comparable] map[T]struct{}
+	Strings = []string
+)
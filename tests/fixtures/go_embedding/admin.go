@@ -0,0 +1,24 @@
+package embedding
+
+import "sync"
+
+type Audit struct {
+	ID      int
+	Created string
+}
+
+type Profile struct {
+	Name string
+	Bio  string
+}
+
+// Admin reaches Account's members through User, and gets Name from both User and Profile.
+type Admin struct {
+	*User
+	Audit
+	Profile
+	sync.Mutex
+	Level int
+}
+
+func (a Admin) Promote() { a.Level++ }
@@ -0,0 +1,35 @@
+package embedding
+
+import "fmt"
+
+type Greeter interface {
+	Greet() string
+}
+
+type Named interface {
+	Name() string
+}
+
+type Person interface {
+	Greeter
+	Named
+	fmt.Stringer
+}
+
+// Member embeds Greeter through Person.
+type Member interface {
+	Person
+	Login() bool
+}
+
+// Friendly repeats Greet with the same signature, which is allowed.
+type Friendly interface {
+	Greeter
+	Greet() string
+}
+
+// Shouter redeclares Greet with another signature.
+type Shouter interface {
+	Greeter
+	Greet() error
+}
@@ -0,0 +1,18 @@
+package embedding
+
+// Account is embedded two levels below Admin.
+type Account struct {
+	ID    int
+	Email string
+}
+
+func (a Account) Login() bool { return a.ID != 0 }
+
+func (a *Account) Lock() {}
+
+type User struct {
+	Account
+	Name string
+}
+
+func (u User) Greet() string { return "hi " + u.Name }
@@ -0,0 +1,5 @@
+//go:build (linux || darwin) && !purego
+
+package platform
+
+func checksum(b []byte) uint32 { return 0 }
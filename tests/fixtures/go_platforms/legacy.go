@@ -0,0 +1,6 @@
+// +build linux,amd64 windows
+// +build !integration
+
+package platform
+
+func legacy() {}
@@ -0,0 +1,4 @@
+package platform
+
+// Name reports the platform the binary was built for.
+func Name() string { return name() }
@@ -0,0 +1,12 @@
+// versions:
+// 	protoc-gen-go v1.34.1
+// 	protoc        v5.27.0
+// source: api/user.proto
+
+package api
+
+type User struct {
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+}
+
+func (x *User) GetName() string { return x.Name }
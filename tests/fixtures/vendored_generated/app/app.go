@@ -0,0 +1,12 @@
+package app
+
+// Color is a palette entry.
+type Color int
+
+const (
+	Red Color = iota
+	Green
+)
+
+// Render describes c.
+func Render(c Color) string { return "color " + c.String() }
@@ -0,0 +1,4 @@
+package semver
+
+// Compare orders two versions.
+func Compare(a, b string) int { return 0 }
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// colors are the names gen writes a String method for.
+var colors = []string{
+	"Red",
+	"Orange",
+	"Yellow",
+	"Green",
+	"Teal",
+	"Blue",
+	"Indigo",
+	"Violet",
+	"Pink",
+	"Brown",
+	"Black",
+	"White",
+	"Grey",
+	"Silver",
+	"Gold",
+	"Navy",
+	"Olive",
+	"Maroon",
+	"Lime",
+	"Cyan",
+	"Magenta",
+	"Beige",
+	"Coral",
+	"Khaki",
+	"Plum",
+}
+
+func main() {
+	header(os.Stdout)
+	for _, c := range colors {
+		fmt.Println(c)
+	}
+}
+
+// header writes the "// Code generated by gen. DO NOT EDIT." line tools look for.
+func header(w io.Writer) {
+	fmt.Fprintln(w, "// Code generated by gen. DO NOT EDIT.")
+}
@@ -0,0 +1,3 @@
+function mount(root) {
+  return root;
+}
@@ -0,0 +1 @@
+function a(b){return b}function c(d){return a(d)}
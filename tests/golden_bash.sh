@@ -0,0 +1,42 @@
+#!/usr/bin/env bash
+# Deploys the shop to one environment.
+set -euo pipefail
+
+# Where the release is unpacked.
+DEPLOY_ROOT="/srv/shop"
+export ENVIRONMENT="${ENVIRONMENT:-staging}"
+readonly MAX_RETRIES=3
+declare -a HOSTS=(web1 web2)
+
+# Prints a message with a timestamp.
+#   log "starting"
+log() {
+  local stamp
+  stamp="$(date +%H:%M:%S)"
+  echo "[$stamp] $*"
+}
+
+# Copies the release to every host, retrying each one.
+function deploy {
+  for host in "${HOSTS[@]}"; do
+    attempt=0
+    until scp -r release "$host:$DEPLOY_ROOT"; do
+      attempt=$((attempt + 1))
+      [ "$attempt" -ge "$MAX_RETRIES" ] && return 1
+    done
+  done
+}
+
+function rollback() {
+  log "rolling back"
+}
+
+# shellcheck disable=SC2317
+cleanup() { rm -rf release; }
+
+if [ "$ENVIRONMENT" = "production" ]; then
+  CONFIRM=yes
+fi
+
+trap cleanup EXIT
+deploy || rollback
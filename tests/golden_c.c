@@ -0,0 +1,39 @@
+#include <stdio.h>
+#include "golden_c.h"
+
+static int calibrated;
+
+/* Not a doc comment, so not a docstring. */
+static int16_t
+apply_offset(int16_t value)
+{
+    return value + (calibrated ? 2 : 0);
+}
+
+int
+sensor_read(Sensor *sensor, struct reading *out)
+{
+    *out = sensor->buffer[0];
+    out->value = apply_offset(out->value);
+    return 0;
+}
+
+const char *sensor_name(const Sensor *sensor)
+{
+    return sensor->id ? "external" : "internal";
+}
+
+#ifdef SENSOR_DEBUG
+void sensor_dump(const Sensor *sensor)
+{
+    printf("%s\n", sensor_name(sensor));
+}
+#endif
+
+/** Old-style definition kept for the bootloader build. */
+int
+sensor_legacy_id(sensor)
+    const Sensor *sensor;
+{
+    return sensor->id;
+}
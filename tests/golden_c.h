@@ -0,0 +1,46 @@
+#ifndef SENSOR_H
+#define SENSOR_H
+
+#include <stdint.h>
+
+/** Most readings a sensor buffers before they are dropped. */
+#define SENSOR_BUFFER 32
+
+/** Keeps a value within [lo, hi]. */
+#define CLAMP(x, lo, hi) ((x) < (lo) ? (lo) : ((x) > (hi) ? (hi) : (x)))
+
+/**
+ * One sample from a sensor.
+ *
+ * Timestamps are milliseconds since boot.
+ */
+struct reading {
+    uint32_t timestamp;
+    int16_t value;
+};
+
+typedef struct {
+    uint8_t id;
+    struct reading buffer[SENSOR_BUFFER];
+} Sensor;
+
+typedef struct calibration {
+    int16_t offset;
+} Calibration;
+
+typedef uint16_t sensor_id;
+
+enum sensor_state { SENSOR_IDLE, SENSOR_BUSY };
+
+/** Reads one sample; returns 0 on success. */
+int sensor_read(Sensor *sensor, struct reading *out);
+
+const char *sensor_name(const Sensor *sensor);
+
+#ifdef SENSOR_DEBUG
+void sensor_dump(const Sensor *sensor);
+#else
+#define sensor_dump(sensor) ((void)0)
+#endif
+
+#endif /* SENSOR_H */
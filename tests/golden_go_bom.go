@@ -0,0 +1,11 @@
+﻿package shop
+
+// Café is a coffee shop.
+type Café struct {
+	Name string
+}
+
+// Open opens the shop.
+func (c *Café) Open() string {
+	return "ouvert"
+}
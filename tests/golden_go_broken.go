@@ -0,0 +1,27 @@
+package cart
+
+// Item is one line of a cart.
+type Item struct {
+	Name  string
+	Price int
+}
+
+// Total adds up the prices.
+func Total(items []Item) int {
+	sum := 0
+	for _, item := range items {
+		sum += item.Price
+	}
+	return sum
+}
+
+// Discount is being edited: its body does not parse yet.
+func Discount(items []Item, percent int) int {
+	total := Total(items) *
+	return total / 100
+}
+
+// Count still comes after the broken function.
+func Count(items []Item) int {
+	return len(items)
+}
@@ -0,0 +1,56 @@
+package generics
+
+// Number is satisfied by the built-in numeric types.
+type Number interface {
+	~int | ~int64 | ~float64
+}
+
+// Set is an unordered collection of unique values.
+type Set[T comparable] struct {
+	items map[T]struct{}
+}
+
+// Pair holds two values of possibly different types.
+type Pair[K comparable, V any] struct {
+	Key   K
+	Value V
+}
+
+// Container is implemented by generic collections.
+type Container[T any] interface {
+	Add(item T)
+	Len() int
+}
+
+// Add inserts item into the set.
+func (s *Set[T]) Add(item T) {
+	s.items[item] = struct{}{}
+}
+
+// Len reports the number of items in the set.
+func (s Set[T]) Len() int {
+	return len(s.items)
+}
+
+// Map applies f to every element of in.
+func Map[T any, U any](in []T, f func(T) U) []U {
+	out := make([]U, 0, len(in))
+	for _, v := range in {
+		out = append(out, f(v))
+	}
+	return out
+}
+
+// Sum adds up a slice of numbers.
+func Sum[T Number](values []T) T {
+	var total T
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
+
+// Zip pairs up keys and values that share a type parameter list.
+func Zip[K, V any](keys []K, values []V) []Pair[K, V] {
+	return nil
+}
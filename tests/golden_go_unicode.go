@@ -0,0 +1,15 @@
+package grüße
+
+// Größe gibt die Größe in Bytes zurück — mit Umlauten.
+func Größe(wert string) int {
+	return len(wert) // Zählt Bytes, nicht Runen
+}
+
+// 面积 calculates an area (CJK identifier).
+func 面积(w, h float64) float64 {
+	return w * h
+}
+
+type Zähler struct {
+	Wert int
+}
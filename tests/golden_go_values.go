@@ -0,0 +1,47 @@
+package values
+
+import (
+	"errors"
+	"time"
+)
+
+// MaxRetries bounds how often a request is retried.
+const MaxRetries = 3
+
+// Timeout is the default request timeout.
+const Timeout time.Duration = 5 * time.Second
+
+// Kind enumerates node kinds.
+type Kind int
+
+const (
+	// KindFile is a regular file.
+	KindFile Kind = iota
+	KindDir
+	_
+	KindLink
+)
+
+// Size units.
+const (
+	_  = iota
+	KB = 1 << (10 * iota)
+	MB
+)
+
+// ErrNotFound is returned when a key is missing.
+var ErrNotFound = errors.New("not found")
+
+var x, y = 1, 2
+
+var (
+	// DefaultName is used when no name is configured.
+	DefaultName string = "codekite"
+	_           = errors.New("unused")
+)
+
+func local() {
+	const inner = 1
+	var shadow = 2
+	_, _ = inner, shadow
+}
@@ -0,0 +1,89 @@
+package com.example.shop;
+
+import java.util.ArrayList;
+import java.util.List;
+
+/**
+ * A shopping cart holding line items.
+ *
+ * Carts are not thread-safe.
+ */
+public class Cart implements Priced {
+    /** Most items a cart may hold. */
+    public static final int MAX_ITEMS = 100;
+
+    private final List<Item> items = new ArrayList<>();
+    int version, revision;
+
+    /** Creates an empty cart. */
+    public Cart() {}
+
+    /**
+     * Adds an item to the cart.
+     *
+     * @param item the item to add
+     */
+    public void add(Item item) {
+        items.add(item);
+    }
+
+    @Override
+    public long total() {
+        long sum = 0;
+        for (Item item : items) {
+            sum += item.price();
+        }
+        return sum;
+    }
+
+    @Deprecated
+    @SuppressWarnings("unchecked")
+    protected static List<Item> copy(List<Item> source) {
+        return new ArrayList<>(source);
+    }
+
+    private void reset() {
+        items.clear();
+    }
+
+    /** One entry in a cart. */
+    public static class Item {
+        private final long price;
+
+        Item(long price) {
+            this.price = price;
+        }
+
+        public long price() {
+            return price;
+        }
+    }
+
+    private class Audit {
+        public void record() {}
+    }
+
+    /** Notified whenever the cart changes. */
+    public interface Listener {
+        void onChange(Cart cart);
+    }
+}
+
+/** Anything with a price in cents. */
+interface Priced {
+    /** Currency of every price. */
+    String CURRENCY = "EUR";
+
+    /** The price in cents. */
+    long total();
+}
+
+/** How an order ships. */
+enum Shipping {
+    STANDARD,
+    EXPRESS;
+
+    public boolean isFast() {
+        return this == EXPRESS;
+    }
+}
@@ -0,0 +1,78 @@
+package shop.types;
+
+import java.util.Comparator;
+import java.util.List;
+
+/**
+ * A point on the warehouse floor.
+ *
+ * @param x metres from the west wall
+ * @param y metres from the south wall
+ */
+public record Point(int x, int y) {
+    /** Rejects points outside the floor. */
+    public Point {
+        if (x < 0 || y < 0) {
+            throw new IllegalArgumentException("off the floor");
+        }
+    }
+
+    public double distanceTo(Point other) {
+        return Math.hypot(x - other.x, y - other.y);
+    }
+}
+
+/** Sizes a parcel can ship in. */
+enum Size {
+    SMALL(1), LARGE(5);
+
+    private final int weight;
+
+    Size(int weight) {
+        this.weight = weight;
+    }
+
+    /**
+     * Weight in kilograms,
+     * rounded up.
+     */
+    public int weight() {
+        return weight;
+    }
+}
+
+/** Marks a handler for audit logging. */
+@interface Audited {
+    String value() default "";
+}
+
+@FunctionalInterface
+public interface Handler<E> {
+    void handle(E event);
+}
+
+/** Keeps items ordered by a key. */
+public class Shelf<T extends Comparable<T>> {
+    private final List<T> items;
+
+    public Shelf(List<T> items) {
+        this.items = items;
+    }
+
+    public <K> void sortBy(Comparator<K> key) {
+        items.sort(new Comparator<T>() {
+            @Override
+            public int compare(T a, T b) {
+                return a.compareTo(b);
+            }
+        });
+    }
+
+    public Runnable printer() {
+        return new Runnable() {
+            public void run() {
+                System.out.println(items);
+            }
+        };
+    }
+}
@@ -1,4 +1,5 @@
 import asyncio # Need this for the async function example
+from dataclasses import dataclass
 
 def top_level_function(arg1, arg2):
     """A regular function."""
@@ -13,9 +14,29 @@ class MyClass:
         """A method within the class."""
         return self.value + param
 
+    @property
+    def doubled(self) -> int:
+        """Twice the value."""
+        return self.value * 2
+
+    @staticmethod
+    def create(value: int = 0) -> "MyClass":
+        return MyClass(value)
+
 async def async_function():
     """An asynchronous function."""
     await asyncio.sleep(1)
 
+@dataclass
+class Point:
+    """A point in 2D space."""
+    x: float
+    y: float = 0.0
+
+    def distance(self) -> float:
+        def square(v: float) -> float:
+            return v * v
+        return (square(self.x) + square(self.y)) ** 0.5
+
 # A top-level variable (currently not expected to be captured by basic query)
 CONSTANT_VALUE = 100
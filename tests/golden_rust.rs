@@ -18,3 +18,73 @@ pub trait MyTrait {
 }
 
 fn free_function() {}
+
+/// Maximum number of retries.
+pub const MAX_RETRIES: u32 = 3;
+
+static GREETING: &str = "hello";
+
+/// A wrapper around any value.
+///
+/// Wrappers are cheap to clone.
+#[derive(Debug, Clone)]
+pub struct Wrapper<T> {
+    value: T,
+}
+
+impl<T: Clone> Wrapper<T> {
+    /// Returns a copy of the wrapped value.
+    pub fn get(&self) -> T {
+        self.value.clone()
+    }
+}
+
+/// Something that can greet.
+pub trait Greeter {
+    fn name(&self) -> String;
+
+    /// Greets using the name; implementors usually keep this default.
+    fn greet(&self) -> String {
+        format!("Hello, {}", self.name())
+    }
+}
+
+// Not a doc comment.
+impl Greeter for Foo {
+    fn name(&self) -> String {
+        String::from("foo")
+    }
+}
+
+pub type Result<T> = std::result::Result<T, String>;
+
+pub mod util {
+    //! Helpers shared across the crate.
+
+    pub fn helper() {
+        fn inner() {}
+        inner()
+    }
+}
+
+macro_rules! square {
+    ($x:expr) => {
+        $x * $x
+    };
+}
+
+/// Storage backend.
+pub trait Store: Send + Sync {
+    /// Loads a value.
+    fn load(&self, key: &str) -> Option<String>;
+
+    fn exists(&self, key: &str) -> bool {
+        self.load(key).is_some()
+    }
+}
+
+pub(crate) struct Internal;
+
+impl Internal {
+    fn reset(&mut self) {}
+}
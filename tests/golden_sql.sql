@@ -0,0 +1,39 @@
+-- Schema for the shop database.
+
+SET search_path TO public;
+
+-- Customers who can place orders.
+-- Emails are unique across the shop.
+CREATE TABLE IF NOT EXISTS public.users (
+    id serial PRIMARY KEY,
+    email text NOT NULL UNIQUE,
+    "display name" varchar(80) DEFAULT 'anonymous; unnamed',
+    created_at timestamptz NOT NULL DEFAULT now(), -- when they signed up
+    CONSTRAINT email_lower CHECK (email = lower(email))
+);
+
+/* Not a doc comment */
+CREATE OR REPLACE VIEW active_users AS
+    SELECT id, email FROM public.users WHERE created_at > now() - interval '30 days';
+
+-- Total of a user's orders.
+CREATE FUNCTION order_total(user_id integer, since date DEFAULT NULL)
+RETURNS numeric
+LANGUAGE plpgsql
+AS $$
+DECLARE
+    total numeric;
+BEGIN
+    -- CREATE TABLE inside a body is not a symbol;
+    SELECT sum(amount) INTO total FROM orders WHERE orders.user_id = order_total.user_id;
+    RETURN total;
+END;
+$$;
+
+CREATE PROCEDURE archive_orders(before date)
+BEGIN ATOMIC
+    INSERT INTO archived_orders SELECT * FROM orders WHERE placed_at < before;
+    DELETE FROM orders WHERE placed_at < before;
+END;
+
+CREATE MATERIALIZED VIEW order_stats AS SELECT count(*) FROM orders;
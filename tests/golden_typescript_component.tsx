@@ -0,0 +1,47 @@
+// Golden TSX file for symbol extraction tests
+import React, { useState } from "react";
+
+/** Props accepted by the Button component. */
+export interface ButtonProps {
+    label: string;
+    onClick?: () => void;
+}
+
+export type Variant = "primary" | "secondary";
+
+export enum Size {
+    Small,
+    Large,
+}
+
+/** A clickable button. */
+export const Button = ({ label, onClick }: ButtonProps) => {
+    return <button onClick={onClick}>{label}</button>;
+};
+
+function Badge<T extends string>({ text }: { text: T }) {
+    return <span className="badge">{text}</span>;
+}
+
+export class Counter extends React.Component<{}, { count: number }> {
+    state = { count: 0 };
+
+    increment() {
+        this.setState({ count: this.state.count + 1 });
+    }
+
+    render() {
+        return (
+            <div>
+                <Badge text="count" />
+                <button onClick={() => this.increment()}>{this.state.count}</button>
+            </div>
+        );
+    }
+}
+
+/** The page rendered by default. */
+export default () => {
+    const [open, setOpen] = useState(false);
+    return <Button label={open ? "Close" : "Open"} onClick={() => setOpen(!open)} />;
+};
@@ -0,0 +1,54 @@
+// Golden TypeScript file for export and JSDoc extraction tests
+
+/**
+ * A user of the application.
+ */
+export interface User {
+    id: number;
+    name: string;
+}
+
+/** Identifier type for users. */
+export type UserId = number;
+
+type InternalState = "idle" | "busy";
+
+/**
+ * Greets users by name.
+ *
+ * @example
+ *   new Greeter("Hi").greet(user)
+ */
+export class Greeter {
+    constructor(private greeting: string) {}
+
+    /** Builds a greeting for the given user. */
+    greet(user: User): string {
+        return `${this.greeting}, ${user.name}`;
+    }
+}
+
+class Cache {
+    get(key: string): string | undefined {
+        return undefined;
+    }
+}
+
+/** Formats a user for display. */
+export const formatUser = (user: User): string => {
+    return `${user.id}: ${user.name}`;
+};
+
+const slugify = (s: string) => s.toLowerCase();
+
+export const parse = function (raw: string): User {
+    return JSON.parse(raw);
+};
+
+// A plain line comment is not JSDoc.
+export function loadUsers(): User[] {
+    const local = () => [];
+    return local();
+}
+
+function helper() {}
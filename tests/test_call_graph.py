@@ -0,0 +1,121 @@
+import os
+import tempfile
+
+from codekite import Repository
+
+
+def write_files(tmpdir, files):
+    for rel_path, content in files.items():
+        path = os.path.join(tmpdir, rel_path)
+        os.makedirs(os.path.dirname(path), exist_ok=True)
+        with open(path, "w") as f:
+            f.write(content)
+
+
+def test_golden_go_call_graph():
+    golden_content = open(os.path.join(os.path.dirname(__file__), "fixtures", "go", "golden_go.go")).read()
+    with tempfile.TemporaryDirectory() as tmpdir:
+        write_files(tmpdir, {"golden_go.go": golden_content})
+        repo = Repository(tmpdir)
+        graph = repo.get_call_graph()
+
+        assert graph.callees_of("main") == ["Add", "HelperFunction", "User.Greet", "fmt.Println"]
+        assert graph.callees_of("User.Greet") == ["fmt.Sprintf"]
+        assert graph.callers_of("Add") == ["main"]
+        assert graph.node("fmt.Println")["kind"] == "external"
+        assert graph.node("User.Greet") == {"name": "User.Greet", "kind": "method", "file": "golden_go.go", "line": 16}
+
+        # Symbols from extract_symbols work as well as node names
+        greet = next(s for s in repo.extract_symbols("golden_go.go") if s["name"] == "Greet" and s["type"] == "method")
+        assert graph.callers_of(greet) == ["main"]
+
+
+def test_call_graph_resolves_packages_methods_and_interfaces():
+    files = {
+        "go.mod": "module example.com/app\n",
+        "store/store.go": """package store
+
+type Store struct{}
+
+type Backend interface {
+	Load(key string) string
+}
+
+func New() *Store { return &Store{} }
+
+func (s *Store) Get(b Backend, key string) string {
+	return s.normalize(b.Load(key))
+}
+
+func (s *Store) normalize(v string) string { return v }
+""",
+        "main.go": """package main
+
+import (
+	"strings"
+
+	st "example.com/app/store"
+)
+
+func main() {
+	s := st.New()
+	var t st.Store
+	_ = t
+	handler := func() {}
+	handler()
+	println(strings.ToUpper(s.Get(nil, "k")), len("x"), string(rune(1)))
+	even(3)
+}
+
+func even(n int) bool {
+	if n == 0 {
+		return true
+	}
+	return odd(n - 1)
+}
+
+func odd(n int) bool { return !even(n - 1) }
+""",
+    }
+    with tempfile.TemporaryDirectory() as tmpdir:
+        write_files(tmpdir, files)
+        graph = Repository(tmpdir).get_call_graph()
+
+        app, store = "example.com/app", "example.com/app/store"
+        # s.Get is through a value from another package, so it is left unresolved
+        assert graph.callees_of(f"{app}.main") == [
+            "?.Get", "?.handler", f"{app}.even", f"{store}.New", "strings.ToUpper"
+        ]
+        assert graph.callees_of(f"{store}.Store.Get") == [f"{store}.Backend.Load", f"{store}.Store.normalize"]
+        assert graph.node(f"{store}.Backend.Load")["kind"] == "interface_method"
+        assert graph.node("?.handler")["kind"] == "unresolved"
+
+        # Mutual recursion terminates
+        assert graph.transitive_callees(f"{app}.even") == [f"{app}.odd"]
+        assert graph.transitive_callers(f"{app}.odd") == [f"{app}.even", f"{app}.main"]
+        assert graph.transitive_callers(f"{store}.Store.normalize") == [f"{store}.Store.Get"]
+
+
+def test_file_call_graph_separates_in_file_calls():
+    golden_content = open(os.path.join(os.path.dirname(__file__), "fixtures", "go", "golden_go.go")).read()
+    with tempfile.TemporaryDirectory() as tmpdir:
+        write_files(tmpdir, {"golden_go.go": golden_content})
+        graph = Repository(tmpdir).file_call_graph("golden_go.go")
+
+    assert graph.calls["main"] == ["Add", "HelperFunction", "User.Greet"]
+    assert graph.external["main"] == ["fmt.Println"]
+    assert graph.calls["User.Greet"] == []
+    assert graph.external["User.Greet"] == ["fmt.Sprintf"]
+    assert "Add" not in graph.external
+
+
+def test_call_graph_dot_export():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        write_files(tmpdir, {"main.go": 'package main\n\nimport "fmt"\n\nfunc main() { fmt.Println(helper()) }\n\nfunc helper() string { return "" }\n'})
+        dot = Repository(tmpdir).get_call_graph().to_dot()
+
+    assert dot.startswith("digraph calls {")
+    assert '  "main" -> "helper";' in dot
+    assert '  "main" -> "fmt.Println";' in dot
+    assert '  "fmt.Println" [style=filled, fillcolor=lightgrey];' in dot
+    assert dot.rstrip().endswith("}")
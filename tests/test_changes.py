@@ -0,0 +1,160 @@
+import os
+import shutil
+import subprocess
+import tempfile
+
+import pytest
+
+from codekite import Repository
+from codekite.changes import parse_unified_diff
+
+pytestmark = pytest.mark.skipif(shutil.which("git") is None, reason="needs git")
+
+BASE_GO = """package calc
+
+// Add adds two integers.
+func Add(a, b int) int {
+	return a + b
+}
+
+// Sub subtracts.
+func Sub(a, b int) int {
+	return a - b
+}
+
+type OldThing struct{}
+
+type User struct {
+	Name string
+}
+"""
+
+HEAD_GO = """package calc
+
+// Add adds two integers.
+func Add(a, b int) int {
+	return b + a
+}
+
+// Sub subtracts b from a.
+func Sub(a, b int) int {
+	return a - b
+}
+
+type User struct {
+	Name string
+}
+
+func (u *User) Greet() string { return u.Name }
+"""
+
+
+def git(tmpdir, *args):
+    subprocess.run(
+        ["git", "-c", "user.name=test", "-c", "user.email=test@example.com", *args],
+        cwd=tmpdir,
+        check=True,
+        capture_output=True,
+    )
+
+
+def write_files(tmpdir, files):
+    for rel_path, content in files.items():
+        path = os.path.join(tmpdir, rel_path)
+        os.makedirs(os.path.dirname(path), exist_ok=True)
+        with open(path, "w") as f:
+            f.write(content)
+
+
+def commit(tmpdir, message):
+    git(tmpdir, "add", "-A")
+    git(tmpdir, "commit", "-q", "-m", message)
+
+
+def fixture_repo(tmpdir):
+    git(tmpdir, "init", "-q")
+    write_files(tmpdir, {"calc.go": BASE_GO, "other/other.go": "package other\n\nfunc Same() {}\n"})
+    commit(tmpdir, "base")
+    git(tmpdir, "mv", "calc.go", "arith.go")
+    write_files(tmpdir, {"arith.go": HEAD_GO})
+    commit(tmpdir, "head")
+    return Repository(tmpdir)
+
+
+def test_changed_symbols_follow_renames():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        changes = fixture_repo(tmpdir).changed_symbols("HEAD~1", "HEAD")
+
+    assert [str(c) for c in changes] == [
+        "Modified: func Add",
+        "Modified: func Sub",
+        "Added: method User.Greet",
+        "Removed: type OldThing",
+    ]
+    assert [c.file for c in changes] == ["arith.go", "arith.go", "arith.go", "calc.go"]
+    assert all(c.old_file == "calc.go" for c in changes)
+    assert [c.doc_only for c in changes] == [False, True, False, False]
+    assert changes[2].symbol["start_line"] == 16
+
+
+def test_changed_files_include_uncommitted_and_untracked_files():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        repo = fixture_repo(tmpdir)
+        write_files(tmpdir, {"other/other.go": "package other\n\nfunc Same() {}\n\nfunc New() {}\n"})
+        write_files(tmpdir, {"fresh.go": "package calc\n\nfunc Fresh() {}\n", ".gitignore": "*.log\n"})
+        write_files(tmpdir, {"debug.log": "ignored\n"})
+
+        # The rename shows up under its new path; the old path is gone
+        assert repo.changed_files("HEAD~1") == [".gitignore", "arith.go", "fresh.go", "other/other.go"]
+        assert repo.changed_files("HEAD") == [".gitignore", "fresh.go", "other/other.go"]
+
+        symbols, errors = repo.parse_directory(changed_files=repo.changed_files("HEAD"))
+        assert errors == [] and sorted(symbols) == ["fresh.go", "other/other.go"]
+        with pytest.raises(ValueError):
+            repo.changed_files("no-such-ref")
+
+
+def test_doc_only_changes_reported_separately():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        repo = fixture_repo(tmpdir)
+        write_files(
+            tmpdir,
+            {"greet.py": 'def greet(name):\n    """Say hello."""\n    return "hi " + name\n\n\ndef bye():\n    return 1\n'},
+        )
+        commit(tmpdir, "python")
+        write_files(
+            tmpdir,
+            {"greet.py": 'def greet(name):\n    """Say hello to someone."""\n    return "hi " + name\n\n\ndef bye():\n    return 2\n'},
+        )
+        commit(tmpdir, "docs")
+        changes = repo.changed_symbols("HEAD~1", separate_doc_changes=True)
+        go_changes = repo.changed_symbols("HEAD~3", "HEAD~2", separate_doc_changes=True)
+
+    assert [str(c) for c in changes] == ["Doc changed: function greet", "Modified: function bye"]
+    assert "Doc changed: func Sub" in [str(c) for c in go_changes]
+
+
+def test_unknown_revision_raises():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        repo = fixture_repo(tmpdir)
+        with pytest.raises(ValueError):
+            repo.changed_symbols("no-such-ref")
+
+
+def test_parse_unified_diff_content_lines_are_not_headers():
+    diff = """diff --git a/a.txt b/a.txt
+index 1..2 100644
+--- a/a.txt
++++ b/a.txt
+@@ -2,2 +1,0 @@ x
+--- not a header
+--- b/nor this
+@@ -9,0 +8 @@ y
++new
+diff --git a/gone.go b/gone.go
+deleted file mode 100644
+"""
+    first, second = parse_unified_diff(diff)
+    assert (first.old_path, first.new_path) == ("a.txt", "a.txt")
+    assert first.old_lines == {1, 2} and first.new_lines == {7}
+    assert (second.old_path, second.new_path) == ("gone.go", None)
@@ -0,0 +1,143 @@
+import json
+import os
+import tempfile
+
+import pytest
+from typer.testing import CliRunner
+
+from codekite.cli import app
+
+GOLDEN_GO = open(os.path.join(os.path.dirname(__file__), "fixtures", "go", "golden_go.go")).read()
+BROKEN_GO = open(os.path.join(os.path.dirname(__file__), "golden_go_broken.go")).read()
+
+runner = CliRunner(mix_stderr=False)
+
+
+def _write_tree(root, files):
+    for rel_path, content in files.items():
+        full_path = os.path.join(root, rel_path)
+        os.makedirs(os.path.dirname(full_path), exist_ok=True)
+        with open(full_path, "w") as f:
+            f.write(content)
+
+
+@pytest.fixture
+def repo_dir():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        _write_tree(tmpdir, {
+            "src/app/main.go": GOLDEN_GO,
+            "src/tools/helpers.py": "def add(a, b):\n    return a + b\n\nclass Greeter:\n    pass\n",
+            "third_party/lib/lib.go": "package lib\n\nfunc Add(a, b int) int { return a + b }\n",
+        })
+        yield tmpdir
+
+
+def test_symbols_names_filters_by_language_kind_name_and_path(repo_dir):
+    result = runner.invoke(app, [
+        "symbols", repo_dir, "--lang", "go", "--kind", "function,method", "--name", "Add|Greet",
+        "--format", "names", "--include", "src/**", "--exclude", "third_party/**",
+    ])
+    assert result.exit_code == 0, result.stderr
+    # One package-qualified name per line; the third-party Add and the Python file are filtered out
+    assert result.stdout.splitlines() == ["src/app.User.Greet", "src/app.Add"]
+
+
+def test_symbols_include_and_exclude_are_repeatable(repo_dir):
+    result = runner.invoke(app, [
+        "symbols", repo_dir, "--format", "names", "--include", "src/tools/*", "--include", "third_party/**",
+        "--exclude", "*.go",
+    ])
+    assert result.exit_code == 0, result.stderr
+    assert result.stdout.splitlines() == ["src/tools/helpers.py.add", "src/tools/helpers.py.Greeter"]
+
+
+def test_symbols_json_is_the_export_schema(repo_dir):
+    result = runner.invoke(app, ["symbols", repo_dir, "--format", "json", "--kind", "struct", "--lang", "go"])
+    assert result.exit_code == 0, result.stderr
+    document = json.loads(result.stdout)
+    assert document["schema_version"] == 2
+    assert [(s["name"], s["kind"], s["file"]) for s in document["symbols"]] == [("User", "struct", "src/app/main.go")]
+    assert [f["path"] for f in document["files"]] == ["src/app/main.go", "third_party/lib/lib.go"]
+
+
+def test_symbols_table_aligns_and_truncates_to_terminal_width(repo_dir):
+    result = runner.invoke(app, ["symbols", repo_dir, "--format", "table", "--lang", "go"], env={"COLUMNS": "60"})
+    assert result.exit_code == 0, result.stderr
+    lines = result.stdout.splitlines()
+    assert lines[0].split() == ["LOCATION", "KIND", "NAME", "SIGNATURE"]
+    assert all(len(line) <= 60 for line in lines)
+    # Every row starts its NAME column at the same offset as the header
+    name_column = lines[0].index("NAME")
+    assert all(line[name_column - 2:name_column] == "  " for line in lines[1:])
+
+
+def test_symbols_nothing_matches_prints_nothing(repo_dir):
+    for output_format in ("text", "table", "names"):
+        result = runner.invoke(app, ["symbols", repo_dir, "--name", "^NoSuchSymbol$", "--format", output_format])
+        assert result.exit_code == 0
+        assert result.stdout == ""
+
+
+def test_symbols_dot_draws_the_type_graph(repo_dir):
+    result = runner.invoke(app, ["symbols", repo_dir, "--format", "dot", "--lang", "go"])
+    assert result.exit_code == 0, result.stderr
+    assert result.stdout.startswith("digraph types {\n")
+    assert '  "src/app.User" -> "src/app.Greeter" [label="implements", style=dashed];' in result.stdout.splitlines()
+
+
+def test_symbols_rejects_unknown_format_and_missing_paths(repo_dir):
+    result = runner.invoke(app, ["symbols", repo_dir, "--format", "csv"])
+    assert result.exit_code == 2
+    assert "--format" in result.stderr
+
+    result = runner.invoke(app, ["symbols", os.path.join(repo_dir, "missing")])
+    assert result.exit_code == 1
+    assert "No such directory" in result.stderr
+
+    result = runner.invoke(app, ["symbols", repo_dir, "--file", "src/app/missing.go"])
+    assert result.exit_code == 1
+    assert "No such file" in result.stderr
+
+    result = runner.invoke(app, ["symbols", repo_dir, "--name", "("])
+    assert result.exit_code == 1
+
+
+def test_symbols_syntax_errors_are_fatal_only_on_request(repo_dir):
+    _write_tree(repo_dir, {"src/cart/broken.go": BROKEN_GO})
+
+    result = runner.invoke(app, ["symbols", repo_dir, "--format", "names", "--include", "src/cart/*"])
+    assert result.exit_code == 0
+    assert "src/cart.Discount" in result.stdout.splitlines()
+    assert "src/cart/broken.go:" in result.stderr
+
+    result = runner.invoke(app, ["symbols", repo_dir, "--include", "src/cart/*", "--fail-on-diagnostics"])
+    assert result.exit_code == 1
+    assert "Discount" in result.stdout
+
+    # Diagnostics of excluded files do not count
+    result = runner.invoke(app, ["symbols", repo_dir, "--exclude", "src/cart/*", "--fail-on-diagnostics"])
+    assert result.exit_code == 0
+    assert "broken.go" not in result.stderr
+
+
+def test_symbols_from_stdin_uses_lang_as_the_language():
+    result = runner.invoke(app, ["symbols", "-", "--lang", "py", "--format", "names"], input="def main():\n    pass\n")
+    assert result.exit_code == 0, result.stderr
+    assert result.stdout.splitlines() == ["main"]
+
+    result = runner.invoke(app, ["symbols", "-"], input="def main():\n    pass\n")
+    assert result.exit_code == 1
+    assert "--lang is required" in result.stderr
+
+
+def test_serve_addr_parsing():
+    from codekite.cli import _parse_addr
+
+    assert _parse_addr(":7450") == ("0.0.0.0", 7450)
+    assert _parse_addr("127.0.0.1:8080") == ("127.0.0.1", 8080)
+    assert _parse_addr("[::1]:7450") == ("::1", 7450)
+    with pytest.raises(Exception):
+        _parse_addr("7450")
+    result = runner.invoke(app, ["serve", "--repo", ".", "--addr", "localhost"])
+    assert result.exit_code == 2
+    assert "--addr" in result.stderr
@@ -0,0 +1,81 @@
+import os
+import tempfile
+
+from codekite import Repository
+from codekite.code_origin import GENERATED_HEADER_LINES, is_generated, is_vendored, origin_fields
+from codekite.code_searcher import SearchOptions
+from codekite.symbol_index import SymbolIndex
+
+# A first-party package next to a third_party/ copy, a vendor/ tree and the output of stringer, protoc and mockgen
+FIXTURE = os.path.join(os.path.dirname(__file__), "fixtures", "vendored_generated")
+FIRST_PARTY = ["app/app.go", "tools/gen/main.go", "web/app.js"]
+
+
+def test_vendored_by_directory_and_generated_by_name_or_header():
+    assert is_vendored("third_party/semver/semver.go") and is_vendored("web/node_modules/lib/index.js")
+    assert not is_vendored("vendor.go") and not is_vendored("app/vendoring/copy.go")
+    assert is_generated("api/user.pb.go") and is_generated("app/color_string.go") and is_generated("web/app.min.js")
+    assert is_generated("mocks/store.go", "// Code generated by MockGen. DO NOT EDIT.\npackage mocks\n")
+    assert is_generated("pkg/schema.py", '# @generated by tool\n"""Schema."""\n')
+    # The phrase in code rather than a comment is not a marker
+    assert not is_generated("gen.go", 'package gen\n\nconst header = "// Code generated by gen. DO NOT EDIT."\n')
+    assert origin_fields("app/app.go", "package app\n") == {}
+    assert origin_fields("third_party/x/x.pb.go") == {"vendored": True, "generated": True}
+
+
+def test_header_is_only_looked_for_near_the_top():
+    padding = "package big\n" + "\n" * (GENERATED_HEADER_LINES - 2)
+    marker = "// Code generated by gen. DO NOT EDIT.\n"
+    assert is_generated("big.go", padding + marker)
+    assert not is_generated("big.go", padding + "\n" + marker)
+
+
+def test_parse_directory_marks_vendored_and_generated_code_and_can_leave_it_out():
+    repo = Repository(FIXTURE)
+    symbols, errors = repo.parse_directory()
+    assert errors == []
+    # vendor/ is never walked; third_party/ is parsed like any directory
+    assert sorted(symbols) == [
+        "api/user.pb.go", "app/app.go", "app/color_string.go", "mocks/store.go",
+        "third_party/semver/semver.go", "tools/gen/main.go", "web/app.js", "web/vendor.min.js",
+    ]
+    flags = {path: (s[0].get("vendored", False), s[0].get("generated", False)) for path, s in symbols.items()}
+    assert flags["third_party/semver/semver.go"] == (True, False)
+    assert flags["mocks/store.go"] == (False, True)  # by the header below its license
+    assert flags["app/color_string.go"] == flags["api/user.pb.go"] == flags["web/vendor.min.js"] == (False, True)
+    # tools/gen mentions the header only past the first lines
+    assert sorted(path for path, flag in flags.items() if flag == (False, False)) == FIRST_PARTY
+
+    first_party, _ = repo.parse_directory(include_vendored=False, include_generated=False)
+    assert sorted(first_party) == FIRST_PARTY
+    kept = sorted(f.path for f in repo.iter_symbols(include_generated=False))
+    assert kept == sorted(FIRST_PARTY + ["third_party/semver/semver.go"])
+
+
+def test_search_leaves_vendored_and_generated_files_out_by_default():
+    repo = Repository(FIXTURE)
+    found = {m["file"] for m in repo.search_text(r"^func ", "*.go")}
+    assert found == {"app/app.go", "tools/gen/main.go"}
+
+    everything = SearchOptions(include_vendored=True, include_generated=True)
+    found = {m["file"] for m in repo.search_text(r"^func ", "*.go", everything)}
+    assert found == {
+        "api/user.pb.go", "app/app.go", "app/color_string.go", "mocks/store.go",
+        "third_party/semver/semver.go", "tools/gen/main.go", "vendor/example.com/dep/dep.go",
+    }
+
+
+def test_fuzzy_search_and_file_entries_carry_the_flags():
+    repo = Repository(FIXTURE)
+    with tempfile.TemporaryDirectory() as tmpdir:
+        index = SymbolIndex(os.path.join(tmpdir, "index.json"))
+        index.update(repo)
+    assert [r.symbol["name"] for r in index.fuzzy_search("Compare")] == []
+    assert [r.symbol["name"] for r in index.fuzzy_search("Compare", include_vendored=True)] == ["Compare"]
+    assert "GetName" not in [r.symbol["name"] for r in index.fuzzy_search("GetName")]
+    assert [r.symbol["name"] for r in index.fuzzy_search("GetName", include_generated=True)][0] == "GetName"
+
+    tree = {entry["path"].replace(os.sep, "/"): entry for entry in repo.get_file_tree()}
+    assert tree["vendor/example.com/dep/dep.go"]["vendored"] is True
+    assert tree["mocks/store.go"]["generated"] is True
+    assert "vendored" not in tree["app/app.go"] and "generated" not in tree["app/app.go"]
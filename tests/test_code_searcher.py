@@ -1,6 +1,7 @@
 import tempfile
 import os
 from codekite import CodeSearcher
+from codekite.code_searcher import SearchOptions
 
 def test_search_text_basic():
     with tempfile.TemporaryDirectory() as tmpdir:
@@ -37,3 +38,45 @@ def test_search_text_regex():
         matches = searcher.search_text(r"def [fb]oo")
         assert any("foo" in m["line"] for m in matches)
         assert not any("bar" in m["line"] for m in matches)
+
+def test_search_text_skips_binary_and_ignored_files():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        with open(os.path.join(tmpdir, "a.py"), "w") as f:
+            f.write("needle = 1\n")
+        with open(os.path.join(tmpdir, "blob.py"), "wb") as f:
+            f.write(b"needle\x00\x01\x02\n")
+        os.makedirs(os.path.join(tmpdir, "sub", "gen"))
+        with open(os.path.join(tmpdir, "sub", ".gitignore"), "w") as f:
+            f.write("gen/\n")
+        with open(os.path.join(tmpdir, "sub", "gen", "out.py"), "w") as f:
+            f.write("needle = 2\n")
+        os.makedirs(os.path.join(tmpdir, ".git"))
+        with open(os.path.join(tmpdir, ".git", "hook.py"), "w") as f:
+            f.write("needle = 3\n")
+
+        searcher = CodeSearcher(tmpdir)
+        assert [m["file"] for m in searcher.search_text("needle")] == ["a.py"]
+        unfiltered = searcher.search_text("needle", options=SearchOptions(use_gitignore=False))
+        assert [m["file"] for m in unfiltered] == ["a.py", os.path.join("sub", "gen", "out.py")]
+
+def test_search_text_caps_context_and_streaming():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        with open(os.path.join(tmpdir, "gen.py"), "w") as f:
+            f.write("".join(f"HIT {i}\n" for i in range(50)))
+        with open(os.path.join(tmpdir, "z.py"), "w") as f:
+            f.write("before\nhit here\nafter\n")
+        searcher = CodeSearcher(tmpdir)
+
+        capped = searcher.search_text("hit", options=SearchOptions(case_sensitive=False, max_matches_per_file=2))
+        assert [(m["file"], m["line_number"]) for m in capped] == [("gen.py", 1), ("gen.py", 2), ("z.py", 2)]
+        assert searcher.search_text("hit")[0]["line"] == "hit here"
+
+        with_context = searcher.search_text(
+            "hit", file_pattern="z.py", options=SearchOptions(context_lines_before=1, context_lines_after=1)
+        )
+        assert (with_context[0]["context_before"], with_context[0]["context_after"]) == (["before"], ["after"])
+
+        streamed = []
+        limited = searcher.search_text("HIT", options=SearchOptions(max_results=3), callback=streamed.append)
+        assert [m["line"] for m in limited] == ["HIT 0", "HIT 1", "HIT 2"] and streamed == limited
+        assert next(searcher.iter_search_text("HIT"))["line_number"] == 1
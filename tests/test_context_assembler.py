@@ -12,3 +12,45 @@ def test_context_assembler_basic(tmp_path):
 
     assert "foo.py" in ctx
     assert "print('hi')" in ctx
+
+def _budget_repo(tmp_path):
+    (tmp_path / "big.py").write_text("".join(f"value_{i} = {i}\n" for i in range(200)))
+    return Repository(str(tmp_path))
+
+def test_context_assembler_drops_lowest_priority_first(tmp_path):
+    repo = _budget_repo(tmp_path)
+    symbol = {"name": "f", "type": "function", "start_line": 2, "end_line": 3, "code": "def f():\n    pass\n"}
+
+    def build():
+        assembler = ContextAssembler(repo, max_tokens=120)
+        assembler.add_file("big.py", priority=1)
+        assembler.add_note("Explain f.", title="Task", priority=10)
+        assembler.add_symbol("lib.py", symbol, priority=5)
+        # The same symbol again through search is deduplicated by file and range
+        assembler.add_search_results([{"file": "lib.py", "start_line": 2, "end_line": 3, "code": "def f():"}], query="f")
+        return assembler.assemble()
+
+    result = build()
+    assert [(e.kind, e.status) for e in result.manifest] == [
+        ("file", "truncated"),
+        ("note", "included"),
+        ("symbol", "included"),
+        ("search", "duplicate"),
+    ]
+    assert result.tokens <= 120
+    # Headers keep provenance even for truncated items, and items stay in the order added
+    assert "## big.py:1-200 (full)" in result.text
+    assert result.text.index("## big.py") < result.text.index("## Task") < result.text.index("## lib.py:3-4 (function f)")
+    assert "more lines truncated]" in result.text
+    assert build().text == result.text
+
+def test_context_assembler_excludes_items_without_room_for_a_header(tmp_path):
+    repo = _budget_repo(tmp_path)
+    assembler = repo.get_context_assembler(max_tokens=12)
+    assembler.add_note("Keep this.", priority=1)
+    assembler.add_file("big.py")
+    result = assembler.assemble()
+
+    assert [e.status for e in result.manifest] == ["included", "excluded"]
+    assert result.excluded[0].tokens == 0
+    assert result.text == "## Note\nKeep this."
@@ -1,3 +1,4 @@
+import pytest
 import tempfile
 from pathlib import Path
 from codekite import ContextExtractor
@@ -183,3 +184,73 @@ def another_valid_func():
         assert ctx_method["name"] == "a_method"
         assert "print(\"inside method\")" in ctx_method["code"]
         assert "class AnotherClass:" not in ctx_method["code"] # Should be just the method
+
+def test_chunk_file_at_symbol_boundaries():
+    golden = (Path(__file__).parent / "fixtures" / "go" / "golden_go.go").read_text()
+    with tempfile.TemporaryDirectory() as tmpdir:
+        (Path(tmpdir) / "golden_go.go").write_text(golden)
+        chunks = ContextExtractor(tmpdir).chunk_file("golden_go.go")
+        split = ContextExtractor(tmpdir).chunk_file("golden_go.go", max_lines=3)
+
+    # The package clause and imports, then the six declarations
+    assert [(c.kind, c.symbols) for c in chunks] == [
+        ("preamble", []),
+        ("symbol", ["User"]),
+        ("symbol", ["Greeter"]),
+        ("symbol", ["User.Greet"]),
+        ("symbol", ["Add"]),
+        ("symbol", ["HelperFunction"]),
+        ("symbol", ["main"]),
+    ]
+    assert chunks[0].code == 'package main\n\nimport "fmt"\n'
+    # Doc comments belong to their symbol's chunk
+    assert chunks[4].code.startswith("// Add calculates the sum of two integers.\nfunc Add(")
+    assert chunks[4].code.endswith("}\n") and chunks[4].end_line - chunks[4].start_line == 3
+    assert not any(c.continuation for c in chunks)
+
+    user = [c for c in split if c.symbols == ["User"]]
+    assert [c.continuation for c in user] == [False, True]
+    assert "".join(c.code for c in user) == chunks[1].code
+    assert all(c.end_line - c.start_line < 3 for c in split)
+
+def test_chunk_file_attaches_gaps_to_the_following_symbol():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        with open(f"{tmpdir}/mod.py", "w") as f:
+            f.write('"""Module doc."""\nimport os\n\n\nclass A:\n    def m(self):\n        pass\n\n# section marker\n\ndef b():\n    pass\n\n# trailing\n')
+        chunks = ContextExtractor(tmpdir).chunk_file("mod.py")
+
+    assert [(c.kind, c.symbols, c.start_line, c.end_line) for c in chunks] == [
+        ("preamble", [], 0, 1),
+        # Methods stay inside their class's chunk
+        ("symbol", ["A"], 4, 6),
+        ("symbol", ["b"], 8, 13),
+    ]
+    assert chunks[2].code.startswith("# section marker\n\ndef b():")
+
+def test_chunk_file_by_tokens_stays_within_budget():
+    from codekite.tokenizers import HeuristicTokenizer
+
+    tokenizer = HeuristicTokenizer()
+    with tempfile.TemporaryDirectory() as tmpdir:
+        lines = [f"line {i:02d} of text\n" for i in range(20)]  # 4 tokens each
+        (Path(tmpdir) / "notes.txt").write_text("".join(lines) + "x" * 50 + "\n")
+        chunks = ContextExtractor(tmpdir).chunk_file_by_tokens("notes.txt", max_tokens=12, overlap=1)
+
+    assert all(tokenizer.count(c.code) <= 12 and c.kind == "lines" for c in chunks)
+    # Three lines per chunk, the last repeated at the start of the next
+    assert [(c.start_line, c.end_line) for c in chunks[:3]] == [(0, 2), (2, 4), (4, 6)]
+    assert chunks[0].code == "".join(lines[:3])
+    # The 51-character line is hard-split; later pieces continue it
+    long_line = [c for c in chunks if c.start_line == 20]
+    assert [c.continuation for c in long_line] == [False, True]
+    assert "".join(c.code for c in long_line) == "x" * 50 + "\n"
+
+def test_chunk_file_by_tokens_overlap_never_repeats_a_whole_chunk():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        (Path(tmpdir) / "f.py").write_text("a = 1\nb = 2\nc = 3\n")
+        chunks = ContextExtractor(tmpdir).chunk_file_by_tokens("f.py", max_tokens=2, overlap=5)
+        with pytest.raises(ValueError):
+            ContextExtractor(tmpdir).chunk_file_by_tokens("f.py", max_tokens=0)
+
+    # Each chunk fits only one line, so there is no room to overlap
+    assert [(c.start_line, c.end_line) for c in chunks] == [(0, 0), (1, 1), (2, 2)]
@@ -0,0 +1,51 @@
+import io
+import os
+import tempfile
+
+from codekite import Repository
+from codekite.ctags import escape, render_tags, tag_lines
+from codekite.formatters import format_symbols
+
+GOLDEN_GO = open(os.path.join(os.path.dirname(__file__), "fixtures", "go", "golden_go.go")).read()
+GOLDEN_TAGS = os.path.join(os.path.dirname(__file__), "golden_go.tags")
+
+
+def test_tags_of_golden_go_match_golden_file():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        with open(os.path.join(tmpdir, "golden_go.go"), "w") as f:
+            f.write(GOLDEN_GO)
+        out = io.StringIO()
+        Repository(tmpdir).export_ctags(out)
+    with open(GOLDEN_TAGS) as f:
+        assert out.getvalue() == f.read()
+
+
+def test_tag_lines_escape_fields_and_keep_duplicate_names():
+    assert escape("a\\b\tc\nd") == "a\\\\b\\tc\\nd"
+    symbols = [
+        {"name": "Add", "type": "function", "start_line": 2, "file": "b/math.go"},
+        {"name": "Add", "type": "function", "start_line": 0, "file": "a\tb/math.go"},
+        {"name": "Adder", "type": "struct", "start_line": 4, "file": "b/math.go"},
+        {"name": "Sum", "type": "method", "start_line": 9, "file": "b/math.go", "parent": "Adder"},
+        # The receiver type is declared in another file
+        {"name": "Reset", "type": "method", "start_line": 1, "file": "b/reset.go", "parent": "Adder"},
+        {"name": "func1", "type": "function", "start_line": 3, "file": "b/math.go", "anonymous": True},
+    ]
+    assert tag_lines(symbols) == [
+        'Add\ta\\tb/math.go\t1;"\tkind:function\tline:1\tlanguage:Go',
+        'Add\tb/math.go\t3;"\tkind:function\tline:3\tlanguage:Go',
+        'Adder\tb/math.go\t5;"\tkind:struct\tline:5\tlanguage:Go',
+        'Reset\tb/reset.go\t2;"\tkind:method\tline:2\tlanguage:Go\ttype:Adder',
+        'Sum\tb/math.go\t10;"\tkind:method\tline:10\tlanguage:Go\tstruct:Adder',
+    ]
+    # Pseudo-tags sort before every tag, and the file ends with a newline
+    rendered = render_tags(symbols).splitlines(True)
+    assert [line.split("\t")[0] for line in rendered[:2]] == ["!_TAG_FILE_FORMAT", "!_TAG_FILE_SORTED"]
+    assert rendered[-1].endswith("\n")
+
+
+def test_ctags_format_renders_the_tags_file():
+    symbols = [{"name": "Add", "type": "function", "start_line": 21, "file": "golden_go.go"}]
+    lines = format_symbols(symbols, "ctags").split("\n")
+    assert lines[0].startswith("!_TAG_FILE_FORMAT\t2\t")
+    assert lines[-1] == 'Add\tgolden_go.go\t22;"\tkind:function\tline:22\tlanguage:Go'
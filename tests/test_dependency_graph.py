@@ -0,0 +1,129 @@
+import json
+import os
+import tempfile
+
+from codekite import Repository
+
+
+def write_files(tmpdir, files):
+    for rel_path, content in files.items():
+        path = os.path.join(tmpdir, rel_path)
+        os.makedirs(os.path.dirname(path), exist_ok=True)
+        with open(path, "w") as f:
+            f.write(content)
+
+
+FIXTURE = {
+    # Go: main -> store -> util, plus an aliased and a blank import
+    "go.mod": "module example.com/app\n",
+    "main.go": 'package main\n\nimport (\n\t"fmt"\n\n\tst "example.com/app/store"\n\t_ "embed"\n)\n\nfunc main() { fmt.Println(st.Get()) }\n',
+    "store/store.go": 'package store\n\nimport "example.com/app/util"\n\nfunc Get() string { return util.Name }\n',
+    "store/store_test.go": "package store\n",
+    "util/util.go": "package util\n\nconst Name = \"x\"\n",
+    # Python: a deliberate cycle between two modules of one package
+    "pkg/__init__.py": "",
+    "pkg/a.py": "import os\nfrom . import b\n\ndef fa():\n    return b.fb()\n",
+    "pkg/b.py": "from pkg.a import fa\nimport requests\n\ndef fb():\n    return 1\n",
+    "app.py": "from pkg import a\n",
+    # TypeScript: relative imports, require, and an npm package
+    "web/index.ts": "import { api } from './api';\nimport React from 'react';\nconst cfg = require('../web/config');\n",
+    "web/api.ts": "import { z } from '@scope/zod/lib';\nexport const api = 1;\n",
+    "web/config.js": "module.exports = {};\n",
+}
+
+
+def test_internal_and_external_edges():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        write_files(tmpdir, FIXTURE)
+        graph = Repository(tmpdir).get_dependency_graph()
+
+        assert graph.imports_of("main.go") == ["store/store.go"]
+        assert graph.external_imports_of("main.go") == ["embed", "fmt"]
+        assert graph.imports_of("pkg/a.py") == ["pkg/b.py"]
+        assert graph.external_imports_of("pkg/b.py") == ["requests"]
+        assert graph.imports_of("web/index.ts") == ["web/api.ts", "web/config.js"]
+        assert graph.external_imports_of("web/index.ts") == ["react"]
+        assert graph.external_imports_of("web/api.ts") == ["@scope/zod"]
+
+        go_edges = [e for e in graph.edges if e["source"] == "main.go"]
+        assert {(e["import"], e.get("alias")) for e in go_edges} == {
+            ("fmt", None), ("example.com/app/store", "st"), ("embed", "_")
+        }
+
+
+def test_dependents_blast_radius_and_cycles():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        write_files(tmpdir, FIXTURE)
+        graph = Repository(tmpdir).get_dependency_graph()
+
+        assert graph.dependents_of("util/util.go") == ["main.go", "store/store.go"]
+        assert graph.dependents_of("util/util.go", transitive=False) == ["store/store.go"]
+        # The cycle is followed once; a.py depends on itself only through b.py
+        assert graph.dependents_of("pkg/a.py") == ["app.py", "pkg/b.py"]
+        assert graph.cycles() == [["pkg/a.py", "pkg/b.py"]]
+        assert graph.cycles(level="package") == []
+
+        packages = graph.package_graph()
+        assert packages["."] == {"internal": ["pkg", "store"], "external": ["embed", "fmt"]}
+        assert packages["store"] == {"internal": ["util"], "external": []}
+
+
+def test_json_and_dot_export():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        write_files(tmpdir, FIXTURE)
+        graph = Repository(tmpdir).get_dependency_graph()
+        out = os.path.join(tmpdir, "deps.json")
+        graph.write_json(out)
+        with open(out) as f:
+            data = json.load(f)
+        dot = graph.to_dot(level="package")
+
+    assert data["cycles"] == [["pkg/a.py", "pkg/b.py"]]
+    assert {"source": "pkg/b.py", "target": "requests", "kind": "external", "import": "requests", "line": 1} in data["edges"]
+    assert '  "store" -> "util";' in dot
+    assert '  "fmt" [style=filled, fillcolor=lightgrey];' in dot
+
+
+# Python: a diamond (top imports left and right, which both import base) next to a three-file cycle
+ORDER_FIXTURE = {
+    "base/core.py": "VERSION = 1\n",
+    "left/view.py": "from base import core\n",
+    "right/model.py": "from base import core\n",
+    "top/app.py": "from left import view\nfrom right import model\n",
+    "ring/x.py": "from ring import y\nfrom base import core\n",
+    "ring/y.py": "from ring import z\n",
+    "ring/z.py": "from ring import x\n",
+    "main.py": "from top import app\nfrom ring import x\n",
+}
+
+
+def test_topological_order_groups_cycles_and_breaks_ties_by_path():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        write_files(tmpdir, ORDER_FIXTURE)
+        graph = Repository(tmpdir).get_dependency_graph()
+
+    # left and right are ready together after base; the smaller path goes first
+    assert graph.topological_order() == [
+        ["base/core.py"],
+        ["left/view.py"],
+        ["right/model.py"],
+        ["ring/x.py", "ring/y.py", "ring/z.py"],
+        ["top/app.py"],
+        ["main.py"],
+    ]
+    assert graph.topological_order(level="package") == [["base"], ["left"], ["right"], ["ring"], ["top"], ["."]]
+
+
+def test_layers_bucket_files_by_depth_from_the_leaves():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        write_files(tmpdir, ORDER_FIXTURE)
+        graph = Repository(tmpdir).get_dependency_graph()
+
+    # The cycle shares a layer; main.py waits for top, the deeper of its two imports
+    assert graph.layers() == [
+        ["base/core.py"],
+        ["left/view.py", "right/model.py", "ring/x.py", "ring/y.py", "ring/z.py"],
+        ["top/app.py"],
+        ["main.py"],
+    ]
+    assert graph.layers(level="package") == [["base"], ["left", "right", "ring"], ["top"], ["."]]
@@ -0,0 +1,61 @@
+import os
+import tempfile
+
+from codekite import BuildContext, Repository
+from codekite.duplicates import find_duplicates
+
+FILES = {
+    "calc/calc.go": "package calc\n\nfunc Add(a, b int) int { return a + b }\n\nfunc clamp(x int) int { return x }\n\nfunc init() {}\n",
+    # A generated file that redefines a helper
+    "calc/zz_generated.go": "package calc\n\nfunc init() {}\n\nfunc clamp(x int) int { return x }\n",
+    "calc/sum_amd64.go": "package calc\n\nfunc sum(xs []int) int { return 0 }\n",
+    "calc/sum_arm64.go": "package calc\n\nfunc sum(xs []int) int { return 0 }\n",
+    "calc/hash.go": "//go:build !purego\n\npackage calc\n\ntype Hasher struct{ Size int }\n",
+    "calc/hash_purego.go": "//go:build purego\n\npackage calc\n\ntype Hasher struct{ Size int }\n",
+    "calc/debug_linux.go": "package calc\n\nfunc debug() {}\n",
+    "calc/debug.go": "//go:build unix\n\npackage calc\n\nfunc debug() {}\n",
+    "calc/calc_test.go": "package calc_test\n\nfunc Add() {}\n",
+    "other/calc.go": "package other\n\nfunc Add(a, b int) int { return a + b }\n",
+}
+
+
+def make_repo(tmpdir):
+    for rel_path, content in FILES.items():
+        path = os.path.join(tmpdir, rel_path)
+        os.makedirs(os.path.dirname(path), exist_ok=True)
+        with open(path, "w") as f:
+            f.write(content)
+    return Repository(tmpdir)
+
+
+def test_duplicates_name_every_location():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        conflicts = make_repo(tmpdir).find_duplicates()
+        assert [c.locations for c in conflicts] == [
+            ["calc/calc.go:5", "calc/zz_generated.go:5"],
+            ["calc/debug.go:5", "calc/debug_linux.go:3"],
+        ]
+        assert str(conflicts[0]) == "function clamp defined 2 times: calc/calc.go:5, calc/zz_generated.go:5"
+        assert conflicts[0].to_dict() == {"id": conflicts[0].id, "locations": conflicts[0].locations}
+
+
+def test_build_context_only_compares_files_it_builds():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        repo = make_repo(tmpdir)
+        windows = repo.find_duplicates(build_context=BuildContext(goos="windows", goarch="amd64"))
+        assert [c.symbols[0]["name"] for c in windows] == ["clamp"]
+        purego = repo.find_duplicates(build_context=BuildContext(goos="linux", goarch="arm64", tags=["purego"]))
+        assert [c.symbols[0]["name"] for c in purego] == ["clamp", "debug"]
+
+
+def test_without_sources_every_cross_file_repeat_clashes():
+    symbols = [
+        {"name": "sum", "type": "function", "file": "calc/sum_amd64.go", "start_line": 2, "id": "calc.sum:function"},
+        {"name": "sum", "type": "function", "file": "calc/sum_arm64.go", "start_line": 2, "id": "calc.sum:function"},
+        # Overloads within one file are not duplicates
+        {"name": "f", "type": "method", "file": "A.java", "start_line": 1, "id": "A.f:method"},
+        {"name": "f", "type": "method", "file": "A.java", "start_line": 3, "id": "A.f:method#2"},
+    ]
+    assert [c.locations for c in find_duplicates(symbols)] == [["calc/sum_amd64.go:3", "calc/sum_arm64.go:3"]]
+    sources = {s["file"]: "package calc\n" for s in symbols}
+    assert find_duplicates(symbols, sources) == []
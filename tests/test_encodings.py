@@ -0,0 +1,84 @@
+import codecs
+import os
+import shutil
+import tempfile
+
+from codekite import RepoMapper, Repository
+from codekite.encodings import DecodedSource, decode_source
+
+FIXTURES = os.path.dirname(__file__)
+
+
+def copy_fixtures(tmpdir, *names):
+    for name in names:
+        shutil.copy(os.path.join(FIXTURES, name), os.path.join(tmpdir, name))
+
+
+def test_decode_source_byte_order_marks():
+    assert decode_source(codecs.BOM_UTF8 + b"package x\n") == DecodedSource("package x\n", "utf-8-sig")
+    assert decode_source("def f(): pass\n".encode("utf-16")).text == "def f(): pass\n"
+    assert decode_source(codecs.BOM_UTF16_BE + "x = 1\n".encode("utf-16-be")).encoding == "utf-16-be"
+    assert decode_source("x = 1\n".encode("utf-32")).text == "x = 1\n"
+
+
+def test_decode_source_fallback_and_replacement():
+    legacy = "name = 'Café “quoted”'\n".encode("cp1252")
+
+    replaced = decode_source(legacy)
+    assert replaced.replaced and replaced.encoding == "utf-8"
+    assert replaced.text == "name = 'Caf� �quoted�'\n"
+
+    transcoded = decode_source(legacy, "cp1252")
+    assert not transcoded.replaced and transcoded.text == "name = 'Café “quoted”'\n"
+
+    # 0x81 is undefined in Windows-1252, so even the fallback needs a replacement
+    assert decode_source(b"x = '\x81'\n", "cp1252").replaced
+
+
+def test_bom_go_file_offsets_refer_to_the_decoded_text():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        copy_fixtures(tmpdir, "golden_go_bom.go")
+        repo = Repository(tmpdir)
+        symbols, errors = repo.parse_directory()
+        content = repo.get_file_content("golden_go_bom.go")
+        raw = open(os.path.join(tmpdir, "golden_go_bom.go"), "rb").read()
+
+    assert errors == []
+    by_name = {s["name"]: s for s in symbols["golden_go_bom.go"]}
+    assert {"Café", "Open"} <= set(by_name)
+    cafe = by_name["Café"]
+    assert cafe["docstring"] == "Café is a coffee shop."
+    assert (cafe["start_line"], cafe["start_column"]) == (3, 0)
+    # Lines match the file; bytes index the text without its 3-byte BOM
+    assert not content.startswith("﻿")
+    assert cafe["start_byte"] == raw.index(b"type Caf") - len(codecs.BOM_UTF8)
+    assert content.encode("utf-8")[cafe["start_byte"] : cafe["end_byte"]].decode("utf-8") == cafe["code"]
+
+
+def test_utf16_file_is_transcoded_not_skipped_as_binary():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        copy_fixtures(tmpdir, "golden_utf16.java")
+        symbols, errors = RepoMapper(tmpdir).parse_directory()
+        content = Repository(tmpdir).get_file_content("golden_utf16.java")
+
+    assert errors == []
+    names = {s["name"]: s for s in symbols["golden_utf16.java"]}
+    assert {"Panier", "total", "décompte"} <= set(names)
+    assert names["décompte"]["start_line"] == 4
+    assert names["décompte"]["parent"] == "Panier"
+    assert content.startswith("// Généré par un outil hérité.\n")
+
+
+def test_undecodable_file_is_flagged_or_read_with_the_fallback():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        with open(os.path.join(tmpdir, "legacy.py"), "wb") as f:
+            f.write("def greeting():\n    return 'café naïve'\n".encode("cp1252"))
+
+        symbols, errors = RepoMapper(tmpdir).parse_directory()
+        assert [(e["file"], e["severity"], e["encoding"]) for e in errors] == [("legacy.py", "warning", "utf-8")]
+        assert "'caf� na�ve'" in symbols["legacy.py"][0]["code"]
+
+        symbols, errors = RepoMapper(tmpdir, fallback_encoding="cp1252").parse_directory()
+        assert errors == []
+        assert "'café naïve'" in symbols["legacy.py"][0]["code"]
+        assert "café" in Repository(tmpdir, fallback_encoding="cp1252").get_file_content("legacy.py")
@@ -0,0 +1,119 @@
+import io
+import json
+import os
+import tempfile
+
+import pytest
+
+from codekite import FileLimits, Repository, register_language, unregister_language
+from codekite.export import SCHEMA_VERSION, ExportOptions, read_export
+
+GOLDEN = os.path.join(os.path.dirname(__file__), "golden_export.json")
+
+
+class ToyExtractor:
+    """Every line "<keyword> <name>" declares a symbol; kinds are reported in mixed case."""
+
+    KINDS = {"fn": "Function", "type": "Struct"}
+
+    def extract(self, path, source):
+        symbols = []
+        for i, line in enumerate(source.splitlines()):
+            keyword, name = line.split()
+            symbols.append(
+                {
+                    "name": name,
+                    "type": self.KINDS[keyword],
+                    "start_line": i,
+                    "end_line": i,
+                    "start_column": 0,
+                    "end_column": len(line),
+                    "signature": line,
+                    "keyword": keyword,
+                    "code": line,
+                }
+            )
+        return symbols
+
+
+@pytest.fixture
+def shop():
+    register_language("Toy", [".toy"], ToyExtractor())
+    with tempfile.TemporaryDirectory() as tmpdir:
+        root = os.path.join(tmpdir, "shop")
+        os.makedirs(os.path.join(root, "util"))
+        with open(os.path.join(root, "cart.toy"), "w") as f:
+            f.write("type Cart\nfn add\nfn total\n")
+        with open(os.path.join(root, "util", "strings.toy"), "w") as f:
+            f.write("fn trim\n")
+        yield Repository(root)
+    unregister_language("Toy")
+
+
+def export(repository, **options):
+    out = io.StringIO()
+    repository.export_json(out, ExportOptions(**options))
+    return out.getvalue()
+
+
+def test_export_matches_golden_schema(shop):
+    # A change here is a schema change: bump SCHEMA_VERSION along with golden_export.json
+    with open(GOLDEN) as f:
+        assert export(shop, pretty=True) == f.read()
+    assert json.loads(export(shop))["schema_version"] == SCHEMA_VERSION == 2
+    assert "\n" not in export(shop).rstrip("\n")
+
+
+def test_export_filters_and_code(shop):
+    document = json.loads(export(shop, kinds=["type"], include_code=True))
+    assert [(s["name"], s["kind"], s["code"]) for s in document["symbols"]] == [("Cart", "struct", "type Cart")]
+
+    document = json.loads(export(shop, paths=["util/*"]))
+    assert [f["path"] for f in document["files"]] == ["util/strings.toy"]
+    assert [s["name"] for s in document["symbols"]] == ["trim"]
+    assert "code" not in document["symbols"][0]
+
+
+def test_export_lists_skipped_files(shop):
+    with open(os.path.join(shop.repo_path, "util", "huge.toy"), "w") as f:
+        f.write("fn padding\n" * 200)
+    document = json.loads(export(shop, limits=FileLimits(max_file_size=1024)))
+    assert [f["path"] for f in document["files"]] == ["cart.toy", "util/strings.toy"]
+    assert [(s["path"], s["reason"]) for s in document["skipped"]] == [("util/huge.toy", "too_large")]
+    assert read_export(io.StringIO(json.dumps(document))).skipped[0].reason == "too_large"
+
+    # Path filters apply to the skipped list as well
+    assert json.loads(export(shop, paths=["cart.*"], limits=FileLimits(max_file_size=1024)))["skipped"] == []
+
+
+def test_import_ignores_unknown_fields_and_round_trips():
+    with open(GOLDEN) as f:
+        original = json.load(f)
+    newer = json.loads(json.dumps(original))
+    newer["generator"] = "codekite 9.9"
+    newer["repo"]["default_branch"] = "main"
+    newer["files"][0]["checksum"] = "abc"
+    newer["symbols"][0]["visibility"] = "public"
+
+    document = read_export(io.StringIO(json.dumps(newer)))
+    assert document.to_dict() == original
+    # Language-specific extras survive in attributes
+    assert document.symbols[0].attributes == {"keyword": "type"}
+
+    with pytest.raises(ValueError):
+        read_export(io.StringIO(json.dumps(dict(original, schema_version=3))))
+    with pytest.raises(ValueError):
+        read_export(io.StringIO("[]"))
+
+
+def test_streamed_export_reads_back_the_same(shop):
+    with open(os.path.join(shop.repo_path, "util", "huge.toy"), "w") as f:
+        f.write("fn padding\n" * 200)
+    limits = FileLimits(max_file_size=1024)
+    for options in ({}, {"pretty": True}, {"kinds": ["fn"], "include_code": True, "limits": limits}):
+        streamed = export(shop, stream=True, **options)
+        assert json.loads(streamed) == json.loads(export(shop, **options))
+        assert read_export(io.StringIO(streamed)) == read_export(io.StringIO(export(shop, **options)))
+    # Symbols are written before the per-file entries they are counted in
+    assert list(json.loads(export(shop, stream=True))) == ["schema_version", "repo", "symbols", "files", "skipped"]
+    assert "\n" not in export(shop, stream=True).rstrip("\n")
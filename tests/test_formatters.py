@@ -0,0 +1,302 @@
+import json
+import os
+import random
+import tempfile
+
+import pytest
+
+from codekite import Repository
+from codekite.formatters import (
+    FORMATTERS, format_symbols, sort_symbols, symbols_to_json, symbols_to_names, symbols_to_table, symbols_to_yaml,
+)
+
+SYMBOLS = [
+    {"name": "b", "type": "function", "file": "z.go", "start_line": 4, "end_line": 6, "code": "func b() {}"},
+    {"name": "a", "type": "function", "file": "z.go", "start_line": 1, "end_line": 2, "code": "func a() {}"},
+    {"name": "C", "type": "struct", "file": "a.go", "start_line": 9, "end_line": 9, "docstring": "C is a type."},
+]
+
+
+def test_symbols_to_json_is_stable():
+    first = symbols_to_json(SYMBOLS)
+    second = symbols_to_json(list(reversed(SYMBOLS)))
+    assert first == second
+
+    parsed = json.loads(first)
+    assert [s["name"] for s in parsed] == ["C", "a", "b"]
+    # Keys are sorted within each object
+    assert list(parsed[0].keys()) == sorted(parsed[0].keys())
+
+
+def test_symbols_to_json_always_includes_optional_fields():
+    parsed = json.loads(symbols_to_json(SYMBOLS))
+    by_name = {s["name"]: s for s in parsed}
+    assert by_name["a"]["docstring"] == ""
+    assert by_name["a"]["signature"] == ""
+    assert by_name["a"]["receiver"] == ""
+    assert by_name["C"]["docstring"] == "C is a type."
+
+
+def test_format_symbols_text_and_unknown_format():
+    text = format_symbols(SYMBOLS, "text")
+    assert text.splitlines()[0] == "a.go:10: struct C"
+    with pytest.raises(ValueError):
+        format_symbols(SYMBOLS, "nope")
+
+
+def test_symbols_to_table_aligns_columns_and_truncates_signatures():
+    symbols = [
+        dict(SYMBOLS[1], signature="func a(first, second,\n    third int) (int, error)"),
+        dict(SYMBOLS[2], node_path="C"),
+    ]
+    lines = symbols_to_table(symbols, width=40).splitlines()
+    assert lines == [
+        "LOCATION  KIND      NAME  SIGNATURE",
+        "a.go:10   struct    C",
+        "z.go:2    function  a     func a(first,…",
+    ]
+    assert len(lines[2]) == 40
+    # Without room for a signature, only the fixed columns remain
+    assert symbols_to_table(symbols, width=10).splitlines()[2] == "z.go:2    function  a"
+    assert symbols_to_table([]) == ""
+
+
+def test_symbols_to_names_qualifies_by_package():
+    symbols = [
+        {"name": "Greet", "type": "method", "node_path": "User.Greet", "file": "pkg/user/user.go", "start_line": 3},
+        {"name": "add", "type": "method", "node_path": "Cart.add", "file": "app/models.py", "start_line": 1},
+        {"name": "main", "type": "function", "file": "main.go", "start_line": 0},
+        {"name": "helper", "type": "function", "file": "<stdin>", "start_line": 0},
+    ]
+    assert symbols_to_names(symbols).splitlines() == ["helper", "app/models.py.Cart.add", "main", "pkg/user.User.Greet"]
+
+
+def test_sort_symbols_orders_by_kind_receiver_and_name():
+    symbols = [
+        {"name": "Greet", "type": "method", "receiver": "User", "file": "b.go", "id": "Greet", "start_line": 1},
+        {"name": "Add", "type": "function", "file": "b.go", "id": "Add:b", "start_line": 9},
+        {"name": "Add", "type": "function", "file": "a.go", "id": "Add:a", "start_line": 5},
+        {"name": "Close", "type": "method", "receiver": "Conn", "file": "a.go", "id": "Close", "start_line": 2},
+    ]
+    assert [(s["name"], s["id"]) for s in sort_symbols(symbols)] == [
+        ("Add", "Add:a"), ("Add", "Add:b"), ("Close", "Close"), ("Greet", "Greet")
+    ]
+
+
+def test_sorted_output_ignores_input_order():
+    golden_content = open(os.path.join(os.path.dirname(__file__), "fixtures", "go", "golden_go.go")).read()
+    with tempfile.TemporaryDirectory() as tmpdir:
+        with open(os.path.join(tmpdir, "golden_go.go"), "w") as f:
+            f.write(golden_content)
+        symbols = Repository(tmpdir).extract_symbols("golden_go.go")
+
+    shuffled = list(symbols)
+    random.Random(42).shuffle(shuffled)
+    for output_format in FORMATTERS:
+        expected = format_symbols(symbols, output_format, sort=True)
+        assert format_symbols(shuffled, output_format, sort=True) == expected, output_format
+        assert format_symbols(list(reversed(symbols)), output_format, sort=True) == expected, output_format
+    names = [s["name"] for s in json.loads(format_symbols(shuffled, "json", sort=True))]
+    assert names[:3] == ["Add", "HelperFunction", "main"]
+
+
+def test_write_symbols_json_for_go_fixture():
+    golden_content = open(os.path.join(os.path.dirname(__file__), "fixtures", "go", "golden_go.go")).read()
+    with tempfile.TemporaryDirectory() as tmpdir:
+        with open(os.path.join(tmpdir, "golden_go.go"), "w") as f:
+            f.write(golden_content)
+        repository = Repository(tmpdir)
+        out_path = os.path.join(tmpdir, "symbols.json")
+        repository.write_symbols(out_path, repository.extract_symbols("golden_go.go"))
+        with open(out_path) as f:
+            parsed = json.load(f)
+
+    greet = next(s for s in parsed if s["name"] == "Greet")
+    assert greet["receiver"] == "User"
+    assert greet["complexity"] == 1
+    assert [s["name"] for s in parsed] == ["User", "Greeter", "Greet", "Add", "HelperFunction", "main"]
+
+
+# Fields pinned by tests/golden_out.yaml; code and byte offsets are left out so it stays readable
+YAML_GOLDEN_FIELDS = (
+    "name", "type", "file", "node_path", "parent", "receiver", "signature", "docstring",
+    "start_line", "end_line", "complexity", "fields", "methods",
+)
+
+
+def test_yaml_for_go_fixture_matches_golden():
+    golden_content = open(os.path.join(os.path.dirname(__file__), "fixtures", "go", "golden_go.go")).read()
+    with tempfile.TemporaryDirectory() as tmpdir:
+        with open(os.path.join(tmpdir, "golden_go.go"), "w") as f:
+            f.write(golden_content)
+        symbols = Repository(tmpdir).extract_symbols("golden_go.go")
+
+    projected = [{k: s[k] for k in YAML_GOLDEN_FIELDS if k in s} for s in symbols]
+    with open(os.path.join(os.path.dirname(__file__), "golden_out.yaml")) as f:
+        assert format_symbols(projected, "yaml") + "\n" == f.read()
+
+
+def test_yaml_mirrors_json_and_anchors_repeated_values():
+    fields = [{"name": "ID", "type": "int", "embedded": False}]
+    symbols = SYMBOLS + [
+        {"name": "D", "type": "struct", "file": "a.go", "start_line": 1, "end_line": 3, "fields": fields},
+        {"name": "E", "type": "struct", "file": "a.go", "start_line": 5, "end_line": 7, "fields": list(fields)},
+        {"name": "f", "type": "function", "file": "a.go", "start_line": 9, "end_line": 9, "docstring": "Line one.\nno"},
+    ]
+    out = symbols_to_yaml(symbols)
+    assert out == symbols_to_yaml(list(reversed(symbols)))
+    assert "fields: &ref1" in out and "fields: *ref1" in out
+    # Empty values are written, not dropped, and strings are quoted so "no" stays a string
+    assert 'docstring: ""' in out
+    assert "    Line one.\n    no" in out
+
+    yaml = pytest.importorskip("yaml")
+    assert yaml.safe_load(out) == json.loads(symbols_to_json(symbols))
+    assert symbols_to_yaml([]) == "[]"
+
+
+def test_render_markdown_groups_by_kind_and_nests_methods():
+    symbols = SYMBOLS + [
+        {"name": "Do", "type": "method", "parent": "C", "node_path": "C.Do", "file": "a.go",
+         "start_line": 11, "end_line": 12, "signature": "func (c C) Do()", "docstring": "Do does it."},
+        {"name": "Loose", "type": "method", "parent": "Missing", "node_path": "Missing.Loose",
+         "file": "b.go", "start_line": 0, "end_line": 1, "code": "func (m Missing) Loose() {"},
+    ]
+    markdown = format_symbols(symbols, "markdown")
+    headings = [line for line in markdown.splitlines() if line.startswith("#")]
+    assert headings == ["## Types", "### C", "#### C.Do", "## Functions", "### a", "### b", "## Methods", "### Missing.Loose"]
+    assert "```go\nfunc (c C) Do()\n```\n\nDo does it." in markdown
+    # Without a recorded signature the first line of the code is used.
+    assert "```go\nfunc (m Missing) Loose()\n```" in markdown
+    assert format_symbols(list(reversed(symbols)), "markdown") == markdown
+
+
+def test_render_markdown_for_go_fixture():
+    golden_content = open(os.path.join(os.path.dirname(__file__), "fixtures", "go", "golden_go.go")).read()
+    with tempfile.TemporaryDirectory() as tmpdir:
+        with open(os.path.join(tmpdir, "golden_go.go"), "w") as f:
+            f.write(golden_content)
+        symbols = Repository(tmpdir).extract_symbols("golden_go.go")
+
+    markdown = format_symbols(symbols, "markdown")
+    headings = [line for line in markdown.splitlines() if line.startswith("#")]
+    assert headings.index("#### User.Greet") == headings.index("### User") + 1
+    assert "```go\nfunc Add(a, b int) int\n```\n\nAdd calculates the sum of two integers." in markdown
+    assert "type Greeter interface" in markdown
+
+
+def test_render_markdown_fences_go_doc_examples_and_flags_deprecation():
+    symbol = {
+        "name": "Connect", "type": "function", "file": "client.go", "start_line": 11, "end_line": 11,
+        "signature": "func Connect(addr string) *Conn",
+        "docstring": 'Connect opens a connection.\n\n\tc := Connect("localhost")\n\t    defer c.Close()\n\nDeprecated: Use Dial.',
+        "deprecated": True, "deprecated_note": "Use Dial.",
+    }
+    markdown = format_symbols([symbol], "markdown")
+    assert "> **Deprecated:** Use Dial.\n\nConnect opens a connection." in markdown
+    assert '\n```go\nc := Connect("localhost")\n    defer c.Close()\n```\n\nDeprecated: Use Dial.' in markdown
+
+
+def test_render_markdown_keeps_space_only_lines_in_go_docs():
+    symbol = {
+        "name": "Foo", "type": "function", "file": "foo.go", "start_line": 3, "end_line": 3,
+        "signature": "func Foo()", "docstring": "Foo\n\n \nBar",
+    }
+    markdown = format_symbols([symbol], "markdown")
+    # Only the signature is fenced
+    assert markdown.count("```go") == 1
+    assert "Foo\n\n \nBar" in markdown
+
+
+def test_positions_are_opt_in():
+    symbol = dict(SYMBOLS[2], start_column=5, end_column=20, doc_start_line=8)
+
+    default = json.loads(symbols_to_json([symbol]))[0]
+    assert "start_column" not in default and "doc_start_line" not in default
+
+    with_positions = json.loads(symbols_to_json([symbol], positions=True))[0]
+    assert (with_positions["start_column"], with_positions["end_column"]) == (5, 20)
+    assert with_positions["doc_start_line"] == 8
+
+    assert format_symbols([symbol], "text", positions=True) == "a.go:10:6: struct C"
+
+
+def test_render_tree_for_go_fixture():
+    golden_content = open(os.path.join(os.path.dirname(__file__), "fixtures", "go", "golden_go.go")).read()
+    with tempfile.TemporaryDirectory() as tmpdir:
+        with open(os.path.join(tmpdir, "golden_go.go"), "w") as f:
+            f.write(golden_content)
+        symbols = Repository(tmpdir).extract_symbols("golden_go.go")
+
+    assert format_symbols(symbols, "tree") == "\n".join(
+        [
+            "golden_go.go",
+            "├── struct User",
+            "│   ├── field ID int",
+            "│   ├── field Name string",
+            "│   └── method Greet",
+            "├── interface Greeter",
+            "│   └── method Greet() string",
+            "├── function Add",
+            "├── function HelperFunction",
+            "└── function main",
+        ]
+    )
+
+
+def test_render_tree_nests_classes_and_keeps_orphans_qualified():
+    symbols = [
+        {"name": "Outer", "type": "class", "file": "m.py", "start_line": 0, "end_line": 9},
+        {"name": "Inner", "type": "class", "parent": "Outer", "node_path": "Outer.Inner", "file": "m.py",
+         "start_line": 1, "end_line": 4},
+        {"name": "run", "type": "method", "parent": "Outer.Inner", "node_path": "Outer.Inner.run", "file": "m.py",
+         "start_line": 2, "end_line": 3},
+        {"name": "top", "type": "method", "parent": "Outer", "node_path": "Outer.top", "file": "m.py",
+         "start_line": 5, "end_line": 6},
+        {"name": "Loose", "type": "method", "parent": "Missing", "node_path": "Missing.Loose", "file": "b.go",
+         "start_line": 0, "end_line": 1},
+    ]
+    assert format_symbols(symbols, "tree") == "\n".join(
+        [
+            "b.go",
+            "└── method Missing.Loose",
+            "",
+            "m.py",
+            "└── class Outer",
+            "    ├── class Inner",
+            "    │   └── method run",
+            "    └── method top",
+        ]
+    )
+
+
+def test_lsp_document_symbols_nest_members_with_zero_based_ranges():
+    symbols = [
+        {"name": "Store", "type": "struct", "file": "s.go", "start_line": 3, "end_line": 6, "doc_start_line": 2,
+         "start_column": 5, "signature": "type Store struct",
+         "code": "Store struct {\n\tmu   sync.Mutex\n\tName string\n}",
+         "fields": [{"name": "mu", "type": "sync.Mutex", "embedded": False},
+                    {"name": "Name", "type": "string", "embedded": False}]},
+        {"name": "Get", "type": "method", "parent": "Store", "node_path": "Store.Get", "file": "s.go",
+         "start_line": 8, "end_line": 8, "signature": "func (s *Store) Get() string",
+         "code": "func (s *Store) Get() string { return s.Name }"},
+        {"name": "Größe", "type": "function", "file": "s.go", "start_line": 10, "end_line": 10,
+         "code": "func Größe() {}"},
+    ]
+    parsed = json.loads(format_symbols(symbols, "lsp"))
+
+    assert [(s["name"], s["kind"]) for s in parsed] == [("Store", 23), ("Größe", 12)]
+    store = parsed[0]
+    # The range starts at the doc comment; the selection is the name itself
+    assert store["range"] == {"start": {"line": 2, "character": 0}, "end": {"line": 6, "character": 1}}
+    assert store["selectionRange"] == {"start": {"line": 3, "character": 5}, "end": {"line": 3, "character": 10}}
+    assert [(c["name"], c["kind"], c["detail"]) for c in store["children"]] == [
+        ("mu", 8, "sync.Mutex"),
+        ("Name", 8, "string"),
+        ("Get", 6, "func (s *Store) Get() string"),
+    ]
+    assert store["children"][1]["selectionRange"]["start"] == {"line": 5, "character": 1}
+    assert parsed[1]["range"]["end"] == {"line": 10, "character": len("func Größe() {}")}
+
+    # Several files come back keyed by file
+    assert set(json.loads(format_symbols(SYMBOLS, "lsp"))) == {"a.go", "z.go"}
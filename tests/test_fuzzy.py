@@ -0,0 +1,87 @@
+import os
+import time
+
+import pytest
+
+from codekite import fuzzy
+from codekite.fuzzy import fuzzy_score, fuzzy_search
+
+
+def sym(name, file="a.go", line=0):
+    return {"name": name, "type": "function", "file": file, "start_line": line}
+
+
+def ranked(symbols, query, limit=20):
+    return [r.symbol["name"] for r in fuzzy_search(symbols, query, limit)]
+
+
+def test_camel_case_boundaries_rank_first():
+    symbols = [sym("hashFuncTable"), sym("HelperFunction"), sym("Unrelated")]
+    assert ranked(symbols, "HFunc") == ["HelperFunction"]
+    # Lowercase queries match both; boundary hits beat scattered ones
+    assert ranked([sym("xhfuncwrapper"), sym("HelperFunction")], "hfunc") == ["HelperFunction", "xhfuncwrapper"]
+
+
+def test_smart_case():
+    assert fuzzy_score("parse", "ParseFile") is not None
+    assert fuzzy_score("Parse", "parseFile") is None
+    assert fuzzy_score("PF", "ParseFile") is not None
+
+
+def test_prefix_beats_inner_match():
+    assert ranked([sym("reparse"), sym("parser")], "pars") == ["parser", "reparse"]
+
+
+def test_consecutive_beats_scattered():
+    assert fuzzy_score("index", "IndexFile") > fuzzy_score("index", "InlineDexFile")
+
+
+def test_ties_break_by_name_length_then_path():
+    symbols = [sym("ParseAll", "b.go"), sym("Parse", "z.go"), sym("ParseAll", "a.go")]
+    results = fuzzy_search(symbols, "Parse")
+    assert [(r.symbol["name"], r.symbol["file"]) for r in results] == [
+        ("Parse", "z.go"), ("ParseAll", "a.go"), ("ParseAll", "b.go")
+    ]
+    assert results[1].score == results[2].score
+
+
+def test_non_matches_and_limit():
+    symbols = [sym(f"Handler{i}") for i in range(50)]
+    assert ranked(symbols, "xyz") == []
+    assert len(fuzzy_search(symbols, "hand", limit=5)) == 5
+    assert fuzzy_search(symbols, "") == []
+
+
+def test_scores_are_returned_for_thresholding():
+    results = fuzzy_search([sym("HelperFunction"), sym("hyperFencingUnicode")], "hfunc")
+    assert results[0].score > results[1].score > 0
+
+
+def test_only_names_containing_the_query_in_order_are_scored(monkeypatch):
+    scored = []
+
+    def counting_score(query, candidate, case_sensitive=None):
+        scored.append(candidate)
+        return fuzzy_score(query, candidate, case_sensitive)
+
+    monkeypatch.setattr(fuzzy, "fuzzy_score", counting_score)
+    symbols = [sym(f"Handler{i}") for i in range(1000)] + [sym("HelperFunction"), sym("hashFuncTable")]
+    assert ranked(symbols, "HFunc") == ["HelperFunction"]
+    # hashFuncTable is a subsequence case-insensitively only, so smart case rejects it before scoring too
+    assert scored == ["HelperFunction"]
+
+    scored.clear()
+    assert sorted(ranked(symbols, "hfunc")) == ["HelperFunction", "hashFuncTable"]
+    assert sorted(scored) == ["HelperFunction", "hashFuncTable"]
+
+
+@pytest.mark.skipif(not os.environ.get("CODEKITE_BENCHMARK"), reason="set CODEKITE_BENCHMARK=1 to run benchmarks")
+def test_benchmark_fuzzy_search():
+    words = ["Helper", "Function", "hash", "Table", "Server", "http", "Request", "parse", "Index", "cache"]
+    symbols = [sym(words[i % 10] + words[(i // 10) % 10].capitalize() + str(i), f"f{i % 100}.go") for i in range(50000)]
+    for query in ("HFunc", "srvreq", "idx"):
+        start = time.perf_counter()
+        results = fuzzy_search(symbols, query)
+        elapsed = time.perf_counter() - start
+        assert results, query
+        assert elapsed < 1.0, f"fuzzy_search {query!r} over {len(symbols)} symbols took {elapsed * 1000:.1f} ms"
@@ -0,0 +1,134 @@
+import os
+import tempfile
+
+import pytest
+
+from codekite import BuildContext, Repository
+from codekite.go_build import ConstraintError, constraints_disjoint, file_constraint, parse_build_expr
+
+PLATFORMS = os.path.join(os.path.dirname(__file__), "fixtures", "go_platforms")
+
+LINUX = BuildContext(goos="linux", goarch="amd64", tags=["integration"])
+WINDOWS = BuildContext(goos="windows", goarch="arm64")
+
+
+@pytest.mark.parametrize("source,linux,windows", [
+    ("//go:build linux\n\npackage a\n", True, False),
+    ("//go:build !linux\n\npackage a\n", False, True),
+    ("//go:build (linux || darwin) && amd64 && !cgo\n\npackage a\n", True, False),
+    ("//go:build integration\n\npackage a\n", True, False),
+    ("//go:build unix\n\npackage a\n", True, False),
+    ("//go:build go1.18\n\npackage a\n", True, True),
+    # Legacy form: options are ORed, comma terms ANDed, separate lines ANDed
+    ("// +build linux,386 darwin\n\npackage a\n", False, False),
+    ("// +build linux windows\n// +build amd64\n\npackage a\n", True, False),
+    # //go:build wins over +build lines
+    ("//go:build windows\n// +build linux\n\npackage a\n", False, True),
+    # Constraints after the package clause are ordinary comments
+    ("package a\n\n//go:build windows\n", True, True),
+])
+def test_match_source_constraints(source, linux, windows):
+    assert LINUX.match_source("a.go", source) is linux
+    assert WINDOWS.match_source("a.go", source) is windows
+
+
+@pytest.mark.parametrize("filename,linux,windows", [
+    ("a_windows.go", False, True),
+    ("a_linux_amd64.go", True, False),
+    ("a_windows_arm64_test.go", False, True),
+    ("a_arm64.go", False, True),
+    ("linux.go", True, True),
+    ("_scratch.go", False, False),
+])
+def test_match_filename_suffixes(filename, linux, windows):
+    assert LINUX.match_filename(filename) is linux
+    assert WINDOWS.match_filename(filename) is windows
+
+
+@pytest.mark.parametrize("a,b,disjoint", [
+    (("sum_linux.go", "package a\n"), ("sum_windows.go", "package a\n"), True),
+    (("sum_amd64.go", "package a\n"), ("sum_arm64.go", "package a\n"), True),
+    (("a.go", "//go:build purego\n\npackage a\n"), ("b.go", "//go:build !purego\n\npackage a\n"), True),
+    (("a.go", "//go:build cgo\n\npackage a\n"), ("b.go", "// +build !cgo\n\npackage a\n"), True),
+    (("a_windows.go", "package a\n"), ("b.go", "//go:build unix\n\npackage a\n"), True),
+    # unix covers linux, and android implies linux
+    (("a_linux.go", "package a\n"), ("b.go", "//go:build unix\n\npackage a\n"), False),
+    (("a_linux.go", "package a\n"), ("b.go", "//go:build android\n\npackage a\n"), False),
+    (("a.go", "package a\n"), ("zz_generated.go", "package a\n"), False),
+    (("a.go", "//go:build linux && (\n\npackage a\n"), ("b_windows.go", "package a\n"), False),
+])
+def test_constraints_disjoint(a, b, disjoint):
+    assert constraints_disjoint(a, b) is disjoint
+    assert constraints_disjoint(b, a) is disjoint
+
+
+@pytest.mark.parametrize("filename,source,constraint", [
+    ("a.go", "package a\n", None),
+    ("a_test.go", "package a\n", None),
+    ("sys_linux_arm64_test.go", "package a\n", "linux && arm64"),
+    ("a.go", "//go:build (linux || darwin) && !cgo\n\npackage a\n", "(linux || darwin) && !cgo"),
+    ("a_windows.go", "//go:build cgo || purego\n\npackage a\n", "windows && (cgo || purego)"),
+    ("a.go", "// +build linux,386 darwin\n// +build !cgo\n\npackage a\n", "((linux && 386) || darwin) && !cgo"),
+])
+def test_file_constraint(filename, source, constraint):
+    assert file_constraint(filename, source) == constraint
+
+
+@pytest.mark.parametrize("expr", ["linux &&", "(linux", "linux)", "linux darwin", "|| linux"])
+def test_malformed_expressions(expr):
+    with pytest.raises(ConstraintError):
+        parse_build_expr(expr)
+
+
+def test_parse_directory_skips_excluded_files():
+    files = {
+        "fs/fs.go": "package fs\n\nfunc Open() {}\n",
+        "fs/fs_linux.go": "package fs\n\nfunc sync() {}\n",
+        "fs/fs_windows.go": "package fs\n\nfunc sync() {}\n",
+        "fs/tagged.go": "//go:build integration\n\npackage fs\n\nfunc Tagged() {}\n",
+        "fs/broken.go": "//go:build linux &&\n\npackage fs\n",
+        "fs/notes.py": "def notes(): pass\n",
+    }
+    with tempfile.TemporaryDirectory() as tmpdir:
+        for rel_path, content in files.items():
+            os.makedirs(os.path.dirname(os.path.join(tmpdir, rel_path)), exist_ok=True)
+            with open(os.path.join(tmpdir, rel_path), "w") as f:
+                f.write(content)
+        repo = Repository(tmpdir)
+
+        symbols, errors = repo.parse_directory(build_context=LINUX)
+        assert set(symbols) == {"fs/fs.go", "fs/fs_linux.go", "fs/tagged.go", "fs/notes.py"}
+        assert [e["file"] for e in errors] == ["fs/broken.go"]
+
+        symbols, _ = repo.parse_directory(build_context=WINDOWS)
+        assert set(symbols) == {"fs/fs.go", "fs/fs_windows.go", "fs/notes.py"}
+
+        # Without a context every file is parsed, as before
+        symbols, errors = repo.parse_directory()
+        assert len(symbols) == 6 and errors == []
+
+
+def test_platform_variants_carry_their_constraint():
+    repo = Repository(PLATFORMS)
+    symbols, errors = repo.parse_directory()
+    assert errors == []
+    variants = {s["file"]: s.get("build_constraint") for syms in symbols.values() for s in syms if s["name"] == "name"}
+    assert variants == {"sys_linux.go": "linux", "sys_windows.go": "windows", "sys_darwin_arm64.go": "darwin && arm64"}
+    assert "build_constraint" not in symbols["sys.go"][0]
+    assert symbols["legacy.go"][0]["build_constraint"] == "((linux && amd64) || windows) && !integration"
+    # Every variant is built for a different target, so none of them clash
+    assert repo.find_duplicates() == []
+
+
+@pytest.mark.parametrize("context,files", [
+    (LINUX, {"sys.go", "sys_linux.go", "fast.go"}),
+    (BuildContext(goos="linux", goarch="amd64"), {"sys.go", "sys_linux.go", "fast.go", "legacy.go"}),
+    (WINDOWS, {"sys.go", "sys_windows.go", "fast_generic.go", "legacy.go"}),
+    (BuildContext(goos="darwin", goarch="arm64"), {"sys.go", "sys_darwin_arm64.go", "fast.go"}),
+    (BuildContext(goos="darwin", goarch="amd64", tags=["purego"]), {"sys.go", "fast_generic.go"}),
+])
+def test_platform_variants_for_one_target(context, files):
+    symbols, _ = Repository(PLATFORMS).parse_directory(build_context=context)
+    assert set(symbols) == files
+    # At most one variant of name() is built for any target
+    assert sum(s["name"] == "name" for syms in symbols.values() for s in syms) <= 1
@@ -0,0 +1,161 @@
+import os
+import tempfile
+
+import pytest
+
+from codekite import Repository
+from codekite.go_tests import GoTestLinker
+
+FILES = {
+    "go.mod": "module example.com/app\n",
+    "calc/calc.go": """package calc
+
+type Calculator struct{ stack []int }
+
+func Add(a, b int) int { return a + b }
+
+func Sum(xs ...int) int {
+	total := 0
+	for _, x := range xs {
+		total = Add(total, x)
+	}
+	return total
+}
+
+func Divide(a, b int) int { return a / b }
+
+func Multiply(a, b int) int { return a * b }
+
+func Modulo(a, b int) int { return a % b }
+
+func (c *Calculator) Push(x int) { c.stack = append(c.stack, x) }
+
+func (c *Calculator) Reset() { c.stack = nil }
+
+func clamp(x int) int { return x }
+""",
+    "calc/calc_test.go": """package calc
+
+import "testing"
+
+func TestMain(m *testing.M) { Modulo(1, 1) }
+
+func TestAdd(t *testing.T) {
+	if Add(1, 2) != 3 {
+		t.Fatal("bad sum")
+	}
+	t.Run("Multiply by zero", func(t *testing.T) {})
+}
+
+func BenchmarkSum(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		Sum(1, 2, 3)
+	}
+}
+
+func TestTable(t *testing.T) {
+	tests := []struct {
+		name string
+		want int
+	}{
+		{name: "Divide", want: 2},
+		{name: "Add_negative", want: -1},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {})
+	}
+}
+
+func TestSmoke(t *testing.T) {
+	c := Calculator{}
+	c.Reset()
+}
+
+func Testclamp(t *testing.T) { clamp(1) }
+""",
+    "calc/suite_test.go": """package calc
+
+type CalcSuite struct{ calc *Calculator }
+
+func (s *CalcSuite) SetupTest() { s.calc = &Calculator{} }
+
+func (s *CalcSuite) TestCalculator_Push() { s.calc.Push(1) }
+""",
+    "calc/example_test.go": """package calc_test
+
+import (
+	"fmt"
+
+	"example.com/app/calc"
+)
+
+func ExampleSum() {
+	fmt.Println(calc.Sum(1, 2))
+}
+""",
+}
+
+
+@pytest.fixture
+def repo():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        for rel_path, content in FILES.items():
+            path = os.path.join(tmpdir, rel_path)
+            os.makedirs(os.path.dirname(path), exist_ok=True)
+            with open(path, "w") as f:
+                f.write(content)
+        yield Repository(tmpdir)
+
+
+def symbol(repo, node_path):
+    return next(s for s in repo.extract_symbols("calc/calc.go") if s.get("node_path", s["name"]) == node_path)
+
+
+def links(repo, node_path):
+    return [(t.test, t.kind, t.via) for t in repo.tests_for(symbol(repo, node_path))]
+
+
+def test_symbols_of_test_files_are_tagged(repo):
+    assert all(s["is_test"] is True for s in repo.extract_symbols("calc/calc_test.go"))
+    assert all("is_test" not in s for s in repo.extract_symbols("calc/calc.go"))
+
+
+def test_tests_link_by_name_and_by_call(repo):
+    assert links(repo, "Add") == [
+        ("TestAdd", "test", ("call", "name")),
+        ("TestTable/Add_negative", "subtest", ("name",)),
+    ]
+    # An external test package reaches Sum through its import
+    assert links(repo, "Sum") == [
+        ("BenchmarkSum", "benchmark", ("call", "name")),
+        ("ExampleSum", "example", ("call", "name")),
+    ]
+    assert links(repo, "Multiply") == [("TestAdd/Multiply_by_zero", "subtest", ("name",))]
+    assert links(repo, "Divide") == [("TestTable/Divide", "subtest", ("name",))]
+    assert links(repo, "Calculator.Reset") == [("TestSmoke", "test", ("call",))]
+    assert links(repo, "Calculator.Push") == [("CalcSuite.TestCalculator_Push", "test", ("name",))]
+
+    add_link = repo.tests_for(symbol(repo, "Add"))[0]
+    assert add_link.to_dict() == {
+        "test": "TestAdd",
+        "kind": "test",
+        "file": "calc/calc_test.go",
+        "line": 7,
+        "symbol": symbol(repo, "Add")["id"],
+        "via": ["call", "name"],
+    }
+
+
+def test_untested_symbols_lists_exported_functions_without_links(repo):
+    # TestMain is not a test and Testclamp is not run by go test, so neither counts
+    assert [s["name"] for s in repo.untested_symbols("calc")] == ["Modulo"]
+    assert repo.untested_symbols("calc/") == repo.untested_symbols("calc")
+    assert repo.untested_symbols(".") == []
+    assert not links(repo, "clamp")
+
+
+def test_linker_lists_every_link_in_file_order(repo):
+    tests = [(link.file, link.test) for link in GoTestLinker(repo).links()]
+    assert tests[0] == ("calc/calc_test.go", "TestAdd")
+    assert ("calc/example_test.go", "ExampleSum") in tests
+    assert tests == sorted(tests, key=lambda t: t[0])
@@ -0,0 +1,67 @@
+import os
+import shutil
+import tempfile
+
+import pytest
+
+from codekite import ExtractionOptions, Repository
+
+pytestmark = pytest.mark.skipif(shutil.which("go") is None, reason="needs the go toolchain")
+
+MODULE = {
+    "go.mod": "module example.com/app\n\ngo 1.21\n",
+    "calc/calc.go": "package calc\n\ntype Value int\n",
+    "api/api.go": """package api
+
+import (
+	"time"
+
+	"example.com/app/calc"
+)
+
+// Wait blocks for d.
+func Wait(d time.Duration, v *calc.Value) error { return nil }
+
+type Client struct{}
+
+func (c *Client) Sum(a, b calc.Value) calc.Value { return a + b }
+""",
+}
+
+
+def write_files(tmpdir, files):
+    for rel_path, content in files.items():
+        path = os.path.join(tmpdir, rel_path)
+        os.makedirs(os.path.dirname(path), exist_ok=True)
+        with open(path, "w") as f:
+            f.write(content)
+
+
+def signatures(symbols):
+    return {s["name"]: s.get("signature") for s in symbols if s["type"] in ("function", "method")}
+
+
+def test_resolved_signatures_qualify_imported_types():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        write_files(tmpdir, MODULE)
+        repo = Repository(tmpdir)
+        raw = repo.extract_symbols("api/api.go")
+        resolved = repo.extract_symbols("api/api.go", ExtractionOptions(resolve_types=True))
+
+    assert signatures(raw)["Wait"] == "func Wait(d time.Duration, v *calc.Value) error"
+    assert signatures(resolved) == {
+        "Wait": "func Wait(d time.Duration, v *example.com/app/calc.Value) error",
+        "Sum": "func (c *Client) Sum(a example.com/app/calc.Value, b example.com/app/calc.Value) example.com/app/calc.Value",
+    }
+    wait = next(s for s in resolved if s["name"] == "Wait")
+    assert wait["underlying"] == {"time.Duration": "int64", "example.com/app/calc.Value": "int"}
+
+
+def test_unbuildable_package_keeps_syntax_signatures():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        write_files(tmpdir, dict(MODULE, **{"api/broken.go": "package api\n\nfunc Broken() Missing { return nil }\n"}))
+        symbols, errors = Repository(tmpdir).parse_directory("api", options=ExtractionOptions(resolve_types=True))
+
+    assert errors == []
+    assert signatures(symbols["api/api.go"])["Wait"] == "func Wait(d time.Duration, v *calc.Value) error"
+    assert signatures(symbols["api/broken.go"])["Broken"] == "func Broken() Missing"
@@ -0,0 +1,101 @@
+"""
+Golden tests over tests/fixtures/<lang>/: each fixture's extraction output is pinned next to it.
+
+A fixture is any file in tests/fixtures/<lang>/ that the language registered
+as <lang> parses, e.g. tests/fixtures/go/golden_go.go; its expected symbols are
+in golden_go.go.golden.json. New fixtures are picked up without editing this
+file. Words between the stem and the extension name ExtractionOptions flags
+to turn on, so closures.include_nested.go is extracted with
+ExtractionOptions(include_nested=True). After an intended change to
+extraction, rewrite the golden files with
+
+    pytest tests/test_golden_fixtures.py --update-golden
+
+and review the diff. Directories such as tests/fixtures/go_embedding are not
+a language and hold repositories other tests use.
+"""
+
+import dataclasses
+import difflib
+import json
+import os
+
+import pytest
+
+from codekite import Repository
+from codekite.formatters import normalize_symbol, sort_by_location
+from codekite.languages import language_for
+from codekite.tree_sitter_symbol_extractor import ExtractionOptions
+
+FIXTURES = os.path.join(os.path.dirname(__file__), "fixtures")
+GOLDEN_SUFFIX = ".golden.json"
+
+# Fields pinned by the golden files; code, columns and byte offsets are left out so they stay readable
+GOLDEN_FIELDS = (
+    "name", "type", "node_path", "parent", "receiver", "signature", "docstring", "start_line", "end_line",
+    "fields", "methods", "params", "results", "complexity", "param_count", "code_lines", "anonymous", "goroutine",
+    "underlying", "alias", "alias_of",
+)
+
+
+def golden_fixtures():
+    """``(language, file name)`` of every fixture, sorted."""
+    fixtures = []
+    for language in sorted(os.listdir(FIXTURES)):
+        directory = os.path.join(FIXTURES, language)
+        if not os.path.isdir(directory):
+            continue
+        for name in sorted(os.listdir(directory)):
+            if not name.endswith(GOLDEN_SUFFIX) and language_for(os.path.splitext(name)[1]) == language:
+                fixtures.append((language, name))
+    return fixtures
+
+
+def fixture_options(name):
+    """The ExtractionOptions a fixture's name asks for: ``x.include_nested.go`` sets ``include_nested``."""
+    flags = name.split(".")[1:-1]
+    known = {f.name for f in dataclasses.fields(ExtractionOptions)}
+    unknown = [flag for flag in flags if flag not in known]
+    assert not unknown, f"{name}: {unknown} are not ExtractionOptions fields"
+    return ExtractionOptions(**{flag: True for flag in flags})
+
+
+def render(symbols):
+    """The golden JSON of *symbols*: pinned fields only, ordered by position, keys sorted."""
+    # Normalized as `codekite symbols --format json` writes them, so docstring and receiver are always present
+    normalized = (normalize_symbol(s) for s in sort_by_location(symbols))
+    projected = [{k: s[k] for k in GOLDEN_FIELDS if k in s} for s in normalized]
+    return json.dumps(projected, indent=2, sort_keys=True, ensure_ascii=False) + "\n"
+
+
+FIXTURE_FILES = golden_fixtures()
+
+
+@pytest.mark.parametrize("language,name", FIXTURE_FILES, ids=[f"{lang}/{name}" for lang, name in FIXTURE_FILES])
+def test_extraction_matches_golden(language, name, request):
+    directory = os.path.join(FIXTURES, language)
+    actual = render(Repository(directory).extract_symbols(name, fixture_options(name)))
+    golden_path = os.path.join(directory, name + GOLDEN_SUFFIX)
+    shown = f"{language}/{name}{GOLDEN_SUFFIX}"
+
+    if request.config.getoption("--update-golden"):
+        with open(golden_path, "w", encoding="utf-8") as f:
+            f.write(actual)
+        return
+    if not os.path.exists(golden_path):
+        pytest.fail(f"{shown} is missing; create it with --update-golden", pytrace=False)
+    with open(golden_path, encoding="utf-8") as f:
+        expected = f.read()
+    if actual != expected:
+        diff = "".join(difflib.unified_diff(expected.splitlines(True), actual.splitlines(True), shown, "extracted"))
+        message = f"{language}/{name} no longer matches; rerun with --update-golden if intended"
+        pytest.fail(f"{message}\n{diff}", pytrace=False)
+
+
+def test_every_golden_file_has_a_fixture():
+    assert FIXTURE_FILES, "no fixtures under tests/fixtures/<lang>/"
+    # A golden file left behind by a renamed or deleted fixture would never be checked
+    for language in {lang for lang, _ in FIXTURE_FILES}:
+        names = set(os.listdir(os.path.join(FIXTURES, language)))
+        stale = [n for n in names if n.endswith(GOLDEN_SUFFIX) and n[: -len(GOLDEN_SUFFIX)] not in names]
+        assert stale == [], f"golden files without a fixture in {language}/: {stale}"
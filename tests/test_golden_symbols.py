@@ -1,8 +1,10 @@
+import json
 import os
 import tempfile
 import pytest
 import asyncio
-from codekite import Repository
+from codekite import ExtractionOptions, Repository
+from codekite.formatters import sort_by_location, symbols_to_json
 
 # Helper to run extraction
 def run_extraction(tmpdir, filename, content):
@@ -51,7 +53,11 @@ def test_python_symbol_extraction():
             ("MyClass", "class"),
             ("__init__", "method"), 
             ("method_one", "method"),
+            ("doubled", "method"),
+            ("create", "method"),
             ("async_function", "function"),
+            ("Point", "class"),
+            ("distance", "method"),
         }
 
         # We'll refine the assertions as we improve the query
@@ -64,6 +70,108 @@ def test_python_symbol_extraction():
         assert names_types == expected
 
 
+def test_python_docstrings_and_qualified_names():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        golden_content = open(os.path.join(os.path.dirname(__file__), "golden_python.py")).read()
+        symbols = run_extraction(tmpdir, "golden_python.py", golden_content)
+        by_name = {s["name"]: s for s in symbols}
+
+        assert by_name["top_level_function"]["docstring"] == "A regular function."
+        assert by_name["MyClass"]["docstring"] == "A sample class."
+        assert by_name["method_one"]["docstring"] == "A method within the class."
+        assert by_name["async_function"]["docstring"] == "An asynchronous function."
+        assert "docstring" not in by_name["__init__"]
+
+        assert by_name["method_one"]["node_path"] == "MyClass.method_one"
+        assert by_name["method_one"]["parent"] == "MyClass"
+        assert "node_path" not in by_name["top_level_function"]
+
+
+# Fields pinned by tests/golden_python.json; code and byte offsets are left out so
+# the golden file stays readable.
+PYTHON_GOLDEN_FIELDS = ("name", "type", "node_path", "signature", "docstring", "decorators", "async", "start_line")
+
+
+def test_python_golden_json():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        golden_content = open(os.path.join(os.path.dirname(__file__), "golden_python.py")).read()
+        symbols = run_extraction(tmpdir, "golden_python.py", golden_content)
+
+    projected = [{k: s[k] for k in PYTHON_GOLDEN_FIELDS if k in s} for s in sort_by_location(symbols)]
+    with open(os.path.join(os.path.dirname(__file__), "golden_python.json")) as f:
+        assert projected == json.load(f)
+
+
+def test_python_nested_functions_are_opt_in():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        golden_content = open(os.path.join(os.path.dirname(__file__), "golden_python_complex.py")).read()
+        with open(os.path.join(tmpdir, "golden_python_complex.py"), "w") as f:
+            f.write(golden_content)
+        repository = Repository(tmpdir)
+
+        default = repository.extract_symbols("golden_python_complex.py")
+        default_names = {s["name"] for s in default}
+        nested = repository.extract_symbols("golden_python_complex.py", ExtractionOptions(include_nested=True))
+        by_path = {s.get("node_path", s["name"]): s for s in nested}
+
+        assert "deeply_nested" not in default_names
+        assert "wrapper" not in default_names
+        assert by_path["OuterClass.InnerClass.nested_function_in_method.deeply_nested"]["type"] == "function"
+        assert by_path["decorator.wrapper"]["signature"] == "def wrapper(*args, **kwargs)"
+        # Nothing that is reported by default goes missing or is duplicated
+        assert len(nested) == len(default) + 2
+
+
+JS_CALLBACKS = """export function mount(root) {
+  root.addEventListener("click", (event) => {
+    setTimeout(function () {
+      console.log(event);
+    }, 0);
+  });
+  const render = () => root;
+  return render;
+}
+
+export const handler = (req) => {
+  return [1, 2].map((n) => n * 2);
+};
+"""
+
+
+def test_js_function_literals_are_opt_in():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        default = run_extraction(tmpdir, "app.js", JS_CALLBACKS)
+        nested = Repository(tmpdir).extract_symbols("app.js", ExtractionOptions(include_nested=True))
+
+    assert sorted(s["name"] for s in default) == ["handler", "mount"]
+    literals = {s["name"]: s for s in nested if s["name"] not in ("handler", "mount")}
+    # Callbacks are named by their 1-based line, closures in variables by the variable
+    assert sorted(literals) == [
+        "handler.<anonymous:12>", "mount.<anonymous:2>", "mount.<anonymous:2>.<anonymous:3>", "mount.render",
+    ]
+    callback = literals["mount.<anonymous:2>"]
+    assert (callback["parent"], callback["start_line"], callback["end_line"]) == ("mount", 1, 5)
+    assert callback["anonymous"] is True and callback["exported"] is False
+    assert literals["mount.<anonymous:2>.<anonymous:3>"]["parent"] == "mount.<anonymous:2>"
+    assert "anonymous" not in literals["mount.render"]
+    # The module-level handler is reported once, as before, and owns the map callback
+    assert [s["name"] for s in nested].count("handler") == 1
+    assert literals["handler.<anonymous:12>"]["parent"] == "handler"
+
+
+def test_python_nested_classes_and_decorators():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        golden_content = open(os.path.join(os.path.dirname(__file__), "golden_python_complex.py")).read()
+        symbols = run_extraction(tmpdir, "golden_python_complex.py", golden_content)
+        by_path = {s.get("node_path", s["name"]): s for s in symbols}
+
+        assert by_path["OuterClass.InnerClass"]["parent"] == "OuterClass"
+        assert by_path["OuterClass.InnerClass.static_inner"]["decorators"] == ["staticmethod"]
+        assert by_path["OuterClass.outer_method"]["type"] == "method"
+        assert by_path["decorated_function"]["decorators"] == ["decorator"]
+        assert by_path["decorated_function"]["docstring"] == "A decorated function."
+
+
 # --- Complex Tests ---
 def test_python_complex_symbol_extraction():
     with tempfile.TemporaryDirectory() as tmpdir:
@@ -105,7 +213,6 @@ def test_typescript_complex_symbol_extraction():
         names_types = {(s["name"], s["type"]) for s in symbols}
 
         # Expected symbols based on current TypeScript query
-        # NOTE: Current query might not capture all nuances (e.g. arrow funcs, namespaces well)
         expected = {
             ("UserProfile", "interface"),
             ("Status", "enum"),
@@ -118,7 +225,7 @@ def test_typescript_complex_symbol_extraction():
             ("add", "method"),              # Method in generic class
             ("getAll", "method"),           # Method in generic class
             ("constructor", "method"),     # Constructor is captured by method query
-            # ("addNumbers", "function"),    # NOT CAPTURED - Arrow function assigned to const
+            ("addNumbers", "function"),       # Arrow function assigned to an exported const
             ("DecoratedClass", "class"),
             ("greet", "method"),
             ("calculateArea", "function"),    # Exported function
@@ -166,7 +273,7 @@ def test_hcl_symbol_extraction():
 # --- Go Test ---
 def test_go_symbol_extraction():
     with tempfile.TemporaryDirectory() as tmpdir:
-        golden_content = open(os.path.join(os.path.dirname(__file__), "golden_go.go")).read()
+        golden_content = open(os.path.join(os.path.dirname(__file__), "fixtures", "go", "golden_go.go")).read()
         symbols = run_extraction(tmpdir, "golden_go.go", golden_content)
         names_types = {(s["name"], s["type"]) for s in symbols}
 
@@ -186,3 +293,1082 @@ def test_go_symbol_extraction():
 
         # Assert the exact set matches
         assert names_types == expected, f"Mismatch: Got {names_types}, Expected {expected}"
+
+        # Methods are linked back to their receiver type
+        greet = next(s for s in symbols if s["name"] == "Greet")
+        assert greet["receiver"] == "User"
+        assert greet["parent"] == "User"
+        assert greet["node_path"] == "User.Greet"
+        assert all("parent" not in s for s in symbols if s["type"] != "method")
+
+
+def test_go_doc_comments():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        golden_content = open(os.path.join(os.path.dirname(__file__), "fixtures", "go", "golden_go.go")).read()
+        symbols = run_extraction(tmpdir, "golden_go.go", golden_content)
+        docs = {s["name"]: s.get("docstring") for s in symbols}
+
+        assert docs["User"] == "User represents a user in the system."
+        assert docs["Greeter"] == "Greeter defines an interface for greeting."
+        assert docs["Greet"] == "Greet implements the Greeter interface for User."
+        assert docs["Add"] == "Add calculates the sum of two integers."
+        # Adjacent comments count as doc comments, even informal ones
+        assert docs["HelperFunction"] == "Standalone function"
+        assert docs["main"] is None
+
+
+def test_go_doc_comment_boundaries():
+    code = """package main
+
+// Unrelated comment separated by a blank line.
+
+func NoDoc() {}
+
+// Multi explains
+// a paragraph.
+//
+// Second paragraph.
+//go:noinline
+func Multi() {}
+
+const (
+	// Inner documents the grouped constant.
+	Inner = 1
+)
+
+/* Block documents Block. */
+var Block = 2
+"""
+    with tempfile.TemporaryDirectory() as tmpdir:
+        symbols = run_extraction(tmpdir, "doc.go", code)
+        docs = {s["name"]: s.get("docstring") for s in symbols}
+
+        assert docs["NoDoc"] is None
+        assert docs["Multi"] == "Multi explains\na paragraph.\n\nSecond paragraph."
+        assert docs["Block"] == "Block documents Block."
+
+
+def test_go_function_signatures():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        golden_content = open(os.path.join(os.path.dirname(__file__), "fixtures", "go", "golden_go.go")).read()
+        symbols = run_extraction(tmpdir, "golden_go.go", golden_content)
+        signatures = {s["name"]: s.get("signature") for s in symbols}
+
+        assert signatures["Add"] == "func Add(a, b int) int"
+        assert signatures["Greet"] == "func (u User) Greet() string"
+        assert signatures["HelperFunction"] == "func HelperFunction()"
+        assert signatures["main"] == "func main()"
+        assert signatures["User"] == "type User struct"
+        assert signatures["Greeter"] == "type Greeter interface"
+
+
+def test_go_params_and_results_for_golden_fixture():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        golden_content = open(os.path.join(os.path.dirname(__file__), "fixtures", "go", "golden_go.go")).read()
+        symbols = run_extraction(tmpdir, "golden_go.go", golden_content)
+    by_name = {s["name"]: s for s in symbols}
+
+    # The receiver is not a parameter; the unnamed result has an empty name
+    assert by_name["Greet"]["params"] == []
+    assert by_name["Greet"]["results"] == [{"name": "", "type": "string"}]
+    assert by_name["Add"]["params"] == [{"name": "a", "type": "int"}, {"name": "b", "type": "int"}]
+    assert by_name["Add"]["results"] == [{"name": "", "type": "int"}]
+    assert by_name["main"]["params"] == [] and by_name["main"]["results"] == []
+    assert "params" not in by_name["User"]
+
+
+def test_go_signatures_variadic_and_multiple_results():
+    code = """package fmtx
+
+func Printf(format string, args ...any) (n int, err error) {
+	return 0, nil
+}
+
+func Split(
+	s string,
+	sep string,
+) ([]string, error) {
+	return nil, nil
+}
+"""
+    with tempfile.TemporaryDirectory() as tmpdir:
+        symbols = run_extraction(tmpdir, "fmtx.go", code)
+        signatures = {s["name"]: s.get("signature") for s in symbols}
+
+        assert signatures["Printf"] == "func Printf(format string, args ...any) (n int, err error)"
+        assert signatures["Split"] == "func Split(s string, sep string) ([]string, error)"
+
+
+def test_go_params_and_results_variadic_and_multiple():
+    code = """package fmtx
+
+func Printf(format string, args ...any) (n int, err error) {
+	return 0, nil
+}
+
+func Split(
+	s string,
+	sep string,
+) ([]string, error) {
+	return nil, nil
+}
+"""
+    with tempfile.TemporaryDirectory() as tmpdir:
+        symbols = run_extraction(tmpdir, "fmtx.go", code)
+    by_name = {s["name"]: s for s in symbols}
+
+    assert by_name["Printf"]["params"] == [{"name": "format", "type": "string"}, {"name": "args", "type": "...any"}]
+    assert by_name["Printf"]["results"] == [{"name": "n", "type": "int"}, {"name": "err", "type": "error"}]
+    assert by_name["Split"]["results"] == [{"name": "", "type": "[]string"}, {"name": "", "type": "error"}]
+
+
+def test_go_cyclomatic_complexity():
+    golden_content = open(os.path.join(os.path.dirname(__file__), "fixtures", "go", "golden_go.go")).read()
+    code = """package stats
+
+func Classify(xs []int) (n int) {
+	for _, x := range xs {
+		if x > 0 && x%2 == 0 {
+			n++
+		} else if x < 0 || x > 100 {
+			n--
+		}
+	}
+	switch n {
+	case 0:
+	case 1, 2:
+	default:
+	}
+	return n
+}
+
+func (s *Set) Has(k string) bool {
+	_, ok := s.m[k]
+	return ok
+}
+"""
+    with tempfile.TemporaryDirectory() as tmpdir:
+        golden = {s["name"]: s.get("complexity") for s in run_extraction(tmpdir, "golden_go.go", golden_content)}
+        complexity = {s["name"]: s.get("complexity") for s in run_extraction(tmpdir, "stats.go", code)}
+
+    assert golden["Add"] == 1 and golden["HelperFunction"] == 1 and golden["Greet"] == 1
+    # Types have no complexity
+    assert golden["User"] is None
+    # 1 + for + if + && + else-if + || + two non-default cases
+    assert complexity["Classify"] == 8
+    assert complexity["Has"] == 1
+
+
+def test_go_byte_offsets_with_non_ascii_source():
+    fixture_path = os.path.join(os.path.dirname(__file__), "golden_go_unicode.go")
+    with open(fixture_path, "rb") as f:
+        source_bytes = f.read()
+    with tempfile.TemporaryDirectory() as tmpdir:
+        symbols = run_extraction(tmpdir, "golden_go_unicode.go", source_bytes.decode("utf-8"))
+        by_name = {s["name"]: s for s in symbols}
+
+        assert set(by_name) == {"Größe", "面积", "Zähler"}
+        for symbol in symbols:
+            # Offsets are byte offsets into the file, not character offsets
+            assert source_bytes[symbol["start_byte"] : symbol["end_byte"]].decode("utf-8") == symbol["code"]
+        assert by_name["Größe"]["start_byte"] == source_bytes.index("func Größe".encode("utf-8"))
+        assert by_name["面积"]["end_byte"] == source_bytes.index(b"}", source_bytes.index("func 面积".encode("utf-8"))) + 1
+
+
+def test_go_method_receivers():
+    code = """package store
+
+type Store[T any] struct {
+	items []T
+}
+
+func (s *Store[T]) Get(i int) T {
+	return s.items[i]
+}
+
+func (s Store[T]) Len() int {
+	return len(s.items)
+}
+
+func (*Store[T]) Reset() {}
+"""
+    with tempfile.TemporaryDirectory() as tmpdir:
+        symbols = run_extraction(tmpdir, "store.go", code)
+        methods = {s["name"]: s for s in symbols if s["type"] == "method"}
+
+        assert methods["Get"]["receiver"] == "*Store[T]"
+        assert methods["Len"]["receiver"] == "Store[T]"
+        assert methods["Reset"]["receiver"] == "*Store[T]"
+        assert {m["parent"] for m in methods.values()} == {"Store"}
+        assert methods["Get"]["node_path"] == "Store.Get"
+
+
+def test_go_unexported_symbols_can_be_excluded():
+    golden_content = open(os.path.join(os.path.dirname(__file__), "fixtures", "go", "golden_go.go")).read()
+    code = golden_content + """
+type cache struct{}
+
+// Get is exported, but cache is not, so neither is the method
+func (c *cache) Get() string { return "" }
+
+func (u User) greeting() string { return "" }
+"""
+    with tempfile.TemporaryDirectory() as tmpdir:
+        with open(os.path.join(tmpdir, "golden_go.go"), "w") as f:
+            f.write(code)
+        repository = Repository(tmpdir)
+        everything = {s["name"] for s in repository.extract_symbols("golden_go.go")}
+        exported = repository.extract_symbols("golden_go.go", ExtractionOptions(include_unexported=False))
+
+        assert {"main", "cache", "Get", "greeting"} <= everything
+        assert {s["name"] for s in exported} == {"User", "Greeter", "Greet", "Add", "HelperFunction"}
+
+
+def test_python_private_names_can_be_excluded():
+    code = "class _Impl:\n    def run(self):\n        pass\n\nclass Api:\n    def __init__(self):\n        pass\n\n    def _helper(self):\n        pass\n\ndef _private():\n    pass\n"
+    with tempfile.TemporaryDirectory() as tmpdir:
+        with open(os.path.join(tmpdir, "api.py"), "w") as f:
+            f.write(code)
+        repository = Repository(tmpdir)
+        exported = repository.extract_symbols("api.py", ExtractionOptions(include_unexported=False))
+
+        assert {s.get("node_path", s["name"]) for s in exported} == {"Api", "Api.__init__"}
+
+
+def test_go_methods_in_separate_file_link_to_type():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        with open(os.path.join(tmpdir, "user.go"), "w") as f:
+            f.write("package user\n\ntype User struct {\n\tName string\n}\n")
+        with open(os.path.join(tmpdir, "user_methods.go"), "w") as f:
+            f.write("package user\n\nfunc (u *User) Rename(name string) {\n\tu.Name = name\n}\n")
+
+        repository = Repository(tmpdir)
+        type_names = {s["name"] for s in repository.extract_symbols("user.go") if s["type"] == "struct"}
+        rename = next(s for s in repository.extract_symbols("user_methods.go") if s["name"] == "Rename")
+
+        assert rename["parent"] in type_names
+        assert rename["receiver"] == "*User"
+
+
+def test_go_generics_symbols():
+    golden_content = open(os.path.join(os.path.dirname(__file__), "golden_go_generics.go")).read()
+    with tempfile.TemporaryDirectory() as tmpdir:
+        symbols = run_extraction(tmpdir, "golden_go_generics.go", golden_content)
+        names_types = {(s["name"], s["type"]) for s in symbols}
+        by_name = {s["name"]: s for s in symbols if s["type"] != "method"}
+        methods = {s["name"]: s for s in symbols if s["type"] == "method"}
+
+        assert names_types == {
+            ("Number", "interface"),
+            ("Set", "struct"),
+            ("Pair", "struct"),
+            ("Container", "interface"),
+            ("Add", "method"),
+            ("Len", "method"),
+            ("Map", "function"),
+            ("Sum", "function"),
+            ("Zip", "function"),
+        }
+
+        # Constrained, multiple and shared-constraint type parameters
+        assert by_name["Set"]["type_params"] == [{"name": "T", "constraint": "comparable"}]
+        assert by_name["Pair"]["type_params"] == [
+            {"name": "K", "constraint": "comparable"},
+            {"name": "V", "constraint": "any"},
+        ]
+        assert by_name["Zip"]["type_params"] == [
+            {"name": "K", "constraint": "any"},
+            {"name": "V", "constraint": "any"},
+        ]
+        assert by_name["Sum"]["type_params"] == [{"name": "T", "constraint": "Number"}]
+        assert by_name["Container"]["type_params"] == [{"name": "T", "constraint": "any"}]
+        assert "type_params" not in by_name["Number"]
+
+        assert by_name["Map"]["signature"] == "func Map[T any, U any](in []T, f func(T) U) []U"
+        # Parameters sharing a constraint keep the shorthand they were written in
+        assert by_name["Zip"]["signature"] == "func Zip[K, V any](keys []K, values []V) []Pair[K, V]"
+        assert by_name["Set"]["signature"] == "type Set[T comparable] struct"
+        assert by_name["Pair"]["signature"] == "type Pair[K comparable, V any] struct"
+        assert by_name["Container"]["signature"] == "type Container[T any] interface"
+
+        # Methods on generic receivers resolve to the base type
+        assert methods["Add"]["receiver"] == "*Set[T]"
+        assert methods["Add"]["parent"] == "Set"
+        assert methods["Len"]["node_path"] == "Set.Len"
+
+
+def test_go_generic_signature_round_trips_through_json():
+    golden_content = open(os.path.join(os.path.dirname(__file__), "golden_go_generics.go")).read()
+    with tempfile.TemporaryDirectory() as tmpdir:
+        symbols = run_extraction(tmpdir, "golden_go_generics.go", golden_content)
+
+    output = symbols_to_json(symbols)
+    assert '"signature": "func Map[T any, U any](in []T, f func(T) U) []U"' in output
+    parsed = {s["name"]: s for s in json.loads(output) if s["type"] == "function"}
+    assert parsed["Map"]["signature"] == "func Map[T any, U any](in []T, f func(T) U) []U"
+
+
+GO_DEPRECATED = """package client
+
+// Connect opens a connection.
+//
+// Example:
+//
+//	c := Connect("localhost")
+//	    defer c.Close()
+//
+// Deprecated: Use Dial, which supports
+// timeouts.
+func Connect(addr string) *Conn { return nil }
+
+// Dial opens a connection with a timeout.
+func Dial(addr string) *Conn { return nil }
+
+// Deprecated: Timeout is ignored.
+const Timeout = 5
+"""
+
+
+def test_go_deprecation_notices_and_raw_doc_examples():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        symbols = run_extraction(tmpdir, "client.go", GO_DEPRECATED)
+        by_name = {s["name"]: s for s in symbols}
+
+    connect = by_name["Connect"]
+    assert connect["deprecated"] is True
+    assert connect["deprecated_note"] == "Use Dial, which supports timeouts."
+    # The example keeps its tab and inner indentation
+    assert '\n\n\tc := Connect("localhost")\n\t    defer c.Close()\n\n' in connect["docstring"]
+    assert by_name["Timeout"]["deprecated_note"] == "Timeout is ignored."
+    assert "deprecated" not in by_name["Dial"]
+
+
+def test_go_positions_and_doc_lines():
+    code = """package main
+
+// Größe returns the size.
+func Größe() int { return 1 }
+
+func plain() {}
+
+type (
+	// Inner is documented inside a group.
+	Inner struct{}
+)
+"""
+    with tempfile.TemporaryDirectory() as tmpdir:
+        symbols = run_extraction(tmpdir, "pos.go", code)
+        by_name = {s["name"]: s for s in symbols}
+
+        groesse = by_name["Größe"]
+        # start_line points at `func`, not at the comment above it
+        assert (groesse["start_line"], groesse["start_column"]) == (3, 0)
+        assert groesse["doc_start_line"] == 2
+        # Columns count characters, so the end column is unaffected by the multi-byte name
+        assert (groesse["end_line"], groesse["end_column"]) == (3, len("func Größe() int { return 1 }"))
+
+        assert "doc_start_line" not in by_name["plain"]
+
+        inner = by_name["Inner"]
+        assert (inner["start_line"], inner["start_column"]) == (9, 1)
+        assert inner["doc_start_line"] == 8
+
+
+def test_go_consts_and_vars():
+    golden_content = open(os.path.join(os.path.dirname(__file__), "golden_go_values.go")).read()
+    with tempfile.TemporaryDirectory() as tmpdir:
+        symbols = run_extraction(tmpdir, "golden_go_values.go", golden_content)
+        values = {s["name"]: s for s in symbols if s["type"] in ("constant", "variable")}
+
+        # Blank identifiers and function-local declarations are skipped
+        assert set(values) == {
+            "MaxRetries",
+            "Timeout",
+            "KindFile",
+            "KindDir",
+            "KindLink",
+            "KB",
+            "MB",
+            "ErrNotFound",
+            "x",
+            "y",
+            "DefaultName",
+        }
+        assert {n for n, s in values.items() if s["type"] == "constant"} == {
+            "MaxRetries",
+            "Timeout",
+            "KindFile",
+            "KindDir",
+            "KindLink",
+            "KB",
+            "MB",
+        }
+
+        assert values["MaxRetries"]["signature"] == "const MaxRetries"
+        assert values["Timeout"]["signature"] == "const Timeout time.Duration"
+        assert values["Timeout"]["docstring"] == "Timeout is the default request timeout."
+        assert values["ErrNotFound"]["signature"] == "var ErrNotFound"
+        assert values["ErrNotFound"]["docstring"] == "ErrNotFound is returned when a key is missing."
+        assert values["DefaultName"]["signature"] == "var DefaultName string"
+        assert values["DefaultName"]["docstring"] == "DefaultName is used when no name is configured."
+
+        # iota members share the block's range and inherit the block's type
+        iota_names = ("KindFile", "KindDir", "KindLink")
+        iota_block = [values[n] for n in iota_names]
+        assert len({(s["start_line"], s["end_line"]) for s in iota_block}) == 1
+        assert iota_block[0]["end_line"] > iota_block[0]["start_line"]
+        assert [s["signature"] for s in iota_block] == [
+            "const KindFile Kind",
+            "const KindDir Kind",
+            "const KindLink Kind",
+        ]
+        assert values["KindFile"]["docstring"] == "KindFile is a regular file."
+        assert "docstring" not in values["KindDir"]
+
+        # One symbol per name in a multi-name spec
+        assert values["x"]["code"] == values["y"]["code"] == "var x, y = 1, 2"
+
+        # Value expressions, with declared or literal-inferred types
+        assert (values["MaxRetries"]["value"], values["MaxRetries"]["value_type"]) == ("3", "int")
+        assert (values["Timeout"]["value"], values["Timeout"]["value_type"]) == ("5 * time.Second", "time.Duration")
+        assert (values["x"]["value"], values["y"]["value"]) == ("1", "2")
+        assert values["DefaultName"]["value"] == '"codekite"'
+        assert values["ErrNotFound"]["value"] == 'errors.New("not found")'
+        assert "value_type" not in values["ErrNotFound"]
+        assert "iota" not in values["MaxRetries"]
+
+        # Implicitly repeated iota expressions are resolved per spec; `_` still counts
+        assert [(values[n]["value"], values[n]["iota"], values[n]["resolved_value"]) for n in iota_names] == [
+            ("iota", 0, 0),
+            ("iota", 1, 1),
+            ("iota", 3, 3),
+        ]
+        assert [(values[n]["value_type"], values[n]["resolved_value"]) for n in ("KB", "MB")] == [
+            ("int", 1024),
+            ("int", 1048576),
+        ]
+        assert values["MB"]["value"] == "1 << (10 * iota)"
+
+
+def test_go_iota_shift_counts_out_of_range_are_left_unresolved():
+    code = """package bits
+
+const (
+	Max = 1 << (512 + iota)
+	Huge
+)
+
+const Wide = 1 << (1000000000 * (iota + 1))
+"""
+    with tempfile.TemporaryDirectory() as tmpdir:
+        symbols = run_extraction(tmpdir, "bits.go", code)
+    values = {s["name"]: s for s in symbols}
+
+    assert values["Max"]["resolved_value"] == 1 << 512
+    # Go rejects shifts past 512 bits, so neither is evaluated
+    assert values["Huge"]["iota"] == 1 and "resolved_value" not in values["Huge"]
+    assert "resolved_value" not in values["Wide"]
+
+
+def test_go_iota_expressions_follow_go_precedence():
+    code = """package bits
+
+const (
+	Mask0 = 1<<iota - 1
+	Mask1
+	Mask2
+)
+
+const (
+	Third = 1<<62/3 + iota
+	Down  = (iota - 8) / 2
+	Rem   = (iota - 9) % 2
+	Huge  = (1 << 512) * (1 << (509 + iota)) / 3
+)
+"""
+    with tempfile.TemporaryDirectory() as tmpdir:
+        symbols = run_extraction(tmpdir, "bits.go", code)
+    values = {s["name"]: s for s in symbols}
+
+    # << binds tighter than - in Go, so each mask is (1 << iota) - 1
+    assert [values[n]["resolved_value"] for n in ("Mask0", "Mask1", "Mask2")] == [0, 1, 3]
+    assert values["Third"]["resolved_value"] == 1537228672809129301
+    # integer division truncates toward zero
+    assert values["Down"]["resolved_value"] == -3
+    assert values["Rem"]["resolved_value"] == -1
+    assert "resolved_value" not in values["Huge"]
+
+
+def test_typescript_exports_and_jsdoc():
+    golden_content = open(os.path.join(os.path.dirname(__file__), "golden_typescript_exports.ts")).read()
+    with tempfile.TemporaryDirectory() as tmpdir:
+        symbols = run_extraction(tmpdir, "golden_typescript_exports.ts", golden_content)
+        by_name = {s["name"]: s for s in symbols}
+
+        assert {(s["name"], s["type"]) for s in symbols} == {
+            ("User", "interface"),
+            ("UserId", "type"),
+            ("InternalState", "type"),
+            ("Greeter", "class"),
+            ("constructor", "method"),
+            ("greet", "method"),
+            ("Cache", "class"),
+            ("get", "method"),
+            ("formatUser", "function"),
+            ("slugify", "function"),
+            ("parse", "function"),
+            ("loadUsers", "function"),
+            ("helper", "function"),
+        }
+        # Function-local arrow functions are not module-scope symbols
+        assert "local" not in by_name
+        # Each declaration is reported once even when exported
+        assert len([s for s in symbols if s["name"] == "Greeter"]) == 1
+
+        exported = {s["name"] for s in symbols if s["exported"]}
+        assert exported == {"User", "UserId", "Greeter", "constructor", "greet", "formatUser", "parse", "loadUsers"}
+
+        assert by_name["User"]["docstring"] == "A user of the application."
+        assert by_name["UserId"]["docstring"] == "Identifier type for users."
+        assert by_name["Greeter"]["docstring"] == (
+            "Greets users by name.\n\n@example\n  new Greeter(\"Hi\").greet(user)"
+        )
+        assert by_name["greet"]["docstring"] == "Builds a greeting for the given user."
+        assert by_name["formatUser"]["docstring"] == "Formats a user for display."
+        assert "docstring" not in by_name["loadUsers"]
+        assert "docstring" not in by_name["slugify"]
+
+        assert by_name["greet"]["node_path"] == "Greeter.greet"
+        assert by_name["get"]["parent"] == "Cache"
+        # The definition spans the whole declaration, not just the name
+        assert by_name["formatUser"]["code"].startswith("formatUser = (user: User)")
+
+
+def test_javascript_exports_and_arrow_functions():
+    code = """/** Adds two numbers. */
+export const add = (a, b) => a + b;
+
+export class Counter {
+    increment() {}
+}
+
+function internal() {}
+"""
+    with tempfile.TemporaryDirectory() as tmpdir:
+        symbols = run_extraction(tmpdir, "example.js", code)
+        by_name = {s["name"]: s for s in symbols}
+
+        assert {(s["name"], s["type"]) for s in symbols} == {
+            ("add", "function"),
+            ("Counter", "class"),
+            ("increment", "method"),
+            ("internal", "function"),
+        }
+        assert by_name["add"]["exported"] is True
+        assert by_name["internal"]["exported"] is False
+        assert by_name["add"]["docstring"] == "Adds two numbers."
+        assert by_name["increment"]["node_path"] == "Counter.increment"
+
+
+def test_tsx_component_symbol_extraction():
+    golden_content = open(os.path.join(os.path.dirname(__file__), "golden_typescript_component.tsx")).read()
+    with tempfile.TemporaryDirectory() as tmpdir:
+        symbols = run_extraction(tmpdir, "golden_typescript_component.tsx", golden_content)
+        by_name = {s["name"]: s for s in symbols}
+
+        # JSX must not hide the declarations around it
+        assert {(s["name"], s["type"]) for s in symbols} == {
+            ("ButtonProps", "interface"),
+            ("Variant", "type"),
+            ("Size", "enum"),
+            ("Button", "function"),
+            ("Badge", "function"),
+            ("Counter", "class"),
+            ("increment", "method"),
+            ("render", "method"),
+            ("default", "function"),
+        }
+        assert {s["name"] for s in symbols if not s["exported"]} == {"Badge"}
+        assert by_name["Button"]["docstring"] == "A clickable button."
+        assert by_name["default"]["docstring"] == "The page rendered by default."
+        assert by_name["render"]["node_path"] == "Counter.render"
+
+
+def test_typescript_default_exports():
+    code = """export default function loadConfig() {}
+"""
+    anonymous = """export default function () {}
+"""
+    with tempfile.TemporaryDirectory() as tmpdir:
+        named = run_extraction(tmpdir, "named.ts", code)
+        unnamed = run_extraction(tmpdir, "unnamed.ts", anonymous)
+
+    assert [(s["name"], s["exported"]) for s in named] == [("loadConfig", True)]
+    assert [(s["name"], s["exported"]) for s in unnamed] == [("default", True)]
+
+
+def test_go_struct_fields():
+    golden_content = open(os.path.join(os.path.dirname(__file__), "fixtures", "go", "golden_go.go")).read()
+    code = """package model
+
+import "sync"
+
+type Base struct{}
+
+type Account struct {
+	Base
+	*sync.Mutex
+	ID        int64  `json:"id" db:"account_id"`
+	Name      string "json:\\"name\\""
+	X, Y      float64
+	Tags      map[string][]string `json:"tags,omitempty"`
+}
+"""
+    with tempfile.TemporaryDirectory() as tmpdir:
+        golden = {s["name"]: s for s in run_extraction(tmpdir, "golden_go.go", golden_content)}
+        symbols = {s["name"]: s for s in run_extraction(tmpdir, "model.go", code)}
+
+    assert golden["User"]["fields"] == [
+        {"name": "ID", "type": "int", "embedded": False},
+        {"name": "Name", "type": "string", "embedded": False},
+    ]
+    assert "fields" not in golden["Greeter"]
+    assert symbols["Base"]["fields"] == []
+
+    fields = symbols["Account"]["fields"]
+    assert [(f["name"], f["type"], f["embedded"]) for f in fields] == [
+        ("Base", "Base", True),
+        ("Mutex", "*sync.Mutex", True),
+        ("ID", "int64", False),
+        ("Name", "string", False),
+        ("X", "float64", False),
+        ("Y", "float64", False),
+        ("Tags", "map[string][]string", False),
+    ]
+    tags = {f["name"]: f.get("tag") for f in fields}
+    assert tags["ID"] == 'json:"id" db:"account_id"'
+    assert tags["Name"] == 'json:"name"'
+    assert tags["Tags"] == 'json:"tags,omitempty"'
+    assert tags["X"] is None
+    # Parsed as reflect.StructTag does, with values unquoted
+    assert fields[2]["tags"] == {"json": "id", "db": "account_id"}
+    assert fields[3]["tags"] == {"json": "name"}
+    assert fields[6]["tags"] == {"json": "tags,omitempty"}
+    assert "tags" not in fields[4]
+
+
+def test_rust_symbol_extraction():
+    golden_content = open(os.path.join(os.path.dirname(__file__), "golden_rust.rs")).read()
+    with tempfile.TemporaryDirectory() as tmpdir:
+        symbols = run_extraction(tmpdir, "golden_rust.rs", golden_content)
+
+    assert {(s["name"], s["type"]) for s in symbols} == {
+        ("Foo", "struct"),
+        ("Foo", "impl"),
+        ("new", "method"),
+        ("bar", "method"),
+        ("MyEnum", "enum"),
+        ("MyTrait", "trait"),
+        ("do_it", "method"),
+        ("free_function", "function"),
+        ("MAX_RETRIES", "constant"),
+        ("GREETING", "static"),
+        ("Wrapper", "struct"),
+        ("Wrapper", "impl"),
+        ("get", "method"),
+        ("Greeter", "trait"),
+        ("name", "method"),
+        ("greet", "method"),
+        ("Result", "type"),
+        ("util", "module"),
+        ("helper", "function"),
+        ("square", "macro"),
+        ("Store", "trait"),
+        ("load", "method"),
+        ("exists", "method"),
+        ("Internal", "struct"),
+        ("Internal", "impl"),
+        ("reset", "method"),
+    }
+    # Nested fns inside function bodies are not symbols
+    assert "inner" not in {s["name"] for s in symbols}
+
+    by_path = {(s.get("node_path") or s["name"], s["type"]): s for s in symbols}
+    assert by_path[("Foo.new", "method")]["signature"] == "pub fn new() -> Self"
+    assert by_path[("Wrapper.get", "method")]["docstring"] == "Returns a copy of the wrapped value."
+    assert by_path[("Wrapper", "struct")]["docstring"] == "A wrapper around any value.\n\nWrappers are cheap to clone."
+    assert by_path[("Wrapper", "impl")]["target"] == "Wrapper<T>"
+    assert by_path[("MAX_RETRIES", "constant")]["docstring"] == "Maximum number of retries."
+    assert by_path[("util", "module")]["docstring"] == "Helpers shared across the crate."
+    assert "docstring" not in by_path[("Foo", "impl")]
+
+    # Trait impls record both the trait and the implementing type
+    trait_impls = [s for s in symbols if s["type"] == "impl" and s.get("trait")]
+    assert [(s["name"], s["trait"]) for s in trait_impls] == [("Foo", "Greeter")]
+    assert by_path[("Foo.name", "method")]["trait"] == "Greeter"
+    assert "trait" not in by_path[("Foo.new", "method")]
+
+    # Trait methods attach to the trait, with or without a default body
+    assert by_path[("Greeter.greet", "method")]["docstring"] == (
+        "Greets using the name; implementors usually keep this default."
+    )
+    assert by_path[("MyTrait.do_it", "method")]["signature"] == "fn do_it(&self)"
+
+
+def test_rust_visibility_and_trait_method_sets():
+    golden_content = open(os.path.join(os.path.dirname(__file__), "golden_rust.rs")).read()
+    with tempfile.TemporaryDirectory() as tmpdir:
+        symbols = run_extraction(tmpdir, "golden_rust.rs", golden_content)
+    by_path = {(s.get("node_path") or s["name"], s["type"]): s for s in symbols}
+
+    exported = {path for path, s in by_path.items() if s.get("exported", True)}
+    assert {("Foo", "struct"), ("Foo.new", "method"), ("Store", "trait"), ("util", "module")} <= exported
+    # pub(crate) stays inside the crate; trait and trait-impl methods follow the trait
+    for hidden in [("free_function", "function"), ("GREETING", "static"), ("Internal", "struct"), ("Internal.reset", "method")]:
+        assert hidden not in exported
+    assert by_path[("Store.load", "method")]["exported"] is True
+    assert by_path[("Foo.name", "method")]["exported"] is True
+
+    store = by_path[("Store", "trait")]
+    assert store["methods"] == ["fn load(&self, key: &str) -> Option<String>", "fn exists(&self, key: &str) -> bool"]
+    assert store["provided"] == ["fn exists(&self, key: &str) -> bool"]
+    assert store["supertraits"] == ["Send", "Sync"]
+    assert store["docstring"] == "Storage backend."
+    assert by_path[("Store.load", "method")]["docstring"] == "Loads a value."
+    assert by_path[("MyTrait", "trait")]["methods"] == ["fn do_it(&self)"]
+    assert "provided" not in by_path[("MyTrait", "trait")]
+
+
+def test_go_partial_parse_of_broken_body():
+    golden_content = open(os.path.join(os.path.dirname(__file__), "golden_go_broken.go")).read()
+    with tempfile.TemporaryDirectory() as tmpdir:
+        with open(os.path.join(tmpdir, "golden_go_broken.go"), "w") as f:
+            f.write(golden_content)
+        symbols, errors = Repository(tmpdir).parse_file("golden_go_broken.go")
+
+    by_name = {s["name"]: s for s in symbols}
+    # Declarations on both sides of the broken body are recovered
+    assert {"Item", "Total", "Discount", "Count"} <= set(by_name)
+    assert by_name["Discount"]["has_errors"] is True
+    assert by_name["Discount"]["signature"] == "func Discount(items []Item, percent int) int"
+    assert not any(by_name[name].get("has_errors") for name in ("Item", "Total", "Count"))
+    assert by_name["Count"]["docstring"] == "Count still comes after the broken function."
+
+    assert errors
+    discount = by_name["Discount"]
+    assert all(discount["start_line"] <= e["start_line"] <= discount["end_line"] for e in errors)
+    assert all(e["message"] for e in errors)
+
+
+def test_java_symbol_extraction():
+    golden_content = open(os.path.join(os.path.dirname(__file__), "golden_java.java")).read()
+    with tempfile.TemporaryDirectory() as tmpdir:
+        symbols = run_extraction(tmpdir, "golden_java.java", golden_content)
+
+    assert {(s.get("node_path") or s["name"], s["type"]) for s in symbols} == {
+        ("Cart", "class"),
+        ("Cart.MAX_ITEMS", "field"),
+        ("Cart.items", "field"),
+        ("Cart.version", "field"),
+        ("Cart.revision", "field"),
+        ("Cart.Cart", "method"),
+        ("Cart.add", "method"),
+        ("Cart.total", "method"),
+        ("Cart.copy", "method"),
+        ("Cart.reset", "method"),
+        ("Cart.Item", "class"),
+        ("Cart.Item.price", "field"),
+        ("Cart.Item.Item", "method"),
+        ("Cart.Item.price", "method"),
+        ("Cart.Audit", "class"),
+        ("Cart.Audit.record", "method"),
+        ("Cart.Listener", "interface"),
+        ("Cart.Listener.onChange", "method"),
+        ("Priced", "interface"),
+        ("Priced.CURRENCY", "constant"),
+        ("Priced.total", "method"),
+        ("Shipping", "enum"),
+        ("Shipping.isFast", "method"),
+    }
+
+    by_path = {(s.get("node_path") or s["name"], s["type"]): s for s in symbols}
+    assert by_path[("Cart.Item.price", "method")]["parent"] == "Cart.Item"
+    assert by_path[("Cart", "class")]["signature"] == "public class Cart implements Priced"
+    assert by_path[("Cart.Cart", "method")]["signature"] == "public Cart()"
+    assert by_path[("Cart.copy", "method")]["signature"] == "protected static List<Item> copy(List<Item> source)"
+    assert by_path[("Priced.total", "method")]["signature"] == "long total()"
+    assert by_path[("Cart.MAX_ITEMS", "field")]["signature"] == "public static final int MAX_ITEMS"
+    # Several fields declared together each get a signature of their own
+    assert by_path[("Cart.revision", "field")]["signature"] == "int revision"
+
+    assert by_path[("Cart", "class")]["docstring"] == "A shopping cart holding line items.\n\nCarts are not thread-safe."
+    assert by_path[("Cart.add", "method")]["docstring"] == "Adds an item to the cart.\n\n@param item the item to add"
+    assert by_path[("Cart.MAX_ITEMS", "field")]["docstring"] == "Most items a cart may hold."
+    assert by_path[("Priced.CURRENCY", "constant")]["docstring"] == "Currency of every price."
+    assert by_path[("Shipping", "enum")]["docstring"] == "How an order ships."
+    assert "docstring" not in by_path[("Cart.total", "method")]
+
+
+def test_java_modifiers_annotations_and_visibility():
+    golden_content = open(os.path.join(os.path.dirname(__file__), "golden_java.java")).read()
+    with tempfile.TemporaryDirectory() as tmpdir:
+        symbols = run_extraction(tmpdir, "golden_java.java", golden_content)
+    by_path = {(s.get("node_path") or s["name"], s["type"]): s for s in symbols}
+
+    assert by_path[("Cart.MAX_ITEMS", "field")]["modifiers"] == ["public", "static", "final"]
+    assert by_path[("Cart.items", "field")]["modifiers"] == ["private", "final"]
+    assert "modifiers" not in by_path[("Cart.version", "field")]
+    assert by_path[("Cart.total", "method")]["annotations"] == ["Override"]
+    copy = by_path[("Cart.copy", "method")]
+    assert copy["annotations"] == ["Deprecated", 'SuppressWarnings("unchecked")']
+    assert copy["modifiers"] == ["protected", "static"]
+    assert "annotations" not in by_path[("Cart.add", "method")]
+
+    exported = {path for path, s in by_path.items() if s["exported"]}
+    assert exported == {
+        ("Cart", "class"),
+        ("Cart.MAX_ITEMS", "field"),
+        ("Cart.Cart", "method"),
+        ("Cart.add", "method"),
+        ("Cart.total", "method"),
+        ("Cart.copy", "method"),
+        ("Cart.Item", "class"),
+        ("Cart.Item.price", "method"),
+        # Interface members are public without saying so
+        ("Cart.Listener", "interface"),
+        ("Cart.Listener.onChange", "method"),
+    }
+    # Public members of private or package-private types stay hidden
+    assert not by_path[("Cart.Audit.record", "method")]["exported"]
+    assert not by_path[("Priced.total", "method")]["exported"]
+
+
+def test_java_records_annotation_types_and_anonymous_classes():
+    golden_content = open(os.path.join(os.path.dirname(__file__), "golden_java_types.java")).read()
+    with tempfile.TemporaryDirectory() as tmpdir:
+        symbols = run_extraction(tmpdir, "golden_java_types.java", golden_content)
+    by_path = {(s.get("node_path") or s["name"], s["type"]): s for s in symbols}
+
+    assert set(by_path) == {
+        ("Point", "record"),
+        ("Point.x", "field"),
+        ("Point.y", "field"),
+        ("Point.Point", "method"),
+        ("Point.distanceTo", "method"),
+        ("Size", "enum"),
+        ("Size.weight", "field"),
+        ("Size.Size", "method"),
+        ("Size.weight", "method"),
+        ("Audited", "annotation"),
+        ("Audited.value", "method"),
+        ("Handler", "interface"),
+        ("Handler.handle", "method"),
+        ("Shelf", "class"),
+        ("Shelf.items", "field"),
+        ("Shelf.Shelf", "method"),
+        ("Shelf.sortBy", "method"),
+        ("Shelf.printer", "method"),
+        # Anonymous classes are numbered within their enclosing type, as javac does
+        ("Shelf.1", "class"),
+        ("Shelf.1.compare", "method"),
+        ("Shelf.2", "class"),
+        ("Shelf.2.run", "method"),
+    }
+
+    point = by_path[("Point", "record")]
+    assert point["signature"] == "public record Point(int x, int y)"
+    assert point["docstring"].startswith("A point on the warehouse floor.\n\n@param x")
+    assert by_path[("Point.x", "field")]["signature"] == "int x"
+    assert by_path[("Point.x", "field")]["exported"]
+    assert by_path[("Point.Point", "method")]["docstring"] == "Rejects points outside the floor."
+    assert by_path[("Size.weight", "method")]["docstring"] == "Weight in kilograms,\nrounded up."
+    assert by_path[("Size.weight", "field")]["modifiers"] == ["private", "final"]
+
+    assert by_path[("Audited", "annotation")]["signature"] == "@interface Audited"
+    assert by_path[("Audited.value", "method")]["parent"] == "Audited"
+    handler = by_path[("Handler", "interface")]
+    assert handler["annotations"] == ["FunctionalInterface"]
+    assert handler["type_params"] == ["E"]
+    assert by_path[("Shelf", "class")]["type_params"] == ["T extends Comparable<T>"]
+    assert by_path[("Shelf.sortBy", "method")]["type_params"] == ["K"]
+
+    comparator = by_path[("Shelf.1", "class")]
+    assert comparator["anonymous"] and comparator["extends"] == "Comparator<T>"
+    assert comparator["signature"] == "new Comparator<T>()"
+    assert not comparator["exported"]
+    assert by_path[("Shelf.2", "class")]["extends"] == "Runnable"
+    assert by_path[("Shelf.1.compare", "method")]["parent"] == "Shelf.1"
+
+
+def test_c_header_symbols():
+    golden_content = open(os.path.join(os.path.dirname(__file__), "golden_c.h")).read()
+    with tempfile.TemporaryDirectory() as tmpdir:
+        symbols = run_extraction(tmpdir, "golden_c.h", golden_content)
+
+    # The SENSOR_H include guard is not a symbol; both branches of #ifdef SENSOR_DEBUG are
+    assert sorted((s["name"], s["type"]) for s in symbols) == [
+        ("CLAMP", "macro"),
+        ("Calibration", "struct"),
+        ("SENSOR_BUFFER", "macro"),
+        ("Sensor", "struct"),
+        ("reading", "struct"),
+        ("sensor_dump", "function"),
+        ("sensor_dump", "macro"),
+        ("sensor_id", "type"),
+        ("sensor_name", "function"),
+        ("sensor_read", "function"),
+        ("sensor_state", "enum"),
+    ]
+
+    by_name = {(s["name"], s["type"]): s for s in symbols}
+    read = by_name[("sensor_read", "function")]
+    assert read["signature"] == "int sensor_read(Sensor *sensor, struct reading *out)"
+    assert read["prototype"] is True
+    assert read["docstring"] == "Reads one sample; returns 0 on success."
+    assert by_name[("sensor_name", "function")]["signature"] == "const char *sensor_name(const Sensor *sensor)"
+    assert "docstring" not in by_name[("sensor_name", "function")]
+
+    assert by_name[("reading", "struct")]["docstring"] == "One sample from a sensor.\n\nTimestamps are milliseconds since boot."
+    assert by_name[("Sensor", "struct")]["typedef"] is True
+    assert by_name[("Calibration", "struct")]["tag"] == "calibration"
+    assert by_name[("sensor_id", "type")]["signature"] == "typedef uint16_t sensor_id"
+
+    clamp = by_name[("CLAMP", "macro")]
+    assert clamp["signature"] == "#define CLAMP(x, lo, hi)"
+    assert clamp["docstring"] == "Keeps a value within [lo, hi]."
+    assert by_name[("SENSOR_BUFFER", "macro")]["value"] == "32"
+
+
+def test_c_source_definitions_pair_with_header_prototypes():
+    here = os.path.dirname(__file__)
+    with tempfile.TemporaryDirectory() as tmpdir:
+        header = run_extraction(tmpdir, "golden_c.h", open(os.path.join(here, "golden_c.h")).read())
+        source = run_extraction(tmpdir, "golden_c.c", open(os.path.join(here, "golden_c.c")).read())
+
+    definitions = {s["name"]: s for s in source if s["type"] == "function"}
+    assert sorted(definitions) == ["apply_offset", "sensor_dump", "sensor_legacy_id", "sensor_name", "sensor_read"]
+    assert not any(s.get("prototype") for s in definitions.values())
+
+    # Each prototype in the header has a definition with the same signature, however the source is laid out
+    prototypes = {s["name"]: s for s in header if s["type"] == "function"}
+    for name, prototype in prototypes.items():
+        assert prototype["prototype"] is True
+        assert definitions[name]["signature"] == prototype["signature"]
+    # The return type on a line of its own does not end up as the name
+    assert definitions["apply_offset"]["signature"] == "static int16_t apply_offset(int16_t value)"
+    assert "docstring" not in definitions["apply_offset"]
+
+    legacy = definitions["sensor_legacy_id"]
+    assert legacy["signature"] == "int sensor_legacy_id(sensor)"
+    assert legacy["docstring"] == "Old-style definition kept for the bootloader build."
+
+
+def test_cpp_classes_and_methods():
+    code = """
+namespace shop {
+
+/** A cart of line items. */
+class Cart {
+public:
+    Cart();
+    void add(int sku);
+    int size() const { return count; }
+private:
+    int count;
+};
+
+void Cart::add(int sku) { count++; }
+
+}  // namespace shop
+
+#define CART_MAX 16
+"""
+    with tempfile.TemporaryDirectory() as tmpdir:
+        symbols = run_extraction(tmpdir, "cart.hpp", code)
+
+    assert {(s.get("node_path") or s["name"], s["type"]) for s in symbols} == {
+        ("shop", "namespace"),
+        ("Cart", "class"),
+        ("Cart.Cart", "method"),
+        ("Cart.add", "method"),
+        ("Cart.size", "method"),
+        ("CART_MAX", "macro"),
+    }
+    by_path = {(s.get("node_path") or s["name"], s.get("prototype", False)): s for s in symbols}
+    assert by_path[("Cart.add", True)]["signature"] == "void add(int sku)"
+    assert by_path[("Cart.add", False)]["signature"] == "void Cart::add(int sku)"
+    assert by_path[("Cart", False)]["docstring"] == "A cart of line items."
+
+
+def test_go_interface_method_sets():
+    golden_content = open(os.path.join(os.path.dirname(__file__), "fixtures", "go", "golden_go.go")).read()
+    code = """package rw
+
+import "io"
+
+type Reader interface {
+	Read(p []byte) (n int, err error)
+}
+
+type ReadCloser interface {
+	Reader
+	io.Closer
+	// Reset discards buffered data.
+	Reset()
+}
+
+type Number interface {
+	~int | ~float64
+}
+"""
+    with tempfile.TemporaryDirectory() as tmpdir:
+        golden = {s["name"]: s for s in run_extraction(tmpdir, "golden_go.go", golden_content)}
+        symbols = {s["name"]: s for s in run_extraction(tmpdir, "rw.go", code)}
+
+    assert golden["Greeter"]["methods"] == ["Greet() string"]
+    assert "embeds" not in golden["Greeter"]
+    assert "methods" not in golden["User"]
+
+    assert symbols["Reader"]["methods"] == ["Read(p []byte) (n int, err error)"]
+    assert symbols["ReadCloser"]["methods"] == ["Reset()"]
+    assert symbols["ReadCloser"]["embeds"] == ["Reader", "io.Closer"]
+    assert symbols["Number"]["methods"] == []
+    assert "embeds" not in symbols["Number"]
+
+
+def test_bash_functions_and_top_level_variables():
+    golden_content = open(os.path.join(os.path.dirname(__file__), "golden_bash.sh")).read()
+    with tempfile.TemporaryDirectory() as tmpdir:
+        symbols = run_extraction(tmpdir, "golden_bash.sh", golden_content)
+
+    # Assignments inside functions and the if block are not symbols
+    assert sorted((s["start_line"], s["name"], s["type"]) for s in symbols) == [
+        (5, "DEPLOY_ROOT", "variable"),
+        (6, "ENVIRONMENT", "variable"),
+        (7, "MAX_RETRIES", "constant"),
+        (8, "HOSTS", "variable"),
+        (12, "log", "function"),
+        (19, "deploy", "function"),
+        (29, "rollback", "function"),
+        (34, "cleanup", "function"),
+    ]
+
+    by_name = {s["name"]: s for s in symbols}
+    assert (by_name["log"]["start_line"], by_name["log"]["end_line"]) == (12, 16)
+    assert by_name["log"]["signature"] == "log()"
+    assert by_name["log"]["docstring"] == 'Prints a message with a timestamp.\n  log "starting"'
+    assert by_name["deploy"]["signature"] == "function deploy"
+    assert by_name["deploy"]["docstring"] == "Copies the release to every host, retrying each one."
+    assert by_name["rollback"]["signature"] == "function rollback()"
+    # Neither a directive nor the shebang is documentation
+    assert "docstring" not in by_name["cleanup"]
+    assert "docstring" not in by_name["rollback"]
+
+    assert by_name["DEPLOY_ROOT"]["docstring"] == "Where the release is unpacked."
+    assert by_name["DEPLOY_ROOT"]["value"] == '"/srv/shop"'
+    assert "modifiers" not in by_name["DEPLOY_ROOT"]
+    assert by_name["ENVIRONMENT"]["modifiers"] == ["export"]
+    assert by_name["MAX_RETRIES"]["modifiers"] == ["readonly"]
+    assert by_name["MAX_RETRIES"]["value"] == "3"
+    assert by_name["HOSTS"]["modifiers"] == ["declare", "-a"]
+    assert by_name["HOSTS"]["value"] == "(web1 web2)"
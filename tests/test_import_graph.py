@@ -0,0 +1,68 @@
+import os
+import tempfile
+
+from codekite import Repository
+from codekite.import_graph import build_import_graph, detect_cycles, extract_go_imports
+
+
+def write_files(tmpdir, files):
+    for rel_path, content in files.items():
+        path = os.path.join(tmpdir, rel_path)
+        os.makedirs(os.path.dirname(path), exist_ok=True)
+        with open(path, "w") as f:
+            f.write(content)
+
+
+def test_extract_go_imports_flags_alias_dot_and_blank():
+    source = """package main
+
+import "fmt"
+
+import (
+	"os"
+	str "strings"
+	. "math"
+	_ "embed"
+)
+"""
+    assert extract_go_imports(source) == [
+        {"path": "fmt", "kind": "normal", "line": 2},
+        {"path": "os", "kind": "normal", "line": 5},
+        {"path": "strings", "kind": "alias", "alias": "str", "line": 6},
+        {"path": "math", "kind": "dot", "alias": ".", "line": 7},
+        {"path": "embed", "kind": "blank", "alias": "_", "line": 8},
+    ]
+
+
+def test_import_graph_uses_module_path_and_finds_cycles():
+    files = {
+        "go.mod": "module example.com/app\n\ngo 1.21\n",
+        "main.go": 'package main\n\nimport (\n\t"fmt"\n\t"example.com/app/store"\n)\n',
+        "store/store.go": 'package store\n\nimport "example.com/app/cache"\n',
+        "store/store_test.go": 'package store\n\nimport "testing"\n',
+        "cache/cache.go": 'package cache\n\nimport _ "example.com/app/store"\n',
+        "util/util.go": "package util\n",
+    }
+    with tempfile.TemporaryDirectory() as tmpdir:
+        write_files(tmpdir, files)
+        repo = Repository(tmpdir)
+        graph = repo.get_import_graph()
+
+    assert graph == {
+        "example.com/app": ["example.com/app/store", "fmt"],
+        "example.com/app/cache": ["example.com/app/store"],
+        "example.com/app/store": ["example.com/app/cache"],
+        "example.com/app/util": [],
+    }
+    assert detect_cycles(graph) == [["example.com/app/cache", "example.com/app/store", "example.com/app/cache"]]
+
+
+def test_detect_cycles_without_module_path():
+    graph = build_import_graph({
+        "a/a.go": [{"path": "b", "kind": "normal"}],
+        "b/b.go": [{"path": "c", "kind": "normal"}],
+        "c/c.go": [{"path": "a", "kind": "normal"}, {"path": "fmt", "kind": "normal"}],
+        "d/d.go": [{"path": "a", "kind": "normal"}],
+    })
+    assert detect_cycles(graph) == [["a", "b", "c", "a"]]
+    assert detect_cycles({"x": ["y"], "y": []}) == []
@@ -0,0 +1,177 @@
+import os
+import tempfile
+import threading
+import time
+
+import pytest
+from fastapi.testclient import TestClient
+
+from codekite import Repository
+from codekite.api.index_server import MAX_LIMIT, create_index_app
+from codekite.export import SCHEMA_VERSION
+
+GOLDEN_GO = open(os.path.join(os.path.dirname(__file__), "fixtures", "go", "golden_go.go")).read()
+
+
+def write(root, rel_path, content):
+    path = os.path.join(root, rel_path)
+    os.makedirs(os.path.dirname(path), exist_ok=True)
+    with open(path, "w") as f:
+        f.write(content)
+
+
+@pytest.fixture
+def repo_dir():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        write(tmpdir, "app/main.go", GOLDEN_GO)
+        write(tmpdir, "tools/helpers.py", "def add(a, b):\n    return a + b\n\n# TODO: subtract\n")
+        yield tmpdir
+
+
+@pytest.fixture
+def client(repo_dir):
+    with TestClient(create_index_app(Repository(repo_dir))) as test_client:
+        yield test_client
+
+
+def test_symbols_filters_by_name_kind_and_file(client):
+    response = client.get("/v1/symbols", params={"name": "^(Add|Greet)$", "kind": "function,method"})
+    assert response.status_code == 200
+    body = response.json()
+    assert body["schema_version"] == SCHEMA_VERSION
+    assert [(s["name"], s["kind"], s["file"]) for s in body["symbols"]] == [
+        ("Greet", "method", "app/main.go"),
+        ("Add", "function", "app/main.go"),
+    ]
+
+    body = client.get("/v1/symbols", params={"file": "tools/*"}).json()
+    assert [s["name"] for s in body["symbols"]] == ["add"]
+    assert client.get("/v1/symbols", params={"name": "("}).status_code == 400
+
+
+def test_symbols_pagination(client):
+    everything = client.get("/v1/symbols").json()
+    total = everything["total"]
+    assert total == len(everything["symbols"]) == 7
+    assert everything["next_offset"] is None
+
+    first = client.get("/v1/symbols", params={"limit": 3}).json()
+    assert (first["total"], first["limit"], first["offset"], first["next_offset"]) == (total, 3, 0, 3)
+    rest = client.get("/v1/symbols", params={"limit": 3, "offset": 6}).json()
+    # The last page is short and has no next page
+    assert [s["id"] for s in first["symbols"] + rest["symbols"]] == [
+        s["id"] for s in everything["symbols"][:3] + everything["symbols"][6:]
+    ]
+    assert rest["next_offset"] is None
+    exact = client.get("/v1/symbols", params={"limit": 7}).json()
+    assert exact["next_offset"] is None and len(exact["symbols"]) == 7
+
+    past_end = client.get("/v1/symbols", params={"offset": 100}).json()
+    assert past_end["symbols"] == [] and past_end["total"] == total
+
+    for params in ({"limit": 0}, {"limit": MAX_LIMIT + 1}, {"offset": -1}, {"limit": "many"}):
+        assert client.get("/v1/symbols", params=params).status_code == 422
+    assert client.get("/v1/symbols", params={"limit": MAX_LIMIT}).status_code == 200
+
+
+def test_file_symbols_and_unknown_paths(client):
+    response = client.get("/v1/files/app/main.go/symbols", params={"limit": 2, "offset": 1})
+    assert response.status_code == 200
+    body = response.json()
+    assert body["file"]["path"] == "app/main.go" and body["file"]["language"] == "go"
+    assert [s["name"] for s in body["symbols"]] == ["Greeter", "Greet"]
+    assert (body["total"], body["next_offset"]) == (6, 3)
+
+    assert client.get("/v1/files/app/missing.go/symbols").status_code == 404
+    assert client.get("/v1/files/app/symbols").status_code == 404
+
+
+def test_unversioned_symbols_and_file_paths(client):
+    body = client.get("/symbols", params={"kind": "func", "name": "Add"}).json()
+    assert [(s["name"], s["kind"]) for s in body["symbols"]] == [("Add", "function")]
+    assert body == client.get("/v1/symbols", params={"kind": "func", "name": "Add"}).json()
+
+    assert client.get("/file", params={"path": "app/main.go"}).json() == client.get("/v1/files/app/main.go/symbols").json()
+    assert client.get("/file", params={"path": "app/missing.go"}).status_code == 404
+    assert client.get("/file").status_code == 422
+
+
+def test_search_literal_and_regex(client):
+    literal = client.get("/v1/search", params={"q": "a + b"}).json()
+    assert [(m["file"], m["line_number"]) for m in literal["matches"]] == [("app/main.go", 23), ("tools/helpers.py", 2)]
+
+    regex = client.get("/v1/search", params={"q": r"TODO|func A\w+", "regex": "true", "pattern": "*.py"}).json()
+    assert [m["line"] for m in regex["matches"]] == ["# TODO: subtract"]
+    page = client.get("/v1/search", params={"q": "a + b", "limit": 1, "offset": 1}).json()
+    assert [m["file"] for m in page["matches"]] == ["tools/helpers.py"] and page["total"] == 2
+
+    assert client.get("/v1/search").status_code == 422
+    assert client.get("/v1/search", params={"q": "(", "regex": "true"}).status_code == 400
+    # Without regex=true the same text is searched literally
+    assert client.get("/v1/search", params={"q": "("}).json()["total"] > 0
+
+
+def test_tree_pages_files_and_directories(client):
+    body = client.get("/v1/tree").json()
+    paths = [f["path"] for f in body["files"]]
+    assert {"app", "app/main.go", "tools", "tools/helpers.py"} <= set(paths)
+    page = client.get("/v1/tree", params={"limit": 1, "offset": 1}).json()
+    assert [f["path"] for f in page["files"]] == paths[1:2]
+
+
+def test_cors_headers_only_with_the_flag(repo_dir):
+    origin = {"Origin": "http://localhost:3000"}
+    with TestClient(create_index_app(Repository(repo_dir))) as plain:
+        assert "access-control-allow-origin" not in plain.get("/v1/tree", headers=origin).headers
+    with TestClient(create_index_app(Repository(repo_dir), cors=True)) as shared:
+        assert shared.get("/v1/tree", headers=origin).headers["access-control-allow-origin"] == "*"
+
+
+def test_watch_mode_refreshes_the_index(repo_dir):
+    app = create_index_app(Repository(repo_dir), watch=True, debounce=0.05)
+    with TestClient(app) as client:
+        assert client.get("/v1/files/tools/new.py/symbols").status_code == 404
+        write(repo_dir, "tools/new.py", "def fresh():\n    pass\n")
+        deadline = time.monotonic() + 10
+        while client.get("/v1/files/tools/new.py/symbols").status_code == 404:
+            assert time.monotonic() < deadline, "watcher never picked up the new file"
+            time.sleep(0.05)
+        assert [s["name"] for s in client.get("/v1/files/tools/new.py/symbols").json()["symbols"]] == ["fresh"]
+        assert "tools/new.py" in [f["path"] for f in client.get("/v1/tree").json()["files"]]
+    # Shutting the app down stops the watcher thread
+    assert app.state.index._watcher is None
+
+
+def test_requests_see_a_consistent_index_while_files_change(repo_dir):
+    app = create_index_app(Repository(repo_dir), watch=True, debounce=0.01)
+    failures = []
+    stop = threading.Event()
+
+    def read(client):
+        while not stop.is_set():
+            response = client.get("/v1/symbols", params={"file": "tools/churn*.py", "limit": MAX_LIMIT})
+            body = response.json()
+            # Each file defines one function named after it, so a torn read shows up as a mismatch
+            if response.status_code != 200 or body["total"] != len(body["symbols"]) or any(
+                s["name"] != os.path.basename(s["file"])[: -len(".py")] for s in body["symbols"]
+            ):
+                failures.append(body)
+
+    with TestClient(app) as client:
+        readers = [threading.Thread(target=read, args=(client,)) for _ in range(4)]
+        for reader in readers:
+            reader.start()
+        for n in range(20):
+            write(repo_dir, f"tools/churn{n}.py", f"def churn{n}():\n    pass\n")
+            if n % 3 == 0:
+                os.remove(os.path.join(repo_dir, f"tools/churn{n}.py"))
+            time.sleep(0.02)
+        deadline = time.monotonic() + 10
+        expected = sorted(f"churn{n}" for n in range(20) if n % 3)
+        while sorted(s["name"] for s in client.get("/v1/symbols", params={"file": "tools/churn*.py"}).json()["symbols"]) != expected:
+            assert time.monotonic() < deadline, "watcher never caught up"
+            time.sleep(0.05)
+        stop.set()
+        for reader in readers:
+            reader.join()
+    assert failures == []
@@ -0,0 +1,164 @@
+import io
+import os
+import tempfile
+
+import pytest
+
+from codekite import LanguageConflictWarning, Repository, register_language, unregister_language
+from codekite import languages
+
+
+class FooExtractor:
+    """Toy extractor: every line "def <name>" is a function."""
+
+    def extract(self, path, source):
+        symbols = []
+        for i, line in enumerate(source.splitlines()):
+            if line.startswith("def "):
+                name = line[4:].strip()
+                if not name:
+                    raise ValueError(f"missing name on line {i + 1}")
+                symbols.append({"name": name, "type": "function", "start_line": i, "end_line": i, "code": line})
+        return symbols
+
+
+@pytest.fixture
+def foo_language():
+    register_language("foo", ["foo"], FooExtractor())
+    yield
+    unregister_language("foo")
+
+
+def test_registered_extractor_feeds_repository_output(foo_language):
+    with tempfile.TemporaryDirectory() as tmpdir:
+        with open(os.path.join(tmpdir, "script.foo"), "w") as f:
+            f.write("def alpha\nnoise\ndef beta\n")
+        with open(os.path.join(tmpdir, "broken.foo"), "w") as f:
+            f.write("def \n")
+        repository = Repository(tmpdir)
+
+        symbols = repository.extract_symbols("script.foo")
+        assert [(s["name"], s["file"]) for s in symbols] == [("alpha", "script.foo"), ("beta", "script.foo")]
+
+        by_file, errors = repository.parse_directory()
+        assert [s["name"] for s in by_file["script.foo"]] == ["alpha", "beta"]
+        assert [e["file"] for e in errors] == ["broken.foo"]
+
+        indexed = repository.index()["symbols"]
+        assert any(s["name"] == "alpha" for syms in indexed.values() for s in syms)
+
+
+def test_registration_overrides_builtins_and_unregister_restores_them():
+    code = "def real():\n    pass\n"
+    assert [s["name"] for s in languages.extract_symbols(".py", "a.py", code)] == ["real"]
+
+    with pytest.warns(LanguageConflictWarning, match="toy-python replaces python"):
+        register_language("toy-python", [".PY"], FooExtractor())
+    try:
+        assert languages.language_for(".py") == "toy-python"
+        assert [s["name"] for s in languages.extract_symbols(".py", "a.py", code)] == ["real():"]
+    finally:
+        unregister_language("toy-python")
+
+    assert languages.language_for(".py") == "python"
+    assert ".foo" not in languages.supported_extensions()
+
+
+def test_parse_reader_dispatches_on_language_name():
+    source = "package main\n\n// Add adds.\nfunc Add(a, b int) int { return a + b }\n"
+    symbols = languages.parse_reader(io.StringIO(source), "go")
+    assert [(s["name"], s["type"], s["file"]) for s in symbols] == [("Add", "function", "<stdin>")]
+
+    assert languages.extension_for("py") == ".py"
+    assert languages.extension_for("python") == ".py"
+    assert languages.extension_for(".TS") == ".ts"
+    assert languages.extension_for("cobol") is None
+    with pytest.raises(ValueError):
+        languages.parse_reader(io.StringIO("x"), "cobol")
+
+
+def test_parse_stream_calls_back_per_symbol_and_stops_early(foo_language):
+    source = "package gen\n\n" + "".join(f"func F{i}() int {{ return {i} }}\n" for i in range(100)) + "const Max = 9\n"
+    streamed = []
+    languages.parse_stream(io.StringIO(source), "go", streamed.append, path="gen/table.go")
+    assert streamed == languages.parse_reader(io.StringIO(source), "go", path="gen/table.go")
+    assert [s["name"] for s in streamed[:2]] + [streamed[-1]["name"]] == ["F0", "F1", "Max"]
+    assert streamed[0]["id"] == "gen.F0:function" and streamed[0]["file"] == "gen/table.go"
+
+    class Enough(Exception):
+        pass
+
+    seen = []
+
+    def callback(symbol):
+        seen.append(symbol["name"])
+        if len(seen) == 3:
+            raise Enough()
+
+    with pytest.raises(Enough):
+        languages.parse_stream(io.StringIO(source), "go", callback)
+    assert seen == ["F0", "F1", "F2"]
+
+    # Custom extractors return a list, which is passed on a symbol at a time
+    names = []
+    languages.parse_stream(io.StringIO("def alpha\ndef beta\n"), "foo", lambda s: names.append(s["name"]))
+    assert names == ["alpha", "beta"]
+    with pytest.raises(ValueError):
+        languages.parse_stream(io.StringIO("x"), "cobol", names.append)
+
+
+def test_parse_stream_gives_overloads_the_ids_of_parse_reader():
+    source = "class Cart {\n    void add(Item item) {}\n    void add(Item item, int n) {}\n}\n"
+    streamed = []
+    languages.parse_stream(io.StringIO(source), "java", streamed.append, path="Cart.java")
+    assert streamed == languages.parse_reader(io.StringIO(source), "java", path="Cart.java")
+    ids = [s["id"] for s in streamed if s["name"] == "add"]
+    assert ids == ["Cart.java.Cart.add:method#652bcc3a", "Cart.java.Cart.add:method#0323fbcc"]
+
+
+def test_concurrent_registration_is_last_wins_per_language():
+    import threading
+    import warnings
+
+    barrier = threading.Barrier(8)
+
+    def register(i):
+        barrier.wait()
+        register_language("bar", [f"bar{i}", "bar"], FooExtractor())
+
+    with warnings.catch_warnings(record=True) as caught:
+        warnings.simplefilter("always")
+        threads = [threading.Thread(target=register, args=(i,)) for i in range(8)]
+        for t in threads:
+            t.start()
+        for t in threads:
+            t.join()
+    assert not [w for w in caught if issubclass(w.category, LanguageConflictWarning)]
+    try:
+        # One language re-registering its own extension is not a conflict
+        assert {f".bar{i}" for i in range(8)} | {".bar"} <= languages.supported_extensions()
+        assert languages.language_for(".bar") == "bar"
+    finally:
+        unregister_language("bar")
+    assert ".bar" not in languages.supported_extensions()
+
+    with warnings.catch_warnings():
+        warnings.simplefilter("error", LanguageConflictWarning)
+        with pytest.raises(LanguageConflictWarning):
+            register_language("toy-go", [".go"], FooExtractor())
+    try:
+        # The warning is raised after the registration took effect
+        assert languages.language_for(".go") == "toy-go"
+    finally:
+        unregister_language("toy-go")
+    assert languages.language_for(".go") == "go"
+
+
+def test_is_test_file_follows_each_languages_convention():
+    assert languages.is_test_file("store/store_test.go") and languages.is_test_file("pkg/testdata/fixture.go")
+    # go test only runs _test.go files; a package under tests/ is ordinary code
+    assert not languages.is_test_file("e2e/tests/run.go")
+    assert languages.is_test_file("tests/helpers.py") and languages.is_test_file("conftest.py")
+    assert languages.is_test_file("web/api.test.mjs") and languages.is_test_file("web/__tests__/api.tsx")
+    assert languages.is_test_file("src/test/java/UserTest.java") and languages.is_test_file("spec/user_spec.rb")
+    assert not languages.is_test_file("contest.py") and not languages.is_test_file("src/Contest.java")
@@ -0,0 +1,52 @@
+import os
+import tempfile
+
+from codekite import Repository
+from codekite.line_counts import LineCounts, count_lines, directory_line_counts
+
+
+def write_files(tmpdir, files):
+    for rel_path, content in files.items():
+        path = os.path.join(tmpdir, rel_path)
+        os.makedirs(os.path.dirname(path), exist_ok=True)
+        with open(path, "w") as f:
+            f.write(content)
+
+
+def test_golden_go_line_counts():
+    golden_content = open(os.path.join(os.path.dirname(__file__), "fixtures", "go", "golden_go.go")).read()
+    with tempfile.TemporaryDirectory() as tmpdir:
+        write_files(tmpdir, {"golden_go.go": golden_content})
+        repo = Repository(tmpdir)
+        counts = repo.line_counts()
+        symbols = {s["name"]: s for s in repo.extract_symbols("golden_go.go")}
+
+    assert counts["golden_go.go"] == LineCounts(total=36, code=24, comment=5, blank=7, files=1)
+    # Spans start at the declaration, so the doc comment above is not counted
+    assert symbols["HelperFunction"]["line_count"] == 3
+    assert symbols["Add"]["line_count"] == 3
+    assert symbols["User"]["line_count"] == 4
+
+
+def test_count_lines_uses_the_syntax_tree_for_comments():
+    source = 'x = "# not a comment"  # but this is\n# a comment line\n\n"""A docstring."""\n    \nprint(x)'
+    assert count_lines(source, ".py") == LineCounts(total=6, code=3, comment=1, blank=2, files=1)
+
+
+def test_count_lines_block_comments_span_lines():
+    source = "/*\n * Package doc.\n */\npackage main\n\nvar x = 1 /* inline */\n"
+    assert count_lines(source, ".go") == LineCounts(total=6, code=2, comment=3, blank=1, files=1)
+
+
+def test_directory_line_counts_roll_up_into_every_ancestor():
+    counts = {
+        "main.go": LineCounts(total=10, code=8, comment=1, blank=1, files=1),
+        "cart/cart.go": LineCounts(total=20, code=15, comment=3, blank=2, files=1),
+        "cart/internal/store.go": LineCounts(total=5, code=5, files=1),
+    }
+    rollup = directory_line_counts(counts)
+
+    assert list(rollup) == [".", "cart", "cart/internal"]
+    assert rollup["."] == LineCounts(total=35, code=28, comment=4, blank=3, files=3)
+    assert rollup["cart"] == LineCounts(total=25, code=20, comment=3, blank=2, files=2)
+    assert rollup["cart/internal"].to_dict() == {"total": 5, "code": 5, "comment": 0, "blank": 0, "files": 1}
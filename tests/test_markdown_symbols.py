@@ -0,0 +1,55 @@
+import os
+import tempfile
+
+from codekite import Repository
+from codekite.markdown_symbols import MarkdownExtractor
+
+GOLDEN = os.path.join(os.path.dirname(__file__), "golden_markdown.md")
+
+
+def extract(source):
+    return MarkdownExtractor().extract("doc.md", source)
+
+
+def test_golden_markdown_sections_and_code_blocks():
+    symbols = extract(open(GOLDEN).read())
+
+    assert [(s["name"], s["type"], s.get("level"), s["start_line"], s["end_line"]) for s in symbols] == [
+        ("Shop", "section", 1, 5, 36),
+        ("Install", "section", 2, 10, 22),
+        ("bash", "code_block", None, 12, 15),
+        ("From source", "section", 3, 17, 22),
+        ("code", "code_block", None, 19, 21),
+        ("Usage", "section", 2, 23, 33),
+        ("python", "code_block", None, 26, 28),
+        ("C# bindings", "section", 2, 34, 36),
+    ]
+    by_name = {s["name"]: s for s in symbols}
+    assert by_name["From source"]["parent"] == "Install"
+    assert by_name["Install"]["parent"] == "Shop"
+    assert "parent" not in by_name["Shop"]
+    assert by_name["python"]["parent"] == "Usage"
+    assert by_name["python"]["language"] == "python"
+    assert by_name["code"]["language"] == ""
+    # Setext headings are outlined the ATX way
+    assert by_name["Usage"]["signature"] == "## Usage"
+    assert by_name["bash"]["code"] == "```bash\npip install shop\n# Not a heading\n```"
+
+
+def test_markdown_front_matter_is_not_a_heading():
+    symbols = extract("---\ntitle: Notes\n---\nIntro\n")
+    assert symbols == []
+
+
+def test_markdown_unclosed_fence_runs_to_the_end():
+    symbols = extract("# Notes\n\n```go\nfunc main() {}\n")
+    assert [(s["name"], s["end_line"]) for s in symbols] == [("Notes", 3), ("go", 3)]
+
+
+def test_readme_sections_are_repository_symbols():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        with open(os.path.join(tmpdir, "README.md"), "w") as f:
+            f.write(open(GOLDEN).read())
+        symbols = Repository(tmpdir).extract_symbols("README.md")
+
+    assert {(s["name"], s["type"]) for s in symbols} >= {("Install", "section"), ("python", "code_block")}
@@ -0,0 +1,126 @@
+import io
+import json
+import os
+import subprocess
+import sys
+import tempfile
+
+import pytest
+
+from codekite import Repository
+from codekite.mcp_server import METHOD_NOT_FOUND, PARSE_ERROR, MCPServer, truncate_result
+
+GOLDEN_GO = open(os.path.join(os.path.dirname(__file__), "fixtures", "go", "golden_go.go")).read()
+
+
+@pytest.fixture
+def repo_dir():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        os.makedirs(os.path.join(tmpdir, "app"))
+        with open(os.path.join(tmpdir, "app", "main.go"), "w") as f:
+            f.write(GOLDEN_GO)
+        yield tmpdir
+
+
+def call(server, name, **arguments):
+    response = server.handle({"jsonrpc": "2.0", "id": 1, "method": "tools/call", "params": {"name": name, "arguments": arguments}})
+    result = response["result"]
+    text = result["content"][0]["text"]
+    return result["isError"], text if result["isError"] else json.loads(text)
+
+
+def test_tools_list_declares_input_schemas(repo_dir):
+    response = MCPServer(Repository(repo_dir)).handle({"jsonrpc": "2.0", "id": 7, "method": "tools/list"})
+    tools = {tool["name"]: tool for tool in response["result"]["tools"]}
+    assert set(tools) == {"extract_symbols", "search_text", "find_usages", "get_file_tree", "get_file_content"}
+    assert tools["get_file_content"]["inputSchema"]["required"] == ["path"]
+    assert tools["search_text"]["inputSchema"]["properties"]["regex"]["type"] == "boolean"
+
+
+def test_tools_return_json_text(repo_dir):
+    server = MCPServer(Repository(repo_dir))
+    is_error, symbols = call(server, "extract_symbols", path="app/main.go")
+    assert not is_error
+    assert ("Add", "function") in [(s["name"], s["kind"]) for s in symbols["symbols"]]
+
+    is_error, search = call(server, "search_text", query="a + b")
+    assert not is_error and [m["line_number"] for m in search["matches"]] == [23]
+    _, search = call(server, "search_text", query=r"func \(u \*?User\)", regex=True)
+    assert search["matches"]
+
+    is_error, usages = call(server, "find_usages", symbol="Add")
+    assert not is_error
+    assert [u["kind"] for u in usages["usages"]] == ["definition", "usage"]
+
+    _, tree = call(server, "get_file_tree")
+    assert "app/main.go" in [f["path"] for f in tree["files"]]
+
+    _, content = call(server, "get_file_content", path="app/main.go", start_line=1, end_line=1)
+    assert content["content"] == GOLDEN_GO.splitlines(keepends=True)[0]
+
+
+def test_tool_failures_are_tool_errors(repo_dir):
+    server = MCPServer(Repository(repo_dir))
+    assert call(server, "get_file_content", path="app/missing.go") == (True, "No such file: app/missing.go")
+    is_error, text = call(server, "get_file_content", path="../outside.go")
+    assert is_error and "outside the repository" in text
+    is_error, text = call(server, "search_text", query="(", regex=True)
+    assert is_error and "Invalid regular expression" in text
+    assert call(server, "find_usages", symbol="NoSuchSymbol")[0]
+    assert call(server, "extract_symbols")[0]
+    assert call(server, "get_file_content", path="app/main.go", start_line="1")[0]
+    assert call(server, "no_such_tool")[0]
+
+
+def test_oversized_results_are_truncated(repo_dir):
+    server = MCPServer(Repository(repo_dir), max_result_chars=200)
+    _, content = call(server, "get_file_content", path="app/main.go")
+    assert content["truncated"] is True
+    assert content["total"] == len(GOLDEN_GO.splitlines())
+    assert GOLDEN_GO.startswith(content["content"])
+
+    result = {"items": list(range(100))}
+    assert truncate_result(result, "items", 1000) is result
+    shortened = truncate_result(result, "items", 50)
+    assert len(json.dumps(shortened)) <= 50 and shortened["total"] == 100 and shortened["items"] == list(range(len(shortened["items"])))
+
+
+def test_serve_answers_requests_and_skips_notifications(repo_dir):
+    messages = [
+        {"jsonrpc": "2.0", "id": 1, "method": "initialize", "params": {"protocolVersion": "2024-11-05"}},
+        {"jsonrpc": "2.0", "method": "notifications/initialized"},
+        {"jsonrpc": "2.0", "id": 2, "method": "resources/list"},
+    ]
+    stdin = io.StringIO("\n".join(json.dumps(m) for m in messages) + "\nnot json\n")
+    stdout = io.StringIO()
+    MCPServer(Repository(repo_dir)).serve(stdin, stdout)
+    responses = [json.loads(line) for line in stdout.getvalue().splitlines()]
+    assert [r["id"] for r in responses] == [1, 2, None]
+    assert responses[0]["result"]["capabilities"] == {"tools": {"listChanged": False}}
+    assert responses[1]["error"]["code"] == METHOD_NOT_FOUND
+    assert responses[2]["error"]["code"] == PARSE_ERROR
+
+
+def test_mcp_command_over_stdio(repo_dir):
+    messages = [
+        {"jsonrpc": "2.0", "id": 1, "method": "initialize", "params": {"protocolVersion": "2024-11-05", "capabilities": {}, "clientInfo": {"name": "test", "version": "0"}}},
+        {"jsonrpc": "2.0", "method": "notifications/initialized"},
+        {"jsonrpc": "2.0", "id": 2, "method": "tools/list"},
+        {"jsonrpc": "2.0", "id": 3, "method": "tools/call", "params": {"name": "extract_symbols", "arguments": {"path": "app/main.go"}}},
+        {"jsonrpc": "2.0", "id": 4, "method": "tools/call", "params": {"name": "get_file_content", "arguments": {"path": "nope.go"}}},
+    ]
+    completed = subprocess.run(
+        [sys.executable, "-m", "codekite.cli", "mcp", "--repo", repo_dir],
+        input="".join(json.dumps(m) + "\n" for m in messages),
+        capture_output=True,
+        text=True,
+        timeout=60,
+    )
+    assert completed.returncode == 0, completed.stderr
+    responses = {r["id"]: r for r in map(json.loads, completed.stdout.splitlines())}
+    assert sorted(responses) == [1, 2, 3, 4]
+    assert responses[1]["result"]["serverInfo"]["name"] == "codekite"
+    assert len(responses[2]["result"]["tools"]) == 5
+    symbols = json.loads(responses[3]["result"]["content"][0]["text"])["symbols"]
+    assert "Greet" in [s["name"] for s in symbols]
+    assert responses[4]["result"]["isError"] is True
@@ -0,0 +1,104 @@
+import os
+import tempfile
+
+from codekite import Repository
+from codekite.metrics import MetricSummary, compute_metrics
+
+GRADE = """package grade
+
+// Letter maps a score to a grade.
+func Letter(score int, curve bool) string {
+	// Curved scores move up a band
+
+	if curve && score < 100 {
+		score += 5
+	}
+	switch {
+	case score >= 90:
+		return "A"
+	case score >= 80:
+		fallthrough
+	case score >= 70:
+		return "C"
+	default:
+		return "F"
+	}
+}
+
+func Sum(xs ...int) int { return 0 }
+
+func Wait(done <-chan struct{}, tick <-chan int) int {
+	select {
+	case <-done:
+		return 0
+	case n := <-tick:
+		return n
+	}
+}
+"""
+
+
+def make_repo(tmpdir):
+    files = {
+        "golden_go.go": open(os.path.join(os.path.dirname(__file__), "fixtures", "go", "golden_go.go")).read(),
+        "grade/grade.go": GRADE,
+        "tools/gen.py": "def generate(a, b):\n    return a\n",
+    }
+    for rel_path, content in files.items():
+        path = os.path.join(tmpdir, rel_path)
+        os.makedirs(os.path.dirname(path), exist_ok=True)
+        with open(path, "w") as f:
+            f.write(content)
+    return Repository(tmpdir)
+
+
+def metrics(symbols):
+    return {s["name"]: (s["code_lines"], s["param_count"], s["complexity"]) for s in symbols if "complexity" in s}
+
+
+def test_trivial_functions_have_exact_metrics():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        symbols = make_repo(tmpdir).extract_symbols("golden_go.go")
+    # (code_lines, param_count, complexity); (a, b int) is two parameters and the receiver none
+    assert metrics(symbols) == {
+        "Greet": (3, 0, 1),
+        "Add": (3, 2, 1),
+        "HelperFunction": (3, 0, 1),
+        "main": (6, 0, 1),
+    }
+
+
+def test_switch_with_fallthrough_counts_each_case_once():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        symbols = make_repo(tmpdir).extract_symbols("grade/grade.go")
+    by_name = {s["name"]: s for s in symbols}
+    # 1 + if + && + three non-default cases; fallthrough and default add no path.
+    # The comment-only and blank lines of the body are not code.
+    assert metrics(symbols)["Letter"] == (15, 2, 6)
+    assert by_name["Letter"]["line_count"] == 17
+    # A variadic parameter is one parameter; select cases count like switch cases
+    assert metrics(symbols)["Sum"] == (1, 1, 1)
+    assert metrics(symbols)["Wait"] == (7, 2, 3)
+
+
+def test_metrics_aggregate_per_file_and_over_the_repository():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        report = make_repo(tmpdir).metrics()
+
+    # Python functions carry no metrics yet, so tools/gen.py is left out
+    assert sorted(report.files) == ["golden_go.go", "grade/grade.go"]
+    grade = report.files["grade/grade.go"]
+    assert grade["complexity"] == MetricSummary(count=3, max=6, mean=3.33, p95=6)
+    assert grade["param_count"].to_dict() == {"count": 3, "max": 2, "mean": 1.67, "p95": 2}
+    assert report.total["complexity"] == MetricSummary(count=7, max=6, mean=2.0, p95=6)
+    assert report.total["code_lines"] == MetricSummary(count=7, max=15, mean=5.43, p95=15)
+    assert report.to_dict()["total"]["complexity"]["p95"] == 6
+    names = sorted(s["name"] for s in report.symbols)
+    assert names == ["Add", "Greet", "HelperFunction", "Letter", "Sum", "Wait", "main"]
+
+
+def test_p95_is_the_nearest_rank():
+    assert MetricSummary.of(range(1, 21)) == MetricSummary(count=20, max=20, mean=10.5, p95=19)
+    assert MetricSummary.of([4]) == MetricSummary(count=1, max=4, mean=4.0, p95=4)
+    assert MetricSummary.of([]) == MetricSummary()
+    assert compute_metrics({"a.py": [{"name": "f", "type": "function"}]}).total["complexity"].count == 0
@@ -0,0 +1,121 @@
+import os
+import tempfile
+
+from codekite import Repository
+
+
+def write_files(tmpdir, files):
+    for rel_path, content in files.items():
+        path = os.path.join(tmpdir, rel_path)
+        os.makedirs(os.path.dirname(path), exist_ok=True)
+        with open(path, "w") as f:
+            f.write(content)
+
+
+def find(repo, file, name):
+    return next(s for s in repo.extract_symbols(file) if s["name"] == name)
+
+
+def summary(usages):
+    return {file: [(r["line"], r["kind"], r["enclosing"]) for r in refs] for file, refs in usages.items()}
+
+
+def test_golden_go_add_is_called_from_main():
+    golden_content = open(os.path.join(os.path.dirname(__file__), "fixtures", "go", "golden_go.go")).read()
+    with tempfile.TemporaryDirectory() as tmpdir:
+        write_files(tmpdir, {"golden_go.go": golden_content})
+        repo = Repository(tmpdir)
+        usages = repo.find_usages(find(repo, "golden_go.go", "Add"))
+        assert summary(usages) == {"golden_go.go": [(21, "definition", None), (33, "usage", "main")]}
+        assert usages["golden_go.go"][1]["column"] == 13
+        assert usages["golden_go.go"][1]["context"] == "fmt.Println(Add(5, 3))"
+
+
+def test_go_usages_skip_comments_strings_and_other_packages():
+    files = {
+        "go.mod": "module example.com/app\n",
+        "calc/calc.go": """package calc
+
+// Add adds; Add is mentioned here only in a comment.
+func Add(a, b int) int { return a + b }
+
+func Twice(a int) int {
+	s := "Add"
+	_ = s
+	return Add(a, a)
+}
+""",
+        "main.go": """package main
+
+import (
+	"fmt"
+
+	m "example.com/app/calc"
+	"example.com/app/other"
+)
+
+func main() {
+	fmt.Println(m.Add(1, 2))
+	fmt.Println(other.Add(1, 2))
+}
+""",
+        "other/other.go": "package other\n\nfunc Add(a, b int) int { return a - b }\n",
+        "unrelated/u.go": "package unrelated\n\nfunc use() int { return Add(1, 2) }\n",
+    }
+    with tempfile.TemporaryDirectory() as tmpdir:
+        write_files(tmpdir, files)
+        repo = Repository(tmpdir)
+        usages = repo.find_usages(find(repo, "calc/calc.go", "Add"))
+        assert summary(usages) == {
+            "calc/calc.go": [(3, "definition", None), (8, "usage", "Twice")],
+            "main.go": [(10, "usage", "main")],
+        }
+
+
+def test_go_method_usages_through_selectors():
+    golden_content = open(os.path.join(os.path.dirname(__file__), "fixtures", "go", "golden_go.go")).read()
+    with tempfile.TemporaryDirectory() as tmpdir:
+        write_files(tmpdir, {"golden_go.go": golden_content})
+        repo = Repository(tmpdir)
+        greet = next(s for s in repo.extract_symbols("golden_go.go") if s["name"] == "Greet" and s["type"] == "method")
+        # The Greeter interface's Greet is a separate declaration, not a reference
+        assert summary(repo.find_usages(greet)) == {
+            "golden_go.go": [(16, "definition", None), (32, "usage", "main")]
+        }
+
+
+def test_go_type_parameters_are_not_references_to_package_types():
+    code = """package p
+
+type T struct{}
+
+func Map[T any](in []T) []T { return in }
+
+type Box[T any] struct{ v T }
+
+func (b Box[T]) Get() T { return b.v }
+
+func Use(t T) T { return t }
+"""
+    with tempfile.TemporaryDirectory() as tmpdir:
+        write_files(tmpdir, {"p/p.go": code})
+        repo = Repository(tmpdir)
+        usages = repo.find_usages(find(repo, "p/p.go", "T"))
+        assert summary(usages) == {
+            "p/p.go": [(2, "definition", None), (10, "usage", "Use"), (10, "usage", "Use")]
+        }
+
+
+def test_python_usages_match_identifiers_only():
+    files = {
+        "lib.py": "def helper():\n    return 1\n",
+        "app.py": "from lib import helper\n\n# helper in a comment\ndef run():\n    return helper() + len('helper')\n",
+    }
+    with tempfile.TemporaryDirectory() as tmpdir:
+        write_files(tmpdir, files)
+        repo = Repository(tmpdir)
+        usages = repo.find_usages(find(repo, "lib.py", "helper"))
+        assert summary(usages) == {
+            "app.py": [(0, "usage", None), (4, "usage", "run")],
+            "lib.py": [(0, "definition", None)],
+        }
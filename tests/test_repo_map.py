@@ -0,0 +1,63 @@
+import os
+import tempfile
+
+from codekite import Repository
+from codekite.repo_map import is_test_file
+
+
+def write_files(tmpdir, files):
+    for rel_path, content in files.items():
+        path = os.path.join(tmpdir, rel_path)
+        os.makedirs(os.path.dirname(path), exist_ok=True)
+        with open(path, "w") as f:
+            f.write(content)
+
+
+FIXTURE = {
+    "go.mod": "module example.com/app\n",
+    # util is imported by store and main, store by main only
+    "util/util.go": 'package util\n\nfunc Name() string { return "x" }\n',
+    "store/store.go": 'package store\n\nimport "example.com/app/util"\n\nfunc Get() string { return util.Name() }\n\nfunc Put(v string) {}\n',
+    "store/store_test.go": "package store\n\nfunc TestGet(t *testing.T) {}\n",
+    "main.go": 'package main\n\nimport (\n\t"example.com/app/store"\n\t"example.com/app/util"\n)\n\nfunc main() { store.Put(util.Name()) }\n',
+}
+
+
+def test_repo_map_ranks_imported_files_first():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        write_files(tmpdir, FIXTURE)
+        result = Repository(tmpdir).repo_map(max_tokens=1000)
+
+    assert result.files == ["util/util.go", "store/store.go", "main.go", "store/store_test.go"]
+    assert result.ranks["util/util.go"] == 2 and result.ranks["store/store.go"] == 1
+    # Plain text in path order, one line per top-level symbol
+    assert result.text == (
+        "main.go: func main()\n"
+        "store/store.go: func Get() string\n"
+        "store/store.go: func Put(v string)\n"
+        "store/store_test.go: func TestGet(t *testing.T)\n"
+        "util/util.go: func Name() string\n"
+    )
+
+
+def test_repo_map_budget_pins_and_test_exclusion():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        write_files(tmpdir, FIXTURE)
+        repo = Repository(tmpdir)
+        # store's outline does not fit after util's, but main's smaller one still does
+        tight = repo.repo_map(max_tokens=16)
+        pinned = repo.repo_map(max_tokens=16, pinned=["store/store.go"], exclude_tests=True)
+
+    assert tight.files == ["util/util.go", "main.go"]
+    assert tight.omitted == ["store/store.go", "store/store_test.go"]
+    assert tight.tokens <= 16
+    # Pinned files are kept even past the budget
+    assert pinned.files == ["store/store.go"]
+    assert pinned.omitted == ["util/util.go", "main.go"]
+
+
+def test_is_test_file():
+    assert is_test_file("store/store_test.go")
+    assert is_test_file("tests/helpers.py") and is_test_file("pkg/test_api.py")
+    assert is_test_file("web/api.spec.ts")
+    assert not is_test_file("store/store.go") and not is_test_file("contest.py")
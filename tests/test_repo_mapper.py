@@ -1,4 +1,8 @@
+import os
 import tempfile
+
+import pytest
+
 from codekite import RepoMapper
 
 def test_get_file_tree():
@@ -13,6 +17,27 @@ def test_get_file_tree():
         assert any(item["path"].endswith("baz.py") for item in tree)
         assert any(item["is_dir"] and item["path"].endswith("foo/bar") for item in tree)
 
+def test_source_files_include_tests():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        for name in ("a.py", "test_a.py", "notes.txt"):
+            with open(os.path.join(tmpdir, name), "w") as f:
+                f.write("x = 1\n")
+        mapper = RepoMapper(tmpdir)
+        assert [f.name for f in mapper.source_files()] == ["a.py", "test_a.py"]
+        assert [f.name for f in mapper.select_files()] == ["a.py"]
+
+def test_invalidate_file_tree_drops_the_cached_tree():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        with open(os.path.join(tmpdir, "a.py"), "w") as f:
+            f.write("x = 1\n")
+        mapper = RepoMapper(tmpdir)
+        tree = mapper.get_file_tree()
+        with open(os.path.join(tmpdir, "b.py"), "w") as f:
+            f.write("y = 2\n")
+        assert mapper.get_file_tree() is tree
+        mapper.invalidate_file_tree()
+        assert "b.py" in {item["path"] for item in mapper.get_file_tree()}
+
 def test_extract_symbols():
     with tempfile.TemporaryDirectory() as tmpdir:
         pyfile = f"{tmpdir}/a.py"
@@ -31,3 +56,500 @@ def baz(): pass
         types = {s["type"] for s in symbols}
         assert "class" in types
         assert "function" in types
+
+def test_parse_directory_skips_vendored_and_ignored_dirs():
+    import os
+    with tempfile.TemporaryDirectory() as tmpdir:
+        files = {
+            "main.go": "package main\n\nfunc main() {}\n",
+            "pkg/util.py": "def helper(): pass\n",
+            "vendor/dep/dep.go": "package dep\n\nfunc Dep() {}\n",
+            "node_modules/lib/index.js": "function lib() {}\n",
+            "generated/out.py": "def generated(): pass\n",
+            "pkg/notes.txt": "not source\n",
+        }
+        for rel_path, content in files.items():
+            os.makedirs(os.path.dirname(f"{tmpdir}/{rel_path}"), exist_ok=True)
+            with open(f"{tmpdir}/{rel_path}", "w") as f:
+                f.write(content)
+        # Invalid UTF-8 is replaced and reported, not a reason to drop the file
+        with open(f"{tmpdir}/pkg/broken.py", "wb") as f:
+            f.write(b"def broken():\n    return '\xff\xfe'\n")
+
+        mapper = RepoMapper(tmpdir)
+        symbols, errors = mapper.parse_directory(ignore=["generated/"])
+
+        assert set(symbols) == {"main.go", "pkg/broken.py", "pkg/util.py"}
+        assert [s["name"] for s in symbols["pkg/util.py"]] == ["helper"]
+        assert [s["name"] for s in symbols["pkg/broken.py"]] == ["broken"]
+        assert symbols["main.go"][0]["file"] == "main.go"
+        assert [(e["file"], e["severity"], e["encoding"]) for e in errors] == [("pkg/broken.py", "warning", "utf-8")]
+        assert "U+FFFD" in errors[0]["error"]
+
+        sub_symbols, _ = mapper.parse_directory("pkg")
+        assert set(sub_symbols) == {"pkg/broken.py", "pkg/util.py"}
+
+def test_parse_directory_skips_binary_large_and_minified_files():
+    from codekite import FileLimits
+
+    with tempfile.TemporaryDirectory() as tmpdir:
+        _write_tree(tmpdir, {
+            "main.go": "package main\n\nfunc main() {}\n",
+            "gen/big.go": "package gen\n\n" + "// filler\n" * 200,
+            "web/app.min.js": "function a(){return 1}" * 100 + "\n",
+        })
+        with open(f"{tmpdir}/blob.py", "wb") as f:
+            f.write(b"def f(): pass\n\0\0\0")
+
+        mapper = RepoMapper(tmpdir)
+        symbols, errors = mapper.parse_directory()
+        # The defaults only catch binaries; long lines are not checked unless asked for
+        assert set(symbols) == {"gen/big.go", "main.go", "web/app.min.js"}
+        assert [(e["file"], e["skipped"]) for e in errors] == [("blob.py", "binary")]
+
+        limits = FileLimits(max_file_size=1024, max_line_length=500)
+        symbols, errors = mapper.parse_directory(limits=limits)
+        assert set(symbols) == {"main.go"}
+        assert {e["file"]: e["skipped"] for e in errors} == {
+            "blob.py": "binary",
+            "gen/big.go": "too_large",
+            "web/app.min.js": "long_lines",
+        }
+        assert all(e["error"].startswith("Skipped: ") for e in errors)
+
+        symbols, errors = mapper.parse_directory(limits=FileLimits(max_file_size=None, skip_binary=False))
+        assert errors == [] and "blob.py" in symbols
+
+class _FailingExtractor:
+    def extract(self, path, source):
+        raise ValueError("cannot parse")
+
+
+def test_parse_directory_reports_syntax_errors_as_warnings():
+    from codekite import register_language, unregister_language
+
+    broken = open(os.path.join(os.path.dirname(__file__), "golden_go_broken.go")).read()
+    register_language("Failing", [".fail"], _FailingExtractor())
+    try:
+        with tempfile.TemporaryDirectory() as tmpdir:
+            _write_tree(tmpdir, {
+                "cart/broken.go": broken,
+                "cart/ok.go": "package cart\n\nfunc Empty() bool { return true }\n",
+                "bad.fail": "anything\n",
+            })
+
+            mapper = RepoMapper(tmpdir)
+            symbols, errors = mapper.parse_directory()
+            # The broken file keeps the symbols recovered around its error
+            assert {"Item", "Total", "Discount", "Count"} <= {s["name"] for s in symbols["cart/broken.go"]}
+            assert [s["name"] for s in symbols["cart/ok.go"]] == ["Empty"]
+            assert "bad.fail" not in symbols
+
+            failed = [e for e in errors if e["severity"] == "error"]
+            assert [(e["file"], e["error"]) for e in failed] == [("bad.fail", "ValueError: cannot parse")]
+            warnings = [e for e in errors if e["severity"] == "warning"]
+            assert warnings and {e["file"] for e in warnings} == {"cart/broken.go"}
+            discount = next(s for s in symbols["cart/broken.go"] if s["name"] == "Discount")
+            assert all(discount["start_line"] <= e["line"] <= discount["end_line"] for e in warnings)
+
+            with pytest.raises(SyntaxError) as raised:
+                mapper.parse_directory(root="cart", fail_fast=True)
+            assert raised.value.filename == "cart/broken.go"
+            assert raised.value.lineno == warnings[0]["line"] + 1
+            with pytest.raises(ValueError):
+                mapper.parse_directory(ignore=["cart/"], fail_fast=True)
+    finally:
+        unregister_language("Failing")
+
+
+def test_parse_directory_only_parses_selected_files():
+    from datetime import datetime, timezone
+
+    with tempfile.TemporaryDirectory() as tmpdir:
+        _write_tree(tmpdir, {
+            "old.go": "package main\n\nfunc Old() {}\n",
+            "new.go": "package main\n\nfunc New() {}\n",
+            "pkg/util.py": "def helper(): pass\n",
+            "vendor/dep/dep.go": "package dep\n\nfunc Dep() {}\n",
+        })
+        os.utime(f"{tmpdir}/old.go", (1_000_000_000, 1_000_000_000))
+        os.utime(f"{tmpdir}/pkg/util.py", (1_000_000_000, 1_000_000_000))
+        mapper = RepoMapper(tmpdir)
+
+        symbols, errors = mapper.parse_directory(modified_since=1_500_000_000)
+        assert errors == [] and set(symbols) == {"new.go"}
+        since = datetime(2017, 7, 14, tzinfo=timezone.utc)
+        assert set(mapper.parse_directory(modified_since=since)[0]) == {"new.go"}
+
+        # Ignored and missing paths in the allowlist are dropped rather than parsed or reported
+        changed = ["old.go", "./pkg/util.py", "vendor/dep/dep.go", "deleted.go"]
+        symbols, errors = mapper.parse_directory(changed_files=changed)
+        assert errors == [] and set(symbols) == {"old.go", "pkg/util.py"}
+        assert set(mapper.parse_directory(changed_files=changed, modified_since=1_500_000_000)[0]) == set()
+        assert mapper.parse_directory(changed_files=[]) == ({}, [])
+
+
+def test_parse_directory_marks_or_leaves_out_test_files():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        _write_tree(tmpdir, {
+            "calc/calc.go": "package calc\n\nfunc Add() {}\n",
+            "calc/calc_test.go": "package calc\n\nfunc TestAdd() {}\n",
+            "e2e/tests/run.go": "package tests\n\nfunc Run() {}\n",
+            "py/test_util.py": "def test_helper(): pass\n",
+            "py/tests/helpers.py": "def make_user(): pass\n",
+            "web/api.ts": "export function get() {}\n",
+            "web/api.test.ts": "export function mockGet() {}\n",
+        })
+        mapper = RepoMapper(tmpdir)
+
+        everything, _ = mapper.parse_directory(include_tests=True)
+        tests = sorted(p for p, symbols in everything.items() if all(s.get("is_test") for s in symbols))
+        assert tests == ["calc/calc_test.go", "py/test_util.py", "py/tests/helpers.py", "web/api.test.ts"]
+        # A Go package in a tests directory is built like any other
+        assert "is_test" not in everything["e2e/tests/run.go"][0]
+        assert "is_test" not in everything["calc/calc.go"][0]
+
+        # Left out unless asked for
+        api, _ = mapper.parse_directory()
+        assert sorted(api) == ["calc/calc.go", "e2e/tests/run.go", "web/api.ts"]
+        assert mapper.parse_directory(include_tests=False) == (api, [])
+        assert sorted(f.path for f in mapper.iter_directory()) == sorted(api)
+
+def _symlink_tree(tmpdir):
+    repo = os.path.join(tmpdir, "repo")
+    _write_tree(tmpdir, {
+        "outside/secret.py": "def secret(): pass\n",
+        "repo/src/app.py": "def app(): pass\n",
+        "repo/vendor/lib/shared.py": "def shared(): pass\n",
+        "repo/vendor/lib/deep/more.py": "def more(): pass\n",
+    })
+    os.symlink("..", f"{repo}/src/loop")
+    os.symlink("src/app.py", f"{repo}/linked.py")
+    os.symlink("vendor/lib", f"{repo}/lib")
+    os.symlink("../outside/secret.py", f"{repo}/escape.py")
+    os.symlink("../outside", f"{repo}/escape_dir")
+    os.symlink("missing.py", f"{repo}/dangling.py")
+    return repo
+
+def test_walk_does_not_follow_directory_symlinks_by_default():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        repo = _symlink_tree(tmpdir)
+        mapper = RepoMapper(repo)
+        symbols, errors = mapper.parse_directory()
+
+        # The linked file is indexed under its own path; links out of the repo never are
+        assert set(symbols) == {"linked.py", "src/app.py"}
+        assert symbols["linked.py"][0]["name"] == "app"
+        assert [(e["file"], e["skipped"], e["severity"]) for e in errors] == [("dangling.py", "broken_symlink", "info")]
+        paths = _tree_paths(mapper)
+        assert not paths & {"src/loop", "lib", "escape.py", "escape_dir"}
+        assert "dangling.py" in paths
+
+def test_walk_follows_symlinks_once_and_never_out_of_the_repository(monkeypatch):
+    from codekite import repo_mapper
+
+    with tempfile.TemporaryDirectory() as tmpdir:
+        repo = _symlink_tree(tmpdir)
+        symbols, errors = RepoMapper(repo, follow_symlinks=True).parse_directory()
+
+        # src/loop points back at the root, which was already visited
+        assert set(symbols) == {"lib/deep/more.py", "lib/shared.py", "linked.py", "src/app.py"}
+        assert [e["file"] for e in errors] == ["dangling.py"]
+
+        monkeypatch.setattr(repo_mapper, "MAX_FOLLOW_DEPTH", 1)
+        symbols, _ = RepoMapper(repo, follow_symlinks=True).parse_directory()
+        assert set(symbols) == {"lib/shared.py", "linked.py", "src/app.py"}
+
+def test_parse_directory_is_deterministic_across_pool_sizes():
+    import os
+    with tempfile.TemporaryDirectory() as tmpdir:
+        for i in range(40):
+            os.makedirs(f"{tmpdir}/pkg{i % 4}", exist_ok=True)
+            with open(f"{tmpdir}/pkg{i % 4}/mod{i}.py", "w") as f:
+                f.write(f"def func_{i}():\n    pass\n\nclass Class{i}:\n    def method(self): pass\n")
+        os.symlink("missing.py", f"{tmpdir}/pkg0/bad.py")
+
+        mapper = RepoMapper(tmpdir)
+        sequential = mapper.parse_directory(concurrency=1)
+        parallel = mapper.parse_directory(concurrency=8)
+
+        assert parallel == sequential
+        assert list(parallel[0]) == sorted(parallel[0])
+        assert len(parallel[0]) == 40
+        assert [e["file"] for e in parallel[1]] == ["pkg0/bad.py"]
+
+def _write_tree(tmpdir, files):
+    import os
+    for rel_path, content in files.items():
+        path = os.path.join(tmpdir, rel_path)
+        os.makedirs(os.path.dirname(path), exist_ok=True)
+        with open(path, "w") as f:
+            f.write(content)
+
+def _tree_paths(mapper):
+    return {item["path"] for item in mapper.get_file_tree()}
+
+def test_file_tree_honours_nested_gitignore_negation():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        _write_tree(tmpdir, {
+            ".gitignore": "*.log\n",
+            "debug.log": "",
+            "logs/.gitignore": "!important.log\n",
+            "logs/important.log": "",
+            "logs/other.log": "",
+            "logs/deep/important.log": "",
+        })
+        paths = _tree_paths(RepoMapper(tmpdir))
+        assert "debug.log" not in paths
+        assert "logs/other.log" not in paths
+        # Negations in logs/.gitignore apply to everything below logs/
+        assert "logs/important.log" in paths
+        assert "logs/deep/important.log" in paths
+
+def test_file_tree_directory_only_and_anchored_patterns():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        _write_tree(tmpdir, {
+            ".gitignore": "build/\n/dist\n",
+            "build/out.py": "",
+            "src/build/gen.py": "",
+            "src/build.py": "",
+            "dist/app.js": "",
+            "src/dist/keep.js": "",
+            "src/sub/.gitignore": "/local.txt\n",
+            "src/sub/local.txt": "",
+            "src/sub/nested/local.txt": "",
+        })
+        paths = _tree_paths(RepoMapper(tmpdir))
+        # "build/" matches directories at any depth, but not files named build
+        assert not any(p.startswith("build") or p.startswith("src/build/") for p in paths)
+        assert "src/build.py" in paths
+        # "/dist" is anchored to the directory of the .gitignore
+        assert "dist" not in paths and "dist/app.js" not in paths
+        assert "src/dist/keep.js" in paths
+        assert "src/sub/local.txt" not in paths
+        assert "src/sub/nested/local.txt" in paths
+
+def test_file_tree_double_star_patterns():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        _write_tree(tmpdir, {
+            ".gitignore": "**/generated/*.go\nvendor/**\ndocs/**/*.tmp\n",
+            "generated/api.go": "",
+            "pkg/generated/api.go": "",
+            "pkg/generated/keep.txt": "",
+            "vendor/lib/x.go": "",
+            "docs/a.tmp": "",
+            "docs/guide/deep/b.tmp": "",
+            "docs/guide/index.md": "",
+        })
+        paths = _tree_paths(RepoMapper(tmpdir))
+        # A leading **/ matches in every directory, the root included
+        assert "generated/api.go" not in paths and "pkg/generated/api.go" not in paths
+        assert "pkg/generated/keep.txt" in paths
+        assert not any(p.startswith("vendor/") for p in paths)
+        # A /**/ in the middle matches zero or more directories
+        assert "docs/a.tmp" not in paths and "docs/guide/deep/b.tmp" not in paths
+        assert "docs/guide/index.md" in paths
+
+def test_ignored_directory_cannot_be_reincluded():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        _write_tree(tmpdir, {
+            ".gitignore": "cache/\n!cache/keep.py\n",
+            "cache/keep.py": "",
+        })
+        assert "cache/keep.py" not in _tree_paths(RepoMapper(tmpdir))
+
+def test_codekiteignore_and_disabling_gitignore():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        _write_tree(tmpdir, {
+            ".gitignore": "secret.py\n",
+            ".codekiteignore": "fixtures/\n",
+            "secret.py": "def hidden(): pass\n",
+            "fixtures/data.py": "def data(): pass\n",
+            "main.py": "def main(): pass\n",
+            ".git/config": "",
+        })
+        paths = _tree_paths(RepoMapper(tmpdir))
+        assert "secret.py" not in paths and "fixtures/data.py" not in paths
+
+        unfiltered = RepoMapper(tmpdir, respect_gitignore=False)
+        paths = _tree_paths(unfiltered)
+        assert "secret.py" in paths
+        # .codekiteignore and .git are honoured even without gitignore handling
+        assert "fixtures/data.py" not in paths
+        assert not any(p.split("/")[0] == ".git" for p in paths)
+
+        symbols, _ = unfiltered.parse_directory()
+        assert set(symbols) == {"main.py", "secret.py"}
+
+def test_parse_directory_cancellation_returns_partial_results():
+    import threading
+    from codekite import ExtractionCancelled, languages
+
+    class CancellingExtractor:
+        """Sets the cancel event once the third file has been parsed."""
+
+        def __init__(self, event):
+            self.event = event
+            self.calls = 0
+            self.lock = threading.Lock()
+
+        def extract(self, path, source):
+            with self.lock:
+                self.calls += 1
+                if self.calls == 3:
+                    self.event.set()
+            return [{"name": path, "type": "file", "start_line": 0, "end_line": 0}]
+
+    with tempfile.TemporaryDirectory() as tmpdir:
+        _write_tree(tmpdir, {f"f{i:02d}.cxl": "x\n" for i in range(50)})
+        for concurrency in (1, 4):
+            cancel = threading.Event()
+            extractor = CancellingExtractor(cancel)
+            languages.register_language("cancel-test", [".cxl"], extractor)
+            try:
+                with pytest.raises(ExtractionCancelled) as excinfo:
+                    RepoMapper(tmpdir).parse_directory(concurrency=concurrency, cancel=cancel)
+            finally:
+                languages.unregister_language("cancel-test")
+            partial = excinfo.value.symbols
+            # Files already in flight when the event was set may still finish
+            assert 3 <= len(partial) <= 3 + concurrency
+            assert extractor.calls == len(partial)
+            assert list(partial) == sorted(partial)
+
+    with tempfile.TemporaryDirectory() as tmpdir:
+        _write_tree(tmpdir, {"a.py": "def a(): pass\n"})
+        # An event that is never set changes nothing
+        symbols, _ = RepoMapper(tmpdir).parse_directory(cancel=threading.Event())
+        assert set(symbols) == {"a.py"}
+
+def test_cancelling_during_the_walk_stops_before_parsing():
+    import threading
+    from codekite import ExtractionCancelled
+
+    with tempfile.TemporaryDirectory() as tmpdir:
+        _write_tree(tmpdir, {"a/b/c/d/e/f.py": "def f(): pass\n", "a/top.py": "def top(): pass\n"})
+        mapper = RepoMapper(tmpdir)
+        cancel = threading.Event()
+        visited = []
+        should_ignore = mapper._should_ignore
+
+        def cancel_at_second_directory(path, is_dir=None):
+            if is_dir:
+                visited.append(path.name)
+                if len(visited) == 2:
+                    cancel.set()
+            return should_ignore(path, is_dir)
+
+        mapper._should_ignore = cancel_at_second_directory
+        for parse in (mapper.parse_directory, lambda **kw: list(mapper.iter_directory(**kw))):
+            visited.clear()
+            cancel.clear()
+            with pytest.raises(ExtractionCancelled) as excinfo:
+                parse(cancel=cancel)
+            assert excinfo.value.symbols == {} and excinfo.value.errors == []
+            # The walk stopped where it was cancelled instead of listing the whole tree
+            assert visited == ["a", "b"]
+
+class _CountingExtractor:
+    """One symbol per line, with the line as its code; counts the files it parsed."""
+
+    def __init__(self):
+        import threading
+        self.calls = 0
+        self.lock = threading.Lock()
+
+    def extract(self, path, source):
+        with self.lock:
+            self.calls += 1
+        return [
+            {"name": f"s{i}", "type": "function", "start_line": i, "end_line": i, "code": line}
+            for i, line in enumerate(source.splitlines())
+        ]
+
+
+def test_iter_directory_yields_what_parse_directory_returns_and_stops_early():
+    from codekite import FileSymbols, Repository, languages
+
+    extractor = _CountingExtractor()
+    languages.register_language("counting-test", [".cnt"], extractor)
+    try:
+        with tempfile.TemporaryDirectory() as tmpdir:
+            _write_tree(tmpdir, {f"pkg{i % 3}/f{i:02d}.cnt": "a\nb\n" for i in range(60)})
+            os.symlink("missing.cnt", f"{tmpdir}/pkg0/bad.cnt")
+            mapper = RepoMapper(tmpdir)
+            symbols, errors = mapper.parse_directory(concurrency=4)
+            streamed = list(mapper.iter_directory(concurrency=4))
+
+            assert {f.path: f.symbols for f in streamed if f.symbols is not None} == symbols
+            assert [e for f in streamed for e in f.diagnostics] == errors
+            assert isinstance(streamed[0], FileSymbols)
+
+            extractor.calls = 0
+            files = mapper.iter_directory(concurrency=4)
+            for _ in range(3):
+                next(files)
+            files.close()
+            # Only a window of a few files per worker is parsed ahead of the consumer
+            assert extractor.calls <= 3 + 4 * 2
+
+            extractor.calls = 0
+            seen = []
+
+            def callback(file):
+                seen.append(file.path)
+                if len(seen) == 2:
+                    raise RuntimeError("enough")
+
+            with pytest.raises(RuntimeError):
+                Repository(tmpdir).stream_symbols(callback, concurrency=4)
+            assert seen == [f.path for f in streamed[:2]]
+            assert extractor.calls <= 2 + 4 * 2
+    finally:
+        languages.unregister_language("counting-test")
+
+
+def test_iter_directory_peak_memory_stays_flat():
+    import tracemalloc
+    from codekite import languages
+
+    def peak(run):
+        tracemalloc.start()
+        try:
+            run()
+            return tracemalloc.get_traced_memory()[1]
+        finally:
+            tracemalloc.stop()
+
+    languages.register_language("counting-test", [".cnt"], _CountingExtractor())
+    try:
+        with tempfile.TemporaryDirectory() as tmpdir:
+            line = "x" * 200 + "\n"
+            _write_tree(tmpdir, {f"pkg{i % 10}/f{i:03d}.cnt": line * 50 for i in range(300)})
+            mapper = RepoMapper(tmpdir)
+            collected = peak(lambda: mapper.parse_directory(concurrency=2))
+            streamed = peak(lambda: sum(len(f.symbols) for f in mapper.iter_directory(concurrency=2)))
+    finally:
+        languages.unregister_language("counting-test")
+    # parse_directory holds all 15,000 symbols at once; the stream a few files' worth
+    assert streamed < collected / 4, (streamed, collected)
+
+
+@pytest.mark.skipif(not os.environ.get("CODEKITE_BENCHMARK"), reason="set CODEKITE_BENCHMARK=1 to run benchmarks")
+def test_benchmark_parse_directory_serial_vs_parallel():
+    import time
+    with tempfile.TemporaryDirectory() as tmpdir:
+        _write_tree(tmpdir, {
+            f"pkg{i % 50}/file{i}.go": "package p\n\n" + "".join(
+                f"// F{i}_{j} adds.\nfunc F{i}_{j}(a, b int) int {{ return a + b }}\n" for j in range(20)
+            )
+            for i in range(3000)
+        })
+        mapper = RepoMapper(tmpdir)
+        timings = {}
+        for concurrency in (1, os.cpu_count() or 4):
+            start = time.perf_counter()
+            symbols, errors = mapper.parse_directory(concurrency=concurrency)
+            timings[concurrency] = time.perf_counter() - start
+            assert len(symbols) == 3000 and errors == []
+        print("parse_directory seconds by concurrency:", timings)
@@ -0,0 +1,105 @@
+import io
+import os
+import tempfile
+
+from codekite import Repository
+from codekite.scip import symbol_moniker
+
+
+def write_files(tmpdir, files):
+    for rel_path, content in files.items():
+        path = os.path.join(tmpdir, rel_path)
+        os.makedirs(os.path.dirname(path), exist_ok=True)
+        with open(path, "w") as f:
+            f.write(content)
+
+
+def read_varint(data, i):
+    value, shift = 0, 0
+    while True:
+        byte = data[i]
+        i += 1
+        value |= (byte & 0x7F) << shift
+        shift += 7
+        if byte < 0x80:
+            return value, i
+
+
+def decode(data):
+    """Decodes one protobuf message into {field number: [values]}; varints as ints, the rest as bytes."""
+    fields, i = {}, 0
+    while i < len(data):
+        key, i = read_varint(data, i)
+        number, wire_type = key >> 3, key & 7
+        if wire_type == 0:
+            value, i = read_varint(data, i)
+        elif wire_type == 2:
+            length, i = read_varint(data, i)
+            value = data[i : i + length]
+            i += length
+        else:
+            raise AssertionError(f"unexpected wire type {wire_type}")
+        fields.setdefault(number, []).append(value)
+    return fields
+
+
+def packed(data):
+    values, i = [], 0
+    while i < len(data):
+        value, i = read_varint(data, i)
+        values.append(value)
+    return values
+
+
+def occurrences(document):
+    for raw in document.get(2, []):
+        occurrence = decode(raw)
+        yield {
+            "range": packed(occurrence[1][0]),
+            "symbol": occurrence[2][0].decode(),
+            "roles": occurrence.get(3, [0])[0],
+            "enclosing": packed(occurrence[7][0]) if 7 in occurrence else None,
+        }
+
+
+def test_scip_index_of_golden_go():
+    golden_content = open(os.path.join(os.path.dirname(__file__), "fixtures", "go", "golden_go.go")).read()
+    with tempfile.TemporaryDirectory() as tmpdir:
+        write_files(tmpdir, {"golden_go.go": golden_content, "notes.txt": "Not parsed\n"})
+        out = io.BytesIO()
+        Repository(tmpdir).export_scip(out)
+
+    index = decode(out.getvalue())
+    metadata = decode(index[1][0])
+    assert decode(metadata[2][0])[1] == [b"codekite"]
+    assert metadata[3][0].decode().startswith("file://")
+
+    # notes.txt is not parsed, so it gets no document at all
+    documents = [decode(d) for d in index[2]]
+    assert [d[1][0].decode() for d in documents] == ["golden_go.go"]
+    document = documents[0]
+    assert document[4] == [b"Go"]
+
+    add = "codekite go . . Add()."
+    greet = "codekite go . . User#Greet()."
+    found = list(occurrences(document))
+    definition = next(o for o in found if o["symbol"] == add and o["roles"] & 1)
+    # The name on line 22, enclosing the whole function
+    assert definition["range"] == [21, 5, 8]
+    assert definition["enclosing"][:2] == [21, 0]
+    assert {"symbol": add, "range": [33, 13, 16], "roles": 0, "enclosing": None} in found
+    assert {"symbol": greet, "range": [32, 18, 23], "roles": 0, "enclosing": None} in found
+
+    information = {decode(s)[1][0].decode(): decode(s) for s in document[3]}
+    assert information[add][6] == [b"Add"]
+    assert any(b"sum of two integers" in text for text in information[add][3])
+
+
+def test_symbol_moniker_escapes_names_and_nests_descriptors():
+    kinds = {"outer": "function", "Cart": "class"}
+    inner = {"name": "inner", "type": "function", "node_path": "outer.inner"}
+    assert symbol_moniker("python", "app/models", inner, kinds) == "codekite python app/models . outer().inner()."
+    assert symbol_moniker("python", "my app", {"name": "items", "type": "field", "node_path": "Cart.items"}, kinds) == (
+        "codekite python my  app . Cart#items."
+    )
+    assert symbol_moniker("go", ".", {"name": "<lambda>", "type": "function"}) == "codekite go . . `<lambda>`()."
@@ -0,0 +1,85 @@
+import os
+import tempfile
+
+from codekite import Repository
+from codekite.sql_symbols import SqlExtractor
+
+GOLDEN = os.path.join(os.path.dirname(__file__), "golden_sql.sql")
+
+
+def extract(source):
+    return SqlExtractor().extract("schema.sql", source)
+
+
+def test_golden_sql_tables_views_and_routines():
+    symbols = extract(open(GOLDEN).read())
+
+    assert [(s["name"], s["type"], s.get("parent"), s["start_line"], s["end_line"]) for s in symbols] == [
+        ("users", "table", None, 6, 12),
+        ("id", "field", "users", 7, 7),
+        ("email", "field", "users", 8, 8),
+        ("display name", "field", "users", 9, 9),
+        ("created_at", "field", "users", 10, 10),
+        ("active_users", "view", None, 15, 16),
+        ("order_total", "function", None, 19, 30),
+        ("archive_orders", "procedure", None, 32, 36),
+        ("order_stats", "view", None, 38, 38),
+    ]
+    by_name = {s["name"]: s for s in symbols}
+    assert by_name["users"]["schema"] == "public"
+    assert by_name["users"]["signature"] == "CREATE TABLE IF NOT EXISTS public.users"
+    assert by_name["users"]["docstring"] == "Customers who can place orders.\nEmails are unique across the shop."
+    assert by_name["users"]["code"].endswith("CHECK (email = lower(email))\n)")
+    # A semicolon in a default and a trailing comment are part of neither the statement's end nor the column
+    assert by_name["display name"]["signature"] == "\"display name\" varchar(80) DEFAULT 'anonymous; unnamed'"
+    assert by_name["created_at"]["signature"] == "created_at timestamptz NOT NULL DEFAULT now()"
+    assert by_name["created_at"]["node_path"] == "users.created_at"
+    assert by_name["order_total"]["signature"] == (
+        "CREATE FUNCTION order_total(user_id integer, since date DEFAULT NULL) RETURNS numeric"
+    )
+    assert by_name["order_total"]["docstring"] == "Total of a user's orders."
+    # A block comment is not a doc comment
+    assert "docstring" not in by_name["active_users"]
+    assert by_name["order_stats"]["materialized"] is True
+
+
+def test_sql_bodies_and_strings_hide_their_statements():
+    symbols = extract(
+        "CREATE FUNCTION f() RETURNS void AS $body$ CREATE TABLE t (x int); $body$ LANGUAGE sql;\n"
+        "INSERT INTO log VALUES ('CREATE VIEW v AS SELECT 1;');\n"
+        "create temp table Scratch (\"a\"\"b\" int, LIKE other);\n"
+    )
+    assert [(s["name"], s["type"]) for s in symbols] == [("f", "function"), ("Scratch", "table"), ('a"b', "field")]
+
+
+def test_unterminated_statement_runs_to_the_end():
+    symbols = extract("CREATE VIEW v AS\nSELECT 1\n")
+    assert [(s["name"], s["start_line"], s["end_line"]) for s in symbols] == [("v", 0, 2)]
+
+
+def test_sql_files_are_repository_symbols():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        os.makedirs(os.path.join(tmpdir, "db"))
+        with open(os.path.join(tmpdir, "db", "schema.sql"), "w") as f:
+            f.write(open(GOLDEN).read())
+        symbols = Repository(tmpdir).extract_symbols("db/schema.sql")
+
+    assert {(s["name"], s["type"]) for s in symbols} >= {("users", "table"), ("order_total", "function")}
+    assert "db/schema.sql.users.email:field" in {s["id"] for s in symbols}
+
+
+def test_generated_and_vendored_sql_files_are_marked():
+    source = "-- Code generated by sqlc. DO NOT EDIT.\nCREATE TABLE users (id INT);\n"
+    with tempfile.TemporaryDirectory() as tmpdir:
+        for rel_path in ("db/schema.sql", "third_party/pg/schema.sql", "tests/fixtures.sql"):
+            os.makedirs(os.path.join(tmpdir, os.path.dirname(rel_path)))
+            with open(os.path.join(tmpdir, rel_path), "w") as f:
+                f.write(source if rel_path == "db/schema.sql" else "CREATE TABLE users (id INT);\n")
+        repo = Repository(tmpdir)
+        generated = repo.extract_symbols("db/schema.sql")
+        vendored = repo.extract_symbols("third_party/pg/schema.sql")
+        test = repo.extract_symbols("tests/fixtures.sql")
+
+    assert generated and all(s.get("generated") for s in generated)
+    assert vendored and all(s.get("vendored") and not s.get("generated") for s in vendored)
+    assert test and all(s.get("is_test") for s in test)
@@ -0,0 +1,101 @@
+import os
+import tempfile
+
+import pytest
+
+from codekite import Repository
+from codekite.summaries import LLMError, SymbolNotFoundError
+from codekite.summary_pipeline import RateLimiter, SummaryCache, SummaryPipeline
+
+from fake_llm import FakeLLMClient
+
+GOLDEN = open(os.path.join(os.path.dirname(__file__), "fixtures", "go", "golden_go.go")).read()
+
+
+def write_files(root, files):
+    for path, content in files.items():
+        full = os.path.join(root, path)
+        os.makedirs(os.path.dirname(full), exist_ok=True)
+        with open(full, "w") as f:
+            f.write(content)
+
+
+def test_symbol_prompt_holds_code_and_doc_comment():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        write_files(tmpdir, {"main.go": GOLDEN})
+        client = FakeLLMClient()
+        summary = SummaryPipeline(Repository(tmpdir), client).summarize_symbol("main.go", "User.Greet")
+
+        assert len(client.prompts) == 1
+        prompt = client.prompts[0]
+        assert "the method User.Greet in main.go" in prompt
+        assert "Greet implements the Greeter interface for User." in prompt
+        assert 'return fmt.Sprintf("Hello, my name is %s", u.Name)' in prompt
+        assert summary == FakeLLMClient.answer(prompt)
+
+        with pytest.raises(SymbolNotFoundError):
+            SummaryPipeline(Repository(tmpdir), client).summarize_symbol("main.go", "Missing")
+
+
+def test_repository_run_only_resummarizes_changed_code():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        write_files(tmpdir, {"a.go": "package a\n\nfunc A() int { return 1 }\n", "b.go": "package a\n\nfunc B() {}\n"})
+        cache_path = os.path.join(tmpdir, "cache", "summaries.json")
+        client = FakeLLMClient()
+        pipeline = Repository(tmpdir).get_summary_pipeline(client, cache_path=cache_path, max_workers=2)
+        first = pipeline.summarize_repository(level="symbol")
+        assert sorted(first) == ["a.go::A", "b.go::B"]
+        assert pipeline.calls == 2
+
+        # A fresh pipeline over the saved cache only asks about the edited function
+        write_files(tmpdir, {"a.go": "package a\n\nfunc A() int { return 2 }\n"})
+        client = FakeLLMClient()
+        pipeline = Repository(tmpdir).get_summary_pipeline(client, cache_path=cache_path)
+        second = pipeline.summarize_repository(level="symbol")
+        assert pipeline.calls == 1
+        assert "return 2" in client.prompts[0]
+        assert second["b.go::B"] == first["b.go::B"]
+        assert second["a.go::A"] != first["a.go::A"]
+
+
+def test_long_files_are_summarized_in_parts_then_merged():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        body = "".join(f"func F{i}() int {{ return {i} }}\n" for i in range(40))
+        write_files(tmpdir, {"long.go": "package long\n\n" + body})
+        client = FakeLLMClient()
+        summary = SummaryPipeline(Repository(tmpdir), client, max_input_tokens=100).summarize_file("long.go")
+
+        parts = [p for p in client.prompts if p.startswith("Below is part")]
+        merges = [p for p in client.prompts if p.startswith("Below are summaries")]
+        assert len(parts) > 2
+        assert "lines 1-" in parts[0]
+        assert merges
+        # Every part's summary feeds a merge, and the last merge is the answer
+        merged_text = "\n".join(merges)
+        assert all(FakeLLMClient.answer(p) in merged_text for p in parts)
+        assert summary == FakeLLMClient.answer(merges[-1])
+
+
+def test_failures_are_reported_or_skipped():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        write_files(tmpdir, {"a.go": "package a\n\nfunc A() {}\n", "b.go": "package a\n\nfunc B() {}\n"})
+        pipeline = SummaryPipeline(Repository(tmpdir), FakeLLMClient(fail_on="func B"), cache=SummaryCache())
+        with pytest.raises(LLMError):
+            pipeline.summarize_file("b.go")
+        assert list(pipeline.summarize_repository()) == ["a.go"]
+
+
+def test_rate_limiter_spaces_calls():
+    now = [0.0]
+    sleeps = []
+
+    def sleep(seconds):
+        sleeps.append(seconds)
+        now[0] += seconds
+
+    limiter = RateLimiter(4, clock=lambda: now[0], sleep=sleep)
+    for _ in range(3):
+        limiter.wait()
+    now[0] += 1.0
+    limiter.wait()
+    assert sleeps == [0.25, 0.25]
@@ -0,0 +1,104 @@
+import json
+import os
+import tempfile
+
+from codekite.symbol_diff import api_hash, api_hashes, diff_symbol_sets, load_symbols
+
+
+def sym(name, signature, file="calc/calc.go", type="function", **extra):
+    return dict(name=name, type=type, file=file, signature=signature, **extra)
+
+
+OLD = [
+    sym("Add", "func Add(a, b int) int"),
+    sym("Sub", "func Sub(a, b int) int"),
+    sym("helper", "func helper()"),
+    sym("Greet", "func (u User) Greet() string", type="method", parent="User", node_path="User.Greet"),
+    sym("Moved", "func Moved()", file="calc/old_file.go"),
+]
+NEW = [
+    sym("Add", "func Add(a, b int64) int64"),
+    sym("helper", "func helper(verbose bool)"),
+    sym("Mul", "func Mul(a, b int) int"),
+    sym("Moved", "func Moved()", file="calc/new_file.go"),
+]
+
+
+def test_diff_classifies_breaking_changes():
+    diff = diff_symbol_sets(OLD, NEW)
+
+    assert [c.name for c in diff.added] == ["Mul"]
+    assert not diff.added[0].breaking
+    # Moving between files of one package is not a change
+    assert [c.name for c in diff.removed] == ["Sub", "User.Greet"]
+    assert [(c.name, c.before, c.after) for c in diff.changed] == [
+        ("Add", "func Add(a, b int) int", "func Add(a, b int64) int64"),
+        ("helper", "func helper()", "func helper(verbose bool)"),
+    ]
+    # Unexported helper changed, but nothing outside the package can call it
+    assert [c.name for c in diff.breaking] == ["Sub", "User.Greet", "Add"]
+
+
+def test_body_only_edits_and_other_packages():
+    old = [sym("Add", "func Add(a, b int) int", code="return a + b")]
+    new = [sym("Add", "func Add(a, b int) int", code="return b + a"), sym("Add", "func Add(a, b int) int", file="other/o.go")]
+    diff = diff_symbol_sets(old, new)
+    assert diff.changed == [] and diff.removed == []
+    assert [c.symbol["file"] for c in diff.added] == ["other/o.go"]
+
+
+def test_test_helpers_never_break_the_api():
+    helper = sym("NewFixture", "func NewFixture() *Fixture", file="calc/calc_test.go", is_test=True)
+    diff = diff_symbol_sets(OLD + [helper], NEW)
+    assert "NewFixture" in [c.name for c in diff.removed]
+    assert "NewFixture" not in [c.name for c in diff.breaking]
+
+
+def test_api_hash_only_follows_exported_signatures():
+    base = api_hash(OLD)
+    assert len(base) == 64 and api_hash(list(reversed(OLD))) == base
+    # Bodies, docs, positions, files within the package and unexported symbols are not API
+    same = [dict(s, code="...", docstring="Adds.", start_line=9) for s in OLD]
+    same[-1] = dict(same[-1], file="calc/new_file.go")
+    same[2] = sym("helper", "func helper(verbose bool)")
+    same.append(sym("TestAdd", "func TestAdd(t *testing.T)", file="calc/calc_test.go", is_test=True))
+    assert api_hash(same) == base
+    assert api_hash(OLD + [sym("Mul", "func Mul(a, b int) int")]) != base
+    assert api_hash([sym("Add", "func Add(a, b int64) int64")] + OLD[1:]) != base
+
+    iface = sym("Greeter", "type Greeter interface", type="interface", methods=["Greet() string"])
+    grown = dict(iface, methods=["Greet() string", "Name() string"])
+    assert api_hash([iface]) != api_hash([grown])
+
+
+def test_api_hashes_are_per_package():
+    hashes = api_hashes(OLD + [sym("Root", "func Root()", file="main.go"), sym("inner", "func inner()", file="x/x.go")])
+    assert list(hashes) == [".", "calc", "x"]
+    assert hashes["calc"] == api_hash(OLD)
+    # A package that exports nothing still gets a hash, that of an empty API
+    assert hashes["x"] == api_hash([])
+
+
+def test_load_symbols_accepts_lists_and_indexes():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        listed, index = os.path.join(tmpdir, "list.json"), os.path.join(tmpdir, "index.json")
+        with open(listed, "w") as f:
+            json.dump(OLD, f)
+        with open(index, "w") as f:
+            json.dump({"files": [], "symbols": {"calc/calc.go": [{"name": "Add", "type": "function"}]}}, f)
+
+        assert load_symbols(listed) == OLD
+        assert load_symbols(index) == [{"name": "Add", "type": "function", "file": "calc/calc.go"}]
+        assert diff_symbol_sets(load_symbols(listed), load_symbols(listed)).to_dict()["breaking"] == 0
+
+
+def test_load_symbols_accepts_exports():
+    export = {
+        "schema_version": 2,
+        "symbols": [{"name": "Add", "kind": "function", "file": "calc/calc.go", "attributes": {"receiver": ""}}],
+    }
+    with tempfile.TemporaryDirectory() as tmpdir:
+        path = os.path.join(tmpdir, "export.json")
+        with open(path, "w") as f:
+            json.dump(export, f)
+        assert load_symbols(path) == [{"name": "Add", "type": "function", "file": "calc/calc.go", "receiver": ""}]
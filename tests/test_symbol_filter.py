@@ -0,0 +1,61 @@
+import os
+import re
+import tempfile
+
+from codekite import Repository
+from codekite.symbol_filter import SymbolFilter, apply_filter, is_exported
+
+
+def go_fixture_symbols():
+    golden_content = open(os.path.join(os.path.dirname(__file__), "fixtures", "go", "golden_go.go")).read()
+    with tempfile.TemporaryDirectory() as tmpdir:
+        with open(os.path.join(tmpdir, "golden_go.go"), "w") as f:
+            f.write(golden_content)
+        return Repository(tmpdir).extract_symbols("golden_go.go")
+
+
+def names(symbols):
+    return [s["name"] for s in sorted(symbols, key=lambda s: s["start_line"])]
+
+
+def test_filter_by_kind_on_go_fixture():
+    symbols = go_fixture_symbols()
+    assert names(apply_filter(symbols, SymbolFilter(kinds=["interface"]))) == ["Greeter"]
+    # "func" and "type" are aliases; methods are not functions
+    assert names(apply_filter(symbols, SymbolFilter.from_strings("func,type"))) == [
+        "User", "Greeter", "Add", "HelperFunction", "main"
+    ]
+
+
+def test_filter_by_name_and_exportedness():
+    symbols = go_fixture_symbols()
+    upper = SymbolFilter(kinds=["function"], name_pattern=re.compile("^[A-Z]"))
+    assert names(apply_filter(symbols, upper)) == ["Add", "HelperFunction"]
+    # HelperFunction starts with an upper-case letter, so only main is unexported
+    assert names(apply_filter(symbols, SymbolFilter(exported_only=True))) == [
+        "User", "Greeter", "Greet", "Add", "HelperFunction"
+    ]
+    assert apply_filter(symbols, SymbolFilter()) == symbols
+
+
+def test_filter_by_min_complexity():
+    symbols = [
+        {"name": "Simple", "type": "function", "complexity": 1},
+        {"name": "Branchy", "type": "method", "complexity": 12},
+        {"name": "User", "type": "struct"},
+    ]
+    assert [s["name"] for s in apply_filter(symbols, SymbolFilter.from_strings(min_complexity=10))] == ["Branchy"]
+    # Symbols without a complexity only match when no minimum is set
+    assert [s["name"] for s in apply_filter(symbols, SymbolFilter(min_complexity=0))] == ["Simple", "Branchy"]
+    assert apply_filter(symbols, SymbolFilter()) == symbols
+
+
+def test_is_exported_per_language():
+    assert is_exported({"name": "helper", "file": "a.ts", "exported": True})
+    assert not is_exported({"name": "Helper", "file": "a.ts", "exported": False})
+    assert not is_exported({"name": "_private", "file": "a.py"})
+    assert is_exported({"name": "public", "file": "a.py"})
+    assert not is_exported({"name": "lower", "file": "a.go"})
+    assert not is_exported({"name": "Get", "parent": "cache", "file": "a.go"})
+    assert is_exported({"name": "__init__", "parent": "Api", "file": "a.py"})
+    assert is_exported({"name": "lower"}, ext=".rs")
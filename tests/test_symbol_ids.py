@@ -0,0 +1,161 @@
+import os
+import tempfile
+
+from codekite import Repository
+from codekite.symbol_ids import assign_ids, full_name, parameter_types, symbol_id
+
+
+GOLDEN_GO = open(os.path.join(os.path.dirname(__file__), "fixtures", "go", "golden_go.go")).read()
+
+
+def write_files(tmpdir, files):
+    for rel_path, content in files.items():
+        path = os.path.join(tmpdir, rel_path)
+        os.makedirs(os.path.dirname(path), exist_ok=True)
+        with open(path, "w") as f:
+            f.write(content)
+
+
+USER_GO = """package user
+
+type User struct{ Name string }
+
+func (u User) Greet() string { return "hi " + u.Name }
+"""
+
+ADMIN_GO = """package user
+
+type Admin struct{}
+
+func (a Admin) Greet() string { return "hello" }
+"""
+
+
+def test_ids_survive_reformatting_and_moves_within_a_package():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        write_files(tmpdir, {"pkg/user/user.go": USER_GO, "pkg/user/admin.go": ADMIN_GO})
+        repo = Repository(tmpdir)
+        before = {s["id"] for s in repo.extract_symbols("pkg/user/user.go")}
+        assert before == {"pkg/user.User:struct", "pkg/user.User.Greet:method"}
+        # Methods of the same name on different receivers stay apart
+        assert "pkg/user.Admin.Greet:method" in {s["id"] for s in repo.extract_symbols("pkg/user/admin.go")}
+
+        # Blank lines and re-indentation shift every position but no ID
+        reformatted = "\n\n" + USER_GO.replace("{ return", "{\n\treturn").replace(" }\n", "\n}\n")
+        write_files(tmpdir, {"pkg/user/user.go": reformatted})
+        assert {s["id"] for s in repo.extract_symbols("pkg/user/user.go")} == before
+
+        # Moving Greet into another file of the package keeps its ID
+        write_files(tmpdir, {
+            "pkg/user/user.go": "package user\n\ntype User struct{ Name string }\n",
+            "pkg/user/greet.go": "package user\n\nfunc (u User) Greet() string { return u.Name }\n",
+        })
+        symbols, _ = repo.parse_directory()
+        assert {s["id"] for s in symbols["pkg/user/greet.go"]} == {"pkg/user.User.Greet:method"}
+
+
+def test_symbol_id_uses_the_file_outside_go():
+    method = {"name": "add", "type": "method", "node_path": "Cart.add"}
+    assert symbol_id("app/models.py", method) == "app/models.py.Cart.add:method"
+    assert symbol_id("./main.go", {"name": "main", "type": "function"}) == "main:function"
+    # init can be declared in every file of a package, so its ID names the file
+    assert symbol_id("cmd/setup.go", {"name": "init", "type": "function"}) == "cmd/setup.go.init:function"
+
+
+def test_assign_ids_tells_overloads_apart_by_parameter_types():
+    def add(start_byte, signature=None):
+        symbol = {"name": "add", "type": "method", "node_path": "Cart.add", "start_byte": start_byte}
+        return dict(symbol, signature=signature) if signature else symbol
+
+    symbols = [
+        add(80, "public void add(Item item, int n)"),
+        add(20, "public void add(Item item)"),
+        {"name": "add", "type": "field", "node_path": "Cart.add", "start_byte": 50},
+    ]
+    assign_ids("Cart.java", symbols)
+    assert [s["id"] for s in symbols] == [
+        "Cart.java.Cart.add:method#0323fbcc",
+        "Cart.java.Cart.add:method#652bcc3a",
+        "Cart.java.Cart.add:field",
+    ]
+
+    # Overloads declared in between or before the others leave their IDs alone
+    symbols = [
+        add(80, "public void add(Item item, int n)"),
+        add(20, "public void add(Item item)"),
+        add(50, "public void add(List<Item> items)"),
+        add(10, "public void add(String sku)"),
+    ]
+    assign_ids("Cart.java", symbols)
+    assert [s["id"] for s in symbols][:2] == [
+        "Cart.java.Cart.add:method#0323fbcc",
+        "Cart.java.Cart.add:method#652bcc3a",
+    ]
+
+    # So does removing the first of them
+    symbols = symbols[:3]
+    assign_ids("Cart.java", symbols)
+    assert [s["id"] for s in symbols][:2] == [
+        "Cart.java.Cart.add:method#0323fbcc",
+        "Cart.java.Cart.add:method#652bcc3a",
+    ]
+
+    # Parameter names, annotations and spacing don't count, so these clash and are numbered
+    symbols = [add(20, "void add(Item a)"), add(40, "void add(@Nonnull final Item  b)"), add(60)]
+    assign_ids("Cart.java", symbols)
+    assert [s["id"] for s in symbols] == [
+        "Cart.java.Cart.add:method#652bcc3a",
+        "Cart.java.Cart.add:method#2",
+        "Cart.java.Cart.add:method",
+    ]
+
+    # A method that isn't overloaded keeps the plain ID
+    symbols = [add(20, "void add(Item a)")]
+    assign_ids("Cart.java", symbols)
+    assert symbols[0]["id"] == "Cart.java.Cart.add:method"
+
+
+def test_parameter_types_drop_names_and_defaults():
+    def types(signature, name="add"):
+        return parameter_types({"name": name, "signature": signature})
+
+    assert types("public <T> void add(Map<K, V> m, String... rest)") == ["Map < K , V >", "String ..."]
+    assert types("def add(self, item: Item = None, *args)") == ["self", "Item", "* args"]
+    assert types("add(a?: string, cb: (x: number) => void): void") == ["string", "( x : number ) = > void"]
+    assert types("void Cart::add(const Item& item)") == ["const Item &"]
+    assert types("class Cart", name="Cart") is None
+    assert parameter_types({"name": "F", "params": [{"name": "a", "type": "int"}]}) == ["int"]
+
+
+def qualified_names(repo, path):
+    return {s["qualified_name"] for s in repo.extract_symbols(path) if s["type"] in ("struct", "method")}
+
+
+def test_go_names_are_qualified_by_the_module_path():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        write_files(tmpdir, {"pkg/user/user.go": USER_GO, "golden_go.go": GOLDEN_GO})
+        repo = Repository(tmpdir)
+        # Without go.mod the directory stands in, and the root package goes by its package clause
+        assert qualified_names(repo, "pkg/user/user.go") == {"pkg/user.User", "pkg/user.User.Greet"}
+        assert "main.User.Greet" in qualified_names(repo, "golden_go.go")
+
+        write_files(tmpdir, {"go.mod": "module example.com/app\n\ngo 1.22\n"})
+        assert qualified_names(repo, "pkg/user/user.go") == {
+            "example.com/app/pkg/user.User",
+            "example.com/app/pkg/user.User.Greet",
+        }
+        assert "example.com/app.User.Greet" in qualified_names(repo, "golden_go.go")
+        symbols, _ = repo.parse_directory()
+        assert "example.com/app/pkg/user.User.Greet" in {s["qualified_name"] for s in symbols["pkg/user/user.go"]}
+        # IDs stay repository-relative
+        assert "pkg/user.User.Greet:method" in {s["id"] for s in symbols["pkg/user/user.go"]}
+
+
+def test_full_name_follows_each_languages_module_naming():
+    method = {"name": "add", "type": "method", "node_path": "Cart.add"}
+    assert full_name("app/models.py", method) == "app.models.Cart.add"
+    assert full_name("app/__init__.py", method) == "app.Cart.add"
+    assert full_name("src/cart.ts", method) == "src/cart.Cart.add"
+    assert full_name("src/cart/index.tsx", method) == "src/cart.Cart.add"
+    assert full_name("Cart.java", method) == "Cart.java.Cart.add"
+    assert full_name("store/store.go", method, "example.com/app/store") == "example.com/app/store.Cart.add"
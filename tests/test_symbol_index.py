@@ -0,0 +1,131 @@
+import json
+import os
+import tempfile
+import threading
+
+import pytest
+
+from codekite import ExtractionCancelled, Repository, languages
+from codekite.symbol_index import SymbolIndex
+
+
+class CountingExtractor:
+    """Delegates to the built-in Python extractor and counts parses."""
+
+    def __init__(self):
+        self.parsed = []
+
+    def extract(self, path, source):
+        self.parsed.append(path)
+        return languages.extract_symbols(".py", path, source)
+
+
+def write_modules(tmpdir, count):
+    for i in range(count):
+        os.makedirs(f"{tmpdir}/pkg{i % 10}", exist_ok=True)
+        with open(f"{tmpdir}/pkg{i % 10}/mod{i}.cnt", "w") as f:
+            f.write(f"def func_{i}():\n    pass\n")
+
+
+def test_update_only_reparses_changed_files():
+    extractor = CountingExtractor()
+    languages.register_language("counted-python", [".cnt"], extractor)
+    try:
+        with tempfile.TemporaryDirectory() as tmpdir, tempfile.TemporaryDirectory() as cache_dir:
+            write_modules(tmpdir, 100)
+            repo = Repository(tmpdir)
+            cache_path = os.path.join(cache_dir, "index.json")
+
+            index = SymbolIndex(cache_path)
+            changes = index.update(repo)
+            assert len(changes["added"]) == 100 and len(extractor.parsed) == 100
+            # A cold index is a full extraction
+            assert index.symbols == repo.parse_directory()[0]
+
+            with open(f"{tmpdir}/pkg3/mod3.cnt", "a") as f:
+                f.write("\ndef extra():\n    pass\n")
+            os.remove(f"{tmpdir}/pkg4/mod4.cnt")
+            with open(f"{tmpdir}/pkg0/new.cnt", "w") as f:
+                f.write("def new():\n    pass\n")
+
+            extractor.parsed.clear()
+            # A fresh instance reads the persisted cache
+            changes = SymbolIndex(cache_path).update(repo)
+            assert (changes["added"], changes["changed"], changes["removed"]) == (
+                ["pkg0/new.cnt"], ["pkg3/mod3.cnt"], ["pkg4/mod4.cnt"]
+            )
+            assert len(changes["cached"]) == 98
+            assert sorted(extractor.parsed) == ["pkg0/new.cnt", "pkg3/mod3.cnt"]
+
+            extractor.parsed.clear()
+            reloaded = SymbolIndex(cache_path)
+            changes = reloaded.update(repo)
+            assert changes["added"] == changes["changed"] == changes["removed"] == []
+            assert extractor.parsed == []
+            assert [s["name"] for s in reloaded.symbols["pkg3/mod3.cnt"]] == ["func_3", "extra"]
+    finally:
+        languages.unregister_language("counted-python")
+
+
+def test_cancelled_update_keeps_what_it_parsed():
+    cancel = threading.Event()
+
+    class CancellingExtractor(CountingExtractor):
+        def extract(self, path, source):
+            if len(self.parsed) == 4:
+                cancel.set()
+            return super().extract(path, source)
+
+    extractor = CancellingExtractor()
+    languages.register_language("counted-python", [".cnt"], extractor)
+    try:
+        with tempfile.TemporaryDirectory() as tmpdir, tempfile.TemporaryDirectory() as cache_dir:
+            write_modules(tmpdir, 20)
+            repo = Repository(tmpdir)
+            cache_path = os.path.join(cache_dir, "index.json")
+            with pytest.raises(ExtractionCancelled):
+                repo.parse_directory_cached(SymbolIndex(cache_path), cancel=cancel)
+            assert len(extractor.parsed) == 5
+
+            # The next update resumes: the five saved files are served from the cache
+            extractor.parsed.clear()
+            changes = SymbolIndex(cache_path).update(repo)
+            assert len(changes["cached"]) == 5 and len(changes["added"]) == 15
+            assert changes["removed"] == []
+    finally:
+        languages.unregister_language("counted-python")
+
+
+def test_cache_with_other_schema_version_is_discarded():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        with open(f"{tmpdir}/a.py", "w") as f:
+            f.write("def a():\n    pass\n")
+        repo = Repository(tmpdir)
+        cache_path = os.path.join(tmpdir, ".kit_cache", "symbol_index.json")
+        os.makedirs(os.path.dirname(cache_path))
+        with open(cache_path, "w") as f:
+            json.dump({"schema_version": 0, "files": {"a.py": {"sha256": "stale", "symbols": []}}}, f)
+
+        index = repo.get_symbol_index()
+        assert index.symbols == {}
+        assert index.update(repo)["added"] == ["a.py"]
+        assert [s["name"] for s in index.symbols["a.py"]] == ["a"]
+
+
+def test_parse_directory_cached_scopes_to_root_and_evicts_deleted_files():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        for rel_path in ("pkg/a.py", "pkg/b.py", "other/c.py"):
+            os.makedirs(os.path.dirname(f"{tmpdir}/{rel_path}"), exist_ok=True)
+            with open(f"{tmpdir}/{rel_path}", "w") as f:
+                f.write(f"def {os.path.basename(rel_path)[0]}():\n    pass\n")
+        repo = Repository(tmpdir)
+        index = repo.get_symbol_index(os.path.join(tmpdir, ".kit_cache", "index.json"))
+
+        symbols, changes = repo.parse_directory_cached(index, "pkg")
+        assert set(symbols) == {"pkg/a.py", "pkg/b.py"}
+        assert changes["added"] == ["pkg/a.py", "pkg/b.py"]
+
+        os.remove(f"{tmpdir}/pkg/b.py")
+        symbols, changes = repo.parse_directory_cached(index)
+        assert set(symbols) == {"pkg/a.py", "other/c.py"}
+        assert (changes["added"], changes["cached"], changes["removed"]) == (["other/c.py"], ["pkg/a.py"], ["pkg/b.py"])
@@ -0,0 +1,59 @@
+import os
+import tempfile
+
+import pytest
+
+from codekite import Repository
+from codekite.symbol_store import SymbolStore
+
+
+def write_files(tmpdir, files):
+    for rel_path, content in files.items():
+        path = os.path.join(tmpdir, rel_path)
+        os.makedirs(os.path.dirname(path), exist_ok=True)
+        with open(path, "w") as f:
+            f.write(content)
+
+
+def test_index_and_query_by_name_file_and_kind():
+    golden_content = open(os.path.join(os.path.dirname(__file__), "fixtures", "go", "golden_go.go")).read()
+    with tempfile.TemporaryDirectory() as tmpdir:
+        write_files(tmpdir, {"golden_go.go": golden_content, "util/helpers.py": "def Add(a, b):\n    return a + b\n"})
+        repo = Repository(tmpdir)
+        with repo.get_symbol_store() as store:
+            assert store.index(repo) == []
+
+            assert [s["file"] for s in store.symbols_by_name("Add")] == ["golden_go.go", "util/helpers.py"]
+            assert [s["name"] for s in store.symbols_by_kind("interface")] == ["Greeter"]
+            in_file = store.symbols_in_file("golden_go.go")
+            assert [s["name"] for s in in_file] == ["User", "Greeter", "Greet", "Add", "HelperFunction", "main"]
+            assert in_file[2]["receiver"] == "User"
+
+        # The database persists across connections
+        with repo.get_symbol_store() as store:
+            assert store.files() == ["golden_go.go", "util/helpers.py"]
+
+
+def test_failed_batch_leaves_store_unchanged():
+    with SymbolStore(":memory:") as store:
+        store.write({"a.go": [{"name": "A", "type": "function", "start_line": 0}]})
+        with pytest.raises(KeyError):
+            # The second file's symbol has no type, so the whole batch rolls back
+            store.write({
+                "a.go": [{"name": "B", "type": "function", "start_line": 0}],
+                "b.go": [{"name": "C", "start_line": 0}],
+            })
+        assert [s["name"] for s in store.symbols_in_file("a.go")] == ["A"]
+        assert store.files() == ["a.go"]
+
+
+def test_compact_removes_deleted_files():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        write_files(tmpdir, {"a.py": "def a(): pass\n", "b.py": "def b(): pass\n"})
+        repo = Repository(tmpdir)
+        with repo.get_symbol_store(os.path.join(tmpdir, "store", "symbols.db")) as store:
+            store.index(repo)
+            os.remove(os.path.join(tmpdir, "b.py"))
+            assert store.compact(repo) == ["b.py"]
+            assert store.symbols_by_name("b") == []
+            assert store.files() == ["a.py"]
@@ -0,0 +1,86 @@
+import os
+import tempfile
+
+from codekite import Repository
+from codekite.todos import Comment, find_todos
+
+
+def write_files(tmpdir, files):
+    for rel_path, content in files.items():
+        path = os.path.join(tmpdir, rel_path)
+        os.makedirs(os.path.dirname(path), exist_ok=True)
+        with open(path, "w") as f:
+            f.write(content)
+
+
+GO_SOURCE = """package cart
+
+// TODO: split this package
+
+// Total adds up the prices.
+// FIXME(bob): ignores discounts
+func Total(prices []int) int {
+	sum := 0
+	for _, p := range prices {
+		sum += p // XXX overflow
+	}
+	msg := "TODO: not a comment"
+	_ = msg
+	/*
+	 * TODO(alice): cache the result
+	 */
+	return sum
+}
+
+// see the TODO above; TODOS are not markers either
+"""
+
+
+def test_find_todos_in_go_comments():
+    symbols = [{"name": "Total", "type": "function", "start_line": 6, "end_line": 17, "doc_start_line": 4}]
+    found = find_todos(GO_SOURCE, ".go", "cart.go", symbols=symbols)
+
+    assert found == [
+        Comment("TODO", "split this package", 2, "cart.go"),
+        Comment("FIXME", "ignores discounts", 5, "cart.go", author="bob", symbol="Total"),
+        Comment("XXX", "overflow", 9, "cart.go", symbol="Total"),
+        Comment("TODO", "cache the result", 14, "cart.go", author="alice", symbol="Total"),
+    ]
+
+
+def test_find_todos_custom_markers_and_python_docstrings():
+    source = (
+        'class Cart:\n    """HACK: keeps a global.\n\n    TODO: thread safety\n    """\n\n'
+        "    def add(self):\n        pass  # NOTE: fine\n"
+    )
+    found = find_todos(source, ".py", markers=("HACK", "NOTE"))
+
+    assert [(c.marker, c.text, c.line) for c in found] == [("HACK", "keeps a global.", 1), ("NOTE", "fine", 7)]
+    assert find_todos(source, ".md") == []
+
+
+def test_repository_todos_attach_the_enclosing_symbol():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        write_files(tmpdir, {
+            "cart/cart.go": GO_SOURCE,
+            "app/models.py": "class Cart:\n    def add(self):\n        # TODO(carol): validate\n        pass\n",
+            "README.md": "# TODO: not scanned\n",
+        })
+        found = Repository(tmpdir).todos()
+
+    assert [(c.file, c.line) for c in found] == [
+        ("app/models.py", 2),
+        ("cart/cart.go", 2),
+        ("cart/cart.go", 5),
+        ("cart/cart.go", 9),
+        ("cart/cart.go", 14),
+    ]
+    assert found[0].to_dict() == {
+        "marker": "TODO",
+        "text": "validate",
+        "line": 2,
+        "file": "app/models.py",
+        "author": "carol",
+        "symbol": "Cart.add",
+    }
+    assert [c.symbol for c in found[1:]] == ["", "Total", "Total", "Total"]
@@ -0,0 +1,355 @@
+import os
+import tempfile
+
+import pytest
+
+from codekite import Repository
+from codekite.type_analyzer import find_fields_by_tag_key, find_implementers, render_dot, resolve_embedding
+
+
+def write_files(tmpdir, files):
+    for rel_path, content in files.items():
+        path = os.path.join(tmpdir, rel_path)
+        os.makedirs(os.path.dirname(path), exist_ok=True)
+        with open(path, "w") as f:
+            f.write(content)
+
+
+def test_implementations_for_golden_fixture():
+    golden_content = open(os.path.join(os.path.dirname(__file__), "fixtures", "go", "golden_go.go")).read()
+    with tempfile.TemporaryDirectory() as tmpdir:
+        write_files(tmpdir, {"golden_go.go": golden_content})
+        analyzer = Repository(tmpdir).get_type_analyzer()
+
+        impls = analyzer.implementations("", "Greeter")
+        assert impls == [{"name": "User", "file": "golden_go.go", "line": 5, "pointer": False}]
+
+        satisfied = analyzer.interfaces_satisfied_by("User")
+        assert [(r["name"], r["file"]) for r in satisfied] == [("Greeter", "golden_go.go")]
+
+
+def test_pointer_receivers_across_files():
+    files = {
+        "store/store.go": """package store
+
+type Store interface {
+	Get(key string) (string, bool)
+	Put(key, value string)
+}
+
+type ReadOnly interface {
+	Get(key string) (string, bool)
+}
+
+type Memory struct {
+	data map[string]string
+}
+
+type Broken struct{}
+
+func (b Broken) Get(key int) (string, bool) { return "", false }
+""",
+        "store/memory.go": """package store
+
+func (m Memory) Get(k string) (string, bool) {
+	v, ok := m.data[k]
+	return v, ok
+}
+
+func (m *Memory) Put(k, v string) {
+	m.data[k] = v
+}
+""",
+        "other/other.go": """package other
+
+type Memory struct{}
+""",
+    }
+    with tempfile.TemporaryDirectory() as tmpdir:
+        write_files(tmpdir, files)
+        analyzer = Repository(tmpdir).get_type_analyzer()
+
+        # Put has a pointer receiver, so only *Memory implements Store
+        assert analyzer.implementations("store", "Store") == [
+            {"name": "Memory", "file": "store/store.go", "line": 11, "pointer": True}
+        ]
+        # Get has a value receiver; Broken's Get has the wrong parameter type
+        read_only = analyzer.implementations("store", "ReadOnly")
+        assert [(r["name"], r["pointer"]) for r in read_only] == [("Memory", False)]
+
+        satisfied = analyzer.interfaces_satisfied_by("Memory", "store")
+        assert {(r["name"], r["pointer"]) for r in satisfied} == {("Store", True), ("ReadOnly", False)}
+        assert analyzer.interfaces_satisfied_by("Memory", "other") == []
+
+
+def test_embedded_interfaces_and_unknown_interface():
+    files = {
+        "io.go": """package rw
+
+type Reader interface {
+	Read(p []byte) (n int, err error)
+}
+
+type ReadCloser interface {
+	Reader
+	error
+	Close() error
+}
+
+type File struct{}
+
+func (f *File) Read(p []byte) (int, error) { return 0, nil }
+func (f *File) Close() error { return nil }
+func (f *File) Error() string { return "" }
+""",
+    }
+    with tempfile.TemporaryDirectory() as tmpdir:
+        write_files(tmpdir, files)
+        analyzer = Repository(tmpdir).get_type_analyzer()
+
+        assert [r["name"] for r in analyzer.implementations("", "ReadCloser")] == ["File"]
+        with pytest.raises(ValueError):
+            analyzer.implementations("", "Writer")
+
+
+def test_find_implementers_on_extracted_symbols():
+    golden_content = open(os.path.join(os.path.dirname(__file__), "fixtures", "go", "golden_go.go")).read()
+    files = {
+        "golden_go.go": golden_content,
+        "store/iface.go": """package store
+
+type Store interface {
+	Get(key string) (string, bool)
+	Put(key, value string)
+}
+
+type Sized interface {
+	Len() int
+}
+""",
+        "store/memory.go": """package store
+
+type Memory struct{}
+
+func (m Memory) Get(k string) (string, bool) { return "", false }
+func (m *Memory) Put(k, v string) {}
+func (m Memory) Len() int { return 0 }
+""",
+    }
+    with tempfile.TemporaryDirectory() as tmpdir:
+        write_files(tmpdir, files)
+        repository = Repository(tmpdir)
+        symbols, errors = repository.parse_directory()
+
+    all_symbols = [s for file_symbols in symbols.values() for s in file_symbols]
+    assert errors == []
+    assert find_implementers(all_symbols) == {
+        "Greeter": ["User"],
+        # Put has a pointer receiver, so only *Memory is a Store
+        "store.Store": ["*Memory"],
+        "store.Sized": ["Memory"],
+    }
+
+
+def test_merge_package_attaches_methods_across_files():
+    files = {"user/user.go": "package user\n\ntype User struct {\n\tName string\n}\n\nfunc New() *User { return &User{} }\n"}
+    for i, method in enumerate(["Rename", "Greet", "Save", "Load", "Delete"]):
+        files[f"user/user_{method.lower()}.go"] = f"package user\n\nfunc (u *User) {method}() {{}}\n"
+    files["user/orphan.go"] = "package user\n\nfunc (g *Ghost) Haunt() {}\n"
+    files["admin/admin.go"] = "package admin\n\ntype User struct{}\n\nfunc (u User) Promote() {}\n"
+    with tempfile.TemporaryDirectory() as tmpdir:
+        write_files(tmpdir, files)
+        merged, errors = Repository(tmpdir).parse_packages()
+
+    assert errors == []
+    by_name = {(s["package"], s["name"]): s for s in merged}
+    user = by_name[("user", "User")]
+    assert [m["name"] for m in user["members"]] == ["Delete", "Greet", "Load", "Rename", "Save"]
+    assert len(user["files"]) == 6 and user["files"][0] == "user/user.go"
+    # Same-named types in other packages keep their own methods
+    assert [m["name"] for m in by_name[("admin", "User")]["members"]] == ["Promote"]
+
+    haunt = by_name[("user", "Haunt")]
+    assert haunt["receiver_missing"] is True and haunt["file"] == "user/orphan.go"
+    assert ("user", "Greet") not in by_name
+    assert by_name[("user", "New")]["type"] == "function"
+    assert [s["package"] for s in merged] == sorted(s["package"] for s in merged)
+
+
+def test_methods_attach_to_named_types_but_not_aliases():
+    named_types = open(os.path.join(os.path.dirname(__file__), "fixtures", "go", "named_types.go")).read()
+    files = {
+        "named/named.go": named_types,
+        "named/stringer.go": "package named\n\ntype Stringer interface {\n\tString() string\n}\n",
+    }
+    with tempfile.TemporaryDirectory() as tmpdir:
+        write_files(tmpdir, files)
+        repository = Repository(tmpdir)
+        merged, errors = repository.parse_packages()
+        symbols, _ = repository.parse_directory()
+
+    assert errors == []
+    by_name = {s["name"]: s for s in merged}
+    assert [m["name"] for m in by_name["UserID"]["members"]] == ["String"]
+    assert [m["name"] for m in by_name["Handler"]["members"]] == ["ServeHTTP"]
+    assert not any(s.get("receiver_missing") for s in merged)
+    assert (by_name["Alias"]["alias"], by_name["Alias"]["alias_of"]) == (True, "OldName")
+    # A named type with the method implements the interface; Celsius and Set have none
+    all_symbols = [s for file_symbols in symbols.values() for s in file_symbols]
+    assert find_implementers(all_symbols) == {"named.Stringer": ["UserID"]}
+
+
+def test_parse_packages_with_changed_files_parses_whole_packages():
+    files = {
+        "user/user.go": "package user\n\ntype User struct{}\n",
+        "user/greet.go": "package user\n\nfunc (u *User) Greet() {}\n",
+        "admin/admin.go": "package admin\n\ntype Admin struct{}\n",
+    }
+    with tempfile.TemporaryDirectory() as tmpdir:
+        write_files(tmpdir, files)
+        merged, errors = Repository(tmpdir).parse_packages(changed_files=["user/greet.go"])
+
+    # Only greet.go changed, but its method still finds User in the unchanged user.go
+    assert errors == []
+    assert {s["package"] for s in merged} == {"user"}
+    user = next(s for s in merged if s["name"] == "User")
+    assert [m["name"] for m in user["members"]] == ["Greet"]
+
+
+def test_resolve_embedding_promotes_through_two_levels():
+    fixture = os.path.join(os.path.dirname(__file__), "fixtures", "go_embedding")
+    symbols, errors = Repository(fixture).parse_directory()
+    assert errors == []
+    report = resolve_embedding([s for file_symbols in symbols.values() for s in file_symbols])
+
+    admin = {p["name"]: p for p in report.promoted["Admin"]}
+    assert sorted(admin) == ["Account", "Bio", "Created", "Email", "Greet", "ID", "Lock", "Login"]
+    assert admin["Greet"]["chain"] == "Admin -> User -> Greet"
+    assert (admin["Login"]["chain"], admin["Login"]["depth"], admin["Login"]["from"]) == (
+        "Admin -> User -> Account -> Login",
+        2,
+        "Account",
+    )
+    # Audit's ID at depth 1 shadows Account's at depth 2
+    assert admin["ID"]["chain"] == "Admin -> Audit -> ID"
+    # Reached through *User, so Admin values have the pointer method too
+    assert "pointer" not in admin["Lock"]
+    user = {p["name"]: p for p in report.promoted["User"]}
+    assert user["Lock"]["pointer"] is True
+    assert report.unresolved["Admin"] == ["sync.Mutex"]
+
+    # The symbols themselves carry the results
+    types = [s for file_symbols in symbols.values() for s in file_symbols if s["type"] in ("struct", "interface")]
+    by_name = {s["name"]: s for s in types}
+    assert by_name["Admin"]["promoted"] == report.promoted["Admin"]
+    assert "promoted" not in by_name["Account"]
+    assert by_name["Member"]["method_set"] == ["Greet() string", "Login() bool", "Name() string"]
+
+
+def test_resolve_embedding_drops_collisions():
+    fixture = os.path.join(os.path.dirname(__file__), "fixtures", "go_embedding")
+    report = Repository(fixture).resolve_embedding()
+
+    assert [(c.type, c.name, c.chains) for c in report.collisions] == [
+        ("Admin", "Name", ["Admin -> User -> Name", "Admin -> Profile -> Name"]),
+        ("Shouter", "Greet", ["Shouter -> Greet", "Shouter -> Greeter -> Greet"]),
+    ]
+    assert "Name" not in {p["name"] for p in report.promoted["Admin"]}
+    assert str(report.collisions[0]) == (
+        "admin.go:16: Admin.Name is ambiguous: Admin -> User -> Name, Admin -> Profile -> Name"
+    )
+    # The same method embedded twice with one signature is one method
+    assert report.method_sets["Friendly"] == ["Greet() string"]
+    assert report.method_sets["Shouter"] == []
+    assert report.unresolved["Person"] == ["fmt.Stringer"]
+    assert report.to_dict()["collisions"][1]["line"] == 32
+
+
+def test_render_dot_draws_implements_dashed_and_embeds_solid():
+    fixture = os.path.join(os.path.dirname(__file__), "fixtures", "go_embedding")
+    symbols, errors = Repository(fixture).parse_directory()
+    assert errors == []
+    lines = render_dot([s for file_symbols in symbols.values() for s in file_symbols]).splitlines()
+
+    assert lines[:3] == ["digraph types {", '  rankdir="BT";', "  node [shape=record];"] and lines[-1] == "}"
+    assert '  "Account" [label="{Account|ID int\\lEmail string\\l|Lock()\\lLogin() bool\\l}"];' in lines
+    assert '  "Greeter" [shape=Mrecord, label="{Greeter|Greet() string\\l}"];' in lines
+    assert '  "Admin" -> "User" [label="embeds (pointer)", style=solid];' in lines
+    assert '  "User" -> "Account" [label="embeds", style=solid];' in lines
+    assert '  "Person" -> "Named" [label="embeds", style=solid];' in lines
+    # Types from other packages are drawn but not looked into
+    assert '  "Admin" -> "sync.Mutex" [label="embeds", style=solid];' in lines
+    assert '  "sync.Mutex" [shape=box, style=dotted];' in lines
+    assert '  "fmt.Stringer" [shape=box, style=dotted];' in lines
+    # As in find_implementers, Admin's Greet promoted through *User does not count
+    assert [line for line in lines if "implements" in line] == [
+        '  "User" -> "Friendly" [label="implements", style=dashed];',
+        '  "User" -> "Greeter" [label="implements", style=dashed];',
+    ]
+
+
+def test_render_dot_clusters_packages_and_escapes_record_labels():
+    files = {
+        "store/store.go": "package store\n\ntype Store interface {\n\tPut(key, value string)\n}\n",
+        "store/memory.go": """package store
+
+type Memory struct {
+	seen map[string]struct{}
+	out  chan<- string
+}
+
+func (m *Memory) Put(k, v string) {}
+""",
+    }
+    with tempfile.TemporaryDirectory() as tmpdir:
+        write_files(tmpdir, files)
+        symbols, errors = Repository(tmpdir).parse_directory()
+    all_symbols = [s for file_symbols in symbols.values() for s in file_symbols]
+    dot = render_dot(all_symbols)
+
+    assert errors == []
+    assert '  subgraph "cluster_store" {\n    label="store";\n' in dot
+    assert '    "store.Memory" [label="{Memory|seen map[string]struct\\{\\}\\lout chan\\<- string\\l|' in dot
+    assert '  "store.Memory" -> "store.Store" [label="implements (pointer)", style=dashed];' in dot
+    assert render_dot(list(reversed(all_symbols))) == dot
+
+
+def test_find_fields_by_tag_key():
+    files = {
+        "api/dto.go": """package api
+
+type Base struct {
+	Version int `json:"v"`
+}
+
+type Order struct {
+	Base
+	ID       string `json:"id,omitempty" db:"order_id" validate:"required"`
+	Total    int64  `db:"total"`
+	Note     string `json:"-"`
+	Secret   string
+	internal bool
+	Broken   string `json:name db:"broken"`
+}
+""",
+    }
+    with tempfile.TemporaryDirectory() as tmpdir:
+        write_files(tmpdir, files)
+        repo = Repository(tmpdir)
+        tagged = repo.find_fields_by_tag_key("json")
+        missing = repo.find_fields_by_tag_key("json", missing=True)
+        db = repo.find_fields_by_tag_key("db")
+
+    assert [(f.name, f.value) for f in tagged] == [
+        ("Base.Version", "v"),
+        ("Order.ID", "id,omitempty"),
+        ("Order.Note", "-"),
+    ]
+    # Embedded and unexported fields are not serialized on their own; a malformed tag
+    # stops parsing at its first pair, so Broken has neither key
+    assert [f.name for f in missing] == ["Order.Total", "Order.Secret", "Order.Broken"]
+    assert [f.name for f in db] == ["Order.ID", "Order.Total"]
+    assert str(tagged[1]) == 'api/dto.go:7: Order.ID json:"id,omitempty"'
+    assert str(missing[1]) == "api/dto.go:7: Order.Secret has no json tag"
+    assert tagged[1].to_dict()["type"] == "string"
+    assert find_fields_by_tag_key([{"name": "f", "type": "function"}], "json") == []
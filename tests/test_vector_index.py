@@ -0,0 +1,60 @@
+import os
+import tempfile
+
+import pytest
+
+from codekite import Repository
+from codekite.context_extractor import Chunk
+from codekite.vector_index import HashingEmbedder, VectorIndex
+
+GOLDEN = open(os.path.join(os.path.dirname(__file__), "fixtures", "go", "golden_go.go")).read()
+
+
+class CountingEmbedder(HashingEmbedder):
+    def __init__(self):
+        super().__init__()
+        self.texts = []
+
+    def embed(self, texts):
+        self.texts.extend(texts)
+        return super().embed(texts)
+
+
+def test_query_retrieves_the_greet_chunk_and_survives_a_reload():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        with open(os.path.join(tmpdir, "golden_go.go"), "w") as f:
+            f.write(GOLDEN)
+        index = Repository(tmpdir).build_vector_index(HashingEmbedder())
+        hits = index.search("greeting users", k=3)
+
+        index.save(os.path.join(tmpdir, "index"))
+        reloaded = VectorIndex.load(os.path.join(tmpdir, "index"), HashingEmbedder())
+
+    assert len(index) == 7
+    assert hits[0].file == "golden_go.go"
+    assert hits[0].chunk.symbols == ["User.Greet"]
+    assert hits[0].score >= hits[1].score >= hits[2].score
+    assert [(h.chunk, h.score) for h in reloaded.search("greeting users", k=3)] == [(h.chunk, h.score) for h in hits]
+
+
+def test_update_only_embeds_changed_chunks():
+    embedder = CountingEmbedder()
+    index = VectorIndex(embedder)
+    first = {"a.go": [Chunk("func A() {}", 0, 0, ["A"]), Chunk("func B() {}", 2, 2, ["B"])]}
+    assert index.update(first) == 2
+
+    embedder.texts.clear()
+    # B moved down a line and C is new; only C's code has not been seen
+    second = {"a.go": [Chunk("func A() {}", 0, 0, ["A"]), Chunk("func B() {}", 3, 3, ["B"]), Chunk("func C() {}", 5, 5, ["C"])]}
+    assert index.update(second) == 1
+    assert embedder.texts == ["func C() {}"]
+    assert len(index) == 3
+    assert index.search("C", k=1)[0].chunk.start_line == 5
+
+
+def test_mismatched_dimensions_are_rejected():
+    index = VectorIndex(HashingEmbedder(dimensions=8))
+    index.update({"a.go": [Chunk("func A() {}", 0, 0)]})
+    index.embedder = HashingEmbedder(dimensions=16)
+    with pytest.raises(ValueError):
+        index.search("A")
@@ -0,0 +1,114 @@
+import os
+import queue
+import tempfile
+
+from codekite import IndexEvent, Repository, Watcher
+from codekite.watcher import diff_symbols
+
+
+def write(tmpdir, rel_path, content):
+    path = os.path.join(tmpdir, rel_path)
+    os.makedirs(os.path.dirname(path), exist_ok=True)
+    with open(path, "w") as f:
+        f.write(content)
+
+
+def settle(watcher, start=0.0):
+    """Polls once to observe changes and again after the debounce window; returns the emitted events."""
+    assert watcher.poll(now=start) == []
+    # Still inside the debounce window
+    assert watcher.poll(now=start + watcher.debounce / 2) == []
+    return watcher.poll(now=start + watcher.debounce + 1)
+
+
+def test_poll_debounces_and_reports_adds_changes_and_renames():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        write(tmpdir, "a.py", "def a():\n    pass\n")
+        write(tmpdir, "b.py", "def b():\n    pass\n")
+        repo = Repository(tmpdir)
+        watcher = repo.get_watcher(index=repo.get_symbol_index(os.path.join(tmpdir, ".kit_cache", "w.json")))
+        watcher.sync()
+        assert watcher.poll(now=0.0) == []
+
+        # A burst of saves plus a directory created after the watch started
+        write(tmpdir, "a.py", "def a():\n    pass\n\ndef a2():\n    pass\n")
+        write(tmpdir, "a.py", "def a():\n    pass\n\ndef a2():\n    pass\n\ndef a3():\n    pass\n")
+        write(tmpdir, "pkg/new/c.py", "def c():\n    pass\n")
+        events = settle(watcher)
+        assert [(e.path, e.op) for e in events] == [("a.py", "changed"), ("pkg/new/c.py", "added")]
+        assert [s["name"] for s in events[0].symbols] == ["a", "a2", "a3"]
+
+        # Rename is a removal plus an addition
+        os.rename(os.path.join(tmpdir, "b.py"), os.path.join(tmpdir, "renamed.py"))
+        events = settle(watcher)
+        assert events == [IndexEvent("b.py", "removed", []), events[1]]
+        assert (events[1].path, events[1].op, events[1].symbols[0]["name"]) == ("renamed.py", "added", "b")
+        assert "b.py" not in watcher.index.symbols
+
+
+def test_temp_file_rename_and_ignored_paths():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        write(tmpdir, "main.go", "package main\n\nfunc main() {}\n")
+        write(tmpdir, ".gitignore", "generated/\n")
+        repo = Repository(tmpdir)
+        watcher = repo.get_watcher(index=repo.get_symbol_index(os.path.join(tmpdir, ".kit_cache", "w.json")))
+        watcher.sync()
+
+        # Editors often write a temporary file and rename it over the original
+        write(tmpdir, "main.go.swp", "package main\n\nfunc main() {}\n\nfunc helper() {}\n")
+        os.replace(os.path.join(tmpdir, "main.go.swp"), os.path.join(tmpdir, "main.go"))
+        write(tmpdir, ".git/hooks/pre-commit.py", "def hook(): pass\n")
+        write(tmpdir, "node_modules/dep/index.js", "function dep() {}\n")
+        write(tmpdir, "generated/out.py", "def out(): pass\n")
+        events = settle(watcher)
+        assert [(e.path, e.op) for e in events] == [("main.go", "changed")]
+        assert {s["name"] for s in events[0].symbols} == {"main", "helper"}
+
+
+def test_background_thread_delivers_events_to_callback_and_queue():
+    received = []
+    with tempfile.TemporaryDirectory() as tmpdir:
+        write(tmpdir, "a.py", "def a():\n    pass\n")
+        repo = Repository(tmpdir)
+        index = repo.get_symbol_index(os.path.join(tmpdir, ".kit_cache", "w.json"))
+        with repo.get_watcher(callback=received.append, index=index, debounce=0.05) as watcher:
+            write(tmpdir, "b.py", "def b():\n    pass\n")
+            event = watcher.events.get(timeout=5)
+        assert (event.path, event.op) == ("b.py", "added")
+        assert received == [event]
+        assert [s["name"] for s in index.symbols["b.py"]] == ["b"]
+        try:
+            watcher.events.get_nowait()
+            raise AssertionError("unexpected extra event")
+        except queue.Empty:
+            pass
+
+
+def test_background_thread_falls_back_to_polling():
+    with tempfile.TemporaryDirectory() as tmpdir:
+        write(tmpdir, "a.py", "def a():\n    pass\n")
+        repo = Repository(tmpdir)
+        index = repo.get_symbol_index(os.path.join(tmpdir, ".kit_cache", "w.json"))
+        with Watcher(repo, index=index, debounce=0.05, poll_interval=0.05, use_events=False) as watcher:
+            write(tmpdir, "a.py", "def a():\n    pass\n\ndef a2():\n    pass\n")
+            event = watcher.events.get(timeout=5)
+        assert (event.path, event.op) == ("a.py", "changed")
+        assert [s["name"] for s in event.symbols] == ["a", "a2"]
+
+
+def test_diff_symbols_matches_by_qualified_name():
+    before = [
+        {"name": "Greet", "type": "method", "node_path": "User.Greet", "start_line": 3, "code": "func (u User) Greet() {}"},
+        {"name": "Old", "type": "function", "start_line": 5, "code": "func Old() {}"},
+        {"name": "Same", "type": "function", "start_line": 7, "code": "func Same() {}"},
+    ]
+    after = [
+        # Moved down by a line but otherwise untouched: not a change
+        {"name": "Same", "type": "function", "start_line": 8, "code": "func Same() {}"},
+        {"name": "Greet", "type": "method", "node_path": "User.Greet", "start_line": 3, "code": "func (u *User) Greet() {}"},
+        {"name": "Greet", "type": "function", "start_line": 10, "code": "func Greet() {}"},
+    ]
+    changes = diff_symbols(before, after)
+    assert [s["name"] for s in changes["added"]] == ["Greet"] and changes["added"][0]["type"] == "function"
+    assert [s["name"] for s in changes["removed"]] == ["Old"]
+    assert [s["code"] for s in changes["changed"]] == ["func (u *User) Greet() {}"]